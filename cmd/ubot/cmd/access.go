@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/control"
+	"github.com/spf13/cobra"
+)
+
+var accessCmd = &cobra.Command{
+	Use:   "access",
+	Short: "List senders waiting for access",
+	Long:  "List senders outside a channel's allowFrom list whose messages the running gateway is holding until you allow or block them.",
+	RunE:  runAccessList,
+}
+
+var accessAllowCmd = &cobra.Command{
+	Use:   "allow <code>",
+	Short: "Allow a waiting sender",
+	Long:  "Allow the sender of an access request and deliver the messages held for them. The decision is remembered.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  func(cmd *cobra.Command, args []string) error { return runAccessDecide(args[0], true) },
+}
+
+var accessBlockCmd = &cobra.Command{
+	Use:   "block <code>",
+	Short: "Block a waiting sender",
+	Long:  "Block the sender of an access request and drop their held messages. The decision is remembered.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  func(cmd *cobra.Command, args []string) error { return runAccessDecide(args[0], false) },
+}
+
+func init() {
+	accessCmd.AddCommand(accessAllowCmd)
+	accessCmd.AddCommand(accessBlockCmd)
+}
+
+// gatewayClient returns a client for the running gateway's control API.
+func gatewayClient() (*control.Client, error) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.Gateway.ControlAPI {
+		return nil, errors.New("gateway control API is disabled (set gateway.controlApi to true and restart the gateway)")
+	}
+	return control.NewClient(cfg.Gateway.Addr(), cfg.Gateway.Token), nil
+}
+
+func runAccessList(cmd *cobra.Command, args []string) error {
+	client, err := gatewayClient()
+	if err != nil {
+		return err
+	}
+	reqs, err := client.PendingAccess(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(reqs) == 0 {
+		fmt.Println("No senders waiting for access.")
+		return nil
+	}
+	for _, r := range reqs {
+		fmt.Printf("%s  %s %s (chat %s) since %s\n", r.Code, r.Channel, r.SenderID, r.ChatID, r.RequestedAt.Format(time.DateTime))
+		fmt.Printf("    %q\n", r.Preview)
+	}
+	fmt.Println("\nRun 'ubot access allow <code>' or 'ubot access block <code>'.")
+	return nil
+}
+
+func runAccessDecide(code string, allow bool) error {
+	client, err := gatewayClient()
+	if err != nil {
+		return err
+	}
+	req, err := client.DecideAccess(context.Background(), code, allow)
+	if err != nil {
+		return err
+	}
+
+	if allow {
+		fmt.Printf("Allowed %s on %s.\n", req.SenderID, req.Channel)
+	} else {
+		fmt.Printf("Blocked %s on %s.\n", req.SenderID, req.Channel)
+	}
+	return nil
+}
@@ -1,17 +1,20 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
+	"github.com/hkuds/ubot/internal/calendar"
+	"github.com/hkuds/ubot/internal/codeindex"
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/failure"
+	"github.com/hkuds/ubot/internal/feeds"
+	"github.com/hkuds/ubot/internal/prompts"
 	"github.com/hkuds/ubot/internal/providers"
 	"github.com/hkuds/ubot/internal/session"
 	"github.com/hkuds/ubot/internal/skills"
@@ -26,12 +29,13 @@ var (
 var agentCmd = &cobra.Command{
 	Use:   "agent",
 	Short: "Chat with the agent",
-	Long:  "Start an interactive chat session with the agent, or send a single message.",
+	Long:  "Start an interactive chat session with the agent, or send a single message. In a terminal the chat opens in a full-screen UI with scrollback and streamed replies; use --plain for a line-by-line prompt.",
 	RunE:  runAgent,
 }
 
 func init() {
 	agentCmd.Flags().StringVarP(&messageFlag, "message", "m", "", "Send a single message and exit")
+	agentCmd.Flags().BoolVar(&plainFlag, "plain", false, "Use a plain prompt instead of the terminal UI")
 }
 
 func runAgent(cmd *cobra.Command, args []string) error {
@@ -54,13 +58,17 @@ func runAgent(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create provider: %w", err)
 	}
+	provider = providers.WithTrafficLog(provider, cfg.Providers.Log, cfg.TrafficLogDir())
+	provider = providers.WithCache(provider, cfg.Providers.Cache)
+	provider = providers.NewRoutingProvider(provider, cfg.Agents, requestChannel)
 
 	// Create session manager using the workspace directory
 	dataDir := cfg.WorkspacePath()
-	sessionMgr := session.NewManager(dataDir)
-
-	// Get or create CLI session
-	sess := sessionMgr.GetOrCreate("cli:default")
+	sessionMgr, closeSessions, err := newSessionManager(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeSessions()
 
 	// Create skills loader and discover available skills
 	skillsLoader := skills.NewLoader(dataDir)
@@ -84,15 +92,24 @@ func runAgent(cmd *cobra.Command, args []string) error {
 	registry.Register(manageUbotTool)
 
 	// Register browser tool
-	browserTool := tools.NewBrowserTool(cfg.Tools.Browser)
-	registry.Register(browserTool)
+	registerBrowserTool(registry, cfg, provider, nil)
+
+	// Register pin tool
+	registry.Register(tools.NewPinTool(sessionMgr))
+
+	// Register search_history tool
+	registry.Register(tools.NewSearchHistoryTool(sessionMgr, ""))
+
+	// Register request_tool, used when tool definitions are trimmed per turn
+	registry.Register(tools.NewRequestToolTool())
 
 	// Wrap registry with security middleware
-	secureReg := tools.NewSecureRegistry(registry)
+	secureReg := newSecureRegistry(registry, cfg)
 
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	ctx = tools.WithApprover(ctx, cliApprover{})
 
 	// Handle signals for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -103,152 +120,119 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
+	chat := &interactiveChat{
+		title:         "uBot",
+		provider:      provider,
+		sessionMgr:    sessionMgr,
+		registry:      secureReg,
+		cfg:           cfg,
+		skillsSummary: skillsSummary,
+		sess:          sessionMgr.Active("cli:default"),
+		request: func(sess *session.Session, message string) (providers.ChatRequest, *tools.ToolSelection) {
+			return cliRequest(sess, secureReg, cfg, message, skillsSummary)
+		},
+		debugContext: true,
+	}
+
 	// If message flag is provided, send single message and exit
 	if messageFlag != "" {
-		return sendSingleMessage(ctx, provider, sess, sessionMgr, secureReg, cfg, messageFlag, skillsSummary)
+		reply, err := chat.send(tools.WithProgress(ctx, printProgress), messageFlag, nil, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Println(reply)
+		return nil
 	}
 
 	// Start interactive mode
-	return runInteractiveMode(ctx, provider, sess, sessionMgr, secureReg, cfg, skillsSummary)
+	return runChat(ctx, chat, "uBot Interactive Mode\n"+
+		"Type your message and press Enter. Type 'exit' or 'quit' to leave.\n"+
+		"Commands: /clear (clear history), /pin <text> (pin context), /help (show help)")
 }
 
-func sendSingleMessage(ctx context.Context, provider providers.Provider, sess *session.Session, sessionMgr *session.Manager, registry *tools.SecureRegistry, cfg *config.Config, message string, skillsSummary string) error {
-	// Add user message to session
-	sess.AddMessage("user", message)
-
-	// Build messages for the LLM
-	messages := buildChatMessages(sess, skillsSummary)
+// cliRequest builds the chat request for a CLI turn from the session, with
+// the tools relevant to message; request_tool adds more during the turn.
+func cliRequest(sess *session.Session, registry *tools.SecureRegistry, cfg *config.Config, message string, skillsSummary string) (providers.ChatRequest, *tools.ToolSelection) {
+	vars := promptVars("cli", "default", "", skillsSummary)
+	messages := buildChatMessages(sess, systemPrompt(cfg, sess.Key, sess.GetSettings(), prompts.CLI, vars))
+	selection := tools.SelectTools(registry.GetDefinitions(), message, cfg.Agents.Defaults.MaxToolDefinitions)
 
-	// Create chat request
-	req := providers.ChatRequest{
+	return providers.ChatRequest{
 		Messages:    messages,
-		Tools:       registry.GetDefinitions(),
+		Tools:       selection.Definitions(),
 		Model:       cfg.Agents.Defaults.Model,
 		MaxTokens:   cfg.Agents.Defaults.MaxTokens,
 		Temperature: cfg.Agents.Defaults.Temperature,
-	}
-
-	// Send request to LLM
-	response, err := provider.Chat(ctx, req)
-	if err != nil {
-		return fmt.Errorf("chat request failed: %w", err)
-	}
-
-	// Handle tool calls if any
-	for response.HasToolCalls() {
-		// Execute tool calls
-		for _, toolCall := range response.ToolCalls {
-			result, err := registry.Execute(ctx, toolCall.Name, toolCall.Arguments)
-			if err != nil {
-				result = fmt.Sprintf("Error: %v", err)
-			}
-
-			// Add tool call and result to messages
-			messages = append(messages, providers.ChatMessage{
-				Role:      "assistant",
-				Content:   response.Content,
-				ToolCalls: response.ToolCalls,
-			})
-			messages = append(messages, providers.ChatMessage{
-				Role:       "tool",
-				Content:    result,
-				ToolCallID: toolCall.ID,
-				Name:       toolCall.Name,
-			})
-		}
-
-		// Continue the conversation
-		req.Messages = messages
-		response, err = provider.Chat(ctx, req)
-		if err != nil {
-			return fmt.Errorf("chat request failed: %w", err)
-		}
-	}
-
-	// Add assistant response to session
-	sess.AddMessage("assistant", response.Content)
+	}, selection
+}
 
-	// Save session
-	if err := sessionMgr.Save(sess); err != nil {
-		return fmt.Errorf("failed to save session: %w", err)
+// printChatError prints a failed chat turn, with a remediation hint when the
+// cause is one the user can fix.
+func printChatError(err error) {
+	fmt.Printf("Error: %v\n", err)
+	if failure.Of(err) != failure.Internal {
+		fmt.Println(failure.Message(err))
 	}
-
-	// Print response
-	fmt.Println(response.Content)
-
-	return nil
 }
 
-func runInteractiveMode(ctx context.Context, provider providers.Provider, sess *session.Session, sessionMgr *session.Manager, registry *tools.SecureRegistry, cfg *config.Config, skillsSummary string) error {
-	fmt.Println("uBot Interactive Mode")
-	fmt.Println("Type your message and press Enter. Type 'exit' or 'quit' to leave.")
-	fmt.Println("Commands: /clear (clear history), /help (show help)")
-	fmt.Println()
-
-	scanner := bufio.NewScanner(os.Stdin)
+// runTurn completes a turn whose request is req: it sends the
+// conversation to the model and runs the tools it calls until it answers,
+// then saves the answer to sess. Reply text is passed to onDelta as it
+// streams in, and each tool name to onTool before the tool runs; either may
+// be nil. When selection is not nil, each call offers its current tools.
+func runTurn(ctx context.Context, provider providers.Provider, sess *session.Session, sessionMgr *session.Manager, registry *tools.SecureRegistry, req providers.ChatRequest, selection *tools.ToolSelection, onDelta, onTool func(string)) (string, error) {
+	if onDelta == nil {
+		onDelta = func(string) {}
+	}
 
 	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
+		if selection != nil {
+			req.Tools = selection.Definitions()
 		}
-
-		fmt.Print("You: ")
-		if !scanner.Scan() {
-			break
-		}
-
-		input := strings.TrimSpace(scanner.Text())
-		if input == "" {
-			continue
+		response, err := providers.ChatStream(ctx, provider, req, onDelta)
+		if err != nil {
+			return "", fmt.Errorf("chat request failed: %w", err)
 		}
 
-		// Handle special commands
-		switch strings.ToLower(input) {
-		case "exit", "quit":
-			fmt.Println("Goodbye!")
-			return nil
-		case "/clear":
-			sess.Clear()
+		if !response.HasToolCalls() {
+			sess.AddMessage("assistant", response.Content)
 			if err := sessionMgr.Save(sess); err != nil {
-				fmt.Printf("Warning: failed to save session: %v\n", err)
+				return "", fmt.Errorf("failed to save session: %w", err)
 			}
-			fmt.Println("Conversation history cleared.")
-			continue
-		case "/help":
-			printHelp()
-			continue
+			return response.Content, nil
 		}
 
-		// Send message and get response
-		err := sendSingleMessage(ctx, provider, sess, sessionMgr, registry, cfg, input, skillsSummary)
-		if err != nil {
-			if ctx.Err() != nil {
-				return nil
+		// Add the tool calls and their results to the conversation
+		req.Messages = append(req.Messages, providers.ChatMessage{
+			Role:      "assistant",
+			Content:   response.Content,
+			ToolCalls: response.ToolCalls,
+		})
+		for _, toolCall := range response.ToolCalls {
+			if onTool != nil {
+				onTool(toolCall.Name)
+			}
+			result, err := registry.Execute(ctx, toolCall.Name, toolCall.Arguments)
+			if err != nil {
+				result = fmt.Sprintf("Error: %v", err)
 			}
-			fmt.Printf("Error: %v\n", err)
+			req.Messages = append(req.Messages, providers.ChatMessage{
+				Role:       "tool",
+				Content:    result,
+				ToolCallID: toolCall.ID,
+				Name:       toolCall.Name,
+			})
 		}
-		fmt.Println()
 	}
-
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("input error: %w", err)
-	}
-
-	return nil
 }
 
-func buildChatMessages(sess *session.Session, skillsSummary string) []providers.ChatMessage {
+func buildChatMessages(sess *session.Session, systemContent string) []providers.ChatMessage {
 	messages := sess.GetMessages()
 	chatMessages := make([]providers.ChatMessage, 0, len(messages)+1)
 
-	// Build system message with optional skills summary
-	systemContent := "You are uBot, a helpful AI assistant. You can use tools to help accomplish tasks: read/write files, execute commands, search the web, and browse websites with a headless browser (use browser_use tool with session parameter to keep logins across restarts). Be concise and helpful."
-
-	// Append skills summary if available
-	if skillsSummary != "" {
-		systemContent += "\n\n" + skillsSummary
+	// Append pinned context so it survives history trimming
+	if pinned := session.PinnedContext(sess.GetPins()); pinned != "" {
+		systemContent += "\n\n" + pinned
 	}
 
 	// Add system message
@@ -269,7 +253,9 @@ func buildChatMessages(sess *session.Session, skillsSummary string) []providers.
 	return chatMessages
 }
 
-func registerDefaultTools(registry *tools.ToolRegistry, cfg *config.Config) {
+// registerDefaultTools registers the built-in tools and returns the
+// variables set_env keeps for each conversation.
+func registerDefaultTools(registry *tools.ToolRegistry, cfg *config.Config) *tools.SessionEnv {
 	// Register filesystem tools
 	readFile := tools.NewReadFileTool()
 	writeFile := tools.NewWriteFileTool()
@@ -279,36 +265,86 @@ func registerDefaultTools(registry *tools.ToolRegistry, cfg *config.Config) {
 	registry.Register(writeFile)
 	registry.Register(listDir)
 
+	// Variables set in a conversation are passed to its exec commands
+	env := tools.NewSessionEnv()
+	registry.Register(tools.NewSetEnvTool(env))
+
+	fetchTool := tools.NewWebFetchTool(tools.MaxResultChars)
+	registry.Register(fetchTool)
+
+	// Feed subscriptions live in the workspace, shared with the gateway
+	registry.Register(tools.NewFeedsTool(feeds.NewStore(cfg.FeedsPath())))
+
+	registerConfiguredTools(registry, cfg, env)
+	return env
+}
+
+// registerConfiguredTools registers the built-in tools that depend on
+// settings, replacing those of an earlier config when the gateway reloads.
+// exec passes the variables in env to its commands.
+func registerConfiguredTools(registry *tools.ToolRegistry, cfg *config.Config, env *tools.SessionEnv) {
 	// Register exec tool
 	timeout := time.Duration(cfg.Tools.Exec.Timeout) * time.Second
 	execTool := tools.NewExecToolWithOptions(timeout, cfg.WorkspacePath(), cfg.Tools.Exec.RestrictToWorkspace)
-	registry.Register(execTool)
+	execTool.SetSessionEnv(env)
+	registry.Replace(execTool)
 
-	// Register web tools if configured
-	if cfg.Tools.Web.Search.APIKey != "" {
-		searchTool := tools.NewWebSearchTool(cfg.Tools.Web.Search.APIKey, cfg.Tools.Web.Search.MaxResults)
-		registry.Register(searchTool)
+	// Limits on how the web tools load pages
+	tools.ConfigurePoliteness(cfg.Tools.Web.Politeness)
+
+	// Register download_file with the configured limits
+	registry.Replace(tools.NewDownloadFileTool(cfg.WorkspacePath(), cfg.Tools.Download))
+
+	// Register web search with the configured provider, unless it is off
+	search, err := tools.NewSearchProvider(cfg.Tools.Web.Search)
+	if err != nil {
+		log.Printf("Warning: web search disabled: %v", err)
+	}
+	if search != nil {
+		registry.Replace(tools.NewWebSearchTool(search, cfg.Tools.Web.Search.MaxResults))
+	} else {
+		registry.Unregister("web_search")
 	}
 
-	fetchTool := tools.NewWebFetchTool(50000) // 50KB max content
-	registry.Register(fetchTool)
-}
+	// Search and patch files in the code project if one is configured,
+	// else in the workspace
+	codeRoot := cfg.WorkspacePath()
+	if projectDir := cfg.CodeProjectPath(); projectDir != "" {
+		codeRoot = projectDir
+	}
+	registry.Replace(tools.NewSearchFilesTool(codeRoot))
+	registry.Replace(tools.NewApplyPatchTool(codeRoot))
+
+	// Register code navigation tools if a project is configured
+	if projectDir := cfg.CodeProjectPath(); projectDir != "" {
+		index := codeindex.New(projectDir)
+		registry.Replace(tools.NewSymbolSearchTool(index))
+		registry.Replace(tools.NewOpenDefinitionTool(index))
+	} else {
+		registry.Unregister("symbol_search")
+		registry.Unregister("open_definition")
+	}
+
+	// Register the calendar tool if a calendar is configured
+	cal, err := calendar.New(cfg.Tools.Calendar, cfg.CalendarCredentialsDir())
+	if err != nil {
+		log.Printf("Warning: calendar disabled: %v", err)
+	}
+	if cal != nil {
+		registry.Replace(tools.NewCalendarTool(cal, calendar.NewReminders(cfg.CalendarRemindersPath())))
+	} else {
+		registry.Unregister("calendar")
+	}
+
+	// Clipboard and notifications for users running uBot on their desktop
+	for _, tool := range tools.NewDesktopTools() {
+		if cfg.Tools.Desktop.Enabled {
+			registry.Replace(tool)
+		} else {
+			registry.Unregister(tool.Name())
+		}
+	}
 
-func printHelp() {
-	fmt.Println()
-	fmt.Println("uBot Interactive Mode Commands:")
-	fmt.Println("  /clear    - Clear conversation history")
-	fmt.Println("  /help     - Show this help message")
-	fmt.Println("  exit/quit - Exit the chat")
-	fmt.Println()
-	fmt.Println("Available Tools:")
-	fmt.Println("  - read_file: Read file contents")
-	fmt.Println("  - write_file: Write content to a file")
-	fmt.Println("  - list_dir: List directory contents")
-	fmt.Println("  - exec: Execute shell commands")
-	fmt.Println("  - web_search: Search the web (if configured)")
-	fmt.Println("  - web_fetch: Fetch content from URLs")
-	fmt.Println("  - list_skills: List available skills")
-	fmt.Println("  - read_skill: Load a specific skill")
-	fmt.Println()
+	// Let the agent check what this deployment supports
+	registry.Replace(tools.NewCapabilitiesTool(runtimeCapabilities(cfg), registry))
 }
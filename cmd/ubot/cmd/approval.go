@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/hkuds/ubot/internal/audit"
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/redis"
+	"github.com/hkuds/ubot/internal/tools"
+	"github.com/hkuds/ubot/internal/tui"
+)
+
+// stdin is shared by the interactive loops and the CLI approver so that
+// approval answers and chat input are read from the same buffer.
+var stdin = bufio.NewScanner(os.Stdin)
+
+// newSecureRegistry wraps registry with the security middleware, the
+// configured tool approval policy and call limits, the audit log and
+// storage for large results.
+func newSecureRegistry(registry *tools.ToolRegistry, cfg *config.Config) *tools.SecureRegistry {
+	secureReg := tools.NewSecureRegistry(registry)
+	secureReg.SetApprovalPolicy(cfg.Tools.Approval.Default, cfg.Tools.Approval.Tools)
+	secureReg.SetSkillRestrictions(skillRestrictions(cfg))
+	secureReg.SetParallelism(cfg.Tools.Parallel.WorkerCount(), cfg.Tools.Parallel.CallTimeout(), cfg.Tools.Parallel.ToolTimeouts())
+	if !cfg.Tools.Audit.Disabled {
+		secureReg.SetAuditor(audit.NewLogger(cfg.AuditDir(), cfg.Tools.Audit.MaxBytes(), cfg.Tools.Audit.Keep()))
+	}
+	// Replaces fetch_more of an earlier config when the gateway reloads
+	registry.Unregister(tools.FetchMoreName)
+	if cfg.Tools.Results.Disabled {
+		secureReg.SetOverflow(nil, cfg.Tools.Results.Threshold())
+	} else {
+		store := newOverflowStore(cfg)
+		if err := registry.Register(tools.NewFetchMoreTool(store)); err != nil {
+			fmt.Printf("Warning: failed to register fetch_more tool: %v\n", err)
+			store = nil
+		}
+		secureReg.SetOverflow(store, cfg.Tools.Results.Threshold())
+	}
+	return secureReg
+}
+
+// skillRestrictions returns the limits applied to a turn after it reads an
+// untrusted skill. File tools stay in the workspace unless
+// tools.skills.anyPath is set.
+func skillRestrictions(cfg *config.Config) tools.SkillRestrictions {
+	r := tools.SkillRestrictions{
+		Policies: cfg.Tools.Skills.Policies(),
+		Trusted:  cfg.Tools.Skills.Trusted,
+	}
+	if !cfg.Tools.Skills.AnyPath {
+		r.Workspace = cfg.WorkspacePath()
+	}
+	return r
+}
+
+// newOverflowStore returns the store for large tool results: Redis when the
+// gateway runs in a cluster, so any worker can fetch them, otherwise files
+// in the workspace.
+func newOverflowStore(cfg *config.Config) tools.OverflowStore {
+	retention := cfg.Tools.Results.Retention()
+	if cfg.Cluster.IsClustered() && cfg.Cluster.RedisURL != "" {
+		client, err := redis.NewClient(cfg.Cluster.RedisURL)
+		if err == nil {
+			store := redis.NewKeyStore(client, cfg.Cluster.KeyPrefix()+"result:")
+			store.SetTTL(retention)
+			return store
+		}
+		fmt.Printf("Warning: storing large results locally: %v\n", err)
+	}
+	return tools.NewDirStore(cfg.ResultsPath(), retention)
+}
+
+// dropArtifacts removes the tool results stored for a conversation whose
+// history was cleared.
+func dropArtifacts(registry *tools.SecureRegistry, sessionKey string) {
+	if err := registry.DropArtifacts(sessionKey); err != nil {
+		log.Printf("Warning: failed to remove stored results of %s: %v", sessionKey, err)
+	}
+}
+
+// cliApprover asks for tool approval on the terminal.
+type cliApprover struct{}
+
+// Approve prints the request and reads a y/n answer from stdin.
+func (cliApprover) Approve(ctx context.Context, req tools.ApprovalRequest) (bool, error) {
+	fmt.Printf("%s [y/N] ", req.Prompt())
+	if !stdin.Scan() {
+		fmt.Println()
+		return false, fmt.Errorf("no answer: %v", stdin.Err())
+	}
+	answer := strings.ToLower(strings.TrimSpace(stdin.Text()))
+	return answer == "y" || answer == "yes", nil
+}
+
+// tuiApprover asks for tool approval in the chat terminal UI.
+type tuiApprover struct {
+	chat *tui.Chat
+}
+
+// Approve shows the request in the chat and waits for a y/n key.
+func (a tuiApprover) Approve(ctx context.Context, req tools.ApprovalRequest) (bool, error) {
+	return a.chat.Approve(ctx, req.Prompt())
+}
+
+// newChatApprovals creates the approver used by the gateway. Prompts go to
+// the originating chat with Approve/Deny buttons where the channel supports
+// them.
+func newChatApprovals(cfg *config.Config, msgBus *bus.MessageBus) *tools.ChatApprovals {
+	return tools.NewChatApprovals(cfg.Tools.Approval.WaitTimeout(), func(req tools.RequestInfo, id, prompt string) {
+		msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel: req.Channel,
+			ChatID:  req.ChatID,
+			Content: prompt,
+			Buttons: []bus.Button{
+				{Text: "Approve", Data: "/approve " + id},
+				{Text: "Deny", Data: "/deny " + id},
+			},
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hkuds/ubot/internal/audit"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the tool call audit log",
+	Long:  "Read the audit log of tool calls (tool, redacted parameters, caller, duration, result size and error) kept in ~/.ubot/audit.",
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Show the latest tool calls",
+	Long:  "Print the most recent entries of the audit log, optionally following new ones as they are written.",
+	Args:  cobra.NoArgs,
+	RunE:  runAuditTail,
+}
+
+var (
+	auditTailLines  int
+	auditTailFollow bool
+	auditTailJSON   bool
+)
+
+func init() {
+	auditTailCmd.Flags().IntVarP(&auditTailLines, "lines", "n", 20, "number of entries to show")
+	auditTailCmd.Flags().BoolVarP(&auditTailFollow, "follow", "f", false, "keep printing new entries")
+	auditTailCmd.Flags().BoolVar(&auditTailJSON, "json", false, "print raw JSON lines")
+	auditCmd.AddCommand(auditTailCmd)
+}
+
+func runAuditTail(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.Tools.Audit.Disabled {
+		fmt.Println("Audit logging is disabled (tools.audit.disabled). Showing existing entries.")
+	}
+
+	entries, err := audit.Tail(cfg.AuditDir(), auditTailLines)
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	for _, e := range entries {
+		printAuditEntry(e)
+	}
+	if !auditTailFollow {
+		if len(entries) == 0 {
+			fmt.Println("No tool calls recorded yet.")
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return audit.Follow(ctx, cfg.AuditDir(), time.Second, printAuditEntry)
+}
+
+// printAuditEntry prints e as a readable line, or as JSON with --json.
+func printAuditEntry(e audit.Entry) {
+	if !auditTailJSON {
+		fmt.Println(e)
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,382 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/cron"
+	"github.com/hkuds/ubot/internal/prompts"
+	"github.com/hkuds/ubot/internal/session"
+)
+
+// maxButtonData is the most a Telegram inline button can send back.
+const maxButtonData = 64
+
+// channelHelp lists the commands chat channels answer without the model.
+const channelHelp = `Commands:
+/model - Choose the model for this chat
+/settings - Model, temperature and persona for this chat
+/jobs - List and delete scheduled jobs
+/reset - Start a new conversation (pins are kept)
+/pin [text] - Pin a fact, or the last reply, to keep in context
+/pins - List pinned facts
+/unpin N - Remove pin N
+/search words - Search earlier messages
+/undo - Remove your last message and the reply
+/fork [name] - Continue in a new branch of this conversation
+/branches - List branches; /branch name switches
+/help - Show this help`
+
+// handleBotCommand answers the commands channels offer in their menus, such
+// as Telegram's /start, /model and /jobs, without calling the model. It
+// returns the reply, with inline buttons where a choice is offered, and
+// true when msg carried one of these commands.
+func handleBotCommand(msg bus.InboundMessage, sess *session.Session, sessionMgr *session.Manager, scheduler *cron.Scheduler, defaults config.AgentDefaults, promptsCfg config.PromptsConfig) (bus.OutboundMessage, bool) {
+	if msg.Command == nil {
+		return bus.OutboundMessage{}, false
+	}
+
+	var reply bus.OutboundMessage
+	switch msg.Command.Name {
+	case "start":
+		greeting := "Hi!"
+		if name := senderName(msg); name != "" {
+			greeting = "Hi " + name + "!"
+		}
+		reply.Content = greeting + " I'm uBot. Write or send a voice message and I'll help.\n\n" + channelHelp
+	case "help":
+		reply.Content = channelHelp
+	case "reset":
+		sess.Clear()
+		reply.Content = "Started a new conversation. Pinned facts are kept; /pins lists them."
+		if err := sessionMgr.Save(sess); err != nil {
+			reply.Content += fmt.Sprintf("\n(warning: failed to save session: %v)", err)
+		}
+	case "model":
+		reply = modelCommand(sess, sessionMgr, defaults, msg.Command)
+	case "settings":
+		reply = settingsCommand(sess, sessionMgr, defaults, promptsCfg.Persona(msg.SessionKey()), msg.Command)
+	case "jobs":
+		reply = jobsCommand(scheduler, msg, msg.Command)
+	case "branches":
+		reply = bus.OutboundMessage{
+			Content: branchesCommand(sess, sessionMgr),
+			Buttons: branchButtons(sessionMgr.Branches(sess.Key)),
+		}
+	default:
+		return bus.OutboundMessage{}, false
+	}
+
+	reply.Channel = msg.Channel
+	reply.ChatID = msg.ChatID
+	reply.Trace = msg.Trace
+	return reply, true
+}
+
+// modelCommand shows the chat's model with a picker of the configured
+// models, or switches to the one named (or numbered) in the arguments.
+func modelCommand(sess *session.Session, sessionMgr *session.Manager, defaults config.AgentDefaults, cmd *bus.Command) bus.OutboundMessage {
+	choices := defaults.ModelChoices()
+	current := defaults.ChatModel(sess.GetModel())
+	arg := strings.Join(cmd.Args, " ")
+
+	if arg == "" {
+		if len(choices) == 1 {
+			return bus.OutboundMessage{Content: fmt.Sprintf("Model: %s\nNo other models are offered; add them to agents.defaults.models in the config.", current)}
+		}
+		return bus.OutboundMessage{
+			Content: fmt.Sprintf("Model: %s\nChoose the model for this chat:", current),
+			Buttons: modelButtons(choices, current),
+		}
+	}
+
+	model := ""
+	switch n, err := strconv.Atoi(arg); {
+	case strings.EqualFold(arg, "default"):
+		model = defaults.Model
+	case err == nil && n >= 1 && n <= len(choices):
+		model = choices[n-1]
+	case slices.Contains(choices, arg):
+		model = arg
+	}
+	if model == "" {
+		return bus.OutboundMessage{
+			Content: fmt.Sprintf("%s is not offered here. Choose one of these:", arg),
+			Buttons: modelButtons(choices, current),
+		}
+	}
+
+	// The default model is not stored, so the chat follows config changes
+	if model == defaults.Model {
+		sess.SetModel("")
+	} else {
+		sess.SetModel(model)
+	}
+	content := fmt.Sprintf("Using %s in this chat.", model)
+	if err := sessionMgr.Save(sess); err != nil {
+		content += fmt.Sprintf("\n(warning: failed to save session: %v)", err)
+	}
+	return bus.OutboundMessage{Content: content}
+}
+
+// modelButtons offers each model, marking the current one. Buttons send the
+// model's name, or its number when the name is too long to send back.
+func modelButtons(choices []string, current string) []bus.Button {
+	buttons := make([]bus.Button, len(choices))
+	for i, model := range choices {
+		data := "/model " + model
+		if len(data) > maxButtonData {
+			data = "/model " + strconv.Itoa(i+1)
+		}
+		text := model
+		if model == current {
+			text = "✓ " + model
+		}
+		buttons[i] = bus.Button{Text: text, Data: data}
+	}
+	return buttons
+}
+
+// settingsCommand shows the chat's model, temperature and persona, or
+// changes one of them for this chat: "/settings temperature 0.2",
+// "/settings persona coder", "/settings model gpt-4o". "default" goes back
+// to the configured value and "/settings reset" to all of them.
+// configuredPersona is the persona the prompts config picks for the chat.
+func settingsCommand(sess *session.Session, sessionMgr *session.Manager, defaults config.AgentDefaults, configuredPersona string, cmd *bus.Command) bus.OutboundMessage {
+	if configuredPersona == "" {
+		configuredPersona = prompts.Default
+	}
+	settings := sess.GetSettings()
+	name, value := strings.ToLower(cmd.Arg(0)), strings.Join(cmd.Args[min(1, len(cmd.Args)):], " ")
+	isDefault := strings.EqualFold(value, "default")
+
+	var content string
+	switch name {
+	case "":
+		return bus.OutboundMessage{Content: describeSettings(sess, defaults, configuredPersona)}
+	case "model":
+		return modelCommand(sess, sessionMgr, defaults, &bus.Command{Name: "model", Args: cmd.Args[1:]})
+	case "temperature":
+		switch t, err := strconv.ParseFloat(value, 64); {
+		case value == "":
+			return bus.OutboundMessage{Content: fmt.Sprintf("Temperature: %g\nUse /settings temperature <0-2>, or /settings temperature default for %g.",
+				settings.TemperatureOr(defaults.Temperature), defaults.Temperature)}
+		case isDefault:
+			settings.Temperature = nil
+			content = fmt.Sprintf("Using the default temperature, %g, in this chat.", defaults.Temperature)
+		case err != nil || t < 0 || t > 2:
+			return bus.OutboundMessage{Content: fmt.Sprintf("%s is not a temperature; use a number from 0 to 2.", value)}
+		default:
+			settings.Temperature = &t
+			content = fmt.Sprintf("Using temperature %g in this chat.", t)
+		}
+	case "persona":
+		personas := chatPersonas()
+		current := settings.Persona
+		if current == "" {
+			current = configuredPersona
+		}
+		switch {
+		case value == "":
+			return bus.OutboundMessage{
+				Content: fmt.Sprintf("Persona: %s\nChoose the persona for this chat:", current),
+				Buttons: personaButtons(personas, current),
+			}
+		case isDefault:
+			settings.Persona = ""
+			content = fmt.Sprintf("Using the default persona, %s, in this chat.", configuredPersona)
+		case !slices.Contains(personas, value):
+			return bus.OutboundMessage{
+				Content: fmt.Sprintf("There is no persona called %s. Choose one of these:", value),
+				Buttons: personaButtons(personas, current),
+			}
+		default:
+			settings.Persona = value
+			content = fmt.Sprintf("Using the %s persona in this chat.", value)
+		}
+	case "reset":
+		settings = session.Settings{}
+		sess.SetModel("")
+		content = "This chat uses the configured model, temperature and persona again."
+	default:
+		return bus.OutboundMessage{Content: "Usage: /settings, /settings model|temperature|persona <value or default>, or /settings reset"}
+	}
+
+	sess.SetSettings(settings)
+	if err := sessionMgr.Save(sess); err != nil {
+		content += fmt.Sprintf("\n(warning: failed to save session: %v)", err)
+	}
+	return bus.OutboundMessage{Content: content}
+}
+
+// describeSettings lists the chat's settings, marking those that follow
+// the config.
+func describeSettings(sess *session.Session, defaults config.AgentDefaults, configuredPersona string) string {
+	settings := sess.GetSettings()
+	mark := func(overridden bool) string {
+		if overridden {
+			return ""
+		}
+		return " (default)"
+	}
+	model := defaults.ChatModel(sess.GetModel())
+	persona := settings.Persona
+	if persona == "" {
+		persona = configuredPersona
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Settings for this chat:\n")
+	fmt.Fprintf(&sb, "Model: %s%s\n", model, mark(model != defaults.Model))
+	fmt.Fprintf(&sb, "Temperature: %g%s\n", settings.TemperatureOr(defaults.Temperature), mark(settings.Temperature != nil))
+	fmt.Fprintf(&sb, "Persona: %s%s\n\n", persona, mark(settings.Persona != ""))
+	sb.WriteString("Change one with /settings model <name>, /settings temperature <0-2> or /settings persona <name>; " +
+		"\"default\" instead of a value goes back to the config. /settings reset goes back for all of them.")
+	return sb.String()
+}
+
+// chatPersonas returns the prompt templates a chat may choose as its
+// persona. The rootchat template is meant for the host's owner and is not
+// offered.
+func chatPersonas() []string {
+	var names []string
+	for _, info := range promptLib.List() {
+		if info.Name != prompts.Rootchat {
+			names = append(names, info.Name)
+		}
+	}
+	return names
+}
+
+// personaButtons offers each persona, marking the current one.
+func personaButtons(personas []string, current string) []bus.Button {
+	var buttons []bus.Button
+	for _, name := range personas {
+		data := "/settings persona " + name
+		if len(data) > maxButtonData {
+			continue
+		}
+		text := name
+		if name == current {
+			text = "✓ " + name
+		}
+		buttons = append(buttons, bus.Button{Text: text, Data: data})
+	}
+	return buttons
+}
+
+// branchButtons offers to switch to each branch but the current one.
+func branchButtons(branches []session.Branch) []bus.Button {
+	if len(branches) < 2 {
+		return nil
+	}
+	var buttons []bus.Button
+	for _, b := range branches {
+		if !b.Active {
+			buttons = append(buttons, bus.Button{Text: b.Name, Data: "/branch " + b.Name})
+		}
+	}
+	return buttons
+}
+
+// jobsCommand lists the jobs scheduled for the chat with a button to delete
+// each, or deletes the job given as "/jobs delete <id>".
+func jobsCommand(scheduler *cron.Scheduler, msg bus.InboundMessage, cmd *bus.Command) bus.OutboundMessage {
+	if scheduler == nil {
+		return bus.OutboundMessage{Content: "Scheduled jobs are not available here."}
+	}
+
+	var deleted string
+	switch cmd.Arg(0) {
+	case "":
+	case "delete":
+		id := cmd.Arg(1)
+		var err error
+		switch {
+		case slices.ContainsFunc(chatJobs(scheduler, msg), func(j cron.Job) bool { return j.ID == id }):
+			err = scheduler.RemoveJob(id)
+		case slices.ContainsFunc(chatMessages(scheduler, msg), func(m cron.ScheduledMessage) bool { return m.ID == id }):
+			err = scheduler.CancelMessage(id)
+		default:
+			return bus.OutboundMessage{Content: fmt.Sprintf("Job %s not found in this chat.", id)}
+		}
+		if err != nil {
+			return bus.OutboundMessage{Content: fmt.Sprintf("Failed to delete job %s: %v", id, err)}
+		}
+		deleted = fmt.Sprintf("Job %s deleted.\n\n", id)
+	default:
+		return bus.OutboundMessage{Content: "Usage: /jobs, or /jobs delete <id>"}
+	}
+
+	jobs, messages := chatJobs(scheduler, msg), chatMessages(scheduler, msg)
+	if len(jobs) == 0 && len(messages) == 0 {
+		return bus.OutboundMessage{Content: deleted + "No jobs are scheduled in this chat. Ask me to schedule one, e.g. \"every morning at 9, send me the weather\"."}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(deleted)
+	buttons := make([]bus.Button, 0, len(jobs)+len(messages))
+	if len(jobs) > 0 {
+		sb.WriteString("Scheduled jobs:\n")
+	}
+	for _, j := range jobs {
+		fmt.Fprintf(&sb, "- %s (%s): %s\n", j.ID, j.Schedule, j.Instruction)
+		buttons = append(buttons, bus.Button{
+			Text: "Delete " + j.ID + ": " + shortText(j.Instruction, 20),
+			Data: "/jobs delete " + j.ID,
+		})
+	}
+	if len(messages) > 0 {
+		sb.WriteString("Messages to send:\n")
+	}
+	for _, m := range messages {
+		fmt.Fprintf(&sb, "- %s (%s): %s\n", m.ID, m.At.Format("Mon 2006-01-02 15:04"), m.Content)
+		buttons = append(buttons, bus.Button{
+			Text: "Delete " + m.ID + ": " + shortText(m.Content, 20),
+			Data: "/jobs delete " + m.ID,
+		})
+	}
+	return bus.OutboundMessage{Content: strings.TrimSuffix(sb.String(), "\n"), Buttons: buttons}
+}
+
+// chatMessages returns the messages scheduled for msg's chat, soonest
+// first.
+func chatMessages(scheduler *cron.Scheduler, msg bus.InboundMessage) []cron.ScheduledMessage {
+	var messages []cron.ScheduledMessage
+	for _, m := range scheduler.ListMessages() {
+		if m.Channel == msg.Channel && m.ChatID == msg.ChatID {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+// chatJobs returns the jobs that report to msg's chat, oldest first.
+func chatJobs(scheduler *cron.Scheduler, msg bus.InboundMessage) []cron.Job {
+	var jobs []cron.Job
+	for _, j := range scheduler.ListJobs() {
+		if j.Channel == msg.Channel && j.ChatID == msg.ChatID {
+			jobs = append(jobs, j)
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool {
+		x, _ := strconv.Atoi(jobs[a].ID)
+		y, _ := strconv.Atoi(jobs[b].ID)
+		return x < y
+	})
+	return jobs
+}
+
+// shortText cuts s to at most n characters, marking the cut with "…".
+func shortText(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
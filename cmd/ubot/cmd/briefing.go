@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/briefing"
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/cron"
+	"github.com/hkuds/ubot/internal/session"
+)
+
+// briefingAhead is how far ahead the briefing looks for meetings, jobs and
+// scheduled messages.
+const briefingAhead = 24 * time.Hour
+
+// runBriefing sends the daily briefing configured under agents.briefing
+// until ctx is done. It returns at once when the briefing is off.
+func runBriefing(ctx context.Context, cfg *config.Config, live *liveGateway, sessionMgr *session.Manager, scheduler *cron.Scheduler, msgBus *bus.MessageBus) {
+	bc := cfg.Agents.Briefing
+	if !bc.Enabled {
+		return
+	}
+	var chats []briefing.Chat
+	for _, key := range bc.Recipients(cfg.Channels.Admin) {
+		chat, err := briefing.ParseChat(key)
+		if err != nil {
+			log.Printf("Warning: briefing: %v", err)
+			continue
+		}
+		chats = append(chats, chat)
+	}
+	if len(chats) == 0 {
+		log.Printf("Warning: briefing is enabled but has no chats to go to (set agents.briefing.chats or channels.admin)")
+		return
+	}
+
+	model := bc.Model
+	if model == "" {
+		model = cfg.Agents.Defaults.Model
+	}
+	b := briefing.New(live.provider, model, cfg.BriefingPath(), msgBus.PublishOutbound)
+	b.SetInstruction(bc.Instruction)
+	toolbox := jobToolbox{live: live}
+	b.AddSection(briefing.Section{Title: "Calendar, next 24 hours", Gather: func(ctx context.Context, chat briefing.Chat, _ time.Time) (string, error) {
+		return briefingTool(ctx, toolbox, chat, "calendar", map[string]interface{}{
+			"action": "list",
+			"to":     time.Now().Add(briefingAhead).Format("2006-01-02 15:04"),
+		}, "No events")
+	}})
+	b.AddSection(briefing.Section{Title: "New feed items", Gather: func(ctx context.Context, chat briefing.Chat, _ time.Time) (string, error) {
+		return briefingTool(ctx, toolbox, chat, "feeds", map[string]interface{}{"action": "check"}, "No feeds followed", "No new items")
+	}})
+	b.AddSection(briefing.Section{Title: "Scheduled", Gather: func(_ context.Context, chat briefing.Chat, _ time.Time) (string, error) {
+		return briefingSchedule(scheduler, chat, time.Now()), nil
+	}})
+	admin := cfg.Channels.Admin.SessionKey()
+	b.AddSection(briefing.Section{Title: "Conversations", Gather: func(_ context.Context, chat briefing.Chat, since time.Time) (string, error) {
+		return briefingThreads(sessionMgr, chat, chat.Key() == admin, since)
+	}})
+
+	if err := b.Run(ctx, bc.Schedule, chats); err != nil {
+		log.Printf("Warning: briefing is off: %v", err)
+	}
+}
+
+// briefingTool runs a job tool for chat and returns its result, or "" when
+// the tool is not available or its result starts with one of quiet, the
+// tool's ways of saying there is nothing to report.
+func briefingTool(ctx context.Context, toolbox jobToolbox, chat briefing.Chat, name string, params map[string]interface{}, quiet ...string) (string, error) {
+	if _, registry := toolbox.live.current(); !registry.Has(name) {
+		return "", nil
+	}
+	result, err := toolbox.run(ctx, chat.Channel, chat.ChatID, name, params)
+	if err != nil {
+		return "", err
+	}
+	for _, prefix := range quiet {
+		if strings.HasPrefix(result, prefix) {
+			return "", nil
+		}
+	}
+	return result, nil
+}
+
+// briefingSchedule lists the chat's jobs and scheduled messages due within
+// briefingAhead of now, soonest first.
+func briefingSchedule(scheduler *cron.Scheduler, chat briefing.Chat, now time.Time) string {
+	type due struct {
+		at   time.Time
+		line string
+	}
+	var items []due
+	for _, job := range scheduler.ListJobs() {
+		if job.Channel != chat.Channel || job.ChatID != chat.ChatID {
+			continue
+		}
+		next, err := cron.NextRun(job.Schedule, now)
+		if err != nil || next.Sub(now) > briefingAhead {
+			continue
+		}
+		items = append(items, due{next, fmt.Sprintf("job %s (%s): %s", job.ID, job.Schedule, job.Instruction)})
+	}
+	for _, msg := range scheduler.ListMessages() {
+		if msg.Channel != chat.Channel || msg.ChatID != chat.ChatID || msg.At.Sub(now) > briefingAhead {
+			continue
+		}
+		items = append(items, due{msg.At, "message: " + msg.Content})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].at.Before(items[j].at) })
+
+	var sb strings.Builder
+	for _, item := range items {
+		fmt.Fprintf(&sb, "- %s %s\n", item.at.Format("Mon 15:04"), item.line)
+	}
+	return sb.String()
+}
+
+// briefingThreads lists the conversations with messages from users since
+// the last briefing, flagging those still waiting for an answer. The admin
+// chat hears about every conversation; other chats only about their own
+// branches.
+func briefingThreads(sessionMgr *session.Manager, chat briefing.Chat, admin bool, since time.Time) (string, error) {
+	infos, err := sessionMgr.ListFiltered(session.Filter{Since: since})
+	if err != nil {
+		return "", err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].UpdatedAt.After(infos[j].UpdatedAt) })
+
+	var sb strings.Builder
+	for _, info := range infos {
+		if info.Key == chat.Key() || (!admin && session.RootKey(info.Key) != chat.Key()) {
+			continue
+		}
+		sess := sessionMgr.Get(info.Key)
+		if sess == nil {
+			continue
+		}
+		messages := sess.GetMessages()
+		fresh := 0
+		for _, msg := range messages {
+			if msg.Role == "user" && msg.Timestamp.After(since) {
+				fresh++
+			}
+		}
+		if fresh == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "- %s: %d new message(s)", info.Key, fresh)
+		if last := messages[len(messages)-1]; last.Role == "user" {
+			fmt.Fprintf(&sb, ", waiting for an answer to %q", shortText(last.Content, 80))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
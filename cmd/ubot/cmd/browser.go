@@ -0,0 +1,49 @@
+//go:build !lite && !nobrowser
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/providers"
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+// registerBrowserTool registers the browser tool, limited to the actions
+// the deployment allows, enabling screenshot descriptions when a vision
+// model is configured. Screenshots go to the chat through send, if set. It
+// also lets web_fetch render pages with Chrome.
+func registerBrowserTool(registry *tools.ToolRegistry, cfg *config.Config, provider providers.Provider, send func(bus.OutboundMessage)) {
+	browserTool := tools.NewBrowserTool(cfg.Tools.Browser)
+	browserTool.SetWorkspace(cfg.WorkspacePath())
+	if send != nil {
+		browserTool.SetSender(send)
+	}
+	if unknown := browserTool.SetAllowedActions(cfg.Security.Browser.AllowedActions); len(unknown) > 0 {
+		log.Printf("Warning: ignoring unknown browser actions in security.browser.allowedActions: %v", unknown)
+	}
+	registry.Register(browserTool)
+
+	// web_fetch renders pages with a Chrome of its own, so that it does
+	// not take over browser_use's tab or session
+	if fetch, ok := registry.Get("web_fetch").(*tools.WebFetchTool); ok {
+		fetch.SetRenderer(tools.NewChromeRenderer(cfg.Tools.Browser))
+	}
+
+	vision := cfg.Tools.Browser.Vision
+	if !vision.Enabled {
+		return
+	}
+	visionProvider := provider
+	if vision.Provider != "" {
+		p, err := providers.NewProviderByName(cfg, vision.Provider)
+		if err != nil {
+			log.Printf("Warning: screenshot descriptions disabled: %v", err)
+			return
+		}
+		visionProvider = p
+	}
+	browserTool.SetImageDescriber(providers.NewVisionDescriber(visionProvider, vision.Model))
+}
@@ -0,0 +1,15 @@
+//go:build lite || nobrowser
+
+package cmd
+
+import (
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/providers"
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+// registerBrowserTool does nothing: the browser is not compiled into this
+// build.
+func registerBrowserTool(registry *tools.ToolRegistry, cfg *config.Config, provider providers.Provider, send func(bus.OutboundMessage)) {
+}
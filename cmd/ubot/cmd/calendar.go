@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/hkuds/ubot/internal/calendar"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var calendarPortFlag int
+
+var calendarCmd = &cobra.Command{
+	Use:   "calendar",
+	Short: "Show the calendar connection and the next events",
+	Long:  "Show which calendar the calendar tool uses, whether credentials are stored, and the events of the next 24 hours.",
+	RunE:  runCalendarStatus,
+}
+
+var calendarLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store the credentials of the configured calendar",
+	Long:  "For CalDAV, ask for the password of tools.calendar.username. For Google Calendar, open the consent page and store the tokens. Credentials are kept in ~/.ubot/calendar, readable only by you.",
+	RunE:  runCalendarLogin,
+}
+
+var calendarLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Delete the stored calendar credentials",
+	RunE:  runCalendarLogout,
+}
+
+func init() {
+	calendarLoginCmd.Flags().IntVar(&calendarPortFlag, "port", 0, "Local port Google redirects to after consent (default: any free port)")
+	calendarCmd.AddCommand(calendarLoginCmd)
+	calendarCmd.AddCommand(calendarLogoutCmd)
+}
+
+// loadCalendarConfig loads the config and checks that a calendar is set up.
+func loadCalendarConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.Tools.Calendar.Provider == "" {
+		return nil, errors.New("no calendar configured (set tools.calendar.provider to \"caldav\" or \"google\")")
+	}
+	return cfg, nil
+}
+
+func runCalendarStatus(cmd *cobra.Command, args []string) error {
+	cfg, err := loadCalendarConfig()
+	if err != nil {
+		return err
+	}
+	c := cfg.Tools.Calendar
+	switch c.Provider {
+	case config.CalendarCalDAV:
+		fmt.Printf("Calendar: CalDAV %s as %s\n", c.URL, c.Username)
+	case config.CalendarGoogle:
+		id := c.CalendarID
+		if id == "" {
+			id = "primary"
+		}
+		fmt.Printf("Calendar: Google Calendar %s\n", id)
+	}
+	if !calendar.LoggedIn(c.Provider, cfg.CalendarCredentialsDir()) {
+		fmt.Println("Not logged in. Run 'ubot calendar login'.")
+		return nil
+	}
+
+	cal, err := calendar.New(c, cfg.CalendarCredentialsDir())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	now := time.Now()
+	events, err := cal.Events(ctx, now, now.Add(24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		fmt.Println("No events in the next 24 hours.")
+		return nil
+	}
+	fmt.Println("Next 24 hours:")
+	for _, ev := range events {
+		when := ev.Start.Format("Mon 15:04") + "-" + ev.End.Format("15:04")
+		if ev.AllDay {
+			when = ev.Start.Format("Mon") + " all day"
+		}
+		fmt.Printf("  %s  %s\n", when, ev.Title)
+	}
+	return nil
+}
+
+func runCalendarLogin(cmd *cobra.Command, args []string) error {
+	cfg, err := loadCalendarConfig()
+	if err != nil {
+		return err
+	}
+	c := cfg.Tools.Calendar
+	dir := cfg.CalendarCredentialsDir()
+
+	switch c.Provider {
+	case config.CalendarCalDAV:
+		var password string
+		err := huh.NewInput().
+			Title(fmt.Sprintf("Password for %s at %s", c.Username, c.URL)).
+			Description("Use an app password if your provider offers them.").
+			EchoMode(huh.EchoModePassword).
+			Value(&password).
+			Run()
+		if err != nil {
+			return err
+		}
+		if password == "" {
+			return errors.New("no password entered")
+		}
+		if err := calendar.SaveCalDAVPassword(dir, password); err != nil {
+			return fmt.Errorf("failed to save password: %w", err)
+		}
+
+	case config.CalendarGoogle:
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+		defer cancel()
+		addr := fmt.Sprintf("127.0.0.1:%d", calendarPortFlag)
+		err := calendar.GoogleLogin(ctx, c.ClientID, c.ClientSecret, dir, addr, func(authURL string) {
+			fmt.Println("Open this page in a browser on this machine and allow access to your calendar:")
+			fmt.Println()
+			fmt.Println("  " + authURL)
+			fmt.Println()
+			fmt.Println("Waiting for Google to redirect back...")
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Credentials saved in %s.\n", dir)
+	return nil
+}
+
+func runCalendarLogout(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := calendar.Logout(cfg.CalendarCredentialsDir()); err != nil {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+	fmt.Println("Calendar credentials deleted.")
+	return nil
+}
@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/providers"
+	"github.com/hkuds/ubot/internal/session"
+	"github.com/hkuds/ubot/internal/tools"
+	"github.com/hkuds/ubot/internal/tui"
+)
+
+// plainFlag makes agent and rootchat use a plain prompt instead of the
+// terminal UI.
+var plainFlag bool
+
+// interactiveChat is a CLI conversation, shared by the terminal UI and the
+// plain prompt of 'ubot agent' and 'ubot rootchat'.
+type interactiveChat struct {
+	title         string
+	provider      providers.Provider
+	sessionMgr    *session.Manager
+	registry      *tools.SecureRegistry
+	cfg           *config.Config
+	skillsSummary string
+	sess          *session.Session
+	model         string // overrides the configured model for this chat; "" = none
+
+	// request builds the request for a turn from the session. The
+	// selection is nil when every tool is offered.
+	request func(sess *session.Session, message string) (providers.ChatRequest, *tools.ToolSelection)
+
+	// debugContext enables /debug-context, which saves agent requests.
+	debugContext bool
+}
+
+// send runs a turn for message in the current session and returns the
+// reply. Reply text is passed to onDelta as it streams in, and each tool
+// name to onTool before the tool runs; either may be nil.
+func (c *interactiveChat) send(ctx context.Context, message string, onDelta, onTool func(string)) (string, error) {
+	c.sess.AddMessage("user", message)
+
+	req, selection := c.request(c.sess, message)
+	if c.model != "" {
+		req.Model = c.model
+	}
+	ctx = tools.WithRequest(ctx, tools.RequestInfo{
+		Channel:    "cli",
+		ChatID:     strings.TrimPrefix(c.sess.Key, "cli:"),
+		SessionKey: c.sess.Key,
+	})
+	if selection != nil {
+		ctx = tools.WithToolSelection(ctx, selection)
+	}
+	ctx = tools.WithSkillTrust(ctx, tools.NewSkillTrust())
+
+	return runTurn(ctx, c.provider, c.sess, c.sessionMgr, c.registry, req, selection, onDelta, onTool)
+}
+
+// command handles input that is a chat command rather than a message,
+// reporting whether it was one.
+func (c *interactiveChat) command(input string) (tui.CommandResult, bool) {
+	input = strings.TrimSpace(input)
+	switch strings.ToLower(input) {
+	case "exit", "quit":
+		return tui.CommandResult{Quit: true}, true
+	}
+
+	name, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+	switch strings.ToLower(name) {
+	case "/clear":
+		c.sess.Clear()
+		dropArtifacts(c.registry, c.sess.Key)
+		reply := "Conversation history cleared."
+		if err := c.sessionMgr.Save(c.sess); err != nil {
+			reply += fmt.Sprintf("\n(warning: failed to save session: %v)", err)
+		}
+		return tui.CommandResult{Clear: true, Reply: reply}, true
+	case "/help":
+		return tui.CommandResult{Reply: chatHelp(c.debugContext)}, true
+	case "/model":
+		return c.modelCommand(arg), true
+	case "/session":
+		return c.sessionCommand(arg), true
+	case "/debug-context":
+		if !c.debugContext {
+			break
+		}
+		reply, err := debugContextCommand(c.sess, c.registry, c.cfg, c.skillsSummary, arg)
+		if err != nil {
+			reply = fmt.Sprintf("Error: %v", err)
+		}
+		return tui.CommandResult{Reply: reply}, true
+	}
+
+	if reply, ok := handleChatCommand(c.sess, c.sessionMgr, "", input); ok {
+		res := tui.CommandResult{Reply: reply}
+		// Undoing and changing branches change the conversation shown
+		switch strings.ToLower(name) {
+		case "/undo", "/fork", "/branch":
+			c.sess = c.sessionMgr.Active(c.sess.Key)
+			res.Session = c.sess.Key
+			res.History = c.history()
+		}
+		return res, true
+	}
+	return tui.CommandResult{}, false
+}
+
+// currentModel returns the model the chat's turns use.
+func (c *interactiveChat) currentModel() string {
+	if c.model != "" {
+		return c.model
+	}
+	return c.cfg.Agents.Defaults.Model
+}
+
+// modelCommand shows the chat's model, or switches it for the rest of the
+// chat; "default" goes back to the configured one.
+func (c *interactiveChat) modelCommand(arg string) tui.CommandResult {
+	if arg == "" {
+		reply := fmt.Sprintf("Model: %s\n", c.currentModel())
+		if choices := offeredModels(context.Background(), c.provider, c.cfg.Agents.Defaults).ModelChoices(); len(choices) > 1 {
+			reply += "Available: " + strings.Join(choices, ", ") + "\n"
+		}
+		return tui.CommandResult{Reply: reply + "Use /model <name> to switch for this chat, or /model default to go back to the configured model."}
+	}
+	c.model = arg
+	if strings.EqualFold(arg, "default") {
+		c.model = ""
+	}
+	return tui.CommandResult{Model: c.currentModel(), Reply: fmt.Sprintf("Using model %s for this chat.", c.currentModel())}
+}
+
+// sessionCommand lists the CLI sessions, or switches to the one called
+// arg, starting it if it does not exist.
+func (c *interactiveChat) sessionCommand(arg string) tui.CommandResult {
+	if arg == "" {
+		return tui.CommandResult{Reply: c.listSessions()}
+	}
+	if strings.ContainsAny(arg, " \t/\\") {
+		return tui.CommandResult{Reply: "Session names cannot contain spaces or slashes."}
+	}
+
+	key := "cli:" + strings.TrimPrefix(arg, "cli:")
+	c.sess = c.sessionMgr.Active(key)
+	history := c.history()
+	reply := fmt.Sprintf("Switched to session %s.", key)
+	if len(history) == 0 {
+		reply = fmt.Sprintf("Started session %s.", key)
+	}
+	return tui.CommandResult{Session: key, History: history, Reply: reply}
+}
+
+// listSessions describes the CLI sessions, most recently used first.
+func (c *interactiveChat) listSessions() string {
+	infos, err := c.sessionMgr.ListFiltered(session.Filter{Channel: "cli"})
+	if err != nil {
+		return fmt.Sprintf("Error: failed to list sessions: %v", err)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].UpdatedAt.After(infos[j].UpdatedAt) })
+
+	var sb strings.Builder
+	sb.WriteString("Sessions:\n")
+	listed := false
+	for _, info := range infos {
+		// Branches are listed by /branches
+		if session.RootKey(info.Key) != info.Key {
+			continue
+		}
+		marker := "  "
+		if info.Key == session.RootKey(c.sess.Key) {
+			marker, listed = "* ", true
+		}
+		fmt.Fprintf(&sb, "%s%s (%d messages)\n", marker, strings.TrimPrefix(info.Key, "cli:"), info.MessageCount)
+	}
+	if !listed {
+		fmt.Fprintf(&sb, "* %s (new)\n", strings.TrimPrefix(session.RootKey(c.sess.Key), "cli:"))
+	}
+	sb.WriteString("Use /session <name> to switch.")
+	return sb.String()
+}
+
+// history returns the messages of the current session as transcript
+// entries.
+func (c *interactiveChat) history() []tui.ChatEntry {
+	entries := make([]tui.ChatEntry, 0)
+	for _, msg := range c.sess.GetMessages() {
+		if msg.Role == "user" || msg.Role == "assistant" {
+			entries = append(entries, tui.ChatEntry{Role: msg.Role, Content: msg.Content})
+		}
+	}
+	return entries
+}
+
+// runChat runs an interactive chat in the terminal UI, or at a plain
+// prompt when --plain is set or the terminal is not interactive.
+func runChat(ctx context.Context, chat *interactiveChat, intro string) error {
+	if plainFlag || !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return runPlainChat(ctx, chat, intro)
+	}
+	return runChatUI(ctx, chat)
+}
+
+// printProgress shows the progress of a long tool call, such as a
+// download, in the plain CLI.
+func printProgress(status string) {
+	fmt.Fprintln(os.Stderr, status)
+}
+
+// runChatUI runs the chat in the full-screen terminal UI.
+func runChatUI(ctx context.Context, chat *interactiveChat) error {
+	// Log lines would draw over the UI, so they go to a file meanwhile
+	logPath := filepath.Join(config.GetConfigDir(), "chat.log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o700); err == nil {
+		if f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600); err == nil {
+			log.SetOutput(f)
+			defer func() {
+				log.SetOutput(os.Stderr)
+				f.Close()
+			}()
+		}
+	}
+
+	var ui *tui.Chat
+	ui = tui.NewChat(tui.ChatConfig{
+		Title:   chat.title,
+		Model:   chat.currentModel(),
+		Session: chat.sess.Key,
+		History: chat.history(),
+		Send: func(ctx context.Context, message string, onDelta, onTool func(string)) (string, error) {
+			return chat.send(tools.WithApprover(ctx, tuiApprover{ui}), message, onDelta, onTool)
+		},
+		Command: chat.command,
+	})
+	return ui.Run(ctx)
+}
+
+// runPlainChat runs the chat as a line-by-line prompt.
+func runPlainChat(ctx context.Context, chat *interactiveChat, intro string) error {
+	fmt.Println(intro)
+	fmt.Println()
+	ctx = tools.WithProgress(ctx, printProgress)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		fmt.Print("You: ")
+		if !stdin.Scan() {
+			break
+		}
+
+		input := strings.TrimSpace(stdin.Text())
+		if input == "" {
+			continue
+		}
+
+		if res, ok := chat.command(input); ok {
+			if res.Quit {
+				fmt.Println("Goodbye!")
+				return nil
+			}
+			fmt.Println(res.Reply)
+			continue
+		}
+
+		reply, err := chat.send(ctx, input, nil, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			printChatError(err)
+		} else {
+			fmt.Println(reply)
+		}
+		fmt.Println()
+	}
+
+	if err := stdin.Err(); err != nil {
+		return fmt.Errorf("input error: %w", err)
+	}
+
+	return nil
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// chatHelp describes the interactive chat commands and tools.
+func chatHelp(debugContext bool) string {
+	var sb strings.Builder
+	sb.WriteString("Commands:\n")
+	sb.WriteString("  /clear            - Clear conversation history (ctrl+l)\n")
+	sb.WriteString("  /model [name]     - Show or switch the model for this chat (ctrl+o)\n")
+	sb.WriteString("  /session [name]   - List sessions, or switch to one (ctrl+s)\n")
+	sb.WriteString("  /pin              - Pin a fact (or the last reply) to always keep in context\n")
+	sb.WriteString("  /pins             - List pinned context\n")
+	sb.WriteString("  /unpin N          - Remove pin N\n")
+	sb.WriteString("  /search           - Search earlier conversations for words\n")
+	sb.WriteString("  /undo             - Remove your last message and the reply\n")
+	sb.WriteString("  /fork [name]      - Continue in a new branch of this conversation\n")
+	sb.WriteString("  /branches         - List the branches of this conversation\n")
+	sb.WriteString("  /branch name      - Switch to another branch\n")
+	if debugContext {
+		sb.WriteString("  /debug-context [message] - Save what the next turn would send to the model\n")
+	}
+	sb.WriteString("  /help             - Show this help message\n")
+	sb.WriteString("  exit/quit         - Exit the chat (ctrl+c)\n")
+	sb.WriteString("\n")
+	sb.WriteString("Available Tools:\n")
+	sb.WriteString("  - read_file: Read file contents\n")
+	sb.WriteString("  - write_file: Write content to a file\n")
+	sb.WriteString("  - list_dir: List directory contents\n")
+	sb.WriteString("  - search_files: Search file contents for text or a regex\n")
+	sb.WriteString("  - apply_patch: Apply a unified diff to files\n")
+	sb.WriteString("  - exec: Execute shell commands\n")
+	sb.WriteString("  - web_search: Search the web (if configured)\n")
+	sb.WriteString("  - web_fetch: Fetch content from URLs\n")
+	sb.WriteString("  - list_skills: List available skills\n")
+	sb.WriteString("  - read_skill: Load a specific skill\n")
+	sb.WriteString("  - capabilities: Describe what this deployment supports")
+	return sb.String()
+}
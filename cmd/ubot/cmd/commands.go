@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hkuds/ubot/internal/session"
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+// handleChatCommand handles slash commands shared by the CLI and channel
+// conversations. adminKey is the session key of the admin chat, which may
+// search every conversation. It returns the reply text and true when input
+// was one of these commands; other input (including unknown commands) is
+// left to the caller.
+func handleChatCommand(sess *session.Session, sessionMgr *session.Manager, adminKey, input string) (string, bool) {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, "/") {
+		return "", false
+	}
+
+	name, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	var reply string
+	switch strings.ToLower(name) {
+	case "/pin":
+		reply = pinCommand(sess, arg)
+	case "/pins":
+		reply = session.PinnedContext(sess.GetPins())
+		if reply == "" {
+			reply = "Nothing is pinned. Use /pin <text> to pin a fact."
+		}
+		return reply, true
+	case "/search":
+		return searchCommand(sess, sessionMgr, adminKey, arg), true
+	case "/undo":
+		removed := sess.Undo()
+		if removed == nil {
+			return "Nothing to undo.", true
+		}
+		reply = fmt.Sprintf("Removed your last message (%q) and the reply to it.", shortText(removed[0].Content, 60))
+	case "/fork":
+		branch, err := sessionMgr.Fork(sess, arg)
+		if err != nil {
+			return fmt.Sprintf("Cannot fork: %v", err), true
+		}
+		return fmt.Sprintf("Forked branch %s from %s; the conversation continues there. /branch %s goes back.",
+			session.BranchName(branch.Key), session.BranchName(sess.Key), session.BranchName(sess.Key)), true
+	case "/branches":
+		return branchesCommand(sess, sessionMgr), true
+	case "/branch":
+		if arg == "" {
+			return branchesCommand(sess, sessionMgr), true
+		}
+		branch, err := sessionMgr.Switch(sess.Key, arg)
+		if err != nil {
+			return fmt.Sprintf("%v. /branches lists them.", err), true
+		}
+		return fmt.Sprintf("Switched to branch %s (%d messages).", session.BranchName(branch.Key), branch.MessageCount()), true
+	case "/unpin":
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return "Usage: /unpin <id>", true
+		}
+		if !sess.RemovePin(id) {
+			return fmt.Sprintf("Pin %d not found.", id), true
+		}
+		reply = fmt.Sprintf("Pin %d removed.", id)
+	default:
+		return "", false
+	}
+
+	if err := sessionMgr.Save(sess); err != nil {
+		reply += fmt.Sprintf("\n(warning: failed to save session: %v)", err)
+	}
+	return reply, true
+}
+
+// pinCommand pins arg, or the last assistant reply when arg is empty.
+func pinCommand(sess *session.Session, arg string) string {
+	content := arg
+	if content == "" {
+		last, ok := sess.LastMessage("assistant")
+		if !ok {
+			return "Usage: /pin <text> (or /pin alone to pin the last reply)"
+		}
+		content = last.Content
+	}
+	pin := sess.AddPin(content)
+	return fmt.Sprintf("Pinned (ID: %d). It will be included in every reply from now on.", pin.ID)
+}
+
+// searchCommand searches earlier messages for arg, across all conversations
+// when sess may see them.
+func searchCommand(sess *session.Session, sessionMgr *session.Manager, adminKey, arg string) string {
+	if arg == "" {
+		return "Usage: /search <words>"
+	}
+	hits, err := sessionMgr.Search(arg, tools.HistoryFilter(sess.Key, adminKey), 10)
+	if err != nil {
+		return fmt.Sprintf("Search failed: %v", err)
+	}
+	return tools.FormatSearchHits(hits, sess.Key)
+}
+
+// branchesCommand lists the branches of sess's conversation, marking the
+// current one.
+func branchesCommand(sess *session.Session, sessionMgr *session.Manager) string {
+	branches := sessionMgr.Branches(sess.Key)
+	if len(branches) == 1 {
+		return "This conversation has no other branches. Use /fork [name] to start one."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Branches:\n")
+	for _, b := range branches {
+		marker := "  "
+		if b.Active {
+			marker = "* "
+		}
+		fmt.Fprintf(&sb, "%s%s (%d messages", marker, b.Name, b.MessageCount)
+		if b.Parent != "" {
+			fmt.Fprintf(&sb, ", from %s", b.Parent)
+		}
+		sb.WriteString(")\n")
+	}
+	sb.WriteString("Use /branch <name> to switch.")
+	return sb.String()
+}
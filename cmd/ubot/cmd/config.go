@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the configuration file",
+	Long:  "Commands for the configuration file, ~/.ubot/config.json.",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check the configuration for mistakes",
+	Long: `Check config.json (or the given file) for unknown keys, values of the wrong
+type, values out of range and settings that contradict each other, printing
+the line and field of each problem. Exits with an error if any are found.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigValidate,
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	path := config.GetConfigPath()
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if !config.Exists(path) {
+		return fmt.Errorf("no config file at %s; run 'ubot setup' to create one", path)
+	}
+
+	if _, err := config.LoadConfig(path); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	fmt.Printf("%s is valid\n", path)
+	return nil
+}
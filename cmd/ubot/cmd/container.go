@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/control"
+	"github.com/hkuds/ubot/internal/features"
+	"github.com/hkuds/ubot/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+// defaultDockerSocket is where the host's Docker socket is usually mounted.
+const defaultDockerSocket = "/var/run/docker.sock"
+
+var dockerSocketFlag string
+
+var healthcheckCmd = &cobra.Command{
+	Use:   "healthcheck",
+	Short: "Check that the running gateway is healthy",
+	Long:  "Ask the running gateway for its health (GET /readyz, needs gateway.health or gateway.controlApi) and exit with status 1 unless it is ready. Meant for container health checks.",
+	Args:  cobra.NoArgs,
+	RunE:  runHealthcheck,
+}
+
+// bootstrapConfig writes the default config file when there is none, so a
+// fresh volume gets a file to edit. Settings given as UBOT_* variables are
+// not written into it; they keep overriding it.
+func bootstrapConfig() error {
+	path := config.GetConfigPath()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err := config.SaveConfig(config.DefaultConfig(), path); err != nil {
+		return err
+	}
+	fmt.Printf("Created %s with the defaults; UBOT_* environment variables override it.\n", path)
+	return nil
+}
+
+// prepareContainer readies a gateway running in a container for the
+// sandbox on the host's Docker daemon, reached through socket. It fails
+// when the daemon cannot be reached.
+func prepareContainer(cfg *config.Config, socket string) error {
+	if !features.Docker {
+		return fmt.Errorf("--docker-socket needs Docker support, which this build leaves out")
+	}
+	if err := os.MkdirAll(cfg.WorkspacePath(), 0o755); err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	if os.Getenv("DOCKER_HOST") == "" {
+		if _, err := os.Stat(socket); err != nil {
+			return fmt.Errorf("no Docker socket at %s; mount the host's with -v /var/run/docker.sock:%s", socket, socket)
+		}
+		os.Setenv("DOCKER_HOST", "unix://"+socket)
+	}
+	if !sandbox.IsDockerAvailable() {
+		return fmt.Errorf("cannot reach Docker through %s; give the ubot user access to the socket (e.g. --group-add with the socket's group ID)", socket)
+	}
+
+	// Sandboxes mount workspace directories by their path on the host
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	paths, err := sandbox.DetectHostPaths(ctx)
+	if err == nil {
+		_, err = paths.Translate(cfg.WorkspacePath())
+	}
+	if err != nil {
+		log.Printf("Warning: skill setup scripts cannot run in the sandbox: %v", err)
+		return nil
+	}
+	fmt.Printf("Sandbox: Docker on the host via %s\n", socket)
+	return nil
+}
+
+func runHealthcheck(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.Gateway.ControlAPI && !cfg.Gateway.Health {
+		return fmt.Errorf("the gateway serves no health checks (set gateway.health to true)")
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Second)
+	defer cancel()
+	health, err := control.NewClient(localGatewayAddr(cfg), cfg.Gateway.Token).Health(ctx)
+	if health.Status != "" {
+		fmt.Println(health.Status)
+		names := make([]string, 0, len(health.Checks))
+		for name := range health.Checks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			check := health.Checks[name]
+			if check.Detail != "" {
+				fmt.Printf("  %-20s %-4s  %s\n", name, check.Status, check.Detail)
+			} else {
+				fmt.Printf("  %-20s %s\n", name, check.Status)
+			}
+		}
+	}
+	return err
+}
+
+// localGatewayAddr returns where to reach the gateway's control API from
+// this host: a gateway listening on all interfaces is asked on the
+// loopback one.
+func localGatewayAddr(cfg *config.Config) string {
+	if ip := net.ParseIP(cfg.Gateway.Host); cfg.Gateway.Host == "" || (ip != nil && ip.IsUnspecified()) {
+		return net.JoinHostPort("127.0.0.1", fmt.Sprint(cfg.Gateway.Port))
+	}
+	return cfg.Gateway.Addr()
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/providers"
+)
+
+// modelListTimeout bounds asking the provider for its models while a
+// message waits.
+const modelListTimeout = 5 * time.Second
+
+// copilotSignIn returns the sign-in the gateway runs when GitHub stops
+// accepting the Copilot token. The code to enter goes to the admin chat
+// and the log, and the new token is saved to the config file at path.
+func copilotSignIn(path string, publish func(bus.OutboundMessage)) providers.CopilotSignIn {
+	return func(ctx context.Context) (string, error) {
+		token, err := providers.RunDeviceFlow(ctx, func(code *providers.DeviceCodeResponse) {
+			text := fmt.Sprintf("GitHub Copilot needs you to sign in again: open %s and enter the code %s within %d minutes.",
+				code.VerificationURI, code.UserCode, code.ExpiresIn/60)
+			log.Print(text)
+			cfg, err := config.LoadConfig(path)
+			if err != nil || cfg.Channels.Admin.SessionKey() == "" {
+				log.Printf("Warning: no admin chat to send the Copilot sign-in code to (set channels.admin)")
+				return
+			}
+			publish(bus.OutboundMessage{Channel: cfg.Channels.Admin.Channel, ChatID: cfg.Channels.Admin.ChatID, Content: text})
+		})
+		if err != nil {
+			return "", err
+		}
+		if err := config.SaveCopilotToken(path, token); err != nil {
+			log.Printf("Warning: signed in to GitHub Copilot, but failed to save the token: %v", err)
+		}
+		return token, nil
+	}
+}
+
+// offeredModels returns defaults also offering the models the provider
+// lists, such as those of the Copilot subscription.
+func offeredModels(ctx context.Context, provider providers.Provider, defaults config.AgentDefaults) config.AgentDefaults {
+	ctx, cancel := context.WithTimeout(ctx, modelListTimeout)
+	defer cancel()
+	models, err := providers.ListModels(ctx, provider)
+	if err != nil {
+		log.Printf("Warning: failed to list the provider's models: %v", err)
+	}
+	return defaults.WithModels(models)
+}
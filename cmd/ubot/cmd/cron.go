@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hkuds/ubot/internal/cron"
+	"github.com/spf13/cobra"
+)
+
+var cronCmd = &cobra.Command{
+	Use:   "cron",
+	Short: "Inspect scheduled jobs",
+	Long:  "List proactive cron jobs and show their run history.",
+}
+
+var cronListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled jobs",
+	Long:  "Show all scheduled jobs with their last run status, and the messages scheduled with send_later.",
+	RunE:  runCronList,
+}
+
+var cronHistoryCmd = &cobra.Command{
+	Use:   "history <id>",
+	Short: "Show run history of a job",
+	Long:  "Display recent runs of a scheduled job including duration, errors and output.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCronHistory,
+}
+
+func init() {
+	cronCmd.AddCommand(cronListCmd)
+	cronCmd.AddCommand(cronHistoryCmd)
+}
+
+// loadScheduler returns a scheduler with persisted jobs loaded but not started.
+func loadScheduler() (*cron.Scheduler, error) {
+	s := cron.NewScheduler(nil, nil, "")
+	if err := s.Load(); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load cron jobs: %w", err)
+	}
+	return s, nil
+}
+
+func runCronList(cmd *cobra.Command, args []string) error {
+	s, err := loadScheduler()
+	if err != nil {
+		return err
+	}
+
+	jobs := s.ListJobs()
+	messages := s.ListMessages()
+	if len(jobs) == 0 && len(messages) == 0 {
+		fmt.Println("No scheduled jobs.")
+		return nil
+	}
+
+	for _, m := range messages {
+		fmt.Printf("%s  %-16s %s:%s\n", m.ID, m.At.Format("2006-01-02 15:04"), m.Channel, m.ChatID)
+		fmt.Printf("    Message:     %s\n", m.Content)
+	}
+
+	for _, j := range jobs {
+		fmt.Printf("%s  %-16s %s:%s\n", j.ID, j.Schedule, j.Channel, j.ChatID)
+		fmt.Printf("    Instruction: %s\n", j.Instruction)
+
+		h := s.History(j.ID)
+		last := h.LastRun()
+		if last == nil {
+			fmt.Printf("    Last run:    never\n")
+			continue
+		}
+		status := "ok"
+		if !last.Success {
+			status = "FAILED: " + last.Error
+		}
+		fmt.Printf("    Last run:    %s (%s) %s\n",
+			last.StartedAt.Format(time.DateTime), last.Duration.Round(time.Millisecond), status)
+		if h.ConsecutiveFailures > 0 {
+			fmt.Printf("    Failures:    %d in a row\n", h.ConsecutiveFailures)
+		}
+	}
+	return nil
+}
+
+func runCronHistory(cmd *cobra.Command, args []string) error {
+	s, err := loadScheduler()
+	if err != nil {
+		return err
+	}
+
+	h := s.History(args[0])
+	if h == nil || len(h.Runs) == 0 {
+		fmt.Printf("No runs recorded for job %q.\n", args[0])
+		return nil
+	}
+
+	for i := len(h.Runs) - 1; i >= 0; i-- {
+		r := h.Runs[i]
+		status := "ok"
+		if !r.Success {
+			status = "FAILED"
+		}
+		fmt.Printf("%s  %-6s %s\n", r.StartedAt.Format(time.DateTime), status, r.Duration.Round(time.Millisecond))
+		if r.Error != "" {
+			fmt.Printf("    Error:  %s\n", r.Error)
+		}
+		if r.Output != "" {
+			fmt.Printf("    Output: %s\n", r.Output)
+		}
+	}
+	return nil
+}
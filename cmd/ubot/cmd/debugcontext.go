@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/providers"
+	"github.com/hkuds/ubot/internal/session"
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+// contextPack is what /debug-context saves: the request the next turn would
+// send, with estimated token counts.
+type contextPack struct {
+	CreatedAt   time.Time               `json:"createdAt"`
+	Session     string                  `json:"session"`
+	Model       string                  `json:"model"`
+	MaxTokens   int                     `json:"maxTokens"`
+	NextMessage string                  `json:"nextMessage,omitempty"`
+	Tokens      contextTokens           `json:"tokens"`
+	NotOffered  []string                `json:"notOffered,omitempty"` // tools left for request_tool
+	Messages    []providers.ChatMessage `json:"messages"`
+	Tools       []tools.ToolDefinition  `json:"tools"`
+}
+
+// contextTokens estimates the size of each part of the request.
+type contextTokens struct {
+	System  int `json:"system"`
+	History int `json:"history"`
+	Tools   int `json:"tools"`
+	Total   int `json:"total"`
+}
+
+// debugContextCommand saves the messages and tool definitions the next CLI
+// turn would send, as if the user typed next, and summarises their size.
+func debugContextCommand(sess *session.Session, registry *tools.SecureRegistry, cfg *config.Config, skillsSummary, next string) (string, error) {
+	req, selection := cliRequest(sess, registry, cfg, next, skillsSummary)
+	if next != "" {
+		req.Messages = append(req.Messages, providers.ChatMessage{Role: "user", Content: next})
+	}
+
+	pack := contextPack{
+		CreatedAt:   time.Now(),
+		Session:     sess.Key,
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		NextMessage: next,
+		NotOffered:  selection.Unselected(),
+		Messages:    req.Messages,
+		Tools:       selection.Definitions(),
+	}
+	pack.Tokens.System = estimateJSONTokens(req.Messages[0])
+	pack.Tokens.History = estimateJSONTokens(req.Messages[1:])
+	pack.Tokens.Tools = estimateJSONTokens(pack.Tools)
+	pack.Tokens.Total = pack.Tokens.System + pack.Tokens.History + pack.Tokens.Tools
+
+	data, err := json.MarshalIndent(pack, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(config.GetConfigDir(), "debug")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create debug directory: %w", err)
+	}
+	path := filepath.Join(dir, "context-"+pack.CreatedAt.Format("20060102-150405")+".json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to save context: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Next turn context (estimated tokens, about 4 characters each):\n")
+	fmt.Fprintf(&sb, "  System prompt: %6d", pack.Tokens.System)
+	var parts []string
+	if len(sess.GetPins()) > 0 {
+		parts = append(parts, fmt.Sprintf("%d pins", len(sess.GetPins())))
+	}
+	if skillsSummary != "" {
+		parts = append(parts, "skills summary")
+	}
+	if len(parts) > 0 {
+		fmt.Fprintf(&sb, " (includes %s)", strings.Join(parts, ", "))
+	}
+	fmt.Fprintf(&sb, "\n  History:       %6d in %d messages\n", pack.Tokens.History, len(req.Messages)-1)
+	fmt.Fprintf(&sb, "  Tools:         %6d for %d tools", pack.Tokens.Tools, len(pack.Tools))
+	if len(pack.NotOffered) > 0 {
+		fmt.Fprintf(&sb, " (not offered: %s)", strings.Join(pack.NotOffered, ", "))
+	}
+	fmt.Fprintf(&sb, "\n  Total:         %6d\n", pack.Tokens.Total)
+	if next == "" {
+		sb.WriteString("Tools were chosen without a message; use /debug-context <message> to see the selection for one.\n")
+	}
+	fmt.Fprintf(&sb, "Saved the full request to %s", path)
+	return sb.String(), nil
+}
+
+// estimateJSONTokens estimates the tokens in v as sent to the provider,
+// using the same four characters per token as session trimming.
+func estimateJSONTokens(v interface{}) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(data) / 4
+}
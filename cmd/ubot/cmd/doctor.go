@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hkuds/ubot/internal/doctor"
+	"github.com/hkuds/ubot/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment for problems",
+	Long: `Check the configuration, provider reachability and the programs optional
+features need (Docker, Chrome, git, Node.js, MCP servers), printing pass,
+warn or fail for each with a hint for fixing it. Exits with an error if any
+check fails.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+var doctorJSON bool
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "print results as JSON")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	if !doctorJSON {
+		fmt.Println("Running checks...")
+	}
+	results := doctor.Run(cmd.Context(), "")
+
+	if doctorJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		tui.ShowDoctor(results)
+	}
+
+	if doctor.Failed(results) {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("some checks failed")
+	}
+	return nil
+}
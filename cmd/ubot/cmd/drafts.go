@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+// draftInterval is how often the reply so far is published while the model
+// generates it. Channels edit their drafts no more often than they may.
+const draftInterval = time.Second
+
+// replyDraft publishes the reply to a message while it is generated, as
+// partial messages that channels able to edit messages show as a draft;
+// the bus drops them for other channels. The final reply names the same
+// stream, so it replaces the draft.
+type replyDraft struct {
+	bus    *bus.MessageBus
+	msg    bus.InboundMessage
+	stream string
+
+	mu        sync.Mutex
+	text      strings.Builder
+	published bool
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// startReplyDraft starts publishing the reply to msg as it grows.
+func startReplyDraft(msgBus *bus.MessageBus, msg bus.InboundMessage) *replyDraft {
+	d := &replyDraft{
+		bus:    msgBus,
+		msg:    msg,
+		stream: fmt.Sprintf("%s/%d", msg.SessionKey(), time.Now().UnixNano()),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// add appends text the model generated; it is the provider's onDelta.
+func (d *replyDraft) add(text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.text.WriteString(text)
+}
+
+// reset starts the text over for the next request of a turn, after the
+// model called tools. The draft keeps showing the old text until new text
+// arrives.
+func (d *replyDraft) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.text.Reset()
+}
+
+func (d *replyDraft) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(draftInterval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+		}
+		d.mu.Lock()
+		text := d.text.String()
+		d.mu.Unlock()
+		if strings.TrimSpace(text) == "" || text == last {
+			continue
+		}
+		last = text
+		d.published = true
+		d.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: d.msg.Channel,
+			ChatID:  d.msg.ChatID,
+			Content: text,
+			ReplyTo: d.msg.MessageID(),
+			Trace:   d.msg.Trace,
+			Stream:  d.stream,
+			Partial: true,
+		})
+	}
+}
+
+// close stops publishing drafts.
+func (d *replyDraft) close() {
+	d.stopOnce.Do(func() { close(d.stop) })
+	<-d.done
+}
+
+// finish stops publishing drafts and returns out, the final reply, marked
+// to replace the draft if one was published.
+func (d *replyDraft) finish(out bus.OutboundMessage) bus.OutboundMessage {
+	d.close()
+	if d.published {
+		out.Stream = d.stream
+	}
+	return out
+}
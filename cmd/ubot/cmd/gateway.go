@@ -2,40 +2,81 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/hkuds/ubot/internal/bus"
 	"github.com/hkuds/ubot/internal/channels"
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/control"
 	"github.com/hkuds/ubot/internal/cron"
-	"github.com/hkuds/ubot/internal/mcp"
+	"github.com/hkuds/ubot/internal/failure"
+	"github.com/hkuds/ubot/internal/features"
+	"github.com/hkuds/ubot/internal/prompts"
 	"github.com/hkuds/ubot/internal/providers"
+	"github.com/hkuds/ubot/internal/redis"
+	"github.com/hkuds/ubot/internal/sandbox"
 	"github.com/hkuds/ubot/internal/session"
 	"github.com/hkuds/ubot/internal/skills"
+	"github.com/hkuds/ubot/internal/stats"
 	"github.com/hkuds/ubot/internal/tools"
-	"github.com/hkuds/ubot/internal/voice"
+	"github.com/hkuds/ubot/internal/tracing"
+	"github.com/hkuds/ubot/internal/watch"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var gatewayCmd = &cobra.Command{
 	Use:   "gateway",
 	Short: "Start the channel gateway",
-	Long:  "Start the gateway server that connects to configured channels (Telegram, WhatsApp) and processes messages.",
-	RunE:  runGateway,
+	Long:  "Start the gateway server that connects to configured channels (Telegram, WhatsApp) and processes messages. With --supervised, the gateway runs as a child process that is started again when it crashes or is asked to restart (manage_ubot restart, POST /restart).",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if supervisedFlag && !isSupervised() {
+			return runSupervisor()
+		}
+		err := runGateway(cmd, args)
+		if errors.Is(err, errRestart) {
+			os.Exit(restartExitCode)
+		}
+		return err
+	},
+}
+
+var supervisedFlag bool
+
+func init() {
+	gatewayCmd.Flags().BoolVar(&supervisedFlag, "supervised", false, "Run the gateway under a supervisor that restarts it on request or crash")
+	gatewayCmd.Flags().StringVar(&dockerSocketFlag, "docker-socket", "", "Run in a container using the host's Docker socket at this path for the sandbox")
+	gatewayCmd.Flags().Lookup("docker-socket").NoOptDefVal = defaultDockerSocket
 }
 
 func runGateway(cmd *cobra.Command, args []string) error {
+	// A container starts with an empty volume
+	if dockerSocketFlag != "" || sandbox.InContainer() {
+		if err := bootstrapConfig(); err != nil {
+			return err
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig("")
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	if dockerSocketFlag != "" {
+		if err := prepareContainer(cfg, dockerSocketFlag); err != nil {
+			return err
+		}
+	}
 
 	// Check if provider is configured
 	providerName, _, _ := cfg.GetActiveProvider()
@@ -45,8 +86,12 @@ func runGateway(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	role := cfg.Cluster.Role
+	runChannels := role != config.ClusterRoleWorker
+	runProcessing := role != config.ClusterRolePoller
+
 	// Check if any channel is enabled
-	if !cfg.Channels.Telegram.Enabled && !cfg.Channels.WhatsApp.Enabled {
+	if runChannels && !cfg.Channels.Telegram.Enabled && !cfg.Channels.WhatsApp.Enabled {
 		fmt.Println("No channels configured.")
 		fmt.Println("Run 'ubot setup' to configure Telegram or WhatsApp.")
 		return nil
@@ -56,15 +101,53 @@ func runGateway(cmd *cobra.Command, args []string) error {
 	msgBus := bus.NewMessageBus(100)
 	defer msgBus.Close()
 
-	// Create provider
-	provider, err := providers.NewProviderFromConfig(cfg)
+	// Keep inbound messages on disk until answered if configured
+	if cfg.Gateway.Queue.Persistent() {
+		closeJournal, err := openInboundJournal(cfg, msgBus)
+		if err != nil {
+			return err
+		}
+		defer closeJournal()
+	}
+
+	// Export traces of each message if configured
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, Version)
+		if err != nil {
+			log.Printf("Warning: tracing is off: %v", err)
+		} else {
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := shutdownTracing(ctx); err != nil {
+					log.Printf("Warning: failed to flush traces: %v", err)
+				}
+			}()
+		}
+	}
+
+	// Collect local usage statistics if the operator opted in
+	var recorder *stats.Recorder
+	if cfg.Stats.Enabled && runProcessing {
+		recorder = stats.NewRecorder(cfg.StatsPath())
+	}
+
+	// Create provider; a config reload can switch it. When GitHub stops
+	// accepting the Copilot token, the admin chat is asked to sign in again
+	signIn := copilotSignIn("", msgBus.PublishOutbound)
+	baseProvider, err := newGatewayProvider(cfg, recorder, signIn)
 	if err != nil {
-		return fmt.Errorf("failed to create provider: %w", err)
+		return err
 	}
+	provider := providers.NewSwitchable(baseProvider)
 
 	// Create session manager using the workspace directory
 	dataDir := cfg.WorkspacePath()
-	sessionMgr := session.NewManager(dataDir)
+	sessionMgr, closeSessions, err := newSessionManager(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeSessions()
 
 	// Create skills loader and discover available skills
 	skillsLoader := skills.NewLoader(dataDir)
@@ -73,93 +156,320 @@ func runGateway(cmd *cobra.Command, args []string) error {
 	if err := skillsLoader.Discover(); err != nil {
 		log.Printf("Warning: failed to discover skills: %v", err)
 	}
-	skillsSummary := skillsLoader.GetSummary()
 
 	// Create tool registry with default tools
 	registry := tools.NewRegistry()
-	registerDefaultTools(registry, cfg)
+	env := registerDefaultTools(registry, cfg)
 
 	// Register skill tools
 	registerSkillTools(registry, skillsLoader)
 
-	// Register manage_ubot tool
+	// Register manage_ubot tool; restarts need a supervisor
+	restarter := newGatewayRestarter(isSupervised())
 	manageUbotTool := tools.NewManageUbotTool("")
+	manageUbotTool.SetRestarter(restarter.Restart)
 	registry.Register(manageUbotTool)
 
 	// Register browser tool
-	browserTool := tools.NewBrowserTool(cfg.Tools.Browser)
-	registry.Register(browserTool)
+	registerBrowserTool(registry, cfg, provider, msgBus.PublishOutbound)
+
+	// Register pin tool
+	registry.Register(tools.NewPinTool(sessionMgr))
+
+	// Register search_history tool
+	registry.Register(tools.NewSearchHistoryTool(sessionMgr, cfg.Channels.Admin.SessionKey()))
+
+	// Register request_tool, used when tool definitions are trimmed per turn
+	registry.Register(tools.NewRequestToolTool())
+
+	// Register send_file, which attaches files to replies
+	registry.Register(tools.NewSendFileTool(msgBus.PublishOutbound))
 
 	// Create and start proactive cron scheduler
 	scheduler := cron.NewScheduler(msgBus, provider, cfg.Agents.Defaults.Model)
 	cronTool := tools.NewCronTool(scheduler)
 	registry.Register(cronTool)
+	registry.Register(tools.NewSendLaterTool(scheduler))
+
+	// Let conversations hear about changes to files they watch
+	watcher := watch.NewWatcher(cfg.WatchesPath(), msgBus.PublishInbound)
+	registry.Register(tools.NewWatchPathTool(watcher))
+
+	// Wrap registry with security middleware. The config, provider and
+	// tool settings follow the config file while the gateway runs.
+	live := &liveGateway{
+		cfg:      cfg,
+		registry: newGatewayRegistry(registry, cfg, recorder),
+		provider: provider,
+		signIn:   signIn,
+		recorder: recorder,
+		tools:    registry,
+		env:      env,
+	}
+	approvals := newChatApprovals(cfg, msgBus)
+
+	// Jobs may read feeds and the web, e.g. for a daily digest
+	scheduler.SetToolbox(jobToolbox{live: live})
+
+	// Suggest skills for kinds of task the agent keeps failing at
+	skillsMgr := newSkillsManager(cfg)
+	var advisor *skills.Advisor
+	if threshold := cfg.Skills.SuggestThreshold(); runProcessing && threshold > 0 {
+		advisor = skills.NewAdvisor(skillsMgr, threshold, func(s skills.Suggestion) {
+			msgBus.PublishOutbound(bus.OutboundMessage{
+				Channel: s.Channel,
+				ChatID:  s.ChatID,
+				Content: skills.FormatSuggestion(s),
+				Buttons: []bus.Button{
+					{Text: "Install", Data: "/install-skill " + s.Skill.Name},
+					{Text: "Not now", Data: "/dismiss-skill " + s.Skill.Name},
+				},
+			})
+		})
+	}
 
-	// Wrap registry with security middleware
-	secureReg := tools.NewSecureRegistry(registry)
+	// Messages that arrive while the provider is unreachable wait here
+	offline := bus.NewOfflineQueue(cfg.OfflineQueuePath())
 
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Start proactive cron scheduler
-	if err := scheduler.Start(ctx); err != nil {
-		log.Printf("Warning: failed to start cron scheduler: %v", err)
+	// On shutdown, inbound messages stop being taken first while those in
+	// progress finish
+	acceptCtx, stopAccepting := context.WithCancel(ctx)
+	defer stopAccepting()
+	work := newInflight(cfg.Gateway.Queue.Concurrency())
+	defer work.abort()
+
+	// Start proactive cron scheduler (pollers leave scheduling to workers)
+	if runProcessing {
+		if err := scheduler.Start(ctx); err != nil {
+			log.Printf("Warning: failed to start cron scheduler: %v", err)
+		}
+		defer scheduler.Stop()
+		if err := watcher.Start(ctx); err != nil {
+			log.Printf("Warning: file watches are off: %v", err)
+		}
 	}
-	defer scheduler.Stop()
 
-	// Initialize MCP manager and connect to configured servers
-	mcpManager := mcp.NewManager()
-	defer mcpManager.Close()
-	registerMCPServers(ctx, mcpManager, cfg, registry)
+	// Connect to configured MCP servers
+	stopMCP, mcpStatus := startMCP(ctx, cfg, registry)
+	defer stopMCP()
 
-	// Handle signals for graceful shutdown
+	// Handle signals for graceful shutdown, and SIGHUP to reload the config
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
 
-	// Start outbound message dispatcher
-	go msgBus.DispatchOutbound(ctx)
+	// Connect to the shared bus when running as part of a cluster
+	if cfg.Cluster.IsClustered() {
+		if err := startClusterBridges(ctx, msgBus, sessionMgr, cfg); err != nil {
+			return err
+		}
+		fmt.Printf("Cluster role: %s (%s)\n", role, config.RedactURL(cfg.Cluster.RedisURL))
+	}
+
+	// Start outbound message dispatcher (workers hand outbound messages to the cluster)
+	if runChannels {
+		go msgBus.DispatchOutbound(ctx)
+	}
 
 	// WaitGroup for tracking running goroutines
 	var wg sync.WaitGroup
 
 	// Start agent loop (processes inbound messages)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		runAgentLoop(ctx, msgBus, provider, sessionMgr, secureReg, cfg, skillsSummary, manageUbotTool)
-	}()
+	loopDone := make(chan struct{})
+	if runProcessing {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(loopDone)
+			runAgentLoop(acceptCtx, work, msgBus, live, sessionMgr, scheduler, skillsLoader, manageUbotTool, approvals, advisor, offline)
+		}()
 
-	// Start channel connectors
-	if cfg.Channels.Telegram.Enabled {
-		if len(cfg.Channels.Telegram.AllowFrom) == 0 {
-			fmt.Println("WARNING: Telegram channel enabled but AllowFrom is empty — all messages will be rejected.")
-			fmt.Println("Add your Telegram user ID to 'channels.telegram.allow_from' in config to allow access.")
-		}
+		// Answer messages held while the provider was unreachable
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			runTelegramChannel(ctx, msgBus, cfg)
+			offline.Run(acceptCtx, msgBus, providerProbe(live), func(msg bus.InboundMessage) {
+				work.queue(msg.SessionKey(), func(ctx context.Context) {
+					processMessage(ctx, msgBus, live, sessionMgr, scheduler, msg, skillsLoader, manageUbotTool, approvals, advisor, offline)
+				})
+			})
 		}()
-		fmt.Printf("Telegram channel: enabled\n")
 	}
 
-	if cfg.Channels.WhatsApp.Enabled {
+	// Pick up skills added, removed or edited in the workspace
+	if runProcessing {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := skillsLoader.Watch(ctx, nil); err != nil {
+				log.Printf("Warning: skills will not reload until restart: %v", err)
+			}
+		}()
+	}
+
+	// Keep the skills cache fresh and announce new skills to the admin
+	if interval := cfg.Skills.RefreshInterval(); runProcessing && interval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			skillsMgr.RunMaintenance(ctx, interval, func(fresh []*skills.AvailableSkill) {
+				admin := cfg.Channels.Admin
+				if admin.ChatID == "" {
+					log.Printf("New skills available: %d (see 'ubot skills list')", len(fresh))
+					return
+				}
+				msgBus.PublishOutbound(bus.OutboundMessage{
+					Channel: admin.Channel,
+					ChatID:  admin.ChatID,
+					Content: skills.FormatNewSkills(fresh),
+				})
+			})
+		}()
+	}
+
+	// Send the daily briefing
+	if runProcessing && cfg.Agents.Briefing.Enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runBriefing(ctx, cfg, live, sessionMgr, scheduler, msgBus)
+		}()
+	}
+
+	// Save usage statistics and send the weekly report to the owner chat
+	if recorder != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder.Run(ctx, func(report string) {
+				if cfg.Stats.ReportChatID == "" {
+					return
+				}
+				msgBus.PublishOutbound(bus.OutboundMessage{
+					Channel: cfg.Stats.ReportChannel,
+					ChatID:  cfg.Stats.ReportChatID,
+					Content: report,
+				})
+			})
+		}()
+	}
+
+	// Start channel connectors
+	channelMgr := channels.NewManager(cfg, msgBus)
+	if runChannels {
+		if cfg.Channels.Telegram.Enabled && len(cfg.Channels.Telegram.AllowFrom) == 0 {
+			fmt.Println("WARNING: Telegram channel enabled but AllowFrom is empty — all messages will be held for approval.")
+			fmt.Println("Add your Telegram user ID or username to 'channels.telegram.allowFrom' in config, or approve senders with 'ubot access'.")
+		}
+		if err := channelMgr.Initialize(); err != nil {
+			return fmt.Errorf("failed to initialize channels: %w", err)
+		}
+		if err := channelMgr.StartAll(ctx); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		defer channelMgr.StopAll()
+		for _, st := range channelMgr.ChannelStatuses() {
+			fmt.Printf("Channel %s: enabled\n", st.Name)
+		}
+		live.channels = channelMgr
+	}
+
+	if runChannels && cfg.Channels.WhatsApp.Enabled {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			runWhatsAppChannel(ctx, msgBus, cfg)
 		}()
-		fmt.Printf("WhatsApp channel: enabled\n")
+		fmt.Printf("WhatsApp channel: enabled (%s)\n", config.RedactURL(cfg.Channels.WhatsApp.BridgeURL))
+	}
+
+	// Start the control API, or only the health checks
+	health := &gatewayHealth{mcp: mcpStatus, dockerRequired: dockerSocketFlag != ""}
+	if runChannels {
+		health.channels = channelMgr
+	}
+	if runProcessing {
+		health.offline = offline
+		health.scheduler = scheduler
+	}
+	if cfg.Gateway.ControlAPI || cfg.Gateway.Health {
+		controlSrv := control.NewServer(cfg.Gateway.Addr(), cfg.Gateway.Token)
+		controlSrv.RegisterHealth(health)
+		health.checkDocker()
+		if cfg.Gateway.ControlAPI {
+			if runChannels {
+				controlSrv.RegisterChannels(channelMgr)
+				controlSrv.RegisterAccess(channelMgr.Access())
+			}
+			controlSrv.RegisterReload(live)
+			controlSrv.RegisterRestart(restarter)
+		}
+		if err := controlSrv.Start(); err != nil {
+			log.Printf("Warning: failed to start control API: %v", err)
+		} else {
+			if cfg.Gateway.ControlAPI {
+				fmt.Printf("Control API: http://%s\n", cfg.Gateway.Addr())
+			} else {
+				fmt.Printf("Health checks: http://%s/readyz\n", cfg.Gateway.Addr())
+			}
+			defer controlSrv.Shutdown(context.Background())
+		}
 	}
 
+	// Apply changes to the config file without a restart
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := config.Watch(ctx, live.path, func() { live.reloadAndLog("config file changed") }); err != nil {
+			log.Printf("Warning: config changes will not apply until reload or restart: %v", err)
+		}
+	}()
+
 	fmt.Printf("Provider: %s (model: %s)\n", providerName, cfg.Agents.Defaults.Model)
 	fmt.Println()
 	fmt.Println("Gateway is running. Press Ctrl+C to stop.")
+	health.ready.Store(true)
+
+	// Wait for shutdown signal or restart request, reloading the config on
+	// SIGHUP
+	restarting := false
+	for waiting := true; waiting; {
+		select {
+		case <-sigChan:
+			waiting = false
+		case <-restarter.requested:
+			restarting = true
+			waiting = false
+		case <-hupChan:
+			live.reloadAndLog("SIGHUP")
+		}
+	}
+	health.ready.Store(false)
+	if restarting {
+		fmt.Println("\nRestarting gateway...")
+	} else {
+		fmt.Println("\nShutting down gateway...")
+	}
 
-	// Wait for shutdown signal
-	<-sigChan
-	fmt.Println("\nShutting down gateway...")
+	// Stop taking new messages, let those in progress finish and send the
+	// replies still queued
+	stopAccepting()
+	if runProcessing {
+		<-loopDone
+	}
+	if !work.drain(cfg.Gateway.DrainDeadline()) {
+		fmt.Println("Messages still in progress were cancelled.")
+	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+	if err := msgBus.Flush(flushCtx); err != nil {
+		fmt.Println("Some replies could not be sent before shutdown.")
+	}
+	cancelFlush()
 
 	// Cancel context to stop all goroutines
 	cancel()
@@ -178,11 +488,322 @@ func runGateway(cmd *cobra.Command, args []string) error {
 		fmt.Println("Gateway shutdown timed out.")
 	}
 
+	if restarting {
+		return errRestart
+	}
+	return nil
+}
+
+// startClusterBridges connects the local bus to the shared Redis queues
+// according to the configured cluster role. Pollers forward inbound messages
+// from their channels and deliver outbound replies; workers consume inbound
+// messages, publish replies, and keep sessions in the shared store.
+func startClusterBridges(ctx context.Context, msgBus *bus.MessageBus, sessionMgr *session.Manager, cfg *config.Config) error {
+	clusterCfg := cfg.Cluster
+	if clusterCfg.RedisURL == "" {
+		return fmt.Errorf("cluster role %q requires cluster.redisUrl", clusterCfg.Role)
+	}
+
+	// Blocking pops need dedicated connections.
+	newClient := func() (*redis.Client, error) {
+		return redis.NewClient(clusterCfg.RedisURL)
+	}
+	pushClient, err := newClient()
+	if err != nil {
+		return err
+	}
+	popClient, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	prefix := clusterCfg.KeyPrefix()
+	inboundQueue := prefix + "inbound"
+	outboundQueue := prefix + "outbound"
+
+	// Streams replace the two shared lists with one stream per channel each way
+	if cfg.Bus.UsesStreams() {
+		push := redisStream{pushClient, cfg.Bus.StreamMaxLen()}
+		pop := redisStream{popClient, cfg.Bus.StreamMaxLen()}
+		reader := bus.StreamReader{
+			Prefix:    prefix,
+			Consumer:  cfg.Bus.ConsumerName(),
+			ClaimIdle: cfg.Bus.ClaimIdleTimeout(),
+		}
+		switch clusterCfg.Role {
+		case config.ClusterRolePoller:
+			reader.Channels = enabledChannels(cfg)
+			go msgBus.ExportInboundStream(ctx, push, prefix)
+			go msgBus.ImportOutboundStream(ctx, pop, reader)
+		case config.ClusterRoleWorker:
+			storeClient, err := newClient()
+			if err != nil {
+				return err
+			}
+			sessionMgr.SetStore(redis.NewKeyStore(storeClient, prefix+"session:"))
+			reader.Channels = cfg.Bus.WorkerChannels()
+			go msgBus.ImportInboundStream(ctx, pop, reader)
+			go msgBus.ExportOutboundStream(ctx, push, prefix)
+		default:
+			return fmt.Errorf("unknown cluster role %q (use standalone, poller or worker)", clusterCfg.Role)
+		}
+		return nil
+	}
+
+	switch clusterCfg.Role {
+	case config.ClusterRolePoller:
+		go msgBus.ExportInbound(ctx, pushClient, inboundQueue)
+		go msgBus.ImportOutbound(ctx, popClient, outboundQueue)
+	case config.ClusterRoleWorker:
+		storeClient, err := newClient()
+		if err != nil {
+			return err
+		}
+		sessionMgr.SetStore(redis.NewKeyStore(storeClient, prefix+"session:"))
+		go msgBus.ImportInbound(ctx, popClient, inboundQueue)
+		go msgBus.ExportOutbound(ctx, pushClient, outboundQueue)
+	default:
+		return fmt.Errorf("unknown cluster role %q (use standalone, poller or worker)", clusterCfg.Role)
+	}
 	return nil
 }
 
-// runAgentLoop processes inbound messages and sends responses.
-func runAgentLoop(ctx context.Context, msgBus *bus.MessageBus, provider providers.Provider, sessionMgr *session.Manager, registry *tools.SecureRegistry, cfg *config.Config, skillsSummary string, manageUbotTool *tools.ManageUbotTool) {
+// enabledChannels returns the names of the channels the gateway runs.
+func enabledChannels(cfg *config.Config) []string {
+	var names []string
+	if cfg.Channels.Telegram.Enabled {
+		names = append(names, "telegram")
+	}
+	if cfg.Channels.WhatsApp.Enabled {
+		names = append(names, "whatsapp")
+	}
+	return names
+}
+
+// redisStream is a bus.Stream on Redis streams, trimming each stream to
+// about maxLen entries.
+type redisStream struct {
+	client *redis.Client
+	maxLen int
+}
+
+func (s redisStream) Add(ctx context.Context, stream string, data []byte) error {
+	_, err := s.client.XAdd(ctx, stream, s.maxLen, data)
+	return err
+}
+
+func (s redisStream) CreateGroup(ctx context.Context, stream, group string) error {
+	return s.client.XGroupCreate(ctx, stream, group)
+}
+
+func (s redisStream) Read(ctx context.Context, group, consumer string, streams []string, pending bool, timeout time.Duration) ([]bus.StreamEntry, error) {
+	entries, err := s.client.XReadGroup(ctx, group, consumer, streams, 16, pending, timeout)
+	return streamEntries(entries), err
+}
+
+func (s redisStream) Claim(ctx context.Context, stream, group, consumer string, minIdle time.Duration) ([]bus.StreamEntry, error) {
+	entries, err := s.client.XAutoClaim(ctx, stream, group, consumer, minIdle, 16)
+	return streamEntries(entries), err
+}
+
+func (s redisStream) Ack(ctx context.Context, stream, group, id string) error {
+	return s.client.XAck(ctx, stream, group, id)
+}
+
+// streamEntries converts Redis stream entries to bus entries.
+func streamEntries(entries []redis.StreamEntry) []bus.StreamEntry {
+	out := make([]bus.StreamEntry, len(entries))
+	for i, e := range entries {
+		out[i] = bus.StreamEntry{Stream: e.Stream, ID: e.ID, Data: e.Data}
+	}
+	return out
+}
+
+// shutdownFlushTimeout bounds the wait for queued replies on shutdown.
+const shutdownFlushTimeout = 10 * time.Second
+
+// dockerCheckInterval is how long the health checks reuse the result of
+// pinging the Docker daemon.
+const dockerCheckInterval = 30 * time.Second
+
+// gatewayHealth reports the gateway's health for the health checks. It
+// implements control.HealthChecker.
+type gatewayHealth struct {
+	ready     atomic.Bool            // started and not shutting down
+	channels  *channels.Manager      // nil when the gateway runs no channels
+	offline   *bus.OfflineQueue      // nil when the gateway processes no messages
+	scheduler *cron.Scheduler        // nil when the gateway processes no messages
+	mcp       func() map[string]bool // connected state by MCP server
+
+	// The sandbox is needed with --docker-socket; otherwise skill setup
+	// scripts are merely skipped without Docker
+	dockerRequired bool
+	dockerMu       sync.Mutex
+	dockerChecked  time.Time
+	dockerChecking bool
+	dockerUp       bool
+}
+
+func (h *gatewayHealth) Health() control.Health {
+	if !h.ready.Load() {
+		return control.Health{Status: control.HealthUnavailable, Checks: map[string]control.Check{
+			"gateway": {Status: control.CheckDown, Detail: "starting or shutting down"},
+		}}
+	}
+
+	checks := map[string]control.Check{}
+	if h.offline != nil {
+		checks["provider"] = control.Check{Status: control.CheckOK}
+		if h.offline.Offline() {
+			checks["provider"] = control.Check{Status: control.CheckDown, Detail: "unreachable"}
+		}
+	}
+	for _, c := range providers.Circuits() {
+		checks["provider."+c.Provider] = circuitCheck(c)
+	}
+	if h.channels != nil {
+		for _, st := range h.channels.ChannelStatuses() {
+			check := control.Check{Status: control.CheckOK, Detail: "running"}
+			if !st.Running {
+				check = control.Check{Status: control.CheckDown, Detail: "stopped"}
+			}
+			checks["channels."+st.Name] = check
+		}
+	}
+	for name, connected := range h.mcp() {
+		check := control.Check{Status: control.CheckOK, Detail: "connected"}
+		if !connected {
+			check = control.Check{Status: control.CheckDown, Detail: "disconnected, retrying"}
+		}
+		checks["mcp."+name] = check
+	}
+	if h.scheduler != nil {
+		check := control.Check{Status: control.CheckOK, Detail: fmt.Sprintf("%d jobs", len(h.scheduler.ListJobs()))}
+		if !h.scheduler.Running() {
+			check = control.Check{Status: control.CheckDown, Detail: "stopped"}
+		}
+		checks["cron"] = check
+	}
+	checks["docker"] = h.docker()
+	return control.NewHealth(checks)
+}
+
+// circuitCheck reports the state of a provider's circuit breaker.
+func circuitCheck(c providers.CircuitState) control.Check {
+	switch c.State {
+	case providers.CircuitOpen:
+		return control.Check{Status: control.CheckDown, Detail: fmt.Sprintf("circuit open until %s after %d failures: %s",
+			c.OpenUntil.Format("15:04:05"), c.Failures, c.LastError)}
+	case providers.CircuitHalfOpen:
+		return control.Check{Status: control.CheckOK, Detail: "circuit half-open, probing"}
+	}
+	if c.Failures > 0 {
+		return control.Check{Status: control.CheckOK, Detail: fmt.Sprintf("circuit closed, %d failures in a row", c.Failures)}
+	}
+	return control.Check{Status: control.CheckOK, Detail: "circuit closed"}
+}
+
+// docker reports the state of the Docker daemon used by the sandbox.
+// Pinging it can take seconds, so the last result is reported while a
+// stale one is refreshed in the background.
+func (h *gatewayHealth) docker() control.Check {
+	if !features.Docker {
+		return control.Check{Status: control.CheckOff, Detail: "not included in this build"}
+	}
+	h.dockerMu.Lock()
+	if !h.dockerChecking && time.Since(h.dockerChecked) > dockerCheckInterval {
+		h.dockerChecking = true
+		go h.checkDocker()
+	}
+	up := h.dockerUp
+	h.dockerMu.Unlock()
+
+	switch {
+	case up:
+		return control.Check{Status: control.CheckOK}
+	case h.dockerRequired:
+		return control.Check{Status: control.CheckDown, Detail: "unreachable"}
+	default:
+		return control.Check{Status: control.CheckOff, Detail: "unreachable; skill setup scripts are skipped"}
+	}
+}
+
+// checkDocker pings the Docker daemon and records the result.
+func (h *gatewayHealth) checkDocker() {
+	up := features.Docker && sandbox.IsDockerAvailable()
+	h.dockerMu.Lock()
+	defer h.dockerMu.Unlock()
+	h.dockerUp = up
+	h.dockerChecked = time.Now()
+	h.dockerChecking = false
+}
+
+// inflight tracks the messages being processed so that shutdown can let
+// them finish. Processing runs under its own context, cancelled only when
+// the drain deadline passes.
+type inflight struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	n        atomic.Int64
+	sessions *bus.SessionWorkers
+}
+
+// newInflight creates an inflight that processes up to limit queued
+// messages at once.
+func newInflight(limit int) *inflight {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &inflight{ctx: ctx, cancel: cancel, sessions: bus.NewSessionWorkers(limit)}
+}
+
+// start runs process in the background with the processing context,
+// counting it as in flight.
+func (f *inflight) start(process func(ctx context.Context)) {
+	f.n.Add(1)
+	go func() {
+		defer f.n.Add(-1)
+		process(f.ctx)
+	}()
+}
+
+// queue runs process in the background once the earlier messages of the
+// session with the given key are done, counting it as in flight while it
+// waits. Messages still waiting when processing is cancelled are skipped.
+func (f *inflight) queue(key string, process func(ctx context.Context)) {
+	f.n.Add(1)
+	f.sessions.Submit(key, func() {
+		defer f.n.Add(-1)
+		if f.ctx.Err() == nil {
+			process(f.ctx)
+		}
+	})
+}
+
+// abort cancels the processing context.
+func (f *inflight) abort() {
+	f.cancel()
+}
+
+// drain waits up to timeout for the messages in progress to finish,
+// reporting whether they did. Those still running are then cancelled.
+func (f *inflight) drain(timeout time.Duration) bool {
+	defer f.abort()
+	if n := f.n.Load(); n > 0 && timeout > 0 {
+		fmt.Printf("Waiting up to %s for %d message(s) in progress...\n", timeout, n)
+	}
+	deadline := time.Now().Add(timeout)
+	for f.n.Load() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
+
+// runAgentLoop takes inbound messages until ctx is cancelled and processes
+// them in the background, tracked by work: each session's messages in the
+// order they arrived, different sessions in parallel.
+func runAgentLoop(ctx context.Context, work *inflight, msgBus *bus.MessageBus, live *liveGateway, sessionMgr *session.Manager, scheduler *cron.Scheduler, skillsLoader *skills.Loader, manageUbotTool *tools.ManageUbotTool, approvals *tools.ChatApprovals, advisor *skills.Advisor, offline *bus.OfflineQueue) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -202,46 +823,206 @@ func runAgentLoop(ctx context.Context, msgBus *bus.MessageBus, provider provider
 			continue
 		}
 
-		// Process message in a goroutine
-		go processMessage(ctx, msgBus, provider, sessionMgr, registry, cfg, msg, skillsSummary, manageUbotTool)
+		process := func(ctx context.Context) {
+			defer settleMessage(ctx, msgBus, msg)
+			processMessage(ctx, msgBus, live, sessionMgr, scheduler, msg, skillsLoader, manageUbotTool, approvals, advisor, offline)
+		}
+		// Answers to approvals go ahead of the session's queue: the turn
+		// they answer is waiting for them at its head
+		if tools.IsApprovalReply(msg.Content) {
+			work.start(process)
+			continue
+		}
+		work.queue(msg.SessionKey(), process)
+	}
+}
+
+// openInboundJournal makes msgBus keep inbound messages in the SQLite
+// journal, replaying those left over from before a crash. The returned
+// function closes the journal.
+func openInboundJournal(cfg *config.Config, msgBus *bus.MessageBus) (func(), error) {
+	journal, err := bus.OpenJournal(cfg.QueueDBPath(), cfg.Gateway.Queue.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := journal.Recover()
+	if err != nil {
+		journal.Close()
+		return nil, err
 	}
+	if pending > 0 {
+		log.Printf("Replaying %d inbound message(s) from %s", pending, cfg.QueueDBPath())
+	}
+	if _, dead, err := journal.Counts(); err == nil && dead > 0 {
+		log.Printf("Warning: %d inbound message(s) are dead letters; see 'ubot queue'", dead)
+	}
+	msgBus.SetJournal(journal)
+	return func() { journal.Close() }, nil
 }
 
-// processMessage handles a single inbound message.
-func processMessage(ctx context.Context, msgBus *bus.MessageBus, provider providers.Provider, sessionMgr *session.Manager, registry *tools.SecureRegistry, cfg *config.Config, msg bus.InboundMessage, skillsSummary string, manageUbotTool *tools.ManageUbotTool) {
-	// Get or create session for this conversation
-	sess := sessionMgr.GetOrCreate(msg.SessionKey())
+// settleMessage acknowledges msg once processed, so the journal forgets
+// it. A panic while processing is recovered and counted as a failed
+// delivery: the message is retried, or the user is told it was given up
+// on. Messages still in progress at shutdown are left to be replayed.
+func settleMessage(ctx context.Context, msgBus *bus.MessageBus, msg bus.InboundMessage) {
+	if r := recover(); r != nil {
+		log.Printf("Warning: processing a message from %s failed: %v", msg.SessionKey(), r)
+		if msgBus.Fail(msg, fmt.Sprint(r)) {
+			sendErrorResponse(msgBus, msg, "Sorry, something went wrong and I couldn't answer this message.")
+		}
+		return
+	}
+	if ctx.Err() == nil {
+		msgBus.Ack(msg)
+	}
+}
+
+// processMessage handles a single inbound message with the config current
+// when it arrives.
+func processMessage(ctx context.Context, msgBus *bus.MessageBus, live *liveGateway, sessionMgr *session.Manager, scheduler *cron.Scheduler, msg bus.InboundMessage, skillsLoader *skills.Loader, manageUbotTool *tools.ManageUbotTool, approvals *tools.ChatApprovals, advisor *skills.Advisor, offline *bus.OfflineQueue) {
+	cfg, registry := live.current()
+	provider := live.provider
+
+	// Continue the trace the channel started on receiving the message;
+	// replies carry it on to the send
+	ctx, span := tracing.Start(tracing.Extract(ctx, msg.Trace), "handle message",
+		attribute.String("ubot.channel", msg.Channel),
+		attribute.String("ubot.session", msg.SessionKey()),
+	)
+	defer span.End()
+	msg.Trace = tracing.Inject(ctx)
+
+	// Get or create session for this conversation, in its current branch
+	sess := sessionMgr.Active(msg.SessionKey())
 	sess.Source = msg.Channel
 
 	// Set manage_ubot tool source context for this request
 	manageUbotTool.SetSource(msg.Channel)
 	defer manageUbotTool.ClearSource()
 
-	// Add user message to session
-	sess.AddMessage("user", msg.Content)
+	// Answer pending tool approvals (/approve, /deny)
+	if reply, ok := approvals.HandleReply(msg.SessionKey(), msg.SenderID, msg.Content); ok {
+		msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: reply,
+			Trace:   msg.Trace,
+		})
+		return
+	}
+
+	// Answer skill suggestions (/install-skill, /dismiss-skill)
+	if reply, ok := advisor.HandleReply(ctx, msg.SessionKey(), msg.Content); ok {
+		msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: reply,
+			Trace:   msg.Trace,
+		})
+		return
+	}
+
+	// Chats may also switch to the models the provider lists
+	defaults := cfg.Agents.Defaults
+	if (msg.Command != nil && (msg.Command.Name == "model" || msg.Command.Name == "settings")) || sess.GetModel() != "" {
+		defaults = offeredModels(ctx, provider, defaults)
+	}
+
+	// Answer the commands channels offer in their menus (e.g. /model, /jobs)
+	if reply, ok := handleBotCommand(msg, sess, sessionMgr, scheduler, defaults, cfg.Prompts); ok {
+		if msg.Command.Name == "reset" {
+			dropArtifacts(registry, sess.Key)
+		}
+		msgBus.PublishOutbound(reply)
+		return
+	}
+
+	// Handle chat commands (e.g. /pin) without calling the LLM
+	if reply, ok := handleChatCommand(sess, sessionMgr, cfg.Channels.Admin.SessionKey(), msg.Content); ok {
+		msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: reply,
+			Trace:   msg.Trace,
+		})
+		return
+	}
+
+	// While the provider is unreachable, hold messages instead of failing
+	// every turn
+	if offline.Offline() {
+		holdMessage(msgBus, offline, msg)
+		return
+	}
+
+	// Let tools know which conversation they act on
+	ctx = tools.WithRequest(ctx, tools.RequestInfo{
+		Channel:    msg.Channel,
+		ChatID:     msg.ChatID,
+		SenderID:   msg.SenderID,
+		SessionKey: msg.SessionKey(),
+	})
+	ctx = tools.WithApprover(ctx, approvals)
+	ctx = tools.WithProgress(ctx, func(status string) {
+		msgBus.PublishOutbound(bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: status})
+	})
+
+	// Add user message to session, with the paths of any files sent with it
+	content := withAttachments(msg.Content, msg.Files())
+	sess.AddMessage("user", content)
 
 	// Build messages for the LLM
-	messages := buildChatMessagesFromSession(sess, skillsSummary)
+	vars := promptVars(msg.Channel, msg.ChatID, senderName(msg), skillsLoader.GetSummary())
+	messages := buildChatMessagesFromSession(sess, systemPrompt(cfg, msg.SessionKey(), sess.GetSettings(), prompts.Default, vars))
+
+	// Offer only the tools relevant to this message; request_tool adds more
+	selection := tools.SelectTools(registry.GetDefinitions(), content, cfg.Agents.Defaults.MaxToolDefinitions)
+	ctx = tools.WithToolSelection(ctx, selection)
+	ctx = tools.WithSkillTrust(ctx, tools.NewSkillTrust())
 
 	// Create chat request
 	req := providers.ChatRequest{
 		Messages:    messages,
-		Tools:       registry.GetDefinitions(),
-		Model:       cfg.Agents.Defaults.Model,
+		Tools:       selection.Definitions(),
+		Model:       defaults.ChatModel(sess.GetModel()),
 		MaxTokens:   cfg.Agents.Defaults.MaxTokens,
-		Temperature: cfg.Agents.Defaults.Temperature,
+		Temperature: sess.GetSettings().TemperatureOr(cfg.Agents.Defaults.Temperature),
 	}
 
+	// Show the reply while it is generated, on channels that can
+	draft := startReplyDraft(msgBus, msg)
+	defer draft.close()
+
 	// Iterate through tool calls up to max iterations
 	iterations := 0
 	maxIterations := cfg.Agents.Defaults.MaxToolIterations
 
+	// Tools that failed this turn, for skill suggestions. Blocked and denied
+	// calls are policy, not a missing ability, and are not counted.
+	var failedTools []string
+	observeFailure := func(category string) {
+		advisor.Observe(skills.Failure{
+			SessionKey: msg.SessionKey(),
+			Channel:    msg.Channel,
+			ChatID:     msg.ChatID,
+			Category:   category,
+			Request:    msg.Content,
+		})
+	}
+
 	for iterations < maxIterations {
 		// Send request to LLM
-		response, err := provider.Chat(ctx, req)
+		draft.reset()
+		response, err := providers.ChatStream(ctx, provider, req, draft.add)
+		if err != nil && iterations == 0 && ctx.Err() == nil && providers.IsUnreachable(err) {
+			log.Printf("Warning: provider unreachable, holding message: %v", err)
+			sess.RemoveLastMessage()
+			holdMessage(msgBus, offline, msg)
+			return
+		}
 		if err != nil {
-			fmt.Printf("Error from provider: %v\n", err)
-			sendErrorResponse(msgBus, msg, "I encountered an error processing your request.")
+			fmt.Printf("Error from provider (%s): %v\n", failure.Of(err), err)
+			msgBus.PublishOutbound(draft.finish(errorResponse(msg, failure.Message(err))))
 			return
 		}
 
@@ -255,12 +1036,18 @@ func processMessage(ctx context.Context, msgBus *bus.MessageBus, provider provid
 				fmt.Printf("Warning: failed to save session: %v\n", err)
 			}
 
-			// Send response
-			msgBus.PublishOutbound(bus.OutboundMessage{
+			// Send response, quoting the message it answers, in place of
+			// the draft
+			msgBus.PublishOutbound(draft.finish(bus.OutboundMessage{
 				Channel: msg.Channel,
 				ChatID:  msg.ChatID,
 				Content: response.Content,
-			})
+				ReplyTo: msg.MessageID(),
+				Trace:   msg.Trace,
+			}))
+			for _, name := range failedTools {
+				observeFailure(name)
+			}
 			return
 		}
 
@@ -271,48 +1058,46 @@ func processMessage(ctx context.Context, msgBus *bus.MessageBus, provider provid
 			ToolCalls: response.ToolCalls,
 		})
 
-		for _, toolCall := range response.ToolCalls {
-			result, err := registry.Execute(ctx, toolCall.Name, toolCall.Arguments)
-			if err != nil {
-				result = fmt.Sprintf("Error executing tool: %v", err)
+		calls := make([]tools.Call, len(response.ToolCalls))
+		for i, toolCall := range response.ToolCalls {
+			calls[i] = tools.Call{ID: toolCall.ID, Name: toolCall.Name, Params: toolCall.Arguments}
+		}
+		for _, res := range registry.ExecuteAll(ctx, calls) {
+			result := res.Result
+			if res.Err != nil {
+				result = fmt.Sprintf("Error executing tool: %v", res.Err)
+				if failure.Of(res.Err) != failure.ToolBlocked && !slices.Contains(failedTools, res.Call.Name) {
+					failedTools = append(failedTools, res.Call.Name)
+				}
 			}
 
 			messages = append(messages, providers.ChatMessage{
 				Role:       "tool",
 				Content:    result,
-				ToolCallID: toolCall.ID,
-				Name:       toolCall.Name,
+				ToolCallID: res.Call.ID,
+				Name:       res.Call.Name,
 			})
 		}
 
 		req.Messages = messages
+		req.Tools = selection.Definitions()
 		iterations++
 	}
 
 	// Max iterations reached
-	sendErrorResponse(msgBus, msg, "I've reached the maximum number of tool iterations. Please try a simpler request.")
+	observeFailure(skills.CategoryUnfinished)
+	msgBus.PublishOutbound(draft.finish(errorResponse(msg, "I've reached the maximum number of tool iterations. Please try a simpler request.")))
 }
 
-// buildChatMessagesFromSession converts session messages to chat messages.
-func buildChatMessagesFromSession(sess *session.Session, skillsSummary string) []providers.ChatMessage {
+// buildChatMessagesFromSession converts session messages to chat messages
+// after the rendered system prompt.
+func buildChatMessagesFromSession(sess *session.Session, systemContent string) []providers.ChatMessage {
 	messages := sess.GetMessages()
 	chatMessages := make([]providers.ChatMessage, 0, len(messages)+1)
 
-	// Build system message with optional skills summary
-	systemContent := `You are uBot — the world's most lightweight self-hosted AI assistant.
-
-Key facts about yourself:
-- Ultra-minimal: ~10,000 lines of Go code (compared to 400k+ lines in similar projects)
-- Self-hosted: users run you on their own hardware, keeping data private
-- Multi-channel: you work through Telegram, WhatsApp, and CLI
-- Tool-capable: you can read/write files, execute commands, search the web, and browse websites with a headless browser (use browser_use tool with session parameter to keep logins across restarts)
-- Fast: compiled Go binary, instant startup, minimal memory footprint
-
-Personality: Be helpful, concise, and technically competent. You're proud of being lightweight but not boastful. Answer in the user's language.`
-
-	// Append skills summary if available
-	if skillsSummary != "" {
-		systemContent += "\n\n" + skillsSummary
+	// Append pinned context so it survives history trimming
+	if pinned := session.PinnedContext(sess.GetPins()); pinned != "" {
+		systemContent += "\n\n" + pinned
 	}
 
 	// Add system message
@@ -332,35 +1117,56 @@ Personality: Be helpful, concise, and technically competent. You're proud of bei
 	return chatMessages
 }
 
-// sendErrorResponse sends an error message back to the channel.
-func sendErrorResponse(msgBus *bus.MessageBus, msg bus.InboundMessage, errorMsg string) {
-	msgBus.PublishOutbound(bus.OutboundMessage{
-		Channel: msg.Channel,
-		ChatID:  msg.ChatID,
-		Content: errorMsg,
-	})
+// withAttachments adds the paths of the files a user sent to their message,
+// so the model can read or process them with its tools.
+func withAttachments(content string, files []string) string {
+	for _, path := range files {
+		content += "\n\n[Attached file: " + path + "]"
+	}
+	return strings.TrimSpace(content)
 }
 
-// runTelegramChannel starts the Telegram channel connector.
-func runTelegramChannel(ctx context.Context, msgBus *bus.MessageBus, cfg *config.Config) {
-	// Build voice transcriber (nil when not configured)
-	transcriber := buildVoiceTranscriber(cfg)
-
-	// Create the Telegram channel
-	telegramChannel := channels.NewTelegramChannel(cfg.Channels.Telegram, msgBus, transcriber)
+// holdMessage queues msg until the provider is reachable again and, for the
+// first message held from a chat, says that the answer will come later.
+func holdMessage(msgBus *bus.MessageBus, offline *bus.OfflineQueue, msg bus.InboundMessage) {
+	if offline.Hold(msg) {
+		sendErrorResponse(msgBus, msg, "I can't reach the AI model right now. I'll answer as soon as it's back.")
+	}
+}
 
-	// Start the channel
-	if err := telegramChannel.Start(ctx); err != nil {
-		log.Printf("Failed to start Telegram channel: %v", err)
-		return
+// providerProbe returns a check that the current provider can be reached,
+// using the smallest possible request. Any answer, even an error, other
+// than a failure to connect counts as reachable.
+func providerProbe(live *liveGateway) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		cfg, _ := live.current()
+		_, err := live.provider.Chat(ctx, providers.ChatRequest{
+			Messages:  []providers.ChatMessage{{Role: "user", Content: "ping"}},
+			Model:     cfg.Agents.Defaults.Model,
+			MaxTokens: 1,
+		})
+		if providers.IsUnreachable(err) {
+			return err
+		}
+		return nil
 	}
+}
 
-	// Wait for context cancellation
-	<-ctx.Done()
+// sendErrorResponse sends an error message back to the channel.
+func sendErrorResponse(msgBus *bus.MessageBus, msg bus.InboundMessage, errorMsg string) {
+	msgBus.PublishOutbound(errorResponse(msg, errorMsg))
+}
 
-	// Stop the channel gracefully
-	if err := telegramChannel.Stop(); err != nil {
-		log.Printf("Error stopping Telegram channel: %v", err)
+// errorResponse is the reply to msg telling the user what went wrong.
+func errorResponse(msg bus.InboundMessage, errorMsg string) bus.OutboundMessage {
+	return bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: errorMsg,
+		ReplyTo: msg.MessageID(),
+		Trace:   msg.Trace,
 	}
 }
 
@@ -383,84 +1189,3 @@ func registerSkillTools(registry *tools.ToolRegistry, loader *skills.Loader) {
 	listSkillsTool := tools.NewListSkillsTool(loader)
 	registry.Register(listSkillsTool)
 }
-
-// registerMCPServers connects to configured MCP servers and registers their tools.
-func registerMCPServers(ctx context.Context, manager *mcp.Manager, cfg *config.Config, registry *tools.ToolRegistry) {
-	if len(cfg.MCP.Servers) == 0 {
-		return
-	}
-
-	fmt.Printf("Connecting to MCP servers...\n")
-
-	for _, serverCfg := range cfg.MCP.Servers {
-		// Convert config server to mcp.Server
-		server := mcp.Server{
-			Name:      serverCfg.Name,
-			Command:   serverCfg.Command,
-			Args:      serverCfg.Args,
-			URL:       serverCfg.URL,
-			Transport: serverCfg.Transport,
-			Env:       serverCfg.Env,
-		}
-
-		// Connect to the server
-		if err := manager.AddServer(ctx, server); err != nil {
-			log.Printf("Warning: failed to connect to MCP server %q: %v", serverCfg.Name, err)
-			continue
-		}
-
-		fmt.Printf("MCP server %q: connected\n", serverCfg.Name)
-	}
-
-	// Register all MCP tools with the tool registry
-	bridgedTools := manager.CreateBridgedTools()
-	for _, tool := range bridgedTools {
-		if err := registry.Register(tool); err != nil {
-			log.Printf("Warning: failed to register MCP tool %q: %v", tool.Name(), err)
-		}
-	}
-
-	if len(bridgedTools) > 0 {
-		fmt.Printf("MCP tools registered: %d\n", len(bridgedTools))
-	}
-}
-
-// buildVoiceTranscriber creates a voice.Transcriber based on config.
-// Returns nil when no suitable API key is available.
-func buildVoiceTranscriber(cfg *config.Config) *voice.Transcriber {
-	voiceCfg := cfg.Tools.Voice
-
-	backend := voice.Backend(voiceCfg.Backend)
-	var apiKey string
-
-	switch backend {
-	case voice.BackendOpenAI:
-		apiKey = cfg.Providers.OpenAI.APIKey
-	case voice.BackendGroq:
-		apiKey = cfg.Providers.Groq.APIKey
-	default:
-		if cfg.Providers.Groq.APIKey != "" {
-			backend = voice.BackendGroq
-			apiKey = cfg.Providers.Groq.APIKey
-		} else if cfg.Providers.OpenAI.APIKey != "" {
-			backend = voice.BackendOpenAI
-			apiKey = cfg.Providers.OpenAI.APIKey
-		}
-	}
-
-	if apiKey == "" {
-		return nil
-	}
-
-	var opts []voice.Option
-	if voiceCfg.Model != "" {
-		opts = append(opts, voice.WithModel(voiceCfg.Model))
-	}
-
-	t, err := voice.NewTranscriber(backend, apiKey, opts...)
-	if err != nil {
-		log.Printf("Failed to create voice transcriber: %v", err)
-		return nil
-	}
-	return t
-}
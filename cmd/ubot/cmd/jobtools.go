@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/hkuds/ubot/internal/cron"
+	"github.com/hkuds/ubot/internal/providers"
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+// jobToolNames are the tools scheduled jobs may call. Jobs run with no one
+// watching, so they get tools that read, such as feeds for a morning
+// digest or calendar to remind of meetings, and nothing that runs commands
+// or writes files.
+var jobToolNames = []string{"feeds", "web_fetch", "web_search", "calendar"}
+
+// jobToolbox runs the tool calls of cron jobs through the gateway's
+// security middleware, on behalf of the chat each job reports to.
+type jobToolbox struct {
+	live *liveGateway
+}
+
+// Definitions returns the definitions of the job tools that are registered.
+func (b jobToolbox) Definitions() interface{} {
+	_, registry := b.live.current()
+	var defs []tools.ToolDefinition
+	for _, def := range registry.GetDefinitions() {
+		if slices.Contains(jobToolNames, def.Function.Name) {
+			defs = append(defs, def)
+		}
+	}
+	if len(defs) == 0 {
+		return nil
+	}
+	return defs
+}
+
+// Execute runs call as if it were made in the job's chat.
+func (b jobToolbox) Execute(ctx context.Context, job cron.Job, call providers.ToolCall) string {
+	if !slices.Contains(jobToolNames, call.Name) {
+		return fmt.Sprintf("Error executing tool: %s is not available to scheduled jobs", call.Name)
+	}
+	if call.Name == "calendar" && call.Arguments["action"] == "create" {
+		return "Error executing tool: scheduled jobs cannot create calendar events"
+	}
+	result, err := b.run(ctx, job.Channel, job.ChatID, call.Name, call.Arguments)
+	if err != nil {
+		return fmt.Sprintf("Error executing tool: %v", err)
+	}
+	return result
+}
+
+// run executes the tool name through the gateway's registry on behalf of
+// the chat channel:chatID.
+func (b jobToolbox) run(ctx context.Context, channel, chatID, name string, params map[string]interface{}) (string, error) {
+	_, registry := b.live.current()
+	ctx = tools.WithRequest(ctx, tools.RequestInfo{
+		Channel:    channel,
+		ChatID:     chatID,
+		SessionKey: channel + ":" + chatID,
+	})
+	return registry.Execute(ctx, name, params)
+}
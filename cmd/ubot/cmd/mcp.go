@@ -0,0 +1,70 @@
+//go:build !lite && !nomcp
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/mcp"
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+// startMCP connects to the configured MCP servers, registers their tools
+// and supervises them until ctx is done. It returns a function closing all
+// connections and one reporting which servers are connected.
+func startMCP(ctx context.Context, cfg *config.Config, registry *tools.ToolRegistry) (stop func(), status func() map[string]bool) {
+	manager := mcp.NewManager()
+	registerMCPServers(ctx, manager, cfg, registry)
+	if len(cfg.MCP.Servers) > 0 {
+		go manager.Supervise(ctx, mcpHealthInterval)
+	}
+	return func() { manager.Close() }, manager.ServerStatus
+}
+
+// mcpHealthInterval is how often connected MCP servers are health-checked.
+const mcpHealthInterval = 30 * time.Second
+
+// registerMCPServers connects to configured MCP servers and registers their
+// tools. The manager keeps the registry in sync as servers reconnect.
+func registerMCPServers(ctx context.Context, manager *mcp.Manager, cfg *config.Config, registry *tools.ToolRegistry) {
+	if len(cfg.MCP.Servers) == 0 {
+		return
+	}
+
+	fmt.Printf("Connecting to MCP servers...\n")
+	manager.SetRegistry(registry)
+
+	for _, serverCfg := range cfg.MCP.Servers {
+		// Convert config server to mcp.Server
+		server := mcp.Server{
+			Name:      serverCfg.Name,
+			Command:   serverCfg.Command,
+			Args:      serverCfg.Args,
+			URL:       serverCfg.URL,
+			Transport: serverCfg.Transport,
+			Env:       serverCfg.Env,
+			Headers:   serverCfg.Headers,
+			Naming: mcp.Naming{
+				Bare:       serverCfg.Bare,
+				Aliases:    serverCfg.Aliases,
+				OnConflict: serverCfg.OnConflict,
+			},
+		}
+
+		// Connect to the server; failed servers are retried by the supervisor
+		if err := manager.AddServer(ctx, server); err != nil {
+			log.Printf("Warning: failed to connect to MCP server %q (will retry): %v", serverCfg.Name, err)
+			continue
+		}
+
+		fmt.Printf("MCP server %q: connected\n", serverCfg.Name)
+	}
+
+	if n := len(manager.GetAllTools()); n > 0 {
+		fmt.Printf("MCP tools registered: %d\n", n)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build lite || nomcp
+
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+// startMCP warns about configured MCP servers: MCP support is not compiled
+// into this build, so no servers are ever connected.
+func startMCP(ctx context.Context, cfg *config.Config, registry *tools.ToolRegistry) (stop func(), status func() map[string]bool) {
+	if len(cfg.MCP.Servers) > 0 {
+		log.Printf("Warning: %d MCP server(s) configured, but MCP support is not included in this build", len(cfg.MCP.Servers))
+	}
+	return func() {}, func() map[string]bool { return nil }
+}
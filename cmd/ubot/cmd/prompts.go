@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/prompts"
+	"github.com/hkuds/ubot/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// promptLib renders system prompts from ~/.ubot/prompts and the built-in
+// templates. Edited files are picked up on the next message.
+var promptLib = prompts.NewLibrary(config.GetPromptsDir())
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "Manage system prompt templates (personas)",
+	Long:  "System prompts are rendered from templates in ~/.ubot/prompts/<name>.md, falling back to the built-in default, cli and rootchat templates. Choose a persona per channel or chat with prompts.channels and prompts.chats in config.",
+}
+
+var promptsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List prompt templates and where they are used",
+	RunE:  runPromptsList,
+}
+
+var promptsInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Copy the built-in templates to ~/.ubot/prompts for editing",
+	RunE:  runPromptsInit,
+}
+
+var promptsShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a template rendered with sample values",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPromptsShow,
+}
+
+func init() {
+	promptsCmd.AddCommand(promptsListCmd)
+	promptsCmd.AddCommand(promptsInitCmd)
+	promptsCmd.AddCommand(promptsShowCmd)
+}
+
+func runPromptsList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Which conversations use each persona
+	usedBy := make(map[string][]string)
+	if cfg.Prompts.Default != "" {
+		usedBy[cfg.Prompts.Default] = append(usedBy[cfg.Prompts.Default], "default")
+	}
+	for channel, name := range cfg.Prompts.Channels {
+		usedBy[name] = append(usedBy[name], "channel "+channel)
+	}
+	for chat, name := range cfg.Prompts.Chats {
+		usedBy[name] = append(usedBy[name], "chat "+chat)
+	}
+
+	for _, info := range promptLib.List() {
+		source := "built-in"
+		if info.Path != "" {
+			source = info.Path
+		}
+		line := fmt.Sprintf("%-12s %s", info.Name, source)
+		if uses := usedBy[info.Name]; len(uses) > 0 {
+			sort.Strings(uses)
+			line += " (" + strings.Join(uses, ", ") + ")"
+		}
+		fmt.Println(line)
+		delete(usedBy, info.Name)
+	}
+	for name := range usedBy {
+		fmt.Printf("%-12s missing: configured but not found in %s\n", name, promptLib.Dir())
+	}
+	return nil
+}
+
+func runPromptsInit(cmd *cobra.Command, args []string) error {
+	written, err := promptLib.WriteBuiltins()
+	for _, path := range written {
+		fmt.Printf("Wrote %s\n", path)
+	}
+	if err != nil {
+		return err
+	}
+	if len(written) == 0 {
+		fmt.Printf("Templates already exist in %s\n", promptLib.Dir())
+	}
+	return nil
+}
+
+func runPromptsShow(cmd *cobra.Command, args []string) error {
+	vars := promptVars("telegram", "123456789", "Alex", "")
+	text, err := promptLib.Render(args[0], vars)
+	if err != nil {
+		return err
+	}
+	fmt.Println(text)
+	return nil
+}
+
+// promptVars returns the template values for a conversation.
+func promptVars(channel, chatID, userName, skillsSummary string) prompts.Vars {
+	vars := prompts.NewVars(time.Now())
+	vars.Channel = channel
+	vars.ChatID = chatID
+	vars.UserName = userName
+	vars.Skills = skillsSummary
+	vars.Tools = toolCapabilities()
+	vars.BuildNote = buildNote()
+	return vars
+}
+
+// systemPrompt renders the persona the conversation chose with /settings,
+// else the one configured for the conversation with the given session key,
+// or the built-in fallback template.
+func systemPrompt(cfg *config.Config, sessionKey string, settings session.Settings, fallback string, vars prompts.Vars) string {
+	persona := settings.Persona
+	if persona == "" {
+		persona = cfg.Prompts.Persona(sessionKey)
+	}
+	return promptLib.RenderPersona(persona, fallback, vars)
+}
+
+// senderName returns the sender's name from channel metadata, if any.
+func senderName(msg bus.InboundMessage) string {
+	for _, key := range []string{"firstName", "username"} {
+		if name, ok := msg.Metadata[key].(string); ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
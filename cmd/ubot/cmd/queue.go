@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Show the inbound message queue and its dead letters",
+	Long:  "Show how many inbound messages wait in the SQLite journal (gateway.queue.store = \"sqlite\") and list the dead letters: messages given up on after failing gateway.queue.maxAttempts times.",
+	Args:  cobra.NoArgs,
+	RunE:  runQueueList,
+}
+
+var queueRetryCmd = &cobra.Command{
+	Use:   "retry [id...]",
+	Short: "Deliver dead letters again",
+	Long:  "Make the given dead letters, or all of them, pending again. The running gateway picks them up within a second.",
+	RunE:  func(cmd *cobra.Command, args []string) error { return runQueueUpdate(args, true) },
+}
+
+var queuePurgeCmd = &cobra.Command{
+	Use:   "purge [id...]",
+	Short: "Delete dead letters",
+	Long:  "Delete the given dead letters, or all of them.",
+	RunE:  func(cmd *cobra.Command, args []string) error { return runQueueUpdate(args, false) },
+}
+
+func init() {
+	queueCmd.AddCommand(queueRetryCmd)
+	queueCmd.AddCommand(queuePurgeCmd)
+}
+
+// openQueue opens the configured inbound journal.
+func openQueue() (*bus.SQLiteJournal, error) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.Gateway.Queue.Persistent() {
+		return nil, fmt.Errorf("inbound messages are kept in memory (set gateway.queue.store to \"sqlite\" to journal them)")
+	}
+	if _, err := os.Stat(cfg.QueueDBPath()); err != nil {
+		return nil, fmt.Errorf("no inbound journal at %s yet", cfg.QueueDBPath())
+	}
+	return bus.OpenJournal(cfg.QueueDBPath(), cfg.Gateway.Queue.MaxAttempts)
+}
+
+func runQueueList(cmd *cobra.Command, args []string) error {
+	journal, err := openQueue()
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	queued, dead, err := journal.Counts()
+	if err != nil {
+		return fmt.Errorf("failed to read inbound journal: %w", err)
+	}
+	fmt.Printf("%d message(s) queued, %d dead letter(s)\n", queued, dead)
+	if dead == 0 {
+		return nil
+	}
+
+	letters, err := journal.DeadLetters()
+	if err != nil {
+		return fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	fmt.Println()
+	for _, d := range letters {
+		fmt.Printf("%d  %s from %s, failed %s after %d attempt(s): %s\n",
+			d.ID, d.Message.SessionKey(), d.Message.SenderID, d.FailedAt.Format(time.DateTime), d.Attempts, d.Reason)
+		content := strings.TrimSpace(d.Message.Content)
+		if len(content) > 80 {
+			content = content[:77] + "..."
+		}
+		fmt.Printf("    %q\n", content)
+	}
+	fmt.Println("\nRun 'ubot queue retry [id...]' or 'ubot queue purge [id...]'.")
+	return nil
+}
+
+func runQueueUpdate(args []string, retry bool) error {
+	ids := make([]int64, len(args))
+	for i, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid dead letter ID %q", arg)
+		}
+		ids[i] = id
+	}
+
+	journal, err := openQueue()
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	if retry {
+		n, err := journal.Retry(ids...)
+		if err != nil {
+			return fmt.Errorf("failed to requeue dead letters: %w", err)
+		}
+		fmt.Printf("Requeued %d dead letter(s)\n", n)
+		return nil
+	}
+	n, err := journal.Purge(ids...)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letters: %w", err)
+	}
+	fmt.Printf("Deleted %d dead letter(s)\n", n)
+	return nil
+}
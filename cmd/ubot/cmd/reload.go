@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/hkuds/ubot/internal/channels"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/control"
+	"github.com/hkuds/ubot/internal/providers"
+	"github.com/hkuds/ubot/internal/stats"
+	"github.com/hkuds/ubot/internal/tools"
+	"github.com/hkuds/ubot/internal/tracing"
+)
+
+// setting is a part of the config compared between reloads.
+type setting struct {
+	name string
+	get  func(*config.Config) interface{}
+}
+
+// reloadableSettings apply to the running gateway when the config is
+// reloaded. Channel changes are reported by the channel manager.
+var reloadableSettings = []setting{
+	{"agents.defaults", func(c *config.Config) interface{} { return c.Agents.Defaults }},
+	{"agents.routing", func(c *config.Config) interface{} { return c.Agents.Routing }},
+	{"providers", func(c *config.Config) interface{} { return c.Providers }},
+	{"prompts", func(c *config.Config) interface{} { return c.Prompts }},
+	{"tools.web", func(c *config.Config) interface{} { return c.Tools.Web }},
+	{"tools.exec", func(c *config.Config) interface{} { return c.Tools.Exec }},
+	{"tools.code", func(c *config.Config) interface{} { return c.Tools.Code }},
+	{"tools.calendar", func(c *config.Config) interface{} { return c.Tools.Calendar }},
+	{"tools.approval", func(c *config.Config) interface{} { return c.Tools.Approval }},
+	{"tools.audit", func(c *config.Config) interface{} { return c.Tools.Audit }},
+	{"tools.results", func(c *config.Config) interface{} { return c.Tools.Results }},
+	{"tools.parallel", func(c *config.Config) interface{} { return c.Tools.Parallel }},
+	{"tools.skills", func(c *config.Config) interface{} { return c.Tools.Skills }},
+}
+
+// restartSettings are read only when the gateway starts.
+var restartSettings = []setting{
+	{"agents.briefing", func(c *config.Config) interface{} { return c.Agents.Briefing }},
+	{"agents.defaults.workspace", func(c *config.Config) interface{} { return c.Agents.Defaults.Workspace }},
+	{"channels.whatsapp", func(c *config.Config) interface{} { return c.Channels.WhatsApp }},
+	{"channels.admin", func(c *config.Config) interface{} { return c.Channels.Admin }},
+	{"gateway", func(c *config.Config) interface{} { return c.Gateway }},
+	{"mcp", func(c *config.Config) interface{} { return c.MCP }},
+	{"cluster", func(c *config.Config) interface{} { return c.Cluster }},
+	{"bus", func(c *config.Config) interface{} { return c.Bus }},
+	{"stats", func(c *config.Config) interface{} { return c.Stats }},
+	{"tracing", func(c *config.Config) interface{} { return c.Tracing }},
+	{"skills", func(c *config.Config) interface{} { return c.Skills }},
+	{"session", func(c *config.Config) interface{} { return c.Session }},
+	{"security", func(c *config.Config) interface{} { return c.Security }},
+	{"tools.browser", func(c *config.Config) interface{} { return c.Tools.Browser }},
+	{"tools.approval.timeout", func(c *config.Config) interface{} { return c.Tools.Approval.Timeout }},
+}
+
+// changedSettings returns the names of the settings that differ between
+// old and cfg.
+func changedSettings(settings []setting, old, cfg *config.Config) []string {
+	var names []string
+	for _, s := range settings {
+		if !reflect.DeepEqual(s.get(old), s.get(cfg)) {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+// liveGateway holds the parts of a running gateway that follow the config
+// file, so that changes apply without a restart. Each message is handled
+// with the config and tools current when it arrives.
+type liveGateway struct {
+	mu       sync.RWMutex
+	cfg      *config.Config
+	registry *tools.SecureRegistry
+
+	reloadMu sync.Mutex // one reload at a time
+	path     string     // config file; empty for the default
+	provider *providers.Switchable
+	signIn   providers.CopilotSignIn
+	recorder *stats.Recorder // nil when statistics are off
+	tools    *tools.ToolRegistry
+	env      *tools.SessionEnv
+	channels *channels.Manager // nil when the gateway runs no channels
+}
+
+// newGatewayProvider creates the configured provider, logging its traffic
+// when the traffic log is on, tracing its calls, recording them when
+// statistics are collected, answering repeated requests from the response
+// cache when it is enabled and routing them to the models configured per
+// phase. Copilot runs signIn when its token
+// stops working.
+func newGatewayProvider(cfg *config.Config, recorder *stats.Recorder, signIn providers.CopilotSignIn) (providers.Provider, error) {
+	provider, err := providers.NewProviderFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	if copilot, ok := provider.(*providers.CopilotProvider); ok {
+		copilot.SetSignIn(signIn)
+	}
+	provider = providers.WithTrafficLog(provider, cfg.Providers.Log, cfg.TrafficLogDir())
+	provider = tracing.WrapProvider(provider)
+	if recorder != nil && cfg.Stats.Tracks(config.StatsTrackModels) {
+		provider = stats.WrapProvider(provider, recorder)
+	}
+	provider = providers.WithCache(provider, cfg.Providers.Cache)
+	return providers.NewRoutingProvider(provider, cfg.Agents, requestChannel), nil
+}
+
+// requestChannel returns the channel of the conversation a request is
+// made for, for the per-channel model routes.
+func requestChannel(ctx context.Context) string {
+	info, _ := tools.RequestFromContext(ctx)
+	return info.Channel
+}
+
+// newGatewayRegistry wraps registry with the security middleware,
+// recording tool calls when statistics are collected.
+func newGatewayRegistry(registry *tools.ToolRegistry, cfg *config.Config, recorder *stats.Recorder) *tools.SecureRegistry {
+	secureReg := newSecureRegistry(registry, cfg)
+	if recorder != nil && cfg.Stats.Tracks(config.StatsTrackTools) {
+		secureReg.SetObserver(recorder)
+	}
+	return secureReg
+}
+
+// current returns the config and tools to handle a message with.
+func (g *liveGateway) current() (*config.Config, *tools.SecureRegistry) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.cfg, g.registry
+}
+
+// Reload reads the config file again and applies it. An invalid config is
+// rejected and the running one kept.
+func (g *liveGateway) Reload() (control.ReloadResult, error) {
+	g.reloadMu.Lock()
+	defer g.reloadMu.Unlock()
+
+	var result control.ReloadResult
+	cfg, err := config.LoadConfig(g.path)
+	if err != nil {
+		return result, fmt.Errorf("keeping the running config: %w", err)
+	}
+	old, _ := g.current()
+	if reflect.DeepEqual(old, cfg) {
+		return result, nil
+	}
+	result.Applied = changedSettings(reloadableSettings, old, cfg)
+	result.NeedsRestart = changedSettings(restartSettings, old, cfg)
+
+	if !reflect.DeepEqual(old.Providers, cfg.Providers) || !reflect.DeepEqual(old.Agents.Routing, cfg.Agents.Routing) ||
+		old.Agents.Defaults.Model != cfg.Agents.Defaults.Model {
+		provider, err := newGatewayProvider(cfg, g.recorder, g.signIn)
+		if err != nil {
+			return control.ReloadResult{}, fmt.Errorf("keeping the running config: %w", err)
+		}
+		g.provider.Switch(provider)
+	}
+	// Tool settings and the capabilities the agent is told about
+	registerConfiguredTools(g.tools, cfg, g.env)
+	registry := newGatewayRegistry(g.tools, cfg, g.recorder)
+
+	var channelErr error
+	if g.channels != nil {
+		changed, err := g.channels.Reconfigure(cfg)
+		for _, name := range changed {
+			result.Applied = append(result.Applied, "channels."+name)
+		}
+		if err != nil {
+			channelErr = fmt.Errorf("config applied, but channels failed: %w", err)
+		}
+	}
+
+	g.mu.Lock()
+	g.cfg, g.registry = cfg, registry
+	g.mu.Unlock()
+	return result, channelErr
+}
+
+// reloadAndLog reloads the config and logs the outcome; reason says what
+// asked for the reload.
+func (g *liveGateway) reloadAndLog(reason string) {
+	result, err := g.Reload()
+	if err != nil {
+		log.Printf("Warning: config reload (%s): %v", reason, err)
+	}
+	if len(result.Applied) > 0 {
+		log.Printf("Config reloaded (%s): %s", reason, strings.Join(result.Applied, ", "))
+	}
+	if len(result.NeedsRestart) > 0 {
+		log.Printf("Warning: restart the gateway to apply: %s", strings.Join(result.NeedsRestart, ", "))
+	}
+}
@@ -20,8 +20,21 @@ func init() {
 	rootCmd.AddCommand(setupCmd)
 	rootCmd.AddCommand(agentCmd)
 	rootCmd.AddCommand(gatewayCmd)
+	rootCmd.AddCommand(serviceCmd)
+	rootCmd.AddCommand(healthcheckCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(selfUpdateCmd)
 	rootCmd.AddCommand(skillsCmd)
 	rootCmd.AddCommand(rootchatCmd)
+	rootCmd.AddCommand(cronCmd)
+	rootCmd.AddCommand(calendarCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(accessCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(sessionsCmd)
+	rootCmd.AddCommand(queueCmd)
+	rootCmd.AddCommand(promptsCmd)
 }
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/control"
+	"github.com/hkuds/ubot/internal/update"
+	"github.com/spf13/cobra"
+)
+
+var (
+	selfUpdateCheck   bool
+	selfUpdateVersion string
+	selfUpdateForce   bool
+	selfUpdateRestart bool
+)
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Replace ubot with the latest release",
+	Long:  "Download the latest release binary for this platform from GitHub, verify it against the release checksums (and their signature, when this build knows the release signing key) and atomically replace the running binary. A gateway started with --supervised picks up the new binary when it restarts.",
+	Args:  cobra.NoArgs,
+	RunE:  runSelfUpdate,
+}
+
+func init() {
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheck, "check", false, "only report whether a newer release exists")
+	selfUpdateCmd.Flags().StringVar(&selfUpdateVersion, "version", "", "install this release instead of the latest, e.g. v0.2.0")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateForce, "force", false, "install even if the release is not newer")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateRestart, "restart", false, "restart the running gateway afterwards (needs gateway.controlApi and --supervised)")
+}
+
+func runSelfUpdate(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat("/.dockerenv"); err == nil && !selfUpdateCheck {
+		return fmt.Errorf("ubot runs in Docker; pull or rebuild the image instead")
+	}
+
+	key, err := update.ParsePublicKey(UpdatePublicKey)
+	if err != nil {
+		return err
+	}
+	updater := &update.Updater{PublicKey: key}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var rel *update.Release
+	if selfUpdateVersion != "" {
+		rel, err = updater.Release(ctx, selfUpdateVersion)
+	} else {
+		rel, err = updater.Latest(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	newer := update.Newer(rel.Version, Version)
+	if selfUpdateCheck {
+		if newer {
+			fmt.Printf("uBot %s is available (this is %s). Run 'ubot self-update' to install it.\n", rel.Version, Version)
+		} else {
+			fmt.Printf("uBot %s is up to date.\n", Version)
+		}
+		return nil
+	}
+	if !newer && selfUpdateVersion == "" && !selfUpdateForce {
+		fmt.Printf("uBot %s is up to date.\n", Version)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the ubot binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	if key == nil {
+		fmt.Println("Warning: this build has no release signing key; only checksums are verified.")
+	}
+	fmt.Printf("Installing uBot %s to %s...\n", rel.Version, exe)
+	if err := updater.Install(ctx, rel, exe); err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+	fmt.Printf("Updated uBot %s -> %s\n", Version, rel.Version)
+
+	if !selfUpdateRestart {
+		fmt.Println("Restart the gateway to run the new version.")
+		return nil
+	}
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.Gateway.ControlAPI {
+		return fmt.Errorf("cannot restart the gateway: the control API is disabled (set gateway.controlApi to true)")
+	}
+	client := control.NewClient(cfg.Gateway.Addr(), cfg.Gateway.Token)
+	if err := client.Restart(ctx); err != nil {
+		return fmt.Errorf("failed to restart the gateway: %w", err)
+	}
+	fmt.Println("Gateway is restarting.")
+	return nil
+}
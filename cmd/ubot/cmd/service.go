@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/service"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serviceLog          string
+	serviceRestart      string
+	serviceRestartDelay int
+	servicePrint        bool
+	serviceNoStart      bool
+)
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Run the gateway as a background service",
+	Long:  "Install, remove or inspect a service running 'ubot gateway' for the current user: a systemd user unit on Linux or a launchd agent on macOS.",
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install and start the gateway service",
+	Long:  "Write a systemd user unit (~/.config/systemd/user/ubot.service) or a launchd agent (~/Library/LaunchAgents/com.ubot.gateway.plist) running this binary as 'ubot gateway', enable it at login and start it. Running it again replaces the service with the new settings.",
+	Args:  cobra.NoArgs,
+	RunE:  runServiceInstall,
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop and remove the gateway service",
+	Args:  cobra.NoArgs,
+	RunE:  runServiceUninstall,
+}
+
+var serviceStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the gateway service is installed and running",
+	Args:  cobra.NoArgs,
+	RunE:  runServiceStatus,
+}
+
+func init() {
+	serviceInstallCmd.Flags().StringVar(&serviceLog, "log", "", "file receiving the gateway's output (default: the journal on Linux, ~/.ubot/logs/gateway.log on macOS)")
+	serviceInstallCmd.Flags().StringVar(&serviceRestart, "restart", service.RestartOnFailure, "restart policy: always, on-failure or no")
+	serviceInstallCmd.Flags().IntVar(&serviceRestartDelay, "restart-delay", 5, "seconds to wait before restarting")
+	serviceInstallCmd.Flags().BoolVar(&servicePrint, "print", false, "print the unit or plist instead of installing it")
+	serviceInstallCmd.Flags().BoolVar(&serviceNoStart, "no-start", false, "write the service file without enabling or starting it")
+	serviceCmd.AddCommand(serviceInstallCmd)
+	serviceCmd.AddCommand(serviceUninstallCmd)
+	serviceCmd.AddCommand(serviceStatusCmd)
+}
+
+// serviceHome returns the home directory services are installed under,
+// and fails on platforms without a supported service manager.
+func serviceHome() (string, error) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return "", fmt.Errorf("ubot service supports systemd (Linux) and launchd (macOS), not %s", runtime.GOOS)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the home directory: %w", err)
+	}
+	return home, nil
+}
+
+// serviceSpec describes the gateway service for this binary.
+func serviceSpec(home string) (service.Spec, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return service.Spec{}, fmt.Errorf("failed to locate the ubot binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	// Let the gateway stop gracefully: drain, flush replies, then wind down
+	stopTimeout := 30*time.Second + shutdownFlushTimeout + 10*time.Second
+	if cfg, err := config.LoadConfig(""); err == nil {
+		stopTimeout = cfg.Gateway.DrainDeadline() + shutdownFlushTimeout + 10*time.Second
+	}
+
+	spec := service.Spec{
+		Executable:   exe,
+		Args:         []string{"gateway"},
+		WorkingDir:   home,
+		Env:          map[string]string{},
+		LogPath:      serviceLog,
+		Restart:      serviceRestart,
+		RestartDelay: time.Duration(serviceRestartDelay) * time.Second,
+		StopTimeout:  stopTimeout,
+	}
+	if err := spec.Validate(); err != nil {
+		return service.Spec{}, err
+	}
+	// Service managers start jobs with a minimal PATH, which would hide
+	// docker, git and the other tools the agent runs
+	if path := os.Getenv("PATH"); path != "" {
+		spec.Env["PATH"] = path
+	}
+	// The service manager restarts the gateway, so manage_ubot restart can
+	// simply exit
+	if spec.Restart != service.RestartNever {
+		spec.Env[supervisedEnv] = "1"
+		spec.RestartExitCode = restartExitCode
+	}
+	if spec.LogPath == "" && runtime.GOOS == "darwin" {
+		spec.LogPath = filepath.Join(config.GetConfigDir(), "logs", "gateway.log")
+	}
+	if spec.LogPath != "" {
+		if spec.LogPath, err = filepath.Abs(spec.LogPath); err != nil {
+			return service.Spec{}, err
+		}
+	}
+	return spec, nil
+}
+
+func runServiceInstall(cmd *cobra.Command, args []string) error {
+	home, err := serviceHome()
+	if err != nil {
+		return err
+	}
+	spec, err := serviceSpec(home)
+	if err != nil {
+		return err
+	}
+
+	path, content := service.SystemdPath(home), service.SystemdUnit(spec)
+	if runtime.GOOS == "darwin" {
+		path, content = service.LaunchdPath(home), service.LaunchdPlist(spec)
+	}
+	if servicePrint {
+		fmt.Print(content)
+		return nil
+	}
+
+	if spec.LogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(spec.LogPath), 0o755); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+	if serviceNoStart {
+		return nil
+	}
+
+	if runtime.GOOS == "darwin" {
+		// Replace a loaded agent so the new settings apply
+		_ = exec.Command("launchctl", "bootout", launchdTarget()).Run()
+		if err := runServiceManager("launchctl", "bootstrap", launchdDomain(), path); err != nil {
+			return err
+		}
+		fmt.Printf("Service started. Logs: %s\n", spec.LogPath)
+		return nil
+	}
+
+	if err := runServiceManager("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	if err := runServiceManager("systemctl", "--user", "enable", service.Name); err != nil {
+		return err
+	}
+	if err := runServiceManager("systemctl", "--user", "restart", service.Name); err != nil {
+		return err
+	}
+	if spec.LogPath != "" {
+		fmt.Printf("Service started. Logs: %s\n", spec.LogPath)
+	} else {
+		fmt.Printf("Service started. Logs: journalctl --user -u %s -f\n", service.Name)
+	}
+	if !lingering() {
+		fmt.Println("User services stop when you log out. To keep the gateway running, run: sudo loginctl enable-linger $USER")
+	}
+	return nil
+}
+
+func runServiceUninstall(cmd *cobra.Command, args []string) error {
+	home, err := serviceHome()
+	if err != nil {
+		return err
+	}
+
+	path := service.SystemdPath(home)
+	if runtime.GOOS == "darwin" {
+		path = service.LaunchdPath(home)
+		_ = exec.Command("launchctl", "bootout", launchdTarget()).Run()
+	} else {
+		_ = exec.Command("systemctl", "--user", "disable", "--now", service.Name).Run()
+	}
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Println("The gateway service is not installed.")
+			return nil
+		}
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	if runtime.GOOS == "linux" {
+		_ = exec.Command("systemctl", "--user", "daemon-reload").Run()
+	}
+	fmt.Printf("Stopped the gateway service and removed %s\n", path)
+	return nil
+}
+
+func runServiceStatus(cmd *cobra.Command, args []string) error {
+	home, err := serviceHome()
+	if err != nil {
+		return err
+	}
+
+	path := service.SystemdPath(home)
+	if runtime.GOOS == "darwin" {
+		path = service.LaunchdPath(home)
+	}
+	if _, err := os.Stat(path); err != nil {
+		fmt.Println("The gateway service is not installed. Run 'ubot service install'.")
+		return nil
+	}
+	fmt.Printf("Installed: %s\n", path)
+
+	if runtime.GOOS == "darwin" {
+		out, err := exec.Command("launchctl", "print", launchdTarget()).Output()
+		if err != nil {
+			fmt.Println("State: not loaded")
+			return nil
+		}
+		// launchctl print is long; show the lines that matter
+		for _, line := range strings.Split(string(out), "\n") {
+			line = strings.TrimSpace(line)
+			for _, key := range []string{"state = ", "pid = ", "last exit code = ", "runs = "} {
+				if strings.HasPrefix(line, key) {
+					fmt.Println(line)
+				}
+			}
+		}
+		return nil
+	}
+
+	// systemctl status exits non-zero for stopped units; its output says why
+	status := exec.Command("systemctl", "--user", "status", service.Name, "--no-pager")
+	status.Stdout = os.Stdout
+	status.Stderr = os.Stderr
+	_ = status.Run()
+	if !lingering() {
+		fmt.Println("\nLinger is off: the gateway stops when you log out (sudo loginctl enable-linger $USER).")
+	}
+	return nil
+}
+
+// runServiceManager runs a systemctl or launchctl command, passing its
+// output through.
+func runServiceManager(name string, args ...string) error {
+	c := exec.Command(name, args...)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// launchdDomain returns the launchd domain of the logged-in user.
+func launchdDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+// launchdTarget returns the launchd service target of the gateway agent.
+func launchdTarget() string {
+	return launchdDomain() + "/" + service.Label
+}
+
+// lingering reports whether systemd keeps the user's services running
+// after they log out.
+func lingering() bool {
+	u, err := user.Current()
+	if err != nil {
+		return true
+	}
+	_, err = os.Stat(filepath.Join("/var/lib/systemd/linger", u.Username))
+	return err == nil
+}
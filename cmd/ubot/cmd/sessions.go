@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List, export and import conversations",
+	Long:  "Back up, share or move conversations between machines. Exports include tool calls and pins.",
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored conversations",
+	Long:  "List conversations, most recently active first, optionally only those of one channel or active in a date range.",
+	RunE:  runSessionsList,
+}
+
+var sessionsSearchCmd = &cobra.Command{
+	Use:   "search <words...>",
+	Short: "Search past conversations",
+	Long:  "Find messages containing all the given words. With \"session\": {\"store\": \"sqlite\"} in config this includes cleared history; otherwise only the history each conversation keeps is searched.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runSessionsSearch,
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export <key>",
+	Short: "Export a conversation as JSON or a Markdown transcript",
+	Long:  "Write a conversation (e.g. telegram:123456) to stdout or a file. JSON exports can be re-imported; Markdown is a readable transcript.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSessionsExport,
+}
+
+var sessionsImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a conversation from a JSON export",
+	Long:  "Restore a conversation exported with 'ubot sessions export'. Use '-' to read from stdin.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSessionsImport,
+}
+
+var (
+	sessionsChannel      string
+	sessionsSince        string
+	sessionsUntil        string
+	sessionsSearchLimit  int
+	sessionsExportFormat string
+	sessionsExportOutput string
+	sessionsImportKey    string
+	sessionsImportForce  bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{sessionsListCmd, sessionsSearchCmd} {
+		c.Flags().StringVar(&sessionsChannel, "channel", "", "only this channel, e.g. telegram")
+		c.Flags().StringVar(&sessionsSince, "since", "", "only from this date on (YYYY-MM-DD)")
+		c.Flags().StringVar(&sessionsUntil, "until", "", "only before this date (YYYY-MM-DD)")
+	}
+	sessionsSearchCmd.Flags().IntVarP(&sessionsSearchLimit, "limit", "n", 20, "maximum number of results")
+	sessionsExportCmd.Flags().StringVarP(&sessionsExportFormat, "format", "f", "json", "output format: json or markdown")
+	sessionsExportCmd.Flags().StringVarP(&sessionsExportOutput, "output", "o", "", "write to this file instead of stdout")
+	sessionsImportCmd.Flags().StringVar(&sessionsImportKey, "key", "", "store under this key instead of the exported one")
+	sessionsImportCmd.Flags().BoolVar(&sessionsImportForce, "force", false, "replace an existing conversation with the same key")
+
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsSearchCmd)
+	sessionsCmd.AddCommand(sessionsExportCmd)
+	sessionsCmd.AddCommand(sessionsImportCmd)
+}
+
+// newSessionManager creates the session manager for the configured store.
+// The first time the SQLite store is used, existing session files are
+// copied into it. The returned function closes the store.
+func newSessionManager(cfg *config.Config) (*session.Manager, func(), error) {
+	mgr := session.NewManager(cfg.WorkspacePath())
+	if !cfg.Session.UsesSQLite() {
+		return mgr, func() {}, nil
+	}
+
+	dbPath := cfg.SessionDBPath()
+	_, statErr := os.Stat(dbPath)
+	store, err := session.OpenSQLite(dbPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	mgr.SetStore(store)
+	mgr.DisableFiles()
+
+	if os.IsNotExist(statErr) {
+		n, err := mgr.MigrateFiles()
+		if err != nil {
+			log.Printf("Warning: failed to copy session files into %s: %v", dbPath, err)
+		} else if n > 0 {
+			log.Printf("Copied %d session file(s) into %s", n, dbPath)
+		}
+	}
+	return mgr, func() { store.Close() }, nil
+}
+
+// loadSessions returns the session manager for the configured workspace
+// and store.
+func loadSessions() (*session.Manager, func(), error) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return newSessionManager(cfg)
+}
+
+// sessionsFilter builds the listing filter from the command flags.
+func sessionsFilter() (session.Filter, error) {
+	filter := session.Filter{Channel: sessionsChannel}
+	for _, f := range []struct {
+		value string
+		dst   *time.Time
+	}{{sessionsSince, &filter.Since}, {sessionsUntil, &filter.Until}} {
+		if f.value == "" {
+			continue
+		}
+		t, err := time.ParseInLocation(time.DateOnly, f.value, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", f.value)
+		}
+		*f.dst = t
+	}
+	return filter, nil
+}
+
+func runSessionsList(cmd *cobra.Command, args []string) error {
+	filter, err := sessionsFilter()
+	if err != nil {
+		return err
+	}
+	mgr, closeSessions, err := loadSessions()
+	if err != nil {
+		return err
+	}
+	defer closeSessions()
+
+	infos, err := mgr.ListFiltered(filter)
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		fmt.Println("No conversations stored.")
+		return nil
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].UpdatedAt.After(infos[j].UpdatedAt) })
+	for _, info := range infos {
+		fmt.Printf("%-32s %4d messages  updated %s\n", info.Key, info.MessageCount, info.UpdatedAt.Format(time.DateTime))
+	}
+	return nil
+}
+
+func runSessionsSearch(cmd *cobra.Command, args []string) error {
+	filter, err := sessionsFilter()
+	if err != nil {
+		return err
+	}
+	mgr, closeSessions, err := loadSessions()
+	if err != nil {
+		return err
+	}
+	defer closeSessions()
+
+	hits, err := mgr.Search(strings.Join(args, " "), filter, sessionsSearchLimit)
+	if err != nil {
+		return err
+	}
+	if len(hits) == 0 {
+		fmt.Println("No matching messages.")
+		return nil
+	}
+	for _, hit := range hits {
+		fmt.Printf("%s  %s  %s\n    %s\n", hit.Timestamp.Format(time.DateTime), hit.Key, hit.Role,
+			strings.ReplaceAll(hit.Snippet, "\n", " "))
+	}
+	return nil
+}
+
+func runSessionsExport(cmd *cobra.Command, args []string) error {
+	if sessionsExportFormat != "json" && sessionsExportFormat != "markdown" && sessionsExportFormat != "md" {
+		return fmt.Errorf("unknown format %q (use json or markdown)", sessionsExportFormat)
+	}
+
+	mgr, closeSessions, err := loadSessions()
+	if err != nil {
+		return err
+	}
+	defer closeSessions()
+	exp, err := mgr.Export(args[0])
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if sessionsExportOutput != "" {
+		f, err := os.OpenFile(sessionsExportOutput, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", sessionsExportOutput, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if sessionsExportFormat == "json" {
+		err = exp.WriteJSON(out)
+	} else {
+		_, err = io.WriteString(out, exp.Markdown())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if sessionsExportOutput != "" {
+		fmt.Printf("Exported %d messages to %s\n", len(exp.Messages), sessionsExportOutput)
+	}
+	return nil
+}
+
+func runSessionsImport(cmd *cobra.Command, args []string) error {
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", args[0], err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	exp, err := session.ReadExport(in)
+	if err != nil {
+		return err
+	}
+	mgr, closeSessions, err := loadSessions()
+	if err != nil {
+		return err
+	}
+	defer closeSessions()
+	sess, err := mgr.Import(exp, sessionsImportKey, sessionsImportForce)
+	if errors.Is(err, session.ErrSessionExists) {
+		return fmt.Errorf("%w (use --force to replace it)", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d messages as %s\n", sess.MessageCount(), sess.Key)
+	return nil
+}
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/sandbox"
 	"github.com/hkuds/ubot/internal/skills"
 	"github.com/spf13/cobra"
 )
@@ -12,186 +19,425 @@ import (
 var skillsCmd = &cobra.Command{
 	Use:   "skills",
 	Short: "Manage skills",
-	Long:  "List, install, uninstall, and inspect skills for uBot.",
+	Long: `List, search, install, uninstall, and inspect skills for uBot.
+
+The commands never prompt, so they can be used in scripts: progress goes to
+stderr, list, search and info print JSON with --json, and --no-fetch works
+from the cached repositories without network access.`,
 }
 
 var skillsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List installed and available skills",
-	Long:  "Show skills installed locally and available from the remote repository.",
+	Long:  "Show skills installed locally and available from the remote repository as a table.",
+	Args:  cobra.NoArgs,
 	RunE:  runSkillsList,
 }
 
+var skillsSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search available skills",
+	Long:  "Show the available skills whose name, title, description or category contains every word of the query.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runSkillsSearch,
+}
+
+var (
+	skillsNoFetch       bool
+	skillsJSON          bool
+	skillsListInstalled bool
+)
+
 var skillsInstallCmd = &cobra.Command{
-	Use:   "install <name>",
-	Short: "Install a skill from the remote repository",
-	Long:  "Download and install a skill from the remote skills repository.",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runSkillsInstall,
+	Use:   "install <name>...",
+	Short: "Install skills from the remote repository",
+	Long: `Download and install skills from the remote skills repository.
+
+Skills may declare requirements (binaries, or Python packages as pip:<name>)
+and setup scripts in their frontmatter. Installation stops if requirements
+are missing unless --ignore-missing is given. Setup scripts run in a Docker
+sandbox with the skill directory mounted at /skill; without Docker they are
+listed for you to review and run yourself.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSkillsInstall,
 }
 
+var (
+	skillsInstallIgnoreMissing bool
+	skillsInstallNoScripts     bool
+)
+
 var skillsUninstallCmd = &cobra.Command{
-	Use:   "uninstall <name>",
-	Short: "Remove an installed skill",
-	Long:  "Uninstall a skill from the local workspace.",
-	Args:  cobra.ExactArgs(1),
+	Use:   "uninstall <name>...",
+	Short: "Remove installed skills",
+	Long:  "Uninstall skills from the local workspace.",
+	Args:  cobra.MinimumNArgs(1),
 	RunE:  runSkillsUninstall,
 }
 
 var skillsInfoCmd = &cobra.Command{
 	Use:   "info <name>",
 	Short: "Show details of a skill",
-	Long:  "Display the title, description, and tools of a skill.",
+	Long:  "Display the title, description, tools, requirements and setup scripts of a skill.",
 	Args:  cobra.ExactArgs(1),
 	RunE:  runSkillsInfo,
 }
 
+var skillsUpgradeCmd = &cobra.Command{
+	Use:   "upgrade [name...]",
+	Short: "Update installed skills to the latest version",
+	Long:  "Fetch the skills repository, report which installed skills are outdated and update them. Skills edited locally are only overwritten with --force.",
+	RunE:  runSkillsUpgrade,
+}
+
+var (
+	skillsUpgradeCheck bool
+	skillsUpgradeForce bool
+)
+
 func init() {
 	skillsCmd.AddCommand(skillsListCmd)
+	skillsCmd.AddCommand(skillsSearchCmd)
 	skillsCmd.AddCommand(skillsInstallCmd)
 	skillsCmd.AddCommand(skillsUninstallCmd)
 	skillsCmd.AddCommand(skillsInfoCmd)
+	skillsCmd.AddCommand(skillsUpgradeCmd)
+
+	skillsCmd.PersistentFlags().BoolVar(&skillsNoFetch, "no-fetch", false, "Use the cached repositories without fetching")
+	for _, c := range []*cobra.Command{skillsListCmd, skillsSearchCmd, skillsInfoCmd} {
+		c.Flags().BoolVar(&skillsJSON, "json", false, "Print JSON instead of text")
+	}
+	skillsListCmd.Flags().BoolVar(&skillsListInstalled, "installed", false, "Only list installed skills")
+
+	skillsInstallCmd.Flags().BoolVar(&skillsInstallIgnoreMissing, "ignore-missing", false, "Install even if requirements are not met")
+	skillsInstallCmd.Flags().BoolVar(&skillsInstallNoScripts, "no-scripts", false, "Do not run setup scripts")
+
+	skillsUpgradeCmd.Flags().BoolVar(&skillsUpgradeCheck, "check", false, "Only report outdated skills")
+	skillsUpgradeCmd.Flags().BoolVar(&skillsUpgradeForce, "force", false, "Overwrite skills that were edited locally")
 }
 
-// loadConfigAndPaths loads config and returns configDir and workspacePath.
-func loadConfigAndPaths() (string, string, error) {
+// loadSkillsManager loads config and returns workspacePath and a skills
+// manager for the configured repositories.
+func loadSkillsManager() (string, *skills.Manager, error) {
 	cfg, err := config.LoadConfig("")
 	if err != nil {
-		return "", "", fmt.Errorf("failed to load config: %w", err)
+		return "", nil, fmt.Errorf("failed to load config: %w", err)
 	}
-	configDir := config.GetConfigDir()
-	workspacePath := cfg.WorkspacePath()
-	return configDir, workspacePath, nil
+	return cfg.WorkspacePath(), newSkillsManager(cfg), nil
+}
+
+// newSkillsManager returns a skills manager for the repositories in
+// skills.repos, or the default repository when none are configured.
+func newSkillsManager(cfg *config.Config) *skills.Manager {
+	mgr := skills.NewManager(config.GetConfigDir(), cfg.WorkspacePath())
+	if len(cfg.Skills.Repos) > 0 {
+		var sources []skills.Source
+		for _, r := range cfg.Skills.EnabledRepos() {
+			sources = append(sources, skills.Source{Name: r.Name, URL: r.URL, Path: r.Path})
+		}
+		mgr.SetSources(sources)
+	}
+	return mgr
+}
+
+// skillRow is one skill in list and search output.
+type skillRow struct {
+	Name        string `json:"name"`
+	Category    string `json:"category,omitempty"`
+	Repo        string `json:"repo,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Installed   bool   `json:"installed"`
+	Description string `json:"description,omitempty"`
+}
+
+// discoverAvailable fetches the skills repositories, unless --no-fetch is
+// given, and discovers the skills in them. Progress goes to stderr so that
+// JSON output stays clean.
+func discoverAvailable(mgr *skills.Manager) error {
+	if !skillsNoFetch {
+		fmt.Fprintln(os.Stderr, "Fetching skills repository...")
+		if _, err := mgr.EnsureRepo(); err != nil {
+			// Other repositories may still have been fetched
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if err := mgr.DiscoverAvailable(); err != nil {
+		return fmt.Errorf("failed to discover available skills: %w", err)
+	}
+	return nil
 }
 
 func runSkillsList(cmd *cobra.Command, args []string) error {
-	configDir, workspacePath, err := loadConfigAndPaths()
+	workspacePath, mgr, err := loadSkillsManager()
 	if err != nil {
 		return err
 	}
 
-	// List installed skills
 	loader := skills.NewLoader(workspacePath)
 	if err := loader.Discover(); err != nil {
 		return fmt.Errorf("failed to discover installed skills: %w", err)
 	}
 
-	installed := loader.List()
-	fmt.Println("Installed skills:")
-	if len(installed) == 0 {
-		fmt.Println("  (none)")
-	} else {
-		for _, name := range installed {
-			s := loader.Get(name)
-			if s != nil && s.Description != "" {
-				desc := s.Description
-				if len(desc) > 60 {
-					desc = desc[:57] + "..."
-				}
-				fmt.Printf("  - %s: %s\n", name, desc)
-			} else {
-				fmt.Printf("  - %s\n", name)
-			}
+	// Available skills add categories to installed ones; with --installed
+	// only an existing cache is read
+	if !skillsListInstalled {
+		if err := discoverAvailable(mgr); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
+	} else if mgr.IsCached() {
+		_ = mgr.DiscoverAvailable()
 	}
 
-	// List available skills from remote
-	fmt.Println()
-	fmt.Println("Available skills (remote):")
+	var rows []skillRow
+	for _, name := range loader.List() {
+		if mgr.GetAvailable(name) != nil {
+			continue
+		}
+		row := skillRow{Name: name, Installed: true}
+		if s := loader.Get(name); s != nil {
+			row.Version, row.Description = s.Version, s.Description
+		}
+		rows = append(rows, row)
+	}
+	for _, a := range mgr.ListAvailable() {
+		installed := mgr.IsInstalled(a.Name)
+		if skillsListInstalled && !installed {
+			continue
+		}
+		rows = append(rows, availableRow(a, installed))
+	}
+	return printSkillRows(rows)
+}
 
-	mgr := skills.NewManager(configDir, workspacePath)
-	if !mgr.IsCached() {
-		fmt.Println("  Fetching skills repository...")
+func runSkillsSearch(cmd *cobra.Command, args []string) error {
+	_, mgr, err := loadSkillsManager()
+	if err != nil {
+		return err
 	}
-	if _, err := mgr.EnsureRepo(); err != nil {
-		fmt.Printf("  (could not fetch remote repository: %v)\n", err)
-		return nil
+	if err := discoverAvailable(mgr); err != nil {
+		return err
 	}
-	if err := mgr.DiscoverAvailable(); err != nil {
-		fmt.Printf("  (could not discover available skills: %v)\n", err)
+
+	var rows []skillRow
+	for _, a := range mgr.Search(strings.Join(args, " ")) {
+		rows = append(rows, availableRow(a, mgr.IsInstalled(a.Name)))
+	}
+	return printSkillRows(rows)
+}
+
+func availableRow(a *skills.AvailableSkill, installed bool) skillRow {
+	return skillRow{
+		Name:        a.Name,
+		Category:    a.Category,
+		Repo:        a.Repo,
+		Version:     a.Version,
+		Installed:   installed,
+		Description: a.Description,
+	}
+}
+
+// printSkillRows prints rows as a table, or as JSON with --json.
+func printSkillRows(rows []skillRow) error {
+	if skillsJSON {
+		if rows == nil {
+			rows = []skillRow{}
+		}
+		return printJSON(rows)
+	}
+	if len(rows) == 0 {
+		fmt.Println("No skills found.")
 		return nil
 	}
 
-	available := mgr.ListAvailable()
-	if len(available) == 0 {
-		fmt.Println("  (none)")
-	} else {
-		for _, s := range available {
-			marker := ""
-			if mgr.IsInstalled(s.Name) {
-				marker = " [installed]"
-			}
-			desc := s.Description
-			if len(desc) > 60 {
-				desc = desc[:57] + "..."
-			}
-			if s.Category != "" {
-				fmt.Printf("  - %s (%s): %s%s\n", s.Name, s.Category, desc, marker)
-			} else {
-				fmt.Printf("  - %s: %s%s\n", s.Name, desc, marker)
-			}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCATEGORY\tVERSION\tSTATUS\tDESCRIPTION")
+	for _, r := range rows {
+		status := "available"
+		if r.Installed {
+			status = "installed"
 		}
+		desc := r.Description
+		if len(desc) > 60 {
+			desc = desc[:57] + "..."
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Name, orDash(r.Category), orDash(r.Version), status, desc)
 	}
+	return w.Flush()
+}
 
+// printJSON prints v as indented JSON.
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
 	return nil
 }
 
-func runSkillsInstall(cmd *cobra.Command, args []string) error {
-	name := args[0]
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
 
-	configDir, workspacePath, err := loadConfigAndPaths()
+func runSkillsInstall(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig("")
 	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	workspacePath, mgr := cfg.WorkspacePath(), newSkillsManager(cfg)
+	if err := discoverAvailable(mgr); err != nil {
 		return err
 	}
 
-	mgr := skills.NewManager(configDir, workspacePath)
-
-	fmt.Printf("Fetching skills repository...\n")
-	if _, err := mgr.EnsureRepo(); err != nil {
-		return fmt.Errorf("failed to fetch skills repository: %w", err)
+	opts := skills.InstallOptions{IgnoreMissing: skillsInstallIgnoreMissing}
+	if !skillsInstallNoScripts {
+		opts.Scripts = sandboxScriptRunner(cfg.Skills)
 	}
-	if err := mgr.DiscoverAvailable(); err != nil {
-		return fmt.Errorf("failed to discover available skills: %w", err)
+	if len(args) == 1 {
+		return installSkill(mgr, workspacePath, args[0], opts)
 	}
+	failed := 0
+	for _, name := range args {
+		if err := installSkill(mgr, workspacePath, name, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d skills not installed", failed, len(args))
+	}
+	return nil
+}
 
+// installSkill installs one skill and reports what happened besides copying
+// it: setup script output, missing requirements and skipped scripts.
+func installSkill(mgr *skills.Manager, workspacePath, name string, opts skills.InstallOptions) error {
 	if mgr.GetAvailable(name) == nil {
 		return fmt.Errorf("skill %q not found in remote repository", name)
 	}
 
-	if err := mgr.Install(name); err != nil {
-		return fmt.Errorf("failed to install skill: %w", err)
+	report, err := mgr.InstallWith(context.Background(), name, opts)
+	var missing *skills.MissingError
+	if errors.As(err, &missing) {
+		return fmt.Errorf("%w\nInstall them and try again, or use --ignore-missing", err)
+	}
+	if report != nil {
+		for _, r := range report.Ran {
+			fmt.Printf("Ran setup script %s\n", r.Script)
+			if out := strings.TrimSpace(r.Output); out != "" {
+				fmt.Println(out)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to install skill %q: %w", name, err)
 	}
 
 	fmt.Printf("Skill %q installed successfully.\n", name)
+	if len(report.Missing) > 0 {
+		fmt.Printf("Warning: missing requirements: %s\n", strings.Join(report.Missing, ", "))
+	}
+	if len(report.Skipped) > 0 {
+		reason := "Docker is not available"
+		if skillsInstallNoScripts {
+			reason = "--no-scripts was given"
+		}
+		fmt.Printf("Setup scripts were not run because %s. Review them before running:\n", reason)
+		for _, script := range report.Skipped {
+			fmt.Printf("  %s\n", filepath.Join(workspacePath, "skills", name, script))
+		}
+	}
 	return nil
 }
 
-func runSkillsUninstall(cmd *cobra.Command, args []string) error {
-	name := args[0]
-
-	configDir, workspacePath, err := loadConfigAndPaths()
-	if err != nil {
-		return err
+// sandboxScriptRunner returns a runner for skill setup scripts that runs
+// each one in a fresh Docker container with the skill directory mounted at
+// /skill, or nil if Docker is not available, so scripts are never run
+// unsandboxed. Scripts reach only the hosts in skills.setupHosts, by
+// default the package registries.
+func sandboxScriptRunner(skillsCfg config.SkillsConfig) skills.ScriptRunner {
+	if !sandbox.IsDockerAvailable() {
+		return nil
 	}
+	return func(ctx context.Context, dir, script string) (string, error) {
+		// In a container, the daemon mounts the skill by its host path
+		hostPaths, err := sandbox.DetectHostPaths(ctx)
+		if err != nil {
+			return "", err
+		}
+		hosts, anyHost := skillsCfg.SetupNetwork(sandbox.RegistryHosts)
+		cfg := sandbox.DefaultConfig().
+			WithNetwork(anyHost).
+			WithAllowedHosts(hosts...).
+			WithWorkDir("/skill").
+			WithTimeout(skills.ScriptTimeout).
+			WithHostPaths(hostPaths).
+			AddMountPath(dir, "/skill", false)
+		exec, err := sandbox.NewExecutor(cfg)
+		if err != nil {
+			return "", err
+		}
+		if c, ok := exec.(interface{ Close() error }); ok {
+			defer c.Close()
+		}
+		if _, local := exec.(*sandbox.LocalExecutor); local {
+			return "", errors.New("could not start a Docker sandbox")
+		}
 
-	mgr := skills.NewManager(configDir, workspacePath)
-
-	if !mgr.IsInstalled(name) {
-		return fmt.Errorf("skill %q is not installed", name)
+		quoted := "'" + strings.ReplaceAll(script, "'", `'\''`) + "'"
+		command := []string{"sh", "-c", "sh " + quoted}
+		var result sandbox.Result
+		if runner, ok := exec.(sandbox.UsageExecutor); ok {
+			result, err = runner.Run(ctx, command, nil)
+		} else {
+			result.Stdout, result.Stderr, result.ExitCode, err = exec.Execute(ctx, command)
+		}
+		// Show what the script used, so limits can be tuned when it fails
+		out := result.Stdout + result.Stderr
+		if result.Usage.WallMs > 0 {
+			if out != "" && !strings.HasSuffix(out, "\n") {
+				out += "\n"
+			}
+			out += "[resources: " + result.Usage.String() + "]"
+		}
+		if err != nil {
+			return out, err
+		}
+		if result.ExitCode != 0 {
+			return out, fmt.Errorf("exit status %d", result.ExitCode)
+		}
+		return out, nil
 	}
+}
 
-	if err := mgr.Uninstall(name); err != nil {
-		return fmt.Errorf("failed to uninstall skill: %w", err)
+func runSkillsUninstall(cmd *cobra.Command, args []string) error {
+	_, mgr, err := loadSkillsManager()
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Skill %q uninstalled successfully.\n", name)
-	return nil
+	var errs []error
+	for _, name := range args {
+		if !mgr.IsInstalled(name) {
+			errs = append(errs, fmt.Errorf("skill %q is not installed", name))
+			continue
+		}
+		if err := mgr.Uninstall(name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to uninstall skill %q: %w", name, err))
+			continue
+		}
+		fmt.Printf("Skill %q uninstalled successfully.\n", name)
+	}
+	return errors.Join(errs...)
 }
 
 func runSkillsInfo(cmd *cobra.Command, args []string) error {
 	name := args[0]
 
-	configDir, workspacePath, err := loadConfigAndPaths()
+	workspacePath, mgr, err := loadSkillsManager()
 	if err != nil {
 		return err
 	}
@@ -208,8 +454,7 @@ func runSkillsInfo(cmd *cobra.Command, args []string) error {
 	}
 
 	// Try available skill from remote
-	mgr := skills.NewManager(configDir, workspacePath)
-	if mgr.IsCached() || func() bool { _, err := mgr.EnsureRepo(); return err == nil }() {
+	if mgr.IsCached() || (!skillsNoFetch && func() bool { _, err := mgr.EnsureRepo(); return err == nil }()) {
 		if err := mgr.DiscoverAvailable(); err == nil {
 			if a := mgr.GetAvailable(name); a != nil {
 				// Parse the full skill file for tools info
@@ -226,7 +471,40 @@ func runSkillsInfo(cmd *cobra.Command, args []string) error {
 	return fmt.Errorf("skill %q not found", name)
 }
 
+// skillInfo is the --json output of skills info.
+type skillInfo struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Tools       []string `json:"tools,omitempty"`
+	Requires    []string `json:"requires,omitempty"`
+	Missing     []string `json:"missing,omitempty"`
+	Scripts     []string `json:"scripts,omitempty"`
+	Installed   bool     `json:"installed"`
+	Path        string   `json:"path,omitempty"`
+}
+
 func printSkillInfo(s *skills.Skill, installed bool) {
+	if skillsJSON {
+		info := skillInfo{
+			Name:        s.Name,
+			Title:       s.Title,
+			Description: s.Description,
+			Version:     s.Version,
+			Tools:       s.Tools,
+			Requires:    s.Requires,
+			Missing:     skills.CheckRequirements(s.Requires),
+			Scripts:     s.Scripts,
+			Installed:   installed,
+		}
+		if installed {
+			info.Path = s.Path
+		}
+		printJSON(info)
+		return
+	}
+
 	fmt.Printf("Name:        %s\n", s.Name)
 	if s.Title != "" {
 		fmt.Printf("Title:       %s\n", s.Title)
@@ -234,9 +512,21 @@ func printSkillInfo(s *skills.Skill, installed bool) {
 	if s.Description != "" {
 		fmt.Printf("Description: %s\n", s.Description)
 	}
+	if s.Version != "" {
+		fmt.Printf("Version:     %s\n", s.Version)
+	}
 	if len(s.Tools) > 0 {
 		fmt.Printf("Tools:       %s\n", strings.Join(s.Tools, ", "))
 	}
+	if len(s.Requires) > 0 {
+		fmt.Printf("Requires:    %s\n", strings.Join(s.Requires, ", "))
+		if missing := skills.CheckRequirements(s.Requires); len(missing) > 0 {
+			fmt.Printf("Missing:     %s\n", strings.Join(missing, ", "))
+		}
+	}
+	if len(s.Scripts) > 0 {
+		fmt.Printf("Scripts:     %s\n", strings.Join(s.Scripts, ", "))
+	}
 	if installed {
 		fmt.Printf("Status:      installed\n")
 		fmt.Printf("Path:        %s\n", s.Path)
@@ -244,3 +534,65 @@ func printSkillInfo(s *skills.Skill, installed bool) {
 		fmt.Printf("Status:      available (not installed)\n")
 	}
 }
+
+func runSkillsUpgrade(cmd *cobra.Command, args []string) error {
+	_, mgr, err := loadSkillsManager()
+	if err != nil {
+		return err
+	}
+	if err := discoverAvailable(mgr); err != nil {
+		return err
+	}
+
+	var outdated []*skills.SkillStatus
+	if len(args) == 0 {
+		if outdated, err = mgr.Outdated(); err != nil {
+			return err
+		}
+	} else {
+		for _, name := range args {
+			st, err := mgr.Status(name)
+			if err != nil {
+				return err
+			}
+			if st.Outdated {
+				outdated = append(outdated, st)
+			} else {
+				fmt.Printf("Skill %q is up to date.\n", name)
+			}
+		}
+	}
+	if len(outdated) == 0 {
+		if len(args) == 0 {
+			fmt.Println("All installed skills are up to date.")
+		}
+		return nil
+	}
+
+	fmt.Println("Outdated skills:")
+	for _, st := range outdated {
+		note := ""
+		if st.Modified {
+			note = " [edited locally]"
+		}
+		fmt.Printf("  - %s: %s -> %s%s\n", st.Name, st.Installed.Label(), st.Available.Label(), note)
+	}
+	if skillsUpgradeCheck {
+		return nil
+	}
+
+	fmt.Println()
+	failed := 0
+	for _, st := range outdated {
+		if _, err := mgr.Update(st.Name, skillsUpgradeForce); err != nil {
+			fmt.Printf("Skipped %s: %v\n", st.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Updated %s to %s\n", st.Name, st.Available.Label())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d skill(s) not updated", failed)
+	}
+	return nil
+}
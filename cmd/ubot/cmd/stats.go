@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show local usage statistics",
+	Long:  "Show tool popularity, error rates and latency collected by the gateway for the current week. Statistics are opt-in and never leave this machine.",
+	RunE:  runStats,
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	snap, err := stats.Load(cfg.StatsPath())
+	if os.IsNotExist(err) {
+		if !cfg.Stats.Enabled {
+			fmt.Println("Usage statistics are disabled. Set 'stats.enabled' to true in config to collect them.")
+		} else {
+			fmt.Println("No statistics recorded yet.")
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load stats: %w", err)
+	}
+
+	fmt.Println(snap.Report(time.Now()))
+	return nil
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/control"
 	"github.com/hkuds/ubot/internal/tui"
 	"github.com/spf13/cobra"
 )
@@ -11,7 +14,7 @@ import (
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show configuration status",
-	Long:  "Display the current uBot configuration status including provider, channels, and tools.",
+	Long:  "Display the current uBot configuration status including provider, channels, and tools, and the provider circuits of a running gateway.",
 	RunE:  runStatus,
 }
 
@@ -22,6 +25,18 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// A running gateway tells the state of its provider circuits
+	var health *control.Health
+	if cfg.Gateway.ControlAPI || cfg.Gateway.Health {
+		ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Second)
+		defer cancel()
+		h, _ := control.NewClient(localGatewayAddr(cfg), cfg.Gateway.Token).Health(ctx)
+		if h.Status == "" {
+			h.Status = control.HealthUnavailable
+		}
+		health = &h
+	}
+
 	// Show status using TUI
-	return tui.ShowStatus(cfg)
+	return tui.ShowStatus(cfg, health)
 }
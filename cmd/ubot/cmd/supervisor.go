@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// supervisedEnv is set for gateways started by the supervisor.
+	supervisedEnv = "UBOT_SUPERVISED"
+	// restartExitCode is the exit code of a gateway asking to be started
+	// again (EX_TEMPFAIL).
+	restartExitCode = 75
+
+	// Crashed gateways are started again after a delay doubling from
+	// supervisorMinBackoff up to supervisorMaxBackoff. A gateway that ran
+	// for supervisorMaxBackoff resets the delay.
+	supervisorMinBackoff = time.Second
+	supervisorMaxBackoff = time.Minute
+)
+
+// errRestart is returned by runGateway after it shut down to be restarted.
+var errRestart = errors.New("gateway restart requested")
+
+// isSupervised reports whether this gateway was started by the supervisor.
+func isSupervised() bool {
+	return os.Getenv(supervisedEnv) == "1"
+}
+
+// gatewayRestarter schedules restarts of a supervised gateway. It
+// implements control.Restarter.
+type gatewayRestarter struct {
+	supervised bool
+	requested  chan struct{}
+}
+
+func newGatewayRestarter(supervised bool) *gatewayRestarter {
+	return &gatewayRestarter{supervised: supervised, requested: make(chan struct{}, 1)}
+}
+
+// Restart asks the gateway to shut down gracefully so that the supervisor
+// starts it again. Without a supervisor nothing would start it again, so
+// the request is refused.
+func (r *gatewayRestarter) Restart() error {
+	if !r.supervised {
+		return errors.New("the gateway is not supervised; restart it with your service manager or run 'ubot gateway --supervised'")
+	}
+	select {
+	case r.requested <- struct{}{}:
+	default: // a restart is already pending
+	}
+	return nil
+}
+
+// runSupervisor runs the gateway as a child process and starts it again
+// when it asks to restart or crashes. Signals are passed on to the child;
+// SIGINT and SIGTERM stop the supervisor once the child has exited.
+func runSupervisor() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the ubot binary: %w", err)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+
+	backoff := supervisorMinBackoff
+	for {
+		// Starting from the path picks up a binary replaced by
+		// 'ubot self-update' since the last start
+		child := exec.Command(exe, "gateway")
+		child.Env = append(os.Environ(), supervisedEnv+"=1")
+		child.Stdin = os.Stdin
+		child.Stdout = os.Stdout
+		child.Stderr = os.Stderr
+		started := time.Now()
+		if err := child.Start(); err != nil {
+			return fmt.Errorf("failed to start gateway: %w", err)
+		}
+		log.Printf("Supervisor: gateway started (pid %d)", child.Process.Pid)
+
+		exited := make(chan error, 1)
+		go func() { exited <- child.Wait() }()
+
+		stopping := false
+		var waitErr error
+		for running := true; running; {
+			select {
+			case sig := <-sigChan:
+				if sig != syscall.SIGHUP {
+					stopping = true
+				}
+				if err := child.Process.Signal(sig); err != nil && stopping {
+					// Platforms without signals can only kill the child
+					_ = child.Process.Kill()
+				}
+			case waitErr = <-exited:
+				running = false
+			}
+		}
+
+		code := child.ProcessState.ExitCode()
+		switch {
+		case stopping:
+			return nil
+		case code == restartExitCode:
+			log.Printf("Supervisor: restarting gateway on request")
+			backoff = supervisorMinBackoff
+			continue
+		case code == 0:
+			return nil
+		}
+
+		// The gateway crashed
+		if time.Since(started) >= supervisorMaxBackoff {
+			backoff = supervisorMinBackoff
+		}
+		log.Printf("Supervisor: gateway exited (%v); restarting in %s", waitErr, backoff)
+		delay := time.NewTimer(backoff)
+		for waiting := true; waiting; {
+			select {
+			case sig := <-sigChan:
+				if sig != syscall.SIGHUP {
+					delay.Stop()
+					return nil
+				}
+			case <-delay.C:
+				waiting = false
+			}
+		}
+		backoff = min(backoff*2, supervisorMaxBackoff)
+	}
+}
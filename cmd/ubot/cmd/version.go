@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"runtime"
+	"strings"
 
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/features"
+	"github.com/hkuds/ubot/internal/tools"
 	"github.com/spf13/cobra"
 )
 
@@ -14,6 +19,9 @@ var (
 	GitCommit = "dev"
 	// BuildDate is the build date, set at build time.
 	BuildDate = "unknown"
+	// UpdatePublicKey is the base64 ed25519 key release checksums are
+	// signed with, set at build time. Empty skips the signature check.
+	UpdatePublicKey = ""
 )
 
 var versionCmd = &cobra.Command{
@@ -29,4 +37,76 @@ func runVersion(cmd *cobra.Command, args []string) {
 	fmt.Printf("  Build date: %s\n", BuildDate)
 	fmt.Printf("  Go version: %s\n", runtime.Version())
 	fmt.Printf("  OS/Arch:    %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("  Build:      %s\n", features.Summary())
+}
+
+// toolCapabilities describes the agent's built-in tools for the system
+// prompt, mentioning the browser only when it is compiled in.
+func toolCapabilities() string {
+	if features.Browser {
+		return "read/write files, execute commands, search the web, and browse websites with a headless browser (use browser_use tool with session parameter to keep logins across restarts)"
+	}
+	return "read/write files, execute commands, and search the web"
+}
+
+// buildNote tells the agent which subsystems this build leaves out, so it
+// does not offer tools that do not exist. It is empty for full builds.
+func buildNote() string {
+	missing := features.Missing()
+	if len(missing) == 0 {
+		return ""
+	}
+	return "This is a lite build of uBot without " + strings.Join(missing, ", ") + " support. If asked for these, explain that they are not available in this build."
+}
+
+// runtimeCapabilities describes this deployment for the capabilities tool.
+func runtimeCapabilities(cfg *config.Config) tools.Capabilities {
+	provider, _, _ := cfg.GetActiveProvider()
+	caps := tools.Capabilities{
+		Build:    features.Summary(),
+		Provider: provider,
+		Model:    cfg.Agents.Defaults.Model,
+		Channels: []string{"cli"},
+		Limits: tools.CapabilityLimits{
+			MaxTokens:          cfg.Agents.Defaults.MaxTokens,
+			MaxToolIterations:  cfg.Agents.Defaults.MaxToolIterations,
+			ToolTimeoutSeconds: int(cfg.Tools.Parallel.CallTimeout().Seconds()),
+			ExecTimeoutSeconds: cfg.Tools.Exec.Timeout,
+		},
+		Sandbox: tools.SandboxInfo{
+			Isolation:           "none (commands run directly on the host)",
+			RestrictToWorkspace: cfg.Tools.Exec.RestrictToWorkspace,
+			Workspace:           cfg.WorkspacePath(),
+			CommandGuard:        true,
+		},
+		Unsupported: []string{
+			"sending files or audio in the CLI (in chat channels, send_file attaches files and images to replies)",
+		},
+	}
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		caps.Sandbox.Isolation = "docker container (uBot itself runs in Docker)"
+	}
+
+	if cfg.Channels.Telegram.Enabled {
+		caps.Channels = append(caps.Channels, "telegram")
+	}
+	if cfg.Channels.WhatsApp.Enabled {
+		caps.Channels = append(caps.Channels, "whatsapp")
+	}
+
+	if vision := cfg.Tools.Browser.Vision; features.Browser && vision.Enabled {
+		model := vision.Model
+		if model == "" {
+			model = "provider default"
+		}
+		caps.OtherModels = map[string]string{"vision": model}
+	}
+
+	if cfg.Tools.Web.Search.ProviderName() == config.SearchNone {
+		caps.Unsupported = append(caps.Unsupported, "web search (turned off in tools.web.search.provider)")
+	}
+	for _, name := range features.Missing() {
+		caps.Unsupported = append(caps.Unsupported, name+" (left out of this build)")
+	}
+	return caps
 }
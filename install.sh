@@ -335,6 +335,7 @@ ExecStart=/usr/bin/docker run --rm --name ubot \\
     --tmpfs /tmp:size=64M \\
     ubot:latest gateway
 ExecStop=/usr/bin/docker stop ubot
+ExecReload=/usr/bin/docker kill -s HUP ubot
 
 [Install]
 WantedBy=multi-user.target
@@ -377,6 +378,9 @@ case "${1:-help}" in
         sleep 1
         $0 start
         ;;
+    reload)
+        docker kill -s HUP ubot >/dev/null 2>&1 && echo "Config reloaded. Check logs with: ubot logs" || echo "uBot is not running"
+        ;;
     logs)
         docker logs -f ubot 2>/dev/null || echo "uBot is not running"
         ;;
@@ -402,7 +406,13 @@ case "${1:-help}" in
             ubot:latest rootchat
         ;;
     config)
-        ${EDITOR:-nano} "$UBOT_DIR/config.json"
+        if [ "$2" = "validate" ]; then
+            docker run --rm \
+                -v "$UBOT_DIR:/home/ubot/.ubot" \
+                ubot:latest config validate
+        else
+            ${EDITOR:-nano} "$UBOT_DIR/config.json"
+        fi
         ;;
     update)
         echo "Updating uBot..."
@@ -465,6 +475,7 @@ case "${1:-help}" in
         echo "  start     Start the gateway (Telegram, etc.)"
         echo "  stop      Stop the gateway"
         echo "  restart   Restart the gateway"
+        echo "  reload    Apply config changes without a restart"
         echo "  logs      Show gateway logs"
         echo "  status    Show configuration status"
         echo "  chat      Interactive chat mode"
@@ -472,6 +483,7 @@ case "${1:-help}" in
         echo "  setup     Run setup wizard"
         echo "  rootchat  Configure uBot via AI chat"
         echo "  config    Edit configuration"
+        echo "  config validate  Check configuration for mistakes"
         echo "  update    Update to latest version"
         echo "  destroy   Remove uBot completely"
         echo ""
@@ -693,6 +705,13 @@ case "\${1:-help}" in
         sleep 1
         \$0 start
         ;;
+    reload)
+        if [ -f "\$UBOT_DIR/ubot.pid" ] && kill -HUP \$(cat "\$UBOT_DIR/ubot.pid") 2>/dev/null; then
+            echo "Config reloaded. Check logs with: ubot logs"
+        else
+            echo "uBot is not running"
+        fi
+        ;;
     logs)
         tail -f "\$UBOT_DIR/ubot.log" 2>/dev/null || echo "No logs found"
         ;;
@@ -710,7 +729,11 @@ case "\${1:-help}" in
         "\$UBOT_BIN" rootchat
         ;;
     config)
-        \${EDITOR:-nano} "\$UBOT_DIR/config.json"
+        if [ "\$2" = "validate" ]; then
+            "\$UBOT_BIN" config validate
+        else
+            \${EDITOR:-nano} "\$UBOT_DIR/config.json"
+        fi
         ;;
     update)
         echo "Updating uBot..."
@@ -764,6 +787,7 @@ case "\${1:-help}" in
         echo "  start     Start the gateway (Telegram, etc.)"
         echo "  stop      Stop the gateway"
         echo "  restart   Restart the gateway"
+        echo "  reload    Apply config changes without a restart"
         echo "  logs      Show gateway logs"
         echo "  status    Show configuration status"
         echo "  chat      Interactive chat mode"
@@ -771,6 +795,7 @@ case "\${1:-help}" in
         echo "  setup     Run setup wizard"
         echo "  rootchat  Configure uBot via AI chat"
         echo "  config    Edit configuration"
+        echo "  config validate  Check configuration for mistakes"
         echo "  update    Update to latest version"
         echo "  destroy   Remove uBot completely"
         echo ""
@@ -81,6 +81,8 @@ You have access to various tools that you can use to accomplish tasks:
 - **read_file**: Read the contents of a file
 - **write_file**: Write content to a file
 - **list_directory**: List files and directories
+- **search_files**: Search file contents for text or a regular expression
+- **apply_patch**: Apply a unified diff to one or more files
 - **shell**: Execute shell commands
 - **web_search**: Search the web for information
 - **web_fetch**: Fetch content from a URL
@@ -84,6 +84,8 @@ func NewLoop(cfg LoopConfig) (*Loop, error) {
 
 	// Wrap registry with security middleware
 	secureReg := tools.NewSecureRegistry(registry)
+	parallel := cfg.Config.Tools.Parallel
+	secureReg.SetParallelism(parallel.WorkerCount(), parallel.CallTimeout(), parallel.ToolTimeouts())
 
 	return &Loop{
 		bus:         cfg.Bus,
@@ -189,7 +191,7 @@ func (l *Loop) ProcessMessage(ctx context.Context, msg bus.InboundMessage) (*bus
 
 	// Get or create session for this conversation
 	sessionKey := msg.SessionKey()
-	sess := l.sessions.GetOrCreate(sessionKey)
+	sess := l.sessions.Active(sessionKey)
 
 	// Update MessageTool context with current channel/chatID
 	l.messageTool.SetContext(msg.Channel, msg.ChatID)
@@ -237,15 +239,14 @@ func (l *Loop) ProcessMessage(ctx context.Context, msg bus.InboundMessage) (*bus
 		// Add assistant message with tool calls to messages
 		messages = l.context.AddAssistantMessage(messages, resp.Content, resp.ToolCalls)
 
-		// Execute each tool call
-		for _, tc := range resp.ToolCalls {
-			result, err := l.executeTool(ctx, tc)
-			if err != nil {
-				result = fmt.Sprintf("Tool error: %v", err)
+		// Execute the tool calls, independent ones concurrently, and add
+		// the results in call order
+		for _, res := range l.executeTools(ctx, resp.ToolCalls) {
+			result := res.Result
+			if res.Err != nil {
+				result = fmt.Sprintf("Tool error: %v", res.Err)
 			}
-
-			// Add tool result to messages
-			messages = l.context.AddToolResult(messages, tc.ID, tc.Name, result)
+			messages = l.context.AddToolResult(messages, res.Call.ID, res.Call.Name, result)
 		}
 
 		// If this is the last iteration, get a final response without tools
@@ -278,14 +279,14 @@ func (l *Loop) ProcessMessage(ctx context.Context, msg bus.InboundMessage) (*bus
 	}, nil
 }
 
-// executeTool executes a single tool call and returns the result.
-func (l *Loop) executeTool(ctx context.Context, tc providers.ToolCall) (string, error) {
-	// Execute the tool
-	result, err := l.tools.Execute(ctx, tc.Name, tc.Arguments)
-	if err != nil {
-		return "", err
+// executeTools executes the tool calls of one turn and returns the results
+// in call order.
+func (l *Loop) executeTools(ctx context.Context, tcs []providers.ToolCall) []tools.CallResult {
+	calls := make([]tools.Call, len(tcs))
+	for i, tc := range tcs {
+		calls[i] = tools.Call{ID: tc.ID, Name: tc.Name, Params: tc.Arguments}
 	}
-	return result, nil
+	return l.tools.ExecuteAll(ctx, calls)
 }
 
 // handleSystemMessage handles messages from the "system" channel (e.g., subagent results).
@@ -0,0 +1,294 @@
+// Package audit writes a structured log of every tool call as JSON lines,
+// rotating the log by size, and reads it back for "ubot audit tail".
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/sandbox"
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+const (
+	// currentFile is the log being written; rotated logs are renamed to
+	// audit-<timestamp>.jsonl.
+	currentFile = "audit.jsonl"
+	// rotatedLayout is the timestamp format of rotated log names.
+	rotatedLayout = "20060102-150405.000"
+)
+
+// Entry is one audited tool call.
+type Entry struct {
+	Time       time.Time         `json:"time"`
+	Tool       string            `json:"tool"`
+	Params     map[string]string `json:"params,omitempty"`
+	Channel    string            `json:"channel,omitempty"`
+	ChatID     string            `json:"chatId,omitempty"`
+	SenderID   string            `json:"senderId,omitempty"`
+	DurationMs int64             `json:"durationMs"`
+	ResultSize int               `json:"resultSize"`
+	Usage      *sandbox.Usage    `json:"usage,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// String formats the entry as a single human-readable line.
+func (e Entry) String() string {
+	caller := "cli"
+	if e.Channel != "" {
+		caller = e.Channel + ":" + e.ChatID
+	}
+	keys := make([]string, 0, len(e.Params))
+	for k := range e.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, fmt.Sprintf("%s=%q", k, e.Params[k]))
+	}
+
+	status := fmt.Sprintf("ok %dB", e.ResultSize)
+	if e.Error != "" {
+		status = "error: " + e.Error
+	}
+	if e.Usage != nil {
+		status += " [" + e.Usage.String() + "]"
+	}
+	return fmt.Sprintf("%s  %-16s %-20s %6dms  %s  %s",
+		e.Time.Local().Format(time.DateTime), caller, e.Tool, e.DurationMs, status, strings.Join(params, " "))
+}
+
+// Logger appends entries to the audit log in dir. It implements
+// tools.Auditor.
+type Logger struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewLogger creates a Logger that rotates the log once it exceeds maxSize
+// bytes and keeps at most maxFiles rotated logs.
+func NewLogger(dir string, maxSize int64, maxFiles int) *Logger {
+	return &Logger{dir: dir, maxSize: maxSize, maxFiles: maxFiles}
+}
+
+// AuditTool records a finished tool call. Write errors are logged rather
+// than failing the tool.
+func (l *Logger) AuditTool(call tools.ToolCall) {
+	e := Entry{
+		Time:       time.Now(),
+		Tool:       call.Name,
+		Params:     call.Params,
+		Channel:    call.Request.Channel,
+		ChatID:     call.Request.ChatID,
+		SenderID:   call.Request.SenderID,
+		DurationMs: call.Duration.Milliseconds(),
+		ResultSize: call.ResultSize,
+		Usage:      call.Usage,
+	}
+	if call.Err != nil {
+		e.Error = call.Err.Error()
+	}
+	if err := l.Write(e); err != nil {
+		log.Printf("Warning: failed to write audit log: %v", err)
+	}
+}
+
+// Write appends e to the log, rotating it first if it is full.
+func (l *Logger) Write(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		if err := l.openLocked(); err != nil {
+			return err
+		}
+	}
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(data)) > l.maxSize {
+		if err := l.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	return err
+}
+
+// Close closes the current log file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *Logger) openLocked() error {
+	if err := os.MkdirAll(l.dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(l.dir, currentFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// rotateLocked renames the current log, opens a new one and removes the
+// oldest rotated logs beyond maxFiles.
+func (l *Logger) rotateLocked() error {
+	l.file.Close()
+	l.file = nil
+
+	rotated := filepath.Join(l.dir, "audit-"+time.Now().Format(rotatedLayout)+".jsonl")
+	if err := os.Rename(filepath.Join(l.dir, currentFile), rotated); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	files, err := rotatedFiles(l.dir)
+	if err != nil {
+		return err
+	}
+	for len(files) > l.maxFiles {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return l.openLocked()
+}
+
+// rotatedFiles returns the rotated logs in dir, oldest first.
+func rotatedFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Tail returns the last n entries of the audit log in dir, oldest first.
+func Tail(dir string, n int) ([]Entry, error) {
+	files, err := rotatedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, filepath.Join(dir, currentFile))
+
+	var entries []Entry
+	for i := len(files) - 1; i >= 0 && len(entries) < n; i-- {
+		f, err := os.Open(files[i])
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fileEntries, err := readEntries(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", files[i], err)
+		}
+		entries = append(fileEntries, entries...)
+	}
+
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries, nil
+}
+
+// Follow calls fn for each entry appended to the audit log in dir until ctx
+// is done, checking for new entries every interval. It follows the log
+// across rotations.
+func Follow(ctx context.Context, dir string, interval time.Duration, fn func(Entry)) error {
+	path := filepath.Join(dir, currentFile)
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			offset = 0
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info, err := f.Stat(); err == nil && info.Size() < offset {
+			offset = 0 // rotated
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return err
+		}
+		r := bufio.NewReader(f)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				break // partial lines are read on the next tick
+			}
+			offset += int64(len(line))
+			var e Entry
+			if json.Unmarshal(line, &e) == nil {
+				fn(e)
+			}
+		}
+		f.Close()
+	}
+}
+
+// readEntries decodes JSON lines from r, skipping malformed ones.
+func readEntries(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+func TestLoggerRecordsToolCalls(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewLogger(dir, 1<<20, 3)
+	defer logger.Close()
+
+	reg := tools.NewRegistry()
+	reg.Register(tools.NewListDirTool())
+	reg.Register(tools.NewWriteFileTool())
+	secure := tools.NewSecureRegistry(reg)
+	secure.SetAuditor(logger)
+
+	ctx := tools.WithRequest(context.Background(), tools.RequestInfo{Channel: "telegram", ChatID: "42", SenderID: "7"})
+	if _, err := secure.Execute(ctx, "list_dir", map[string]interface{}{"path": t.TempDir()}); err != nil {
+		t.Fatalf("list_dir: %v", err)
+	}
+	secret := strings.Repeat("s", 100)
+	if _, err := secure.Execute(context.Background(), "write_file", map[string]interface{}{
+		"path":    "~/.ssh/authorized_keys",
+		"content": secret,
+	}); err == nil {
+		t.Fatal("write to ~/.ssh was not blocked")
+	}
+
+	entries, err := Tail(dir, 10)
+	if err != nil {
+		t.Fatalf("Tail: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(entries))
+	}
+
+	ok := entries[0]
+	if ok.Tool != "list_dir" || ok.Channel != "telegram" || ok.ChatID != "42" || ok.SenderID != "7" || ok.Error != "" {
+		t.Errorf("unexpected entry: %+v", ok)
+	}
+	if ok.ResultSize == 0 {
+		t.Error("result size not recorded")
+	}
+
+	blocked := entries[1]
+	if blocked.Tool != "write_file" || blocked.Error == "" || blocked.Channel != "" {
+		t.Errorf("unexpected entry for blocked call: %+v", blocked)
+	}
+	if strings.Contains(blocked.Params["content"], secret) {
+		t.Error("long content was not redacted")
+	}
+}
+
+func TestLoggerRotation(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewLogger(dir, 300, 2)
+	defer logger.Close()
+
+	for i := 0; i < 20; i++ {
+		if err := logger.Write(Entry{Time: time.Now(), Tool: "exec", Params: map[string]string{"command": "echo " + strings.Repeat("x", i)}}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond) // distinct rotated names
+	}
+
+	rotated, err := rotatedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 2 {
+		t.Errorf("rotated logs = %d, want 2 (maxFiles)", len(rotated))
+	}
+
+	entries, err := Tail(dir, 4)
+	if err != nil {
+		t.Fatalf("Tail: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("entries = %d, want 4", len(entries))
+	}
+	if got := entries[3].Params["command"]; got != "echo "+strings.Repeat("x", 19) {
+		t.Errorf("last entry = %q, want the last written", got)
+	}
+	for i := 1; i < len(entries); i++ {
+		if len(entries[i].Params["command"]) <= len(entries[i-1].Params["command"]) {
+			t.Errorf("entries out of order across files: %v", entries)
+		}
+	}
+
+	if _, err := Tail(filepath.Join(dir, "missing"), 5); err != nil {
+		t.Errorf("Tail on a missing log: %v", err)
+	}
+}
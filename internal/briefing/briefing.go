@@ -0,0 +1,225 @@
+// Package briefing composes a daily briefing for each chat: what the
+// gateway knows about the day ahead (calendar, new feed items, pending
+// jobs, conversations waiting for an answer) gathered into sections and
+// written up by the agent in one pass, then sent on schedule. The time of
+// each chat's last briefing is kept in a small JSON file, so the next one
+// covers only what happened since.
+package briefing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/cron"
+	"github.com/hkuds/ubot/internal/providers"
+)
+
+// firstLookback is how far back the first briefing of a chat looks.
+const firstLookback = 24 * time.Hour
+
+// Chat is a chat that receives the briefing.
+type Chat struct {
+	Channel string
+	ChatID  string
+}
+
+// ParseChat parses a session key such as "telegram:123".
+func ParseChat(key string) (Chat, error) {
+	channel, chatID, ok := strings.Cut(key, ":")
+	if !ok || channel == "" || chatID == "" {
+		return Chat{}, fmt.Errorf("invalid chat %q: want channel:chatId, e.g. telegram:123", key)
+	}
+	return Chat{Channel: channel, ChatID: chatID}, nil
+}
+
+// Key returns the session key of the chat.
+func (c Chat) Key() string {
+	return c.Channel + ":" + c.ChatID
+}
+
+// Section gathers one part of a briefing. Gather returns what the chat
+// should hear about since the time given, or "" when there is nothing, in
+// which case the section is left out.
+type Section struct {
+	Title  string
+	Gather func(ctx context.Context, chat Chat, since time.Time) (string, error)
+}
+
+// Briefer composes and sends briefings.
+type Briefer struct {
+	provider    providers.Provider
+	model       string
+	instruction string
+	sections    []Section
+	publish     func(bus.OutboundMessage)
+	now         func() time.Time
+
+	mu        sync.Mutex
+	statePath string
+	last      map[string]time.Time // session key -> last briefing
+}
+
+// New creates a Briefer that writes briefings with model and hands them to
+// publish, remembering when each chat was last briefed in statePath.
+func New(provider providers.Provider, model, statePath string, publish func(bus.OutboundMessage)) *Briefer {
+	b := &Briefer{
+		provider:  provider,
+		model:     model,
+		publish:   publish,
+		now:       time.Now,
+		statePath: statePath,
+		last:      make(map[string]time.Time),
+	}
+	if data, err := os.ReadFile(statePath); err == nil {
+		if err := json.Unmarshal(data, &b.last); err != nil {
+			log.Printf("Warning: ignoring unreadable briefing state %s: %v", statePath, err)
+		}
+	}
+	return b
+}
+
+// SetInstruction adds instruction, such as how long the briefing should
+// be, to the prompt.
+func (b *Briefer) SetInstruction(instruction string) {
+	b.instruction = strings.TrimSpace(instruction)
+}
+
+// AddSection adds a section to every briefing, after the ones added
+// before it.
+func (b *Briefer) AddSection(s Section) {
+	b.sections = append(b.sections, s)
+}
+
+// Since returns when chat was last briefed, or firstLookback ago when it
+// never was.
+func (b *Briefer) Since(chat Chat) time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.last[chat.Key()]; ok {
+		return t
+	}
+	return b.now().Add(-firstLookback)
+}
+
+// Compose gathers the sections for chat and has the model write them up.
+// It returns "" when no section has anything to report. When the model
+// fails, the sections are returned as gathered.
+func (b *Briefer) Compose(ctx context.Context, chat Chat) (string, error) {
+	since := b.Since(chat)
+	var gathered strings.Builder
+	for _, s := range b.sections {
+		content, err := s.Gather(ctx, chat, since)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			log.Printf("Warning: briefing section %q for %s: %v", s.Title, chat.Key(), err)
+			continue
+		}
+		if content = strings.TrimSpace(content); content != "" {
+			fmt.Fprintf(&gathered, "## %s\n%s\n\n", s.Title, content)
+		}
+	}
+	if gathered.Len() == 0 {
+		return "", nil
+	}
+	sections := strings.TrimSpace(gathered.String())
+
+	resp, err := b.provider.Chat(ctx, providers.ChatRequest{
+		Messages:    []providers.ChatMessage{{Role: "user", Content: b.prompt(sections)}},
+		Model:       b.model,
+		MaxTokens:   1024,
+		Temperature: 0.5,
+	})
+	if err == nil && (resp == nil || strings.TrimSpace(resp.Content) == "") {
+		err = fmt.Errorf("empty response from provider")
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		log.Printf("Warning: sending the briefing for %s unedited: %v", chat.Key(), err)
+		return "Your briefing\n\n" + sections, nil
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// prompt asks the model to write the briefing from the gathered sections.
+func (b *Briefer) prompt(sections string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "It is now %s. Write the user's briefing for the day from the notes below: ", b.now().Format(time.RFC1123))
+	sb.WriteString("lead with what needs their attention soonest, keep it short and skimmable, and do not invent anything the notes do not say.")
+	if b.instruction != "" {
+		sb.WriteString("\n" + b.instruction)
+	}
+	sb.WriteString("\n\n" + sections)
+	return sb.String()
+}
+
+// Send composes the briefing for chat and publishes it, unless there is
+// nothing to tell. Either way the chat counts as briefed.
+func (b *Briefer) Send(ctx context.Context, chat Chat) error {
+	started := b.now()
+	content, err := b.Compose(ctx, chat)
+	if err != nil {
+		return err
+	}
+	if content != "" {
+		b.publish(bus.OutboundMessage{Channel: chat.Channel, ChatID: chat.ChatID, Content: content})
+	}
+	return b.markSent(chat, started)
+}
+
+// markSent records that chat was briefed at t.
+func (b *Briefer) markSent(chat Chat, t time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.last[chat.Key()] = t
+	data, err := json.MarshalIndent(b.last, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.statePath), 0o700); err != nil {
+		return fmt.Errorf("failed to save briefing state: %w", err)
+	}
+	if err := os.WriteFile(b.statePath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save briefing state: %w", err)
+	}
+	return nil
+}
+
+// Run sends the briefing to chats each time schedule fires, until ctx is
+// done. It fails at once when schedule is invalid.
+func (b *Briefer) Run(ctx context.Context, schedule string, chats []Chat) error {
+	for {
+		now := b.now()
+		next, err := cron.NextRun(schedule, now)
+		if err != nil {
+			return fmt.Errorf("briefing: %w", err)
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		for _, chat := range chats {
+			if err := b.Send(ctx, chat); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				log.Printf("Warning: briefing for %s failed: %v", chat.Key(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package briefing
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/providers"
+)
+
+// fakeProvider answers with response, or fails with err, recording the
+// requests it gets.
+type fakeProvider struct {
+	response string
+	err      error
+	requests []providers.ChatRequest
+}
+
+func (p *fakeProvider) Name() string         { return "fake" }
+func (p *fakeProvider) DefaultModel() string { return "fake-model" }
+func (p *fakeProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.requests = append(p.requests, req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &providers.ChatResponse{Content: p.response}, nil
+}
+
+func section(title, content string) Section {
+	return Section{Title: title, Gather: func(context.Context, Chat, time.Time) (string, error) {
+		return content, nil
+	}}
+}
+
+func TestParseChat(t *testing.T) {
+	chat, err := ParseChat("telegram:-100:5")
+	if err != nil || chat.Channel != "telegram" || chat.ChatID != "-100:5" || chat.Key() != "telegram:-100:5" {
+		t.Errorf("ParseChat = %+v, %v", chat, err)
+	}
+	for _, key := range []string{"telegram", ":42", "telegram:"} {
+		if _, err := ParseChat(key); err == nil {
+			t.Errorf("ParseChat(%q) succeeded", key)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	provider := &fakeProvider{response: "Good morning! Standup at 10."}
+	var sent []bus.OutboundMessage
+	path := filepath.Join(t.TempDir(), "briefing.json")
+	b := New(provider, "small-model", path, func(msg bus.OutboundMessage) { sent = append(sent, msg) })
+	now := time.Date(2026, 3, 14, 8, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	b.SetInstruction("Mention the weather.")
+
+	var since []time.Time
+	b.AddSection(Section{Title: "Calendar", Gather: func(_ context.Context, chat Chat, t time.Time) (string, error) {
+		since = append(since, t)
+		return "- 10:00 Standup", nil
+	}})
+	b.AddSection(section("Feeds", ""))
+	b.AddSection(Section{Title: "Jobs", Gather: func(context.Context, Chat, time.Time) (string, error) {
+		return "", errors.New("unavailable")
+	}})
+
+	chat := Chat{Channel: "telegram", ChatID: "42"}
+	if err := b.Send(context.Background(), chat); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(sent) != 1 || sent[0].ChatID != "42" || sent[0].Content != provider.response {
+		t.Fatalf("sent %+v", sent)
+	}
+	req := provider.requests[0]
+	prompt, _ := req.Messages[0].Content.(string)
+	if req.Model != "small-model" || !strings.Contains(prompt, "## Calendar\n- 10:00 Standup") ||
+		strings.Contains(prompt, "Feeds") || !strings.Contains(prompt, "Mention the weather.") {
+		t.Errorf("request = %+v", req)
+	}
+	if !since[0].Equal(now.Add(-firstLookback)) {
+		t.Errorf("first briefing looked back to %v", since[0])
+	}
+
+	// The next briefing starts where this one ended, also after a restart
+	b = New(provider, "small-model", path, func(msg bus.OutboundMessage) { sent = append(sent, msg) })
+	if got := b.Since(chat); !got.Equal(now) {
+		t.Errorf("Since = %v, want %v", got, now)
+	}
+}
+
+func TestSendFallsBackToSections(t *testing.T) {
+	provider := &fakeProvider{err: errors.New("provider down")}
+	var sent []bus.OutboundMessage
+	b := New(provider, "", filepath.Join(t.TempDir(), "briefing.json"), func(msg bus.OutboundMessage) { sent = append(sent, msg) })
+	b.AddSection(section("Calendar", "- 10:00 Standup"))
+
+	if err := b.Send(context.Background(), Chat{Channel: "telegram", ChatID: "42"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(sent) != 1 || !strings.Contains(sent[0].Content, "## Calendar\n- 10:00 Standup") {
+		t.Errorf("sent %+v", sent)
+	}
+}
+
+func TestSendNothingToTell(t *testing.T) {
+	provider := &fakeProvider{response: "unused"}
+	var sent []bus.OutboundMessage
+	b := New(provider, "", filepath.Join(t.TempDir(), "briefing.json"), func(msg bus.OutboundMessage) { sent = append(sent, msg) })
+	b.AddSection(section("Calendar", " \n"))
+
+	chat := Chat{Channel: "telegram", ChatID: "42"}
+	if err := b.Send(context.Background(), chat); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(sent) != 0 || len(provider.requests) != 0 {
+		t.Errorf("sent %+v after %d requests", sent, len(provider.requests))
+	}
+	if time.Since(b.Since(chat)) > time.Minute {
+		t.Error("chat not marked as briefed")
+	}
+}
+
+func TestRunInvalidSchedule(t *testing.T) {
+	b := New(&fakeProvider{}, "", filepath.Join(t.TempDir(), "briefing.json"), func(bus.OutboundMessage) {})
+	if err := b.Run(context.Background(), "every morning", nil); err == nil {
+		t.Error("Run accepted an invalid schedule")
+	}
+}
@@ -0,0 +1,139 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Queue is a shared FIFO used to hand messages between gateway instances
+// (for example a Redis list). Pop returns nil data when no message arrived
+// within the timeout.
+type Queue interface {
+	Push(ctx context.Context, name string, data []byte) error
+	Pop(ctx context.Context, name string, timeout time.Duration) ([]byte, error)
+}
+
+const (
+	// clusterPopTimeout is how long a bridge blocks on the shared queue
+	// before re-checking its context.
+	clusterPopTimeout = 2 * time.Second
+	// clusterRetryDelay is the pause after a shared queue error.
+	clusterRetryDelay = time.Second
+)
+
+// ExportInbound moves locally published inbound messages to the shared
+// queue. It is run by a polling instance that owns the channel connectors
+// but does not process messages itself. Journaled messages are
+// acknowledged once pushed. Blocks until ctx is cancelled.
+func (b *MessageBus) ExportInbound(ctx context.Context, q Queue, name string) {
+	for {
+		select {
+		case <-b.closed:
+			return
+		default:
+		}
+		msg, err := b.ConsumeInboundWithTimeout(ctx, clusterPopTimeout)
+		if err == ErrTimeout {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if pushJSON(ctx, q, name, msg) {
+			b.Ack(msg)
+		}
+	}
+}
+
+// ImportInbound feeds inbound messages from the shared queue into the local
+// bus. It is run by a processing instance. Blocks until ctx is cancelled.
+func (b *MessageBus) ImportInbound(ctx context.Context, q Queue, name string) {
+	popJSON(ctx, q, name, func(data []byte) {
+		var msg InboundMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("bus: dropping malformed inbound message: %v", err)
+			return
+		}
+		b.PublishInbound(msg)
+	})
+}
+
+// ExportOutbound moves locally published outbound messages to the shared
+// queue instead of dispatching them to local subscribers. Blocks until ctx
+// is cancelled.
+func (b *MessageBus) ExportOutbound(ctx context.Context, q Queue, name string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.closed:
+			return
+		case msg := <-b.outbound:
+			pushJSON(ctx, q, name, msg)
+			b.unsent.Add(-1)
+		}
+	}
+}
+
+// ImportOutbound feeds outbound messages from the shared queue into the
+// local bus, where DispatchOutbound delivers them to channel subscribers.
+// Blocks until ctx is cancelled.
+func (b *MessageBus) ImportOutbound(ctx context.Context, q Queue, name string) {
+	popJSON(ctx, q, name, func(data []byte) {
+		var msg OutboundMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("bus: dropping malformed outbound message: %v", err)
+			return
+		}
+		b.PublishOutbound(msg)
+	})
+}
+
+// pushJSON encodes v and pushes it to the shared queue, retrying until it
+// succeeds or ctx is cancelled so messages are not lost on a transient
+// queue outage. It reports false when ctx was cancelled before the push
+// succeeded.
+func pushJSON(ctx context.Context, q Queue, name string, v interface{}) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("bus: failed to encode message for %s: %v", name, err)
+		return true
+	}
+	for {
+		err := q.Push(ctx, name, data)
+		if err == nil {
+			return true
+		}
+		log.Printf("bus: push to %s failed: %v", name, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(clusterRetryDelay):
+		}
+	}
+}
+
+// popJSON repeatedly pops raw messages from the shared queue and hands them
+// to handle until ctx is cancelled.
+func popJSON(ctx context.Context, q Queue, name string, handle func([]byte)) {
+	for ctx.Err() == nil {
+		data, err := q.Pop(ctx, name, clusterPopTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("bus: pop from %s failed: %v", name, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(clusterRetryDelay):
+			}
+			continue
+		}
+		if data != nil {
+			handle(data)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// languageHints are patterns that suggest a code block's language, each
+// worth one point. Languages are listed in the order ties are broken.
+var languageHints = []struct {
+	lang     string
+	patterns []*regexp.Regexp
+}{
+	{"go", compileHints(`(?m)^package \w+$`, `(?m)^func (\(\w+ \*?\w+\) )?\w+\(`, `:= `, `\bfmt\.\w+\(`, `\berr != nil\b`)},
+	{"python", compileHints(`(?m)^\s*def \w+\(.*\):\s*$`, `(?m)^(from \S+ )?import \w+`, `(?m)^\s*(if|for|while|with|class) .*:\s*$`, `\bprint\(`, `\bself\.`, `(?m)^\s*elif `)},
+	{"typescript", compileHints(`(?m)^\s*(export )?interface \w+`, `:\s*(string|number|boolean)\b`, `(?m)^import .* from ['"]`, `\bconst \w+(: \w+)? = `)},
+	{"javascript", compileHints(`\bconsole\.log\(`, `\b(const|let) \w+ = `, `=> \{?`, `\bfunction \w*\(`, `\brequire\(['"]`, `(?m)^import .* from ['"]`)},
+	{"rust", compileHints(`(?m)^\s*(pub )?fn \w+`, `\blet mut \b`, `\bprintln!\(`, `(?m)^use \w+(::\w+)+`, `\bimpl\b`)},
+	{"java", compileHints(`\bpublic (static )?(class|void)\b`, `\bSystem\.out\.`, `(?m)^import java\.`, `\bprivate \w+ \w+;`)},
+	{"cpp", compileHints(`#include <(iostream|vector|string)>`, `\bstd::`, `\bcout <<`)},
+	{"c", compileHints(`(?m)^#include <\w+\.h>`, `\bprintf\(`, `(?m)^int main\(`, `\bmalloc\(`)},
+	{"bash", compileHints(`(?m)^#!/(usr/)?bin/(env )?(ba)?sh`, `(?m)^\$ `, `(?m)^(sudo|apt|apt-get|brew|npm|pip|go|git|docker|kubectl|cd|export|echo|curl|mkdir) `, `(?m)^\s*(fi|done|esac)$`, `\$\{?\w+\}?`)},
+	{"sql", compileHints(`(?im)^\s*(SELECT|INSERT INTO|UPDATE|DELETE FROM|CREATE TABLE|ALTER TABLE)\b`, `(?i)\bFROM \w+`, `(?i)\bWHERE\b`)},
+	{"dockerfile", compileHints(`(?m)^FROM \S+`, `(?m)^(RUN|COPY|WORKDIR|CMD|ENTRYPOINT|EXPOSE) `)},
+	{"html", compileHints(`(?i)<!DOCTYPE html>`, `(?i)</?(html|head|body|div|span|p|a|script)\b[^>]*>`)},
+	{"yaml", compileHints(`(?m)^[\w.-]+:( .*)?$`, `(?m)^\s+- \S`, `(?m)^---$`)},
+}
+
+// fileExtensions maps languages to the extension of their files.
+var fileExtensions = map[string]string{
+	"go": "go", "python": "py", "typescript": "ts", "javascript": "js",
+	"rust": "rs", "java": "java", "cpp": "cpp", "c": "c", "bash": "sh",
+	"sql": "sql", "html": "html", "yaml": "yaml", "json": "json",
+}
+
+func compileHints(patterns ...string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		res[i] = regexp.MustCompile(p)
+	}
+	return res
+}
+
+// DetectLanguage guesses the language of a piece of code from common
+// keywords and syntax. It returns "" when nothing matches.
+func DetectLanguage(code string) string {
+	trimmed := strings.TrimSpace(code)
+	if trimmed == "" {
+		return ""
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+
+	best, bestScore := "", 0
+	for _, hint := range languageHints {
+		score := 0
+		for _, re := range hint.patterns {
+			if re.MatchString(code) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = hint.lang, score
+		}
+	}
+	return best
+}
+
+// FormatCodeBlocks labels each fenced code block in text that has no
+// language with the one DetectLanguage finds, so channels can highlight it.
+// When fileLimit is positive, blocks longer than fileLimit characters are
+// taken out of the text and returned as files, leaving a short note in
+// their place. Unclosed blocks are left as they are.
+func FormatCodeBlocks(text string, fileLimit int) (string, []File) {
+	if !strings.Contains(text, "```") && !strings.Contains(text, "~~~") {
+		return text, nil
+	}
+
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	var files []File
+	for i := 0; i < len(lines); i++ {
+		if !isFence(lines[i]) {
+			out = append(out, lines[i])
+			continue
+		}
+		end := closingFence(lines, i)
+		if end < 0 {
+			out = append(out, lines[i:]...)
+			break
+		}
+
+		fence := lines[i]
+		indent := fence[:len(fence)-len(strings.TrimLeft(fence, " \t"))]
+		marker := fenceMarker(strings.TrimSpace(fence))
+		lang := strings.TrimSpace(strings.TrimSpace(fence)[len(marker):])
+		code := strings.Join(lines[i+1:end], "\n")
+		if lang == "" {
+			lang = DetectLanguage(code)
+		}
+
+		if fileLimit > 0 && utf8.RuneCountInString(code) > fileLimit {
+			name := codeFileName(lang, len(files)+1)
+			files = append(files, File{Name: name, Data: []byte(code + "\n")})
+			out = append(out, fmt.Sprintf("%s(%d lines of code sent as %s)", indent, end-i-1, name))
+		} else {
+			out = append(out, indent+marker+strings.ToLower(lang))
+			out = append(out, lines[i+1:end+1]...)
+		}
+		i = end
+	}
+	return strings.Join(out, "\n"), files
+}
+
+// closingFence returns the index of the line closing the code block opened
+// at lines[open], or -1 if the block is never closed.
+func closingFence(lines []string, open int) int {
+	marker := fenceMarker(strings.TrimSpace(lines[open]))
+	for j := open + 1; j < len(lines); j++ {
+		t := strings.TrimSpace(lines[j])
+		if strings.HasPrefix(t, marker) && strings.Trim(t, marker[:1]) == "" {
+			return j
+		}
+	}
+	return -1
+}
+
+// codeFileName names the n-th code block of a message after its language.
+func codeFileName(lang string, n int) string {
+	lang = strings.ToLower(lang)
+	if lang == "dockerfile" {
+		return fmt.Sprintf("Dockerfile.%d", n)
+	}
+	ext, ok := fileExtensions[lang]
+	if !ok {
+		ext = "txt"
+	}
+	return fmt.Sprintf("code-%d.%s", n, ext)
+}
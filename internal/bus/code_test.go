@@ -0,0 +1,63 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	cases := map[string]string{
+		"package main\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}":      "go",
+		"def greet(name):\n    print(f\"hi {name}\")":                  "python",
+		"const x = [1, 2].map(n => n * 2);\nconsole.log(x);":           "javascript",
+		"fn main() {\n    let mut v = 1;\n    println!(\"{}\", v);\n}": "rust",
+		"#include <stdio.h>\nint main() {\n  printf(\"hi\");\n}":       "c",
+		"$ sudo apt install git\n$ git clone repo":                     "bash",
+		"SELECT name FROM users WHERE id = 1;":                         "sql",
+		`{"name": "ubot", "tags": ["a"]}`:                              "json",
+		"FROM golang:1.25\nRUN go build ./...":                         "dockerfile",
+		"just some words":                                              "",
+	}
+	for code, want := range cases {
+		if got := DetectLanguage(code); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestFormatCodeBlocksLabelsLanguage(t *testing.T) {
+	text := "Try:\n```\ndef f():\n    return 1\n```\nand\n```sh\nls\n```\n```\nunclosed"
+	got, files := FormatCodeBlocks(text, 0)
+	want := "Try:\n```python\ndef f():\n    return 1\n```\nand\n```sh\nls\n```\n```\nunclosed"
+	if got != want || files != nil {
+		t.Errorf("FormatCodeBlocks = %q, %v\nwant %q", got, files, want)
+	}
+}
+
+func TestFormatCodeBlocksMovesLongBlocksToFiles(t *testing.T) {
+	long := strings.Repeat("fmt.Println(\"line\")\n", 10) + "err != nil"
+	text := "Here it is:\n```go\n" + long + "\n```\nShort one:\n```\nx := 1\n```"
+
+	got, files := FormatCodeBlocks(text, 100)
+	if len(files) != 1 || files[0].Name != "code-1.go" || string(files[0].Data) != long+"\n" {
+		t.Fatalf("files = %+v", files)
+	}
+	want := "Here it is:\n(11 lines of code sent as code-1.go)\nShort one:\n```go\nx := 1\n```"
+	if got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestFormatCodeOnlyForEnabledChannels(t *testing.T) {
+	b := NewMessageBus(1)
+	b.SetCodeBlocks("telegram", 5)
+	msg := OutboundMessage{Channel: "whatsapp", Content: "```\npackage main\n```"}
+
+	if got := b.formatCode(msg); got.Content != msg.Content || got.Files != nil {
+		t.Errorf("whatsapp message changed: %+v", got)
+	}
+	msg.Channel = "telegram"
+	if got := b.formatCode(msg); len(got.Files) != 1 || strings.Contains(got.Content, "package") {
+		t.Errorf("telegram message = %+v, want the block sent as a file", got)
+	}
+}
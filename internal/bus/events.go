@@ -1,6 +1,9 @@
 package bus
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
 
 // InboundMessage represents a message received from any channel.
 type InboundMessage struct {
@@ -11,6 +14,25 @@ type InboundMessage struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Media     []string               `json:"media,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Trace     map[string]string      `json:"trace,omitempty"` // trace context of the span that received it
+	Command   *Command               `json:"command,omitempty"` // set when the channel recognised a command
+	QueueID   int64                  `json:"-"`                 // journal ID while the message is being processed
+}
+
+// Command is a bot command recognised by a channel, typed by the user or
+// sent by an inline button, such as "/model gpt-4o". The message content
+// still holds the command as text.
+type Command struct {
+	Name string   `json:"name"` // lower case, without the slash
+	Args []string `json:"args,omitempty"`
+}
+
+// Arg returns the i-th argument, or "" if there are fewer.
+func (c *Command) Arg(i int) string {
+	if i < len(c.Args) {
+		return c.Args[i]
+	}
+	return ""
 }
 
 // SessionKey returns a unique identifier for the conversation session.
@@ -18,6 +40,39 @@ func (m *InboundMessage) SessionKey() string {
 	return m.Channel + ":" + m.ChatID
 }
 
+// MessageID returns the channel's ID for the message, from the "messageId"
+// metadata, or "" if the channel does not report one. Replies use it to
+// quote the message they answer.
+func (m *InboundMessage) MessageID() string {
+	switch id := m.Metadata["messageId"].(type) {
+	case int:
+		return strconv.Itoa(id)
+	case float64: // decoded from JSON in a cluster
+		return strconv.FormatInt(int64(id), 10)
+	case string:
+		return id
+	}
+	return ""
+}
+
+// Files returns the paths of the files the user sent with the message, from
+// the "files" metadata. Channels save them in the chat's files directory.
+func (m *InboundMessage) Files() []string {
+	switch files := m.Metadata["files"].(type) {
+	case []string:
+		return files
+	case []interface{}: // decoded from JSON in a cluster
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			if path, ok := f.(string); ok {
+				paths = append(paths, path)
+			}
+		}
+		return paths
+	}
+	return nil
+}
+
 // OutboundMessage represents a message to be sent to a channel.
 type OutboundMessage struct {
 	Channel  string                 `json:"channel"`
@@ -25,5 +80,30 @@ type OutboundMessage struct {
 	Content  string                 `json:"content"`
 	ReplyTo  string                 `json:"replyTo,omitempty"`
 	Media    []string               `json:"media,omitempty"`
+	Buttons  []Button               `json:"buttons,omitempty"`
+	Files    []File                 `json:"files,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Trace    map[string]string      `json:"trace,omitempty"` // trace context of the turn that produced it
+
+	// Stream identifies a reply shown while it is generated. Messages with
+	// Partial set carry the reply so far; the message without it is the
+	// final reply, which replaces them.
+	Stream  string `json:"stream,omitempty"`
+	Partial bool   `json:"partial,omitempty"`
+}
+
+// File is a document sent along with an outbound message, such as a code
+// block too long to read comfortably inline or a file from send_file.
+type File struct {
+	Name    string `json:"name"`
+	Data    []byte `json:"data"`
+	Caption string `json:"caption,omitempty"` // shown with the file
+}
+
+// Button is a quick reply offered with an outbound message. Channels that
+// support it show an inline button that sends Data back as the user's
+// message; others show only the message content.
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"data"`
 }
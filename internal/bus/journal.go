@@ -0,0 +1,129 @@
+package bus
+
+import (
+	"log"
+	"time"
+)
+
+// DefaultMaxAttempts is how often a journaled message is delivered before
+// it is moved to the dead letters.
+const DefaultMaxAttempts = 3
+
+// Journal persists inbound messages until they have been processed, so
+// that a crash or restart does not lose them and a burst of messages waits
+// on disk instead of blocking the channels that receive them. Delivery is
+// at least once: a message claimed but not acknowledged before a crash is
+// delivered again on startup.
+type Journal interface {
+	// Append stores msg as pending and returns its ID.
+	Append(msg InboundMessage) (int64, error)
+	// Claim returns the oldest pending message, marked as in progress with
+	// its QueueID set, or false when none is pending.
+	Claim() (InboundMessage, bool, error)
+	// Ack removes a processed message.
+	Ack(id int64) error
+	// Fail records a failed delivery. The message is pending again unless
+	// it has used up its attempts, in which case it becomes a dead letter
+	// and Fail reports true.
+	Fail(id int64, reason string) (bool, error)
+	// Close closes the journal.
+	Close() error
+}
+
+// DeadLetter is a message given up on after repeated failures.
+type DeadLetter struct {
+	ID       int64
+	Message  InboundMessage
+	Attempts int
+	Reason   string // the last failure
+	FailedAt time.Time
+}
+
+// SetJournal makes the bus keep inbound messages in j rather than only in
+// memory. Messages left pending in j, for example by a crash, are delivered
+// again from the next consume.
+func (b *MessageBus) SetJournal(j Journal) {
+	b.mu.Lock()
+	b.journal = j
+	b.mu.Unlock()
+	b.wakeConsumer()
+}
+
+// getJournal returns the bus's journal, if any.
+func (b *MessageBus) getJournal() Journal {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.journal
+}
+
+// wakeConsumer tells a waiting consumer that the journal has a message.
+func (b *MessageBus) wakeConsumer() {
+	select {
+	case b.journaled <- struct{}{}:
+	default:
+	}
+}
+
+// appendJournal stores msg in the journal, reporting false when there is
+// no journal or it cannot be written, so the caller falls back to the
+// in-memory channel.
+func (b *MessageBus) appendJournal(msg InboundMessage) bool {
+	j := b.getJournal()
+	if j == nil {
+		return false
+	}
+	if _, err := j.Append(msg); err != nil {
+		log.Printf("Warning: failed to journal inbound message, keeping it in memory: %v", err)
+		return false
+	}
+	b.wakeConsumer()
+	return true
+}
+
+// claimJournal returns the oldest pending journaled message, if any.
+func (b *MessageBus) claimJournal() (InboundMessage, bool) {
+	j := b.getJournal()
+	if j == nil {
+		return InboundMessage{}, false
+	}
+	msg, ok, err := j.Claim()
+	if err != nil {
+		log.Printf("Warning: failed to read inbound journal: %v", err)
+		return InboundMessage{}, false
+	}
+	return msg, ok
+}
+
+// Ack marks msg as processed, removing it from the journal. It does nothing
+// for messages that were not journaled.
+func (b *MessageBus) Ack(msg InboundMessage) {
+	j := b.getJournal()
+	if j == nil || msg.QueueID == 0 {
+		return
+	}
+	if err := j.Ack(msg.QueueID); err != nil {
+		log.Printf("Warning: failed to acknowledge inbound message %d: %v", msg.QueueID, err)
+	}
+}
+
+// Fail records that processing msg failed. The message is delivered again
+// unless it has used up its attempts; Fail then reports true and the
+// message is kept as a dead letter. Messages that were not journaled are
+// not retried.
+func (b *MessageBus) Fail(msg InboundMessage, reason string) bool {
+	j := b.getJournal()
+	if j == nil || msg.QueueID == 0 {
+		return true
+	}
+	dead, err := j.Fail(msg.QueueID, reason)
+	if err != nil {
+		log.Printf("Warning: failed to record failure of inbound message %d: %v", msg.QueueID, err)
+		return false
+	}
+	if dead {
+		log.Printf("Warning: inbound message %d moved to dead letters: %s", msg.QueueID, reason)
+	} else {
+		b.wakeConsumer()
+	}
+	return dead
+}
@@ -0,0 +1,223 @@
+//go:build !lite && !nosqlite
+
+package bus
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+const journalSchema = `
+CREATE TABLE IF NOT EXISTS inbound (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	message    TEXT NOT NULL,
+	state      TEXT NOT NULL,
+	attempts   INTEGER NOT NULL DEFAULT 0,
+	reason     TEXT NOT NULL DEFAULT '',
+	updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS inbound_state ON inbound (state, id);
+`
+
+// Journal entry states.
+const (
+	journalPending = "pending" // waiting to be delivered
+	journalClaimed = "claimed" // delivered, not yet acknowledged
+	journalDead    = "dead"    // given up on
+)
+
+// SQLiteJournal is a Journal kept in a SQLite database. Each change is one
+// statement or transaction, so a crash never loses an appended message.
+type SQLiteJournal struct {
+	db          *sql.DB
+	maxAttempts int
+}
+
+// OpenJournal opens or creates the journal at path. A message is delivered
+// at most maxAttempts times (DefaultMaxAttempts when not positive) before
+// it becomes a dead letter.
+func OpenJournal(path string, maxAttempts int) (*SQLiteJournal, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open inbound journal: %w", err)
+	}
+	// A single connection serializes writers within the process
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(journalSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create inbound journal: %w", err)
+	}
+	return &SQLiteJournal{db: db, maxAttempts: maxAttempts}, nil
+}
+
+// Recover makes messages claimed but not acknowledged before a crash or
+// restart pending again, or dead letters when they have used up their
+// attempts, since a message that keeps crashing the process should not
+// crash it forever. It must only be called by the journal's consumer, on
+// startup, and returns how many messages are pending.
+func (j *SQLiteJournal) Recover() (int, error) {
+	now := time.Now().UnixNano()
+	if _, err := j.db.Exec(`UPDATE inbound SET state = ?, reason = 'interrupted', updated_at = ? WHERE state = ? AND attempts >= ?`,
+		journalDead, now, journalClaimed, j.maxAttempts); err != nil {
+		return 0, fmt.Errorf("failed to recover inbound journal: %w", err)
+	}
+	if _, err := j.db.Exec(`UPDATE inbound SET state = ?, updated_at = ? WHERE state = ?`,
+		journalPending, now, journalClaimed); err != nil {
+		return 0, fmt.Errorf("failed to recover inbound journal: %w", err)
+	}
+	var pending int
+	err := j.db.QueryRow(`SELECT COUNT(*) FROM inbound WHERE state = ?`, journalPending).Scan(&pending)
+	return pending, err
+}
+
+// Close closes the database.
+func (j *SQLiteJournal) Close() error {
+	return j.db.Close()
+}
+
+// Append implements Journal.
+func (j *SQLiteJournal) Append(msg InboundMessage) (int64, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	res, err := j.db.Exec(`INSERT INTO inbound (message, state, updated_at) VALUES (?, ?, ?)`,
+		string(data), journalPending, time.Now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Claim implements Journal.
+func (j *SQLiteJournal) Claim() (InboundMessage, bool, error) {
+	tx, err := j.db.Begin()
+	if err != nil {
+		return InboundMessage{}, false, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var data string
+	err = tx.QueryRow(`SELECT id, message FROM inbound WHERE state = ? ORDER BY id LIMIT 1`, journalPending).Scan(&id, &data)
+	if err == sql.ErrNoRows {
+		return InboundMessage{}, false, nil
+	}
+	if err != nil {
+		return InboundMessage{}, false, err
+	}
+	if _, err := tx.Exec(`UPDATE inbound SET state = ?, attempts = attempts + 1, updated_at = ? WHERE id = ?`,
+		journalClaimed, time.Now().UnixNano(), id); err != nil {
+		return InboundMessage{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return InboundMessage{}, false, err
+	}
+
+	var msg InboundMessage
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		// Keep the row for inspection rather than failing every claim
+		_, _ = j.db.Exec(`UPDATE inbound SET state = ?, reason = ? WHERE id = ?`, journalDead, "unreadable message: "+err.Error(), id)
+		return InboundMessage{}, false, fmt.Errorf("message %d: %w", id, err)
+	}
+	msg.QueueID = id
+	return msg, true, nil
+}
+
+// Ack implements Journal.
+func (j *SQLiteJournal) Ack(id int64) error {
+	_, err := j.db.Exec(`DELETE FROM inbound WHERE id = ?`, id)
+	return err
+}
+
+// Fail implements Journal.
+func (j *SQLiteJournal) Fail(id int64, reason string) (bool, error) {
+	var attempts int
+	if err := j.db.QueryRow(`SELECT attempts FROM inbound WHERE id = ?`, id).Scan(&attempts); err != nil {
+		return false, err
+	}
+	state := journalPending
+	if attempts >= j.maxAttempts {
+		state = journalDead
+	}
+	_, err := j.db.Exec(`UPDATE inbound SET state = ?, reason = ?, updated_at = ? WHERE id = ?`,
+		state, reason, time.Now().UnixNano(), id)
+	return state == journalDead, err
+}
+
+// Counts returns how many messages are waiting or being processed, and how
+// many are dead letters.
+func (j *SQLiteJournal) Counts() (queued, dead int, err error) {
+	err = j.db.QueryRow(`SELECT COUNT(*) FILTER (WHERE state != ?), COUNT(*) FILTER (WHERE state = ?) FROM inbound`,
+		journalDead, journalDead).Scan(&queued, &dead)
+	return queued, dead, err
+}
+
+// DeadLetters returns the dead letters, oldest first.
+func (j *SQLiteJournal) DeadLetters() ([]DeadLetter, error) {
+	rows, err := j.db.Query(`SELECT id, message, attempts, reason, updated_at FROM inbound WHERE state = ? ORDER BY id`, journalDead)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var d DeadLetter
+		var data string
+		var failed int64
+		if err := rows.Scan(&d.ID, &data, &d.Attempts, &d.Reason, &failed); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(data), &d.Message)
+		d.FailedAt = time.Unix(0, failed)
+		letters = append(letters, d)
+	}
+	return letters, rows.Err()
+}
+
+// Retry makes the given dead letters, or all of them when no IDs are given,
+// pending again with fresh attempts. It returns how many were requeued.
+func (j *SQLiteJournal) Retry(ids ...int64) (int, error) {
+	where, args := deadFilter(ids)
+	args = append([]interface{}{journalPending, time.Now().UnixNano()}, args...)
+	return rowsAffected(j.db.Exec(`UPDATE inbound SET state = ?, attempts = 0, reason = '', updated_at = ?`+where, args...))
+}
+
+// Purge deletes the given dead letters, or all of them when no IDs are
+// given. It returns how many were deleted.
+func (j *SQLiteJournal) Purge(ids ...int64) (int, error) {
+	where, args := deadFilter(ids)
+	return rowsAffected(j.db.Exec(`DELETE FROM inbound`+where, args...))
+}
+
+// deadFilter returns the WHERE clause and arguments selecting the dead
+// letters with the given IDs, or all of them.
+func deadFilter(ids []int64) (string, []interface{}) {
+	where := " WHERE state = ?"
+	args := []interface{}{journalDead}
+	if len(ids) > 0 {
+		where += " AND id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	return where, args
+}
+
+// rowsAffected returns the number of rows a statement changed.
+func rowsAffected(res sql.Result, err error) (int, error) {
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
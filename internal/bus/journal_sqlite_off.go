@@ -0,0 +1,44 @@
+//go:build lite || nosqlite
+
+package bus
+
+import "fmt"
+
+// SQLiteJournal is not available: SQLite support is not compiled into this
+// build.
+type SQLiteJournal struct{}
+
+// OpenJournal reports that SQLite support is not compiled into this build.
+func OpenJournal(path string, maxAttempts int) (*SQLiteJournal, error) {
+	return nil, fmt.Errorf("the sqlite inbound journal is not included in this build")
+}
+
+// Close does nothing.
+func (j *SQLiteJournal) Close() error { return nil }
+
+// Recover is never called; OpenJournal does not return a journal.
+func (j *SQLiteJournal) Recover() (int, error) { return 0, nil }
+
+// Append is never called; OpenJournal does not return a journal.
+func (j *SQLiteJournal) Append(msg InboundMessage) (int64, error) { return 0, nil }
+
+// Claim is never called; OpenJournal does not return a journal.
+func (j *SQLiteJournal) Claim() (InboundMessage, bool, error) { return InboundMessage{}, false, nil }
+
+// Ack is never called; OpenJournal does not return a journal.
+func (j *SQLiteJournal) Ack(id int64) error { return nil }
+
+// Fail is never called; OpenJournal does not return a journal.
+func (j *SQLiteJournal) Fail(id int64, reason string) (bool, error) { return false, nil }
+
+// Counts is never called; OpenJournal does not return a journal.
+func (j *SQLiteJournal) Counts() (queued, dead int, err error) { return 0, 0, nil }
+
+// DeadLetters is never called; OpenJournal does not return a journal.
+func (j *SQLiteJournal) DeadLetters() ([]DeadLetter, error) { return nil, nil }
+
+// Retry is never called; OpenJournal does not return a journal.
+func (j *SQLiteJournal) Retry(ids ...int64) (int, error) { return 0, nil }
+
+// Purge is never called; OpenJournal does not return a journal.
+func (j *SQLiteJournal) Purge(ids ...int64) (int, error) { return 0, nil }
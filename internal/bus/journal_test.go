@@ -0,0 +1,118 @@
+//go:build !lite && !nosqlite
+
+package bus
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestJournal(t *testing.T, path string) *SQLiteJournal {
+	t.Helper()
+	j, err := OpenJournal(path, 2)
+	if err != nil {
+		t.Fatalf("OpenJournal: %v", err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+func TestJournalDelivery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.db")
+	j := openTestJournal(t, path)
+	b := NewMessageBus(1)
+	b.SetJournal(j)
+
+	// A burst larger than the channel does not block
+	for _, content := range []string{"one", "two", "three"} {
+		b.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "1", Content: content})
+	}
+
+	ctx := context.Background()
+	first, err := b.ConsumeInboundWithTimeout(ctx, time.Second)
+	if err != nil || first.Content != "one" || first.QueueID == 0 {
+		t.Fatalf("first = %+v, %v", first, err)
+	}
+	b.Ack(first)
+
+	// A failed message is delivered again, then given up on
+	second, _ := b.ConsumeInboundWithTimeout(ctx, time.Second)
+	if b.Fail(second, "boom") {
+		t.Fatal("Fail after one attempt reported a dead letter")
+	}
+	again, _ := b.ConsumeInboundWithTimeout(ctx, time.Second)
+	if again.Content != "two" {
+		t.Fatalf("redelivered %q, want %q", again.Content, "two")
+	}
+	if !b.Fail(again, "boom again") {
+		t.Fatal("Fail after the last attempt did not report a dead letter")
+	}
+
+	// "three" is claimed but not acknowledged before a crash
+	third, _ := b.ConsumeInboundWithTimeout(ctx, time.Second)
+	if third.Content != "three" {
+		t.Fatalf("third = %q", third.Content)
+	}
+	j.Close()
+
+	j = openTestJournal(t, path)
+	if n, err := j.Recover(); err != nil || n != 1 {
+		t.Fatalf("Recover() = %d, %v", n, err)
+	}
+	b = NewMessageBus(1)
+	b.SetJournal(j)
+	replayed, err := b.ConsumeInboundWithTimeout(ctx, time.Second)
+	if err != nil || replayed.Content != "three" {
+		t.Fatalf("replayed = %+v, %v", replayed, err)
+	}
+	b.Ack(replayed)
+	if _, err := b.ConsumeInboundWithTimeout(ctx, 50*time.Millisecond); err != ErrTimeout {
+		t.Errorf("consume from an empty journal: %v", err)
+	}
+
+	queued, dead, err := j.Counts()
+	if err != nil || queued != 0 || dead != 1 {
+		t.Errorf("Counts() = %d, %d, %v", queued, dead, err)
+	}
+	letters, err := j.DeadLetters()
+	if err != nil || len(letters) != 1 || letters[0].Message.Content != "two" || letters[0].Attempts != 2 || letters[0].Reason != "boom again" {
+		t.Fatalf("DeadLetters() = %+v, %v", letters, err)
+	}
+
+	if n, err := j.Retry(letters[0].ID); err != nil || n != 1 {
+		t.Fatalf("Retry() = %d, %v", n, err)
+	}
+	retried, _ := b.ConsumeInboundWithTimeout(ctx, time.Second)
+	if retried.Content != "two" {
+		t.Errorf("retried = %q", retried.Content)
+	}
+	b.Fail(retried, "boom")
+	retried, _ = b.ConsumeInboundWithTimeout(ctx, time.Second)
+	b.Fail(retried, "boom")
+	if n, err := j.Purge(); err != nil || n != 1 {
+		t.Errorf("Purge() = %d, %v", n, err)
+	}
+}
+
+func TestJournalInterruptedTooOften(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.db")
+	j := openTestJournal(t, path)
+	j.Append(InboundMessage{Content: "crashes the process"})
+
+	for i := 0; i < 2; i++ {
+		if _, ok, err := j.Claim(); !ok || err != nil {
+			t.Fatalf("Claim %d: %v, %v", i, ok, err)
+		}
+		j.Close()
+		j = openTestJournal(t, path)
+		j.Recover()
+	}
+	if _, ok, _ := j.Claim(); ok {
+		t.Error("message interrupted on every attempt was delivered again")
+	}
+	if letters, _ := j.DeadLetters(); len(letters) != 1 || letters[0].Reason != "interrupted" {
+		t.Errorf("DeadLetters() = %+v", letters)
+	}
+}
@@ -0,0 +1,197 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// offlineMinRetry is the first pause before checking whether the
+	// provider is back; it doubles up to offlineMaxRetry.
+	offlineMinRetry = 15 * time.Second
+	offlineMaxRetry = 5 * time.Minute
+)
+
+// offlineFile is the on-disk format of an OfflineQueue.
+type offlineFile struct {
+	Since time.Time        `json:"since"`
+	Held  []InboundMessage `json:"held"`
+}
+
+// OfflineQueue holds inbound messages that could not be answered because
+// the model provider was unreachable, and hands them back once it can be
+// reached again. Held messages are saved to disk so they survive a restart.
+type OfflineQueue struct {
+	path string
+
+	mu    sync.Mutex
+	since time.Time // when the provider became unreachable; zero when online
+	held  []InboundMessage
+}
+
+// NewOfflineQueue creates an OfflineQueue saved at path, loading messages
+// held before a restart.
+func NewOfflineQueue(path string) *OfflineQueue {
+	q := &OfflineQueue{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return q
+	}
+	var f offlineFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		log.Printf("Warning: ignoring unreadable offline queue %s: %v", path, err)
+		return q
+	}
+	q.since, q.held = f.Since, f.Held
+	return q
+}
+
+// Offline reports whether messages are being held.
+func (q *OfflineQueue) Offline() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return !q.since.IsZero()
+}
+
+// Hold queues msg until the provider is reachable. It reports whether msg
+// is the first message held from its chat since the provider went away, so
+// the caller can tell the user once that the answer will come later.
+func (q *OfflineQueue) Hold(msg InboundMessage) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.since.IsZero() {
+		q.since = time.Now()
+	}
+	first := true
+	for _, h := range q.held {
+		if h.SessionKey() == msg.SessionKey() {
+			first = false
+			break
+		}
+	}
+	q.held = append(q.held, msg)
+	if err := q.saveLocked(); err != nil {
+		log.Printf("Warning: failed to save offline queue: %v", err)
+	}
+	return first
+}
+
+// Run waits, with increasing pauses, for probe to succeed while messages
+// are held. It then publishes a notice to each waiting chat saying how long
+// the provider was away and how many answers follow, and passes the held
+// messages, oldest first, to replay. Messages that fail again are expected
+// to be held again by replay. Blocks until ctx is cancelled.
+func (q *OfflineQueue) Run(ctx context.Context, b *MessageBus, probe func(context.Context) error, replay func(InboundMessage)) {
+	delay := offlineMinRetry
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		if !q.Offline() {
+			delay = offlineMinRetry
+			continue
+		}
+		if err := probe(ctx); err != nil {
+			if delay *= 2; delay > offlineMaxRetry {
+				delay = offlineMaxRetry
+			}
+			continue
+		}
+		delay = offlineMinRetry
+		q.release(b, replay)
+	}
+}
+
+// release empties the queue, publishes a notice to each waiting chat and
+// passes the held messages, oldest first, to replay.
+func (q *OfflineQueue) release(b *MessageBus, replay func(InboundMessage)) {
+	since, held := q.drain()
+	log.Printf("Provider reachable again after %s; answering %d held message(s)", time.Since(since).Round(time.Second), len(held))
+
+	counts := make(map[string]int)
+	var chats []InboundMessage
+	for _, msg := range held {
+		if counts[msg.SessionKey()] == 0 {
+			chats = append(chats, msg)
+		}
+		counts[msg.SessionKey()]++
+	}
+	for _, msg := range chats {
+		b.PublishOutbound(OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: offlineNotice(time.Since(since), counts[msg.SessionKey()]),
+		})
+	}
+	for _, msg := range held {
+		replay(msg)
+	}
+}
+
+// drain empties the queue and returns when the outage began and the held
+// messages.
+func (q *OfflineQueue) drain() (time.Time, []InboundMessage) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	since, held := q.since, q.held
+	q.since, q.held = time.Time{}, nil
+	if err := os.Remove(q.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to remove offline queue: %v", err)
+	}
+	return since, held
+}
+
+func (q *OfflineQueue) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(offlineFile{Since: q.since, Held: q.held}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(q.path, data, 0o600)
+}
+
+// offlineNotice tells a user that the provider was unreachable for d and
+// that the answers to their n held messages follow.
+func offlineNotice(d time.Duration, n int) string {
+	return fmt.Sprintf("I was offline for %s; here are the answers to your %s.", humanDuration(d), count(n, "message"))
+}
+
+// DeliveryNotice tells a user that their chat could not be reached for d
+// and that n delayed messages follow.
+func DeliveryNotice(d time.Duration, n int) string {
+	return fmt.Sprintf("I was offline for %s; here are the %s I couldn't send.", humanDuration(d), count(n, "message"))
+}
+
+// count formats n with unit, pluralised: "1 minute", "3 minutes".
+func count(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// humanDuration formats d in whole minutes, hours or days.
+func humanDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "less than a minute"
+	case d < time.Hour:
+		return count(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return count(int(d/time.Hour), "hour")
+	}
+	return count(int(d/(24*time.Hour)), "day")
+}
@@ -0,0 +1,70 @@
+package bus
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOfflineQueueHoldAndRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "offline.json")
+	q := NewOfflineQueue(path)
+	if q.Offline() {
+		t.Fatal("new queue is offline")
+	}
+
+	msgs := []InboundMessage{
+		{Channel: "telegram", ChatID: "1", Content: "first"},
+		{Channel: "telegram", ChatID: "2", Content: "other chat"},
+		{Channel: "telegram", ChatID: "1", Content: "second"},
+	}
+	wantFirst := []bool{true, true, false}
+	for i, msg := range msgs {
+		if got := q.Hold(msg); got != wantFirst[i] {
+			t.Errorf("Hold(%q) = %v, want %v", msg.Content, got, wantFirst[i])
+		}
+	}
+
+	// Held messages survive a restart
+	q = NewOfflineQueue(path)
+	if !q.Offline() {
+		t.Fatal("reloaded queue is not offline")
+	}
+	q.since = time.Now().Add(-12 * time.Minute)
+
+	b := NewMessageBus(10)
+	var replayed []string
+	q.release(b, func(msg InboundMessage) { replayed = append(replayed, msg.Content) })
+
+	if len(replayed) != 3 || replayed[0] != "first" || replayed[1] != "other chat" || replayed[2] != "second" {
+		t.Errorf("replayed = %v", replayed)
+	}
+	notices := map[string]string{}
+	for i := 0; i < 2; i++ {
+		out := b.ConsumeOutbound()
+		notices[out.ChatID] = out.Content
+	}
+	if want := "I was offline for 12 minutes; here are the answers to your 2 messages."; notices["1"] != want {
+		t.Errorf("notice for chat 1 = %q, want %q", notices["1"], want)
+	}
+	if want := "I was offline for 12 minutes; here are the answers to your 1 message."; notices["2"] != want {
+		t.Errorf("notice for chat 2 = %q, want %q", notices["2"], want)
+	}
+	if q.Offline() || NewOfflineQueue(path).Offline() {
+		t.Error("queue still offline after release")
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	cases := map[time.Duration]string{
+		20 * time.Second: "less than a minute",
+		time.Minute:      "1 minute",
+		90 * time.Minute: "1 hour",
+		50 * time.Hour:   "2 days",
+	}
+	for d, want := range cases {
+		if got := humanDuration(d); got != want {
+			t.Errorf("humanDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
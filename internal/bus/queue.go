@@ -2,13 +2,21 @@ package bus
 
 import (
 	"context"
-	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/hkuds/ubot/internal/failure"
+	"github.com/hkuds/ubot/internal/tracing"
 )
 
+// flushPollInterval is how often Flush checks for unsent messages.
+const flushPollInterval = 20 * time.Millisecond
+
 // ErrTimeout is returned when a message receive operation times out.
-var ErrTimeout = errors.New("timeout waiting for message")
+var ErrTimeout = failure.New(failure.Timeout, "timeout waiting for message")
 
 // MessageBus provides a channel-based message passing system for inbound
 // and outbound messages with subscriber support.
@@ -17,8 +25,16 @@ type MessageBus struct {
 	outbound chan OutboundMessage
 
 	subscribers map[string][]func(OutboundMessage)
+	limits      map[string]int // maximum message length per channel
+	codeFiles   map[string]int // code block length sent as a file, per channel
+	streaming   map[string]bool
 	mu          sync.RWMutex
 
+	journal   Journal       // persists inbound messages when set
+	journaled chan struct{} // signals that the journal has a message
+
+	unsent atomic.Int64 // outbound messages published but not yet handed on
+
 	closed chan struct{}
 }
 
@@ -29,12 +45,20 @@ func NewMessageBus(bufferSize int) *MessageBus {
 		inbound:     make(chan InboundMessage, bufferSize),
 		outbound:    make(chan OutboundMessage, bufferSize),
 		subscribers: make(map[string][]func(OutboundMessage)),
+		limits:      make(map[string]int),
+		codeFiles:   make(map[string]int),
+		streaming:   make(map[string]bool),
+		journaled:   make(chan struct{}, 1),
 		closed:      make(chan struct{}),
 	}
 }
 
-// PublishInbound sends a message to the inbound channel.
+// PublishInbound sends a message to the inbound channel, or stores it in
+// the journal when the bus has one.
 func (b *MessageBus) PublishInbound(msg InboundMessage) {
+	if b.appendJournal(msg) {
+		return
+	}
 	select {
 	case <-b.closed:
 		return
@@ -44,29 +68,47 @@ func (b *MessageBus) PublishInbound(msg InboundMessage) {
 
 // ConsumeInbound blocks until an inbound message is available.
 func (b *MessageBus) ConsumeInbound() InboundMessage {
-	return <-b.inbound
+	for {
+		if msg, ok := b.claimJournal(); ok {
+			return msg
+		}
+		select {
+		case msg := <-b.inbound:
+			return msg
+		case <-b.journaled:
+		}
+	}
 }
 
 // ConsumeInboundWithTimeout waits for an inbound message with a timeout.
 // Returns ErrTimeout if no message is received within the specified duration.
+// Journaled messages must be passed to Ack or Fail once processed.
 func (b *MessageBus) ConsumeInboundWithTimeout(ctx context.Context, timeout time.Duration) (InboundMessage, error) {
 	timer := time.NewTimer(timeout)
 	defer timer.Stop()
 
-	select {
-	case msg := <-b.inbound:
-		return msg, nil
-	case <-timer.C:
-		return InboundMessage{}, ErrTimeout
-	case <-ctx.Done():
-		return InboundMessage{}, ctx.Err()
+	for {
+		if msg, ok := b.claimJournal(); ok {
+			return msg, nil
+		}
+		select {
+		case msg := <-b.inbound:
+			return msg, nil
+		case <-b.journaled:
+		case <-timer.C:
+			return InboundMessage{}, ErrTimeout
+		case <-ctx.Done():
+			return InboundMessage{}, ctx.Err()
+		}
 	}
 }
 
 // PublishOutbound sends a message to the outbound channel.
 func (b *MessageBus) PublishOutbound(msg OutboundMessage) {
+	b.unsent.Add(1)
 	select {
 	case <-b.closed:
+		b.unsent.Add(-1)
 		return
 	case b.outbound <- msg:
 	}
@@ -74,7 +116,27 @@ func (b *MessageBus) PublishOutbound(msg OutboundMessage) {
 
 // ConsumeOutbound blocks until an outbound message is available.
 func (b *MessageBus) ConsumeOutbound() OutboundMessage {
-	return <-b.outbound
+	msg := <-b.outbound
+	b.unsent.Add(-1)
+	return msg
+}
+
+// Flush waits until every outbound message published so far has been
+// delivered by the subscribers or handed to the cluster, or until ctx is
+// done. It is used on shutdown so that replies are not dropped.
+func (b *MessageBus) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(flushPollInterval)
+	defer ticker.Stop()
+	for b.unsent.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.closed:
+			return nil
+		case <-ticker.C:
+		}
+	}
+	return nil
 }
 
 // SubscribeOutbound registers a callback function to receive outbound
@@ -85,6 +147,80 @@ func (b *MessageBus) SubscribeOutbound(channel string, callback func(OutboundMes
 	b.subscribers[channel] = append(b.subscribers[channel], callback)
 }
 
+// SetMessageLimit declares the maximum length, in characters, of a message
+// on channel. Longer outbound messages are split with SplitMessage before
+// they reach the channel's subscribers.
+func (b *MessageBus) SetMessageLimit(channel string, maxChars int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limits[channel] = maxChars
+}
+
+// SetCodeBlocks enables code block formatting for outbound messages on
+// channel: code blocks without a language are labelled with the detected
+// one, and, when fileLimit is positive, blocks longer than fileLimit
+// characters are sent as files instead. See FormatCodeBlocks.
+func (b *MessageBus) SetCodeBlocks(channel string, fileLimit int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.codeFiles[channel] = fileLimit
+}
+
+// SetStreaming declares whether channel shows partial replies, usually by
+// editing a message as the reply grows. Partial messages for other channels
+// are dropped, so they only get the final reply.
+func (b *MessageBus) SetStreaming(channel string, enabled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.streaming[channel] = enabled
+}
+
+// formatCode applies the channel's code block formatting to msg.
+func (b *MessageBus) formatCode(msg OutboundMessage) OutboundMessage {
+	b.mu.RLock()
+	fileLimit, ok := b.codeFiles[msg.Channel]
+	b.mu.RUnlock()
+	if !ok {
+		return msg
+	}
+
+	content, files := FormatCodeBlocks(msg.Content, fileLimit)
+	msg.Content = content
+	if len(files) > 0 {
+		msg.Files = append(append([]File(nil), msg.Files...), files...)
+	}
+	return msg
+}
+
+// split divides msg into parts that fit the channel's message limit. Media,
+// files and the reply reference go with the first part, buttons with the
+// last.
+func (b *MessageBus) split(msg OutboundMessage) []OutboundMessage {
+	b.mu.RLock()
+	limit := b.limits[msg.Channel]
+	b.mu.RUnlock()
+
+	chunks := SplitMessage(msg.Content, limit)
+	if len(chunks) == 1 {
+		return []OutboundMessage{msg}
+	}
+	parts := make([]OutboundMessage, len(chunks))
+	for i, chunk := range chunks {
+		part := msg
+		part.Content = chunk
+		if i > 0 {
+			part.ReplyTo = ""
+			part.Media = nil
+			part.Files = nil
+		}
+		if i < len(chunks)-1 {
+			part.Buttons = nil
+		}
+		parts[i] = part
+	}
+	return parts
+}
+
 // DispatchOutbound runs a goroutine that dispatches outbound messages
 // to registered subscribers. It should be called once and will run until
 // the context is cancelled.
@@ -98,24 +234,50 @@ func (b *MessageBus) DispatchOutbound(ctx context.Context) {
 		case msg := <-b.outbound:
 			b.mu.RLock()
 			callbacks := b.subscribers[msg.Channel]
+			streaming := b.streaming[msg.Channel]
 			b.mu.RUnlock()
+			if msg.Partial && !streaming {
+				b.unsent.Add(-1)
+				continue
+			}
+			// Partial replies are drafts, shown as they are; only the final
+			// reply is formatted and split
+			parts := []OutboundMessage{msg}
+			if !msg.Partial {
+				parts = b.split(b.formatCode(msg))
+			}
 
+			// The message counts as sent once every subscriber has it
+			var sending sync.WaitGroup
+			sending.Add(len(callbacks))
+			go func() {
+				sending.Wait()
+				b.unsent.Add(-1)
+			}()
 			for _, cb := range callbacks {
 				go func(callback func(OutboundMessage)) {
+					defer sending.Done()
 					defer func() {
 						if r := recover(); r != nil {
 							// Panic recovered in subscriber callback
 							// In production, this could be logged
 						}
 					}()
-					callback(msg)
+					// Parts are delivered in order by the same goroutine
+					for _, part := range parts {
+						_, span := tracing.Start(tracing.Extract(ctx, part.Trace), "send "+part.Channel,
+							attribute.String("ubot.channel", part.Channel))
+						callback(part)
+						span.End()
+					}
 				}(cb)
 			}
 		}
 	}
 }
 
-// InboundSize returns the current number of messages in the inbound channel.
+// InboundSize returns the current number of messages in the inbound channel,
+// not counting journaled ones.
 func (b *MessageBus) InboundSize() int {
 	return len(b.inbound)
 }
@@ -2,7 +2,9 @@ package bus
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -14,6 +16,44 @@ func TestSessionKey(t *testing.T) {
 	}
 }
 
+func TestMessageID(t *testing.T) {
+	tests := []struct {
+		id   interface{}
+		want string
+	}{
+		{1234567, "1234567"},
+		{float64(1234567), "1234567"}, // after a JSON round trip
+		{"wamid.1", "wamid.1"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		msg := InboundMessage{Metadata: map[string]interface{}{"messageId": tt.id}}
+		if got := msg.MessageID(); got != tt.want {
+			t.Errorf("MessageID() with %#v = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestFilesSurviveJSON(t *testing.T) {
+	msg := InboundMessage{Metadata: map[string]interface{}{"files": []string{"/w/files/telegram_1/a.pdf"}}}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded InboundMessage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []InboundMessage{msg, decoded} {
+		if got := m.Files(); len(got) != 1 || got[0] != "/w/files/telegram_1/a.pdf" {
+			t.Errorf("Files() = %v", got)
+		}
+	}
+	if got := (&InboundMessage{}).Files(); got != nil {
+		t.Errorf("Files() without metadata = %v, want nil", got)
+	}
+}
+
 func TestNewMessageBus(t *testing.T) {
 	bus := NewMessageBus(10)
 	if bus == nil {
@@ -113,6 +153,70 @@ func TestSubscribeAndDispatchOutbound(t *testing.T) {
 	}
 }
 
+func TestFlushWaitsForDelivery(t *testing.T) {
+	bus := NewMessageBus(10)
+	var delivered atomic.Int32
+	bus.SubscribeOutbound("telegram", func(msg OutboundMessage) {
+		time.Sleep(50 * time.Millisecond)
+		delivered.Add(1)
+	})
+
+	// Nothing is delivered without a dispatcher
+	bus.PublishOutbound(OutboundMessage{Channel: "telegram", Content: "one"})
+	bus.PublishOutbound(OutboundMessage{Channel: "telegram", Content: "two"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := bus.Flush(ctx); err == nil {
+		t.Fatal("Flush returned before any message was delivered")
+	}
+
+	dispatchCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go bus.DispatchOutbound(dispatchCtx)
+	if err := bus.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := delivered.Load(); n != 2 {
+		t.Errorf("Flush returned after %d of 2 deliveries", n)
+	}
+}
+
+func TestDispatchPartialReplies(t *testing.T) {
+	bus := NewMessageBus(10)
+	bus.SetMessageLimit("telegram", 20)
+	bus.SetStreaming("telegram", true)
+
+	var mu sync.Mutex
+	received := make(map[string][]OutboundMessage)
+	for _, channel := range []string{"telegram", "whatsapp"} {
+		bus.SubscribeOutbound(channel, func(msg OutboundMessage) {
+			mu.Lock()
+			received[msg.Channel] = append(received[msg.Channel], msg)
+			mu.Unlock()
+		})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bus.DispatchOutbound(ctx)
+
+	draft := "a draft that is longer than the limit"
+	for _, channel := range []string{"telegram", "whatsapp"} {
+		bus.PublishOutbound(OutboundMessage{Channel: channel, Content: draft, Stream: "s1", Partial: true})
+	}
+	if err := bus.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := received["telegram"]; len(got) != 1 || got[0].Content != draft {
+		t.Errorf("telegram got %+v, want the draft unsplit", got)
+	}
+	if got := received["whatsapp"]; len(got) != 0 {
+		t.Errorf("whatsapp got %+v, want no drafts", got)
+	}
+}
+
 func TestCloseStopsPublish(t *testing.T) {
 	// Fill the buffer so next publish would block
 	bus := NewMessageBus(1)
@@ -133,3 +237,73 @@ func TestCloseStopsPublish(t *testing.T) {
 		t.Fatal("PublishInbound blocked after Close")
 	}
 }
+
+// memQueue is an in-memory Queue used to test the cluster bridges.
+type memQueue struct {
+	mu    sync.Mutex
+	items map[string][][]byte
+}
+
+func (q *memQueue) Push(_ context.Context, name string, data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.items == nil {
+		q.items = make(map[string][][]byte)
+	}
+	q.items[name] = append(q.items[name], data)
+	return nil
+}
+
+func (q *memQueue) Pop(ctx context.Context, name string, timeout time.Duration) ([]byte, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		q.mu.Lock()
+		if len(q.items[name]) > 0 {
+			data := q.items[name][0]
+			q.items[name] = q.items[name][1:]
+			q.mu.Unlock()
+			return data, nil
+		}
+		q.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	return nil, nil
+}
+
+func TestClusterBridgeRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := &memQueue{}
+	poller := NewMessageBus(10)
+	worker := NewMessageBus(10)
+
+	go poller.ExportInbound(ctx, q, "in")
+	go worker.ImportInbound(ctx, q, "in")
+	go worker.ExportOutbound(ctx, q, "out")
+	go poller.ImportOutbound(ctx, q, "out")
+
+	poller.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "1", Content: "ping"})
+
+	in, err := worker.ConsumeInboundWithTimeout(ctx, 2*time.Second)
+	if err != nil {
+		t.Fatalf("worker did not receive inbound message: %v", err)
+	}
+	if in.Content != "ping" || in.SessionKey() != "telegram:1" {
+		t.Errorf("unexpected inbound message: %+v", in)
+	}
+
+	worker.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "1", Content: "pong"})
+
+	deadline := time.After(2 * time.Second)
+	for poller.OutboundSize() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("poller did not receive outbound message")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if out := poller.ConsumeOutbound(); out.Content != "pong" {
+		t.Errorf("outbound content = %q, want %q", out.Content, "pong")
+	}
+}
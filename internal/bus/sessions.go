@@ -0,0 +1,65 @@
+package bus
+
+import "sync"
+
+// SessionWorkers processes work for many sessions at once while keeping
+// each session in order: the work submitted for a session runs one at a
+// time, in the order it was submitted, and at most limit run at once
+// across all sessions. A session's worker exists only while it has work.
+type SessionWorkers struct {
+	slots chan struct{}
+
+	mu     sync.Mutex
+	queues map[string][]func()
+}
+
+// NewSessionWorkers creates SessionWorkers that run at most limit pieces
+// of work at once (1 when limit is not positive).
+func NewSessionWorkers(limit int) *SessionWorkers {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &SessionWorkers{
+		slots:  make(chan struct{}, limit),
+		queues: make(map[string][]func()),
+	}
+}
+
+// Submit queues process behind the work already submitted for the session
+// with the given key and returns at once.
+func (w *SessionWorkers) Submit(key string, process func()) {
+	w.mu.Lock()
+	queue, busy := w.queues[key]
+	w.queues[key] = append(queue, process)
+	w.mu.Unlock()
+	if !busy {
+		go w.run(key)
+	}
+}
+
+// run works through the queue of a session until it is empty.
+func (w *SessionWorkers) run(key string) {
+	for {
+		w.mu.Lock()
+		process := w.queues[key][0]
+		w.mu.Unlock()
+
+		w.slots <- struct{}{}
+		func() {
+			defer func() { <-w.slots }()
+			process()
+		}()
+
+		w.mu.Lock()
+		// The finished work stays at the head of the queue while it runs,
+		// so that Submit knows the session has a worker
+		if queue := w.queues[key][1:]; len(queue) > 0 {
+			w.queues[key] = queue
+			w.mu.Unlock()
+			continue
+		}
+		delete(w.queues, key)
+		w.mu.Unlock()
+		return
+	}
+}
@@ -0,0 +1,85 @@
+package bus
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSessionWorkersKeepSessionOrder(t *testing.T) {
+	w := NewSessionWorkers(4)
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	var running [3]atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		for s := 0; s < 3; s++ {
+			key, s, i := fmt.Sprintf("telegram:%d", s), s, i
+			wg.Add(1)
+			w.Submit(key, func() {
+				defer wg.Done()
+				if running[s].Add(1) > 1 {
+					t.Errorf("two messages of %s processed at once", key)
+				}
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				got[key] = append(got[key], i)
+				mu.Unlock()
+				running[s].Add(-1)
+			})
+		}
+	}
+	wg.Wait()
+
+	for key, order := range got {
+		for i, n := range order {
+			if n != i {
+				t.Fatalf("%s processed in order %v", key, order)
+			}
+		}
+	}
+	if len(got) != 3 {
+		t.Errorf("processed %d sessions, want 3", len(got))
+	}
+}
+
+func TestSessionWorkersLimit(t *testing.T) {
+	w := NewSessionWorkers(2)
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for s := 0; s < 5; s++ {
+		wg.Add(1)
+		w.Submit(fmt.Sprintf("telegram:%d", s), func() {
+			defer wg.Done()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+		})
+	}
+
+	// Other sessions go ahead while one waits
+	deadline := time.Now().Add(time.Second)
+	for running.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := running.Load(); n != 2 {
+		t.Errorf("%d sessions processed at once, want 2", n)
+	}
+	close(release)
+	wg.Wait()
+	if p := peak.Load(); p != 2 {
+		t.Errorf("peak concurrency = %d, want 2", p)
+	}
+}
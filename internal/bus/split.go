@@ -0,0 +1,190 @@
+package bus
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitMessage splits text into chunks of at most limit characters. It
+// breaks between paragraphs where it can, otherwise between lines, and only
+// splits a line that is longer than a whole chunk. A code block cut in two
+// is closed at the end of one chunk and reopened, with its language, at the
+// start of the next, so each chunk renders on its own. A limit of zero or
+// less returns text unchanged.
+func SplitMessage(text string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	s := &splitter{limit: limit}
+	for _, line := range strings.Split(text, "\n") {
+		s.add(line)
+	}
+	s.emit(s.lines, s.fence)
+	if len(s.chunks) == 0 {
+		return []string{text}
+	}
+	return s.chunks
+}
+
+// splitter accumulates lines into chunks for SplitMessage.
+type splitter struct {
+	limit  int
+	chunks []string
+
+	lines []string // lines of the current chunk
+	size  int      // characters in lines joined by newlines
+	fence string   // opening line of the code block open at the end of lines
+	cut   int      // lines[:cut] end with a blank line outside code blocks; 0 = none
+}
+
+// add appends a line, first flushing the current chunk if the line does not
+// fit.
+func (s *splitter) add(line string) {
+	// Leave room to reopen and close a code block around the line
+	maxLine := s.limit - 2*(utf8.RuneCountInString(s.fence)+1)
+	if maxLine < s.limit/2 {
+		maxLine = s.limit / 2
+	}
+	if utf8.RuneCountInString(line) > maxLine {
+		for _, piece := range splitLine(line, maxLine) {
+			s.add(piece)
+		}
+		return
+	}
+
+	n := utf8.RuneCountInString(line)
+	for len(s.lines) > 0 && s.size+1+n+s.closingSize(line) > s.limit {
+		if !s.flush() {
+			break
+		}
+	}
+
+	if len(s.lines) > 0 {
+		s.size++
+	}
+	s.lines = append(s.lines, line)
+	s.size += n
+
+	if isFence(line) {
+		if s.fence == "" {
+			s.fence = strings.TrimSpace(line)
+		} else {
+			s.fence = ""
+		}
+	}
+	if s.fence == "" && strings.TrimSpace(line) == "" {
+		s.cut = len(s.lines)
+	}
+}
+
+// closingSize is the room needed to close a code block that is open after
+// line is added.
+func (s *splitter) closingSize(line string) int {
+	fence := s.fence
+	if isFence(line) {
+		if fence != "" {
+			return 0 // line closes the block
+		}
+		fence = strings.TrimSpace(line)
+	}
+	if fence == "" {
+		return 0
+	}
+	return 1 + len(fenceMarker(fence))
+}
+
+// flush emits the current chunk, preferring to end it at the last
+// paragraph break if that keeps at least half a chunk. It reports whether
+// any lines were emitted.
+func (s *splitter) flush() bool {
+	if s.cut > 0 && s.cut < len(s.lines) && joinedSize(s.lines[:s.cut]) >= s.limit/2 {
+		rest := append([]string(nil), s.lines[s.cut:]...)
+		s.emit(s.lines[:s.cut], "")
+		s.reset()
+		for _, line := range rest {
+			s.add(line)
+		}
+		return true
+	}
+
+	fence := s.fence
+	if fence != "" && len(s.lines) == 1 {
+		return false // only the reopened fence line; nothing to emit
+	}
+	s.emit(s.lines, fence)
+	s.reset()
+	if fence != "" {
+		s.lines = []string{fence}
+		s.size = utf8.RuneCountInString(fence)
+		s.fence = fence
+	}
+	return true
+}
+
+// emit adds lines as a chunk, closing the open code block fence if any.
+func (s *splitter) emit(lines []string, fence string) {
+	chunk := strings.TrimRight(strings.Join(lines, "\n"), "\n ")
+	if fence != "" {
+		chunk += "\n" + fenceMarker(fence)
+	}
+	if strings.TrimSpace(chunk) != "" && chunk != fence+"\n"+fenceMarker(fence) {
+		s.chunks = append(s.chunks, chunk)
+	}
+}
+
+func (s *splitter) reset() {
+	s.lines = nil
+	s.size = 0
+	s.fence = ""
+	s.cut = 0
+}
+
+// joinedSize returns the characters in lines joined by newlines.
+func joinedSize(lines []string) int {
+	n := len(lines) - 1
+	for _, l := range lines {
+		n += utf8.RuneCountInString(l)
+	}
+	return n
+}
+
+// isFence reports whether line opens or closes a fenced code block.
+func isFence(line string) bool {
+	t := strings.TrimSpace(line)
+	return strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~")
+}
+
+// fenceMarker returns the run of backticks or tildes that starts fence.
+func fenceMarker(fence string) string {
+	if fence == "" {
+		return ""
+	}
+	end := 0
+	for end < len(fence) && fence[end] == fence[0] {
+		end++
+	}
+	return fence[:end]
+}
+
+// splitLine cuts a line into pieces of at most max characters, at the last
+// space where possible.
+func splitLine(line string, max int) []string {
+	var pieces []string
+	runes := []rune(line)
+	for len(runes) > max {
+		cut := max
+		for i := max; i > max/2; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		pieces = append(pieces, string(runes[:cut]))
+		runes = runes[cut:]
+		if len(runes) > 0 && runes[0] == ' ' {
+			runes = runes[1:]
+		}
+	}
+	return append(pieces, string(runes))
+}
@@ -0,0 +1,133 @@
+package bus
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessageShortText(t *testing.T) {
+	for _, limit := range []int{0, 100} {
+		got := SplitMessage("hello\n\nworld", limit)
+		if len(got) != 1 || got[0] != "hello\n\nworld" {
+			t.Errorf("SplitMessage(limit=%d) = %q, want the text unchanged", limit, got)
+		}
+	}
+}
+
+func TestSplitMessagePrefersParagraphs(t *testing.T) {
+	para := strings.TrimSpace(strings.Repeat("word ", 12)) // 59 chars
+	text := para + "\n\n" + para + "\n\n" + para
+
+	chunks := SplitMessage(text, 130)
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2: %q", len(chunks), chunks)
+	}
+	if chunks[0] != para+"\n\n"+para || chunks[1] != para {
+		t.Errorf("chunks not split at paragraph break: %q", chunks)
+	}
+}
+
+func TestSplitMessageRespectsLimit(t *testing.T) {
+	text := strings.Repeat("ünïcode line of text\n", 200)
+	chunks := SplitMessage(text, 100)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	for i, c := range chunks {
+		if n := utf8.RuneCountInString(c); n > 100 {
+			t.Errorf("chunk %d has %d chars, over the limit", i, n)
+		}
+	}
+	if got := strings.Join(chunks, "\n"); got != strings.TrimRight(text, "\n") {
+		t.Error("rejoined chunks do not match the original text")
+	}
+}
+
+func TestSplitMessageLongLine(t *testing.T) {
+	line := strings.TrimSpace(strings.Repeat("abcdefghi ", 30)) // 299 chars
+	chunks := SplitMessage(line, 100)
+	for i, c := range chunks {
+		if n := utf8.RuneCountInString(c); n > 100 {
+			t.Errorf("chunk %d has %d chars, over the limit", i, n)
+		}
+		if strings.HasPrefix(c, " ") || strings.HasSuffix(c, " ") {
+			t.Errorf("chunk %d was not cut at a space: %q", i, c)
+		}
+	}
+	if got := strings.Join(chunks, " "); got != line {
+		t.Errorf("rejoined chunks = %q, want the original line", got)
+	}
+}
+
+func TestSplitMessageReopensCodeFence(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("Here is the code:\n\n```go\n")
+	for i := 0; i < 20; i++ {
+		sb.WriteString("fmt.Println(\"line\")\n")
+	}
+	sb.WriteString("```\n\nDone.")
+
+	chunks := SplitMessage(sb.String(), 150)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	for i, c := range chunks {
+		if n := utf8.RuneCountInString(c); n > 150 {
+			t.Errorf("chunk %d has %d chars, over the limit", i, n)
+		}
+		if strings.Count(c, "```")%2 != 0 {
+			t.Errorf("chunk %d leaves a code block open: %q", i, c)
+		}
+		if i > 0 && strings.Contains(c, "fmt.Println") && !strings.HasPrefix(c, "```go\n") {
+			t.Errorf("chunk %d does not reopen the code block with its language: %q", i, c)
+		}
+	}
+	if last := chunks[len(chunks)-1]; !strings.HasSuffix(last, "Done.") {
+		t.Errorf("last chunk = %q, want the closing text", last)
+	}
+}
+
+func TestDispatchOutboundSplitsByChannelLimit(t *testing.T) {
+	bus := NewMessageBus(10)
+	bus.SetMessageLimit("telegram", 20)
+
+	var mu sync.Mutex
+	var received []OutboundMessage
+	var wg sync.WaitGroup
+	wg.Add(3)
+	bus.SubscribeOutbound("telegram", func(msg OutboundMessage) {
+		mu.Lock()
+		received = append(received, msg)
+		mu.Unlock()
+		wg.Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go bus.DispatchOutbound(ctx)
+
+	bus.PublishOutbound(OutboundMessage{
+		Channel: "telegram",
+		Content: "first part\n\nsecond part\n\nthird part",
+		ReplyTo: "42",
+		Buttons: []Button{{Text: "OK", Data: "ok"}},
+	})
+
+	wg.Wait()
+	cancel()
+
+	want := []string{"first part", "second part", "third part"}
+	for i, msg := range received {
+		if msg.Content != want[i] {
+			t.Errorf("part %d = %q, want %q", i, msg.Content, want[i])
+		}
+		if (msg.ReplyTo != "") != (i == 0) {
+			t.Errorf("part %d ReplyTo = %q", i, msg.ReplyTo)
+		}
+		if (len(msg.Buttons) > 0) != (i == len(want)-1) {
+			t.Errorf("part %d has %d buttons", i, len(msg.Buttons))
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Consumer groups reading the shared streams. Every channel has its own
+// inbound and outbound stream, each read by one group.
+const (
+	InboundGroup  = "workers" // reads inbound streams and processes messages
+	OutboundGroup = "pollers" // reads outbound streams and sends replies
+)
+
+// StreamEntry is an entry read from a shared stream.
+type StreamEntry struct {
+	Stream string
+	ID     string
+	Data   []byte // nil when the entry was trimmed before it was read
+}
+
+// Stream is a shared log read by consumer groups, for example Redis
+// streams. Each entry is delivered to one consumer of a group and stays
+// pending until acknowledged, so the entries of an instance that died can
+// be taken over by another.
+type Stream interface {
+	Add(ctx context.Context, stream string, data []byte) error
+	CreateGroup(ctx context.Context, stream, group string) error
+	// Read returns new entries for consumer, blocking up to timeout, or
+	// with pending the entries delivered to consumer but not acknowledged.
+	Read(ctx context.Context, group, consumer string, streams []string, pending bool, timeout time.Duration) ([]StreamEntry, error)
+	// Claim takes over entries left unacknowledged by other consumers for
+	// at least minIdle.
+	Claim(ctx context.Context, stream, group, consumer string, minIdle time.Duration) ([]StreamEntry, error)
+	Ack(ctx context.Context, stream, group, id string) error
+}
+
+// StreamReader names a consumer reading the streams of some channels.
+type StreamReader struct {
+	Prefix    string        // key prefix of the streams, e.g. "ubot:"
+	Channels  []string      // channels whose streams are read
+	Consumer  string        // this instance's name within the group
+	ClaimIdle time.Duration // take over other consumers' entries idle this long; 0 = never
+}
+
+// InboundStream returns the name of channel's inbound stream.
+func InboundStream(prefix, channel string) string {
+	return prefix + "inbound:" + channel
+}
+
+// OutboundStream returns the name of channel's outbound stream.
+func OutboundStream(prefix, channel string) string {
+	return prefix + "outbound:" + channel
+}
+
+// ExportInboundStream moves locally published inbound messages to their
+// channel's inbound stream. It is the stream counterpart of ExportInbound.
+// Blocks until ctx is cancelled.
+func (b *MessageBus) ExportInboundStream(ctx context.Context, s Stream, prefix string) {
+	for {
+		select {
+		case <-b.closed:
+			return
+		default:
+		}
+		msg, err := b.ConsumeInboundWithTimeout(ctx, clusterPopTimeout)
+		if err == ErrTimeout {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if addJSON(ctx, s, InboundStream(prefix, msg.Channel), msg) {
+			b.Ack(msg)
+		}
+	}
+}
+
+// ImportInboundStream feeds the entries of the channels' inbound streams
+// into the local bus as a consumer of InboundGroup. Entries are
+// acknowledged once published locally; a journal (SetJournal) keeps them
+// from then on. Blocks until ctx is cancelled.
+func (b *MessageBus) ImportInboundStream(ctx context.Context, s Stream, r StreamReader) {
+	streams := make([]string, len(r.Channels))
+	for i, channel := range r.Channels {
+		streams[i] = InboundStream(r.Prefix, channel)
+	}
+	readStreams(ctx, s, InboundGroup, streams, r, func(data []byte) {
+		var msg InboundMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("bus: dropping malformed inbound message: %v", err)
+			return
+		}
+		b.PublishInbound(msg)
+	})
+}
+
+// ExportOutboundStream moves locally published outbound messages to their
+// channel's outbound stream instead of dispatching them to local
+// subscribers. Blocks until ctx is cancelled.
+func (b *MessageBus) ExportOutboundStream(ctx context.Context, s Stream, prefix string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.closed:
+			return
+		case msg := <-b.outbound:
+			addJSON(ctx, s, OutboundStream(prefix, msg.Channel), msg)
+			b.unsent.Add(-1)
+		}
+	}
+}
+
+// ImportOutboundStream feeds the entries of the channels' outbound streams
+// into the local bus as a consumer of OutboundGroup, where
+// DispatchOutbound delivers them. Blocks until ctx is cancelled.
+func (b *MessageBus) ImportOutboundStream(ctx context.Context, s Stream, r StreamReader) {
+	streams := make([]string, len(r.Channels))
+	for i, channel := range r.Channels {
+		streams[i] = OutboundStream(r.Prefix, channel)
+	}
+	readStreams(ctx, s, OutboundGroup, streams, r, func(data []byte) {
+		var msg OutboundMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("bus: dropping malformed outbound message: %v", err)
+			return
+		}
+		b.PublishOutbound(msg)
+	})
+}
+
+// addJSON encodes v and adds it to stream, retrying until it succeeds or
+// ctx is cancelled. It reports false when ctx was cancelled first.
+func addJSON(ctx context.Context, s Stream, stream string, v interface{}) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("bus: failed to encode message for %s: %v", stream, err)
+		return true
+	}
+	for {
+		err := s.Add(ctx, stream, data)
+		if err == nil {
+			return true
+		}
+		log.Printf("bus: add to %s failed: %v", stream, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(clusterRetryDelay):
+		}
+	}
+}
+
+// readStreams reads streams as r.Consumer of group and passes each entry to
+// handle before acknowledging it. It first handles the entries delivered
+// to this consumer before a restart, then new ones, and every r.ClaimIdle
+// takes over entries other consumers left unacknowledged. Blocks until ctx
+// is cancelled.
+func readStreams(ctx context.Context, s Stream, group string, streams []string, r StreamReader, handle func([]byte)) {
+	if len(streams) == 0 {
+		return
+	}
+	for _, stream := range streams {
+		for {
+			err := s.CreateGroup(ctx, stream, group)
+			if err == nil {
+				break
+			}
+			log.Printf("bus: failed to create group %s on %s: %v", group, stream, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(clusterRetryDelay):
+			}
+		}
+	}
+
+	settle := func(entries []StreamEntry) {
+		for _, e := range entries {
+			if e.Data != nil {
+				handle(e.Data)
+			}
+			if err := s.Ack(ctx, e.Stream, group, e.ID); err != nil {
+				log.Printf("bus: failed to acknowledge %s %s: %v", e.Stream, e.ID, err)
+			}
+		}
+	}
+
+	pending := true
+	lastClaim := time.Now()
+	for ctx.Err() == nil {
+		if r.ClaimIdle > 0 && time.Since(lastClaim) >= r.ClaimIdle {
+			lastClaim = time.Now()
+			for _, stream := range streams {
+				entries, err := s.Claim(ctx, stream, group, r.Consumer, r.ClaimIdle)
+				if err != nil {
+					log.Printf("bus: failed to claim idle entries of %s: %v", stream, err)
+					continue
+				}
+				settle(entries)
+			}
+		}
+
+		entries, err := s.Read(ctx, group, r.Consumer, streams, pending, clusterPopTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("bus: read from %s failed: %v", group, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(clusterRetryDelay):
+			}
+			continue
+		}
+		if pending && len(entries) == 0 {
+			pending = false
+		}
+		settle(entries)
+	}
+}
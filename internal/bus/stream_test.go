@@ -0,0 +1,161 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStream is an in-memory Stream with a single consumer group per
+// stream, enough for the bridges.
+type memStream struct {
+	mu      sync.Mutex
+	seq     int
+	entries map[string][]StreamEntry
+	read    map[string]int                    // entries delivered, per stream
+	pending map[string]map[string]StreamEntry // unacknowledged entries by ID, per consumer
+}
+
+func newMemStream() *memStream {
+	return &memStream{
+		entries: make(map[string][]StreamEntry),
+		read:    make(map[string]int),
+		pending: make(map[string]map[string]StreamEntry),
+	}
+}
+
+func (m *memStream) Add(ctx context.Context, stream string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	m.entries[stream] = append(m.entries[stream], StreamEntry{Stream: stream, ID: fmt.Sprint(m.seq), Data: data})
+	return nil
+}
+
+func (m *memStream) CreateGroup(ctx context.Context, stream, group string) error { return nil }
+
+func (m *memStream) deliver(consumer string, e StreamEntry) {
+	if m.pending[consumer] == nil {
+		m.pending[consumer] = make(map[string]StreamEntry)
+	}
+	m.pending[consumer][e.ID] = e
+}
+
+func (m *memStream) Read(ctx context.Context, group, consumer string, streams []string, pending bool, timeout time.Duration) ([]StreamEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []StreamEntry
+	for _, stream := range streams {
+		if pending {
+			for _, e := range m.pending[consumer] {
+				if e.Stream == stream {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		for _, e := range m.entries[stream][m.read[stream]:] {
+			m.deliver(consumer, e)
+			out = append(out, e)
+		}
+		m.read[stream] = len(m.entries[stream])
+	}
+	if len(out) == 0 && !pending {
+		m.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		m.mu.Lock()
+	}
+	return out, nil
+}
+
+func (m *memStream) Claim(ctx context.Context, stream, group, consumer string, minIdle time.Duration) ([]StreamEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []StreamEntry
+	for owner, entries := range m.pending {
+		if owner == consumer {
+			continue
+		}
+		for id, e := range entries {
+			if e.Stream == stream {
+				delete(entries, id)
+				m.deliver(consumer, e)
+				out = append(out, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *memStream) Ack(ctx context.Context, stream, group, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entries := range m.pending {
+		delete(entries, id)
+	}
+	return nil
+}
+
+func (m *memStream) unacknowledged() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, entries := range m.pending {
+		n += len(entries)
+	}
+	return n
+}
+
+func TestStreamBridges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newMemStream()
+
+	// An entry a crashed worker read but never handed on
+	s.Add(ctx, InboundStream("ubot:", "telegram"), []byte(`{"channel":"telegram","chatId":"1","content":"orphaned"}`))
+	s.Read(ctx, InboundGroup, "crashed", []string{InboundStream("ubot:", "telegram")}, false, 0)
+
+	poller := NewMessageBus(10)
+	worker := NewMessageBus(10)
+	go poller.ExportInboundStream(ctx, s, "ubot:")
+	go poller.ImportOutboundStream(ctx, s, StreamReader{Prefix: "ubot:", Channels: []string{"telegram"}, Consumer: "poller"})
+	go worker.ImportInboundStream(ctx, s, StreamReader{Prefix: "ubot:", Channels: []string{"telegram", "whatsapp"}, Consumer: "worker", ClaimIdle: 20 * time.Millisecond})
+	go worker.ExportOutboundStream(ctx, s, "ubot:")
+
+	poller.PublishInbound(InboundMessage{Channel: "whatsapp", ChatID: "2", Content: "hello"})
+
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		msg, err := worker.ConsumeInboundWithTimeout(ctx, 2*time.Second)
+		if err != nil {
+			t.Fatalf("worker received %v, then %v", got, err)
+		}
+		got[msg.Content] = true
+	}
+	if !got["hello"] || !got["orphaned"] {
+		t.Errorf("worker received %v", got)
+	}
+
+	worker.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "1", Content: "reply"})
+	done := make(chan OutboundMessage, 1)
+	go func() { done <- poller.ConsumeOutbound() }()
+	select {
+	case out := <-done:
+		if out.Content != "reply" {
+			t.Errorf("poller received %+v", out)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reply did not reach the poller")
+	}
+
+	// Entries are acknowledged right after they are handed on
+	deadline := time.Now().Add(2 * time.Second)
+	for s.unacknowledged() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d entries left unacknowledged", s.unacknowledged())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,168 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// caldavFile holds the CalDAV password, in the credentials directory.
+const caldavFile = "caldav.json"
+
+// maxResponseBytes limits the size of a calendar server response.
+const maxResponseBytes = 10 << 20
+
+// CalDAV is a calendar collection on a CalDAV server such as Nextcloud,
+// Fastmail or iCloud.
+type CalDAV struct {
+	url         string // the calendar collection
+	username    string
+	credentials string // file holding the password
+	client      *http.Client
+}
+
+// calendarQuery asks for the events overlapping a time range, with
+// recurring events expanded by the server.
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <C:calendar-data><C:expand start="%[1]s" end="%[2]s"/></C:calendar-data>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT"><C:time-range start="%[1]s" end="%[2]s"/></C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+
+// multistatus is a WebDAV REPORT response.
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Prop struct {
+				Data string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// Events returns the events overlapping from-to.
+func (c *CalDAV) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	body := fmt.Sprintf(calendarQuery, from.UTC().Format(icalUTC), to.UTC().Format(icalUTC))
+	resp, err := c.do(ctx, "REPORT", c.url, "application/xml; charset=utf-8", body, map[string]string{"Depth": "1"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read calendar: %w", err)
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("calendar query failed (status %d): %s", resp.StatusCode, snippet(data))
+	}
+
+	var ms multistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("parse calendar response: %w", err)
+	}
+	var events []Event
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			if ps.Prop.Data == "" {
+				continue
+			}
+			evs, err := parseICalEvents(ps.Prop.Data)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", r.Href, err)
+			}
+			for _, ev := range evs {
+				if ev.End.After(from) && ev.Start.Before(to) {
+					events = append(events, ev)
+				}
+			}
+		}
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+// Create stores ev as a new object in the collection.
+func (c *CalDAV) Create(ctx context.Context, ev Event) (Event, error) {
+	if ev.ID == "" {
+		ev.ID = newUID()
+	}
+	body := formatICalEvent(ev, time.Now())
+	target := strings.TrimSuffix(c.url, "/") + "/" + ev.ID + ".ics"
+	resp, err := c.do(ctx, http.MethodPut, target, "text/calendar; charset=utf-8", body, map[string]string{"If-None-Match": "*"})
+	if err != nil {
+		return Event{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return Event{}, fmt.Errorf("create event failed (status %d): %s", resp.StatusCode, snippet(data))
+	}
+	return ev, nil
+}
+
+// Busy returns the busy periods between from and to, worked out from the
+// events since servers rarely answer free-busy queries for a collection.
+func (c *CalDAV) Busy(ctx context.Context, from, to time.Time) ([]Period, error) {
+	events, err := c.Events(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return busyFromEvents(events, from, to), nil
+}
+
+// do sends a request authenticated with the stored password.
+func (c *CalDAV) do(ctx context.Context, method, target, contentType, body string, headers map[string]string) (*http.Response, error) {
+	password, err := loadCalDAVPassword(c.credentials)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.SetBasicAuth(c.username, password)
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calendar request failed: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, errors.New("the calendar server rejected the credentials; run 'ubot calendar login' again")
+	}
+	return resp, nil
+}
+
+// newUID returns a random identifier for a new event.
+func newUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b) + "@ubot"
+}
+
+// snippet returns the start of a response body for an error message.
+func snippet(data []byte) string {
+	s := strings.TrimSpace(string(data))
+	if len(s) > 200 {
+		s = strings.ToValidUTF8(s[:200], "") + "..."
+	}
+	return s
+}
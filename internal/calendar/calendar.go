@@ -0,0 +1,127 @@
+// Package calendar lists, creates and checks the free/busy time of events
+// in a CalDAV or Google calendar. Credentials are kept under
+// ~/.ubot/calendar and written by "ubot calendar login".
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// requestTimeout limits each request to the calendar server.
+const requestTimeout = 30 * time.Second
+
+// Event is an event in a calendar. All-day events start at midnight of
+// their first day and end at midnight after their last day, in local time.
+type Event struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	AllDay      bool      `json:"allDay,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Free        bool      `json:"free,omitempty"` // shown as free, so it does not make the time busy
+}
+
+// Period is a span of busy time.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Calendar is a calendar on a CalDAV server or in Google Calendar.
+type Calendar interface {
+	// Events returns the events overlapping from-to, by start time.
+	// Recurring events are expanded into their occurrences.
+	Events(ctx context.Context, from, to time.Time) ([]Event, error)
+	// Create adds ev to the calendar and returns it with its ID.
+	Create(ctx context.Context, ev Event) (Event, error)
+	// Busy returns the busy periods between from and to, merged and in
+	// order.
+	Busy(ctx context.Context, from, to time.Time) ([]Period, error)
+}
+
+// ErrNotLoggedIn is returned when the calendar's credentials have not been
+// stored yet.
+var ErrNotLoggedIn = errors.New("not logged in to the calendar; run 'ubot calendar login'")
+
+// New returns the calendar configured in cfg, with the credentials stored
+// in dir. It returns nil when no calendar is configured.
+func New(cfg config.CalendarConfig, dir string) (Calendar, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case config.CalendarCalDAV:
+		return &CalDAV{url: cfg.URL, username: cfg.Username, credentials: filepath.Join(dir, caldavFile), client: client}, nil
+	case config.CalendarGoogle:
+		calendarID := cfg.CalendarID
+		if calendarID == "" {
+			calendarID = "primary"
+		}
+		return &Google{
+			calendarID: calendarID,
+			auth:       newGoogleAuth(cfg.ClientID, cfg.ClientSecret, filepath.Join(dir, googleTokenFile), client),
+			client:     client,
+			base:       googleAPIBase,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown calendar provider %q", cfg.Provider)
+	}
+}
+
+// busyFromEvents merges the times of the events that are not free into
+// busy periods, clipped to from-to.
+func busyFromEvents(events []Event, from, to time.Time) []Period {
+	var periods []Period
+	for _, ev := range events {
+		if ev.Free {
+			continue
+		}
+		start, end := ev.Start, ev.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			periods = append(periods, Period{Start: start, End: end})
+		}
+	}
+	return mergePeriods(periods)
+}
+
+// mergePeriods sorts periods and joins those that overlap or touch.
+func mergePeriods(periods []Period) []Period {
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Start.Before(periods[j].Start) })
+	var merged []Period
+	for _, p := range periods {
+		if n := len(merged); n > 0 && !p.Start.After(merged[n-1].End) {
+			if p.End.After(merged[n-1].End) {
+				merged[n-1].End = p.End
+			}
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// sortEvents orders events by start time, then title.
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Start.Equal(events[j].Start) {
+			return events[i].Start.Before(events[j].Start)
+		}
+		return events[i].Title < events[j].Title
+	})
+}
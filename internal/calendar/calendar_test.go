@@ -0,0 +1,157 @@
+package calendar
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+const standup = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:standup-1\r\n" +
+	"DTSTART:20260316T090000Z\r\nDTEND:20260316T091500Z\r\nSUMMARY:Standup\\, team\r\n" +
+	"LOCATION:Room 1\r\nDESCRIPTION:Line one\\nline two with a long text that is\r\n  folded\r\n" +
+	"BEGIN:VALARM\r\nSUMMARY:alarm\r\nEND:VALARM\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:holiday\r\nDTSTART;VALUE=DATE:20260317\r\nSUMMARY:Holiday\r\nTRANSP:TRANSPARENT\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:review\r\nDTSTART;TZID=Europe/Berlin:20260316T140000\r\nDURATION:PT1H30M\r\nSUMMARY:Review\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:gone\r\nDTSTART:20260316T100000Z\r\nSUMMARY:Cancelled\r\nSTATUS:CANCELLED\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+func TestParseICalEvents(t *testing.T) {
+	events, err := parseICalEvents(standup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("events = %+v", events)
+	}
+	s := events[0]
+	if s.ID != "standup-1" || s.Title != "Standup, team" || s.Location != "Room 1" ||
+		s.Description != "Line one\nline two with a long text that is folded" ||
+		!s.Start.Equal(time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)) || s.End.Sub(s.Start) != 15*time.Minute {
+		t.Errorf("standup = %+v", s)
+	}
+	if h := events[1]; !h.AllDay || !h.Free || h.End.Sub(h.Start) != 24*time.Hour {
+		t.Errorf("holiday = %+v", h)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	if r := events[2]; !r.Start.Equal(time.Date(2026, 3, 16, 14, 0, 0, 0, berlin)) || r.End.Sub(r.Start) != 90*time.Minute {
+		t.Errorf("review = %+v", r)
+	}
+
+	// What uBot writes reads back the same
+	ev := Event{ID: "x@ubot", Title: "Plan; " + strings.Repeat("é", 60), Start: s.Start, End: s.End, Description: "a\nb"}
+	data := formatICalEvent(ev, time.Now())
+	for _, line := range strings.Split(data, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 bytes: %q", line)
+		}
+	}
+	back, err := parseICalEvents(data)
+	if err != nil || len(back) != 1 || back[0].Title != ev.Title || back[0].Description != "a\nb" || !back[0].Start.Equal(ev.Start) {
+		t.Errorf("round trip = %+v, %v", back, err)
+	}
+}
+
+func TestCalDAV(t *testing.T) {
+	var put string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "me" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method {
+		case "REPORT":
+			if r.Header.Get("Depth") != "1" || !strings.Contains(string(body), `time-range start="20260316T000000Z" end="20260318T000000Z"`) {
+				t.Errorf("query = %s", body)
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+<d:response><d:href>/cal/standup.ics</d:href><d:propstat><d:prop><cal:calendar-data>`+
+				strings.ReplaceAll(standup, "\r\n", "&#13;\n")+`</cal:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+</d:multistatus>`)
+		case http.MethodPut:
+			if r.Header.Get("If-None-Match") != "*" || !strings.HasPrefix(r.URL.Path, "/cal/") {
+				t.Errorf("PUT %s %v", r.URL.Path, r.Header)
+			}
+			put = string(body)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cal, _ := New(config.CalendarConfig{Provider: config.CalendarCalDAV, URL: srv.URL + "/cal/", Username: "me"}, dir)
+	from := time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)
+	if _, err := cal.Events(context.Background(), from, from.AddDate(0, 0, 2)); err != ErrNotLoggedIn {
+		t.Errorf("without a password: %v", err)
+	}
+	if err := SaveCalDAVPassword(dir, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := cal.Events(context.Background(), from, from.AddDate(0, 0, 2))
+	if err != nil || len(events) != 3 || events[0].Title != "Standup, team" {
+		t.Fatalf("Events = %+v, %v", events, err)
+	}
+	busy, _ := cal.Busy(context.Background(), from, from.AddDate(0, 0, 2))
+	if len(busy) != 2 { // the holiday is free
+		t.Errorf("busy = %+v", busy)
+	}
+
+	created, err := cal.Create(context.Background(), Event{Title: "Lunch", Start: from.Add(12 * time.Hour), End: from.Add(13 * time.Hour)})
+	if err != nil || created.ID == "" || !strings.Contains(put, "SUMMARY:Lunch") || !strings.Contains(put, "UID:"+created.ID) {
+		t.Errorf("Create = %+v, %v\n%s", created, err, put)
+	}
+
+	SaveCalDAVPassword(dir, "wrong")
+	if _, err := cal.Events(context.Background(), from, from.AddDate(0, 0, 1)); err == nil || !strings.Contains(err.Error(), "calendar login") {
+		t.Errorf("rejected credentials: %v", err)
+	}
+	if err := Logout(dir); err != nil || LoggedIn(config.CalendarCalDAV, dir) {
+		t.Errorf("Logout: %v", err)
+	}
+}
+
+func TestMergePeriods(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2026, 3, 16, h, 0, 0, 0, time.UTC) }
+	events := []Event{
+		{Start: at(13), End: at(14)},
+		{Start: at(9), End: at(11)},
+		{Start: at(10), End: at(12)},
+		{Start: at(12), End: at(13)},
+		{Start: at(7), End: at(9)},
+		{Start: at(15), End: at(16), Free: true},
+	}
+	busy := busyFromEvents(events, at(8), at(20))
+	if len(busy) != 1 || !busy[0].Start.Equal(at(8)) || !busy[0].End.Equal(at(14)) {
+		t.Errorf("busy = %+v", busy)
+	}
+}
+
+func TestReminders(t *testing.T) {
+	r := NewReminders(filepath.Join(t.TempDir(), "reminders.json"))
+	now := time.Date(2026, 3, 16, 8, 50, 0, 0, time.UTC)
+	standup := Event{ID: "standup", Start: now.Add(10 * time.Minute), End: now.Add(25 * time.Minute)}
+
+	if got, _ := r.Claim("telegram:1", []Event{standup}, now); len(got) != 1 {
+		t.Errorf("first claim = %+v", got)
+	}
+	if got, _ := r.Claim("telegram:1", []Event{standup}, now.Add(5*time.Minute)); len(got) != 0 {
+		t.Errorf("second claim = %+v", got)
+	}
+	if got, _ := r.Claim("cli:direct", []Event{standup}, now); len(got) != 1 {
+		t.Errorf("other chat = %+v", got)
+	}
+	// The next day's occurrence is a new event
+	tomorrow := standup
+	tomorrow.Start, tomorrow.End = standup.Start.AddDate(0, 0, 1), standup.End.AddDate(0, 0, 1)
+	if got, _ := r.Claim("telegram:1", []Event{tomorrow}, now.AddDate(0, 0, 1)); len(got) != 1 {
+		t.Errorf("next occurrence = %+v", got)
+	}
+}
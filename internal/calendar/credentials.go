@@ -0,0 +1,88 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// caldavCredentials is the content of caldavFile.
+type caldavCredentials struct {
+	Password string `json:"password"`
+}
+
+// googleToken is the content of googleTokenFile.
+type googleToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// SaveCalDAVPassword stores the CalDAV password in dir.
+func SaveCalDAVPassword(dir, password string) error {
+	return writeSecret(filepath.Join(dir, caldavFile), caldavCredentials{Password: password})
+}
+
+func loadCalDAVPassword(path string) (string, error) {
+	var creds caldavCredentials
+	if err := readSecret(path, &creds); err != nil {
+		return "", err
+	}
+	return creds.Password, nil
+}
+
+// Logout deletes the calendar credentials stored in dir.
+func Logout(dir string) error {
+	for _, name := range []string{caldavFile, googleTokenFile} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoggedIn reports whether credentials for provider are stored in dir.
+func LoggedIn(provider, dir string) bool {
+	name := caldavFile
+	if provider == config.CalendarGoogle {
+		name = googleTokenFile
+	}
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+// readSecret decodes the JSON file at path into v.
+func readSecret(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotLoggedIn
+	}
+	if err != nil {
+		return fmt.Errorf("read calendar credentials: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeSecret stores v as JSON at path, readable only by the user.
+func writeSecret(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
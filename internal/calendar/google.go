@@ -0,0 +1,419 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// googleAPIBase is the Google Calendar API.
+	googleAPIBase = "https://www.googleapis.com/calendar/v3"
+	// googleAuthURL is where the user grants uBot access to the calendar.
+	googleAuthURL = "https://accounts.google.com/o/oauth2/v2/auth"
+	// googleTokenURL exchanges codes and refresh tokens for access tokens.
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	// googleScopes allow reading and creating events and free/busy queries.
+	googleScopes = "https://www.googleapis.com/auth/calendar.events https://www.googleapis.com/auth/calendar.freebusy"
+	// googleTokenFile holds the OAuth tokens, in the credentials directory.
+	googleTokenFile = "google_token.json"
+	// maxGoogleEvents limits how many events one listing fetches.
+	maxGoogleEvents = 1000
+)
+
+// Google is a calendar in Google Calendar.
+type Google struct {
+	calendarID string
+	auth       *googleAuth
+	client     *http.Client
+	base       string // API base URL; tests point it at a local server
+}
+
+// googleEvent is an event in the Google Calendar API.
+type googleEvent struct {
+	ID           string     `json:"id,omitempty"`
+	Summary      string     `json:"summary"`
+	Location     string     `json:"location,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	Transparency string     `json:"transparency,omitempty"`
+	Start        googleTime `json:"start"`
+	End          googleTime `json:"end"`
+}
+
+// googleTime is the start or end of an event: a date for all-day events,
+// else a date and time.
+type googleTime struct {
+	Date     string `json:"date,omitempty"`
+	DateTime string `json:"dateTime,omitempty"`
+}
+
+func (t googleTime) parse() (time.Time, bool, error) {
+	if t.Date != "" {
+		d, err := time.ParseInLocation(time.DateOnly, t.Date, time.Local)
+		return d, true, err
+	}
+	d, err := time.Parse(time.RFC3339, t.DateTime)
+	return d.Local(), false, err
+}
+
+func toGoogleTime(t time.Time, allDay bool) googleTime {
+	if allDay {
+		return googleTime{Date: t.Format(time.DateOnly)}
+	}
+	return googleTime{DateTime: t.Format(time.RFC3339)}
+}
+
+func (g googleEvent) event() (Event, error) {
+	start, allDay, err := g.Start.parse()
+	if err != nil {
+		return Event{}, fmt.Errorf("event %s has an invalid start: %w", g.ID, err)
+	}
+	end, _, err := g.End.parse()
+	if err != nil {
+		return Event{}, fmt.Errorf("event %s has an invalid end: %w", g.ID, err)
+	}
+	return Event{
+		ID:          g.ID,
+		Title:       g.Summary,
+		Start:       start,
+		End:         end,
+		AllDay:      allDay,
+		Location:    g.Location,
+		Description: g.Description,
+		Free:        g.Transparency == "transparent",
+	}, nil
+}
+
+// Events returns the events overlapping from-to, recurring events
+// expanded.
+func (g *Google) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	var events []Event
+	pageToken := ""
+	for {
+		q := url.Values{}
+		q.Set("timeMin", from.Format(time.RFC3339))
+		q.Set("timeMax", to.Format(time.RFC3339))
+		q.Set("singleEvents", "true")
+		q.Set("orderBy", "startTime")
+		q.Set("maxResults", "250")
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		var page struct {
+			Items         []googleEvent `json:"items"`
+			NextPageToken string        `json:"nextPageToken"`
+		}
+		if err := g.call(ctx, http.MethodGet, g.eventsURL()+"?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if item.Status == "cancelled" {
+				continue
+			}
+			ev, err := item.event()
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, ev)
+		}
+		pageToken = page.NextPageToken
+		if pageToken == "" || len(events) >= maxGoogleEvents {
+			break
+		}
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+// Create adds ev to the calendar.
+func (g *Google) Create(ctx context.Context, ev Event) (Event, error) {
+	body := googleEvent{
+		Summary:     ev.Title,
+		Location:    ev.Location,
+		Description: ev.Description,
+		Start:       toGoogleTime(ev.Start, ev.AllDay),
+		End:         toGoogleTime(ev.End, ev.AllDay),
+	}
+	var created googleEvent
+	if err := g.call(ctx, http.MethodPost, g.eventsURL(), body, &created); err != nil {
+		return Event{}, err
+	}
+	return created.event()
+}
+
+// Busy asks Google for the busy periods of the calendar.
+func (g *Google) Busy(ctx context.Context, from, to time.Time) ([]Period, error) {
+	body := map[string]interface{}{
+		"timeMin": from.Format(time.RFC3339),
+		"timeMax": to.Format(time.RFC3339),
+		"items":   []map[string]string{{"id": g.calendarID}},
+	}
+	var resp struct {
+		Calendars map[string]struct {
+			Busy []struct {
+				Start time.Time `json:"start"`
+				End   time.Time `json:"end"`
+			} `json:"busy"`
+			Errors []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"calendars"`
+	}
+	if err := g.call(ctx, http.MethodPost, g.base+"/freeBusy", body, &resp); err != nil {
+		return nil, err
+	}
+	cal := resp.Calendars[g.calendarID]
+	if len(cal.Errors) > 0 {
+		return nil, fmt.Errorf("free/busy query failed: %s", cal.Errors[0].Reason)
+	}
+	var periods []Period
+	for _, b := range cal.Busy {
+		periods = append(periods, Period{Start: b.Start.Local(), End: b.End.Local()})
+	}
+	return mergePeriods(periods), nil
+}
+
+func (g *Google) eventsURL() string {
+	return g.base + "/calendars/" + url.PathEscape(g.calendarID) + "/events"
+}
+
+// call sends a JSON request with the current access token and decodes the
+// response into out.
+func (g *Google) call(ctx context.Context, method, target string, in, out interface{}) error {
+	token, err := g.auth.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calendar request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("read calendar response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New("Google rejected the access token; run 'ubot calendar login' again")
+	}
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("Google Calendar: %s (status %d)", apiErr.Error.Message, resp.StatusCode)
+		}
+		return fmt.Errorf("Google Calendar request failed (status %d): %s", resp.StatusCode, snippet(data))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parse calendar response: %w", err)
+	}
+	return nil
+}
+
+// googleAuth hands out access tokens, refreshing them with the stored
+// refresh token when they expire.
+type googleAuth struct {
+	clientID     string
+	clientSecret string
+	path         string // token file
+	tokenURL     string
+	client       *http.Client
+
+	mu sync.Mutex
+}
+
+func newGoogleAuth(clientID, clientSecret, path string, client *http.Client) *googleAuth {
+	return &googleAuth{clientID: clientID, clientSecret: clientSecret, path: path, tokenURL: googleTokenURL, client: client}
+}
+
+// accessToken returns a token valid for at least another minute.
+func (a *googleAuth) accessToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// The file is read each time so a new login is picked up at once
+	var tok googleToken
+	if err := readSecret(a.path, &tok); err != nil {
+		return "", err
+	}
+	if tok.AccessToken != "" && time.Until(tok.Expiry) > time.Minute {
+		return tok.AccessToken, nil
+	}
+	if tok.RefreshToken == "" {
+		return "", ErrNotLoggedIn
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", tok.RefreshToken)
+	fresh, err := a.exchange(ctx, form)
+	if err != nil {
+		return "", fmt.Errorf("refresh Google token: %w", err)
+	}
+	if fresh.RefreshToken == "" {
+		fresh.RefreshToken = tok.RefreshToken
+	}
+	if err := writeSecret(a.path, fresh); err != nil {
+		return "", fmt.Errorf("save Google token: %w", err)
+	}
+	return fresh.AccessToken, nil
+}
+
+// exchange posts form to the token endpoint and returns the tokens.
+func (a *googleAuth) exchange(ctx context.Context, form url.Values) (googleToken, error) {
+	form.Set("client_id", a.clientID)
+	form.Set("client_secret", a.clientSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return googleToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return googleToken{}, err
+	}
+	defer resp.Body.Close()
+
+	var tr struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Error        string `json:"error"`
+		ErrorDesc    string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tr); err != nil {
+		return googleToken{}, fmt.Errorf("parse token response (status %d): %w", resp.StatusCode, err)
+	}
+	if tr.Error != "" {
+		if tr.Error == "invalid_grant" {
+			return googleToken{}, errors.New("access was revoked or expired; run 'ubot calendar login' again")
+		}
+		desc := tr.ErrorDesc
+		if desc == "" {
+			desc = tr.Error
+		}
+		return googleToken{}, errors.New(desc)
+	}
+	if tr.AccessToken == "" {
+		return googleToken{}, errors.New("received empty access token")
+	}
+	return googleToken{
+		AccessToken:  tr.AccessToken,
+		RefreshToken: tr.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// GoogleLogin asks the user to grant access to their calendar and stores
+// the tokens in dir. It listens on addr (e.g. "127.0.0.1:0") for Google to
+// redirect the browser back, and calls show with the URL the user opens.
+func GoogleLogin(ctx context.Context, clientID, clientSecret, dir, addr string, show func(authURL string)) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen for the redirect: %w", err)
+	}
+	defer ln.Close()
+	redirect := "http://" + ln.Addr().String() + "/"
+
+	// PKCE and state keep the code to this login
+	verifier, state := randomString(32), randomString(16)
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{}
+	q.Set("client_id", clientID)
+	q.Set("redirect_uri", redirect)
+	q.Set("response_type", "code")
+	q.Set("scope", googleScopes)
+	q.Set("access_type", "offline")
+	q.Set("prompt", "consent")
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	show(googleAuthURL + "?" + q.Encode())
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			http.Error(w, "Unexpected request.", http.StatusBadRequest)
+			return
+		}
+		res := result{code: q.Get("code")}
+		if e := q.Get("error"); e != "" || res.code == "" {
+			res.err = fmt.Errorf("authorization failed: %s", e)
+			fmt.Fprintln(w, "uBot was not given access. You can close this window.")
+		} else {
+			fmt.Fprintln(w, "uBot can now use your calendar. You can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	var res result
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res = <-results:
+	}
+	if res.err != nil {
+		return res.err
+	}
+
+	auth := newGoogleAuth(clientID, clientSecret, "", &http.Client{Timeout: requestTimeout})
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", res.code)
+	form.Set("redirect_uri", redirect)
+	form.Set("code_verifier", verifier)
+	tok, err := auth.exchange(ctx, form)
+	if err != nil {
+		return fmt.Errorf("exchange authorization code: %w", err)
+	}
+	return writeSecret(filepath.Join(dir, googleTokenFile), tok)
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
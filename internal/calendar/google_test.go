@@ -0,0 +1,96 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+func TestGoogle(t *testing.T) {
+	refreshes := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-1" || r.Form.Get("client_id") != "client" {
+			t.Errorf("token request %v", r.Form)
+		}
+		refreshes++
+		io.WriteString(w, `{"access_token":"fresh","expires_in":3600}`)
+	})
+	api := func(pattern string, handle func(body []byte) string) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer fresh" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			io.WriteString(w, handle(body))
+		})
+	}
+	api("GET /calendars/work@example.com/events", func([]byte) string {
+		return `{"items":[
+			{"id":"a","summary":"Standup","start":{"dateTime":"2026-03-16T09:00:00Z"},"end":{"dateTime":"2026-03-16T09:15:00Z"}},
+			{"id":"b","summary":"Offsite","start":{"date":"2026-03-17"},"end":{"date":"2026-03-19"},"transparency":"transparent"},
+			{"id":"c","summary":"Dropped","status":"cancelled","start":{"dateTime":"2026-03-16T10:00:00Z"},"end":{"dateTime":"2026-03-16T11:00:00Z"}}]}`
+	})
+	api("POST /calendars/work@example.com/events", func(body []byte) string {
+		var ev googleEvent
+		json.Unmarshal(body, &ev)
+		ev.ID = "new"
+		out, _ := json.Marshal(ev)
+		return string(out)
+	})
+	api("POST /freeBusy", func(body []byte) string {
+		if !strings.Contains(string(body), `"id":"work@example.com"`) {
+			t.Errorf("freeBusy body %s", body)
+		}
+		return `{"calendars":{"work@example.com":{"busy":[
+			{"start":"2026-03-16T10:00:00Z","end":"2026-03-16T11:00:00Z"},
+			{"start":"2026-03-16T09:00:00Z","end":"2026-03-16T10:00:00Z"}]}}}`
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cal, _ := New(config.CalendarConfig{Provider: config.CalendarGoogle, ClientID: "client", ClientSecret: "secret", CalendarID: "work@example.com"}, dir)
+	g := cal.(*Google)
+	g.base, g.auth.tokenURL = srv.URL, srv.URL+"/token"
+	from := time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)
+	if _, err := g.Events(context.Background(), from, from.AddDate(0, 0, 7)); err != ErrNotLoggedIn {
+		t.Errorf("without a token: %v", err)
+	}
+
+	// An expired token is refreshed and saved, keeping the refresh token
+	path := filepath.Join(dir, googleTokenFile)
+	writeSecret(path, googleToken{AccessToken: "old", RefreshToken: "refresh-1", Expiry: time.Now().Add(-time.Hour)})
+	events, err := g.Events(context.Background(), from, from.AddDate(0, 0, 7))
+	if err != nil || len(events) != 2 || events[0].Title != "Standup" || !events[1].AllDay || !events[1].Free ||
+		events[1].End.Sub(events[1].Start) != 48*time.Hour {
+		t.Fatalf("Events = %+v, %v", events, err)
+	}
+	var saved googleToken
+	readSecret(path, &saved)
+	if saved.AccessToken != "fresh" || saved.RefreshToken != "refresh-1" || refreshes != 1 {
+		t.Errorf("saved token %+v after %d refreshes", saved, refreshes)
+	}
+
+	created, err := g.Create(context.Background(), Event{Title: "Lunch", Start: from.Add(12 * time.Hour), End: from.Add(13 * time.Hour)})
+	if err != nil || created.ID != "new" || created.Title != "Lunch" || !created.Start.Equal(from.Add(12*time.Hour)) {
+		t.Errorf("Create = %+v, %v", created, err)
+	}
+	busy, err := g.Busy(context.Background(), from, from.AddDate(0, 0, 1))
+	if err != nil || len(busy) != 1 || busy[0].End.Sub(busy[0].Start) != 2*time.Hour {
+		t.Errorf("Busy = %+v, %v", busy, err)
+	}
+	if refreshes != 1 {
+		t.Errorf("token refreshed %d times", refreshes)
+	}
+}
@@ -0,0 +1,264 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) date and time layouts.
+const (
+	icalDate     = "20060102"
+	icalDateTime = "20060102T150405"
+	icalUTC      = "20060102T150405Z"
+)
+
+// icalProperty is one content line of an iCalendar object.
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfoldICal splits data into content lines, joining folded lines.
+func unfoldICal(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseICalLine splits a content line into its name, parameters and value.
+func parseICalLine(line string) icalProperty {
+	// The value starts at the first colon outside a quoted parameter value
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icalProperty{name: strings.ToUpper(line)}
+	}
+	parts := strings.Split(line[:colon], ";")
+	prop := icalProperty{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			prop.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return prop
+}
+
+// parseICalEvents returns the events in an iCalendar object. Events that
+// were cancelled are left out.
+func parseICalEvents(data string) ([]Event, error) {
+	var events []Event
+	var ev *Event
+	var duration time.Duration
+	cancelled := false
+	depth := 0 // components nested in the VEVENT, such as VALARM
+
+	for _, line := range unfoldICal(data) {
+		prop := parseICalLine(line)
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			ev, duration, cancelled, depth = &Event{}, 0, false, 0
+			continue
+		case ev == nil:
+			continue
+		case prop.name == "BEGIN":
+			depth++
+			continue
+		case prop.name == "END" && depth > 0:
+			depth--
+			continue
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if ev.End.IsZero() {
+				switch {
+				case duration > 0:
+					ev.End = ev.Start.Add(duration)
+				case ev.AllDay:
+					ev.End = ev.Start.AddDate(0, 0, 1)
+				default:
+					ev.End = ev.Start
+				}
+			}
+			if !cancelled && !ev.Start.IsZero() {
+				events = append(events, *ev)
+			}
+			ev = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch prop.name {
+		case "UID":
+			ev.ID = prop.value
+		case "SUMMARY":
+			ev.Title = unescapeICalText(prop.value)
+		case "LOCATION":
+			ev.Location = unescapeICalText(prop.value)
+		case "DESCRIPTION":
+			ev.Description = unescapeICalText(prop.value)
+		case "STATUS":
+			cancelled = strings.EqualFold(prop.value, "CANCELLED")
+		case "TRANSP":
+			ev.Free = strings.EqualFold(prop.value, "TRANSPARENT")
+		case "DTSTART":
+			t, allDay, err := parseICalTime(prop)
+			if err != nil {
+				return nil, err
+			}
+			ev.Start, ev.AllDay = t, allDay
+		case "DTEND":
+			t, _, err := parseICalTime(prop)
+			if err != nil {
+				return nil, err
+			}
+			ev.End = t
+		case "DURATION":
+			d, err := parseICalDuration(prop.value)
+			if err != nil {
+				return nil, err
+			}
+			duration = d
+		}
+	}
+	return events, nil
+}
+
+// parseICalTime parses a DTSTART or DTEND value and reports whether it is
+// a date without a time.
+func parseICalTime(prop icalProperty) (time.Time, bool, error) {
+	value := prop.value
+	if prop.params["VALUE"] == "DATE" || len(value) == len(icalDate) {
+		t, err := time.ParseInLocation(icalDate, value, time.Local)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s %q", prop.name, value)
+		}
+		return t, true, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icalUTC, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s %q", prop.name, value)
+		}
+		return t.Local(), false, nil
+	}
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(icalDateTime, value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid %s %q", prop.name, value)
+	}
+	return t.Local(), false, nil
+}
+
+// parseICalDuration parses a duration such as "PT1H30M" or "P1D".
+func parseICalDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if s == value || strings.HasPrefix(value, "-") {
+		return 0, fmt.Errorf("invalid DURATION %q", value)
+	}
+	var d time.Duration
+	inTime := false
+	n := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			n = n*10 + int(r-'0')
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		case r == 'W':
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case r == 'D':
+			d += time.Duration(n) * 24 * time.Hour
+		case r == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case r == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case r == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid DURATION %q", value)
+		}
+		n = 0
+	}
+	return d, nil
+}
+
+var icalTextUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, ",", `\,`, ";", `\;`)
+
+func unescapeICalText(s string) string { return icalTextUnescaper.Replace(s) }
+
+// formatICalEvent returns an iCalendar object holding ev, stamped at now.
+func formatICalEvent(ev Event, now time.Time) string {
+	var lines []string
+	add := func(line string) { lines = append(lines, foldICalLine(line)) }
+	add("BEGIN:VCALENDAR")
+	add("VERSION:2.0")
+	add("PRODID:-//uBot//Calendar//EN")
+	add("BEGIN:VEVENT")
+	add("UID:" + ev.ID)
+	add("DTSTAMP:" + now.UTC().Format(icalUTC))
+	if ev.AllDay {
+		add("DTSTART;VALUE=DATE:" + ev.Start.Format(icalDate))
+		add("DTEND;VALUE=DATE:" + ev.End.Format(icalDate))
+	} else {
+		add("DTSTART:" + ev.Start.UTC().Format(icalUTC))
+		add("DTEND:" + ev.End.UTC().Format(icalUTC))
+	}
+	add("SUMMARY:" + icalTextEscaper.Replace(ev.Title))
+	if ev.Location != "" {
+		add("LOCATION:" + icalTextEscaper.Replace(ev.Location))
+	}
+	if ev.Description != "" {
+		add("DESCRIPTION:" + icalTextEscaper.Replace(ev.Description))
+	}
+	add("END:VEVENT")
+	add("END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// foldICalLine breaks a content line into lines of at most 75 bytes,
+// without splitting a UTF-8 character.
+func foldICalLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+	var sb strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	sb.WriteString(line)
+	return sb.String()
+}
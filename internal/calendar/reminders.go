@@ -0,0 +1,92 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Reminders remembers which events each conversation has been reminded
+// of, so a job checking for upcoming meetings every few minutes mentions
+// each one once. Entries are dropped when their event has ended.
+type Reminders struct {
+	mu   sync.Mutex
+	path string
+}
+
+// reminded maps a conversation to its reminded events, by event key, with
+// the time each event ends.
+type reminded map[string]map[string]time.Time
+
+// NewReminders creates a Reminders backed by the file at path.
+func NewReminders(path string) *Reminders {
+	return &Reminders{path: path}
+}
+
+// Claim returns the events owner has not been reminded of and records
+// them as reminded.
+func (r *Reminders) Claim(owner string, events []Event, now time.Time) ([]Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	for o, evs := range state {
+		for key, end := range evs {
+			if end.Before(now) {
+				delete(evs, key)
+			}
+		}
+		if len(evs) == 0 {
+			delete(state, o)
+		}
+	}
+
+	var fresh []Event
+	for _, ev := range events {
+		key := ev.ID + "@" + ev.Start.UTC().Format(time.RFC3339)
+		if _, ok := state[owner][key]; ok {
+			continue
+		}
+		if state[owner] == nil {
+			state[owner] = make(map[string]time.Time)
+		}
+		state[owner][key] = ev.End
+		fresh = append(fresh, ev)
+	}
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+	return fresh, r.save(state)
+}
+
+func (r *Reminders) load() (reminded, error) {
+	state := make(reminded)
+	data, err := os.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", r.path, err)
+	}
+	return state, nil
+}
+
+func (r *Reminders) save(state reminded) error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0o600)
+}
@@ -0,0 +1,262 @@
+package channels
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/control"
+)
+
+const (
+	// maxQueuedMessages caps how many messages are held per unknown sender.
+	maxQueuedMessages = 10
+	// maxPreviewChars caps the message preview shown to the admin.
+	maxPreviewChars = 200
+)
+
+// accessEntry is a persisted decision about a sender.
+type accessEntry struct {
+	Channel   string    `json:"channel"`
+	SenderID  string    `json:"senderId"`
+	DecidedAt time.Time `json:"decidedAt"`
+}
+
+// accessFile is the on-disk format of the access decisions.
+type accessFile struct {
+	Allowed []accessEntry `json:"allowed"`
+	Blocked []accessEntry `json:"blocked"`
+}
+
+// pendingAccess is an unknown sender awaiting a decision, with the messages
+// they sent in the meantime.
+type pendingAccess struct {
+	request control.AccessRequest
+	queued  []bus.InboundMessage
+}
+
+// Access lets the admin approve senders that are not in a channel's
+// allowFrom list. Messages from unknown senders are held and the admin is
+// asked (in the admin chat or through the control API); allowed senders'
+// messages are then delivered. Decisions are persisted.
+type Access struct {
+	path         string
+	bus          *bus.MessageBus
+	adminChannel string
+	adminChatID  string
+
+	mu        sync.Mutex
+	decisions accessFile
+	pending   map[string]*pendingAccess // by code
+}
+
+// NewAccess creates an Access persisting decisions to path. Prompts are
+// sent to the given admin chat; with no admin chat, requests can only be
+// decided through the control API.
+func NewAccess(path string, msgBus *bus.MessageBus, adminChannel, adminChatID string) *Access {
+	a := &Access{
+		path:         path,
+		bus:          msgBus,
+		adminChannel: adminChannel,
+		adminChatID:  adminChatID,
+		pending:      make(map[string]*pendingAccess),
+	}
+	if err := a.load(); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to load access decisions: %v", err)
+	}
+	return a
+}
+
+// Allowed reports whether the admin has allowed the sender on channel.
+func (a *Access) Allowed(channel, senderID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return findEntry(a.decisions.Allowed, channel, senderID) >= 0
+}
+
+// Request holds a message from a sender that is not allowed and, the first
+// time the sender writes, asks the admin to decide. Messages from blocked
+// senders are dropped.
+func (a *Access) Request(msg bus.InboundMessage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if findEntry(a.decisions.Blocked, msg.Channel, msg.SenderID) >= 0 {
+		return
+	}
+	for _, p := range a.pending {
+		if p.request.Channel == msg.Channel && p.request.SenderID == msg.SenderID {
+			if len(p.queued) < maxQueuedMessages {
+				p.queued = append(p.queued, msg)
+			}
+			return
+		}
+	}
+
+	code, err := newAccessCode()
+	if err != nil {
+		log.Printf("Warning: dropping message from unknown sender: %v", err)
+		return
+	}
+	req := control.AccessRequest{
+		Code:        code,
+		Channel:     msg.Channel,
+		SenderID:    msg.SenderID,
+		ChatID:      msg.ChatID,
+		Preview:     preview(msg.Content),
+		RequestedAt: time.Now(),
+	}
+	a.pending[code] = &pendingAccess{request: req, queued: []bus.InboundMessage{msg}}
+	log.Printf("[security] channel=%s action=access_requested sender=%s code=%s", msg.Channel, msg.SenderID, code)
+
+	if a.adminChatID == "" {
+		return
+	}
+	a.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: a.adminChannel,
+		ChatID:  a.adminChatID,
+		Content: fmt.Sprintf("New %s sender %s wants to talk to uBot:\n%q\nReply /allow %s or /block %s.",
+			req.Channel, req.SenderID, req.Preview, code, code),
+		Buttons: []bus.Button{
+			{Text: "Allow", Data: "/allow " + code},
+			{Text: "Block", Data: "/block " + code},
+		},
+	})
+}
+
+// Pending returns the senders awaiting a decision, oldest first.
+func (a *Access) Pending() []control.AccessRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	reqs := make([]control.AccessRequest, 0, len(a.pending))
+	for _, p := range a.pending {
+		reqs = append(reqs, p.request)
+	}
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].RequestedAt.Before(reqs[j].RequestedAt) })
+	return reqs
+}
+
+// Decide allows or blocks the sender of a pending request and persists the
+// decision. Held messages of an allowed sender are delivered.
+func (a *Access) Decide(code string, allow bool) (control.AccessRequest, error) {
+	a.mu.Lock()
+	p, ok := a.pending[code]
+	if !ok {
+		a.mu.Unlock()
+		return control.AccessRequest{}, fmt.Errorf("no pending access request %s", code)
+	}
+	delete(a.pending, code)
+
+	entry := accessEntry{Channel: p.request.Channel, SenderID: p.request.SenderID, DecidedAt: time.Now()}
+	if allow {
+		a.decisions.Allowed = append(a.decisions.Allowed, entry)
+	} else {
+		a.decisions.Blocked = append(a.decisions.Blocked, entry)
+	}
+	err := a.saveLocked()
+	a.mu.Unlock()
+	if err != nil {
+		return p.request, fmt.Errorf("failed to save access decision: %w", err)
+	}
+
+	action := "access_blocked"
+	if allow {
+		action = "access_allowed"
+		for _, msg := range p.queued {
+			a.bus.PublishInbound(msg)
+		}
+	}
+	log.Printf("[security] channel=%s action=%s sender=%s", p.request.Channel, action, p.request.SenderID)
+	return p.request, nil
+}
+
+// HandleAdminCommand decides a request if msg is /allow or /block sent from
+// the admin chat. It reports whether msg was such a command.
+func (a *Access) HandleAdminCommand(msg bus.InboundMessage) bool {
+	if a.adminChatID == "" || msg.Channel != a.adminChannel || msg.ChatID != a.adminChatID {
+		return false
+	}
+	fields := strings.Fields(msg.Content)
+	if len(fields) != 2 || (fields[0] != "/allow" && fields[0] != "/block") {
+		return false
+	}
+
+	allow := fields[0] == "/allow"
+	var reply string
+	if req, err := a.Decide(fields[1], allow); err != nil {
+		reply = err.Error()
+	} else if allow {
+		reply = fmt.Sprintf("Allowed %s on %s.", req.SenderID, req.Channel)
+	} else {
+		reply = fmt.Sprintf("Blocked %s on %s.", req.SenderID, req.Channel)
+	}
+	a.bus.PublishOutbound(bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: reply})
+	return true
+}
+
+// findEntry returns the index of the entry matching senderID on channel, or
+// -1. Compound IDs like "123456|username" match an entry for either part.
+func findEntry(entries []accessEntry, channel, senderID string) int {
+	parts := strings.Split(senderID, "|")
+	for i, e := range entries {
+		if e.Channel != channel {
+			continue
+		}
+		if e.SenderID == senderID {
+			return i
+		}
+		for _, part := range parts {
+			if part != "" && strings.Split(e.SenderID, "|")[0] == part {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (a *Access) load() error {
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &a.decisions)
+}
+
+func (a *Access) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(a.decisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(a.path, data, 0o600)
+}
+
+// preview shortens message content for the admin prompt, counting
+// characters so that it never splits one.
+func preview(content string) string {
+	if runes := []rune(content); len(runes) > maxPreviewChars {
+		return string(runes[:maxPreviewChars]) + "..."
+	}
+	return content
+}
+
+// newAccessCode returns a short random code identifying a request.
+func newAccessCode() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate access code: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
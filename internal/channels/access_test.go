@@ -0,0 +1,126 @@
+package channels
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+func TestAccessAllowDeliversHeldMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	msgBus := bus.NewMessageBus(10)
+	a := NewAccess(path, msgBus, "telegram", "1")
+
+	ch := NewBaseChannel("telegram", msgBus, []string{"1"})
+	ch.SetAccess(a)
+
+	sender := "42|stranger"
+	if ch.IsAllowed(sender) {
+		t.Fatal("unknown sender allowed")
+	}
+	ch.requestAccess(sender, "42", "hello")
+	ch.requestAccess(sender, "42", "are you there?")
+
+	pending := a.Pending()
+	if len(pending) != 1 {
+		t.Fatalf("pending = %d, want 1 request per sender", len(pending))
+	}
+	if msgBus.OutboundSize() != 1 {
+		t.Fatalf("admin prompts = %d, want 1", msgBus.OutboundSize())
+	}
+	prompt := msgBus.ConsumeOutbound()
+	if prompt.ChatID != "1" || len(prompt.Buttons) != 2 || !strings.Contains(prompt.Content, pending[0].Code) {
+		t.Fatalf("unexpected admin prompt: %+v", prompt)
+	}
+
+	// A stranger cannot decide; the admin chat can.
+	ch.publishInbound(context.Background(), sender, "42", "/allow "+pending[0].Code, nil, nil)
+	if msgBus.InboundSize() != 1 || len(a.Pending()) != 1 {
+		t.Fatal("/allow outside the admin chat must not decide the request")
+	}
+	msgBus.ConsumeInbound()
+	ch.publishInbound(context.Background(), "1", "1", "/allow "+pending[0].Code, nil, nil)
+
+	if len(a.Pending()) != 0 {
+		t.Fatal("request still pending after /allow")
+	}
+	if got := msgBus.InboundSize(); got != 2 {
+		t.Fatalf("delivered = %d, want 2 held messages", got)
+	}
+	if msg := msgBus.ConsumeInbound(); msg.Content != "hello" {
+		t.Errorf("first delivered message = %q", msg.Content)
+	}
+
+	// The decision persists and matches the sender's numeric ID alone.
+	reloaded := NewAccess(path, msgBus, "", "")
+	if !reloaded.Allowed("telegram", "42|renamed") {
+		t.Error("allowed sender not remembered")
+	}
+	if reloaded.Allowed("whatsapp", "42") {
+		t.Error("decision leaked to another channel")
+	}
+}
+
+func TestAccessBlock(t *testing.T) {
+	msgBus := bus.NewMessageBus(10)
+	a := NewAccess(filepath.Join(t.TempDir(), "access.json"), msgBus, "", "")
+
+	a.Request(bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "7", Content: "hi"})
+	if msgBus.OutboundSize() != 0 {
+		t.Fatal("prompt sent without an admin chat")
+	}
+	code := a.Pending()[0].Code
+	if _, err := a.Decide(code, false); err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+	if msgBus.InboundSize() != 0 {
+		t.Fatal("blocked sender's messages delivered")
+	}
+
+	a.Request(bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "7", Content: "again"})
+	if len(a.Pending()) != 0 {
+		t.Error("blocked sender requested access again")
+	}
+	if _, err := a.Decide(code, true); err == nil {
+		t.Error("deciding an already decided request should fail")
+	}
+}
+
+func TestIsAllowedByIDOrUsername(t *testing.T) {
+	ch := NewBaseChannel("telegram", bus.NewMessageBus(1), []string{"42", "@Alice", "bob"})
+	tests := []struct {
+		sender string
+		want   bool
+	}{
+		{"42", true},
+		{"42|renamed", true},
+		{"7|alice", true},
+		{"8|Bob", true},
+		{"9|mallory", false},
+		{"9", false},
+		{"4|2", false},
+	}
+	for _, tt := range tests {
+		if got := ch.IsAllowed(tt.sender); got != tt.want {
+			t.Errorf("IsAllowed(%q) = %v, want %v", tt.sender, got, tt.want)
+		}
+	}
+}
+
+func TestPreviewKeepsCharactersWhole(t *testing.T) {
+	content := strings.Repeat("é", maxPreviewChars+10)
+	got := preview(content)
+	if !utf8.ValidString(got) {
+		t.Fatalf("preview is not valid UTF-8: %q", got)
+	}
+	if want := strings.Repeat("é", maxPreviewChars) + "..."; got != want {
+		t.Errorf("preview = %q, want %d characters and an ellipsis", got, maxPreviewChars)
+	}
+	if got := preview("привет"); got != "привет" {
+		t.Errorf("short preview = %q", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/tracing"
 )
 
 // Channel is the interface all channels must implement.
@@ -33,6 +34,7 @@ type BaseChannel struct {
 	name      string
 	bus       *bus.MessageBus
 	allowList []string
+	access    *Access // nil when unknown senders are dropped
 	running   bool
 	mu        sync.RWMutex
 }
@@ -66,37 +68,47 @@ func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
 
+// SetAccess makes the channel hold messages from unknown senders for the
+// admin's decision instead of dropping them.
+func (c *BaseChannel) SetAccess(a *Access) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.access = a
+}
+
+// getAccess returns the channel's Access, or nil.
+func (c *BaseChannel) getAccess() *Access {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.access
+}
+
 // IsAllowed checks if a sender is permitted to use this channel.
 // Returns true if:
 // - The senderID matches any item in the allowList
-// - For compound IDs like "123456|username", checks both the numeric ID and username
+// - For compound IDs like "123456|username", the numeric ID or the username matches
+// - Usernames match with or without "@", in any case
+// - The admin has allowed the sender through an access request
 // Returns false if the allowList is empty (deny all by default).
 func (c *BaseChannel) IsAllowed(senderID string) bool {
+	if a := c.getAccess(); a != nil && a.Allowed(c.name, senderID) {
+		return true
+	}
+
 	// Empty allowList means deny everyone — no users configured
 	if len(c.allowList) == 0 {
 		log.Printf("[security] channel=%s action=denied reason=no_allowed_users sender=%s", c.name, senderID)
 		return false
 	}
 
-	// Check if senderID directly matches any allowed ID
-	for _, allowed := range c.allowList {
-		if senderID == allowed {
-			return true
+	for _, part := range strings.Split(senderID, "|") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
 		}
-	}
-
-	// Handle compound IDs like "123456|username"
-	if strings.Contains(senderID, "|") {
-		parts := strings.Split(senderID, "|")
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			if part == "" {
-				continue
-			}
-			for _, allowed := range c.allowList {
-				if part == allowed {
-					return true
-				}
+		for _, allowed := range c.allowList {
+			if strings.EqualFold(part, strings.TrimPrefix(strings.TrimSpace(allowed), "@")) {
+				return true
 			}
 		}
 	}
@@ -104,9 +116,16 @@ func (c *BaseChannel) IsAllowed(senderID string) bool {
 	return false
 }
 
-// publishInbound creates and publishes an inbound message to the message bus.
-func (c *BaseChannel) publishInbound(senderID, chatID, content string, media []string, metadata map[string]interface{}) {
-	msg := bus.InboundMessage{
+// publishInbound creates and publishes an inbound message to the message
+// bus, continuing the trace of the span in ctx.
+func (c *BaseChannel) publishInbound(ctx context.Context, senderID, chatID, content string, media []string, metadata map[string]interface{}) {
+	c.publish(c.newInbound(ctx, senderID, chatID, content, media, metadata))
+}
+
+// newInbound creates an inbound message from this channel, continuing the
+// trace of the span in ctx.
+func (c *BaseChannel) newInbound(ctx context.Context, senderID, chatID, content string, media []string, metadata map[string]interface{}) bus.InboundMessage {
+	return bus.InboundMessage{
 		Channel:   c.name,
 		SenderID:  senderID,
 		ChatID:    chatID,
@@ -114,10 +133,37 @@ func (c *BaseChannel) publishInbound(senderID, chatID, content string, media []s
 		Timestamp: time.Now(),
 		Media:     media,
 		Metadata:  metadata,
+		Trace:     tracing.Inject(ctx),
+	}
+}
+
+// publish publishes msg to the message bus, unless it is an admin command
+// answered by the channel's Access.
+func (c *BaseChannel) publish(msg bus.InboundMessage) {
+	if a := c.getAccess(); a != nil && a.HandleAdminCommand(msg) {
+		return
 	}
 	c.bus.PublishInbound(msg)
 }
 
+// requestAccess holds a message from a sender that is not allowed and asks
+// the admin about them. Without an Access the message is dropped. It
+// reports whether the message was held.
+func (c *BaseChannel) requestAccess(senderID, chatID, content string) bool {
+	a := c.getAccess()
+	if a == nil {
+		return false
+	}
+	a.Request(bus.InboundMessage{
+		Channel:   c.name,
+		SenderID:  senderID,
+		ChatID:    chatID,
+		Content:   content,
+		Timestamp: time.Now(),
+	})
+	return true
+}
+
 // getBus returns the message bus for use by derived channels.
 func (c *BaseChannel) getBus() *bus.MessageBus {
 	return c.bus
@@ -0,0 +1,135 @@
+package channels
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+const (
+	// draftBackoff postpones the next edit of a draft after one failed,
+	// e.g. because the chat was edited too often after all.
+	draftBackoff = 5 * time.Second
+	// draftKeep is how long a stream is remembered, so that drafts that
+	// arrive after the final reply are ignored.
+	draftKeep = 10 * time.Minute
+	// draftCursor ends a draft, to show that the reply is still coming.
+	draftCursor = " …"
+)
+
+// Drafts shows replies while they are generated. The first partial reply
+// of a stream is sent as a new message and later ones edit it, no more
+// often than the chat's interval allows; a partial reply that comes too
+// soon is skipped, since the next one carries its text too. The final reply
+// then takes the draft's place, see Finish.
+type Drafts struct {
+	send     func(msg bus.OutboundMessage) (messageID int, err error)
+	edit     func(chatID string, messageID int, text string) error
+	interval func(chatID string) time.Duration // least time between edits
+	maxChars int
+
+	mu      sync.Mutex
+	streams map[string]*draft
+}
+
+// draft is the message showing one stream.
+type draft struct {
+	mu        sync.Mutex
+	started   time.Time
+	messageID int       // 0 until the draft has been sent
+	shown     string    // text of the draft
+	next      time.Time // earliest time for the next edit
+	finished  bool
+}
+
+// NewDrafts creates a Drafts that sends the first draft of a stream with
+// send and edits it with edit. Drafts are cut to maxChars characters.
+func NewDrafts(send func(bus.OutboundMessage) (int, error), edit func(chatID string, messageID int, text string) error, interval func(chatID string) time.Duration, maxChars int) *Drafts {
+	return &Drafts{
+		send:     send,
+		edit:     edit,
+		interval: interval,
+		maxChars: maxChars,
+		streams:  make(map[string]*draft),
+	}
+}
+
+// stream returns the draft of the stream id, forgetting old streams.
+func (d *Drafts) stream(id string) *draft {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	for key, s := range d.streams {
+		if now.Sub(s.started) > draftKeep {
+			delete(d.streams, key)
+		}
+	}
+	s, ok := d.streams[id]
+	if !ok {
+		s = &draft{started: now}
+		d.streams[id] = s
+	}
+	return s
+}
+
+// Update shows msg, a partial reply, unless its stream has finished or the
+// chat was edited too recently.
+func (d *Drafts) Update(msg bus.OutboundMessage) {
+	if msg.Stream == "" || strings.TrimSpace(msg.Content) == "" {
+		return
+	}
+	s := d.stream(msg.Stream)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	text := draftText(msg.Content, d.maxChars)
+	if s.finished || text == s.shown || now.Before(s.next) {
+		return
+	}
+	var err error
+	if s.messageID == 0 {
+		msg.Content = text
+		s.messageID, err = d.send(msg)
+	} else {
+		err = d.edit(msg.ChatID, s.messageID, text)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to show the reply so far: %v", err)
+		s.next = now.Add(draftBackoff)
+		return
+	}
+	s.shown = text
+	s.next = now.Add(d.interval(msg.ChatID))
+}
+
+// Finish ends the stream and returns the ID of the message showing its
+// draft, for the final reply to replace, or 0 when no draft was sent.
+// Later calls for the stream return 0, so that the remaining parts of a
+// split reply are sent as new messages.
+func (d *Drafts) Finish(stream string) int {
+	if stream == "" {
+		return 0
+	}
+	s := d.stream(stream)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.finished = true
+	id := s.messageID
+	s.messageID = 0
+	return id
+}
+
+// draftText returns the reply so far as shown in a draft: cut to maxChars
+// characters and ending in a cursor.
+func draftText(content string, maxChars int) string {
+	text := []rune(strings.TrimSpace(content))
+	if limit := maxChars - len([]rune(draftCursor)); maxChars > 0 && len(text) > limit {
+		text = text[:limit]
+	}
+	return string(text) + draftCursor
+}
@@ -0,0 +1,87 @@
+package channels
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+func TestDrafts(t *testing.T) {
+	var sent []bus.OutboundMessage
+	var edits []string
+	failEdit := false
+	interval := time.Duration(0)
+	drafts := NewDrafts(
+		func(msg bus.OutboundMessage) (int, error) {
+			sent = append(sent, msg)
+			return 7, nil
+		},
+		func(chatID string, messageID int, text string) error {
+			if failEdit {
+				return errors.New("too many requests")
+			}
+			if messageID != 7 {
+				t.Errorf("edited message %d, want the draft", messageID)
+			}
+			edits = append(edits, text)
+			return nil
+		},
+		func(string) time.Duration { return interval },
+		20,
+	)
+	partial := func(content string) bus.OutboundMessage {
+		return bus.OutboundMessage{ChatID: "1", Content: content, ReplyTo: "3", Stream: "s1", Partial: true}
+	}
+
+	// The first draft is sent quoting the message, later ones edit it
+	drafts.Update(partial("Hello"))
+	drafts.Update(partial("Hello"))
+	drafts.Update(partial("Hello, world"))
+	if len(sent) != 1 || sent[0].Content != "Hello …" || sent[0].ReplyTo != "3" {
+		t.Fatalf("sent = %+v", sent)
+	}
+	if len(edits) != 1 || edits[0] != "Hello, world …" {
+		t.Fatalf("edits = %q, want one edit (unchanged drafts are skipped)", edits)
+	}
+
+	// Drafts are cut to the limit, and skipped while the chat rests
+	interval = time.Hour
+	drafts.Update(partial(strings.Repeat("x", 30)))
+	drafts.Update(partial("skipped"))
+	if len(edits) != 2 || len([]rune(edits[1])) != 20 {
+		t.Fatalf("edits = %q, want a second edit of 20 characters", edits)
+	}
+
+	// The final reply takes the draft once; later drafts are ignored
+	if id := drafts.Finish("s1"); id != 7 {
+		t.Errorf("Finish = %d, want the draft's message", id)
+	}
+	if id := drafts.Finish("s1"); id != 0 {
+		t.Errorf("second Finish = %d, want 0 for the rest of a split reply", id)
+	}
+	interval = 0
+	drafts.Update(partial("late"))
+	if len(sent) != 1 || len(edits) != 2 {
+		t.Error("a draft arriving after the final reply was shown")
+	}
+	if id := drafts.Finish(""); id != 0 {
+		t.Errorf("Finish without a stream = %d", id)
+	}
+
+	// A failed edit waits before the next try
+	other := partial("one")
+	other.Stream = "s2"
+	drafts.Update(other)
+	failEdit = true
+	other.Content = "one two"
+	drafts.Update(other)
+	failEdit = false
+	other.Content = "one two three"
+	drafts.Update(other)
+	if len(edits) != 2 {
+		t.Errorf("edits = %q, want none right after a failure", edits)
+	}
+}
@@ -0,0 +1,62 @@
+package channels
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// chatFilesDir returns the directory under root holding the files exchanged
+// in one chat, such as root/telegram_123456.
+func chatFilesDir(root, channel, chatID string) string {
+	return filepath.Join(root, safeFileName(channel+"_"+chatID))
+}
+
+// saveChatFile stores a file a user sent into the chat's directory under
+// root and returns its path. An earlier file with the same name is kept;
+// the new one gets a numbered name such as report-2.pdf.
+func saveChatFile(root, channel, chatID, name string, r io.Reader) (string, error) {
+	dir := chatFilesDir(root, channel, chatID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create files directory: %w", err)
+	}
+
+	name = safeFileName(name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 1; ; n++ {
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if os.IsExist(err) {
+			name = base + "-" + strconv.Itoa(n+1) + ext
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to save file: %w", err)
+		}
+		_, err = io.Copy(f, r)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("failed to save file: %w", err)
+		}
+		return path, nil
+	}
+}
+
+// safeFileName turns a name chosen by a user into one that stays inside
+// its directory.
+func safeFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base(strings.ReplaceAll(name, "\x00", ""))
+	name = strings.TrimLeft(name, ".")
+	if name == "" || name == "/" {
+		return "file"
+	}
+	return name
+}
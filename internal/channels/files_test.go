@@ -0,0 +1,49 @@
+package channels
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveChatFile(t *testing.T) {
+	root := t.TempDir()
+
+	first, err := saveChatFile(root, "telegram", "-100", "report.pdf", strings.NewReader("one"))
+	if err != nil {
+		t.Fatalf("saveChatFile: %v", err)
+	}
+	if want := filepath.Join(root, "telegram_-100", "report.pdf"); first != want {
+		t.Errorf("path = %q, want %q", first, want)
+	}
+
+	// A second file of the same name does not replace the first
+	second, err := saveChatFile(root, "telegram", "-100", "report.pdf", strings.NewReader("two"))
+	if err != nil {
+		t.Fatalf("saveChatFile: %v", err)
+	}
+	if filepath.Base(second) != "report-2.pdf" {
+		t.Errorf("second path = %q, want report-2.pdf", second)
+	}
+	if data, _ := os.ReadFile(first); string(data) != "one" {
+		t.Errorf("first file = %q, want one", data)
+	}
+}
+
+func TestSafeFileName(t *testing.T) {
+	for name, want := range map[string]string{
+		"notes.txt":          "notes.txt",
+		"../../.ssh/id_rsa":  "id_rsa",
+		`..\..\windows.ini`:  "windows.ini",
+		".bashrc":            "bashrc",
+		"..":                 "file",
+		"":                   "file",
+		"dir/":               "dir",
+		"report\x00.pdf.exe": "report.pdf.exe",
+	} {
+		if got := safeFileName(name); got != want {
+			t.Errorf("safeFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
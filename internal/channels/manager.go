@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"reflect"
 	"sort"
 	"sync"
 
 	"github.com/hkuds/ubot/internal/bus"
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/control"
 	"github.com/hkuds/ubot/internal/voice"
 )
 
@@ -17,18 +19,30 @@ type Manager struct {
 	config   *config.Config
 	bus      *bus.MessageBus
 	channels map[string]Channel
+	access   *Access
 	mu       sync.RWMutex
+
+	// ctx is the context passed to StartAll; channels started later at
+	// runtime are bound to it.
+	ctx context.Context
 }
 
 // NewManager creates a new channel manager.
 func NewManager(cfg *config.Config, msgBus *bus.MessageBus) *Manager {
+	admin := cfg.Channels.Admin
 	return &Manager{
 		config:   cfg,
 		bus:      msgBus,
 		channels: make(map[string]Channel),
+		access:   NewAccess(cfg.AccessPath(), msgBus, admin.Channel, admin.ChatID),
 	}
 }
 
+// Access returns the manager's access requests from unknown senders.
+func (m *Manager) Access() *Access {
+	return m.access
+}
+
 // Initialize creates enabled channels based on configuration.
 // This must be called before StartAll.
 func (m *Manager) Initialize() error {
@@ -37,19 +51,9 @@ func (m *Manager) Initialize() error {
 
 	// Initialize Telegram channel if enabled
 	if m.config.Channels.Telegram.Enabled {
-		if m.config.Channels.Telegram.Token == "" {
-			return fmt.Errorf("telegram channel enabled but token not configured")
+		if err := m.createLocked("telegram"); err != nil {
+			return err
 		}
-
-		// Build voice transcriber if a suitable API key is available
-		transcriber := m.buildTranscriber()
-
-		telegram := NewTelegramChannel(
-			m.config.Channels.Telegram,
-			m.bus,
-			transcriber,
-		)
-		m.channels["telegram"] = telegram
 		log.Println("Telegram channel initialized")
 	}
 
@@ -63,8 +67,38 @@ func (m *Manager) Initialize() error {
 	return nil
 }
 
+// createLocked builds a channel from configuration and registers it.
+// Caller must hold m.mu.
+func (m *Manager) createLocked(name string) error {
+	switch name {
+	case "telegram":
+		if m.config.Channels.Telegram.Token == "" {
+			return fmt.Errorf("telegram channel enabled but token not configured")
+		}
+
+		// Build voice transcriber if a suitable API key is available
+		transcriber := m.buildTranscriber()
+
+		ch := NewTelegramChannel(
+			m.config.Channels.Telegram,
+			m.bus,
+			transcriber,
+			m.config.FilesPath(),
+		)
+		ch.SetAccess(m.access)
+		m.channels["telegram"] = ch
+		return nil
+	default:
+		return fmt.Errorf("unknown channel %q", name)
+	}
+}
+
 // StartAll starts all initialized channels.
 func (m *Manager) StartAll(ctx context.Context) error {
+	m.mu.Lock()
+	m.ctx = ctx
+	m.mu.Unlock()
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -111,6 +145,113 @@ func (m *Manager) StopAll() error {
 	return nil
 }
 
+// StartChannel starts a single channel at runtime. Channels that were not
+// enabled at startup are created from configuration on demand.
+func (m *Manager) StartChannel(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx == nil {
+		return fmt.Errorf("channel manager not started")
+	}
+
+	ch, ok := m.channels[name]
+	if !ok {
+		if err := m.createLocked(name); err != nil {
+			return err
+		}
+		ch = m.channels[name]
+	}
+
+	if ch.IsRunning() {
+		return fmt.Errorf("channel %s is already running", name)
+	}
+	if err := ch.Start(m.ctx); err != nil {
+		return fmt.Errorf("failed to start channel %s: %w", name, err)
+	}
+	log.Printf("Channel %s started", name)
+	return nil
+}
+
+// StopChannel stops a single running channel. The channel stays registered
+// so it can be started again with StartChannel.
+func (m *Manager) StopChannel(name string) error {
+	m.mu.RLock()
+	ch, ok := m.channels[name]
+	m.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("channel %s not found", name)
+	}
+	if !ch.IsRunning() {
+		return fmt.Errorf("channel %s is not running", name)
+	}
+	if err := ch.Stop(); err != nil {
+		return fmt.Errorf("failed to stop channel %s: %w", name, err)
+	}
+	log.Printf("Channel %s stopped", name)
+	return nil
+}
+
+// Reconfigure applies new channel settings at runtime: a channel enabled
+// in cfg is started, a disabled one is stopped and removed, and one whose
+// settings changed is restarted with them. It returns the names of the
+// channels it changed.
+func (m *Manager) Reconfigure(cfg *config.Config) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.config
+	m.config = cfg
+
+	was, now := old.Channels.Telegram, cfg.Channels.Telegram
+	ch, exists := m.channels["telegram"]
+	changed := !reflect.DeepEqual(was, now) || !reflect.DeepEqual(old.Tools.Voice, cfg.Tools.Voice)
+	if exists && (!now.Enabled || changed) {
+		if ch.IsRunning() {
+			if err := ch.Stop(); err != nil {
+				return nil, fmt.Errorf("failed to stop channel telegram: %w", err)
+			}
+			log.Printf("Channel telegram stopped")
+		}
+		delete(m.channels, "telegram")
+	}
+	if !now.Enabled {
+		if exists {
+			return []string{"telegram"}, nil
+		}
+		return nil, nil
+	}
+	if exists && !changed {
+		return nil, nil
+	}
+
+	if err := m.createLocked("telegram"); err != nil {
+		return nil, err
+	}
+	if m.ctx != nil {
+		if err := m.channels["telegram"].Start(m.ctx); err != nil {
+			return nil, fmt.Errorf("failed to start channel telegram: %w", err)
+		}
+		log.Printf("Channel telegram started")
+	}
+	return []string{"telegram"}, nil
+}
+
+// ChannelStatuses returns the name and running state of every registered
+// channel, sorted by name.
+func (m *Manager) ChannelStatuses() []control.ChannelStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]control.ChannelStatus, 0, len(m.channels))
+	for name, ch := range m.channels {
+		statuses = append(statuses, control.ChannelStatus{Name: name, Running: ch.IsRunning()})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
 // GetChannel returns a channel by name, or nil if not found.
 func (m *Manager) GetChannel(name string) Channel {
 	m.mu.RLock()
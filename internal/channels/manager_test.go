@@ -0,0 +1,63 @@
+package channels
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/testenv"
+)
+
+func TestManagerReconfigure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := testenv.Start(t)
+
+	cfg := config.DefaultConfig()
+	m := NewManager(cfg, bus.NewMessageBus(10))
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.StartAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer m.StopAll()
+
+	// Enabled
+	enabled := config.DefaultConfig()
+	enabled.Channels.Telegram = config.TelegramConfig{Enabled: true, Token: "123456:TEST", APIEndpoint: env.Telegram.APIEndpoint()}
+	changed, err := m.Reconfigure(enabled)
+	if err != nil || !slices.Equal(changed, []string{"telegram"}) {
+		t.Fatalf("enable: changed = %v, err = %v", changed, err)
+	}
+	if running := m.RunningChannels(); !slices.Equal(running, []string{"telegram"}) {
+		t.Errorf("running after enable = %v", running)
+	}
+
+	// Unchanged
+	same := *enabled
+	if changed, err := m.Reconfigure(&same); err != nil || len(changed) != 0 {
+		t.Errorf("unchanged: changed = %v, err = %v", changed, err)
+	}
+
+	// Settings changed: restarted with them
+	edited := *enabled
+	edited.Channels.Telegram.AllowFrom = []string{"42"}
+	if changed, err := m.Reconfigure(&edited); err != nil || len(changed) != 1 {
+		t.Errorf("edit: changed = %v, err = %v", changed, err)
+	}
+	if ch, ok := m.GetChannel("telegram").(*TelegramChannel); !ok || !ch.IsRunning() || !ch.IsAllowed("42") {
+		t.Errorf("telegram not restarted with the new settings")
+	}
+
+	// Disabled
+	if changed, err := m.Reconfigure(cfg); err != nil || len(changed) != 1 {
+		t.Errorf("disable: changed = %v, err = %v", changed, err)
+	}
+	if m.ChannelCount() != 0 {
+		t.Errorf("channels after disable = %v", m.ListChannels())
+	}
+}
@@ -0,0 +1,156 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+const (
+	// outboxMinRetry is the first pause before retrying held messages; it
+	// doubles up to outboxMaxRetry.
+	outboxMinRetry = 10 * time.Second
+	outboxMaxRetry = 5 * time.Minute
+)
+
+// Outbox delivers outbound messages for a channel, holding them while the
+// channel's service cannot be reached and sending them, in order, once it
+// can. Held messages are kept in memory only.
+type Outbox struct {
+	send func(bus.OutboundMessage) error
+
+	mu    sync.Mutex
+	since time.Time // when delivery started failing; zero when online
+	held  []bus.OutboundMessage
+	wake  chan struct{}
+}
+
+// NewOutbox creates an Outbox delivering through send.
+func NewOutbox(send func(bus.OutboundMessage) error) *Outbox {
+	return &Outbox{send: send, wake: make(chan struct{}, 1)}
+}
+
+// Deliver sends msg, or holds it if earlier messages are still held or the
+// service cannot be reached. Other errors are returned.
+func (o *Outbox) Deliver(msg bus.OutboundMessage) error {
+	o.mu.Lock()
+	if len(o.held) > 0 {
+		o.held = append(o.held, msg)
+		o.mu.Unlock()
+		return nil
+	}
+	o.mu.Unlock()
+
+	err := o.send(msg)
+	if !isNetworkError(err) {
+		return err
+	}
+
+	log.Printf("Warning: holding message for %s:%s until the channel is reachable: %v", msg.Channel, msg.ChatID, err)
+	o.mu.Lock()
+	if o.since.IsZero() {
+		o.since = time.Now()
+	}
+	o.held = append(o.held, msg)
+	o.mu.Unlock()
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run retries held messages, with increasing pauses, until ctx is
+// cancelled. Once the service answers, each waiting chat first gets a notice
+// saying how long delivery was delayed.
+func (o *Outbox) Run(ctx context.Context) {
+	delay := outboxMinRetry
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.wake:
+			delay = outboxMinRetry
+			continue
+		case <-time.After(delay):
+		}
+
+		if o.flush() {
+			delay = outboxMinRetry
+		} else if delay *= 2; delay > outboxMaxRetry {
+			delay = outboxMaxRetry
+		}
+	}
+}
+
+// flush sends the held messages, preceded by a notice to each chat. It
+// reports false if the service is still unreachable.
+func (o *Outbox) flush() bool {
+	o.mu.Lock()
+	if len(o.held) == 0 {
+		o.mu.Unlock()
+		return true
+	}
+	since := o.since
+	counts := make(map[string]int)
+	var chats []bus.OutboundMessage
+	for _, msg := range o.held {
+		key := msg.Channel + ":" + msg.ChatID
+		if counts[key] == 0 {
+			chats = append(chats, msg)
+		}
+		counts[key]++
+	}
+	o.mu.Unlock()
+
+	for _, chat := range chats {
+		err := o.send(bus.OutboundMessage{
+			Channel: chat.Channel,
+			ChatID:  chat.ChatID,
+			Content: bus.DeliveryNotice(time.Since(since), counts[chat.Channel+":"+chat.ChatID]),
+		})
+		if isNetworkError(err) {
+			return false
+		}
+	}
+	o.mu.Lock()
+	o.since = time.Time{}
+	o.mu.Unlock()
+
+	for {
+		o.mu.Lock()
+		if len(o.held) == 0 {
+			o.mu.Unlock()
+			return true
+		}
+		msg := o.held[0]
+		o.mu.Unlock()
+
+		err := o.send(msg)
+		if isNetworkError(err) {
+			// Unreachable again; the rest get a new notice next time
+			o.mu.Lock()
+			o.since = time.Now()
+			o.mu.Unlock()
+			return false
+		}
+		if err != nil {
+			log.Printf("Error sending held %s message: %v", msg.Channel, err)
+		}
+		o.mu.Lock()
+		o.held = o.held[1:]
+		o.mu.Unlock()
+	}
+}
+
+// isNetworkError reports whether err is a failure to reach the service
+// rather than a rejected request.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return err != nil && errors.As(err, &netErr)
+}
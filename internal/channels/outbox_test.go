@@ -0,0 +1,55 @@
+package channels
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+func TestOutboxHoldsWhileUnreachable(t *testing.T) {
+	down := true
+	var sent []string
+	o := NewOutbox(func(msg bus.OutboundMessage) error {
+		if down {
+			return &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+		}
+		sent = append(sent, msg.Content)
+		return nil
+	})
+
+	for _, text := range []string{"one", "two"} {
+		if err := o.Deliver(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: text}); err != nil {
+			t.Fatalf("Deliver(%q): %v", text, err)
+		}
+	}
+	if o.flush() {
+		t.Fatal("flush succeeded while unreachable")
+	}
+
+	down = false
+	if err := o.Deliver(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "three"}); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("sent %v ahead of held messages", sent)
+	}
+	if !o.flush() {
+		t.Fatal("flush failed")
+	}
+	if len(sent) != 4 || !strings.Contains(sent[0], "3 messages I couldn't send") || sent[1] != "one" || sent[3] != "three" {
+		t.Errorf("sent = %q", sent)
+	}
+
+	// Back online, messages go straight out and other errors are returned
+	sent = nil
+	if err := o.Deliver(bus.OutboundMessage{Content: "four"}); err != nil || len(sent) != 1 {
+		t.Errorf("Deliver online = %v, sent %v", err, sent)
+	}
+	rejected := NewOutbox(func(bus.OutboundMessage) error { return errors.New("chat not found") })
+	if err := rejected.Deliver(bus.OutboundMessage{}); err == nil {
+		t.Error("rejected message was held")
+	}
+}
@@ -2,27 +2,54 @@ package channels
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/hkuds/ubot/internal/bus"
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/tracing"
 	"github.com/hkuds/ubot/internal/voice"
 )
 
+const (
+	// telegramMaxMessageChars is Telegram's limit on the text of a message.
+	telegramMaxMessageChars = 4096
+	// telegramMaxDownload is the largest file the Bot API lets bots download.
+	telegramMaxDownload = 20 << 20
+	// telegramMaxPhoto is the largest picture sent as a photo; larger ones
+	// are sent as documents.
+	telegramMaxPhoto = 10 << 20
+	// Telegram lets a bot send or edit about one message a second in a
+	// chat, and 20 a minute in a group; drafts are edited less often.
+	telegramDraftInterval      = 1500 * time.Millisecond
+	telegramGroupDraftInterval = 4 * time.Second
+)
+
 // TelegramChannel implements the Channel interface for Telegram messaging.
 type TelegramChannel struct {
 	BaseChannel
-	token       string
-	bot         *tgbotapi.BotAPI
-	transcriber *voice.Transcriber // nil when voice is not configured
+	token         string
+	apiEndpoint   string // Bot API URL format; "" = api.telegram.org
+	codeFileLimit int    // code blocks longer than this are sent as files
+	bot           *tgbotapi.BotAPI
+	transcriber   *voice.Transcriber // nil when voice is not configured
+	filesDir      string             // where files users send are saved; "" = not saved
+	outbox        *Outbox            // holds replies while Telegram is unreachable
+	typing        *Typing            // shows "typing…" until a chat gets its reply
+	drafts        *Drafts            // shows replies as they are generated
+	streaming     bool
+	groups        config.TelegramGroupsConfig
 
 	// chatIDs maps string chat IDs to int64 for message sending
 	chatIDs map[string]int64
@@ -30,16 +57,30 @@ type TelegramChannel struct {
 
 	// cancel function for stopping the update loop
 	cancel context.CancelFunc
+
+	// subscribeOnce ensures the outbound subscription survives restarts
+	// without registering duplicate callbacks.
+	subscribeOnce sync.Once
 }
 
-// NewTelegramChannel creates a new Telegram channel instance.
-func NewTelegramChannel(cfg config.TelegramConfig, msgBus *bus.MessageBus, transcriber *voice.Transcriber) *TelegramChannel {
-	return &TelegramChannel{
-		BaseChannel: NewBaseChannel("telegram", msgBus, cfg.AllowFrom),
-		token:       cfg.Token,
-		transcriber: transcriber,
-		chatIDs:     make(map[string]int64),
+// NewTelegramChannel creates a new Telegram channel instance. Documents and
+// photos users send are saved under filesDir, one directory per chat.
+func NewTelegramChannel(cfg config.TelegramConfig, msgBus *bus.MessageBus, transcriber *voice.Transcriber, filesDir string) *TelegramChannel {
+	c := &TelegramChannel{
+		BaseChannel:   NewBaseChannel("telegram", msgBus, cfg.AllowFrom),
+		token:         cfg.Token,
+		apiEndpoint:   cfg.APIEndpoint,
+		codeFileLimit: cfg.CodeFileLimit(),
+		transcriber:   transcriber,
+		filesDir:      filesDir,
+		groups:        cfg.Groups,
+		streaming:     !cfg.DisableStreaming,
+		chatIDs:       make(map[string]int64),
 	}
+	c.outbox = NewOutbox(c.Send)
+	c.typing = NewTyping(c.sendTyping)
+	c.drafts = NewDrafts(c.sendDraft, c.editDraft, draftInterval, telegramMaxMessageChars)
+	return c
 }
 
 // Start begins listening for Telegram updates.
@@ -48,8 +89,12 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 		return fmt.Errorf("telegram channel is already running")
 	}
 
-	// Create bot API with token
-	bot, err := tgbotapi.NewBotAPI(c.token)
+	// Create bot API with token, at a self-hosted Bot API server if set
+	endpoint := c.apiEndpoint
+	if endpoint == "" {
+		endpoint = tgbotapi.APIEndpoint
+	}
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(c.token, endpoint)
 	if err != nil {
 		return fmt.Errorf("failed to create Telegram bot: %w", err)
 	}
@@ -57,6 +102,11 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 
 	log.Printf("Telegram bot authorized as @%s", bot.Self.UserName)
 
+	// Offer the commands in Telegram's menu
+	if _, err := bot.Request(tgbotapi.NewSetMyCommands(telegramCommands...)); err != nil {
+		log.Printf("Warning: failed to set Telegram commands: %v", err)
+	}
+
 	// Create cancellable context
 	ctx, cancel := context.WithCancel(ctx)
 	c.cancel = cancel
@@ -69,15 +119,29 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 
 	c.setRunning(true)
 
-	// Subscribe to outbound messages for this channel
-	c.getBus().SubscribeOutbound("telegram", func(msg bus.OutboundMessage) {
-		if err := c.Send(msg); err != nil {
-			log.Printf("Error sending Telegram message: %v", err)
-		}
+	// Subscribe to outbound messages for this channel; the bus labels code
+	// blocks, moves long ones into files and splits messages longer than
+	// Telegram allows. Partial replies are shown as drafts, unless streaming
+	// is disabled.
+	c.subscribeOnce.Do(func() {
+		c.getBus().SetCodeBlocks("telegram", c.codeFileLimit)
+		c.getBus().SetMessageLimit("telegram", telegramMaxMessageChars)
+		c.getBus().SetStreaming("telegram", c.streaming)
+		c.getBus().SubscribeOutbound("telegram", func(msg bus.OutboundMessage) {
+			if msg.Partial {
+				c.drafts.Update(msg)
+				return
+			}
+			c.typing.Stop(msg.ChatID)
+			if err := c.outbox.Deliver(msg); err != nil {
+				log.Printf("Error sending Telegram message: %v", err)
+			}
+		})
 	})
 
 	// Start processing updates in a goroutine
 	go c.processUpdates(ctx, updates)
+	go c.outbox.Run(ctx)
 
 	return nil
 }
@@ -90,6 +154,10 @@ func (c *TelegramChannel) processUpdates(ctx context.Context, updates tgbotapi.U
 			log.Println("Telegram update processing stopped")
 			return
 		case update := <-updates:
+			if update.CallbackQuery != nil {
+				c.handleCallback(update.CallbackQuery)
+				continue
+			}
 			if update.Message == nil {
 				continue
 			}
@@ -107,22 +175,54 @@ func (c *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
 		senderID = senderID + "|" + msg.From.UserName
 	}
 
+	// In groups the bot only answers where it is allowed, and only messages
+	// meant for it; the others are not for us and go unnoticed.
+	chatIDStr := strconv.FormatInt(msg.Chat.ID, 10)
+	group := isGroupChat(msg.Chat)
+	if group {
+		if !c.groups.AllowsGroup(chatIDStr) {
+			log.Printf("Telegram message from a group that is not allowed: %s", chatIDStr)
+			return
+		}
+		if !groupActivated(msg, c.bot.Self, c.groups.Activation) {
+			return
+		}
+	}
+
 	// Check if sender is allowed
-	if !c.IsAllowed(senderID) {
-		log.Printf("Telegram message from unauthorized sender: %s", senderID)
+	anyMember := group && c.groups.AnyMember && len(c.groups.AllowFrom) > 0
+	if !anyMember && !c.IsAllowed(senderID) {
+		// Unknown senders are held for the admin; their voice messages are
+		// not transcribed until they are allowed.
+		content := msg.Text
+		if content == "" {
+			content = msg.Caption
+		}
+		if !c.requestAccess(senderID, chatIDStr, content) {
+			log.Printf("Telegram message from unauthorized sender: %s", senderID)
+		}
 		return
 	}
 
 	// Store chat ID mapping
-	chatIDStr := strconv.FormatInt(msg.Chat.ID, 10)
 	c.chatMu.Lock()
 	c.chatIDs[chatIDStr] = msg.Chat.ID
 	c.chatMu.Unlock()
 
+	// The trace of the message starts here, including voice transcription
+	ctx, span := tracing.Start(context.Background(), "receive telegram",
+		attribute.String("ubot.channel", "telegram"),
+		attribute.String("ubot.chat_id", chatIDStr),
+	)
+	defer span.End()
+
 	// Build metadata
 	metadata := make(map[string]interface{})
 	metadata["messageId"] = msg.MessageID
 	metadata["chatType"] = msg.Chat.Type
+	if group {
+		metadata["chatTitle"] = msg.Chat.Title
+	}
 	if msg.From.FirstName != "" {
 		metadata["firstName"] = msg.From.FirstName
 	}
@@ -135,6 +235,7 @@ func (c *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
 
 	var content string
 	var media []string
+	var cmd *bus.Command
 
 	// Handle different message types
 	switch {
@@ -155,6 +256,8 @@ func (c *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
 		media = append(media, photo.FileID)
 		content = msg.Caption
 		metadata["originalType"] = "photo"
+		name := fmt.Sprintf("photo-%d.jpg", msg.MessageID)
+		content = c.receiveFile(chatIDStr, photo.FileID, name, photo.FileSize, content, metadata)
 
 	case msg.Document != nil:
 		media = append(media, msg.Document.FileID)
@@ -162,9 +265,14 @@ func (c *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
 		metadata["originalType"] = "document"
 		metadata["fileName"] = msg.Document.FileName
 		metadata["mimeType"] = msg.Document.MimeType
+		content = c.receiveFile(chatIDStr, msg.Document.FileID, msg.Document.FileName, msg.Document.FileSize, content, metadata)
 
 	case msg.Text != "":
 		content = msg.Text
+		var ok bool
+		if cmd, content, ok = parseCommand(msg.Text, c.bot.Self.UserName); !ok {
+			return // a command for another bot in the group
+		}
 
 	default:
 		// Handle other message types as generic content
@@ -173,8 +281,123 @@ func (c *TelegramChannel) handleMessage(msg *tgbotapi.Message) {
 		}
 	}
 
-	// Publish to message bus
-	c.publishInbound(senderID, chatIDStr, content, media, metadata)
+	// The group shares one conversation: the mention is dropped and the
+	// model is told who is speaking
+	if group {
+		content, _ = stripMention(content, c.bot.Self.UserName)
+		if cmd == nil && content != "" {
+			content = groupSpeaker(msg.From) + ": " + content
+		}
+	}
+
+	// Publish to message bus, showing "typing…" until the reply is sent
+	c.typing.Start(chatIDStr)
+	inbound := c.newInbound(ctx, senderID, chatIDStr, content, media, metadata)
+	inbound.Command = cmd
+	c.publish(inbound)
+}
+
+// handleCallback turns an inline button press into a message from the user
+// carrying the button's data, and removes the buttons so they are used once.
+// Buttons carry commands such as "/model gpt-4o", which reach the bus as
+// commands like typed ones.
+func (c *TelegramChannel) handleCallback(cb *tgbotapi.CallbackQuery) {
+	senderID := strconv.FormatInt(cb.From.ID, 10)
+	if cb.From.UserName != "" {
+		senderID = senderID + "|" + cb.From.UserName
+	}
+	if !c.IsAllowed(senderID) {
+		log.Printf("Telegram callback from unauthorized sender: %s", senderID)
+		return
+	}
+
+	if _, err := c.bot.Request(tgbotapi.NewCallback(cb.ID, "")); err != nil {
+		log.Printf("Failed to answer Telegram callback: %v", err)
+	}
+	if cb.Message == nil {
+		return
+	}
+
+	chatID := cb.Message.Chat.ID
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, cb.Message.MessageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := c.bot.Request(edit); err != nil {
+		log.Printf("Failed to remove Telegram buttons: %v", err)
+	}
+
+	chatIDStr := strconv.FormatInt(chatID, 10)
+	c.chatMu.Lock()
+	c.chatIDs[chatIDStr] = chatID
+	c.chatMu.Unlock()
+
+	ctx, span := tracing.Start(context.Background(), "receive telegram",
+		attribute.String("ubot.channel", "telegram"),
+		attribute.String("ubot.chat_id", chatIDStr),
+	)
+	defer span.End()
+	cmd, content, _ := parseCommand(cb.Data, c.bot.Self.UserName)
+	c.typing.Start(chatIDStr)
+	inbound := c.newInbound(ctx, senderID, chatIDStr, content, nil, map[string]interface{}{
+		"originalType": "callback",
+	})
+	inbound.Command = cmd
+	c.publish(inbound)
+}
+
+// receiveFile saves a file the user sent into the chat's files directory
+// and adds its path to the "files" metadata. It returns content, noting
+// there when the file could not be saved.
+func (c *TelegramChannel) receiveFile(chatID, fileID, name string, size int, content string, metadata map[string]interface{}) string {
+	if c.filesDir == "" {
+		return content
+	}
+	path, err := c.saveFile(chatID, fileID, name, size)
+	if err != nil {
+		log.Printf("Failed to save Telegram file %s: %v", name, err)
+		return strings.TrimSpace(content + "\n\n[File " + name + " could not be received: " + err.Error() + "]")
+	}
+	metadata["files"] = []string{path}
+	return content
+}
+
+// saveFile downloads a file the user sent into the chat's files directory
+// and returns its path.
+func (c *TelegramChannel) saveFile(chatID, fileID, name string, size int) (string, error) {
+	if size > telegramMaxDownload {
+		return "", fmt.Errorf("larger than the %d MB bots may download", telegramMaxDownload>>20)
+	}
+	body, err := c.downloadFile(fileID)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	return saveChatFile(c.filesDir, c.Name(), chatID, name, io.LimitReader(body, telegramMaxDownload))
+}
+
+// downloadFile fetches a file from Telegram. The caller must close it.
+func (c *TelegramChannel) downloadFile(fileID string) (io.ReadCloser, error) {
+	file, err := c.bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	resp, err := http.Get(c.fileURL(file.FilePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file from Telegram")
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download file from Telegram: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// fileURL returns the URL to download the file at path from: the file
+// endpoint of the Bot API server in use, such as a self-hosted one.
+func (c *TelegramChannel) fileURL(path string) string {
+	if c.apiEndpoint == "" {
+		return fmt.Sprintf(tgbotapi.FileEndpoint, c.token, path)
+	}
+	return fmt.Sprintf(strings.Replace(c.apiEndpoint, "/bot%s/", "/file/bot%s/", 1), c.token, path)
 }
 
 // transcribeVoice transcribes a voice message using the configured voice transcriber.
@@ -183,22 +406,14 @@ func (c *TelegramChannel) transcribeVoice(v *tgbotapi.Voice) (string, error) {
 		return "", fmt.Errorf("voice transcription not configured")
 	}
 
-	// Get the voice file from Telegram
-	fileConfig := tgbotapi.FileConfig{FileID: v.FileID}
-	file, err := c.bot.GetFile(fileConfig)
-	if err != nil {
-		return "", fmt.Errorf("failed to get voice file: %w", err)
-	}
-
-	// Download the file
-	fileURL := file.Link(c.token)
-	resp, err := http.Get(fileURL)
+	// Download the voice file from Telegram
+	body, err := c.downloadFile(v.FileID)
 	if err != nil {
-		return "", fmt.Errorf("failed to download voice file from Telegram")
+		return "", fmt.Errorf("voice file: %w", err)
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 
-	audioData, err := io.ReadAll(resp.Body)
+	audioData, err := io.ReadAll(body)
 	if err != nil {
 		return "", fmt.Errorf("failed to read voice data: %w", err)
 	}
@@ -219,6 +434,7 @@ func (c *TelegramChannel) Stop() error {
 	if c.bot != nil {
 		c.bot.StopReceivingUpdates()
 	}
+	c.typing.StopAll()
 
 	c.setRunning(false)
 	log.Println("Telegram channel stopped")
@@ -237,6 +453,35 @@ func (c *TelegramChannel) Send(msg bus.OutboundMessage) error {
 		return fmt.Errorf("invalid chat ID: %w", err)
 	}
 
+	// A message may carry only files, as from send_file. A reply shown as
+	// a draft while it was generated replaces the draft.
+	if strings.TrimSpace(msg.Content) != "" {
+		replaced := false
+		if draftID := c.drafts.Finish(msg.Stream); draftID != 0 {
+			if err := c.editText(chatID, draftID, msg); err != nil {
+				log.Printf("Failed to replace the draft reply, sending it anew: %v", err)
+				c.bot.Request(tgbotapi.NewDeleteMessage(chatID, draftID))
+			} else {
+				replaced = true
+			}
+		}
+		if !replaced {
+			if err := c.sendText(chatID, msg); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range msg.Files {
+		if err := c.sendFile(chatID, f); err != nil {
+			return fmt.Errorf("failed to send %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// sendText sends the content of msg with its buttons.
+func (c *TelegramChannel) sendText(chatID int64, msg bus.OutboundMessage) error {
 	// Convert markdown to Telegram HTML
 	htmlContent := MarkdownToTelegramHTML(msg.Content)
 
@@ -244,25 +489,144 @@ func (c *TelegramChannel) Send(msg bus.OutboundMessage) error {
 	telegramMsg := tgbotapi.NewMessage(chatID, htmlContent)
 	telegramMsg.ParseMode = tgbotapi.ModeHTML
 
-	// Set reply-to if specified
+	// Offer quick replies as inline buttons
+	if len(msg.Buttons) > 0 {
+		telegramMsg.ReplyMarkup = inlineKeyboard(msg.Buttons)
+	}
+
+	// Quote the message being answered, unless it has been deleted
 	if msg.ReplyTo != "" {
 		if replyID, err := strconv.Atoi(msg.ReplyTo); err == nil {
 			telegramMsg.ReplyToMessageID = replyID
+			telegramMsg.AllowSendingWithoutReply = true
 		}
 	}
 
-	_, err = c.bot.Send(telegramMsg)
-	if err != nil {
-		// Fallback to plain text if HTML fails
+	_, err := c.bot.Send(telegramMsg)
+	if isBadRequest(err) {
+		// Fall back to plain text if Telegram rejects the HTML; network
+		// errors are returned so the outbox holds the message
 		log.Printf("HTML message failed, falling back to plain text: %v", err)
 		telegramMsg.ParseMode = ""
 		telegramMsg.Text = StripMarkdown(msg.Content)
 		_, err = c.bot.Send(telegramMsg)
 	}
+	return err
+}
+
+// editText replaces the text of the bot's message messageID with the
+// content and buttons of msg.
+func (c *TelegramChannel) editText(chatID int64, messageID int, msg bus.OutboundMessage) error {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, MarkdownToTelegramHTML(msg.Content))
+	edit.ParseMode = tgbotapi.ModeHTML
+	if len(msg.Buttons) > 0 {
+		keyboard := inlineKeyboard(msg.Buttons)
+		edit.ReplyMarkup = &keyboard
+	}
+	_, err := c.bot.Request(edit)
+	if isBadRequest(err) && !isNotModified(err) {
+		log.Printf("HTML edit failed, falling back to plain text: %v", err)
+		edit.ParseMode = ""
+		edit.Text = StripMarkdown(msg.Content)
+		_, err = c.bot.Request(edit)
+	}
+	if isNotModified(err) {
+		return nil
+	}
+	return err
+}
+
+// sendDraft sends the first draft of a reply as plain text, since a
+// partial reply may end inside markdown, and returns its message ID.
+func (c *TelegramChannel) sendDraft(msg bus.OutboundMessage) (int, error) {
+	chatID, err := c.getChatID(msg.ChatID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid chat ID: %w", err)
+	}
+	draft := tgbotapi.NewMessage(chatID, msg.Content)
+	if replyID, err := strconv.Atoi(msg.ReplyTo); err == nil {
+		draft.ReplyToMessageID = replyID
+		draft.AllowSendingWithoutReply = true
+	}
+	sent, err := c.bot.Send(draft)
+	if err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
 
+// editDraft shows a later draft of a reply in the message of the first.
+func (c *TelegramChannel) editDraft(chatIDStr string, messageID int, text string) error {
+	chatID, err := c.getChatID(chatIDStr)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID: %w", err)
+	}
+	_, err = c.bot.Request(tgbotapi.NewEditMessageText(chatID, messageID, text))
+	if isNotModified(err) {
+		return nil
+	}
 	return err
 }
 
+// draftInterval is the least time between edits of a draft in chatID.
+// Group chats have negative IDs.
+func draftInterval(chatID string) time.Duration {
+	if strings.HasPrefix(chatID, "-") {
+		return telegramGroupDraftInterval
+	}
+	return telegramDraftInterval
+}
+
+// sendFile sends f as a photo when it is a picture Telegram can show, and
+// as a document otherwise.
+func (c *TelegramChannel) sendFile(chatID int64, f bus.File) error {
+	data := tgbotapi.FileBytes{Name: f.Name, Bytes: f.Data}
+	var upload tgbotapi.Chattable
+	if isPhoto(f.Name) && len(f.Data) <= telegramMaxPhoto {
+		photo := tgbotapi.NewPhoto(chatID, data)
+		photo.Caption = f.Caption
+		upload = photo
+	} else {
+		doc := tgbotapi.NewDocument(chatID, data)
+		doc.Caption = f.Caption
+		upload = doc
+	}
+	_, err := c.bot.Send(upload)
+	return err
+}
+
+// isPhoto reports whether a file is a picture Telegram shows as a photo.
+func isPhoto(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		return true
+	}
+	return false
+}
+
+// isBadRequest reports whether Telegram rejected a request as malformed,
+// e.g. because it could not parse the message's HTML.
+func isBadRequest(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest
+}
+
+// isNotModified reports whether Telegram refused an edit because the
+// message already reads that way.
+func isNotModified(err error) bool {
+	return isBadRequest(err) && strings.Contains(err.Error(), "message is not modified")
+}
+
+// sendTyping shows "typing…" in a chat for a few seconds. Failures are
+// ignored: the indicator is a courtesy and the reply follows anyway.
+func (c *TelegramChannel) sendTyping(chatIDStr string) {
+	chatID, err := c.getChatID(chatIDStr)
+	if err != nil || c.bot == nil {
+		return
+	}
+	c.bot.Request(tgbotapi.NewChatAction(chatID, tgbotapi.ChatTyping))
+}
+
 // getChatID retrieves the int64 chat ID from a string ID.
 func (c *TelegramChannel) getChatID(chatIDStr string) (int64, error) {
 	// First check our cache
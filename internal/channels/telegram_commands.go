@@ -0,0 +1,97 @@
+package channels
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+// telegramCommands are offered in Telegram's command menu. The gateway
+// answers them without calling the model; /start is sent by Telegram when
+// a user opens the chat and is not listed.
+var telegramCommands = []tgbotapi.BotCommand{
+	{Command: "help", Description: "What I can do"},
+	{Command: "model", Description: "Choose the model for this chat"},
+	{Command: "settings", Description: "Model, temperature and persona for this chat"},
+	{Command: "jobs", Description: "List and delete scheduled jobs"},
+	{Command: "reset", Description: "Start a new conversation"},
+	{Command: "pins", Description: "List pinned facts"},
+	{Command: "search", Description: "Search earlier messages"},
+	{Command: "undo", Description: "Remove your last message and the reply"},
+	{Command: "fork", Description: "Continue in a new branch"},
+	{Command: "branches", Description: "List and switch branches"},
+}
+
+// keyboardRowChars is how much button text shares a row of an inline
+// keyboard; a button that does not fit starts the next row.
+const keyboardRowChars = 30
+
+// parseCommand recognises a bot command at the start of text, as typed
+// ("/model gpt-4o", or "/model@MyBot gpt-4o" in a group) or sent by an
+// inline button. It returns the command, if any, and the content to pass
+// on, with the bot name removed. ok is false for a command addressed to
+// another bot, which is not for us to answer.
+func parseCommand(text, botName string) (cmd *bus.Command, content string, ok bool) {
+	if !strings.HasPrefix(text, "/") {
+		return nil, text, true
+	}
+	head, rest := text, ""
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		head, rest = text[:i], text[i:]
+	}
+
+	name, bot, _ := strings.Cut(head[1:], "@")
+	if bot != "" && !strings.EqualFold(bot, botName) {
+		return nil, text, false
+	}
+	if !isCommandName(name) {
+		// A path such as /etc/hosts, or a lone slash
+		return nil, text, true
+	}
+	name = strings.ToLower(name)
+	cmd = &bus.Command{Name: name}
+	if args := strings.Fields(rest); len(args) > 0 {
+		cmd.Args = args
+	}
+	return cmd, "/" + name + rest, true
+}
+
+// isCommandName reports whether name can be a command: letters, digits,
+// underscores and hyphens, as in /install-skill.
+func isCommandName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// inlineKeyboard lays out buttons under a message. Short buttons, such as
+// Install and Not now, share a row; longer ones such as model names get a
+// row each.
+func inlineKeyboard(buttons []bus.Button) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	width := 0
+	for _, b := range buttons {
+		n := utf8.RuneCountInString(b.Text)
+		if len(row) > 0 && width+n > keyboardRowChars {
+			rows = append(rows, row)
+			row, width = nil, 0
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(b.Text, b.Data))
+		width += n
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
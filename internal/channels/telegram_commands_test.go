@@ -0,0 +1,52 @@
+package channels
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text    string
+		cmd     *bus.Command
+		content string
+		ok      bool
+	}{
+		{"hello", nil, "hello", true},
+		{"/start", &bus.Command{Name: "start"}, "/start", true},
+		{"/Model gpt-4o", &bus.Command{Name: "model", Args: []string{"gpt-4o"}}, "/model gpt-4o", true},
+		{"/jobs@UbotBot delete 3", &bus.Command{Name: "jobs", Args: []string{"delete", "3"}}, "/jobs delete 3", true},
+		{"/pin buy milk\nand eggs", &bus.Command{Name: "pin", Args: []string{"buy", "milk", "and", "eggs"}}, "/pin buy milk\nand eggs", true},
+		{"/install-skill weather", &bus.Command{Name: "install-skill", Args: []string{"weather"}}, "/install-skill weather", true},
+		{"/help@OtherBot", nil, "/help@OtherBot", false},
+		{"/etc/hosts is missing", nil, "/etc/hosts is missing", true},
+		{"/", nil, "/", true},
+	}
+	for _, tt := range tests {
+		cmd, content, ok := parseCommand(tt.text, "ubotbot")
+		if !reflect.DeepEqual(cmd, tt.cmd) || content != tt.content || ok != tt.ok {
+			t.Errorf("parseCommand(%q) = %+v, %q, %v; want %+v, %q, %v", tt.text, cmd, content, ok, tt.cmd, tt.content, tt.ok)
+		}
+	}
+}
+
+func TestInlineKeyboard(t *testing.T) {
+	short := inlineKeyboard([]bus.Button{{Text: "Install", Data: "/a"}, {Text: "Not now", Data: "/b"}})
+	if len(short.InlineKeyboard) != 1 || len(short.InlineKeyboard[0]) != 2 {
+		t.Errorf("short buttons = %+v, want one row of two", short.InlineKeyboard)
+	}
+
+	long := inlineKeyboard([]bus.Button{
+		{Text: "anthropic/claude-sonnet-4.5", Data: "/model 1"},
+		{Text: "openai/gpt-4o-mini", Data: "/model 2"},
+		{Text: "Default", Data: "/model default"},
+	})
+	if len(long.InlineKeyboard) != 2 || len(long.InlineKeyboard[1]) != 2 {
+		t.Errorf("long buttons = %+v, want a row for the first and one for the rest", long.InlineKeyboard)
+	}
+	if got := long.InlineKeyboard[0][0].CallbackData; got == nil || *got != "/model 1" {
+		t.Errorf("callback data = %v, want /model 1", got)
+	}
+}
@@ -11,7 +11,7 @@ import (
 // - **bold** -> <b>bold</b>
 // - _italic_ -> <i>italic</i>
 // - `code` -> <code>code</code>
-// - ```code blocks``` -> <pre><code>...</code></pre>
+// - ```lang code blocks``` -> <pre><code class="language-lang">...</code></pre>
 // - [text](url) -> <a href="url">text</a>
 // - # headers -> plain text (heading markers removed)
 // - > blockquotes -> plain text (quote markers removed)
@@ -30,16 +30,21 @@ func MarkdownToTelegramHTML(text string) string {
 	placeholder := "\x00CODE_BLOCK_%d\x00"
 
 	// Handle fenced code blocks (```) first
-	codeBlockRegex := regexp.MustCompile("(?s)```(?:[a-zA-Z0-9]*\n?)?(.*?)```")
+	codeBlockRegex := regexp.MustCompile("(?s)```(?:([a-zA-Z0-9+#_-]*)\n)?(.*?)```")
 	text = codeBlockRegex.ReplaceAllStringFunc(text, func(match string) string {
-		// Extract content between ```
+		// Extract the language and content between ```
 		content := codeBlockRegex.FindStringSubmatch(match)
-		if len(content) > 1 {
+		if len(content) > 2 {
 			// Escape HTML in the code content
-			escapedContent := escapeHTML(strings.TrimSpace(content[1]))
+			escapedContent := escapeHTML(strings.TrimSpace(content[2]))
+			// Telegram highlights the code when given its language
+			open := "<pre><code>"
+			if content[1] != "" {
+				open = `<pre><code class="language-` + content[1] + `">`
+			}
 			idx := len(codeBlocks)
 			codeBlocks = append(codeBlocks, codeBlock{
-				content: "<pre><code>" + escapedContent + "</code></pre>",
+				content: open + escapedContent + "</code></pre>",
 				isBlock: true,
 			})
 			return strings.Replace(placeholder, "%d", string(rune('0'+idx)), 1)
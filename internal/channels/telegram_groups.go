@@ -0,0 +1,64 @@
+package channels
+
+import (
+	"regexp"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// isGroupChat reports whether chat is a group or supergroup.
+func isGroupChat(chat *tgbotapi.Chat) bool {
+	return chat != nil && (chat.IsGroup() || chat.IsSuperGroup())
+}
+
+// groupActivated reports whether a group message is meant for the bot: with
+// the "mention" activation it must mention the bot, reply to one of its
+// messages or be a command; with "always" every message is.
+func groupActivated(msg *tgbotapi.Message, self tgbotapi.User, activation string) bool {
+	if activation == config.GroupActivationAlways {
+		return true
+	}
+	if reply := msg.ReplyToMessage; reply != nil && reply.From != nil && reply.From.ID == self.ID {
+		return true
+	}
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	if _, mentioned := stripMention(text, self.UserName); mentioned {
+		return true
+	}
+	cmd, _, ok := parseCommand(msg.Text, self.UserName)
+	return ok && cmd != nil
+}
+
+// stripMention removes @botName from text, in any case, and reports
+// whether it was there. "@ubot, what's the time?" becomes "what's the
+// time?".
+func stripMention(text, botName string) (string, bool) {
+	if botName == "" {
+		return text, false
+	}
+	mention := regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(botName) + `\b[,:]?`)
+	if !mention.MatchString(text) {
+		return text, false
+	}
+	text = strings.ReplaceAll(mention.ReplaceAllString(text, ""), "  ", " ")
+	return strings.TrimSpace(text), true
+}
+
+// groupSpeaker names the sender of a group message, so that the model can
+// tell the members of a shared conversation apart.
+func groupSpeaker(from *tgbotapi.User) string {
+	name := strings.TrimSpace(from.FirstName + " " + from.LastName)
+	switch {
+	case name == "":
+		name = "@" + from.UserName
+	case from.UserName != "":
+		name += " (@" + from.UserName + ")"
+	}
+	return name
+}
@@ -0,0 +1,53 @@
+package channels
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+func TestGroupActivated(t *testing.T) {
+	self := tgbotapi.User{ID: 1, UserName: "UbotBot", IsBot: true}
+	tests := []struct {
+		name string
+		msg  tgbotapi.Message
+		want bool
+	}{
+		{"chatter", tgbotapi.Message{Text: "lunch at noon?"}, false},
+		{"mention", tgbotapi.Message{Text: "@ubotbot what's the weather?"}, true},
+		{"mention in caption", tgbotapi.Message{Caption: "summarise this @UbotBot"}, true},
+		{"other bot", tgbotapi.Message{Text: "@UbotBotFan hi"}, false},
+		{"reply", tgbotapi.Message{Text: "and tomorrow?", ReplyToMessage: &tgbotapi.Message{From: &self}}, true},
+		{"reply to a member", tgbotapi.Message{Text: "yes", ReplyToMessage: &tgbotapi.Message{From: &tgbotapi.User{ID: 2}}}, false},
+		{"command", tgbotapi.Message{Text: "/help"}, true},
+		{"command for another bot", tgbotapi.Message{Text: "/help@OtherBot"}, false},
+	}
+	for _, tt := range tests {
+		if got := groupActivated(&tt.msg, self, config.GroupActivationMention); got != tt.want {
+			t.Errorf("%s: groupActivated = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if !groupActivated(&tgbotapi.Message{Text: "lunch at noon?"}, self, config.GroupActivationAlways) {
+		t.Error("always: message ignored")
+	}
+}
+
+func TestStripMention(t *testing.T) {
+	tests := []struct {
+		text, want string
+		found      bool
+	}{
+		{"@UbotBot, what's the time?", "what's the time?", true},
+		{"what do you think @ubotbot", "what do you think", true},
+		{"ask @UbotBot about it\nplease", "ask about it\nplease", true},
+		{"@UbotBotFan hi", "@UbotBotFan hi", false},
+	}
+	for _, tt := range tests {
+		got, found := stripMention(tt.text, "UbotBot")
+		if got != tt.want || found != tt.found {
+			t.Errorf("stripMention(%q) = %q, %v; want %q, %v", tt.text, got, found, tt.want, tt.found)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package channels
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// typingInterval is how often the typing action is repeated; Telegram
+	// shows it for five seconds or until the bot sends a message.
+	typingInterval = 4 * time.Second
+	// typingLimit stops the indicator for a chat that never gets a reply,
+	// e.g. a message held while the provider is unreachable.
+	typingLimit = 2 * time.Minute
+)
+
+// Typing shows that the bot is working on a reply, by repeating a chat
+// action such as "typing…" in each chat from the moment its message arrives
+// until the reply is sent.
+type Typing struct {
+	send     func(chatID string) // shows the action once
+	interval time.Duration
+	limit    time.Duration
+
+	mu    sync.Mutex
+	chats map[string]*typingChat
+}
+
+// typingChat is the indicator running in one chat.
+type typingChat struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewTyping creates a Typing that shows the action through send.
+func NewTyping(send func(chatID string)) *Typing {
+	return &Typing{
+		send:     send,
+		interval: typingInterval,
+		limit:    typingLimit,
+		chats:    make(map[string]*typingChat),
+	}
+}
+
+// Start shows the action in chatID until Stop is called or the limit
+// passes. Starting a chat that already shows it does nothing.
+func (t *Typing) Start(chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.chats[chatID]; ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.limit)
+	chat := &typingChat{cancel: cancel, done: make(chan struct{})}
+	t.chats[chatID] = chat
+	go func() {
+		defer close(chat.done)
+		defer cancel()
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			t.send(chatID)
+			select {
+			case <-ctx.Done():
+				t.remove(chatID, chat)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the action in chatID. It returns once the action is no longer
+// being sent, so a reply sent afterwards is not followed by another one.
+func (t *Typing) Stop(chatID string) {
+	t.mu.Lock()
+	chat, ok := t.chats[chatID]
+	delete(t.chats, chatID)
+	t.mu.Unlock()
+	if ok {
+		chat.cancel()
+		<-chat.done
+	}
+}
+
+// StopAll stops the action in every chat.
+func (t *Typing) StopAll() {
+	t.mu.Lock()
+	chats := t.chats
+	t.chats = make(map[string]*typingChat)
+	t.mu.Unlock()
+	for _, chat := range chats {
+		chat.cancel()
+		<-chat.done
+	}
+}
+
+// remove forgets chat after its limit passed, unless it was replaced.
+func (t *Typing) remove(chatID string, chat *typingChat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.chats[chatID] == chat {
+		delete(t.chats, chatID)
+	}
+}
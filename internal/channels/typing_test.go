@@ -0,0 +1,54 @@
+package channels
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTyping(t *testing.T) {
+	var mu sync.Mutex
+	sent := make(map[string]int)
+	typing := NewTyping(func(chatID string) {
+		mu.Lock()
+		sent[chatID]++
+		mu.Unlock()
+	})
+	typing.interval = 10 * time.Millisecond
+	count := func(chatID string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return sent[chatID]
+	}
+
+	// Repeated until the reply is sent, and never after
+	typing.Start("1")
+	typing.Start("1")
+	time.Sleep(55 * time.Millisecond)
+	typing.Stop("1")
+	n := count("1")
+	if n < 3 {
+		t.Errorf("sent the action %d times in 55ms, want it repeated", n)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if count("1") != n {
+		t.Error("the action was sent after Stop")
+	}
+
+	// Stops by itself when no reply comes
+	typing.limit = 30 * time.Millisecond
+	typing.Start("2")
+	time.Sleep(80 * time.Millisecond)
+	n = count("2")
+	time.Sleep(30 * time.Millisecond)
+	if n == 0 || count("2") != n {
+		t.Errorf("action sent %d then %d times, want it to stop at the limit", n, count("2"))
+	}
+
+	// A chat can show it again, and StopAll ends every chat
+	typing.limit = time.Minute
+	typing.Start("2")
+	typing.Start("3")
+	typing.StopAll()
+	typing.Stop("4") // never started
+}
@@ -0,0 +1,304 @@
+package codeindex
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxBlockLines caps how far a definition's end is searched for.
+const maxBlockLines = 400
+
+// extractor pulls symbols out of a source file.
+type extractor interface {
+	extract(rel string, src []byte) []Symbol
+}
+
+// languageFor returns the extractor for a file, or nil if the file is not
+// source code the index understands.
+func languageFor(path string) extractor {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return goLang{}
+	case ".py":
+		return pythonLang
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx":
+		return jsLang
+	case ".rs":
+		return rustLang
+	case ".java":
+		return javaLang
+	}
+	return nil
+}
+
+// signature trims a defining line for display.
+func signature(line string) string {
+	line = strings.TrimSpace(line)
+	if len(line) > 160 {
+		line = line[:160] + "..."
+	}
+	return line
+}
+
+// goLang extracts top-level Go declarations using go/parser.
+type goLang struct{}
+
+func (goLang) extract(rel string, src []byte) []Symbol {
+	fset := token.NewFileSet()
+	// A partially parsed file still yields the declarations before the error.
+	f, _ := parser.ParseFile(fset, rel, src, parser.SkipObjectResolution)
+	if f == nil {
+		return nil
+	}
+	lines := strings.Split(string(src), "\n")
+
+	var syms []Symbol
+	add := func(name, kind, container string, pos, end token.Pos) {
+		if name == "_" {
+			return
+		}
+		start, stop := fset.Position(pos).Line, fset.Position(end).Line
+		sig := ""
+		if start-1 < len(lines) {
+			sig = signature(lines[start-1])
+		}
+		syms = append(syms, Symbol{
+			Name: name, Kind: kind, Container: container,
+			File: rel, Line: start, EndLine: stop, Signature: sig,
+		})
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				add(d.Name.Name, "method", receiverName(d.Recv.List[0].Type), d.Pos(), d.End())
+			} else {
+				add(d.Name.Name, "func", "", d.Pos(), d.End())
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				pos, end := spec.Pos(), spec.End()
+				if !d.Lparen.IsValid() {
+					pos, end = d.Pos(), d.End()
+				}
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					kind := "type"
+					if _, ok := s.Type.(*ast.InterfaceType); ok {
+						kind = "interface"
+					}
+					add(s.Name.Name, kind, "", pos, end)
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, n := range s.Names {
+						add(n.Name, kind, "", pos, end)
+					}
+				}
+			}
+		}
+	}
+	return syms
+}
+
+// receiverName returns the type name of a method receiver expression.
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// pattern matches a definition line. The symbol name is capture group 1.
+type pattern struct {
+	re         *regexp.Regexp
+	kind       string
+	scope      bool // definitions inside it get it as their container
+	scopeOnly  bool // only opens a scope (e.g. Rust impl blocks)
+	memberOnly bool // only valid inside a scope (e.g. class methods)
+}
+
+// lineLang extracts symbols line by line with regular expressions, using
+// blockEnd to find where each definition ends.
+type lineLang struct {
+	patterns []pattern
+	blockEnd func(lines []string, i int) int
+	keywords map[string]bool // names that are control flow, not definitions
+}
+
+func (l *lineLang) extract(rel string, src []byte) []Symbol {
+	lines := strings.Split(string(src), "\n")
+	var syms []Symbol
+	var scopes []Symbol
+
+	for i, line := range lines {
+		for len(scopes) > 0 && i+1 > scopes[len(scopes)-1].EndLine {
+			scopes = scopes[:len(scopes)-1]
+		}
+
+		for _, p := range l.patterns {
+			m := p.re.FindStringSubmatch(line)
+			if m == nil || l.keywords[m[1]] {
+				continue
+			}
+			if p.memberOnly && len(scopes) == 0 {
+				continue
+			}
+
+			sym := Symbol{
+				Name: m[1], Kind: p.kind, File: rel,
+				Line: i + 1, EndLine: l.blockEnd(lines, i), Signature: signature(line),
+			}
+			if len(scopes) > 0 {
+				sym.Container = scopes[len(scopes)-1].Name
+				if sym.Kind == "func" {
+					sym.Kind = "method"
+				}
+			}
+			if p.scope || p.scopeOnly {
+				scopes = append(scopes, sym)
+			}
+			if !p.scopeOnly {
+				syms = append(syms, sym)
+			}
+			break
+		}
+	}
+	return syms
+}
+
+// braceEnd finds the line closing the block opened at or after line i. A
+// definition without a block (e.g. ending in ';') ends on its own line.
+func braceEnd(lines []string, i int) int {
+	depth, opened := 0, false
+	for j := i; j < len(lines) && j < i+maxBlockLines; j++ {
+		for _, r := range stripStrings(lines[j]) {
+			switch r {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+		if opened && depth <= 0 {
+			return j + 1
+		}
+		if !opened && (strings.Contains(lines[j], ";") || j >= i+2) {
+			return j + 1
+		}
+	}
+	return min(len(lines), i+maxBlockLines)
+}
+
+// stripStrings blanks out string literals and line comments so braces inside
+// them are not counted.
+func stripStrings(line string) string {
+	var sb strings.Builder
+	var quote rune
+	escaped := false
+	for idx, r := range line {
+		switch {
+		case quote != 0:
+			if escaped {
+				escaped = false
+			} else if r == '\\' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '/' && strings.HasPrefix(line[idx:], "//"):
+			return sb.String()
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// indentEnd finds the last line of an indentation-delimited block (Python).
+func indentEnd(lines []string, i int) int {
+	indent := indentation(lines[i])
+	last := i
+	for j := i + 1; j < len(lines) && j < i+maxBlockLines; j++ {
+		if strings.TrimSpace(lines[j]) == "" {
+			continue
+		}
+		if indentation(lines[j]) <= indent {
+			break
+		}
+		last = j
+	}
+	return last + 1
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+var controlKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "new": true, "else": true, "function": true, "do": true,
+	"try": true, "with": true, "super": true, "this": true,
+}
+
+var pythonLang = &lineLang{
+	blockEnd: indentEnd,
+	patterns: []pattern{
+		{re: regexp.MustCompile(`^\s*class\s+([A-Za-z_]\w*)`), kind: "class", scope: true},
+		{re: regexp.MustCompile(`^\s*(?:async\s+)?def\s+([A-Za-z_]\w*)`), kind: "func"},
+	},
+}
+
+var jsLang = &lineLang{
+	blockEnd: braceEnd,
+	keywords: controlKeywords,
+	patterns: []pattern{
+		{re: regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`), kind: "class", scope: true},
+		{re: regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`), kind: "func"},
+		{re: regexp.MustCompile(`^\s*(?:export\s+)?interface\s+([A-Za-z_$][\w$]*)`), kind: "interface"},
+		{re: regexp.MustCompile(`^\s*(?:export\s+)?type\s+([A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\s*=`), kind: "type"},
+		{re: regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|[A-Za-z_$][\w$]*\s*=>)`), kind: "func"},
+		{re: regexp.MustCompile(`^\s+(?:(?:public|private|protected|static|readonly|async|override|get|set)\s+)*([A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\([^)]*\)\s*(?::\s*[^{]+)?\{\s*$`), kind: "func", memberOnly: true},
+	},
+}
+
+var rustLang = &lineLang{
+	blockEnd: braceEnd,
+	patterns: []pattern{
+		{re: regexp.MustCompile(`^\s*impl(?:<[^>]*>)?\s+(?:[\w:<>, ]+\s+for\s+)?([A-Za-z_]\w*)`), kind: "impl", scopeOnly: true},
+		{re: regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?trait\s+([A-Za-z_]\w*)`), kind: "interface", scope: true},
+		{re: regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:async\s+)?(?:unsafe\s+)?(?:const\s+)?(?:extern\s+"[^"]*"\s+)?fn\s+([A-Za-z_]\w*)`), kind: "func"},
+		{re: regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?struct\s+([A-Za-z_]\w*)`), kind: "type"},
+		{re: regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?enum\s+([A-Za-z_]\w*)`), kind: "type"},
+		{re: regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?type\s+([A-Za-z_]\w*)`), kind: "type"},
+		{re: regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const|static)\s+([A-Z_][A-Z0-9_]*)\s*:`), kind: "const"},
+	},
+}
+
+var javaLang = &lineLang{
+	blockEnd: braceEnd,
+	keywords: controlKeywords,
+	patterns: []pattern{
+		{re: regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|final|abstract|sealed)\s+)*(?:class|record)\s+([A-Za-z_]\w*)`), kind: "class", scope: true},
+		{re: regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|sealed)\s+)*interface\s+([A-Za-z_]\w*)`), kind: "interface", scope: true},
+		{re: regexp.MustCompile(`^\s*(?:(?:public|private|protected|static)\s+)*enum\s+([A-Za-z_]\w*)`), kind: "type", scope: true},
+		{re: regexp.MustCompile(`^\s+(?:(?:public|private|protected|static|final|abstract|synchronized|native|default)\s+)*(?:<[^>]+>\s+)?[\w<>\[\],.? ]+\s+([A-Za-z_]\w*)\s*\([^;]*$`), kind: "func", memberOnly: true},
+	},
+}
@@ -0,0 +1,284 @@
+// Package codeindex maintains a symbol index over a project directory so the
+// agent can find and read definitions without grepping the whole tree. Go
+// files are parsed with go/parser; other languages use lightweight
+// line-based patterns.
+package codeindex
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxFileSize skips generated or minified files that bloat the index.
+	maxFileSize = 1 << 20
+	// refreshInterval is how long a scan stays fresh before changed files
+	// are re-indexed.
+	refreshInterval = 30 * time.Second
+)
+
+// skipDirs are directory names never descended into.
+var skipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true,
+	"build": true, "target": true, "__pycache__": true, ".venv": true,
+	"venv": true, ".idea": true, ".vscode": true,
+}
+
+// SkipDir reports whether a directory named name holds dependencies, build
+// output, editor settings or other hidden files rather than project source.
+func SkipDir(name string) bool {
+	return skipDirs[name] || strings.HasPrefix(name, ".")
+}
+
+// Symbol is a named definition in the project.
+type Symbol struct {
+	Name      string // identifier, e.g. "NewClient"
+	Kind      string // "func", "method", "type", "class", "interface", "const", "var", ...
+	Container string // enclosing type or class, if any
+	File      string // path relative to the project root
+	Line      int    // 1-based first line of the definition
+	EndLine   int    // 1-based last line of the definition
+	Signature string // the defining line, trimmed
+}
+
+// QualifiedName returns Container.Name, or Name when there is no container.
+func (s Symbol) QualifiedName() string {
+	if s.Container == "" {
+		return s.Name
+	}
+	return s.Container + "." + s.Name
+}
+
+// fileEntry holds the symbols of one file and the mtime they were read at.
+type fileEntry struct {
+	modTime time.Time
+	symbols []Symbol
+}
+
+// Index is a lazily refreshed symbol index over a project directory.
+type Index struct {
+	root string
+
+	mu      sync.Mutex
+	files   map[string]fileEntry
+	scanned time.Time
+}
+
+// New creates an index over root. Nothing is read until the first query.
+func New(root string) *Index {
+	return &Index{root: root, files: make(map[string]fileEntry)}
+}
+
+// Root returns the indexed project directory.
+func (ix *Index) Root() string {
+	return ix.root
+}
+
+// Refresh re-indexes files that changed since the last scan and drops
+// deleted ones.
+func (ix *Index) Refresh() error {
+	ix.mu.Lock()
+	old := ix.files
+	ix.mu.Unlock()
+
+	files := make(map[string]fileEntry, len(old))
+	err := filepath.WalkDir(ix.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip unreadable entries
+		}
+		if d.IsDir() {
+			if path != ix.root && SkipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		lang := languageFor(path)
+		if lang == nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(ix.root, path)
+		if prev, ok := old[rel]; ok && prev.modTime.Equal(info.ModTime()) {
+			files[rel] = prev
+			return nil
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		files[rel] = fileEntry{modTime: info.ModTime(), symbols: lang.extract(rel, src)}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ix.mu.Lock()
+	ix.files = files
+	ix.scanned = time.Now()
+	ix.mu.Unlock()
+	return nil
+}
+
+// ensureFresh refreshes the index if the last scan is stale.
+func (ix *Index) ensureFresh() error {
+	ix.mu.Lock()
+	stale := time.Since(ix.scanned) > refreshInterval
+	ix.mu.Unlock()
+	if !stale {
+		return nil
+	}
+	return ix.Refresh()
+}
+
+// Search returns up to limit symbols matching query, best matches first:
+// exact name, then prefix, then substring (case-insensitive). A query of the
+// form "Type.Method" also matches the container. kind filters by symbol
+// kind when non-empty.
+func (ix *Index) Search(query, kind string, limit int) ([]Symbol, error) {
+	if err := ix.ensureFresh(); err != nil {
+		return nil, err
+	}
+
+	container, name := splitQualified(strings.ToLower(strings.TrimSpace(query)))
+	type match struct {
+		sym  Symbol
+		rank int
+	}
+	var matches []match
+
+	ix.mu.Lock()
+	for _, entry := range ix.files {
+		for _, sym := range entry.symbols {
+			if kind != "" && sym.Kind != kind {
+				continue
+			}
+			if container != "" && strings.ToLower(sym.Container) != container {
+				continue
+			}
+			lower := strings.ToLower(sym.Name)
+			rank := -1
+			switch {
+			case lower == name:
+				rank = 0
+			case strings.HasPrefix(lower, name):
+				rank = 1
+			case strings.Contains(lower, name):
+				rank = 2
+			}
+			if rank >= 0 {
+				matches = append(matches, match{sym, rank})
+			}
+		}
+	}
+	ix.mu.Unlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if len(a.sym.Name) != len(b.sym.Name) {
+			return len(a.sym.Name) < len(b.sym.Name)
+		}
+		if a.sym.File != b.sym.File {
+			return a.sym.File < b.sym.File
+		}
+		return a.sym.Line < b.sym.Line
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]Symbol, len(matches))
+	for i, m := range matches {
+		result[i] = m.sym
+	}
+	return result, nil
+}
+
+// Definitions returns symbols whose (qualified) name equals name exactly,
+// optionally restricted to files under fileHint.
+func (ix *Index) Definitions(name, fileHint string) ([]Symbol, error) {
+	if err := ix.ensureFresh(); err != nil {
+		return nil, err
+	}
+
+	container, base := splitQualified(strings.TrimSpace(name))
+	var defs []Symbol
+
+	ix.mu.Lock()
+	for file, entry := range ix.files {
+		if fileHint != "" && !strings.HasPrefix(file, filepath.Clean(fileHint)) {
+			continue
+		}
+		for _, sym := range entry.symbols {
+			if sym.Name == base && (container == "" || sym.Container == container) {
+				defs = append(defs, sym)
+			}
+		}
+	}
+	ix.mu.Unlock()
+
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].File != defs[j].File {
+			return defs[i].File < defs[j].File
+		}
+		return defs[i].Line < defs[j].Line
+	})
+	return defs, nil
+}
+
+// Source returns the source lines of sym, capped at maxLines.
+func (ix *Index) Source(sym Symbol, maxLines int) (string, bool, error) {
+	data, err := os.ReadFile(filepath.Join(ix.root, sym.File))
+	if err != nil {
+		return "", false, err
+	}
+	lines := strings.Split(string(data), "\n")
+
+	start, end := sym.Line-1, sym.EndLine
+	if end < sym.Line {
+		end = sym.Line
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	truncated := false
+	if maxLines > 0 && end-start > maxLines {
+		end = start + maxLines
+		truncated = true
+	}
+	if start < 0 || start >= end {
+		return "", false, nil
+	}
+	return strings.Join(lines[start:end], "\n"), truncated, nil
+}
+
+// Stats returns the number of indexed files and symbols.
+func (ix *Index) Stats() (files, symbols int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for _, entry := range ix.files {
+		symbols += len(entry.symbols)
+	}
+	return len(ix.files), symbols
+}
+
+// splitQualified splits "Type.Method" into its container and name.
+func splitQualified(s string) (container, name string) {
+	if i := strings.LastIndex(s, "."); i > 0 && i < len(s)-1 {
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
@@ -0,0 +1,160 @@
+package codeindex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testIndex(t *testing.T) *Index {
+	root := t.TempDir()
+	writeFile(t, root, "server/client.go", `package server
+
+// Client talks to the server.
+type Client struct {
+	addr string
+}
+
+// NewClient creates a client.
+func NewClient(addr string) *Client {
+	return &Client{addr: addr}
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+const DefaultPort = 8080
+`)
+	writeFile(t, root, "app/models.py", `class User:
+    def __init__(self, name):
+        self.name = name
+
+    def greet(self):
+        return "hi " + self.name
+
+
+def load_users(path):
+    return []
+`)
+	writeFile(t, root, "web/src/api.ts", `export interface Options {
+  timeout: number;
+}
+
+export class ApiClient {
+  constructor(private base: string) {}
+
+  async fetchUser(id: string): Promise<User> {
+    if (id === "") {
+      throw new Error("empty id");
+    }
+    return get(this.base + "/users/" + id);
+  }
+}
+
+export const formatName = (u: User) => u.name;
+`)
+	writeFile(t, root, "node_modules/dep/index.js", "function NewClient() {}\n")
+	return New(root)
+}
+
+func TestSearch(t *testing.T) {
+	ix := testIndex(t)
+
+	syms, err := ix.Search("client", "", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(syms) == 0 || syms[0].Name != "Client" || syms[0].Kind != "type" {
+		t.Fatalf("Search(client) = %+v, want Client type first", syms)
+	}
+	for _, s := range syms {
+		if strings.HasPrefix(s.File, "node_modules") {
+			t.Errorf("node_modules should not be indexed: %+v", s)
+		}
+	}
+
+	syms, _ = ix.Search("Client.Close", "", 10)
+	if len(syms) != 1 || syms[0].Kind != "method" || syms[0].Container != "Client" {
+		t.Errorf("Search(Client.Close) = %+v, want one method on Client", syms)
+	}
+
+	syms, _ = ix.Search("greet", "", 10)
+	if len(syms) != 1 || syms[0].Container != "User" || syms[0].Kind != "method" {
+		t.Errorf("Search(greet) = %+v, want User.greet method", syms)
+	}
+
+	syms, _ = ix.Search("fetch", "method", 10)
+	if len(syms) != 1 || syms[0].QualifiedName() != "ApiClient.fetchUser" {
+		t.Errorf("Search(fetch, method) = %+v, want ApiClient.fetchUser", syms)
+	}
+
+	syms, _ = ix.Search("formatName", "", 10)
+	if len(syms) != 1 || syms[0].Kind != "func" {
+		t.Errorf("Search(formatName) = %+v, want arrow function", syms)
+	}
+}
+
+func TestDefinitionSource(t *testing.T) {
+	ix := testIndex(t)
+
+	defs, err := ix.Definitions("ApiClient.fetchUser", "")
+	if err != nil || len(defs) != 1 {
+		t.Fatalf("Definitions = %+v, %v; want one", defs, err)
+	}
+	src, truncated, err := ix.Source(defs[0], 0)
+	if err != nil || truncated {
+		t.Fatalf("Source: %v (truncated=%v)", err, truncated)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(src), "async fetchUser") || !strings.HasSuffix(strings.TrimSpace(src), "}") {
+		t.Errorf("source = %q, want the whole method", src)
+	}
+	if strings.Count(src, "\n") != 5 {
+		t.Errorf("source spans %d lines, want 6", strings.Count(src, "\n")+1)
+	}
+
+	defs, _ = ix.Definitions("load_users", "app")
+	if len(defs) != 1 {
+		t.Fatalf("Definitions(load_users) = %+v", defs)
+	}
+	src, _, _ = ix.Source(defs[0], 0)
+	if !strings.Contains(src, "return []") {
+		t.Errorf("python source = %q", src)
+	}
+
+	defs, _ = ix.Definitions("NewClient", "web")
+	if len(defs) != 0 {
+		t.Errorf("file hint should restrict results, got %+v", defs)
+	}
+}
+
+func TestRefreshPicksUpChanges(t *testing.T) {
+	ix := testIndex(t)
+	if err := ix.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, ix.Root(), "server/extra.go", "package server\n\nfunc Extra() {}\n")
+	os.Remove(filepath.Join(ix.Root(), "app/models.py"))
+	if err := ix.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if syms, _ := ix.Search("Extra", "", 1); len(syms) != 1 {
+		t.Error("new file should be indexed after Refresh")
+	}
+	if syms, _ := ix.Search("load_users", "", 1); len(syms) != 0 {
+		t.Error("deleted file should be dropped after Refresh")
+	}
+}
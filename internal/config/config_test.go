@@ -1,7 +1,12 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -188,3 +193,137 @@ func TestExpandPath(t *testing.T) {
 		t.Errorf("expandPath('/tmp/test') = %q, want /tmp/test", result)
 	}
 }
+
+func TestPromptsPersona(t *testing.T) {
+	p := PromptsConfig{
+		Default:  "plain",
+		Channels: map[string]string{"telegram": "friendly"},
+		Chats:    map[string]string{"telegram:42": "coder"},
+	}
+	tests := map[string]string{
+		"telegram:42": "coder",
+		"telegram:7":  "friendly",
+		"cli:default": "plain",
+	}
+	for key, want := range tests {
+		if got := p.Persona(key); got != want {
+			t.Errorf("Persona(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if got := (PromptsConfig{}).Persona("telegram:42"); got != "" {
+		t.Errorf("Persona with no config = %q, want empty", got)
+	}
+}
+
+func TestIsAdminUser(t *testing.T) {
+	c := ChannelsConfig{
+		Telegram: TelegramConfig{AdminUsers: []string{"42"}},
+		WhatsApp: WhatsAppConfig{AdminUsers: []string{"15551234567"}},
+	}
+	tests := []struct {
+		channel, sender string
+		want            bool
+	}{
+		{"telegram", "42|owner", true},
+		{"telegram", "42", true},
+		{"telegram", "7|42", false},
+		{"telegram", "|42", false},
+		{"whatsapp", "15551234567", true},
+		{"whatsapp", "42", false},
+		{"cli", "42", false},
+	}
+	for _, tt := range tests {
+		if got := c.IsAdminUser(tt.channel, tt.sender); got != tt.want {
+			t.Errorf("IsAdminUser(%q, %q) = %v, want %v", tt.channel, tt.sender, got, tt.want)
+		}
+	}
+}
+
+func TestChatModel(t *testing.T) {
+	d := AgentDefaults{Model: "big", Models: []string{"small", "big", "", "small"}}
+	if got := d.ModelChoices(); !reflect.DeepEqual(got, []string{"big", "small"}) {
+		t.Errorf("ModelChoices() = %v, want [big small]", got)
+	}
+	for chosen, want := range map[string]string{"": "big", "small": "small", "removed": "big"} {
+		if got := d.ChatModel(chosen); got != want {
+			t.Errorf("ChatModel(%q) = %q, want %q", chosen, got, want)
+		}
+	}
+	listed := d.WithModels([]string{"small", "listed"})
+	if got := listed.ChatModel("listed"); got != "listed" {
+		t.Errorf("ChatModel(listed) = %q, want a model the provider lists", got)
+	}
+	if !reflect.DeepEqual(d.Models, []string{"small", "big", "", "small"}) {
+		t.Errorf("WithModels changed the original: %v", d.Models)
+	}
+}
+
+func TestSaveCopilotToken(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"providers": {"copilot": {"enabled": true, "accessToken": "gho_old"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCopilotToken(path, "gho_new"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Providers.Copilot.AccessToken != "gho_new" || !cfg.Providers.Copilot.Enabled {
+		t.Fatalf("copilot = %+v", cfg.Providers.Copilot)
+	}
+
+	// A token kept in a secret file is replaced there
+	secret := filepath.Join(dir, "copilot-token")
+	os.WriteFile(secret, []byte("gho_old\n"), 0600)
+	os.WriteFile(path, []byte(`{"providers": {"copilot": {"enabled": true, "accessToken": "@file:`+secret+`"}}}`), 0600)
+	if err := SaveCopilotToken(path, "gho_newer"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "@file:") {
+		t.Errorf("config file lost the secret reference: %s", data)
+	}
+	if cfg, err = LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Providers.Copilot.AccessToken != "gho_newer" {
+		t.Errorf("token = %q, want gho_newer", cfg.Providers.Copilot.AccessToken)
+	}
+}
+
+func TestSkillsSetupNetwork(t *testing.T) {
+	registries := []string{"pypi.org"}
+	tests := []struct {
+		hosts    []string
+		want     []string
+		wantFull bool
+	}{
+		{nil, registries, false},
+		{[]string{"github.com", "*.githubusercontent.com"}, []string{"github.com", "*.githubusercontent.com"}, false},
+		{[]string{"github.com", "*"}, nil, true},
+	}
+	for _, tt := range tests {
+		hosts, full := SkillsConfig{SetupHosts: tt.hosts}.SetupNetwork(registries)
+		if !reflect.DeepEqual(hosts, tt.want) || full != tt.wantFull {
+			t.Errorf("SetupNetwork with %v = %v, %v; want %v, %v", tt.hosts, hosts, full, tt.want, tt.wantFull)
+		}
+	}
+}
+
+func TestParallelTimeouts(t *testing.T) {
+	var p ParallelConfig
+	if d := p.CallTimeout(); d != 0 {
+		t.Errorf("default CallTimeout = %v, want no limit", d)
+	}
+	p = ParallelConfig{Timeout: 60}
+	timeouts := p.ToolTimeouts()
+	if d, ok := timeouts["exec"]; !ok || d != 0 {
+		t.Errorf("exec timeout = %v, %v; want it left to tools.exec.timeout", d, ok)
+	}
+	p.Timeouts = map[string]int{"exec": 600}
+	if d := p.ToolTimeouts()["exec"]; d != 10*time.Minute {
+		t.Errorf("configured exec timeout = %v, want 10m", d)
+	}
+}
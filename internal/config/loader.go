@@ -2,9 +2,11 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const (
@@ -28,9 +30,19 @@ func GetConfigPath() string {
 	return filepath.Join(GetConfigDir(), DefaultConfigFile)
 }
 
+// GetPromptsDir returns the directory of user prompt templates
+// (~/.ubot/prompts).
+func GetPromptsDir() string {
+	return filepath.Join(GetConfigDir(), "prompts")
+}
+
 // LoadConfig loads configuration from the specified path.
 // If path is empty, it uses the default config path (~/.ubot/config.json).
-// If the config file doesn't exist, it returns the default configuration.
+// If the config file doesn't exist, it starts from the default
+// configuration. UBOT_* environment variables override the file, and
+// "@file:" values are replaced with the contents of the named file (see
+// EnvPrefix and SecretFilePrefix). A file with unknown keys or invalid
+// settings fails with a *ValidationError.
 func LoadConfig(path string) (*Config, error) {
 	if path == "" {
 		path = GetConfigPath()
@@ -41,8 +53,8 @@ func LoadConfig(path string) (*Config, error) {
 
 	// Check if config file exists
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		// Return default config if file doesn't exist
-		return DefaultConfig(), nil
+		// Start from the default config if file doesn't exist
+		return finish(DefaultConfig(), nil, nil)
 	}
 
 	// Read the config file
@@ -51,15 +63,50 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
 	}
 
-	// Start with defaults and unmarshal over them
-	cfg := DefaultConfig()
-	if err := json.Unmarshal(data, cfg); err != nil {
+	// Start with defaults, unmarshal over them and validate the result
+	cfg, err := Parse(data)
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		validationErr.Path = path
+		return nil, validationErr
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
 
 	return cfg, nil
 }
 
+// LoadRawConfig loads the config file at path as written, over the
+// defaults, without environment overrides or resolving secret references,
+// so that it can be changed and saved back without writing secrets into
+// it. Settings are not validated; use Parse on the changed file.
+func LoadRawConfig(path string) (*Config, error) {
+	if path == "" {
+		path = GetConfigPath()
+	}
+	path = expandPath(path)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultConfig(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	cfg, err := Decode(data)
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		validationErr.Path = path
+		return nil, validationErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
 // SaveConfig saves the configuration to the specified path.
 // If path is empty, it uses the default config path (~/.ubot/config.json).
 func SaveConfig(cfg *Config, path string) error {
@@ -90,6 +137,25 @@ func SaveConfig(cfg *Config, path string) error {
 	return nil
 }
 
+// SaveCopilotToken saves token, a GitHub OAuth token from a new sign-in,
+// as providers.copilot.accessToken in the config file at path. When the
+// setting refers to a secret file, the token is written to that file
+// instead. The rest of the file is kept as written.
+func SaveCopilotToken(path, token string) error {
+	cfg, err := LoadRawConfig(path)
+	if err != nil {
+		return err
+	}
+	if file, ok := strings.CutPrefix(cfg.Providers.Copilot.AccessToken, SecretFilePrefix); ok {
+		if err := os.WriteFile(expandPath(file), []byte(token+"\n"), 0600); err != nil {
+			return fmt.Errorf("failed to write secret file %s: %w", file, err)
+		}
+		return nil
+	}
+	cfg.Providers.Copilot.AccessToken = token
+	return SaveConfig(cfg, path)
+}
+
 // EnsureConfigDir ensures the config directory (~/.ubot) exists.
 // Creates the directory with appropriate permissions if it doesn't exist.
 func EnsureConfigDir() error {
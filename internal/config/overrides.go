@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix starts the names of environment variables that override config
+// settings. The rest of the name is the setting's path with each key in
+// upper case, either run together or with its words split by underscores:
+// UBOT_PROVIDERS_OPENROUTER_APIKEY and UBOT_PROVIDERS_OPENROUTER_API_KEY
+// both set providers.openrouter.apiKey. List items are addressed by index,
+// e.g. UBOT_MCP_SERVERS_0_URL, and lists of strings are given separated by
+// commas.
+const EnvPrefix = "UBOT_"
+
+// SecretFilePrefix marks a string setting whose value is read from a file,
+// e.g. "apiKey": "@file:/run/secrets/openrouter". Surrounding whitespace,
+// such as a trailing newline, is removed.
+const SecretFilePrefix = "@file:"
+
+// applyEnv sets the settings named by UBOT_* variables in environ, a list
+// of "NAME=value" entries. Variables that name no setting are ignored, as
+// UBOT_ is also used by scripts.
+func applyEnv(cfg *Config, environ []string) []Problem {
+	var problems []Problem
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		if err := setEnv(reflect.ValueOf(cfg).Elem(), strings.TrimPrefix(name, EnvPrefix), value); err != nil {
+			problems = append(problems, Problem{Field: name, Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// setEnv sets the setting of v named by the rest of a variable name. It
+// does nothing if the name matches no setting.
+func setEnv(v reflect.Value, name, value string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range reflect.VisibleFields(v.Type()) {
+			key := jsonName(f)
+			if key == "" {
+				continue
+			}
+			for _, prefix := range envNames(key) {
+				if name == prefix {
+					return setString(v.FieldByIndex(f.Index), value)
+				}
+				if rest, ok := strings.CutPrefix(name, prefix+"_"); ok {
+					return setEnv(v.FieldByIndex(f.Index), rest, value)
+				}
+			}
+		}
+	case reflect.Slice:
+		index, rest, _ := strings.Cut(name, "_")
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= v.Len() {
+			return nil
+		}
+		if rest == "" {
+			return setString(v.Index(i), value)
+		}
+		return setEnv(v.Index(i), rest, value)
+	}
+	return nil
+}
+
+// envNames returns the forms of a JSON key in a variable name, e.g. APIKEY
+// and API_KEY for apiKey.
+func envNames(key string) []string {
+	upper := strings.ToUpper(key)
+	var words strings.Builder
+	for i, r := range key {
+		if i > 0 && unicode.IsUpper(r) {
+			words.WriteByte('_')
+		}
+		words.WriteRune(unicode.ToUpper(r))
+	}
+	if words.String() == upper {
+		return []string{upper}
+	}
+	return []string{upper, words.String()}
+}
+
+// setString sets a setting from its text in an environment variable.
+func setString(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("expected true or false, found %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected a whole number, found %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, found %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("%s cannot be set from the environment", kindName(v.Type()))
+		}
+		items := []string{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("%s cannot be set from the environment", kindName(v.Type()))
+	}
+	return nil
+}
+
+// resolveSecrets replaces string settings that refer to a secret file with
+// the file's contents.
+func resolveSecrets(cfg *Config) []Problem {
+	var problems []Problem
+	resolve := func(path, s string) string {
+		file, ok := strings.CutPrefix(s, SecretFilePrefix)
+		if !ok {
+			return s
+		}
+		data, err := os.ReadFile(expandPath(file))
+		if err != nil {
+			problems = append(problems, Problem{Field: path, Message: fmt.Sprintf("cannot read secret: %v", err)})
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	walkStrings(reflect.ValueOf(cfg).Elem(), "", resolve)
+	return problems
+}
+
+// walkStrings replaces every string in v, including list items and map
+// values, with fn(path, s).
+func walkStrings(v reflect.Value, path string, fn func(path, s string) string) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(fn(path, v.String()))
+	case reflect.Pointer:
+		if !v.IsNil() {
+			walkStrings(v.Elem(), path, fn)
+		}
+	case reflect.Struct:
+		for _, f := range reflect.VisibleFields(v.Type()) {
+			if key := jsonName(f); key != "" {
+				walkStrings(v.FieldByIndex(f.Index), join(key), fn)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values are not addressable; update a copy and store it
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			walkStrings(elem, join(iter.Key().String()), fn)
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	cfg, err := Parse([]byte(`{"mcp": {"servers": [{"name": "fs", "command": "mcp-fs"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := applyEnv(cfg, []string{
+		"UBOT_PROVIDERS_OPENROUTER_API_KEY=sk-or-env",
+		"UBOT_AGENTS_DEFAULTS_MAXTOKENS=512",
+		"UBOT_AGENTS_DEFAULTS_TEMPERATURE=0.3",
+		"UBOT_CHANNELS_TELEGRAM_ENABLED=true",
+		"UBOT_CHANNELS_TELEGRAM_ALLOW_FROM=1, 2,",
+		"UBOT_MCP_SERVERS_0_COMMAND=mcp-fs-env",
+		"UBOT_MCP_SERVERS_1_COMMAND=ignored",
+		"UBOT_DIR=/opt/ubot",
+		"PATH=/usr/bin",
+	})
+	if len(problems) != 0 {
+		t.Fatalf("problems = %v", problems)
+	}
+
+	if got := cfg.Providers.OpenRouter.APIKey; got != "sk-or-env" {
+		t.Errorf("apiKey = %q", got)
+	}
+	if d := cfg.Agents.Defaults; d.MaxTokens != 512 || d.Temperature != 0.3 {
+		t.Errorf("defaults = %+v", d)
+	}
+	tg := cfg.Channels.Telegram
+	if !tg.Enabled || !reflect.DeepEqual(tg.AllowFrom, []string{"1", "2"}) {
+		t.Errorf("telegram = %+v", tg)
+	}
+	if got := cfg.MCP.Servers[0].Command; got != "mcp-fs-env" || len(cfg.MCP.Servers) != 1 {
+		t.Errorf("mcp servers = %+v", cfg.MCP.Servers)
+	}
+}
+
+func TestApplyEnvBadValue(t *testing.T) {
+	problems := applyEnv(DefaultConfig(), []string{"UBOT_GATEWAY_PORT=http", "UBOT_CHANNELS_TELEGRAM_ENABLED=maybe"})
+	if len(problems) != 2 {
+		t.Fatalf("problems = %v", problems)
+	}
+	if p := problems[0]; p.Field != "UBOT_GATEWAY_PORT" || !strings.Contains(p.Message, "whole number") {
+		t.Errorf("problem = %v", p)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "openrouter")
+	if err := os.WriteFile(secret, []byte("sk-or-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Providers.OpenRouter.APIKey = SecretFilePrefix + secret
+	cfg.MCP.Servers = []MCPServerConfig{{Name: "gh", Env: map[string]string{"TOKEN": SecretFilePrefix + secret}}}
+	cfg.Providers.OpenAI.APIKey = SecretFilePrefix + filepath.Join(dir, "missing")
+
+	problems := resolveSecrets(cfg)
+	if len(problems) != 1 || problems[0].Field != "providers.openai.apiKey" {
+		t.Errorf("problems = %v", problems)
+	}
+	if got := cfg.Providers.OpenRouter.APIKey; got != "sk-or-secret" {
+		t.Errorf("apiKey = %q", got)
+	}
+	if got := cfg.MCP.Servers[0].Env["TOKEN"]; got != "sk-or-secret" {
+		t.Errorf("server env = %q", got)
+	}
+}
+
+func TestLoadConfigEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "token")
+	if err := os.WriteFile(secret, []byte("123:ABC"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("UBOT_GATEWAY_PORT", "9090")
+	t.Setenv("UBOT_CHANNELS_TELEGRAM_ENABLED", "true")
+	t.Setenv("UBOT_CHANNELS_TELEGRAM_TOKEN", SecretFilePrefix+secret)
+
+	// Without a config file
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Gateway.Port != 9090 || cfg.Channels.Telegram.Token != "123:ABC" {
+		t.Errorf("gateway port = %d, telegram token = %q", cfg.Gateway.Port, cfg.Channels.Telegram.Token)
+	}
+
+	// The environment overrides the file, and its values are validated
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"gateway": {"port": 8081}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = LoadConfig(path); err != nil || cfg.Gateway.Port != 9090 {
+		t.Errorf("LoadConfig = %v, %v", cfg, err)
+	}
+	t.Setenv("UBOT_GATEWAY_PORT", "0")
+	var verr *ValidationError
+	if _, err = LoadConfig(path); !errors.As(err, &verr) || !strings.Contains(err.Error(), "gateway.port") {
+		t.Errorf("err = %v, want an invalid gateway.port", err)
+	}
+
+	// The file as written is left alone
+	raw, err := LoadRawConfig(path)
+	if err != nil || raw.Gateway.Port != 8081 || raw.Channels.Telegram.Token != "" {
+		t.Errorf("LoadRawConfig = %+v, %v", raw, err)
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// secretWords mark a config key as holding a secret when they appear in its
+// last part, e.g. apiKey, accessToken or an MCP server's GITHUB_TOKEN.
+var secretWords = []string{"key", "token", "secret", "password", "authorization"}
+
+// Redacted returns a copy of the config that is safe to show: API keys,
+// tokens and other secrets are masked and passwords are removed from URLs.
+// Use it whenever the config is printed, logged or given to the model.
+func (c *Config) Redacted() *Config {
+	data, err := json.Marshal(c)
+	if err != nil {
+		// A Config always marshals; never hand back the secrets
+		return DefaultConfig()
+	}
+	out := &Config{}
+	if err := json.Unmarshal(data, out); err != nil {
+		return DefaultConfig()
+	}
+	walkStrings(reflect.ValueOf(out).Elem(), "", func(path, s string) string {
+		if s != "" && IsSecretKey(path) {
+			return MaskSecret(s)
+		}
+		return RedactURL(s)
+	})
+	return out
+}
+
+// IsSecretKey reports whether a dot-separated config key, such as
+// "providers.openai.apiKey", holds a secret. Only the last part is
+// considered.
+func IsSecretKey(key string) bool {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	key = strings.ToLower(key)
+	for _, word := range secretWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecret hides a secret, keeping its last 4 characters so it can be
+// told apart from others. Short secrets are hidden entirely.
+func MaskSecret(s string) string {
+	if len(s) <= 8 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
+
+// RedactURL hides the password in a URL such as redis://:password@host:6379;
+// other strings are returned unchanged.
+func RedactURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Providers.OpenRouter.APIKey = "sk-or-v1-abcdef123456"
+	cfg.Providers.Copilot.AccessToken = "gho_abc"
+	cfg.Channels.Telegram.Token = "123456:ABCDEFGHIJ"
+	cfg.Channels.Telegram.AllowFrom = []string{"alice"}
+	cfg.Cluster.RedisURL = "redis://:hunter2@localhost:6379/0"
+	cfg.MCP.Servers = []MCPServerConfig{{
+		Name:    "github",
+		Command: "mcp-github",
+		Env:     map[string]string{"GITHUB_TOKEN": "ghp_0123456789", "LOG_LEVEL": "debug"},
+	}}
+
+	red := cfg.Redacted()
+	data, err := json.Marshal(red)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"abcdef", "gho_", "ABCDEFGHIJ", "hunter2", "ghp_"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("redacted config contains %q:\n%s", secret, data)
+		}
+	}
+	if got := red.Providers.OpenRouter.APIKey; got != "****3456" {
+		t.Errorf("apiKey = %q, want ****3456", got)
+	}
+	if got := red.Providers.Copilot.AccessToken; got != "****" {
+		t.Errorf("short accessToken = %q, want ****", got)
+	}
+	if got := red.Cluster.RedisURL; got != "redis://:xxxxx@localhost:6379/0" {
+		t.Errorf("redisUrl = %q", got)
+	}
+	if red.Channels.Telegram.AllowFrom[0] != "alice" || red.MCP.Servers[0].Env["LOG_LEVEL"] != "debug" {
+		t.Error("settings that are not secrets were changed")
+	}
+
+	// The original is untouched
+	if cfg.Providers.OpenRouter.APIKey != "sk-or-v1-abcdef123456" || cfg.MCP.Servers[0].Env["GITHUB_TOKEN"] != "ghp_0123456789" {
+		t.Error("Redacted modified the config it was called on")
+	}
+}
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config represents the root configuration structure for uBot.
@@ -13,40 +18,349 @@ type Config struct {
 	Gateway   GatewayConfig   `json:"gateway"`
 	Tools     ToolsConfig     `json:"tools"`
 	MCP       MCPConfig       `json:"mcp"`
+	Cluster   ClusterConfig   `json:"cluster"`
+	Bus       BusConfig       `json:"bus"`
+	Stats     StatsConfig     `json:"stats"`
+	Tracing   TracingConfig   `json:"tracing"`
+	Skills    SkillsConfig    `json:"skills"`
+	Security  SecurityConfig  `json:"security"`
+	Session   SessionConfig   `json:"session"`
+	Prompts   PromptsConfig   `json:"prompts"`
+}
+
+// Session store backends.
+const (
+	SessionStoreFiles  = "files"  // one JSONL file per conversation (default)
+	SessionStoreSQLite = "sqlite" // a single SQLite database with full-text search
+)
+
+// SessionConfig selects where conversations are stored. A clustered gateway
+// always keeps them in Redis.
+type SessionConfig struct {
+	Store string `json:"store,omitempty"` // "files" (default) or "sqlite"
+}
+
+// UsesSQLite reports whether conversations are kept in SQLite.
+func (s SessionConfig) UsesSQLite() bool {
+	return s.Store == SessionStoreSQLite
+}
+
+// PromptsConfig picks the system prompt template (persona) for each
+// conversation. Templates are ~/.ubot/prompts/<name>.md files or built in.
+type PromptsConfig struct {
+	Default  string            `json:"default,omitempty"`  // persona for all conversations; empty = built-in
+	Channels map[string]string `json:"channels,omitempty"` // persona per channel, e.g. "telegram"
+	Chats    map[string]string `json:"chats,omitempty"`    // persona per chat, keyed by channel:chatId
+}
+
+// Persona returns the persona configured for the conversation with the
+// given session key, preferring the chat's over its channel's over the
+// default, or "" when none is set.
+func (p PromptsConfig) Persona(sessionKey string) string {
+	if name := p.Chats[sessionKey]; name != "" {
+		return name
+	}
+	channel, _, _ := strings.Cut(sessionKey, ":")
+	if name := p.Channels[channel]; name != "" {
+		return name
+	}
+	return p.Default
+}
+
+// SecurityConfig holds per-deployment restrictions on what tools may do.
+type SecurityConfig struct {
+	Browser BrowserSecurityConfig `json:"browser"`
+}
+
+// BrowserSecurityConfig restricts the browser tool, e.g. to web reading
+// without clicking or typing into forms.
+type BrowserSecurityConfig struct {
+	// AllowedActions lists the permitted browser_use actions, e.g.
+	// ["browse_page", "extract_text", "screenshot"]. Empty = all actions.
+	AllowedActions []string `json:"allowedActions,omitempty"`
+}
+
+// SkillsConfig selects the skill repositories and controls background
+// maintenance of their cache. New skills in categories of installed skills
+// are announced in the admin chat (channels.admin).
+type SkillsConfig struct {
+	RefreshHours int               `json:"refreshHours,omitempty"` // refresh the cache every N hours; default 24, negative disables
+	Repos        []SkillRepoConfig `json:"repos,omitempty"`        // skill sources; default: the community repository
+	SuggestAfter int               `json:"suggestAfter,omitempty"` // failures at one kind of task before suggesting a skill; default 3, negative disables
+	SetupHosts   []string          `json:"setupHosts,omitempty"`   // hosts setup scripts may reach; default: the package registries, "*" = any
+}
+
+// SetupNetwork returns the hosts skill setup scripts may reach, or nil
+// with anyHost true when skills.setupHosts lets them reach the whole
+// network. With no hosts configured, they reach the hosts in defaults.
+func (s SkillsConfig) SetupNetwork(defaults []string) (hosts []string, anyHost bool) {
+	if len(s.SetupHosts) == 0 {
+		return defaults, false
+	}
+	for _, h := range s.SetupHosts {
+		if h == "*" {
+			return nil, true
+		}
+	}
+	return s.SetupHosts, false
+}
+
+// SkillRepoConfig is a source of skills: a git repository or a local
+// directory. Skills from every repository but the first are named
+// "<name>.<skill>" so that they cannot collide.
+type SkillRepoConfig struct {
+	Name     string `json:"name,omitempty"`     // default: derived from the URL or path
+	URL      string `json:"url,omitempty"`      // git URL, cloned into ~/.ubot/cache
+	Path     string `json:"path,omitempty"`     // local directory, used as is
+	Disabled bool   `json:"disabled,omitempty"` // keep the repository listed but unused
+}
+
+// EnabledRepos returns the repositories that are not disabled.
+func (s SkillsConfig) EnabledRepos() []SkillRepoConfig {
+	var repos []SkillRepoConfig
+	for _, r := range s.Repos {
+		if !r.Disabled {
+			repos = append(repos, r)
+		}
+	}
+	return repos
+}
+
+// RefreshInterval returns how often the skills cache is refreshed, or zero
+// when refreshing is disabled.
+func (s SkillsConfig) RefreshInterval() time.Duration {
+	switch {
+	case s.RefreshHours < 0:
+		return 0
+	case s.RefreshHours == 0:
+		return 24 * time.Hour
+	}
+	return time.Duration(s.RefreshHours) * time.Hour
+}
+
+// SuggestThreshold returns how many failures at the same kind of task in a
+// chat lead to a skill suggestion, or zero when suggestions are disabled.
+func (s SkillsConfig) SuggestThreshold() int {
+	switch {
+	case s.SuggestAfter < 0:
+		return 0
+	case s.SuggestAfter == 0:
+		return 3
+	}
+	return s.SuggestAfter
 }
 
 // AgentsConfig holds agent-related configuration with defaults.
 type AgentsConfig struct {
-	Defaults AgentDefaults `json:"defaults"`
+	Defaults AgentDefaults  `json:"defaults"`
+	Routing  RoutingConfig  `json:"routing"`
+	Briefing BriefingConfig `json:"briefing"`
+}
+
+// BriefingConfig configures the daily briefing: a summary of the day's
+// calendar, new feed items, pending jobs and conversations waiting for an
+// answer, composed by the agent and sent to each chat on schedule.
+type BriefingConfig struct {
+	Enabled     bool     `json:"enabled"`
+	Schedule    string   `json:"schedule"`              // cron expression, e.g. "0 8 * * *"
+	Chats       []string `json:"chats,omitempty"`       // session keys such as "telegram:123"; default the admin chat
+	Model       string   `json:"model,omitempty"`       // "" uses agents.defaults.model
+	Instruction string   `json:"instruction,omitempty"` // added to the prompt, e.g. "keep it under 10 lines"
+}
+
+// Recipients returns the session keys of the chats that get the briefing:
+// the configured chats, or else the admin chat.
+func (b BriefingConfig) Recipients(admin AdminConfig) []string {
+	if len(b.Chats) > 0 {
+		return b.Chats
+	}
+	if key := admin.SessionKey(); key != "" {
+		return []string{key}
+	}
+	return nil
+}
+
+// ModelRoutes names the models used for the phases of a turn. An empty
+// entry leaves that phase to the chat's model.
+type ModelRoutes struct {
+	Planning string `json:"planning,omitempty"` // picks the tool calls, e.g. a cheap, fast model
+	Summary  string `json:"summary,omitempty"`  // summarizes content such as screenshots
+	Answer   string `json:"answer,omitempty"`   // writes the reply the user reads
+}
+
+// RoutingConfig picks models by phase, with overrides per channel.
+type RoutingConfig struct {
+	Planning string                 `json:"planning,omitempty"`
+	Summary  string                 `json:"summary,omitempty"`
+	Answer   string                 `json:"answer,omitempty"`
+	Channels map[string]ModelRoutes `json:"channels,omitempty"` // per channel, e.g. "telegram"; replaces the entries it sets
+}
+
+// Enabled reports whether any phase is routed to a model.
+func (r RoutingConfig) Enabled() bool {
+	if r.Planning != "" || r.Summary != "" || r.Answer != "" {
+		return true
+	}
+	for _, routes := range r.Channels {
+		if routes != (ModelRoutes{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// For returns the routes for conversations on channel: the channel's
+// entries where set, otherwise the general ones.
+func (r RoutingConfig) For(channel string) ModelRoutes {
+	routes := ModelRoutes{Planning: r.Planning, Summary: r.Summary, Answer: r.Answer}
+	override := r.Channels[channel]
+	if override.Planning != "" {
+		routes.Planning = override.Planning
+	}
+	if override.Summary != "" {
+		routes.Summary = override.Summary
+	}
+	if override.Answer != "" {
+		routes.Answer = override.Answer
+	}
+	return routes
 }
 
 // AgentDefaults defines default values for agent configuration.
 type AgentDefaults struct {
-	Workspace         string  `json:"workspace"`
-	Model             string  `json:"model"`
-	MaxTokens         int     `json:"maxTokens"`
-	Temperature       float64 `json:"temperature"`
-	MaxToolIterations int     `json:"maxToolIterations"`
+	Workspace          string   `json:"workspace"`
+	Model              string   `json:"model"`
+	MaxTokens          int      `json:"maxTokens"`
+	Temperature        float64  `json:"temperature"`
+	MaxToolIterations  int      `json:"maxToolIterations"`
+	MaxToolDefinitions int      `json:"maxToolDefinitions"` // tool schemas sent per turn, picked by relevance; 0 sends all
+	Models             []string `json:"models,omitempty"`   // other models chats may switch to with /model
+}
+
+// ModelChoices returns the models a chat may use: the default model first,
+// then the other configured models.
+func (d AgentDefaults) ModelChoices() []string {
+	choices := []string{d.Model}
+	for _, m := range d.Models {
+		if m != "" && !slices.Contains(choices, m) {
+			choices = append(choices, m)
+		}
+	}
+	return choices
+}
+
+// ChatModel returns the model for a chat that chose model with /model: the
+// choice if it is still offered, otherwise the default model.
+func (d AgentDefaults) ChatModel(model string) string {
+	if model != "" && slices.Contains(d.ModelChoices(), model) {
+		return model
+	}
+	return d.Model
+}
+
+// WithModels returns d also offering the models in extra, such as the ones
+// the provider lists, after the configured ones.
+func (d AgentDefaults) WithModels(extra []string) AgentDefaults {
+	d.Models = append(slices.Clip(d.Models), extra...)
+	return d
 }
 
 // ChannelsConfig holds all communication channel configurations.
 type ChannelsConfig struct {
 	Telegram TelegramConfig `json:"telegram"`
 	WhatsApp WhatsAppConfig `json:"whatsapp"`
+	Admin    AdminConfig    `json:"admin"`
+}
+
+// IsAdminUser reports whether senderID, as the channel reports it (e.g.
+// "123456|username" on Telegram), is listed in the channel's adminUsers.
+// Only the user ID is compared, since anyone can change their username.
+func (c ChannelsConfig) IsAdminUser(channel, senderID string) bool {
+	var admins []string
+	switch channel {
+	case "telegram":
+		admins = c.Telegram.AdminUsers
+	case "whatsapp":
+		admins = c.WhatsApp.AdminUsers
+	}
+	id, _, _ := strings.Cut(senderID, "|")
+	return id != "" && slices.Contains(admins, id)
+}
+
+// AdminConfig identifies the chat where the owner is asked to allow or block
+// senders that are not in a channel's allowFrom list. When ChatID is empty,
+// such senders can only be approved with "ubot access".
+type AdminConfig struct {
+	Channel string `json:"channel,omitempty"` // e.g. "telegram"
+	ChatID  string `json:"chatId,omitempty"`
+}
+
+// SessionKey returns the session key of the admin chat, or "" when no admin
+// chat is configured.
+func (a AdminConfig) SessionKey() string {
+	if a.ChatID == "" {
+		return ""
+	}
+	return a.Channel + ":" + a.ChatID
 }
 
 // TelegramConfig represents Telegram bot configuration.
 type TelegramConfig struct {
-	Enabled   bool     `json:"enabled"`
-	Token     string   `json:"token"`
-	AllowFrom []string `json:"allowFrom"`
+	Enabled       bool     `json:"enabled"`
+	Token         string   `json:"token"`
+	AllowFrom     []string `json:"allowFrom"`
+	AdminUsers    []string `json:"adminUsers,omitempty"`    // user IDs that may run manage_ubot from chat
+	CodeFileChars int      `json:"codeFileChars,omitempty"` // send longer code blocks as files; default 3000, negative disables
+	APIEndpoint   string   `json:"apiEndpoint,omitempty"`   // Bot API URL with %s for the token and method; default api.telegram.org
+	// DisableStreaming sends replies once they are complete, instead of
+	// showing a draft that is edited as the reply is generated.
+	DisableStreaming bool `json:"disableStreaming,omitempty"`
+
+	Groups TelegramGroupsConfig `json:"groups,omitempty"`
+}
+
+// Telegram group activation modes.
+const (
+	GroupActivationMention = "mention" // answer when mentioned, replied to or sent a command
+	GroupActivationAlways  = "always"  // answer every message
+)
+
+// TelegramGroupsConfig controls the bot in group chats. Each group has its
+// own conversation, shared by its members.
+type TelegramGroupsConfig struct {
+	// AllowFrom lists the chat IDs of the groups the bot answers in, such as
+	// "-1001234567890". Empty = any group, but only allowed users.
+	AllowFrom  []string `json:"allowFrom,omitempty"`
+	Activation string   `json:"activation,omitempty"` // "mention" (default) or "always"
+	// AnyMember lets every member of a group in AllowFrom talk to the bot,
+	// not only the users in channels.telegram.allowFrom. They get the same
+	// tools, exec and the file tools included, as the allowed users.
+	AnyMember bool `json:"anyMember,omitempty"`
+}
+
+// AllowsGroup reports whether the bot answers in the group chatID.
+func (g TelegramGroupsConfig) AllowsGroup(chatID string) bool {
+	return len(g.AllowFrom) == 0 || slices.Contains(g.AllowFrom, chatID)
+}
+
+// CodeFileLimit returns the length, in characters, above which a code block
+// in a reply is sent as a file, or zero when code blocks always stay inline.
+func (t TelegramConfig) CodeFileLimit() int {
+	switch {
+	case t.CodeFileChars < 0:
+		return 0
+	case t.CodeFileChars == 0:
+		return 3000
+	}
+	return t.CodeFileChars
 }
 
 // WhatsAppConfig represents WhatsApp bridge configuration.
 type WhatsAppConfig struct {
-	Enabled   bool     `json:"enabled"`
-	BridgeURL string   `json:"bridgeUrl"`
-	AllowFrom []string `json:"allowFrom"`
+	Enabled    bool     `json:"enabled"`
+	BridgeURL  string   `json:"bridgeUrl"`
+	AllowFrom  []string `json:"allowFrom"`
+	AdminUsers []string `json:"adminUsers,omitempty"` // phone numbers that may run manage_ubot from chat
 }
 
 // ProvidersConfig holds all LLM provider configurations.
@@ -59,6 +373,81 @@ type ProvidersConfig struct {
 	VLLM       ProviderConfig        `json:"vllm"`
 	Copilot    CopilotProviderConfig `json:"copilot"`
 	MiniMax    MiniMaxProviderConfig `json:"minimax"`
+	Cache      ResponseCacheConfig   `json:"cache"`
+	Log        TrafficLogConfig      `json:"log"`
+}
+
+// Traffic log modes: how much of what is sent to the provider and received
+// back is kept.
+const (
+	TrafficMetadata = "metadata" // models, sizes, timings and token counts only (default)
+	TrafficHashed   = "hashed"   // also a SHA-256 of each message, to tell what was sent without keeping it
+	TrafficFull     = "full"     // the messages and replies as sent and received
+)
+
+// TrafficLogConfig controls the log of requests sent to the model provider
+// and its responses, one JSONL file per provider in ~/.ubot/traffic. The
+// log is off by default.
+type TrafficLogConfig struct {
+	Enabled   bool   `json:"enabled,omitempty"`
+	Mode      string `json:"mode,omitempty"`      // TrafficMetadata (default), TrafficHashed or TrafficFull
+	MaxSizeMB int    `json:"maxSizeMb,omitempty"` // rotate a provider's log at this size; default 10
+	MaxFiles  int    `json:"maxFiles,omitempty"`  // rotated logs kept per provider; default 5
+}
+
+// MaxBytes returns the size at which a provider's traffic log is rotated.
+func (t TrafficLogConfig) MaxBytes() int64 {
+	if t.MaxSizeMB <= 0 {
+		return 10 << 20
+	}
+	return int64(t.MaxSizeMB) << 20
+}
+
+// Keep returns how many rotated traffic logs are kept per provider.
+func (t TrafficLogConfig) Keep() int {
+	if t.MaxFiles <= 0 {
+		return 5
+	}
+	return t.MaxFiles
+}
+
+// ResponseCacheConfig configures reusing the model's responses to identical
+// requests. Only requests sampled at or below maxTemperature are cached,
+// by default the deterministic ones at temperature 0.
+type ResponseCacheConfig struct {
+	Enabled        bool    `json:"enabled,omitempty"`
+	TTL            int     `json:"ttl,omitempty"`            // seconds a response is reused; default 300
+	MaxEntries     int     `json:"maxEntries,omitempty"`     // responses kept in memory; default 256
+	MaxTemperature float64 `json:"maxTemperature,omitempty"` // highest temperature of cached requests; default 0
+	StaleTTL       int     `json:"staleTtl,omitempty"`       // seconds an expired response may answer while the provider is unreachable; default 3600, negative = never
+}
+
+// Lifetime returns how long a cached response is reused.
+func (c ResponseCacheConfig) Lifetime() time.Duration {
+	if c.TTL <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(c.TTL) * time.Second
+}
+
+// Size returns how many responses are kept.
+func (c ResponseCacheConfig) Size() int {
+	if c.MaxEntries <= 0 {
+		return 256
+	}
+	return c.MaxEntries
+}
+
+// StaleLifetime returns how long after expiring a response may still
+// answer when the provider cannot be reached.
+func (c ResponseCacheConfig) StaleLifetime() time.Duration {
+	switch {
+	case c.StaleTTL < 0:
+		return 0
+	case c.StaleTTL == 0:
+		return time.Hour
+	}
+	return time.Duration(c.StaleTTL) * time.Second
 }
 
 // ProviderConfig represents a standard LLM provider configuration.
@@ -84,8 +473,202 @@ type MiniMaxProviderConfig struct {
 
 // GatewayConfig holds HTTP gateway configuration.
 type GatewayConfig struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	Host         string      `json:"host"`
+	Port         int         `json:"port"`
+	ControlAPI   bool        `json:"controlApi"`             // serve the control API on Host:Port
+	Health       bool        `json:"health"`                 // serve /healthz and /readyz on Host:Port, also without the control API
+	Token        string      `json:"token,omitempty"`        // bearer token required by the control API
+	DrainTimeout int         `json:"drainTimeout,omitempty"` // seconds to let messages in progress finish on shutdown; default 30, negative = none
+	Queue        QueueConfig `json:"queue"`
+}
+
+// Inbound queue backends.
+const (
+	QueueStoreMemory = "memory" // an in-memory channel (default)
+	QueueStoreSQLite = "sqlite" // a SQLite journal that survives crashes
+)
+
+// QueueConfig selects where inbound messages wait to be processed and how
+// many are processed at once. With the SQLite journal, messages are kept
+// until answered, replayed after a crash and set aside as dead letters
+// after repeated failures.
+type QueueConfig struct {
+	Store         string `json:"store,omitempty"`         // "memory" (default) or "sqlite"
+	MaxAttempts   int    `json:"maxAttempts,omitempty"`   // deliveries before a message becomes a dead letter; default 3
+	MaxConcurrent int    `json:"maxConcurrent,omitempty"` // messages processed at once across chats; default 8
+}
+
+// Persistent reports whether inbound messages are journaled.
+func (q QueueConfig) Persistent() bool {
+	return q.Store == QueueStoreSQLite
+}
+
+// Concurrency returns how many messages are processed at once. Messages
+// of the same chat are always processed one at a time.
+func (q QueueConfig) Concurrency() int {
+	if q.MaxConcurrent <= 0 {
+		return 8
+	}
+	return q.MaxConcurrent
+}
+
+// Addr returns the gateway listen address as host:port.
+func (g GatewayConfig) Addr() string {
+	return net.JoinHostPort(g.Host, strconv.Itoa(g.Port))
+}
+
+// DrainDeadline returns how long messages in progress may take to finish
+// when the gateway shuts down.
+func (g GatewayConfig) DrainDeadline() time.Duration {
+	switch {
+	case g.DrainTimeout < 0:
+		return 0
+	case g.DrainTimeout == 0:
+		return 30 * time.Second
+	}
+	return time.Duration(g.DrainTimeout) * time.Second
+}
+
+// Cluster roles for ClusterConfig.Role.
+const (
+	ClusterRoleStandalone = "standalone" // channels and processing in one process
+	ClusterRolePoller     = "poller"     // runs channel connectors only
+	ClusterRoleWorker     = "worker"     // processes messages only
+)
+
+// ClusterConfig enables running several gateway instances that share a bus
+// and session store through Redis, e.g. one polling Telegram and one or more
+// processing messages.
+type ClusterConfig struct {
+	Role     string `json:"role,omitempty"`     // "standalone" (default), "poller" or "worker"
+	RedisURL string `json:"redisUrl,omitempty"` // e.g. "redis://:password@localhost:6379/0"
+	Prefix   string `json:"prefix,omitempty"`   // key prefix; default "ubot"
+}
+
+// IsClustered reports whether the gateway runs as part of a cluster.
+func (c ClusterConfig) IsClustered() bool {
+	return c.Role != "" && c.Role != ClusterRoleStandalone
+}
+
+// KeyPrefix returns the Redis key prefix including a trailing colon.
+func (c ClusterConfig) KeyPrefix() string {
+	if c.Prefix == "" {
+		return "ubot:"
+	}
+	return c.Prefix + ":"
+}
+
+// Bus transports for BusConfig.Transport.
+const (
+	BusTransportLists   = "lists"   // one Redis list each way (default)
+	BusTransportStreams = "streams" // Redis streams with consumer groups, per channel
+)
+
+// BusChannels are the channels whose messages can cross a clustered bus.
+var BusChannels = []string{"telegram", "whatsapp"}
+
+// BusConfig selects how the instances of a cluster share messages through
+// Redis. With streams, every channel has its own inbound and outbound
+// stream; messages a crashed instance had read but not handed on are taken
+// over by another, and workers can be dedicated to some channels.
+type BusConfig struct {
+	Transport string   `json:"transport,omitempty"` // "lists" (default) or "streams"
+	Channels  []string `json:"channels,omitempty"`  // channels a worker takes messages from (streams); default all
+	Consumer  string   `json:"consumer,omitempty"`  // this instance's name in the consumer groups; default the host name
+	MaxLen    int      `json:"maxLen,omitempty"`    // approximate entries kept per stream; default 10000
+	ClaimIdle int      `json:"claimIdle,omitempty"` // seconds before another instance's unacknowledged messages are taken over; default 60, negative = never
+}
+
+// UsesStreams reports whether the bus uses Redis streams.
+func (b BusConfig) UsesStreams() bool {
+	return b.Transport == BusTransportStreams
+}
+
+// WorkerChannels returns the channels a worker takes messages from.
+func (b BusConfig) WorkerChannels() []string {
+	if len(b.Channels) == 0 {
+		return BusChannels
+	}
+	return b.Channels
+}
+
+// ConsumerName returns this instance's name in the consumer groups.
+func (b BusConfig) ConsumerName() string {
+	if b.Consumer != "" {
+		return b.Consumer
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "ubot"
+}
+
+// StreamMaxLen returns about how many entries each stream keeps.
+func (b BusConfig) StreamMaxLen() int {
+	if b.MaxLen <= 0 {
+		return 10000
+	}
+	return b.MaxLen
+}
+
+// ClaimIdleTimeout returns how long another instance's messages stay
+// unacknowledged before they are taken over, or zero for never.
+func (b BusConfig) ClaimIdleTimeout() time.Duration {
+	switch {
+	case b.ClaimIdle < 0:
+		return 0
+	case b.ClaimIdle == 0:
+		return time.Minute
+	}
+	return time.Duration(b.ClaimIdle) * time.Second
+}
+
+// Statistics categories for StatsConfig.Track.
+const (
+	StatsTrackTools  = "tools"  // tool popularity, error rates and latency
+	StatsTrackModels = "models" // model request error rates and latency
+)
+
+// StatsConfig controls anonymous local usage statistics. Collection is
+// opt-in and nothing leaves the machine; a weekly report is sent to the
+// configured owner chat.
+type StatsConfig struct {
+	Enabled       bool     `json:"enabled"`
+	Track         []string `json:"track,omitempty"`         // categories to collect: "tools", "models"; default all
+	ReportChannel string   `json:"reportChannel,omitempty"` // channel of the owner chat, e.g. "telegram"
+	ReportChatID  string   `json:"reportChatId,omitempty"`  // owner chat for the weekly report; empty = no report
+}
+
+// Tracks reports whether the given category is collected.
+func (s StatsConfig) Tracks(category string) bool {
+	if !s.Enabled {
+		return false
+	}
+	if len(s.Track) == 0 {
+		return true
+	}
+	for _, t := range s.Track {
+		if t == category {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTracingEndpoint is where traces are sent when neither
+// tracing.endpoint nor the standard OTEL_EXPORTER_OTLP_ENDPOINT variable is
+// set: an OpenTelemetry collector or Jaeger on the same machine.
+const DefaultTracingEndpoint = "http://localhost:4318/v1/traces"
+
+// TracingConfig exports OpenTelemetry traces of each message, from the
+// channel receiving it through the model and tool calls to the reply being
+// sent, over OTLP/HTTP.
+type TracingConfig struct {
+	Enabled     bool              `json:"enabled"`
+	Endpoint    string            `json:"endpoint,omitempty"`    // OTLP/HTTP traces URL; default DefaultTracingEndpoint
+	Headers     map[string]string `json:"headers,omitempty"`     // sent with each export, e.g. an API key for a hosted backend
+	ServiceName string            `json:"serviceName,omitempty"` // default "ubot"
+	SampleRatio float64           `json:"sampleRatio,omitempty"` // fraction of messages traced; 0 = all
 }
 
 // VoiceConfig holds voice transcription configuration.
@@ -99,10 +682,183 @@ type VoiceConfig struct {
 
 // ToolsConfig holds tool-related configurations.
 type ToolsConfig struct {
-	Web     WebToolsConfig `json:"web"`
-	Exec    ExecToolConfig `json:"exec"`
-	Voice   VoiceConfig    `json:"voice"`
-	Browser BrowserConfig  `json:"browser"`
+	Web      WebToolsConfig   `json:"web"`
+	Exec     ExecToolConfig   `json:"exec"`
+	Download DownloadConfig   `json:"download"`
+	Voice    VoiceConfig      `json:"voice"`
+	Browser  BrowserConfig    `json:"browser"`
+	Code     CodeConfig       `json:"code"`
+	Approval ApprovalConfig   `json:"approval"`
+	Audit    AuditConfig      `json:"audit"`
+	Results  ResultsConfig    `json:"results"`
+	Parallel ParallelConfig   `json:"parallel"`
+	Skills   SkillToolsConfig `json:"skills"`
+	Desktop  DesktopConfig    `json:"desktop"`
+	Calendar CalendarConfig   `json:"calendar"`
+}
+
+// Calendar providers for CalendarConfig.Provider.
+const (
+	CalendarCalDAV = "caldav" // a CalDAV calendar at url; needs username
+	CalendarGoogle = "google" // Google Calendar; needs clientId and clientSecret
+)
+
+// CalendarConfig configures the calendar tool. Passwords and tokens are
+// not kept here but stored under ~/.ubot/calendar by "ubot calendar login".
+type CalendarConfig struct {
+	Provider     string `json:"provider,omitempty"`     // see the Calendar constants; empty disables the tool
+	URL          string `json:"url,omitempty"`          // CalDAV calendar collection URL
+	Username     string `json:"username,omitempty"`     // CalDAV user name
+	ClientID     string `json:"clientId,omitempty"`     // Google OAuth client ID of a desktop app
+	ClientSecret string `json:"clientSecret,omitempty"` // Google OAuth client secret
+	CalendarID   string `json:"calendarId,omitempty"`   // Google calendar; default "primary"
+}
+
+// DesktopConfig enables the clipboard and desktop notification tools for
+// users running uBot on their own machine. They only run for the CLI.
+type DesktopConfig struct {
+	Enabled bool `json:"enabled,omitempty"` // register get_clipboard, set_clipboard and send_desktop_notification
+}
+
+// ParallelConfig controls running the tool calls of one model turn at the
+// same time. Only read-only tools (file reads, web fetches, searches) run
+// concurrently; other calls keep their order.
+type ParallelConfig struct {
+	Workers  int            `json:"workers,omitempty"`  // calls run at once; default 4, 1 runs calls one at a time
+	Timeout  int            `json:"timeout,omitempty"`  // seconds a tool call may run; default none
+	Timeouts map[string]int `json:"timeouts,omitempty"` // per-tool limit in seconds, e.g. {"browser_use": 300}; 0 = no limit
+}
+
+// WorkerCount returns how many tool calls run at once.
+func (p ParallelConfig) WorkerCount() int {
+	if p.Workers <= 0 {
+		return 4
+	}
+	return p.Workers
+}
+
+// CallTimeout returns how long a tool call may run; zero means no limit,
+// leaving it to the tools' own limits such as tools.exec.timeout.
+func (p ParallelConfig) CallTimeout() time.Duration {
+	if p.Timeout <= 0 {
+		return 0
+	}
+	return time.Duration(p.Timeout) * time.Second
+}
+
+// ToolTimeouts returns the per-tool time limits. download_file gets 30
+// minutes unless configured otherwise, since large files take a while.
+// exec is left to tools.exec.timeout, which stops the command itself,
+// unless it has an entry of its own.
+func (p ParallelConfig) ToolTimeouts() map[string]time.Duration {
+	timeouts := map[string]time.Duration{"download_file": 30 * time.Minute, "exec": 0}
+	for name, secs := range p.Timeouts {
+		timeouts[name] = time.Duration(secs) * time.Second
+	}
+	return timeouts
+}
+
+// ResultsConfig controls storing large tool results (page dumps, file
+// contents) out of the transcript. The model sees their start and end and a
+// continuation token, and reads the rest with fetch_more. Results are kept
+// in the workspace, or in Redis when clustered so every worker can read
+// them.
+type ResultsConfig struct {
+	Disabled  bool `json:"disabled,omitempty"`
+	MaxChars  int  `json:"maxChars,omitempty"`  // store results longer than this; default 16000
+	KeepHours int  `json:"keepHours,omitempty"` // how long stored results are kept; default 24
+}
+
+// Threshold returns the result length above which results are stored.
+func (r ResultsConfig) Threshold() int {
+	if r.MaxChars <= 0 {
+		return 16000
+	}
+	return r.MaxChars
+}
+
+// Retention returns how long stored results are kept.
+func (r ResultsConfig) Retention() time.Duration {
+	if r.KeepHours <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(r.KeepHours) * time.Hour
+}
+
+// AuditConfig controls the JSONL audit log of tool calls in ~/.ubot/audit.
+// The log is on by default.
+type AuditConfig struct {
+	Disabled  bool `json:"disabled,omitempty"`
+	MaxSizeMB int  `json:"maxSizeMb,omitempty"` // rotate the log at this size; default 10
+	MaxFiles  int  `json:"maxFiles,omitempty"`  // rotated logs to keep; default 5
+}
+
+// MaxBytes returns the size at which the audit log is rotated.
+func (a AuditConfig) MaxBytes() int64 {
+	if a.MaxSizeMB <= 0 {
+		return 10 << 20
+	}
+	return int64(a.MaxSizeMB) << 20
+}
+
+// Keep returns how many rotated audit logs are kept.
+func (a AuditConfig) Keep() int {
+	if a.MaxFiles <= 0 {
+		return 5
+	}
+	return a.MaxFiles
+}
+
+// ApprovalConfig sets per-tool execution policies: "auto" runs the tool,
+// "ask" asks the user first (inline buttons in Telegram, y/n in the CLI)
+// and "deny" never runs it.
+type ApprovalConfig struct {
+	Default string            `json:"default,omitempty"` // policy for tools not listed in Tools; default "auto"
+	Tools   map[string]string `json:"tools,omitempty"`   // per-tool policy, e.g. {"exec": "ask"}
+	Timeout int               `json:"timeout,omitempty"` // seconds to wait for an answer before denying; default 300
+}
+
+// SkillToolsConfig limits tools for the rest of a turn once the model has
+// read a skill with read_skill. Skills often come from third-party
+// repositories, so their instructions must not widen what the agent may do.
+type SkillToolsConfig struct {
+	Trusted []string          `json:"trusted,omitempty"` // skills exempt from the limits, e.g. ones you wrote
+	Tools   map[string]string `json:"tools,omitempty"`   // policy per tool after reading a skill; replaces the defaults
+	AnyPath bool              `json:"anyPath,omitempty"` // let file tools leave the workspace after reading a skill
+}
+
+// Policies returns the tool policies applied after reading an untrusted
+// skill: the configured ones, or "ask" for tools that run commands, change
+// files or change uBot itself.
+func (c SkillToolsConfig) Policies() map[string]string {
+	if c.Tools != nil {
+		return c.Tools
+	}
+	return map[string]string{
+		"exec":          "ask",
+		"write_file":    "ask",
+		"edit_file":     "ask",
+		"apply_patch":   "ask",
+		"download_file": "ask",
+		"set_env":       "ask",
+		"manage_ubot":   "ask",
+		"cron":          "ask",
+		"send_later":    "ask",
+	}
+}
+
+// WaitTimeout returns how long to wait for the user to answer an approval.
+func (a ApprovalConfig) WaitTimeout() time.Duration {
+	if a.Timeout <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(a.Timeout) * time.Second
+}
+
+// CodeConfig configures the code index behind the symbol_search and
+// open_definition tools.
+type CodeConfig struct {
+	ProjectDir string `json:"projectDir,omitempty"` // project to index; tools are disabled when empty
 }
 
 // BrowserConfig holds headless browser tool configuration.
@@ -111,25 +867,112 @@ type BrowserConfig struct {
 	Proxy       string `json:"proxy,omitempty"`       // proxy URL, e.g. "socks5://127.0.0.1:1080"
 	Stealth     bool   `json:"stealth"`               // enable anti-detection stealth; default true
 	IdleTimeout int    `json:"idleTimeout,omitempty"` // seconds before idle browser is closed; default 300
+
+	// AllowedDomains limits browser_use to these sites; BlockedDomains are
+	// refused even when allowed. "example.com" matches that host only,
+	// "*.example.com" also its subdomains. Empty = any site. Private
+	// addresses stay blocked either way.
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+	BlockedDomains []string `json:"blockedDomains,omitempty"`
+
+	// Vision describes screenshots with a vision-capable model so that
+	// text-only primary models can reason about page state.
+	Vision BrowserVisionConfig `json:"vision"`
+}
+
+// BrowserVisionConfig selects the model used to describe screenshots.
+type BrowserVisionConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Provider string `json:"provider,omitempty"` // provider name, e.g. "openai"; default: the active provider
+	Model    string `json:"model,omitempty"`    // vision model, e.g. "gpt-4o"; default: the provider's default model
 }
 
 // WebToolsConfig represents web-related tools configuration.
 type WebToolsConfig struct {
-	Search WebSearchConfig `json:"search"`
+	Search     WebSearchConfig     `json:"search"`
+	Politeness WebPolitenessConfig `json:"politeness"`
+}
+
+// WebPolitenessConfig limits how web_fetch and browser_use load pages, so an
+// agent stuck in a loop cannot hammer a site. Requests to one site are
+// always made one at a time.
+type WebPolitenessConfig struct {
+	RespectRobots bool `json:"respectRobots"`        // skip pages robots.txt disallows; default true
+	MinDelayMs    int  `json:"minDelayMs,omitempty"` // least time between requests to one site; default 1000
+	MaxWait       int  `json:"maxWait,omitempty"`    // seconds to wait for a site's Retry-After or crawl delay before giving up; default 30
+}
+
+// MinDelay returns the least time between two requests to one site.
+func (p WebPolitenessConfig) MinDelay() time.Duration {
+	return time.Duration(p.MinDelayMs) * time.Millisecond
 }
 
+// MaxWaitDuration returns how long a request may wait for its turn.
+func (p WebPolitenessConfig) MaxWaitDuration() time.Duration {
+	return time.Duration(p.MaxWait) * time.Second
+}
+
+// Search providers for WebSearchConfig.Provider.
+const (
+	SearchBrave      = "brave"      // Brave Search API; needs apiKey
+	SearchDuckDuckGo = "duckduckgo" // DuckDuckGo's HTML results; no key
+	SearchSearxNG    = "searxng"    // a SearxNG instance at url
+	SearchGoogle     = "google"     // Google Custom Search; needs apiKey and engineId
+	SearchNone       = "none"       // no web_search tool
+)
+
 // WebSearchConfig represents web search tool configuration.
 type WebSearchConfig struct {
-	APIKey     string `json:"apiKey"`
+	Provider   string `json:"provider,omitempty"` // see the Search constants; default: brave with an apiKey, else duckduckgo
+	APIKey     string `json:"apiKey"`             // Brave or Google API key
+	URL        string `json:"url,omitempty"`      // SearxNG instance, e.g. "https://searx.example.org"
+	EngineID   string `json:"engineId,omitempty"` // Google Programmable Search Engine ID (cx)
 	MaxResults int    `json:"maxResults"`
 }
 
+// ProviderName returns the search provider in use, choosing Brave when only
+// an API key is set, as before providers could be chosen, and DuckDuckGo,
+// which needs no key, otherwise.
+func (w WebSearchConfig) ProviderName() string {
+	if w.Provider != "" {
+		return w.Provider
+	}
+	if w.APIKey != "" {
+		return SearchBrave
+	}
+	return SearchDuckDuckGo
+}
+
 // ExecToolConfig represents shell execution tool configuration.
 type ExecToolConfig struct {
 	Timeout             int  `json:"timeout"`
 	RestrictToWorkspace bool `json:"restrictToWorkspace"`
 }
 
+// DownloadConfig limits the download_file tool.
+type DownloadConfig struct {
+	MaxSizeMB        int      `json:"maxSizeMb,omitempty"`        // largest file in MB; default 100
+	Dir              string   `json:"dir,omitempty"`              // workspace folder files go to when no path is given; default "downloads"
+	AllowedTypes     []string `json:"allowedTypes,omitempty"`     // content types allowed, e.g. "application/pdf" or "image/"; empty = any
+	AllowExecutables bool     `json:"allowExecutables,omitempty"` // also save programs and scripts
+}
+
+// MaxBytes returns the largest file download_file saves.
+func (d DownloadConfig) MaxBytes() int64 {
+	if d.MaxSizeMB <= 0 {
+		return 100 << 20
+	}
+	return int64(d.MaxSizeMB) << 20
+}
+
+// Folder returns the workspace folder for downloads without a path.
+func (d DownloadConfig) Folder() string {
+	if d.Dir == "" {
+		return "downloads"
+	}
+	return d.Dir
+}
+
 // MCPConfig holds Model Context Protocol server configurations.
 type MCPConfig struct {
 	Servers []MCPServerConfig `json:"servers"`
@@ -138,11 +981,22 @@ type MCPConfig struct {
 // MCPServerConfig represents an MCP server configuration.
 type MCPServerConfig struct {
 	Name      string            `json:"name"`
-	Command   string            `json:"command"`   // For stdio: command to run
-	Args      []string          `json:"args"`      // Command arguments
-	URL       string            `json:"url"`       // For HTTP: server URL
-	Transport string            `json:"transport"` // "stdio" or "http"
-	Env       map[string]string `json:"env"`       // Environment variables
+	Command   string            `json:"command"`           // For stdio: command to run
+	Args      []string          `json:"args"`              // Command arguments
+	URL       string            `json:"url"`               // For HTTP/SSE: server URL
+	Transport string            `json:"transport"`         // "stdio", "http" (streamable HTTP) or "sse"
+	Env       map[string]string `json:"env"`               // Environment variables
+	Headers   map[string]string `json:"headers,omitempty"` // For HTTP/SSE: extra request headers, e.g. Authorization
+
+	// Tools are registered as mcp_<name>_<tool> unless Bare is set, in
+	// which case they keep their own names. Aliases maps a tool to the name
+	// to register it under instead. OnConflict decides what happens when
+	// that name is already taken: "prefix" (default) falls back to
+	// mcp_<name>_<tool>, "skip" leaves the existing tool and "override"
+	// replaces it while the server is connected.
+	Bare       bool              `json:"bare,omitempty"`
+	Aliases    map[string]string `json:"aliases,omitempty"`
+	OnConflict string            `json:"onConflict,omitempty"`
 }
 
 // DefaultConfig returns a new Config with sensible default values.
@@ -150,11 +1004,15 @@ func DefaultConfig() *Config {
 	return &Config{
 		Agents: AgentsConfig{
 			Defaults: AgentDefaults{
-				Workspace:         "~/.ubot/workspace",
-				Model:             "gpt-4",
-				MaxTokens:         4096,
-				Temperature:       0.7,
-				MaxToolIterations: 10,
+				Workspace:          "~/.ubot/workspace",
+				Model:              "gpt-4",
+				MaxTokens:          4096,
+				Temperature:        0.7,
+				MaxToolIterations:  10,
+				MaxToolDefinitions: 12,
+			},
+			Briefing: BriefingConfig{
+				Schedule: "0 8 * * *",
 			},
 		},
 		Channels: ChannelsConfig{
@@ -215,6 +1073,11 @@ func DefaultConfig() *Config {
 					APIKey:     "",
 					MaxResults: 10,
 				},
+				Politeness: WebPolitenessConfig{
+					RespectRobots: true,
+					MinDelayMs:    1000,
+					MaxWait:       30,
+				},
 			},
 			Exec: ExecToolConfig{
 				Timeout:             30,
@@ -289,6 +1152,91 @@ func (c *Config) GetActiveProvider() (name string, apiKey string, apiBase string
 	return "", "", ""
 }
 
+// StatsPath returns the file holding the current period's usage statistics.
+func (c *Config) StatsPath() string {
+	return filepath.Join(c.WorkspacePath(), "stats.json")
+}
+
+// BriefingPath returns the file recording when each chat last got the
+// daily briefing.
+func (c *Config) BriefingPath() string {
+	return filepath.Join(c.WorkspacePath(), "briefing.json")
+}
+
+// AccessPath returns the file storing access decisions about unknown senders.
+func (c *Config) AccessPath() string {
+	return filepath.Join(c.WorkspacePath(), "access.json")
+}
+
+// OfflineQueuePath returns the file holding messages received while the
+// model provider was unreachable.
+func (c *Config) OfflineQueuePath() string {
+	return filepath.Join(c.WorkspacePath(), "offline.json")
+}
+
+// ResultsPath returns the directory holding large tool results.
+func (c *Config) ResultsPath() string {
+	return filepath.Join(c.WorkspacePath(), "results")
+}
+
+// FilesPath returns the directory holding files users send in chats, with
+// a directory for each conversation.
+func (c *Config) FilesPath() string {
+	return filepath.Join(c.WorkspacePath(), "files")
+}
+
+// FeedsPath returns the file holding each conversation's feed
+// subscriptions and the items already seen.
+func (c *Config) FeedsPath() string {
+	return filepath.Join(c.WorkspacePath(), "feeds.json")
+}
+
+// WatchesPath returns the file holding the files and directories
+// conversations asked to watch.
+func (c *Config) WatchesPath() string {
+	return filepath.Join(c.WorkspacePath(), "watches.json")
+}
+
+// SessionDBPath returns the SQLite database holding conversations when
+// session.store is "sqlite".
+func (c *Config) SessionDBPath() string {
+	return filepath.Join(c.WorkspacePath(), "sessions.db")
+}
+
+// QueueDBPath returns the SQLite journal of inbound messages when
+// gateway.queue.store is "sqlite".
+func (c *Config) QueueDBPath() string {
+	return filepath.Join(c.WorkspacePath(), "queue.db")
+}
+
+// AuditDir returns the directory holding the tool call audit log.
+func (c *Config) AuditDir() string {
+	return filepath.Join(GetConfigDir(), "audit")
+}
+
+// TrafficLogDir returns the directory holding the provider traffic logs.
+func (c *Config) TrafficLogDir() string {
+	return filepath.Join(GetConfigDir(), "traffic")
+}
+
+// CalendarCredentialsDir returns the directory holding the calendar
+// password or tokens.
+func (c *Config) CalendarCredentialsDir() string {
+	return filepath.Join(GetConfigDir(), "calendar")
+}
+
+// CalendarRemindersPath returns the file recording which events each
+// conversation has been reminded of.
+func (c *Config) CalendarRemindersPath() string {
+	return filepath.Join(c.WorkspacePath(), "calendar_reminders.json")
+}
+
+// CodeProjectPath returns the expanded project directory indexed by the code
+// tools, or an empty string when none is configured.
+func (c *Config) CodeProjectPath() string {
+	return expandPath(c.Tools.Code.ProjectDir)
+}
+
 // expandPath expands ~ to the user's home directory and resolves the path.
 func expandPath(path string) string {
 	if path == "" {
@@ -0,0 +1,664 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Problem is a mistake in the configuration.
+type Problem struct {
+	Field   string // JSON path, e.g. "gateway.port" or "mcp.servers[0].url"
+	Line    int    // line of the field in the config file; 0 when unknown
+	Message string
+}
+
+func (p Problem) String() string {
+	s := p.Message
+	if p.Field != "" {
+		s = p.Field + ": " + s
+	}
+	if p.Line > 0 {
+		s = fmt.Sprintf("line %d: %s", p.Line, s)
+	}
+	return s
+}
+
+// ValidationError lists the problems found in a configuration.
+type ValidationError struct {
+	Path     string // config file; empty for a config that was not loaded from a file
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	if e.Path != "" {
+		fmt.Fprintf(&sb, "invalid config %s:", e.Path)
+	} else {
+		sb.WriteString("invalid config:")
+	}
+	for _, p := range e.Problems {
+		sb.WriteString("\n  ")
+		sb.WriteString(p.String())
+	}
+	return sb.String()
+}
+
+// Parse reads a config file's contents over the defaults, applies UBOT_*
+// environment overrides and @file: secret references, and validates the
+// result. Unknown keys, values of the wrong type and invalid settings are
+// reported together as a *ValidationError, with the line of each.
+func Parse(data []byte) (*Config, error) {
+	cfg, checker, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return finish(cfg, checker.problems, checker.line)
+}
+
+// Decode reads a config file's contents over the defaults as written,
+// without environment overrides or resolving secret references, for editing
+// the file and saving it back. Only keys and value types are checked, since
+// the environment may complete the settings.
+func Decode(data []byte) (*Config, error) {
+	cfg, checker, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(checker.problems) > 0 {
+		return nil, &ValidationError{Problems: checker.problems}
+	}
+	return cfg, nil
+}
+
+// decode unmarshals data over the defaults and checks its keys and value
+// types. Only syntax errors are returned as an error.
+func decode(data []byte) (*Config, *fileChecker, error) {
+	cfg := DefaultConfig()
+	err := json.Unmarshal(data, cfg)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// Offset counts the bytes read, up to and including the bad one
+		line, col := position(data, max(syntaxErr.Offset-1, 0))
+		return nil, nil, fmt.Errorf("line %d, column %d: %w", line, col, err)
+	}
+
+	checker := &fileChecker{data: data, dec: json.NewDecoder(bytes.NewReader(data)), lines: make(map[string]int)}
+	checker.dec.UseNumber()
+	if walkErr := checker.value(reflect.TypeOf(cfg).Elem(), ""); walkErr != nil && err == nil {
+		err = walkErr
+	}
+	if err != nil && len(checker.problems) == 0 {
+		checker.problems = append(checker.problems, Problem{Message: err.Error()})
+	}
+	return cfg, checker, nil
+}
+
+// finish applies the environment to a decoded config and validates it,
+// adding to the problems found in the file. line, if not nil, gives the
+// line of a field in the file.
+func finish(cfg *Config, problems []Problem, line func(field string) int) (*Config, error) {
+	problems = append(problems, applyEnv(cfg, os.Environ())...)
+	problems = append(problems, resolveSecrets(cfg)...)
+	problems = append(problems, cfg.problems()...)
+	if len(problems) > 0 {
+		for i := range problems {
+			if problems[i].Line == 0 && line != nil {
+				problems[i].Line = line(problems[i].Field)
+			}
+		}
+		// In file order, then the settings missing from the file
+		sort.SliceStable(problems, func(i, j int) bool {
+			li, lj := problems[i].Line, problems[j].Line
+			return li != 0 && (lj == 0 || li < lj)
+		})
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// Validate checks settings for values out of range and settings that
+// contradict each other.
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Tool policies for ApprovalConfig and SkillToolsConfig.
+var toolPolicies = []string{"auto", "ask", "deny"}
+
+// problems returns the invalid settings of c.
+func (c *Config) problems() []Problem {
+	var problems []Problem
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	oneOf := func(field, value string, allowed ...string) {
+		if value != "" && !slices.Contains(allowed, value) {
+			add(field, "%q is not one of %s", value, strings.Join(allowed, ", "))
+		}
+	}
+
+	d := c.Agents.Defaults
+	if d.MaxTokens < 1 {
+		add("agents.defaults.maxTokens", "must be at least 1")
+	}
+	if d.Temperature < 0 || d.Temperature > 2 {
+		add("agents.defaults.temperature", "must be between 0 and 2")
+	}
+	if d.MaxToolIterations < 1 {
+		add("agents.defaults.maxToolIterations", "must be at least 1")
+	}
+	if d.MaxToolDefinitions < 0 {
+		add("agents.defaults.maxToolDefinitions", "must not be negative (0 sends all tools)")
+	}
+
+	b := c.Agents.Briefing
+	if b.Enabled && strings.TrimSpace(b.Schedule) == "" {
+		add("agents.briefing.schedule", "required when the briefing is enabled")
+	}
+	if b.Enabled && len(b.Recipients(c.Channels.Admin)) == 0 {
+		add("agents.briefing.chats", "required when the briefing is enabled and channels.admin is not set")
+	}
+	for i, chat := range b.Chats {
+		if channel, chatID, ok := strings.Cut(chat, ":"); !ok || channel == "" || chatID == "" {
+			add(fmt.Sprintf("agents.briefing.chats[%d]", i), "must be a chat like \"telegram:123456\"")
+		}
+	}
+
+	tg := c.Channels.Telegram
+	if tg.Enabled && tg.Token == "" {
+		add("channels.telegram.token", "required when Telegram is enabled")
+	}
+	if tg.APIEndpoint != "" && strings.Count(tg.APIEndpoint, "%s") != 2 {
+		add("channels.telegram.apiEndpoint", "must contain %%s twice, for the token and the method")
+	}
+	for i, id := range tg.AdminUsers {
+		if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+			add(fmt.Sprintf("channels.telegram.adminUsers[%d]", i), "must be a numeric user ID, not a username")
+		}
+	}
+	for i, id := range tg.Groups.AllowFrom {
+		if n, err := strconv.ParseInt(id, 10, 64); err != nil || n >= 0 {
+			add(fmt.Sprintf("channels.telegram.groups.allowFrom[%d]", i), "must be a group chat ID such as -1001234567890")
+		}
+	}
+	oneOf("channels.telegram.groups.activation", tg.Groups.Activation, GroupActivationMention, GroupActivationAlways)
+	if tg.Groups.AnyMember && len(tg.Groups.AllowFrom) == 0 {
+		add("channels.telegram.groups.anyMember", "requires channels.telegram.groups.allowFrom, or anyone could add the bot to a group and use it")
+	}
+	if c.Channels.WhatsApp.Enabled && c.Channels.WhatsApp.BridgeURL == "" {
+		add("channels.whatsapp.bridgeUrl", "required when WhatsApp is enabled")
+	}
+	if c.Channels.Admin.ChatID != "" && c.Channels.Admin.Channel == "" {
+		add("channels.admin.channel", "required when channels.admin.chatId is set")
+	}
+
+	oneOf("providers.minimax.region", c.Providers.MiniMax.Region, "global", "cn")
+
+	if c.Gateway.Port < 1 || c.Gateway.Port > 65535 {
+		add("gateway.port", "must be between 1 and 65535")
+	}
+	oneOf("gateway.queue.store", c.Gateway.Queue.Store, QueueStoreMemory, QueueStoreSQLite)
+	if c.Gateway.Queue.MaxAttempts < 0 {
+		add("gateway.queue.maxAttempts", "must not be negative")
+	}
+	if c.Gateway.Queue.MaxConcurrent < 0 {
+		add("gateway.queue.maxConcurrent", "must not be negative")
+	}
+
+	oneOf("cluster.role", c.Cluster.Role, ClusterRoleStandalone, ClusterRolePoller, ClusterRoleWorker)
+	if c.Cluster.IsClustered() && c.Cluster.RedisURL == "" {
+		add("cluster.redisUrl", "required for cluster role %q", c.Cluster.Role)
+	}
+	oneOf("bus.transport", c.Bus.Transport, BusTransportLists, BusTransportStreams)
+	for i, channel := range c.Bus.Channels {
+		oneOf(fmt.Sprintf("bus.channels[%d]", i), channel, BusChannels...)
+	}
+	if c.Bus.MaxLen < 0 {
+		add("bus.maxLen", "must not be negative")
+	}
+	oneOf("session.store", c.Session.Store, SessionStoreFiles, SessionStoreSQLite)
+
+	for i, track := range c.Stats.Track {
+		oneOf(fmt.Sprintf("stats.track[%d]", i), track, StatsTrackTools, StatsTrackModels)
+	}
+
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("tracing.endpoint", "must be an http:// or https:// URL")
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sampleRatio", "must be between 0 and 1")
+	}
+
+	for i, repo := range c.Skills.Repos {
+		field := fmt.Sprintf("skills.repos[%d]", i)
+		if (repo.URL == "") == (repo.Path == "") {
+			add(field, "needs either url or path")
+		}
+	}
+	for i, h := range c.Skills.SetupHosts {
+		if h == "" || strings.ContainsAny(h, "/: ") {
+			add(fmt.Sprintf("skills.setupHosts[%d]", i), "must be a host name such as example.com or *.example.com, or *")
+		}
+	}
+
+	cache := c.Providers.Cache
+	if cache.TTL < 0 {
+		add("providers.cache.ttl", "must not be negative")
+	}
+	if cache.MaxEntries < 0 {
+		add("providers.cache.maxEntries", "must not be negative")
+	}
+	if cache.MaxTemperature < 0 || cache.MaxTemperature > 2 {
+		add("providers.cache.maxTemperature", "must be between 0 and 2")
+	}
+	oneOf("providers.log.mode", c.Providers.Log.Mode, TrafficMetadata, TrafficHashed, TrafficFull)
+
+	t := c.Tools
+	if t.Exec.Timeout < 0 {
+		add("tools.exec.timeout", "must not be negative")
+	}
+	if t.Download.MaxSizeMB < 0 {
+		add("tools.download.maxSizeMb", "must not be negative")
+	}
+	if dir := t.Download.Dir; dir != "" && (filepath.IsAbs(dir) || !filepath.IsLocal(dir)) {
+		add("tools.download.dir", "must be a folder inside the workspace")
+	}
+	if t.Web.Search.MaxResults < 0 {
+		add("tools.web.search.maxResults", "must not be negative")
+	}
+	if t.Web.Politeness.MinDelayMs < 0 {
+		add("tools.web.politeness.minDelayMs", "must not be negative")
+	}
+	if t.Web.Politeness.MaxWait < 0 {
+		add("tools.web.politeness.maxWait", "must not be negative")
+	}
+	search := t.Web.Search
+	oneOf("tools.web.search.provider", search.Provider, SearchBrave, SearchDuckDuckGo, SearchSearxNG, SearchGoogle, SearchNone)
+	switch search.Provider {
+	case SearchBrave:
+		if search.APIKey == "" {
+			add("tools.web.search.apiKey", "is required for Brave Search")
+		}
+	case SearchGoogle:
+		if search.APIKey == "" {
+			add("tools.web.search.apiKey", "is required for Google Custom Search")
+		}
+		if search.EngineID == "" {
+			add("tools.web.search.engineId", "is required for Google Custom Search")
+		}
+	case SearchSearxNG:
+		if u, err := url.Parse(search.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("tools.web.search.url", "must be the http:// or https:// URL of a SearxNG instance")
+		}
+	}
+	cal := t.Calendar
+	oneOf("tools.calendar.provider", cal.Provider, CalendarCalDAV, CalendarGoogle)
+	switch cal.Provider {
+	case CalendarCalDAV:
+		if u, err := url.Parse(cal.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("tools.calendar.url", "must be the http:// or https:// URL of a CalDAV calendar")
+		}
+		if cal.Username == "" {
+			add("tools.calendar.username", "is required for CalDAV")
+		}
+	case CalendarGoogle:
+		if cal.ClientID == "" {
+			add("tools.calendar.clientId", "is required for Google Calendar")
+		}
+		if cal.ClientSecret == "" {
+			add("tools.calendar.clientSecret", "is required for Google Calendar")
+		}
+	}
+	if t.Browser.IdleTimeout < 0 {
+		add("tools.browser.idleTimeout", "must not be negative")
+	}
+	for _, list := range []struct {
+		field   string
+		domains []string
+	}{
+		{"tools.browser.allowedDomains", t.Browser.AllowedDomains},
+		{"tools.browser.blockedDomains", t.Browser.BlockedDomains},
+	} {
+		for i, d := range list.domains {
+			if d == "" || strings.ContainsAny(d, "/: ") {
+				add(fmt.Sprintf("%s[%d]", list.field, i), "must be a host name such as example.com or *.example.com")
+			}
+		}
+	}
+	if t.Parallel.Workers < 0 {
+		add("tools.parallel.workers", "must not be negative")
+	}
+	for name, secs := range t.Parallel.Timeouts {
+		if secs < 0 {
+			add("tools.parallel.timeouts."+name, "must not be negative (0 means no limit)")
+		}
+	}
+	oneOf("tools.approval.default", t.Approval.Default, toolPolicies...)
+	for name, policy := range t.Approval.Tools {
+		oneOf("tools.approval.tools."+name, policy, toolPolicies...)
+	}
+	for name, policy := range t.Skills.Tools {
+		oneOf("tools.skills.tools."+name, policy, toolPolicies...)
+	}
+
+	names := make(map[string]bool)
+	for i, server := range c.MCP.Servers {
+		field := fmt.Sprintf("mcp.servers[%d]", i)
+		switch {
+		case server.Name == "":
+			add(field+".name", "required")
+		case names[server.Name]:
+			add(field+".name", "another server is already named %q", server.Name)
+		}
+		names[server.Name] = true
+
+		switch server.Transport {
+		case "", "stdio":
+			if server.Command == "" {
+				add(field+".command", "required for the stdio transport")
+			}
+		case "http", "sse":
+			if server.URL == "" {
+				add(field+".url", "required for the %s transport", server.Transport)
+			}
+		default:
+			oneOf(field+".transport", server.Transport, "stdio", "http", "sse")
+		}
+		oneOf(field+".onConflict", server.OnConflict, "prefix", "skip", "override")
+		for tool, alias := range server.Aliases {
+			if !validToolName(alias) {
+				add(field+".aliases."+tool, "%q is not a valid tool name (letters, digits, _ and -, up to 64)", alias)
+			}
+		}
+	}
+
+	// Keep problems in a stable order; map iteration above is random
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Field < problems[j].Field })
+	return problems
+}
+
+// validToolName reports whether name is accepted as a tool name by model
+// APIs.
+func validToolName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// fileChecker walks the JSON of a config file alongside the Config type,
+// recording the line of every key and reporting keys the type does not
+// have and values of the wrong type.
+type fileChecker struct {
+	data     []byte
+	dec      *json.Decoder
+	lines    map[string]int
+	problems []Problem
+}
+
+func (c *fileChecker) problem(field, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{
+		Field:   field,
+		Line:    c.line(field),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// line returns the line of field in the file, or of the closest enclosing
+// field present, e.g. the section a missing setting belongs in.
+func (c *fileChecker) line(field string) int {
+	for field != "" {
+		if line, ok := c.lines[field]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(field, ".[")
+		if i < 0 {
+			break
+		}
+		field = field[:i]
+	}
+	return 0
+}
+
+// value checks the next value in the file against t.
+func (c *fileChecker) value(t reflect.Type, path string) error {
+	tok, err := c.dec.Token()
+	if err != nil {
+		return err
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			if t.Kind() != reflect.Slice {
+				c.problem(path, "expected %s, found a list", kindName(t))
+				return c.skipRest()
+			}
+			for i := 0; c.dec.More(); i++ {
+				if err := c.value(t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err := c.dec.Token()
+			return err
+		}
+
+		switch t.Kind() {
+		case reflect.Struct, reflect.Map:
+			return c.object(t, path)
+		case reflect.Interface:
+			return c.skipRest()
+		}
+		c.problem(path, "expected %s, found an object", kindName(t))
+		return c.skipRest()
+
+	case nil:
+		return nil
+	case bool:
+		if t.Kind() != reflect.Bool && t.Kind() != reflect.Interface {
+			c.problem(path, "expected %s, found true or false", kindName(t))
+		}
+	case string:
+		if t.Kind() != reflect.String && t.Kind() != reflect.Interface {
+			c.problem(path, "expected %s, found a string", kindName(t))
+		}
+	case json.Number:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if _, err := tok.Int64(); err != nil {
+				c.problem(path, "expected a whole number, found %s", tok)
+			}
+		case reflect.Float32, reflect.Float64, reflect.Interface:
+		default:
+			c.problem(path, "expected %s, found a number", kindName(t))
+		}
+	}
+	return nil
+}
+
+// object checks the members of an object, read into the struct or map type
+// t, whose opening brace has been read.
+func (c *fileChecker) object(t reflect.Type, path string) error {
+	for c.dec.More() {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		c.lines[keyPath], _ = position(c.data, c.dec.InputOffset())
+
+		elem, ok := t, true
+		if t.Kind() == reflect.Map {
+			elem = t.Elem()
+		} else if elem, ok = field(t, key); !ok {
+			msg := "unknown key"
+			if name := closestField(t, key); name != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", name)
+			}
+			c.problem(keyPath, "%s", msg)
+			if err := c.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := c.value(elem, keyPath); err != nil {
+			return err
+		}
+	}
+	_, err := c.dec.Token()
+	return err
+}
+
+// skip skips the next value.
+func (c *fileChecker) skip() error {
+	tok, err := c.dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); ok && (d == '{' || d == '[') {
+		return c.skipRest()
+	}
+	return nil
+}
+
+// skipRest skips to the end of the object or list just opened.
+func (c *fileChecker) skipRest() error {
+	for depth := 1; depth > 0; {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// field returns the type of the struct field with the given JSON key.
+// Like encoding/json, it falls back to a case-insensitive match.
+func field(t reflect.Type, key string) (reflect.Type, bool) {
+	var folded reflect.Type
+	for _, f := range reflect.VisibleFields(t) {
+		name := jsonName(f)
+		if name == key {
+			return f.Type, true
+		}
+		if folded == nil && name != "" && strings.EqualFold(name, key) {
+			folded = f.Type
+		}
+	}
+	return folded, folded != nil
+}
+
+// jsonName returns the JSON key of a struct field, or "" if it has none.
+func jsonName(f reflect.StructField) string {
+	if !f.IsExported() || f.Anonymous {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+// closestField returns the JSON key of t closest to an unknown key, if one
+// is within two edits of it.
+func closestField(t reflect.Type, key string) string {
+	best, bestDist := "", 3
+	for _, f := range reflect.VisibleFields(t) {
+		name := jsonName(f)
+		if name == "" {
+			continue
+		}
+		if d := editDistance(strings.ToLower(name), strings.ToLower(key)); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// kindName describes the JSON value expected for t.
+func kindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return t.Kind().String()
+}
+
+// position returns the 1-based line and column of a byte offset.
+func position(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(`{
+  "agents": {"defaults": {"model": "gpt-4o", "temperature": 0.2}},
+  "prompts": {"chats": {"telegram:42": "coder"}},
+  "mcp": {"servers": [{"name": "fs", "command": "mcp-fs", "env": {"ROOT": "/tmp"}}]}
+}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Agents.Defaults.Model != "gpt-4o" || cfg.Agents.Defaults.MaxTokens != 4096 {
+		t.Errorf("settings not read over the defaults: %+v", cfg.Agents.Defaults)
+	}
+}
+
+func TestParseReportsProblems(t *testing.T) {
+	data := `{
+  "agents": {
+    "defaults": {
+      "maxTokenz": 100,
+      "temperature": 3
+    }
+  },
+  "gateway": {"port": "8080"},
+  "tools": {"exec": {"timeout": 1.5}},
+  "cluster": {"role": "worker"}
+}`
+	_, err := Parse([]byte(data))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want a ValidationError", err)
+	}
+
+	want := []string{
+		`line 4: agents.defaults.maxTokenz: unknown key (did you mean "maxTokens"?)`,
+		"line 8: gateway.port: expected a whole number, found a string",
+		"line 9: tools.exec.timeout: expected a whole number, found 1.5",
+		"line 5: agents.defaults.temperature: must be between 0 and 2",
+		`line 10: cluster.redisUrl: required for cluster role "worker"`,
+	}
+	msg := err.Error()
+	for _, w := range want {
+		if !strings.Contains(msg, w) {
+			t.Errorf("error lacks %q:\n%s", w, msg)
+		}
+	}
+	if len(verr.Problems) != len(want) {
+		t.Errorf("got %d problems, want %d:\n%s", len(verr.Problems), len(want), msg)
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	_, err := Parse([]byte("{\n  \"agents\": {,\n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 2, column 14") {
+		t.Errorf("err = %v, want the position of the mistake", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config is invalid: %v", err)
+	}
+
+	cfg.Gateway.Port = 70000
+	cfg.Agents.Briefing = BriefingConfig{Enabled: true, Chats: []string{"telegram"}}
+	cfg.Channels.Telegram.Enabled = true
+	cfg.Channels.Telegram.AdminUsers = []string{"42", "@owner"}
+	cfg.Channels.Telegram.Groups = TelegramGroupsConfig{AllowFrom: []string{"-1001234567890", "@team"}, Activation: "sometimes"}
+	cfg.Tools.Approval.Tools = map[string]string{"exec": "maybe"}
+	cfg.Tracing.Endpoint = "localhost:4318"
+	cfg.Tools.Web.Search = WebSearchConfig{Provider: SearchGoogle, APIKey: "key"}
+	cfg.Tools.Download.Dir = "../outside"
+	cfg.Tools.Browser.AllowedDomains = []string{"*.example.com", "https://intranet.example"}
+	cfg.Providers.Log.Mode = "everything"
+	cfg.MCP.Servers = []MCPServerConfig{
+		{Name: "web", Transport: "http"},
+		{Name: "web", Command: "mcp-web", OnConflict: "replace", Aliases: map[string]string{"fetch": "web.fetch"}},
+	}
+	err := cfg.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want a ValidationError", err)
+	}
+	var fields []string
+	for _, p := range verr.Problems {
+		fields = append(fields, p.Field)
+	}
+	want := "agents.briefing.chats[0] agents.briefing.schedule channels.telegram.adminUsers[1] channels.telegram.groups.activation channels.telegram.groups.allowFrom[1] channels.telegram.token gateway.port mcp.servers[0].url mcp.servers[1].aliases.fetch mcp.servers[1].name mcp.servers[1].onConflict providers.log.mode tools.approval.tools.exec tools.browser.allowedDomains[1] tools.download.dir tools.web.search.engineId tracing.endpoint"
+	if got := strings.Join(fields, " "); got != want {
+		t.Errorf("fields = %s, want %s", got, want)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"gateway": {"prot": 9000}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadConfig(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Path != path {
+		t.Fatalf("err = %v, want a ValidationError for %s", err, path)
+	}
+	if !strings.Contains(err.Error(), `gateway.prot: unknown key (did you mean "port"?)`) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the config directory must be quiet before the
+// file is read again, so an editor's save causes one reload.
+const watchDebounce = 500 * time.Millisecond
+
+// Watch calls onChange whenever the contents of the config file at path
+// change, until ctx is cancelled. If path is empty, it watches the default
+// config path. The directory is watched rather than the file, so the file
+// may be replaced (as editors and Kubernetes config maps do), created or
+// removed.
+func Watch(ctx context.Context, path string, onChange func()) error {
+	if path == "" {
+		path = GetConfigPath()
+	}
+	path = expandPath(path)
+	dir := filepath.Dir(path)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch config: %w", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	// Other files in the directory change too; only react when this one does
+	last, _ := os.ReadFile(path)
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			reload = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("Warning: config watcher: %v", err)
+		case <-reload:
+			reload = nil
+			data, _ := os.ReadFile(path)
+			if bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var changes atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Watch(ctx, path, func() { changes.Add(1) }) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch: %v", err)
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	waitChanges := func(want int32) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for changes.Load() < want {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for change %d", want)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// Edited in place
+	if err := os.WriteFile(path, []byte(`{"gateway": {"port": 9090}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	waitChanges(1)
+
+	// Replaced by renaming a new file over it, as editors do
+	tmp := filepath.Join(dir, "config.json.tmp")
+	if err := os.WriteFile(tmp, []byte(`{"gateway": {"port": 9091}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitChanges(2)
+
+	// Other files in the directory and rewrites with the same contents are
+	// not changes
+	if err := os.WriteFile(filepath.Join(dir, "access.json"), []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"gateway": {"port": 9091}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * watchDebounce)
+	if n := changes.Load(); n != 2 {
+		t.Errorf("changes = %d, want 2", n)
+	}
+}
@@ -0,0 +1,134 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client talks to a running gateway's control API.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for the control API at addr (host:port).
+func NewClient(addr, token string) *Client {
+	return &Client{
+		baseURL: "http://" + addr,
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Channels lists the gateway's channels and their state.
+func (c *Client) Channels(ctx context.Context) ([]ChannelStatus, error) {
+	var out []ChannelStatus
+	if err := c.do(ctx, http.MethodGet, "/channels", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StartChannel starts the named channel connector.
+func (c *Client) StartChannel(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/channels/"+url.PathEscape(name)+"/start", nil)
+}
+
+// StopChannel stops the named channel connector.
+func (c *Client) StopChannel(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/channels/"+url.PathEscape(name)+"/stop", nil)
+}
+
+// PendingAccess lists senders waiting for the admin's decision.
+func (c *Client) PendingAccess(ctx context.Context) ([]AccessRequest, error) {
+	var out []AccessRequest
+	if err := c.do(ctx, http.MethodGet, "/access", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DecideAccess allows or blocks the sender of the access request code.
+func (c *Client) DecideAccess(ctx context.Context, code string, allow bool) (AccessRequest, error) {
+	action := "block"
+	if allow {
+		action = "allow"
+	}
+	var out AccessRequest
+	err := c.do(ctx, http.MethodPost, "/access/"+url.PathEscape(code)+"/"+action, &out)
+	return out, err
+}
+
+// Reload makes the gateway read its config file again and apply it.
+func (c *Client) Reload(ctx context.Context) (ReloadResult, error) {
+	var out ReloadResult
+	err := c.do(ctx, http.MethodPost, "/reload", &out)
+	return out, err
+}
+
+// Restart asks the gateway to restart. It returns once the restart is
+// scheduled.
+func (c *Client) Restart(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/restart", nil)
+}
+
+// Health returns the gateway's health. A gateway answering that it is
+// unavailable gives its health along with an error.
+func (c *Client) Health(ctx context.Context) (Health, error) {
+	var out Health
+	err := c.do(ctx, http.MethodGet, "/readyz", &out)
+	if err != nil && out.Status == HealthUnavailable {
+		return out, fmt.Errorf("gateway is %s", out.Status)
+	}
+	return out, err
+}
+
+// Get performs a GET request and decodes the JSON response into out.
+func (c *Client) Get(ctx context.Context, path string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, out)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("gateway control API unreachable (is the gateway running?): %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusServiceUnavailable && out != nil && json.Unmarshal(body, out) == nil {
+		return fmt.Errorf("gateway: HTTP %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			return fmt.Errorf("gateway: %s", e.Error)
+		}
+		return fmt.Errorf("gateway: HTTP %d", resp.StatusCode)
+	}
+
+	if out != nil {
+		return json.Unmarshal(body, out)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+// Package control implements the gateway's local HTTP control API, used to
+// inspect and manage a running gateway (e.g. start/stop channel connectors)
+// from the CLI or the manage_ubot tool.
+package control
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ChannelStatus describes a channel connector known to the gateway.
+type ChannelStatus struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+}
+
+// ChannelController starts and stops channel connectors at runtime.
+type ChannelController interface {
+	ChannelStatuses() []ChannelStatus
+	StartChannel(name string) error
+	StopChannel(name string) error
+}
+
+// AccessRequest is a sender from outside a channel's allowFrom list waiting
+// for the admin to allow or block them.
+type AccessRequest struct {
+	Code        string    `json:"code"`
+	Channel     string    `json:"channel"`
+	SenderID    string    `json:"senderId"`
+	ChatID      string    `json:"chatId"`
+	Preview     string    `json:"preview"`
+	RequestedAt time.Time `json:"requestedAt"`
+}
+
+// AccessController decides access requests from unknown senders.
+type AccessController interface {
+	Pending() []AccessRequest
+	Decide(code string, allow bool) (AccessRequest, error)
+}
+
+// ReloadResult reports what a configuration reload changed.
+type ReloadResult struct {
+	Applied      []string `json:"applied"`      // settings now in effect, e.g. "provider" or "channels.telegram"
+	NeedsRestart []string `json:"needsRestart"` // changed settings that only apply after a restart
+}
+
+// Reloader reloads the gateway's configuration.
+type Reloader interface {
+	Reload() (ReloadResult, error)
+}
+
+// Restarter restarts the gateway process.
+type Restarter interface {
+	// Restart schedules a graceful restart and returns without waiting
+	// for it, or reports why the gateway cannot restart itself.
+	Restart() error
+}
+
+// Health states.
+const (
+	HealthOK          = "ok"          // ready for messages
+	HealthDegraded    = "degraded"    // running, but some part is not working
+	HealthUnavailable = "unavailable" // starting or shutting down
+)
+
+// Check states.
+const (
+	CheckOK   = "ok"   // the subsystem works
+	CheckDown = "down" // the subsystem should work but does not
+	CheckOff  = "off"  // the subsystem is not in use
+)
+
+// Check is the state of one of the gateway's subsystems.
+type Check struct {
+	Status string `json:"status"`           // one of the Check* states
+	Detail string `json:"detail,omitempty"` // e.g. "unreachable" or "3 jobs"
+}
+
+// Health reports whether the gateway is ready for messages, along with the
+// state of each subsystem.
+type Health struct {
+	Status string           `json:"status"`           // one of the Health* states
+	Checks map[string]Check `json:"checks,omitempty"` // by subsystem, e.g. "provider" or "channels.telegram"
+}
+
+// NewHealth sums up checks: the gateway is degraded when any of them is
+// down.
+func NewHealth(checks map[string]Check) Health {
+	status := HealthOK
+	for _, c := range checks {
+		if c.Status == CheckDown {
+			status = HealthDegraded
+		}
+	}
+	return Health{Status: status, Checks: checks}
+}
+
+// HealthChecker reports the gateway's health.
+type HealthChecker interface {
+	Health() Health
+}
+
+// healthPaths are served without the token, for container health checks
+// and load balancers. They reveal nothing but the state of the gateway.
+var healthPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// Server is the control API HTTP server.
+type Server struct {
+	mux   *http.ServeMux
+	srv   *http.Server
+	token string
+}
+
+// NewServer creates a control server listening on addr. When token is
+// non-empty every request must carry "Authorization: Bearer <token>".
+func NewServer(addr, token string) *Server {
+	s := &Server{
+		mux:   http.NewServeMux(),
+		token: token,
+	}
+	s.srv = &http.Server{
+		Addr:              addr,
+		Handler:           s.authenticate(s.mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Handle registers a handler for the given pattern (Go 1.22 mux syntax).
+func (s *Server) Handle(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// RegisterChannels exposes channel management endpoints:
+//
+//	GET  /channels               list channels and their state
+//	POST /channels/{name}/start  start a channel connector
+//	POST /channels/{name}/stop   stop a channel connector
+func (s *Server) RegisterChannels(ctrl ChannelController) {
+	s.Handle("GET /channels", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, ctrl.ChannelStatuses())
+	})
+	s.Handle("POST /channels/{name}/start", func(w http.ResponseWriter, r *http.Request) {
+		if err := ctrl.StartChannel(r.PathValue("name")); err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]string{"status": "started"})
+	})
+	s.Handle("POST /channels/{name}/stop", func(w http.ResponseWriter, r *http.Request) {
+		if err := ctrl.StopChannel(r.PathValue("name")); err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
+	})
+}
+
+// RegisterAccess exposes endpoints for deciding unknown senders:
+//
+//	GET  /access               list pending access requests
+//	POST /access/{code}/allow  allow the sender and deliver held messages
+//	POST /access/{code}/block  block the sender
+func (s *Server) RegisterAccess(ctrl AccessController) {
+	s.Handle("GET /access", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, ctrl.Pending())
+	})
+	decide := func(allow bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			req, err := ctrl.Decide(r.PathValue("code"), allow)
+			if err != nil {
+				WriteError(w, http.StatusBadRequest, err)
+				return
+			}
+			WriteJSON(w, http.StatusOK, req)
+		}
+	}
+	s.Handle("POST /access/{code}/allow", decide(true))
+	s.Handle("POST /access/{code}/block", decide(false))
+}
+
+// RegisterReload exposes the configuration reload endpoint:
+//
+//	POST /reload  read the config file again and apply it
+func (s *Server) RegisterReload(r Reloader) {
+	s.Handle("POST /reload", func(w http.ResponseWriter, req *http.Request) {
+		result, err := r.Reload()
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		WriteJSON(w, http.StatusOK, result)
+	})
+}
+
+// RegisterHealth exposes the health endpoints, which need no token:
+//
+//	GET /healthz  the gateway process is up
+//	GET /readyz   the gateway's health; 503 while it is unavailable
+func (s *Server) RegisterHealth(h HealthChecker) {
+	s.Handle("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, Health{Status: HealthOK})
+	})
+	s.Handle("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		health := h.Health()
+		status := http.StatusOK
+		if health.Status == HealthUnavailable {
+			status = http.StatusServiceUnavailable
+		}
+		WriteJSON(w, status, health)
+	})
+}
+
+// RegisterRestart exposes the restart endpoint:
+//
+//	POST /restart  stop gracefully and let the supervisor start the gateway again
+func (s *Server) RegisterRestart(r Restarter) {
+	s.Handle("POST /restart", func(w http.ResponseWriter, req *http.Request) {
+		if err := r.Restart(); err != nil {
+			WriteError(w, http.StatusConflict, err)
+			return
+		}
+		WriteJSON(w, http.StatusAccepted, map[string]string{"status": "restarting"})
+	})
+}
+
+// Start begins serving in the background. It returns once the listener is
+// bound so address conflicts are reported to the caller.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Control API stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown gracefully stops the server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// authenticate enforces the bearer token when one is configured.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && healthPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			WriteError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WriteJSON writes v as a JSON response with the given status code.
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// WriteError writes an {"error": "..."} JSON response.
+func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteJSON(w, status, map[string]string{"error": err.Error()})
+}
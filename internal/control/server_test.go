@@ -0,0 +1,207 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+type fakeController struct {
+	running map[string]bool
+}
+
+func (f *fakeController) ChannelStatuses() []ChannelStatus {
+	var out []ChannelStatus
+	for name, running := range f.running {
+		out = append(out, ChannelStatus{Name: name, Running: running})
+	}
+	return out
+}
+
+func (f *fakeController) StartChannel(name string) error {
+	if _, ok := f.running[name]; !ok {
+		return fmt.Errorf("channel %s not found", name)
+	}
+	f.running[name] = true
+	return nil
+}
+
+func (f *fakeController) StopChannel(name string) error {
+	if !f.running[name] {
+		return fmt.Errorf("channel %s is not running", name)
+	}
+	f.running[name] = false
+	return nil
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestChannelEndpoints(t *testing.T) {
+	addr := freeAddr(t)
+	ctrl := &fakeController{running: map[string]bool{"telegram": true}}
+
+	srv := NewServer(addr, "secret")
+	srv.RegisterChannels(ctrl)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	ctx := context.Background()
+
+	// Wrong token is rejected.
+	if _, err := NewClient(addr, "wrong").Channels(ctx); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+
+	client := NewClient(addr, "secret")
+	if err := client.StopChannel(ctx, "telegram"); err != nil {
+		t.Fatalf("StopChannel: %v", err)
+	}
+	if ctrl.running["telegram"] {
+		t.Error("telegram should be stopped")
+	}
+
+	// Stopping again reports the controller error.
+	if err := client.StopChannel(ctx, "telegram"); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("expected 'not running' error, got %v", err)
+	}
+
+	if err := client.StartChannel(ctx, "telegram"); err != nil {
+		t.Fatalf("StartChannel: %v", err)
+	}
+	statuses, err := client.Channels(ctx)
+	if err != nil {
+		t.Fatalf("Channels: %v", err)
+	}
+	if len(statuses) != 1 || !statuses[0].Running {
+		t.Errorf("unexpected statuses: %+v", statuses)
+	}
+}
+
+type reloaderFunc func() (ReloadResult, error)
+
+func (f reloaderFunc) Reload() (ReloadResult, error) { return f() }
+
+func TestReloadEndpoint(t *testing.T) {
+	addr := freeAddr(t)
+	fail := false
+	srv := NewServer(addr, "")
+	srv.RegisterReload(reloaderFunc(func() (ReloadResult, error) {
+		if fail {
+			return ReloadResult{}, fmt.Errorf("invalid config")
+		}
+		return ReloadResult{Applied: []string{"provider"}, NeedsRestart: []string{"gateway"}}, nil
+	}))
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	client := NewClient(addr, "")
+	result, err := client.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.Applied) != 1 || result.Applied[0] != "provider" || len(result.NeedsRestart) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+
+	fail = true
+	if _, err := client.Reload(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Errorf("expected the reload error, got %v", err)
+	}
+}
+
+type restarterFunc func() error
+
+func (f restarterFunc) Restart() error { return f() }
+
+func TestRestartEndpoint(t *testing.T) {
+	addr := freeAddr(t)
+	restarts := 0
+	supervised := true
+	srv := NewServer(addr, "")
+	srv.RegisterRestart(restarterFunc(func() error {
+		if !supervised {
+			return fmt.Errorf("not supervised")
+		}
+		restarts++
+		return nil
+	}))
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	client := NewClient(addr, "")
+	if err := client.Restart(context.Background()); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	if restarts != 1 {
+		t.Errorf("restarts = %d, want 1", restarts)
+	}
+
+	supervised = false
+	if err := client.Restart(context.Background()); err == nil || !strings.Contains(err.Error(), "not supervised") {
+		t.Errorf("expected the restart error, got %v", err)
+	}
+}
+
+type healthFunc func() Health
+
+func (f healthFunc) Health() Health { return f() }
+
+func TestHealthEndpoints(t *testing.T) {
+	addr := freeAddr(t)
+	health := Health{Status: HealthUnavailable, Checks: map[string]Check{"gateway": {Status: CheckDown, Detail: "starting"}}}
+	srv := NewServer(addr, "secret")
+	srv.RegisterHealth(healthFunc(func() Health { return health }))
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	// Health checks need no token
+	client := NewClient(addr, "")
+	if err := client.Get(context.Background(), "/healthz", nil); err != nil {
+		t.Errorf("/healthz: %v", err)
+	}
+	got, err := client.Health(context.Background())
+	if err == nil || got.Checks["gateway"].Detail != "starting" {
+		t.Errorf("Health = %+v, %v; want unavailable while starting", got, err)
+	}
+
+	health = NewHealth(map[string]Check{
+		"provider": {Status: CheckDown, Detail: "unreachable"},
+		"docker":   {Status: CheckOff},
+	})
+	if got, err = client.Health(context.Background()); err != nil || got.Status != HealthDegraded || got.Checks["provider"].Detail != "unreachable" {
+		t.Errorf("Health = %+v, %v; want degraded", got, err)
+	}
+}
+
+func TestNewHealth(t *testing.T) {
+	if h := NewHealth(nil); h.Status != HealthOK {
+		t.Errorf("NewHealth(nil) = %q, want ok", h.Status)
+	}
+	h := NewHealth(map[string]Check{"cron": {Status: CheckOK}, "docker": {Status: CheckOff}})
+	if h.Status != HealthOK {
+		t.Errorf("NewHealth with a subsystem off = %q, want ok", h.Status)
+	}
+	h = NewHealth(map[string]Check{"cron": {Status: CheckOK}, "mcp.github": {Status: CheckDown}})
+	if h.Status != HealthDegraded {
+		t.Errorf("NewHealth with a subsystem down = %q, want degraded", h.Status)
+	}
+}
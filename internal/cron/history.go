@@ -0,0 +1,149 @@
+package cron
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// maxRunsPerJob caps how many run records are kept per job.
+	maxRunsPerJob = 20
+	// maxOutputSnippet caps the stored output snippet length (in characters).
+	maxOutputSnippet = 200
+	// failureNotifyThreshold is the number of consecutive failures after
+	// which the owning chat is notified.
+	failureNotifyThreshold = 3
+)
+
+// RunRecord describes a single execution of a job.
+type RunRecord struct {
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Success   bool          `json:"success"`
+	Error     string        `json:"error,omitempty"`
+	Output    string        `json:"output,omitempty"` // truncated snippet
+}
+
+// JobHistory holds the recent run records for a job.
+type JobHistory struct {
+	JobID               string      `json:"job_id"`
+	Runs                []RunRecord `json:"runs"` // oldest first
+	ConsecutiveFailures int         `json:"consecutive_failures"`
+}
+
+// LastRun returns the most recent run record, or nil if the job never ran.
+func (h *JobHistory) LastRun() *RunRecord {
+	if h == nil || len(h.Runs) == 0 {
+		return nil
+	}
+	return &h.Runs[len(h.Runs)-1]
+}
+
+// record appends a run to the history, trimming old entries.
+func (h *JobHistory) record(run RunRecord) {
+	h.Runs = append(h.Runs, run)
+	if len(h.Runs) > maxRunsPerJob {
+		h.Runs = h.Runs[len(h.Runs)-maxRunsPerJob:]
+	}
+	if run.Success {
+		h.ConsecutiveFailures = 0
+	} else {
+		h.ConsecutiveFailures++
+	}
+}
+
+// snippet truncates s to maxOutputSnippet characters.
+func snippet(s string) string {
+	r := []rune(s)
+	if len(r) <= maxOutputSnippet {
+		return s
+	}
+	return string(r[:maxOutputSnippet]) + "..."
+}
+
+// historyPath returns the run history file stored next to the jobs file.
+func (s *Scheduler) historyPath() string {
+	return filepath.Join(filepath.Dir(s.persistPath), "cron_history.json")
+}
+
+// History returns a copy of the run history for a job, or nil if the job
+// has no recorded runs.
+func (s *Scheduler) History(id string) *JobHistory {
+	s.histMu.Lock()
+	defer s.histMu.Unlock()
+
+	h, ok := s.history[id]
+	if !ok {
+		return nil
+	}
+	cp := *h
+	cp.Runs = append([]RunRecord(nil), h.Runs...)
+	return &cp
+}
+
+// recordRun stores a run record for a job, persists the history, and returns
+// the number of consecutive failures after this run.
+func (s *Scheduler) recordRun(id string, run RunRecord) int {
+	s.histMu.Lock()
+	defer s.histMu.Unlock()
+
+	h, ok := s.history[id]
+	if !ok {
+		h = &JobHistory{JobID: id}
+		s.history[id] = h
+	}
+	h.record(run)
+	_ = s.saveHistoryLocked()
+	return h.ConsecutiveFailures
+}
+
+// dropHistory removes the history of a deleted job.
+func (s *Scheduler) dropHistory(id string) {
+	s.histMu.Lock()
+	defer s.histMu.Unlock()
+
+	if _, ok := s.history[id]; !ok {
+		return
+	}
+	delete(s.history, id)
+	_ = s.saveHistoryLocked()
+}
+
+func (s *Scheduler) saveHistoryLocked() error {
+	list := make([]*JobHistory, 0, len(s.history))
+	for _, h := range s.history {
+		list = append(list, h)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.persistPath), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.historyPath(), data, 0o600)
+}
+
+func (s *Scheduler) loadHistory() error {
+	data, err := os.ReadFile(s.historyPath())
+	if err != nil {
+		return err
+	}
+
+	var list []*JobHistory
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	s.histMu.Lock()
+	defer s.histMu.Unlock()
+	for _, h := range list {
+		if h != nil && h.JobID != "" {
+			s.history[h.JobID] = h
+		}
+	}
+	return nil
+}
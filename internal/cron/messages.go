@@ -0,0 +1,134 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+// ScheduledMessage is a literal message sent once at a set time, such as
+// "call mom" at 17:00. Unlike a job it does not call the model: the text is
+// sent as is.
+type ScheduledMessage struct {
+	ID      string    `json:"id"`
+	At      time.Time `json:"at"`
+	Content string    `json:"content"`
+	Channel string    `json:"channel"`
+	ChatID  string    `json:"chat_id"`
+}
+
+// messageEntry wraps a ScheduledMessage with runtime state for the
+// scheduler.
+type messageEntry struct {
+	Message ScheduledMessage
+	cancel  context.CancelFunc
+}
+
+// SendLater schedules content to be sent to the chat at the given time and
+// returns the message's ID. IDs are shared with jobs.
+func (s *Scheduler) SendLater(at time.Time, content, channel, chatID string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("message is empty")
+	}
+	if channel == "" || chatID == "" {
+		return "", fmt.Errorf("channel and chat ID are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := strconv.Itoa(s.nextID)
+	s.nextID++
+
+	entry := &messageEntry{
+		Message: ScheduledMessage{
+			ID:      id,
+			At:      at,
+			Content: content,
+			Channel: channel,
+			ChatID:  chatID,
+		},
+	}
+	s.messages[id] = entry
+
+	if s.ctx != nil {
+		s.startMessageLocked(entry)
+	}
+
+	if err := s.saveLocked(); err != nil {
+		return id, fmt.Errorf("message scheduled but failed to persist: %w", err)
+	}
+	return id, nil
+}
+
+// CancelMessage removes a scheduled message that has not been sent yet.
+func (s *Scheduler) CancelMessage(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.messages[id]
+	if !ok {
+		return fmt.Errorf("scheduled message %q not found", id)
+	}
+	if entry.cancel != nil {
+		entry.cancel()
+	}
+	delete(s.messages, id)
+
+	return s.saveLocked()
+}
+
+// ListMessages returns the messages waiting to be sent, soonest first.
+func (s *Scheduler) ListMessages() []ScheduledMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	msgs := make([]ScheduledMessage, 0, len(s.messages))
+	for _, entry := range s.messages {
+		msgs = append(msgs, entry.Message)
+	}
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].At.Before(msgs[j].At) })
+	return msgs
+}
+
+// startMessageLocked launches the goroutine waiting to send a message.
+// Caller must hold at least an RLock on s.mu.
+func (s *Scheduler) startMessageLocked(entry *messageEntry) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	entry.cancel = cancel
+
+	go s.runMessage(ctx, entry.Message)
+}
+
+// runMessage waits until msg is due, then sends it and forgets it.
+func (s *Scheduler) runMessage(ctx context.Context, msg ScheduledMessage) {
+	timer := time.NewTimer(time.Until(msg.At))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	// Cancelled at the last moment: the entry is gone
+	s.mu.Lock()
+	if _, ok := s.messages[msg.ID]; !ok {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.messages, msg.ID)
+	_ = s.saveLocked()
+	s.mu.Unlock()
+
+	s.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: msg.Content,
+	})
+}
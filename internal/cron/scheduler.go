@@ -1,6 +1,7 @@
 // Package cron provides a proactive scheduler that fires LLM-driven messages
-// on cron schedules. Jobs are persisted to ~/.ubot/cron_jobs.json and
-// survive restarts.
+// on cron schedules and sends literal messages at set times. Jobs and
+// scheduled messages are persisted to ~/.ubot/cron_jobs.json and survive
+// restarts.
 package cron
 
 import (
@@ -27,6 +28,26 @@ type Job struct {
 	ChatID      string `json:"chat_id"`
 }
 
+// maxJobToolRounds is how many rounds of tool calls a job may make before
+// it has to answer.
+const maxJobToolRounds = 5
+
+// SilentReply is the answer of a job with nothing to tell the user this
+// time, such as a reminder job when no meeting is about to start. Nothing
+// is sent to the chat.
+const SilentReply = "NO_REPLY"
+
+// Toolbox offers tools to jobs while they run, such as feeds for a morning
+// digest of new posts. It is defined here, not in the tools package, so the
+// gateway can choose which tools jobs may call.
+type Toolbox interface {
+	// Definitions returns the tool definitions sent to the model.
+	Definitions() interface{}
+	// Execute runs a tool call made for job and returns the result, or a
+	// description of the error, to show the model.
+	Execute(ctx context.Context, job Job, call providers.ToolCall) string
+}
+
 // jobEntry wraps a Job with runtime state for the scheduler.
 type jobEntry struct {
 	Job    Job
@@ -39,10 +60,15 @@ type Scheduler struct {
 	bus      *bus.MessageBus
 	provider providers.Provider
 	model    string
+	toolbox  Toolbox // nil when jobs get no tools
 
-	mu      sync.RWMutex
-	entries map[string]*jobEntry
-	nextID  int
+	mu       sync.RWMutex
+	entries  map[string]*jobEntry
+	messages map[string]*messageEntry
+	nextID   int // shared by jobs and scheduled messages
+
+	histMu  sync.Mutex
+	history map[string]*JobHistory
 
 	persistPath string
 	ctx         context.Context
@@ -57,16 +83,24 @@ func NewScheduler(msgBus *bus.MessageBus, provider providers.Provider, model str
 		provider:    provider,
 		model:       model,
 		entries:     make(map[string]*jobEntry),
+		messages:    make(map[string]*messageEntry),
+		history:     make(map[string]*JobHistory),
 		nextID:      1,
 		persistPath: filepath.Join(home, ".ubot", "cron_jobs.json"),
 	}
 }
 
-// Start loads persisted jobs and begins all cron timers.
+// SetToolbox gives jobs the tools in tb. It must be called before Start.
+func (s *Scheduler) SetToolbox(tb Toolbox) {
+	s.toolbox = tb
+}
+
+// Start loads persisted jobs and begins all cron timers. Scheduled messages
+// whose time passed while the scheduler was stopped are sent at once.
 func (s *Scheduler) Start(ctx context.Context) error {
 	s.ctx, s.cancel = context.WithCancel(ctx)
 
-	if err := s.load(); err != nil {
+	if err := s.Load(); err != nil {
 		// Non-fatal: file might not exist yet.
 		_ = err
 	}
@@ -76,6 +110,9 @@ func (s *Scheduler) Start(ctx context.Context) error {
 	for _, entry := range s.entries {
 		s.startJobLocked(entry)
 	}
+	for _, entry := range s.messages {
+		s.startMessageLocked(entry)
+	}
 	return nil
 }
 
@@ -86,13 +123,15 @@ func (s *Scheduler) Stop() {
 	}
 }
 
+// Running reports whether the scheduler has been started and not stopped.
+func (s *Scheduler) Running() bool {
+	return s.ctx != nil && s.ctx.Err() == nil
+}
+
 // AddJob registers a new cron job and starts it. Returns the job ID.
 func (s *Scheduler) AddJob(schedule, instruction, channel, chatID string) (string, error) {
-	// Validate schedule by parsing it once.
-	if _, err := parseDuration(schedule); err != nil {
-		if _, err2 := parseCronFields(schedule); err2 != nil {
-			return "", fmt.Errorf("invalid schedule %q: %w", schedule, err2)
-		}
+	if err := validateSchedule(schedule); err != nil {
+		return "", err
 	}
 
 	s.mu.Lock()
@@ -137,10 +176,57 @@ func (s *Scheduler) RemoveJob(id string) error {
 		entry.cancel()
 	}
 	delete(s.entries, id)
+	s.dropHistory(id)
 
 	return s.saveLocked()
 }
 
+// UpdateJob changes a job in place, keeping its ID and run history. Empty
+// arguments leave the field unchanged. A new schedule is validated first,
+// and the job is restarted so the change takes effect at once.
+func (s *Scheduler) UpdateJob(id, schedule, instruction, channel, chatID string) (Job, error) {
+	if schedule != "" {
+		if err := validateSchedule(schedule); err != nil {
+			return Job{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		return Job{}, fmt.Errorf("job %q not found", id)
+	}
+
+	if schedule != "" {
+		entry.Job.Schedule = schedule
+	}
+	if instruction != "" {
+		entry.Job.Instruction = instruction
+	}
+	if channel != "" {
+		entry.Job.Channel = channel
+	}
+	if chatID != "" {
+		entry.Job.ChatID = chatID
+	}
+
+	// The running goroutine holds a copy of the old job
+	if entry.cancel != nil {
+		entry.cancel()
+		entry.cancel = nil
+	}
+	if s.ctx != nil {
+		s.startJobLocked(entry)
+	}
+
+	if err := s.saveLocked(); err != nil {
+		return entry.Job, fmt.Errorf("job updated but failed to persist: %w", err)
+	}
+	return entry.Job, nil
+}
+
 // ListJobs returns all registered jobs.
 func (s *Scheduler) ListJobs() []Job {
 	s.mu.RLock()
@@ -198,6 +284,9 @@ func (s *Scheduler) runCronJob(ctx context.Context, job Job, fields cronFields)
 	for {
 		now := time.Now()
 		next := fields.nextAfter(now)
+		if next.IsZero() {
+			return // schedule can never fire
+		}
 		delay := next.Sub(now)
 		if delay < 0 {
 			delay = time.Second
@@ -214,12 +303,16 @@ func (s *Scheduler) runCronJob(ctx context.Context, job Job, fields cronFields)
 	}
 }
 
-// fireJob calls the LLM with the job's instruction and publishes the result.
+// fireJob calls the LLM with the job's instruction, letting it use the
+// toolbox, publishes the result unless it is SilentReply and records the
+// run in the job history.
+// When a job fails failureNotifyThreshold times in a row, the owning chat
+// is notified once.
 func (s *Scheduler) fireJob(ctx context.Context, job Job) {
-	now := time.Now().Format(time.RFC1123)
+	started := time.Now()
 	prompt := fmt.Sprintf(
-		"It is now %s. Based on your instruction: %s\nWhat should you tell the user?",
-		now, job.Instruction,
+		"It is now %s. Based on your instruction: %s\nWhat should you tell the user? If there is nothing to tell them this time, answer only %s.",
+		started.Format(time.RFC1123), job.Instruction, SilentReply,
 	)
 
 	req := providers.ChatRequest{
@@ -230,12 +323,46 @@ func (s *Scheduler) fireJob(ctx context.Context, job Job) {
 		MaxTokens:   512,
 		Temperature: 0.7,
 	}
+	if s.toolbox != nil {
+		req.Tools = s.toolbox.Definitions()
+	}
+
+	resp, err := s.chat(ctx, job, req)
+	if err == nil && (resp == nil || strings.TrimSpace(resp.Content) == "") {
+		err = fmt.Errorf("empty response from provider")
+	}
 
-	resp, err := s.provider.Chat(ctx, req)
-	if err != nil || resp == nil || strings.TrimSpace(resp.Content) == "" {
+	run := RunRecord{
+		StartedAt: started,
+		Duration:  time.Since(started),
+		Success:   err == nil,
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return // scheduler stopping; not a job failure
+		}
+		run.Error = err.Error()
+	} else {
+		run.Output = snippet(resp.Content)
+	}
+
+	failures := s.recordRun(job.ID, run)
+
+	if err != nil {
+		if failures == failureNotifyThreshold {
+			s.bus.PublishOutbound(bus.OutboundMessage{
+				Channel: job.Channel,
+				ChatID:  job.ChatID,
+				Content: fmt.Sprintf("Scheduled job %s has failed %d times in a row (last error: %s).",
+					job.ID, failures, run.Error),
+			})
+		}
 		return
 	}
 
+	if strings.TrimSpace(resp.Content) == SilentReply {
+		return
+	}
 	s.bus.PublishOutbound(bus.OutboundMessage{
 		Channel: job.Channel,
 		ChatID:  job.ChatID,
@@ -243,11 +370,40 @@ func (s *Scheduler) fireJob(ctx context.Context, job Job) {
 	})
 }
 
+// chat sends req to the model, running the tools it calls through the
+// toolbox until it answers.
+func (s *Scheduler) chat(ctx context.Context, job Job, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	for round := 0; ; round++ {
+		resp, err := s.provider.Chat(ctx, req)
+		if err != nil || resp == nil || !resp.HasToolCalls() || s.toolbox == nil {
+			return resp, err
+		}
+		if round == maxJobToolRounds {
+			return nil, fmt.Errorf("still calling tools after %d rounds", maxJobToolRounds)
+		}
+
+		req.Messages = append(req.Messages, providers.ChatMessage{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		for _, call := range resp.ToolCalls {
+			req.Messages = append(req.Messages, providers.ChatMessage{
+				Role:       "tool",
+				Content:    s.toolbox.Execute(ctx, job, call),
+				ToolCallID: call.ID,
+				Name:       call.Name,
+			})
+		}
+	}
+}
+
 // --- persistence ---
 
 type persistedState struct {
-	Jobs   []Job `json:"jobs"`
-	NextID int   `json:"next_id"`
+	Jobs     []Job              `json:"jobs"`
+	Messages []ScheduledMessage `json:"messages,omitempty"`
+	NextID   int                `json:"next_id"`
 }
 
 func (s *Scheduler) saveLocked() error {
@@ -258,6 +414,9 @@ func (s *Scheduler) saveLocked() error {
 	for _, e := range s.entries {
 		state.Jobs = append(state.Jobs, e.Job)
 	}
+	for _, e := range s.messages {
+		state.Messages = append(state.Messages, e.Message)
+	}
 
 	dir := filepath.Dir(s.persistPath)
 	if err := os.MkdirAll(dir, 0o700); err != nil {
@@ -285,12 +444,30 @@ func (s *Scheduler) load() error {
 	for _, job := range state.Jobs {
 		s.entries[job.ID] = &jobEntry{Job: job}
 	}
+	for _, msg := range state.Messages {
+		s.messages[msg.ID] = &messageEntry{Message: msg}
+	}
 	if state.NextID > s.nextID {
 		s.nextID = state.NextID
 	}
 	return nil
 }
 
+// Load reads persisted jobs and their run history from disk. It is called by
+// Start and can be used on its own to inspect jobs without running them.
+func (s *Scheduler) Load() error {
+	s.mu.Lock()
+	err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := s.loadHistory(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // SetPersistPath overrides the default persistence path (useful for tests).
 func (s *Scheduler) SetPersistPath(path string) {
 	s.persistPath = path
@@ -298,6 +475,38 @@ func (s *Scheduler) SetPersistPath(path string) {
 
 // --- cron expression parsing ---
 
+// validateSchedule checks that schedule is an interval or a cron expression
+// that fires at least once.
+func validateSchedule(schedule string) error {
+	if d, err := parseDuration(schedule); err == nil {
+		if d <= 0 {
+			return fmt.Errorf("invalid schedule %q: interval must be positive", schedule)
+		}
+		return nil
+	}
+	fields, err := parseCronFields(schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", schedule, err)
+	}
+	if fields.nextAfter(time.Now()).IsZero() {
+		return fmt.Errorf("invalid schedule %q: never fires", schedule)
+	}
+	return nil
+}
+
+// NextRun returns when schedule, an interval or a cron expression, next
+// fires after t. An interval fires that long after t.
+func NextRun(schedule string, t time.Time) (time.Time, error) {
+	if err := validateSchedule(schedule); err != nil {
+		return time.Time{}, err
+	}
+	if d, err := parseDuration(schedule); err == nil {
+		return t.Add(d), nil
+	}
+	fields, _ := parseCronFields(schedule)
+	return fields.nextAfter(t), nil
+}
+
 // parseDuration handles "@every 5m" style schedules.
 func parseDuration(spec string) (time.Duration, error) {
 	spec = strings.TrimSpace(spec)
@@ -315,11 +524,35 @@ type cronFields struct {
 	daysOfMon  []int // 1-31
 	months     []int // 1-12
 	daysOfWeek []int // 0-6 (0=Sunday)
+
+	// domStar and dowStar record whether the day fields were "*". As in
+	// standard cron, when both are restricted a day matches if either does.
+	domStar bool
+	dowStar bool
+}
+
+// namedSchedules maps predefined schedule descriptors to cron expressions.
+var namedSchedules = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
 }
 
-// parseCronFields parses a standard 5-field cron expression.
+// parseCronFields parses a standard 5-field cron expression or one of the
+// named descriptors (@hourly, @daily, @weekly, @monthly, @yearly).
 func parseCronFields(spec string) (cronFields, error) {
 	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@") {
+		expr, ok := namedSchedules[strings.ToLower(spec)]
+		if !ok {
+			return cronFields{}, fmt.Errorf("unknown schedule descriptor %q", spec)
+		}
+		spec = expr
+	}
 	parts := strings.Fields(spec)
 	if len(parts) != 5 {
 		return cronFields{}, fmt.Errorf("expected 5 fields, got %d", len(parts))
@@ -352,6 +585,8 @@ func parseCronFields(spec string) (cronFields, error) {
 		daysOfMon:  dom,
 		months:     months,
 		daysOfWeek: dow,
+		domStar:    strings.HasPrefix(parts[2], "*"),
+		dowStar:    strings.HasPrefix(parts[4], "*"),
 	}, nil
 }
 
@@ -417,30 +652,90 @@ func parseField(field string, min, max int) ([]int, error) {
 	return result, nil
 }
 
-// nextAfter returns the next time after t that matches the cron fields.
+// nextAfter returns the next time after t that matches the cron fields, or
+// the zero time if no match exists within five years (e.g. "0 0 30 2 *").
+//
+// Rather than testing every minute, it advances field by field from the
+// largest unit down: when a field does not match, the time is bumped to the
+// start of the next value of that unit and all smaller units are reset.
+// When a unit wraps (e.g. the month rolls into a new year), the search
+// restarts from the top so larger fields are re-checked.
 func (cf cronFields) nextAfter(t time.Time) time.Time {
-	// Start from the next minute.
-	t = t.Add(time.Minute).Truncate(time.Minute)
-
-	// Try up to 4 years of minutes (safety bound).
-	limit := t.Add(4 * 365 * 24 * time.Hour)
-	for t.Before(limit) {
-		if cf.matches(t) {
-			return t
+	// Start from the next whole minute.
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	loc := t.Location()
+	yearLimit := t.Year() + 5
+
+	// added tracks whether t was moved forward, in which case smaller
+	// units must be reset to their minimum before incrementing.
+	added := false
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for !contains(cf.months, int(t.Month())) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !cf.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto wrap
 		}
+	}
+
+	for !contains(cf.hours, t.Hour()) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for !contains(cf.minutes, t.Minute()) {
+		added = true
 		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
 	}
-	// Fallback: should never happen with valid cron expressions.
+
 	return t
 }
 
+// dayMatches reports whether t satisfies the day-of-month and day-of-week
+// fields using standard cron semantics.
+func (cf cronFields) dayMatches(t time.Time) bool {
+	domMatch := contains(cf.daysOfMon, t.Day())
+	dowMatch := contains(cf.daysOfWeek, int(t.Weekday()))
+	if cf.domStar || cf.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
 // matches returns true if t matches all cron fields.
 func (cf cronFields) matches(t time.Time) bool {
 	return contains(cf.minutes, t.Minute()) &&
 		contains(cf.hours, t.Hour()) &&
-		contains(cf.daysOfMon, t.Day()) &&
 		contains(cf.months, int(t.Month())) &&
-		contains(cf.daysOfWeek, int(t.Weekday()))
+		cf.dayMatches(t)
 }
 
 func contains(vals []int, v int) bool {
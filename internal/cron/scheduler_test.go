@@ -2,8 +2,10 @@ package cron
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -98,6 +100,48 @@ func TestInvalidSchedule(t *testing.T) {
 	}
 }
 
+func TestUpdateJob(t *testing.T) {
+	s, _ := newTestScheduler(t, &mockProvider{response: "hello"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	id, err := s.AddJob("0 7 * * *", "morning digest", "telegram", "123")
+	if err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+
+	// Only the given fields change; the ID stays
+	job, err := s.UpdateJob(id, "0 8 * * *", "", "", "")
+	if err != nil {
+		t.Fatalf("UpdateJob: %v", err)
+	}
+	if job.ID != id || job.Schedule != "0 8 * * *" || job.Instruction != "morning digest" || job.ChatID != "123" {
+		t.Fatalf("updated job = %+v", job)
+	}
+
+	// An invalid schedule leaves the job untouched
+	if _, err := s.UpdateJob(id, "not a schedule", "evening digest", "", ""); err == nil {
+		t.Fatal("expected error for invalid schedule")
+	}
+	if _, err := s.UpdateJob("999", "", "x", "", ""); err == nil {
+		t.Fatal("expected error updating non-existent job")
+	}
+
+	// The change is persisted
+	s2 := NewScheduler(bus.NewMessageBus(10), &mockProvider{}, "test-model")
+	s2.SetPersistPath(s.persistPath)
+	if err := s2.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	jobs := s2.ListJobs()
+	if len(jobs) != 1 || jobs[0].Schedule != "0 8 * * *" || jobs[0].Instruction != "morning digest" {
+		t.Fatalf("reloaded jobs = %+v", jobs)
+	}
+}
+
 func TestPersistence(t *testing.T) {
 	persistPath := filepath.Join(t.TempDir(), "cron_jobs.json")
 
@@ -189,6 +233,75 @@ func TestJobFiring(t *testing.T) {
 	}
 }
 
+func TestRunning(t *testing.T) {
+	s, _ := newTestScheduler(t, &mockProvider{})
+	if s.Running() {
+		t.Error("scheduler is running before Start")
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !s.Running() {
+		t.Error("scheduler is not running after Start")
+	}
+	s.Stop()
+	if s.Running() {
+		t.Error("scheduler is running after Stop")
+	}
+}
+
+func TestSendLater(t *testing.T) {
+	s, msgBus := newTestScheduler(t, &mockProvider{err: errors.New("the model is not called")})
+
+	// Messages scheduled before Start are kept until it
+	past, err := s.SendLater(time.Now().Add(-time.Minute), "missed while down", "telegram", "42")
+	if err != nil {
+		t.Fatalf("SendLater: %v", err)
+	}
+	cancelled, _ := s.SendLater(time.Now().Add(time.Hour), "never sent", "telegram", "42")
+	if err := s.CancelMessage(cancelled); err != nil {
+		t.Fatalf("CancelMessage: %v", err)
+	}
+	if _, err := s.SendLater(time.Now(), " ", "telegram", "42"); err == nil {
+		t.Error("SendLater accepted an empty message")
+	}
+
+	// A restarted scheduler sends the overdue message at once
+	reloaded := NewScheduler(msgBus, &mockProvider{}, "test-model")
+	reloaded.SetPersistPath(s.persistPath)
+	if err := reloaded.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer reloaded.Stop()
+	if _, err := reloaded.SendLater(time.Now().Add(100*time.Millisecond), "call mom", "telegram", "42"); err != nil {
+		t.Fatalf("SendLater: %v", err)
+	}
+
+	var got []string
+	deadline := time.After(3 * time.Second)
+	for len(got) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("sent %v, want the overdue message and the reminder", got)
+		default:
+		}
+		if msgBus.OutboundSize() > 0 {
+			got = append(got, msgBus.ConsumeOutbound().Content)
+			continue
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got[0] != "missed while down" || got[1] != "call mom" {
+		t.Errorf("sent %v in the wrong order or with the wrong text", got)
+	}
+	if msgs := reloaded.ListMessages(); len(msgs) != 0 {
+		t.Errorf("messages left after sending: %+v", msgs)
+	}
+	if err := reloaded.CancelMessage(past); err == nil {
+		t.Error("CancelMessage found a sent message")
+	}
+}
+
 func TestCronFieldsParsing(t *testing.T) {
 	tests := []struct {
 		spec    string
@@ -248,6 +361,21 @@ func TestNextAfter(t *testing.T) {
 	}
 }
 
+func TestNextRun(t *testing.T) {
+	base := time.Date(2025, 6, 15, 9, 30, 0, 0, time.Local)
+	next, err := NextRun("0 8 * * *", base)
+	if err != nil || !next.Equal(time.Date(2025, 6, 16, 8, 0, 0, 0, time.Local)) {
+		t.Errorf("NextRun(cron) = %v, %v", next, err)
+	}
+	next, err = NextRun("@every 2h", base)
+	if err != nil || !next.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("NextRun(interval) = %v, %v", next, err)
+	}
+	if _, err := NextRun("0 8 * *", base); err == nil {
+		t.Error("NextRun accepted an invalid schedule")
+	}
+}
+
 func TestParseDuration(t *testing.T) {
 	d, err := parseDuration("@every 5m")
 	if err != nil {
@@ -270,3 +398,193 @@ func TestParseDuration(t *testing.T) {
 		t.Fatal("expected error for non-interval spec")
 	}
 }
+
+func TestRunHistoryAndFailureNotification(t *testing.T) {
+	provider := &mockProvider{err: errors.New("provider down")}
+	s, msgBus := newTestScheduler(t, provider)
+
+	job := Job{ID: "7", Schedule: "@every 1h", Instruction: "x", Channel: "telegram", ChatID: "42"}
+	for i := 0; i < failureNotifyThreshold; i++ {
+		s.fireJob(context.Background(), job)
+	}
+
+	h := s.History("7")
+	if h == nil || len(h.Runs) != failureNotifyThreshold {
+		t.Fatalf("expected %d runs recorded, got %+v", failureNotifyThreshold, h)
+	}
+	if h.ConsecutiveFailures != failureNotifyThreshold {
+		t.Errorf("expected %d consecutive failures, got %d", failureNotifyThreshold, h.ConsecutiveFailures)
+	}
+	if last := h.LastRun(); last.Success || last.Error == "" {
+		t.Errorf("expected failed last run with error, got %+v", last)
+	}
+	if msgBus.OutboundSize() != 1 {
+		t.Fatalf("expected exactly one failure notification, got %d", msgBus.OutboundSize())
+	}
+	if msg := msgBus.ConsumeOutbound(); msg.ChatID != "42" {
+		t.Errorf("notification sent to wrong chat %q", msg.ChatID)
+	}
+
+	// A success resets the failure counter.
+	provider.err = nil
+	provider.response = "all good"
+	s.fireJob(context.Background(), job)
+	if h := s.History("7"); h.ConsecutiveFailures != 0 || h.LastRun().Output != "all good" {
+		t.Errorf("expected reset after success, got %+v", h)
+	}
+
+	// History survives a reload.
+	s2 := NewScheduler(msgBus, provider, "test-model")
+	s2.SetPersistPath(s.persistPath)
+	if err := s2.loadHistory(); err != nil {
+		t.Fatalf("loadHistory: %v", err)
+	}
+	if h := s2.History("7"); h == nil || len(h.Runs) != failureNotifyThreshold+1 {
+		t.Fatalf("history not persisted: %+v", h)
+	}
+}
+
+func TestNextAfterMatchesBruteForce(t *testing.T) {
+	specs := []string{"*/7 3-5 * * *", "15 10 1,15 * *", "0 0 * * 1", "30 8 13 * 5", "0 12 * 2,8 *", "@hourly", "@weekly"}
+	base := time.Date(2025, 1, 30, 22, 41, 17, 0, time.UTC)
+
+	for _, spec := range specs {
+		fields, err := parseCronFields(spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", spec, err)
+		}
+
+		// Brute-force the expected next match minute by minute.
+		want := base.Add(time.Minute).Truncate(time.Minute)
+		for !fields.matches(want) {
+			want = want.Add(time.Minute)
+		}
+
+		if got := fields.nextAfter(base); !got.Equal(want) {
+			t.Errorf("%q: nextAfter = %s, want %s", spec, got, want)
+		}
+	}
+}
+
+func TestNamedSchedules(t *testing.T) {
+	base := time.Date(2025, 6, 15, 8, 30, 0, 0, time.UTC) // a Sunday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"@hourly", time.Date(2025, 6, 15, 9, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, 6, 22, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		fields, err := parseCronFields(tc.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.spec, err)
+		}
+		if got := fields.nextAfter(base); !got.Equal(tc.want) {
+			t.Errorf("%s: nextAfter = %s, want %s", tc.spec, got, tc.want)
+		}
+	}
+
+	if _, err := parseCronFields("@fortnightly"); err == nil {
+		t.Error("expected error for unknown descriptor")
+	}
+}
+
+func TestNextAfterSparseSchedules(t *testing.T) {
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	leap, _ := parseCronFields("0 0 29 2 *")
+	if got := leap.nextAfter(base); !got.Equal(time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Feb 29: got %s", got)
+	}
+
+	never, _ := parseCronFields("0 0 30 2 *")
+	if got := never.nextAfter(base); !got.IsZero() {
+		t.Errorf("Feb 30: expected zero time, got %s", got)
+	}
+
+	s, _ := newTestScheduler(t, &mockProvider{response: "x"})
+	if _, err := s.AddJob("0 0 30 2 *", "never", "cli", "1"); err == nil {
+		t.Error("expected AddJob to reject a schedule that never fires")
+	}
+	if _, err := s.AddJob("@daily", "digest", "cli", "1"); err != nil {
+		t.Errorf("AddJob(@daily): %v", err)
+	}
+}
+
+// toolProvider calls the "feeds" tool once, then answers with what the
+// tool returned.
+type toolProvider struct {
+	requests []providers.ChatRequest
+}
+
+func (p *toolProvider) Name() string         { return "tool" }
+func (p *toolProvider) DefaultModel() string { return "tool-model" }
+func (p *toolProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.requests = append(p.requests, req)
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role == "tool" {
+		return &providers.ChatResponse{Content: "Digest: " + last.Content.(string)}, nil
+	}
+	return &providers.ChatResponse{ToolCalls: []providers.ToolCall{
+		{ID: "call-1", Name: "feeds", Arguments: map[string]interface{}{"action": "check"}},
+	}}, nil
+}
+
+type fakeToolbox struct {
+	jobs []Job
+}
+
+func (f *fakeToolbox) Definitions() interface{} { return []string{"feeds"} }
+func (f *fakeToolbox) Execute(_ context.Context, job Job, call providers.ToolCall) string {
+	f.jobs = append(f.jobs, job)
+	return call.Name + " has 2 new items"
+}
+
+func TestJobToolbox(t *testing.T) {
+	provider := &toolProvider{}
+	s, msgBus := newTestScheduler(t, provider)
+	toolbox := &fakeToolbox{}
+	s.SetToolbox(toolbox)
+
+	job := Job{ID: "3", Schedule: "@daily", Instruction: "summarize my feeds", Channel: "telegram", ChatID: "42"}
+	s.fireJob(context.Background(), job)
+
+	if len(provider.requests) != 2 || provider.requests[0].Tools == nil {
+		t.Fatalf("requests = %+v", provider.requests)
+	}
+	if len(toolbox.jobs) != 1 || toolbox.jobs[0].ChatID != "42" {
+		t.Errorf("tool ran for %+v", toolbox.jobs)
+	}
+	if msg := msgBus.ConsumeOutbound(); msg.Content != "Digest: feeds has 2 new items" {
+		t.Errorf("published %q", msg.Content)
+	}
+}
+
+func TestSilentReply(t *testing.T) {
+	s, msgBus := newTestScheduler(t, &mockProvider{response: " " + SilentReply + "\n"})
+	job := Job{ID: "4", Schedule: "@every 5m", Instruction: "remind me before meetings", Channel: "telegram", ChatID: "42"}
+	s.fireJob(context.Background(), job)
+
+	// Anything the job published would come before this
+	msgBus.PublishOutbound(bus.OutboundMessage{Content: "marker"})
+	if msg := msgBus.ConsumeOutbound(); msg.Content != "marker" {
+		t.Errorf("published %q", msg.Content)
+	}
+	if h := s.History(job.ID); h == nil || len(h.Runs) != 1 || !h.Runs[0].Success {
+		t.Errorf("history = %+v", h)
+	}
+}
+
+func TestSnippetCutsByRunes(t *testing.T) {
+	if got := snippet("short"); got != "short" {
+		t.Errorf("snippet = %q, want %q", got, "short")
+	}
+	got := snippet(strings.Repeat("я", maxOutputSnippet+1))
+	if want := strings.Repeat("я", maxOutputSnippet) + "..."; got != want {
+		t.Errorf("snippet = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !lite && !nobrowser
+
+package doctor
+
+import "github.com/hkuds/ubot/internal/tools"
+
+// checkBrowser reports whether Chrome or Chromium is installed for the
+// browser_use tool.
+func checkBrowser() Result {
+	result := Result{Name: "Chrome"}
+	path, err := tools.FindChromeBinary()
+	if err != nil {
+		result.Status, result.Detail = Warn, "not found; the browser_use tool will not work"
+		result.Hint = "Install Google Chrome or Chromium"
+		return result
+	}
+	result.Status, result.Detail = Pass, programVersion(path, "--version")
+	return result
+}
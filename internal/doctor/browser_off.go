@@ -0,0 +1,14 @@
+//go:build lite || nobrowser
+
+package doctor
+
+// checkBrowser reports that the browser_use tool is not compiled into this
+// build.
+func checkBrowser() Result {
+	return Result{
+		Name:   "Chrome",
+		Status: Warn,
+		Detail: "the browser is not included in this build",
+		Hint:   "Use a full build for the browser_use tool",
+	}
+}
@@ -0,0 +1,260 @@
+// Package doctor checks the environment uBot runs in — its configuration,
+// the model provider, and the programs and services optional features rely
+// on — and suggests a fix for each problem it finds.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/failure"
+	"github.com/hkuds/ubot/internal/features"
+	"github.com/hkuds/ubot/internal/providers"
+	"github.com/hkuds/ubot/internal/sandbox"
+)
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	Pass Status = iota // everything is in order
+	Warn               // an optional feature will not work
+	Fail               // uBot will not work as configured
+)
+
+func (s Status) String() string {
+	switch s {
+	case Warn:
+		return "warn"
+	case Fail:
+		return "fail"
+	}
+	return "pass"
+}
+
+// MarshalText encodes the status as its name, e.g. in JSON output.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"` // what to do about a warning or failure
+}
+
+// checkTimeout bounds each check that talks to another service.
+const checkTimeout = 15 * time.Second
+
+// lookPath finds programs; tests replace it.
+var lookPath = exec.LookPath
+
+// Run runs all checks against the config file at path (the default config
+// when empty) and returns their results in order. The provider and MCP
+// checks are skipped when the config cannot be loaded.
+func Run(ctx context.Context, path string) []Result {
+	cfg, results := checkConfig(path)
+	if cfg != nil {
+		results = append(results, checkProvider(ctx, cfg))
+	}
+	results = append(results, checkDocker(), checkBrowser(), checkGit())
+	if cfg != nil {
+		results = append(results, checkNode(cfg))
+		results = append(results, checkMCP(ctx, cfg)...)
+	}
+	return results
+}
+
+// Failed reports whether any result is a failure.
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfig loads the config and reports whether it is usable. It
+// returns a nil config if the file cannot be read.
+func checkConfig(path string) (*config.Config, []Result) {
+	if path == "" {
+		path = config.GetConfigPath()
+	}
+	missing := !config.Exists(path)
+	// Environment overrides apply even without a file
+	cfg, err := config.LoadConfig(path)
+	var validationErr *config.ValidationError
+	if errors.As(err, &validationErr) {
+		results := make([]Result, 0, len(validationErr.Problems))
+		for _, p := range validationErr.Problems {
+			results = append(results, Result{
+				Name:   "Config",
+				Status: Fail,
+				Detail: p.String(),
+				Hint:   "Fix the setting in " + path + "; 'ubot config validate' checks it again",
+			})
+		}
+		return nil, results
+	}
+	if err != nil {
+		return nil, []Result{{
+			Name:   "Config",
+			Status: Fail,
+			Detail: err.Error(),
+			Hint:   "Fix the file, or run 'ubot setup' to write a new one",
+		}}
+	}
+	if missing {
+		return cfg, []Result{{
+			Name:   "Config",
+			Status: Warn,
+			Detail: "no config file at " + path + ", using defaults",
+			Hint:   "Run 'ubot setup' to create one",
+		}}
+	}
+
+	results := configProblems(cfg)
+	if len(results) == 0 {
+		results = []Result{{Name: "Config", Status: Pass, Detail: path}}
+	}
+	return cfg, results
+}
+
+// configProblems returns settings that are valid but leave uBot unusable
+// or exposed. Invalid settings are caught by LoadConfig.
+func configProblems(cfg *config.Config) []Result {
+	var results []Result
+	problem := func(status Status, detail, hint string) {
+		results = append(results, Result{Name: "Config", Status: status, Detail: detail, Hint: hint})
+	}
+
+	if name, _, _ := cfg.GetActiveProvider(); name == "" {
+		problem(Fail, "no LLM provider configured", "Run 'ubot setup' to configure a provider")
+	}
+
+	if tg := cfg.Channels.Telegram; tg.Enabled && len(tg.AllowFrom) == 0 {
+		problem(Warn, "Telegram accepts messages from anyone", "List your user ID in channels.telegram.allowFrom")
+	}
+	if cfg.Gateway.ControlAPI && cfg.Gateway.Token == "" {
+		problem(Warn, "the control API is enabled without a token", "Set gateway.token so only you can use it")
+	}
+	return results
+}
+
+// checkProvider sends the active provider the smallest possible request.
+func checkProvider(ctx context.Context, cfg *config.Config) Result {
+	result := Result{Name: "Provider"}
+	name, _, _ := cfg.GetActiveProvider()
+	if name == "" {
+		result.Status, result.Detail = Fail, "no provider to check"
+		result.Hint = "Run 'ubot setup' to configure a provider"
+		return result
+	}
+	provider, err := providers.NewProviderFromConfig(cfg)
+	if err != nil {
+		result.Status, result.Detail = Fail, err.Error()
+		result.Hint = "Run 'ubot setup' to configure the provider again"
+		return result
+	}
+
+	model := cfg.Agents.Defaults.Model
+	if model == "" {
+		model = provider.DefaultModel()
+	}
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	start := time.Now()
+	_, err = provider.Chat(ctx, providers.ChatRequest{
+		Messages:  []providers.ChatMessage{{Role: "user", Content: "ping"}},
+		Model:     cfg.Agents.Defaults.Model,
+		MaxTokens: 1,
+	})
+	if err == nil {
+		result.Status = Pass
+		result.Detail = fmt.Sprintf("%s (%s) answered in %s", name, model, time.Since(start).Round(time.Millisecond))
+		return result
+	}
+
+	result.Detail = fmt.Sprintf("%s: %v", name, err)
+	switch failure.Of(err) {
+	case failure.ProviderAuth:
+		result.Status, result.Hint = Fail, "Check the API key, or run 'ubot setup' to enter a new one"
+	case failure.Unreachable, failure.Timeout:
+		result.Status, result.Hint = Fail, "Check the network connection and the provider's apiBase"
+	case failure.RateLimit:
+		result.Status, result.Hint = Warn, "The provider is rate limiting requests; try again in a minute"
+	default:
+		// The API answered, so it is reachable, but it rejected the request
+		result.Status, result.Hint = Warn, "Check that the model "+model+" is available to your account"
+	}
+	return result
+}
+
+// checkDocker reports whether the Docker daemon can sandbox skill setup
+// scripts.
+func checkDocker() Result {
+	result := Result{Name: "Docker"}
+	switch {
+	case !features.Docker:
+		result.Status, result.Detail = Warn, "not included in this build"
+		result.Hint = "Use a full build to run skill setup scripts in a sandbox"
+	case sandbox.IsDockerAvailable():
+		result.Status, result.Detail = Pass, "daemon is reachable"
+	default:
+		result.Status, result.Detail = Warn, "daemon is not reachable; skill setup scripts will not run"
+		result.Hint = "Start Docker and make sure your user may access it (e.g. the docker group)"
+	}
+	return result
+}
+
+// checkGit reports whether git is installed for installing and updating
+// skills.
+func checkGit() Result {
+	result := Result{Name: "git"}
+	path, err := lookPath("git")
+	if err != nil {
+		result.Status, result.Detail = Warn, "not found; skills cannot be installed or updated"
+		result.Hint = "Install git"
+		return result
+	}
+	result.Status, result.Detail = Pass, programVersion(path, "--version")
+	return result
+}
+
+// checkNode reports whether Node.js is installed for the WhatsApp bridge.
+// It is only required when WhatsApp is enabled.
+func checkNode(cfg *config.Config) Result {
+	result := Result{Name: "Node.js"}
+	path, err := lookPath("node")
+	if err != nil {
+		result.Status, result.Detail = Warn, "not found; needed for the WhatsApp bridge"
+		if cfg.Channels.WhatsApp.Enabled {
+			result.Status = Fail
+		}
+		result.Hint = "Install Node.js 18 or newer"
+		return result
+	}
+	result.Status, result.Detail = Pass, programVersion(path, "--version")
+	return result
+}
+
+// programVersion returns the first line a program prints with the given
+// arguments, or its path if it prints nothing.
+func programVersion(path string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, args...).Output()
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if err != nil || version == "" {
+		return path
+	}
+	return version
+}
@@ -0,0 +1,109 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/testenv"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckConfig(t *testing.T) {
+	cfg, results := checkConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if cfg == nil || len(results) != 1 || results[0].Status != Warn {
+		t.Errorf("missing file: cfg = %v, results = %+v", cfg != nil, results)
+	}
+
+	cfg, results = checkConfig(writeConfig(t, "{not json"))
+	if cfg != nil || len(results) != 1 || results[0].Status != Fail || results[0].Hint == "" {
+		t.Errorf("invalid file: cfg = %v, results = %+v", cfg != nil, results)
+	}
+
+	cfg, results = checkConfig(writeConfig(t, `{"gateway": {"port": 0}, "cluster": {"role": "worker"}}`))
+	if cfg != nil || len(results) != 2 || results[0].Status != Fail || !strings.Contains(results[0].Detail, "cluster.redisUrl") {
+		t.Errorf("invalid settings: cfg = %v, results = %+v", cfg != nil, results)
+	}
+
+	path := writeConfig(t, `{"providers": {"openai": {"apiKey": "sk-test"}}}`)
+	cfg, results = checkConfig(path)
+	if cfg == nil || len(results) != 1 || results[0].Status != Pass || results[0].Detail != path {
+		t.Errorf("valid file: results = %+v", results)
+	}
+}
+
+func TestConfigProblems(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers.VLLM.APIBase = ""
+	cfg.Channels.Telegram.Enabled = true
+	cfg.Gateway.ControlAPI = true
+
+	results := configProblems(cfg)
+	if len(results) != 3 {
+		t.Fatalf("results = %+v", results)
+	}
+	if r := results[0]; r.Status != Fail || !strings.Contains(r.Detail, "no LLM provider") {
+		t.Errorf("missing provider: %+v", r)
+	}
+	if r := results[1]; r.Status != Warn || !strings.Contains(r.Detail, "anyone") {
+		t.Errorf("open Telegram bot: %+v", r)
+	}
+	if r := results[2]; r.Status != Warn || !strings.Contains(r.Detail, "control API") {
+		t.Errorf("control API without token: %+v", r)
+	}
+}
+
+func TestCheckProvider(t *testing.T) {
+	env := testenv.Start(t)
+	cfg := config.DefaultConfig()
+	cfg.Providers.VLLM = config.ProviderConfig{APIKey: "test", APIBase: env.Ollama.BaseURL()}
+	cfg.Agents.Defaults.Model = "stub-model"
+	if r := checkProvider(context.Background(), cfg); r.Status != Pass || !strings.Contains(r.Detail, "stub-model") {
+		t.Errorf("reachable provider: %+v", r)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid api key"}}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	cfg.Providers.VLLM.APIBase = srv.URL
+	if r := checkProvider(context.Background(), cfg); r.Status != Fail || !strings.Contains(r.Hint, "API key") {
+		t.Errorf("rejected key: %+v", r)
+	}
+
+	srv.Close()
+	if r := checkProvider(context.Background(), cfg); r.Status != Fail || !strings.Contains(r.Hint, "network") {
+		t.Errorf("unreachable provider: %+v", r)
+	}
+}
+
+func TestCheckNode(t *testing.T) {
+	defer func(orig func(string) (string, error)) { lookPath = orig }(lookPath)
+	lookPath = func(string) (string, error) { return "", errors.New("not found") }
+
+	cfg := config.DefaultConfig()
+	if r := checkNode(cfg); r.Status != Warn {
+		t.Errorf("without WhatsApp: %+v", r)
+	}
+	cfg.Channels.WhatsApp.Enabled = true
+	if r := checkNode(cfg); r.Status != Fail || r.Hint == "" {
+		t.Errorf("with WhatsApp: %+v", r)
+	}
+	if r := checkGit(); r.Status != Warn {
+		t.Errorf("git: %+v", r)
+	}
+}
@@ -0,0 +1,58 @@
+//go:build !lite && !nomcp
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/mcp"
+)
+
+// checkMCP connects to each configured MCP server and lists its tools.
+func checkMCP(ctx context.Context, cfg *config.Config) []Result {
+	if len(cfg.MCP.Servers) == 0 {
+		return []Result{{Name: "MCP", Status: Pass, Detail: "no servers configured"}}
+	}
+
+	results := make([]Result, 0, len(cfg.MCP.Servers))
+	for _, serverCfg := range cfg.MCP.Servers {
+		results = append(results, checkMCPServer(ctx, serverCfg))
+	}
+	return results
+}
+
+// checkMCPServer connects to one MCP server and lists its tools.
+func checkMCPServer(ctx context.Context, serverCfg config.MCPServerConfig) Result {
+	result := Result{Name: "MCP " + serverCfg.Name}
+	client := mcp.NewClient(mcp.Server{
+		Name:      serverCfg.Name,
+		Command:   serverCfg.Command,
+		Args:      serverCfg.Args,
+		URL:       serverCfg.URL,
+		Transport: serverCfg.Transport,
+		Env:       serverCfg.Env,
+		Headers:   serverCfg.Headers,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	err := client.Connect(ctx)
+	if err == nil {
+		defer client.Disconnect()
+		var tools []mcp.Tool
+		if tools, err = client.ListTools(ctx); err == nil {
+			result.Status, result.Detail = Pass, fmt.Sprintf("connected, %d tool(s)", len(tools))
+			return result
+		}
+	}
+
+	result.Status, result.Detail = Fail, err.Error()
+	if serverCfg.URL != "" {
+		result.Hint = "Check that the server is running at " + serverCfg.URL
+	} else {
+		result.Hint = fmt.Sprintf("Check that %q is installed and starts an MCP server", serverCfg.Command)
+	}
+	return result
+}
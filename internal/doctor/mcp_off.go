@@ -0,0 +1,24 @@
+//go:build lite || nomcp
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// checkMCP reports configured MCP servers as unusable: MCP support is not
+// compiled into this build.
+func checkMCP(ctx context.Context, cfg *config.Config) []Result {
+	if len(cfg.MCP.Servers) == 0 {
+		return []Result{{Name: "MCP", Status: Pass, Detail: "no servers configured"}}
+	}
+	return []Result{{
+		Name:   "MCP",
+		Status: Fail,
+		Detail: fmt.Sprintf("%d server(s) configured, but MCP is not included in this build", len(cfg.MCP.Servers)),
+		Hint:   "Use a full build, or remove the servers from mcp.servers",
+	}}
+}
@@ -0,0 +1,32 @@
+//go:build !lite && !nomcp
+
+package doctor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/testenv"
+)
+
+func TestCheckMCP(t *testing.T) {
+	env := testenv.Start(t)
+	cfg := config.DefaultConfig()
+	cfg.MCP.Servers = []config.MCPServerConfig{
+		{Name: "fake", Transport: "http", URL: env.MCP.ServerURL()},
+		{Name: "missing", Command: "ubot-no-such-mcp-server"},
+	}
+
+	results := checkMCP(context.Background(), cfg)
+	if len(results) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	if r := results[0]; r.Name != "MCP fake" || r.Status != Pass || !strings.Contains(r.Detail, "2 tool(s)") {
+		t.Errorf("reachable server: %+v", r)
+	}
+	if r := results[1]; r.Status != Fail || !strings.Contains(r.Hint, "ubot-no-such-mcp-server") {
+		t.Errorf("missing server: %+v", r)
+	}
+}
@@ -0,0 +1,98 @@
+// Package failure sorts errors into a few categories a user can act on and
+// describes each in plain words with a hint for fixing it, so chats never
+// see a bare "an error occurred".
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Category is the kind of failure, as far as the user is concerned.
+type Category int
+
+const (
+	Internal     Category = iota // anything not listed below
+	ProviderAuth                 // the provider rejected the API key or token
+	RateLimit                    // the provider is throttling requests
+	ToolBlocked                  // a security rule or the user stopped a tool
+	Timeout                      // something took too long
+	Unreachable                  // the provider could not be reached
+)
+
+func (c Category) String() string {
+	switch c {
+	case ProviderAuth:
+		return "provider auth"
+	case RateLimit:
+		return "rate limit"
+	case ToolBlocked:
+		return "tool blocked"
+	case Timeout:
+		return "timeout"
+	case Unreachable:
+		return "unreachable"
+	}
+	return "internal"
+}
+
+// Categorized is implemented by errors that know their category, such as
+// provider status errors and blocked tool calls.
+type Categorized interface {
+	Category() Category
+}
+
+// categorized is an error created by New.
+type categorized struct {
+	category Category
+	msg      string
+}
+
+func (e *categorized) Error() string      { return e.msg }
+func (e *categorized) Category() Category { return e.category }
+
+// New returns an error with the given category and text.
+func New(category Category, msg string) error {
+	return &categorized{category: category, msg: msg}
+}
+
+// Of returns the category of err, looking through wrapped errors.
+func Of(err error) Category {
+	if err == nil {
+		return Internal
+	}
+	var c Categorized
+	if errors.As(err, &c) {
+		return c.Category()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Timeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return Timeout
+		}
+		return Unreachable
+	}
+	return Internal
+}
+
+// Message describes err for the user, with a hint for what to do about it.
+func Message(err error) string {
+	switch Of(err) {
+	case ProviderAuth:
+		return "The model provider rejected the API key. Run `ubot rootchat` to update it, or edit ~/.ubot/config.json and restart."
+	case RateLimit:
+		return "The model provider is rate limiting requests. Please wait a minute and try again, or switch to another model."
+	case ToolBlocked:
+		return fmt.Sprintf("That action was blocked (%v). Ask for something else, or change the security settings if it should be allowed.", err)
+	case Timeout:
+		return "The request took too long and timed out. Please try again, perhaps with a smaller request."
+	case Unreachable:
+		return "I couldn't reach the model provider. Check the network connection and try again in a moment."
+	}
+	return "Something went wrong while processing your request. Please try again; details are in the logs (`ubot logs`)."
+}
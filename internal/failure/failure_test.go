@@ -0,0 +1,42 @@
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Category
+	}{
+		{"nil", nil, Internal},
+		{"plain", errors.New("boom"), Internal},
+		{"categorized", New(RateLimit, "slow down"), RateLimit},
+		{"wrapped", fmt.Errorf("chat: %w", New(ProviderAuth, "bad key")), ProviderAuth},
+		{"deadline", fmt.Errorf("chat: %w", context.DeadlineExceeded), Timeout},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, Unreachable},
+	}
+	for _, tt := range tests {
+		if got := Of(tt.err); got != tt.want {
+			t.Errorf("%s: Of = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if msg := Message(New(ProviderAuth, "401")); !strings.Contains(msg, "ubot rootchat") {
+		t.Errorf("auth message has no remediation hint: %q", msg)
+	}
+	if msg := Message(New(ToolBlocked, "command blocked: rm -rf /")); !strings.Contains(msg, "rm -rf /") {
+		t.Errorf("blocked message does not say what was blocked: %q", msg)
+	}
+	if msg := Message(errors.New("boom")); strings.Contains(msg, "boom") {
+		t.Errorf("internal message leaks details: %q", msg)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !lite && !nobrowser
+
+package features
+
+// Browser reports whether the headless browser tool is compiled in.
+const Browser = true
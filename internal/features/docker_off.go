@@ -0,0 +1,6 @@
+//go:build lite || nodocker
+
+package features
+
+// Docker reports whether Docker sandboxing is compiled in.
+const Docker = false
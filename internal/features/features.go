@@ -0,0 +1,45 @@
+// Package features reports which optional subsystems are compiled into this
+// build. Building with -tags lite leaves out Docker sandboxing, the headless
+// browser, MCP, the SQLite session store and trace export for small devices
+// such as routers and NAS boxes; the tags nodocker, nobrowser, nomcp,
+// nosqlite and notracing drop them individually.
+package features
+
+import "strings"
+
+// Feature describes an optional subsystem.
+type Feature struct {
+	Name    string
+	Enabled bool
+}
+
+// All returns the optional subsystems and whether each is in this build.
+func All() []Feature {
+	return []Feature{
+		{Name: "docker", Enabled: Docker},
+		{Name: "browser", Enabled: Browser},
+		{Name: "mcp", Enabled: MCP},
+		{Name: "sqlite", Enabled: SQLite},
+		{Name: "tracing", Enabled: Tracing},
+	}
+}
+
+// Missing returns the names of the subsystems left out of this build.
+func Missing() []string {
+	var missing []string
+	for _, f := range All() {
+		if !f.Enabled {
+			missing = append(missing, f.Name)
+		}
+	}
+	return missing
+}
+
+// Summary describes the build, e.g. "full" or "lite (without docker, mcp)".
+func Summary() string {
+	missing := Missing()
+	if len(missing) == 0 {
+		return "full"
+	}
+	return "lite (without " + strings.Join(missing, ", ") + ")"
+}
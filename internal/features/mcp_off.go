@@ -0,0 +1,6 @@
+//go:build lite || nomcp
+
+package features
+
+// MCP reports whether MCP server support is compiled in.
+const MCP = false
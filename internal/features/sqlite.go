@@ -0,0 +1,6 @@
+//go:build !lite && !nosqlite
+
+package features
+
+// SQLite reports whether the SQLite session store is compiled in.
+const SQLite = true
@@ -0,0 +1,6 @@
+//go:build lite || notracing
+
+package features
+
+// Tracing reports whether OpenTelemetry trace export is compiled in.
+const Tracing = false
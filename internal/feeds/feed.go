@@ -0,0 +1,267 @@
+// Package feeds reads RSS and Atom feeds and remembers which of their items
+// a conversation has already seen, so that each check returns only what is
+// new. Subscriptions are stored in the workspace and shared by the CLI and
+// the gateway.
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxFeedBytes is the largest feed document Fetch reads.
+const maxFeedBytes = 5 << 20
+
+// maxSummaryChars is the length item summaries are cut to.
+const maxSummaryChars = 300
+
+// Feed is a parsed RSS or Atom document.
+type Feed struct {
+	Title string
+	Items []Item
+}
+
+// Item is one entry of a feed.
+type Item struct {
+	ID        string // guid or Atom id, else the link
+	Title     string
+	Link      string
+	Published time.Time // zero when the feed gives no date
+	Summary   string    // plain text, shortened
+}
+
+// xmlFeed covers RSS 2.0 (<rss><channel><item>), RSS 1.0 (<rdf:RDF><item>)
+// and Atom (<feed><entry>); elements are matched by local name.
+type xmlFeed struct {
+	XMLName xml.Name
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []xmlItem `xml:"item"`
+	} `xml:"channel"`
+	Title   string     `xml:"title"`
+	Items   []xmlItem  `xml:"item"`
+	Entries []xmlEntry `xml:"entry"`
+}
+
+type xmlItem struct {
+	Title       string   `xml:"title"`
+	Links       []string `xml:"link"` // atom:link elements are empty here
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"date"` // dc:date in RSS 1.0
+	Description string   `xml:"description"`
+}
+
+type xmlEntry struct {
+	Title     string    `xml:"title"`
+	Links     []xmlLink `xml:"link"`
+	ID        string    `xml:"id"`
+	Updated   string    `xml:"updated"`
+	Published string    `xml:"published"`
+	Summary   string    `xml:"summary"`
+	Content   string    `xml:"content"`
+}
+
+type xmlLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// Parse reads an RSS or Atom document.
+func Parse(data []byte) (*Feed, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = charsetReader
+
+	var doc xmlFeed
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("not an RSS or Atom feed: %w", err)
+	}
+
+	feed := &Feed{}
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss":
+		feed.Title = doc.Channel.Title
+		feed.Items = rssItems(doc.Channel.Items)
+	case "rdf":
+		feed.Title = doc.Channel.Title
+		feed.Items = rssItems(doc.Items)
+	case "feed":
+		feed.Title = doc.Title
+		for _, e := range doc.Entries {
+			feed.Items = append(feed.Items, e.item())
+		}
+	default:
+		return nil, fmt.Errorf("not an RSS or Atom feed: root element is <%s>", doc.XMLName.Local)
+	}
+	feed.Title = cleanText(feed.Title)
+	return feed, nil
+}
+
+func rssItems(items []xmlItem) []Item {
+	out := make([]Item, 0, len(items))
+	for _, it := range items {
+		item := Item{
+			ID:        strings.TrimSpace(it.GUID),
+			Title:     cleanText(it.Title),
+			Link:      firstText(it.Links),
+			Published: parseDate(it.PubDate, it.Date),
+			Summary:   summarize(it.Description),
+		}
+		out = append(out, item.withID())
+	}
+	return out
+}
+
+func (e xmlEntry) item() Item {
+	summary := e.Summary
+	if strings.TrimSpace(summary) == "" {
+		summary = e.Content
+	}
+	item := Item{
+		ID:        strings.TrimSpace(e.ID),
+		Title:     cleanText(e.Title),
+		Link:      e.link(),
+		Published: parseDate(e.Published, e.Updated),
+		Summary:   summarize(summary),
+	}
+	return item.withID()
+}
+
+// link returns the entry's alternate link, the one pointing at the page.
+func (e xmlEntry) link() string {
+	for _, l := range e.Links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	if len(e.Links) > 0 {
+		return strings.TrimSpace(e.Links[0].Href)
+	}
+	return ""
+}
+
+// firstText returns the first of values that is not blank.
+func firstText(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// withID fills in an ID for items whose feed gives none.
+func (it Item) withID() Item {
+	if it.ID == "" {
+		it.ID = it.Link
+	}
+	if it.ID == "" {
+		it.ID = it.Title + "|" + it.Published.Format(time.RFC3339)
+	}
+	return it
+}
+
+// dateLayouts are the date formats found in feeds, RFC 822 variants first.
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate returns the first of values that parses as a date.
+func parseDate(values ...string) time.Time {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// cleanText turns feed text, which may hold HTML, into one line of plain
+// text.
+func cleanText(s string) string {
+	s = tagPattern.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// summarize returns the plain text of an item description, shortened.
+func summarize(s string) string {
+	s = cleanText(s)
+	if utf8.RuneCountInString(s) <= maxSummaryChars {
+		return s
+	}
+	r := []rune(s)
+	return strings.TrimSpace(string(r[:maxSummaryChars-1])) + "…"
+}
+
+// charsetReader decodes the Latin-1 feeds still found in the wild; UTF-8
+// is handled by the decoder itself.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "iso-8859-1", "latin1", "latin-1", "us-ascii", "windows-1252", "cp1252":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", label)
+}
+
+// Fetch downloads and parses the feed at url.
+func Fetch(ctx context.Context, client *http.Client, url string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "uBot/1.0 (feed reader)")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch feed: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	if len(data) > maxFeedBytes {
+		return nil, fmt.Errorf("feed is larger than %d MB", maxFeedBytes>>20)
+	}
+	return Parse(data)
+}
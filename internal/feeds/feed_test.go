@@ -0,0 +1,135 @@
+package feeds
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Go Blog</title>
+  <atom:link href="https://go.dev/blog/feed.rss" rel="self"/>
+  <item>
+    <title>Go 1.99 is released</title>
+    <link>https://go.dev/blog/go1.99</link>
+    <atom:link href="https://go.dev/blog/go1.99.rss" rel="self"/>
+    <guid>tag:go.dev,2026:1.99</guid>
+    <pubDate>Tue, 13 Oct 2026 09:00:00 +0000</pubDate>
+    <description>&lt;p&gt;Faster &amp;amp; smaller&amp;nbsp;builds.&lt;/p&gt;</description>
+  </item>
+  <item>
+    <title>No guid here</title>
+    <link>https://go.dev/blog/no-guid</link>
+  </item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title type="text">Example Atom</title>
+  <entry>
+    <title>First post</title>
+    <link rel="edit" href="https://example.com/edit/1"/>
+    <link rel="alternate" href="https://example.com/posts/1"/>
+    <id>urn:uuid:1</id>
+    <updated>2026-10-12T08:30:00Z</updated>
+    <content type="html">&lt;b&gt;Hello&lt;/b&gt; world</content>
+  </entry>
+</feed>`
+
+const rdfFeed = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel><title>Caf` + "\xe9" + ` News</title></channel>
+  <item>
+    <title>Old school</title>
+    <link>https://example.org/1</link>
+    <dc:date>2026-10-01T07:00:00Z</dc:date>
+  </item>
+</rdf:RDF>`
+
+func TestParseRSS(t *testing.T) {
+	feed, err := Parse([]byte(rssFeed))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if feed.Title != "Go Blog" || len(feed.Items) != 2 {
+		t.Fatalf("feed = %+v", feed)
+	}
+	first := feed.Items[0]
+	if first.ID != "tag:go.dev,2026:1.99" || first.Link != "https://go.dev/blog/go1.99" {
+		t.Errorf("first = %+v", first)
+	}
+	if first.Summary != "Faster & smaller builds." {
+		t.Errorf("summary = %q", first.Summary)
+	}
+	if want := time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC); !first.Published.Equal(want) {
+		t.Errorf("published = %v, want %v", first.Published, want)
+	}
+	if feed.Items[1].ID != "https://go.dev/blog/no-guid" {
+		t.Errorf("item without guid has ID %q, want its link", feed.Items[1].ID)
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	feed, err := Parse([]byte(atomFeed))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if feed.Title != "Example Atom" || len(feed.Items) != 1 {
+		t.Fatalf("feed = %+v", feed)
+	}
+	item := feed.Items[0]
+	if item.ID != "urn:uuid:1" || item.Link != "https://example.com/posts/1" || item.Summary != "Hello world" {
+		t.Errorf("item = %+v", item)
+	}
+	if item.Published.IsZero() {
+		t.Error("updated date not parsed")
+	}
+}
+
+func TestParseRDF(t *testing.T) {
+	feed, err := Parse([]byte(rdfFeed))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if feed.Title != "Café News" || len(feed.Items) != 1 || feed.Items[0].Published.IsZero() {
+		t.Fatalf("feed = %+v", feed)
+	}
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	for _, doc := range []string{"<html><body>hi</body></html>", "not xml at all", ""} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Parse(%q) succeeded", doc)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(rssFeed))
+	}))
+	defer srv.Close()
+
+	feed, err := Fetch(context.Background(), srv.Client(), srv.URL+"/feed")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(feed.Items) != 2 {
+		t.Errorf("got %d items, want 2", len(feed.Items))
+	}
+
+	if _, err := Fetch(context.Background(), srv.Client(), srv.URL+"/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Fetch of a missing feed: err = %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package feeds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSeen is how many item IDs are remembered per subscription, enough for
+// the items a feed lists at once and those it recently dropped.
+const maxSeen = 500
+
+// Subscription is a feed followed by one conversation.
+type Subscription struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Owner       string    `json:"owner"` // session key of the conversation
+	Added       time.Time `json:"added"`
+	LastChecked time.Time `json:"last_checked,omitempty"`
+	Seen        []string  `json:"seen,omitempty"` // item IDs, newest first
+}
+
+// NewItems returns the items of a fetched feed that the subscription has
+// not seen, in feed order.
+func (sub Subscription) NewItems(items []Item) []Item {
+	seen := make(map[string]bool, len(sub.Seen))
+	for _, id := range sub.Seen {
+		seen[id] = true
+	}
+	var fresh []Item
+	for _, it := range items {
+		if !seen[it.ID] {
+			fresh = append(fresh, it)
+		}
+	}
+	return fresh
+}
+
+// Store keeps subscriptions in a JSON file. Every operation reads the file
+// and writes it back, so processes sharing a workspace see each other's
+// changes.
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+type storeFile struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// NewStore creates a Store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// List returns owner's subscriptions in the order they were added.
+func (s *Store) List(owner string) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var subs []Subscription
+	for _, sub := range state.Subscriptions {
+		if sub.Owner == owner {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+// Find returns owner's subscription matching ref, a name or URL. Names are
+// compared without regard to case.
+func (s *Store) Find(owner, ref string) (Subscription, bool, error) {
+	subs, err := s.List(owner)
+	if err != nil {
+		return Subscription{}, false, err
+	}
+	for _, sub := range subs {
+		if sub.matches(ref) {
+			return sub, true, nil
+		}
+	}
+	return Subscription{}, false, nil
+}
+
+// Add stores a new subscription. Its name and URL must not already be used
+// by the owner.
+func (s *Store) Add(sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, other := range state.Subscriptions {
+		if other.Owner == sub.Owner && (other.matches(sub.Name) || other.URL == sub.URL) {
+			return fmt.Errorf("already subscribed as %q (%s)", other.Name, other.URL)
+		}
+	}
+	if sub.Added.IsZero() {
+		sub.Added = time.Now()
+	}
+	state.Subscriptions = append(state.Subscriptions, sub)
+	return s.save(state)
+}
+
+// Remove deletes owner's subscription matching ref, a name or URL, and
+// returns it.
+func (s *Store) Remove(owner, ref string) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return Subscription{}, err
+	}
+	for i, sub := range state.Subscriptions {
+		if sub.Owner == owner && sub.matches(ref) {
+			state.Subscriptions = append(state.Subscriptions[:i], state.Subscriptions[i+1:]...)
+			return sub, s.save(state)
+		}
+	}
+	return Subscription{}, fmt.Errorf("no feed named %q", ref)
+}
+
+// MarkSeen records that owner has seen the items of the named feed and when
+// it was checked. IDs seen earlier are kept, up to maxSeen in all, so items
+// that drop out of the feed and come back are not reported again.
+func (s *Store) MarkSeen(owner, name string, items []Item, checked time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+	for i := range state.Subscriptions {
+		sub := &state.Subscriptions[i]
+		if sub.Owner != owner || sub.Name != name {
+			continue
+		}
+		sub.Seen = mergeSeen(items, sub.Seen)
+		sub.LastChecked = checked
+		return s.save(state)
+	}
+	return fmt.Errorf("no feed named %q", name)
+}
+
+// mergeSeen puts the IDs of items before the earlier ones, dropping
+// duplicates and the oldest beyond maxSeen.
+func mergeSeen(items []Item, earlier []string) []string {
+	merged := make([]string, 0, len(items)+len(earlier))
+	have := make(map[string]bool, cap(merged))
+	for _, it := range items {
+		if !have[it.ID] {
+			have[it.ID] = true
+			merged = append(merged, it.ID)
+		}
+	}
+	for _, id := range earlier {
+		if !have[id] {
+			have[id] = true
+			merged = append(merged, id)
+		}
+	}
+	if len(merged) > maxSeen {
+		merged = merged[:maxSeen]
+	}
+	return merged
+}
+
+func (sub Subscription) matches(ref string) bool {
+	return strings.EqualFold(sub.Name, ref) || sub.URL == ref
+}
+
+func (s *Store) load() (storeFile, error) {
+	var state storeFile
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read feeds: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return state, nil
+}
+
+func (s *Store) save(state storeFile) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to save feeds: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to save feeds: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save feeds: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package feeds
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feeds.json")
+	store := NewStore(path)
+	const owner = "telegram:42"
+
+	sub := Subscription{Name: "Go Blog", URL: "https://go.dev/blog/feed.atom", Owner: owner}
+	if err := store.Add(sub); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	for _, dup := range []Subscription{
+		{Name: "go blog", URL: "https://other.example/feed", Owner: owner},
+		{Name: "Other", URL: sub.URL, Owner: owner},
+	} {
+		if err := store.Add(dup); err == nil {
+			t.Errorf("Add(%+v) succeeded for a duplicate", dup)
+		}
+	}
+	// Another conversation may follow the same feed
+	if err := store.Add(Subscription{Name: "Go Blog", URL: sub.URL, Owner: "cli:default"}); err != nil {
+		t.Fatalf("Add for another owner: %v", err)
+	}
+
+	items := []Item{{ID: "a"}, {ID: "b"}}
+	if err := store.MarkSeen(owner, "Go Blog", items, time.Now()); err != nil {
+		t.Fatalf("MarkSeen: %v", err)
+	}
+
+	// A new Store reads what the first one wrote
+	got, ok, err := NewStore(path).Find(owner, "GO BLOG")
+	if err != nil || !ok {
+		t.Fatalf("Find: %v, %v", ok, err)
+	}
+	if got.LastChecked.IsZero() || got.Added.IsZero() {
+		t.Errorf("times not stored: %+v", got)
+	}
+	fresh := got.NewItems([]Item{{ID: "c"}, {ID: "a"}, {ID: "b"}})
+	if len(fresh) != 1 || fresh[0].ID != "c" {
+		t.Errorf("NewItems = %+v, want only c", fresh)
+	}
+	if other, _ := store.List("cli:default"); len(other) != 1 || len(other[0].Seen) != 0 {
+		t.Errorf("other owner's subscription changed: %+v", other)
+	}
+
+	if _, err := store.Remove(owner, sub.URL); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if subs, _ := store.List(owner); len(subs) != 0 {
+		t.Errorf("subscriptions after Remove = %+v", subs)
+	}
+	if _, err := store.Remove(owner, "Go Blog"); err == nil {
+		t.Error("Remove of a missing feed succeeded")
+	}
+}
+
+func TestMergeSeenKeepsNewest(t *testing.T) {
+	earlier := make([]string, maxSeen)
+	for i := range earlier {
+		earlier[i] = fmt.Sprint("old", i)
+	}
+	merged := mergeSeen([]Item{{ID: "new"}, {ID: "old0"}}, earlier)
+	if len(merged) != maxSeen || merged[0] != "new" || merged[1] != "old0" {
+		t.Fatalf("merged starts %v, len %d", merged[:3], len(merged))
+	}
+	if merged[maxSeen-1] != fmt.Sprint("old", maxSeen-2) {
+		t.Errorf("last kept = %q", merged[maxSeen-1])
+	}
+}
@@ -1,9 +1,6 @@
 package mcp
 
-import (
-	"context"
-	"fmt"
-)
+import "context"
 
 // MCPToolBridge wraps an MCP tool as a uBot tool, allowing MCP tools
 // to be used seamlessly within the uBot tool system.
@@ -11,6 +8,7 @@ type MCPToolBridge struct {
 	manager    *Manager
 	serverName string
 	tool       Tool
+	name       string // Name registered under; see Naming
 }
 
 // NewMCPToolBridge creates a new bridge for an MCP tool.
@@ -22,10 +20,19 @@ func NewMCPToolBridge(manager *Manager, serverName string, tool Tool) *MCPToolBr
 	}
 }
 
-// Name returns the tool's identifier.
-// The name is prefixed with "mcp_<serverName>_" to avoid conflicts.
+// Name returns the tool's identifier: the name the manager registered it
+// under, or mcp_<serverName>_<tool> before that.
 func (b *MCPToolBridge) Name() string {
-	return fmt.Sprintf("mcp_%s_%s", b.serverName, b.tool.Name)
+	if b.name != "" {
+		return b.name
+	}
+	return NamespacedName(b.serverName, b.tool.Name)
+}
+
+// ID returns the tool's identity as <serverName>.<tool>, whatever name it
+// is registered under.
+func (b *MCPToolBridge) ID() string {
+	return b.serverName + "." + b.tool.Name
 }
 
 // Description returns a human-readable description for the LLM.
@@ -1,46 +1,87 @@
 package mcp
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"os"
 	"os/exec"
 	"sync"
 	"time"
+
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+const (
+	// requestTimeout bounds a single request to an HTTP-based server.
+	requestTimeout = 30 * time.Second
+
+	// reconnectMinDelay and reconnectMaxDelay bound the backoff between
+	// attempts to re-establish a dropped event stream.
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
 )
 
+// NotificationHandler receives notifications pushed by an MCP server, such as
+// "notifications/tools/list_changed".
+type NotificationHandler func(method string, params json.RawMessage)
+
 // Client manages a connection to an MCP server.
 type Client struct {
-	server  Server
-	process *exec.Cmd      // For stdio transport
-	stdin   io.WriteCloser // stdin pipe to process
-	stdout  io.ReadCloser  // stdout pipe from process
-	scanner *bufio.Scanner // Scanner for reading responses
-	client  *http.Client   // For HTTP transport
-	tools   []Tool         // Cached list of tools
-	nextID  int            // Request ID counter
-	mu      sync.Mutex     // Protects nextID and tools
+	server     Server
+	process    *exec.Cmd      // For stdio transport
+	exited     chan struct{}  // Closed when the stdio process exits
+	readerDone chan struct{}  // Closed when the stdio reader stops
+	writeMu    sync.Mutex     // Serializes writes to stdin
+	stdin      io.WriteCloser // stdin pipe to process
+	stdout     io.ReadCloser  // stdout pipe from process
+	client     *http.Client   // For HTTP transport
+	stream     *http.Client   // For long-lived event streams (no timeout)
+	tools      []Tool         // Cached list of tools
+	nextID     int            // Request ID counter
+	mu         sync.Mutex     // Protects nextID and tools
+
+	onNotify NotificationHandler
+
+	pendingMu sync.Mutex            // Protects pending
+	pending   map[int]chan Response // stdio/SSE: requests awaiting a reply from the reader
+
+	httpMu          sync.Mutex         // Protects the fields below
+	sessionID       string             // Session ID assigned by a streamable HTTP server
+	protocolVersion string             // Protocol version negotiated during initialize
+	postURL         string             // SSE: message endpoint announced by the server
+	streamCancel    context.CancelFunc // Stops background event streams
 
 	connected bool
 	connMu    sync.RWMutex
 }
 
 // NewClient creates a new MCP client for the given server configuration.
+// "streamable-http" is accepted as an alias for the "http" transport.
 func NewClient(server Server) *Client {
+	if server.Transport == "streamable-http" {
+		server.Transport = "http"
+	}
 	return &Client{
 		server: server,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		nextID: 1,
+		// Only the configured server may be on an internal address, not
+		// endpoints or redirects it points to
+		client:  safenet.NewClient(requestTimeout, safenet.URLHost(server.URL)),
+		stream:  safenet.NewClient(0, safenet.URLHost(server.URL)),
+		nextID:  1,
+		pending: make(map[int]chan Response),
 	}
 }
 
+// SetNotificationHandler sets the function called for notifications pushed
+// by the server. It must be set before Connect.
+func (c *Client) SetNotificationHandler(h NotificationHandler) {
+	c.onNotify = h
+}
+
 // Connect establishes a connection to the MCP server.
 func (c *Client) Connect(ctx context.Context) error {
 	c.connMu.Lock()
@@ -56,6 +97,8 @@ func (c *Client) Connect(ctx context.Context) error {
 		err = c.connectStdio(ctx)
 	case "http":
 		err = c.connectHTTP(ctx)
+	case "sse":
+		err = c.connectSSE(ctx)
 	default:
 		return fmt.Errorf("unsupported transport: %s", c.server.Transport)
 	}
@@ -68,67 +111,14 @@ func (c *Client) Connect(ctx context.Context) error {
 	return nil
 }
 
-// connectStdio establishes a stdio connection by spawning the MCP server process.
-func (c *Client) connectStdio(ctx context.Context) error {
-	// Build command
-	c.process = exec.CommandContext(ctx, c.server.Command, c.server.Args...)
-
-	// Set a minimal environment instead of inheriting the full parent environment
-	var env []string
-	for _, key := range []string{"PATH", "HOME", "LANG", "USER", "TERM", "SHELL", "TMPDIR", "XDG_RUNTIME_DIR", "NODE_PATH"} {
-		if val, ok := os.LookupEnv(key); ok {
-			env = append(env, fmt.Sprintf("%s=%s", key, val))
-		}
-	}
-	// Add explicitly configured env vars
-	for k, v := range c.server.Env {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
-	}
-	c.process.Env = env
-
-	// Set up pipes
-	var err error
-	c.stdin, err = c.process.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdin pipe: %w", err)
-	}
-
-	c.stdout, err = c.process.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-
-	// Create scanner for reading line-by-line responses
-	c.scanner = bufio.NewScanner(c.stdout)
-	c.scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line size
-
-	// Start the process
-	if err := c.process.Start(); err != nil {
-		return fmt.Errorf("failed to start MCP server: %w", err)
-	}
-
-	// Send initialize request
-	if err := c.initialize(ctx); err != nil {
-		c.Disconnect()
-		return fmt.Errorf("failed to initialize MCP server: %w", err)
-	}
-
-	return nil
-}
-
-// connectHTTP establishes an HTTP connection to the MCP server.
-func (c *Client) connectHTTP(ctx context.Context) error {
-	// For HTTP, we just need to verify the server is reachable
-	if err := c.initialize(ctx); err != nil {
-		return fmt.Errorf("failed to initialize HTTP MCP server: %w", err)
-	}
-	return nil
-}
-
 // initialize sends the initialize request to the MCP server.
 func (c *Client) initialize(ctx context.Context) error {
+	version := "2024-11-05"
+	if c.server.Transport == "http" {
+		version = "2025-06-18" // streamable HTTP requires 2025-03-26 or later
+	}
 	params := InitializeParams{
-		ProtocolVersion: "2024-11-05",
+		ProtocolVersion: version,
 		Capabilities: Capabilities{
 			Roots: &RootsCapability{
 				ListChanged: false,
@@ -145,6 +135,10 @@ func (c *Client) initialize(ctx context.Context) error {
 		return err
 	}
 
+	c.httpMu.Lock()
+	c.protocolVersion = result.ProtocolVersion
+	c.httpMu.Unlock()
+
 	// Send initialized notification
 	if err := c.notify(ctx, "notifications/initialized", nil); err != nil {
 		return err
@@ -164,20 +158,41 @@ func (c *Client) Disconnect() error {
 
 	c.connected = false
 
-	if c.stdin != nil {
-		c.stdin.Close()
+	if c.streamCancel != nil {
+		c.streamCancel()
+		c.streamCancel = nil
 	}
-	if c.stdout != nil {
-		c.stdout.Close()
-	}
-	if c.process != nil && c.process.Process != nil {
-		c.process.Process.Kill()
-		c.process.Wait()
+	if c.server.Transport == "http" {
+		c.closeHTTPSession()
 	}
 
+	c.closeStdio()
 	return nil
 }
 
+// Ping checks that the server is responsive. A stdio server whose process
+// has exited fails immediately. Servers that answer ping with a JSON-RPC
+// error (e.g. method not found) are still considered alive.
+func (c *Client) Ping(ctx context.Context) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	if c.exited != nil {
+		select {
+		case <-c.exited:
+			return fmt.Errorf("server process exited")
+		default:
+		}
+	}
+
+	err := c.call(ctx, "ping", nil, nil)
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return nil
+	}
+	return err
+}
+
 // IsConnected returns whether the client is connected.
 func (c *Client) IsConnected() bool {
 	c.connMu.RLock()
@@ -273,6 +288,8 @@ func (c *Client) call(ctx context.Context, method string, params interface{}, re
 		resp, err = c.callStdio(ctx, req)
 	case "http":
 		resp, err = c.callHTTP(ctx, req)
+	case "sse":
+		resp, err = c.callSSE(ctx, req)
 	default:
 		return fmt.Errorf("unsupported transport: %s", c.server.Transport)
 	}
@@ -299,96 +316,6 @@ func (c *Client) call(ctx context.Context, method string, params interface{}, re
 	return nil
 }
 
-// callStdio sends a request over stdio and reads the response.
-func (c *Client) callStdio(ctx context.Context, req Request) (Response, error) {
-	// Marshal request
-	reqBytes, err := json.Marshal(req)
-	if err != nil {
-		return Response{}, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Write request to stdin (with newline)
-	c.mu.Lock()
-	_, err = c.stdin.Write(append(reqBytes, '\n'))
-	c.mu.Unlock()
-	if err != nil {
-		return Response{}, fmt.Errorf("failed to write request: %w", err)
-	}
-
-	// Read response from stdout
-	// We need to read until we get a response with our ID
-	for {
-		select {
-		case <-ctx.Done():
-			return Response{}, ctx.Err()
-		default:
-		}
-
-		if !c.scanner.Scan() {
-			if err := c.scanner.Err(); err != nil {
-				return Response{}, fmt.Errorf("failed to read response: %w", err)
-			}
-			return Response{}, fmt.Errorf("unexpected end of response stream")
-		}
-
-		line := c.scanner.Bytes()
-		if len(line) == 0 {
-			continue
-		}
-
-		var resp Response
-		if err := json.Unmarshal(line, &resp); err != nil {
-			// Skip lines that aren't valid JSON responses (could be notifications)
-			continue
-		}
-
-		// Check if this is a response to our request
-		if resp.ID == req.ID {
-			return resp, nil
-		}
-
-		// Otherwise it might be a notification or response to a different request
-		// For now, we skip it
-	}
-}
-
-// callHTTP sends a request over HTTP and reads the response.
-func (c *Client) callHTTP(ctx context.Context, req Request) (Response, error) {
-	// Marshal request
-	reqBytes, err := json.Marshal(req)
-	if err != nil {
-		return Response{}, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.server.URL, bytes.NewReader(reqBytes))
-	if err != nil {
-		return Response{}, fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	// Send request
-	httpResp, err := c.client.Do(httpReq)
-	if err != nil {
-		return Response{}, fmt.Errorf("HTTP request failed: %w", err)
-	}
-	defer httpResp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		return Response{}, fmt.Errorf("failed to read HTTP response: %w", err)
-	}
-
-	// Parse response
-	var resp Response
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return Response{}, fmt.Errorf("failed to parse HTTP response: %w", err)
-	}
-
-	return resp, nil
-}
-
 // notify sends a notification (no response expected).
 func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
 	req := struct {
@@ -400,55 +327,102 @@ func (c *Client) notify(ctx context.Context, method string, params interface{})
 		Method:  method,
 		Params:  params,
 	}
+	return c.send(ctx, req)
+}
 
+// send writes a message that expects no response (a notification or a reply
+// to a server request).
+func (c *Client) send(ctx context.Context, msg interface{}) error {
 	switch c.server.Transport {
 	case "stdio", "":
-		return c.notifyStdio(req)
+		return c.notifyStdio(msg)
 	case "http":
-		return c.notifyHTTP(ctx, req)
+		return c.notifyHTTP(ctx, msg)
+	case "sse":
+		return c.postSSE(ctx, msg)
 	default:
 		return fmt.Errorf("unsupported transport: %s", c.server.Transport)
 	}
 }
 
-// notifyStdio sends a notification over stdio.
-func (c *Client) notifyStdio(req interface{}) error {
-	reqBytes, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal notification: %w", err)
+// handleMessage decodes a message received from the server. Responses are
+// returned with ok set; notifications are passed to the notification
+// handler and server requests are answered.
+func (c *Client) handleMessage(ctx context.Context, data []byte) (resp Response, ok bool) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return Response{}, false
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	if msg.Method == "" {
+		var id int
+		if err := json.Unmarshal(msg.ID, &id); err != nil {
+			return Response{}, false
+		}
+		return Response{JSONRPC: msg.JSONRPC, ID: id, Result: msg.Result, Error: msg.Error}, true
+	}
 
-	_, err = c.stdin.Write(append(reqBytes, '\n'))
-	if err != nil {
-		return fmt.Errorf("failed to write notification: %w", err)
+	if len(msg.ID) == 0 {
+		if c.onNotify != nil {
+			c.onNotify(msg.Method, msg.Params)
+		}
+		return Response{}, false
 	}
 
-	return nil
+	// Server-initiated request: only ping is supported
+	reply := Message{JSONRPC: "2.0", ID: msg.ID}
+	if msg.Method == "ping" {
+		reply.Result = map[string]interface{}{}
+	} else {
+		reply.Error = &Error{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	go func() {
+		replyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), requestTimeout)
+		defer cancel()
+		if err := c.send(replyCtx, reply); err != nil {
+			log.Printf("MCP server %q: failed to answer %s: %v", c.server.Name, msg.Method, err)
+		}
+	}()
+	return Response{}, false
 }
 
-// notifyHTTP sends a notification over HTTP.
-func (c *Client) notifyHTTP(ctx context.Context, req interface{}) error {
-	reqBytes, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal notification: %w", err)
-	}
+// addPending registers a caller waiting for the response with the given ID.
+func (c *Client) addPending(id int) chan Response {
+	ch := make(chan Response, 1)
+	c.pendingMu.Lock()
+	c.pending[id] = ch
+	c.pendingMu.Unlock()
+	return ch
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.server.URL, bytes.NewReader(reqBytes))
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
+// removePending forgets a caller that is no longer waiting.
+func (c *Client) removePending(id int) {
+	c.pendingMu.Lock()
+	delete(c.pending, id)
+	c.pendingMu.Unlock()
+}
 
-	httpResp, err := c.client.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("HTTP notification failed: %w", err)
+// deliver hands a response read from the server to the waiting caller.
+// Responses nobody is waiting for (e.g. after a timeout) are dropped.
+func (c *Client) deliver(resp Response) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	if ch, ok := c.pending[resp.ID]; ok {
+		ch <- resp
+		delete(c.pending, resp.ID)
 	}
-	httpResp.Body.Close()
+}
 
-	return nil
+// failPending wakes all callers waiting for a response on a lost connection.
+func (c *Client) failPending() {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
 }
 
 // GetServerName returns the server name.
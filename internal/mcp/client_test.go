@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rpcRequest decodes an incoming JSON-RPC message in test servers.
+type rpcRequest struct {
+	ID     *int   `json:"id"`
+	Method string `json:"method"`
+}
+
+func rpcResult(id int, result string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, id, result)
+}
+
+const testTools = `{"tools":[{"name":"echo","description":"Echo input"}]}`
+
+func TestStreamableHTTP(t *testing.T) {
+	var mu sync.Mutex
+	sessions := 0
+	expired := false
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			return
+		}
+
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		defer mu.Unlock()
+
+		if req.Method == "initialize" {
+			sessions++
+			w.Header().Set(sessionHeader, fmt.Sprintf("s%d", sessions))
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, rpcResult(*req.ID, `{"protocolVersion":"2025-06-18"}`))
+			return
+		}
+		if r.Header.Get(sessionHeader) != fmt.Sprintf("s%d", sessions) || expired {
+			expired = false
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		// Stream a notification before the response
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+		fmt.Fprintf(w, "id: 1\ndata: %s\n\n", rpcResult(*req.ID, testTools))
+	}))
+	defer srv.Close()
+
+	var notified []string
+	client := NewClient(Server{Name: "test", URL: srv.URL, Transport: "streamable-http"})
+	client.SetNotificationHandler(func(method string, params json.RawMessage) {
+		notified = append(notified, method)
+	})
+
+	ctx := context.Background()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Disconnect()
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("tools = %+v, want [echo]", tools)
+	}
+	if len(notified) != 1 || notified[0] != "notifications/progress" {
+		t.Errorf("notifications = %v, want [notifications/progress]", notified)
+	}
+
+	// An expired session is re-initialized and the request retried
+	mu.Lock()
+	expired = true
+	mu.Unlock()
+	if _, err := client.ListTools(ctx); err != nil {
+		t.Fatalf("ListTools after session expiry: %v", err)
+	}
+	if sessions != 2 {
+		t.Errorf("sessions = %d, want 2", sessions)
+	}
+}
+
+func TestLegacySSE(t *testing.T) {
+	replies := make(chan string, 10)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: endpoint\ndata: /messages?session=1\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case reply := <-replies:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", reply)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusAccepted)
+
+		switch {
+		case req.ID == nil:
+		case req.Method == "initialize":
+			replies <- rpcResult(*req.ID, `{"protocolVersion":"2024-11-05"}`)
+		default:
+			replies <- rpcResult(*req.ID, testTools)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := NewClient(Server{Name: "test", URL: srv.URL + "/sse", Transport: "sse"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Disconnect()
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("tools = %+v, want [echo]", tools)
+	}
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	base := "https://mcp.example.com/sse"
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"/messages?session=1", "https://mcp.example.com/messages?session=1"},
+		{"messages", "https://mcp.example.com/messages"},
+		{"https://MCP.example.com/messages", "https://MCP.example.com/messages"},
+		{"https://attacker.example/collect", ""},
+		{"//attacker.example/collect", ""},
+		{"http://mcp.example.com/messages", ""},
+		{"https://mcp.example.com:8443/messages", ""},
+	}
+	for _, tt := range tests {
+		got, err := resolveEndpoint(base, tt.endpoint)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("resolveEndpoint(%q) = %q, want an error", tt.endpoint, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveEndpoint(%q) = %q, %v; want %q", tt.endpoint, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Streamable HTTP transport (protocol version 2025-03-26 and later): every
+// client message is POSTed to a single endpoint, and the server answers
+// either with a JSON body or with an event stream that may carry
+// notifications before the response. A GET on the same endpoint opens an
+// optional stream for server-initiated messages.
+
+const (
+	sessionHeader     = "Mcp-Session-Id"
+	versionHeader     = "Mcp-Protocol-Version"
+	lastEventIDHeader = "Last-Event-ID"
+
+	// maxStreamResumes bounds how often a dropped response stream is resumed.
+	maxStreamResumes = 3
+)
+
+// errSessionExpired is returned when the server no longer recognizes the
+// session ID, e.g. after a restart.
+var errSessionExpired = errors.New("MCP session expired")
+
+// connectHTTP initializes a streamable HTTP session and starts listening for
+// server-initiated messages.
+func (c *Client) connectHTTP(ctx context.Context) error {
+	if err := c.initialize(ctx); err != nil {
+		return fmt.Errorf("failed to initialize HTTP MCP server: %w", err)
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	c.streamCancel = cancel
+	go c.listenHTTP(streamCtx)
+	return nil
+}
+
+// newHTTPRequest builds a request carrying the configured headers, the
+// session ID and the negotiated protocol version.
+func (c *Client) newHTTPRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, rd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range c.server.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	c.httpMu.Lock()
+	sessionID, version := c.sessionID, c.protocolVersion
+	c.httpMu.Unlock()
+	if sessionID != "" {
+		httpReq.Header.Set(sessionHeader, sessionID)
+	}
+	if version != "" {
+		httpReq.Header.Set(versionHeader, version)
+	}
+	return httpReq, nil
+}
+
+// postHTTP POSTs a message and returns the HTTP response after checking the
+// status and recording any session ID the server assigned.
+func (c *Client) postHTTP(ctx context.Context, msg interface{}) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	httpReq, err := c.newHTTPRequest(ctx, http.MethodPost, c.server.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	if sessionID := httpResp.Header.Get(sessionHeader); sessionID != "" {
+		c.httpMu.Lock()
+		c.sessionID = sessionID
+		c.httpMu.Unlock()
+	}
+
+	if httpResp.StatusCode == http.StatusNotFound && httpReq.Header.Get(sessionHeader) != "" {
+		httpResp.Body.Close()
+		return nil, errSessionExpired
+	}
+	if httpResp.StatusCode >= 300 {
+		defer httpResp.Body.Close()
+		return nil, httpStatusError(httpResp)
+	}
+	return httpResp, nil
+}
+
+// callHTTP sends a request over streamable HTTP and reads the response. If
+// the server dropped the session, a new one is initialized and the request
+// is retried once.
+func (c *Client) callHTTP(ctx context.Context, req Request) (Response, error) {
+	resp, err := c.roundTripHTTP(ctx, req)
+	if errors.Is(err, errSessionExpired) && req.Method != "initialize" {
+		log.Printf("MCP server %q: session expired, re-initializing", c.server.Name)
+		if err := c.reinitialize(ctx); err != nil {
+			return Response{}, err
+		}
+		return c.roundTripHTTP(ctx, req)
+	}
+	return resp, err
+}
+
+// roundTripHTTP POSTs req and reads the matching response from either a JSON
+// body or an event stream.
+func (c *Client) roundTripHTTP(ctx context.Context, req Request) (Response, error) {
+	httpResp, err := c.postHTTP(ctx, req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpResp.Body.Close()
+
+	if isEventStream(httpResp) {
+		return c.awaitStreamResponse(ctx, httpResp.Body, req.ID)
+	}
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read HTTP response: %w", err)
+	}
+	var resp Response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Response{}, fmt.Errorf("failed to parse HTTP response: %w", err)
+	}
+	return resp, nil
+}
+
+// awaitStreamResponse reads an event stream until the response with the
+// given ID arrives, dispatching notifications along the way. A stream that
+// ends early is resumed with Last-Event-ID when the server numbers events.
+func (c *Client) awaitStreamResponse(ctx context.Context, body io.Reader, id int) (Response, error) {
+	var result *Response
+	var lastEventID string
+	read := func(r io.Reader) error {
+		return readSSE(r, func(ev sseEvent) bool {
+			if ev.ID != "" {
+				lastEventID = ev.ID
+			}
+			if ev.Data == "" {
+				return true
+			}
+			if resp, ok := c.handleMessage(ctx, []byte(ev.Data)); ok && resp.ID == id {
+				result = &resp
+				return false
+			}
+			return true
+		})
+	}
+
+	err := read(body)
+	for attempt := 0; result == nil && lastEventID != "" && attempt < maxStreamResumes; attempt++ {
+		if ctx.Err() != nil {
+			return Response{}, ctx.Err()
+		}
+		stream, serr := c.openHTTPStream(ctx, c.client, lastEventID)
+		if serr != nil {
+			err = serr
+			break
+		}
+		err = read(stream.Body)
+		stream.Body.Close()
+	}
+
+	if result != nil {
+		return *result, nil
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read response stream: %w", err)
+	}
+	return Response{}, fmt.Errorf("response stream closed before reply to request %d", id)
+}
+
+// openHTTPStream opens a GET event stream, resuming after lastEventID if set.
+// It returns errStreamUnsupported if the server does not offer one.
+func (c *Client) openHTTPStream(ctx context.Context, client *http.Client, lastEventID string) (*http.Response, error) {
+	httpReq, err := c.newHTTPRequest(ctx, http.MethodGet, c.server.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		httpReq.Header.Set(lastEventIDHeader, lastEventID)
+	}
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	switch {
+	case httpResp.StatusCode == http.StatusMethodNotAllowed:
+		httpResp.Body.Close()
+		return nil, errStreamUnsupported
+	case httpResp.StatusCode == http.StatusNotFound && httpReq.Header.Get(sessionHeader) != "":
+		httpResp.Body.Close()
+		return nil, errSessionExpired
+	case httpResp.StatusCode != http.StatusOK || !isEventStream(httpResp):
+		defer httpResp.Body.Close()
+		return nil, httpStatusError(httpResp)
+	}
+	return httpResp, nil
+}
+
+// errStreamUnsupported is returned when the server answers a GET stream
+// request with 405 Method Not Allowed.
+var errStreamUnsupported = errors.New("server does not offer an event stream")
+
+// listenHTTP keeps a GET event stream open so the server can push
+// notifications outside of a request. Dropped streams are resumed with
+// exponential backoff until ctx is cancelled.
+func (c *Client) listenHTTP(ctx context.Context) {
+	delay := reconnectMinDelay
+	var lastEventID string
+	for {
+		stream, err := c.openHTTPStream(ctx, c.stream, lastEventID)
+		if errors.Is(err, errStreamUnsupported) {
+			return
+		}
+		if err == nil {
+			delay = reconnectMinDelay
+			err = readSSE(stream.Body, func(ev sseEvent) bool {
+				if ev.ID != "" {
+					lastEventID = ev.ID
+				}
+				if ev.Data != "" {
+					c.handleMessage(ctx, []byte(ev.Data))
+				}
+				return true
+			})
+			stream.Body.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("MCP server %q: notification stream: %v", c.server.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+// notifyHTTP POSTs a notification or response; the server acknowledges it
+// with 202 Accepted.
+func (c *Client) notifyHTTP(ctx context.Context, msg interface{}) error {
+	httpResp, err := c.postHTTP(ctx, msg)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	io.Copy(io.Discard, httpResp.Body)
+	return nil
+}
+
+// reinitialize starts a new session after the server expired the old one.
+func (c *Client) reinitialize(ctx context.Context) error {
+	c.httpMu.Lock()
+	c.sessionID = ""
+	c.httpMu.Unlock()
+
+	if err := c.initialize(ctx); err != nil {
+		return fmt.Errorf("failed to re-initialize session: %w", err)
+	}
+	return nil
+}
+
+// closeHTTPSession asks the server to terminate the session. Servers that do
+// not support explicit termination answer 405, which is ignored.
+func (c *Client) closeHTTPSession() {
+	c.httpMu.Lock()
+	sessionID := c.sessionID
+	c.httpMu.Unlock()
+	if sessionID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	httpReq, err := c.newHTTPRequest(ctx, http.MethodDelete, c.server.URL, nil)
+	if err != nil {
+		return
+	}
+	if httpResp, err := c.client.Do(httpReq); err == nil {
+		httpResp.Body.Close()
+	}
+
+	c.httpMu.Lock()
+	c.sessionID = ""
+	c.httpMu.Unlock()
+}
+
+// httpStatusError describes an unexpected HTTP status, including the start
+// of the response body.
+func httpStatusError(resp *http.Response) error {
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+}
@@ -2,50 +2,136 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/hkuds/ubot/internal/tools"
 )
 
 // Manager handles multiple MCP server connections.
 type Manager struct {
-	clients map[string]*Client
-	mu      sync.RWMutex
+	clients    map[string]*Client
+	servers    map[string]Server     // All added servers, including disconnected ones
+	registered map[string][]string   // Tool names registered per server
+	shadowed   map[string]tools.Tool // Tools replaced under the override conflict policy, by name
+	registry   *tools.ToolRegistry   // Registry kept in sync with server tools; may be nil
+	mu         sync.RWMutex
 }
 
 // NewManager creates a new MCP manager.
 func NewManager() *Manager {
 	return &Manager{
-		clients: make(map[string]*Client),
+		clients:    make(map[string]*Client),
+		servers:    make(map[string]Server),
+		registered: make(map[string][]string),
+		shadowed:   make(map[string]tools.Tool),
 	}
 }
 
-// AddServer adds and connects to a new MCP server.
+// SetRegistry makes the manager register bridged tools in registry and keep
+// them in sync when servers reconnect or change their tool list.
+func (m *Manager) SetRegistry(registry *tools.ToolRegistry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.registry = registry
+}
+
+// AddServer adds and connects to a new MCP server. A server that fails to
+// connect is remembered, so Supervise keeps retrying it.
 func (m *Manager) AddServer(ctx context.Context, server Server) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.clients[server.Name]; exists {
+	if _, exists := m.servers[server.Name]; exists {
 		return fmt.Errorf("server %q already exists", server.Name)
 	}
+	m.servers[server.Name] = server
+
+	client, err := m.connect(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	m.clients[server.Name] = client
+	m.syncToolsLocked(server.Name)
+	log.Printf("Connected to MCP server %q", server.Name)
 
+	return nil
+}
+
+// connect creates a client for server, connects it and fetches its tools.
+func (m *Manager) connect(ctx context.Context, server Server) (*Client, error) {
 	client := NewClient(server)
+	client.SetNotificationHandler(func(method string, params json.RawMessage) {
+		m.handleNotification(server.Name, client, method, params)
+	})
 	if err := client.Connect(ctx); err != nil {
-		return fmt.Errorf("failed to connect to server %q: %w", server.Name, err)
+		return nil, fmt.Errorf("failed to connect to server %q: %w", server.Name, err)
 	}
 
 	// Fetch tools from the server
 	if _, err := client.ListTools(ctx); err != nil {
 		client.Disconnect()
-		return fmt.Errorf("failed to list tools from server %q: %w", server.Name, err)
+		return nil, fmt.Errorf("failed to list tools from server %q: %w", server.Name, err)
 	}
+	return client, nil
+}
 
-	m.clients[server.Name] = client
-	log.Printf("Connected to MCP server %q", server.Name)
+// syncToolsLocked replaces the registry's bridged tools for a server with
+// its current tool list. Must be called with m.mu held.
+func (m *Manager) syncToolsLocked(serverName string) {
+	if m.registry == nil {
+		return
+	}
 
-	return nil
+	for _, name := range m.registered[serverName] {
+		m.unregisterLocked(name)
+	}
+	delete(m.registered, serverName)
+	// Tools of this server that another one replaced are decided again below
+	for name, t := range m.shadowed {
+		if bridge, ok := t.(*MCPToolBridge); ok && bridge.serverName == serverName {
+			delete(m.shadowed, name)
+		}
+	}
+
+	client, ok := m.clients[serverName]
+	if !ok {
+		return
+	}
+	var names []string
+	for _, tool := range client.GetCachedTools() {
+		if name := m.registerBridgeLocked(NewMCPToolBridge(m, serverName, tool)); name != "" {
+			names = append(names, name)
+		}
+	}
+	m.registered[serverName] = names
+}
+
+// handleNotification reacts to notifications pushed by a server.
+func (m *Manager) handleNotification(serverName string, client *Client, method string, params json.RawMessage) {
+	switch method {
+	case "notifications/tools/list_changed":
+		// Refresh outside the stream reader so it keeps dispatching messages
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if _, err := client.ListTools(ctx); err != nil {
+				log.Printf("Failed to refresh tools from server %q: %v", serverName, err)
+				return
+			}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.clients[serverName] == client {
+				m.syncToolsLocked(serverName)
+			}
+		}()
+	case "notifications/message":
+		log.Printf("MCP server %q: %s", serverName, params)
+	}
 }
 
 // RemoveServer disconnects and removes an MCP server.
@@ -53,16 +139,21 @@ func (m *Manager) RemoveServer(name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	client, exists := m.clients[name]
-	if !exists {
+	if _, exists := m.servers[name]; !exists {
 		return fmt.Errorf("server %q not found", name)
 	}
+	delete(m.servers, name)
+
+	client, connected := m.clients[name]
+	delete(m.clients, name)
+	m.syncToolsLocked(name)
+	if !connected {
+		return nil
+	}
 
 	if err := client.Disconnect(); err != nil {
 		return fmt.Errorf("failed to disconnect from server %q: %w", name, err)
 	}
-
-	delete(m.clients, name)
 	log.Printf("Disconnected from MCP server %q", name)
 
 	return nil
@@ -76,19 +167,39 @@ func (m *Manager) GetClient(name string) *Client {
 }
 
 // GetAllTools returns all tools from all connected servers as uBot tool definitions.
+// With a registry set, these are the tools registered under the names
+// they were given, leaving out the ones skipped over a conflict.
 func (m *Manager) GetAllTools() []tools.ToolDefinition {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	var definitions []tools.ToolDefinition
 
+	if m.registry != nil {
+		for _, names := range m.registered {
+			for _, name := range names {
+				if t := m.registry.Get(name); t != nil {
+					definitions = append(definitions, tools.ToolDefinition{
+						Type: "function",
+						Function: tools.FunctionDefinition{
+							Name:        name,
+							Description: t.Description(),
+							Parameters:  t.Parameters(),
+						},
+					})
+				}
+			}
+		}
+		return definitions
+	}
+
 	for serverName, client := range m.clients {
 		mcpTools := client.GetCachedTools()
 		for _, tool := range mcpTools {
 			def := tools.ToolDefinition{
 				Type: "function",
 				Function: tools.FunctionDefinition{
-					Name:        fmt.Sprintf("mcp_%s_%s", serverName, tool.Name),
+					Name:        NamespacedName(serverName, tool.Name),
 					Description: tool.Description,
 					Parameters:  tool.InputSchema,
 				},
@@ -168,6 +279,19 @@ func (m *Manager) ListServers() []string {
 	return names
 }
 
+// ServerStatus reports for each added server whether it is connected.
+// Servers that are not are being retried by Supervise.
+func (m *Manager) ServerStatus() map[string]bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := make(map[string]bool, len(m.servers))
+	for name := range m.servers {
+		status[name] = m.clients[name] != nil
+	}
+	return status
+}
+
 // Close disconnects from all MCP servers.
 func (m *Manager) Close() error {
 	m.mu.Lock()
@@ -182,6 +306,7 @@ func (m *Manager) Close() error {
 	}
 
 	m.clients = make(map[string]*Client)
+	m.servers = make(map[string]Server)
 	return lastErr
 }
 
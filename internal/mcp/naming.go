@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"log"
+	"regexp"
+
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+// Conflict policies: what happens when the name a server's tool would be
+// registered under is already taken by a built-in tool or another server's.
+const (
+	ConflictPrefix   = "prefix"   // register it under its namespaced name instead (default)
+	ConflictSkip     = "skip"     // leave the existing tool and drop this one
+	ConflictOverride = "override" // replace the existing tool while the server is connected
+)
+
+// Naming decides the names a server's tools are registered under.
+type Naming struct {
+	Bare       bool              // register tools under their own names instead of mcp_<server>_<tool>
+	Aliases    map[string]string // tool name -> name to register it under
+	OnConflict string            // ConflictPrefix (default), ConflictSkip or ConflictOverride
+}
+
+// invalidNameChars are the characters model APIs do not accept in tool
+// names.
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// NamespacedName returns the name a server's tool is registered under by
+// default, mcp_<server>_<tool>. Model APIs do not allow dots, so this is
+// how server.tool is spelled for them.
+func NamespacedName(server, tool string) string {
+	return invalidNameChars.ReplaceAllString("mcp_"+server+"_"+tool, "_")
+}
+
+// wantedName returns the name tool should be registered under, before
+// conflicts are considered.
+func (n Naming) wantedName(server, tool string) string {
+	if alias := n.Aliases[tool]; alias != "" {
+		return alias
+	}
+	if n.Bare {
+		return invalidNameChars.ReplaceAllString(tool, "_")
+	}
+	return NamespacedName(server, tool)
+}
+
+// registerBridgeLocked registers bridge under the name its server's naming
+// gives it, resolving conflicts with the server's policy, and returns the
+// name or "" when the tool was left out. Must be called with m.mu held.
+func (m *Manager) registerBridgeLocked(bridge *MCPToolBridge) string {
+	naming := m.servers[bridge.serverName].Naming
+	id := bridge.ID()
+	name := naming.wantedName(bridge.serverName, bridge.tool.Name)
+
+	if existing := m.registry.Get(name); existing != nil {
+		switch naming.OnConflict {
+		case ConflictSkip:
+			log.Printf("Warning: MCP tool %s skipped: %s is already taken by %s", id, name, toolOwner(existing))
+			return ""
+		case ConflictOverride:
+			if other, ok := existing.(*MCPToolBridge); ok {
+				m.forgetLocked(other.serverName, name)
+			}
+			m.shadowed[name] = existing
+			bridge.name = name
+			m.registry.Replace(bridge)
+			log.Printf("MCP tool %s replaces %s as %s", id, toolOwner(existing), name)
+			return name
+		default:
+			namespaced := NamespacedName(bridge.serverName, bridge.tool.Name)
+			if namespaced == name || m.registry.Has(namespaced) {
+				log.Printf("Warning: MCP tool %s skipped: %s is already taken by %s", id, name, toolOwner(existing))
+				return ""
+			}
+			log.Printf("Warning: MCP tool %s registered as %s: %s is already taken by %s", id, namespaced, name, toolOwner(existing))
+			name = namespaced
+		}
+	}
+
+	bridge.name = name
+	if err := m.registry.Register(bridge); err != nil {
+		log.Printf("Warning: failed to register MCP tool %s: %v", id, err)
+		return ""
+	}
+	return name
+}
+
+// unregisterLocked removes the tool registered as name, bringing back the
+// tool it replaced, if any. Must be called with m.mu held.
+func (m *Manager) unregisterLocked(name string) {
+	m.registry.Unregister(name)
+	previous, ok := m.shadowed[name]
+	if !ok {
+		return
+	}
+	delete(m.shadowed, name)
+	m.registry.Replace(previous)
+	if bridge, ok := previous.(*MCPToolBridge); ok {
+		m.registered[bridge.serverName] = append(m.registered[bridge.serverName], name)
+	}
+	log.Printf("Restored %s as %s", toolOwner(previous), name)
+}
+
+// forgetLocked drops name from the tools registered for server, when
+// another server's tool replaces it. Must be called with m.mu held.
+func (m *Manager) forgetLocked(server, name string) {
+	names := m.registered[server]
+	for i, n := range names {
+		if n == name {
+			m.registered[server] = append(names[:i:i], names[i+1:]...)
+			return
+		}
+	}
+}
+
+// toolOwner describes where a registered tool comes from, for logs.
+func toolOwner(t tools.Tool) string {
+	if bridge, ok := t.(*MCPToolBridge); ok {
+		return "MCP tool " + bridge.ID()
+	}
+	return "built-in tool " + t.Name()
+}
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+// toolServer starts a streamable HTTP MCP server offering the named tools.
+func toolServer(t *testing.T, names ...string) string {
+	var list []Tool
+	for _, name := range names {
+		list = append(list, Tool{Name: name, Description: "Remote " + name})
+	}
+	result, _ := json.Marshal(map[string]interface{}{"tools": list})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if req.Method == "tools/list" {
+			fmt.Fprint(w, rpcResult(*req.ID, string(result)))
+			return
+		}
+		fmt.Fprint(w, rpcResult(*req.ID, `{}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestNamingConflicts(t *testing.T) {
+	ctx := context.Background()
+	registry := tools.NewRegistry()
+	builtin := tools.NewReadFileTool()
+	registry.Register(builtin)
+	m := NewManager()
+	m.SetRegistry(registry)
+	defer m.Close()
+
+	add := func(name string, naming Naming, toolNames ...string) {
+		t.Helper()
+		if err := m.AddServer(ctx, Server{Name: name, URL: toolServer(t, toolNames...), Transport: "http", Naming: naming}); err != nil {
+			t.Fatalf("AddServer(%s): %v", name, err)
+		}
+	}
+	owner := func(name string) string {
+		switch tool := registry.Get(name).(type) {
+		case nil:
+			return ""
+		case *MCPToolBridge:
+			return tool.ID()
+		default:
+			return "builtin"
+		}
+	}
+
+	// Bare names fall back to the namespaced name when taken
+	add("fs", Naming{Bare: true}, "read_file", "echo")
+	if owner("read_file") != "builtin" || owner("mcp_fs_read_file") != "fs.read_file" || owner("echo") != "fs.echo" {
+		t.Fatalf("tools = %v", registry.List())
+	}
+
+	// skip leaves the existing tool; aliases rename
+	add("quiet", Naming{Bare: true, OnConflict: ConflictSkip, Aliases: map[string]string{"list": "quiet_list"}}, "echo", "list")
+	if owner("echo") != "fs.echo" || registry.Has("mcp_quiet_echo") || owner("quiet_list") != "quiet.list" {
+		t.Fatalf("tools = %v", registry.List())
+	}
+
+	// override replaces built-in and MCP tools until the server goes away
+	add("loud", Naming{Bare: true, OnConflict: ConflictOverride}, "read_file", "echo")
+	if owner("read_file") != "loud.read_file" || owner("echo") != "loud.echo" {
+		t.Fatalf("tools = %v", registry.List())
+	}
+	var names []string
+	for _, def := range m.GetAllTools() {
+		names = append(names, def.Function.Name)
+	}
+	sort.Strings(names)
+	if fmt.Sprint(names) != "[echo mcp_fs_read_file quiet_list read_file]" {
+		t.Errorf("GetAllTools names = %v", names)
+	}
+
+	if err := m.RemoveServer("loud"); err != nil {
+		t.Fatal(err)
+	}
+	if registry.Get("read_file") != builtin || owner("echo") != "fs.echo" {
+		t.Fatalf("replaced tools not restored: %v", registry.List())
+	}
+	if err := m.RemoveServer("fs"); err != nil {
+		t.Fatal(err)
+	}
+	if registry.Has("echo") || registry.Has("mcp_fs_read_file") {
+		t.Errorf("restored tools left behind: %v", registry.List())
+	}
+}
@@ -0,0 +1,263 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sseEvent is a single Server-Sent Events event.
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readSSE parses an event stream from r and calls handle for each event. It
+// returns when r is exhausted, handle returns false, or reading fails.
+func readSSE(r io.Reader, handle func(sseEvent) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024) // 10MB max event line
+
+	var ev sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line dispatches the event
+			if len(data) > 0 || ev.Event != "" {
+				ev.Data = strings.Join(data, "\n")
+				if !handle(ev) {
+					return nil
+				}
+			}
+			ev = sseEvent{}
+			data = nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment / keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			ev.ID = value
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return scanner.Err()
+}
+
+// isEventStream reports whether resp carries a Server-Sent Events body.
+func isEventStream(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// connectSSE connects to a server using the legacy HTTP+SSE transport
+// (protocol version 2024-11-05): a long-lived GET event stream carries all
+// server messages, and client messages are POSTed to the endpoint the server
+// announces on that stream.
+func (c *Client) connectSSE(ctx context.Context) error {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	c.streamCancel = cancel
+
+	if err := c.openSSE(ctx, streamCtx); err != nil {
+		cancel()
+		return fmt.Errorf("failed to open SSE stream: %w", err)
+	}
+	if err := c.initialize(ctx); err != nil {
+		cancel()
+		return fmt.Errorf("failed to initialize SSE MCP server: %w", err)
+	}
+	return nil
+}
+
+// openSSE opens the event stream and waits for the endpoint event. The
+// stream is read in the background until streamCtx is cancelled.
+func (c *Client) openSSE(ctx, streamCtx context.Context) error {
+	httpReq, err := c.newHTTPRequest(streamCtx, http.MethodGet, c.server.URL, nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	httpResp, err := c.stream.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK || !isEventStream(httpResp) {
+		httpResp.Body.Close()
+		return fmt.Errorf("unexpected response: HTTP %d (%s)", httpResp.StatusCode, httpResp.Header.Get("Content-Type"))
+	}
+
+	endpoint := make(chan string, 1)
+	go c.readSSEStream(streamCtx, httpResp.Body, endpoint)
+
+	select {
+	case ep := <-endpoint:
+		postURL, err := resolveEndpoint(c.server.URL, ep)
+		if err != nil {
+			httpResp.Body.Close()
+			return err
+		}
+		c.httpMu.Lock()
+		c.postURL = postURL
+		c.httpMu.Unlock()
+		return nil
+	case <-time.After(requestTimeout):
+		httpResp.Body.Close()
+		return errors.New("server did not announce a message endpoint")
+	case <-ctx.Done():
+		httpResp.Body.Close()
+		return ctx.Err()
+	}
+}
+
+// resolveEndpoint resolves the endpoint announced by the server against the
+// stream URL. The endpoint must be on the same scheme and host as the
+// stream, so that a server cannot send the requests, and the headers with
+// their credentials, elsewhere.
+func resolveEndpoint(base, endpoint string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL: %w", err)
+	}
+	ref, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	resolved := baseURL.ResolveReference(ref)
+	if resolved.Scheme != baseURL.Scheme || !strings.EqualFold(resolved.Host, baseURL.Host) {
+		return "", fmt.Errorf("endpoint %q is not on the server %s://%s", endpoint, baseURL.Scheme, baseURL.Host)
+	}
+	return resolved.String(), nil
+}
+
+// readSSEStream dispatches messages from the event stream. When the stream
+// drops, pending requests fail and the client reconnects in the background.
+func (c *Client) readSSEStream(ctx context.Context, body io.ReadCloser, endpoint chan<- string) {
+	defer body.Close()
+
+	err := readSSE(body, func(ev sseEvent) bool {
+		switch ev.Event {
+		case "endpoint":
+			select {
+			case endpoint <- ev.Data:
+			default:
+			}
+		case "", "message":
+			if resp, ok := c.handleMessage(ctx, []byte(ev.Data)); ok {
+				c.deliver(resp)
+			}
+		}
+		return true
+	})
+
+	c.failPending()
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	log.Printf("MCP server %q: event stream lost (%v), reconnecting", c.server.Name, err)
+	c.reconnectSSE(ctx)
+}
+
+// reconnectSSE reopens the event stream with exponential backoff and
+// re-initializes, since the server forgets the client with the stream.
+func (c *Client) reconnectSSE(ctx context.Context) {
+	delay := reconnectMinDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+		err := c.openSSE(attemptCtx, ctx)
+		if err == nil {
+			err = c.initialize(attemptCtx)
+		}
+		cancel()
+		if err == nil {
+			log.Printf("MCP server %q: reconnected", c.server.Name)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("MCP server %q: reconnect failed: %v", c.server.Name, err)
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+// callSSE posts a request to the message endpoint and waits for the reply
+// to arrive on the event stream.
+func (c *Client) callSSE(ctx context.Context, req Request) (Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	ch := c.addPending(req.ID)
+	defer c.removePending(req.ID)
+
+	if err := c.postSSE(ctx, req); err != nil {
+		return Response{}, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return Response{}, errors.New("event stream closed before the response arrived")
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+}
+
+// postSSE POSTs a message to the endpoint announced on the event stream.
+func (c *Client) postSSE(ctx context.Context, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	c.httpMu.Lock()
+	postURL := c.postURL
+	c.httpMu.Unlock()
+	if postURL == "" {
+		return errors.New("no message endpoint (event stream not connected)")
+	}
+
+	httpReq, err := c.newHTTPRequest(ctx, http.MethodPost, postURL, body)
+	if err != nil {
+		return err
+	}
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode >= 300 {
+		return httpStatusError(httpResp)
+	}
+	io.Copy(io.Discard, httpResp.Body)
+	return nil
+}
@@ -0,0 +1,152 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+)
+
+// connectStdio establishes a stdio connection by spawning the MCP server process.
+func (c *Client) connectStdio(ctx context.Context) error {
+	// Build command. The process outlives ctx (which only bounds the
+	// handshake) and is stopped by Disconnect.
+	c.process = exec.Command(c.server.Command, c.server.Args...)
+
+	// Set a minimal environment instead of inheriting the full parent environment
+	var env []string
+	for _, key := range []string{"PATH", "HOME", "LANG", "USER", "TERM", "SHELL", "TMPDIR", "XDG_RUNTIME_DIR", "NODE_PATH"} {
+		if val, ok := os.LookupEnv(key); ok {
+			env = append(env, fmt.Sprintf("%s=%s", key, val))
+		}
+	}
+	// Add explicitly configured env vars
+	for k, v := range c.server.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	c.process.Env = env
+
+	// Set up pipes
+	var err error
+	c.stdin, err = c.process.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	c.stdout, err = c.process.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	// Start the process
+	if err := c.process.Start(); err != nil {
+		return fmt.Errorf("failed to start MCP server: %w", err)
+	}
+	exited := make(chan struct{})
+	c.exited = exited
+	go func() {
+		c.process.Wait()
+		close(exited)
+	}()
+
+	readerDone := make(chan struct{})
+	c.readerDone = readerDone
+	go c.readStdio(readerDone)
+
+	// Send initialize request
+	if err := c.initialize(ctx); err != nil {
+		c.closeStdio()
+		return fmt.Errorf("failed to initialize MCP server: %w", err)
+	}
+
+	return nil
+}
+
+// readStdio reads messages from the server's stdout until it closes,
+// routing responses to their callers by ID and dispatching notifications
+// and server requests. Callers still waiting when the stream ends fail.
+func (c *Client) readStdio(done chan<- struct{}) {
+	defer close(done)
+	defer c.failPending()
+
+	scanner := bufio.NewScanner(c.stdout)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line size
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if resp, ok := c.handleMessage(context.Background(), line); ok {
+			c.deliver(resp)
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+		log.Printf("MCP server %q: failed to read output: %v", c.server.Name, err)
+	}
+}
+
+// callStdio writes a request to the process and waits for the reader to
+// deliver the response with the matching ID. Any number of calls may be in
+// flight at once.
+func (c *Client) callStdio(ctx context.Context, req Request) (Response, error) {
+	ch := c.addPending(req.ID)
+	defer c.removePending(req.ID)
+
+	if err := c.writeStdio(req); err != nil {
+		return Response{}, fmt.Errorf("failed to write request: %w", err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return Response{}, errors.New("server closed its output before responding")
+		}
+		return resp, nil
+	case <-c.readerDone:
+		return Response{}, errors.New("server closed its output before responding")
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+}
+
+// notifyStdio sends a notification over stdio.
+func (c *Client) notifyStdio(msg interface{}) error {
+	if err := c.writeStdio(msg); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
+
+// writeStdio writes one newline-delimited JSON message to the process.
+// Writes are serialized so concurrent messages never interleave.
+func (c *Client) writeStdio(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err = c.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// closeStdio closes the pipes and kills the stdio server process, if any.
+func (c *Client) closeStdio() {
+	if c.stdin != nil {
+		c.stdin.Close()
+	}
+	if c.stdout != nil {
+		c.stdout.Close()
+	}
+	if c.process != nil && c.process.Process != nil {
+		c.process.Process.Kill()
+		<-c.exited
+	}
+}
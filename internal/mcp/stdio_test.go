@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHelperStdioServer is not a real test: it runs as the MCP server
+// process spawned by TestStdioConcurrentCalls. Each tools/call replies
+// after the requested delay, so responses arrive out of order.
+func TestHelperStdioServer(t *testing.T) {
+	if os.Getenv("UBOT_MCP_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+
+	var mu sync.Mutex
+	write := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Println(s)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     *int   `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Arguments struct {
+					Delay int `json:"delay"`
+				} `json:"arguments"`
+			} `json:"params"`
+		}
+		json.Unmarshal(scanner.Bytes(), &req)
+		if req.ID == nil {
+			continue
+		}
+		id := *req.ID
+		switch req.Method {
+		case "initialize":
+			write(rpcResult(id, `{"protocolVersion":"2024-11-05"}`))
+		case "tools/call":
+			delay := time.Duration(req.Params.Arguments.Delay) * time.Millisecond
+			go func() {
+				time.Sleep(delay)
+				write(`{"jsonrpc":"2.0","method":"notifications/progress","params":{}}`)
+				write(rpcResult(id, fmt.Sprintf(`{"content":[{"type":"text","text":"%d"}]}`, id)))
+			}()
+		default:
+			write(rpcResult(id, `{}`))
+		}
+	}
+	os.Exit(0)
+}
+
+func TestStdioConcurrentCalls(t *testing.T) {
+	c := NewClient(Server{
+		Name:    "helper",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestHelperStdioServer$"},
+		Env:     map[string]string{"UBOT_MCP_HELPER": "1"},
+	})
+	var notified atomic.Int32
+	c.SetNotificationHandler(func(method string, params json.RawMessage) {
+		if method == "notifications/progress" {
+			notified.Add(1)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Disconnect()
+
+	// Later calls finish first; each caller must still get its own reply
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(delay int) {
+			defer wg.Done()
+			if _, err := c.CallTool(ctx, "echo", map[string]interface{}{"delay": delay}); err != nil {
+				errs <- err
+			}
+		}((5 - i) * 50)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("CallTool: %v", err)
+	}
+	// Each reply is preceded by a notification, so all have been dispatched
+	if n := notified.Load(); n != 5 {
+		t.Errorf("notifications = %d, want 5", n)
+	}
+
+	// A call that times out does not disturb the next one
+	short, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	if _, err := c.CallTool(short, "echo", map[string]interface{}{"delay": 200}); err == nil {
+		t.Error("CallTool should time out")
+	}
+	if _, err := c.CallTool(ctx, "echo", map[string]interface{}{"delay": 0}); err != nil {
+		t.Errorf("CallTool after timeout: %v", err)
+	}
+
+	// Callers fail promptly once the process is gone
+	c.process.Process.Kill()
+	if _, err := c.CallTool(ctx, "echo", map[string]interface{}{"delay": 0}); err == nil {
+		t.Error("CallTool should fail after the server exits")
+	}
+}
@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// pingTimeout bounds a single health check.
+	pingTimeout = 10 * time.Second
+
+	// retryMinDelay and retryMaxDelay bound the backoff between attempts to
+	// reconnect an unhealthy server.
+	retryMinDelay = 5 * time.Second
+	retryMaxDelay = 5 * time.Minute
+)
+
+// serverHealth tracks reconnect attempts for one server.
+type serverHealth struct {
+	failures    int
+	nextAttempt time.Time
+}
+
+// Supervise health-checks servers every interval until ctx is cancelled.
+// Servers that stop answering pings (including stdio servers whose process
+// died) are disconnected and reconnected with exponential backoff, along
+// with servers that failed their initial connection. Bridged tools are
+// re-registered after each reconnect.
+func (m *Manager) Supervise(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	health := make(map[string]*serverHealth)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkServers(ctx, health)
+		}
+	}
+}
+
+// checkServers runs one supervision pass over all servers.
+func (m *Manager) checkServers(ctx context.Context, health map[string]*serverHealth) {
+	m.mu.RLock()
+	servers := make([]Server, 0, len(m.servers))
+	for _, server := range m.servers {
+		servers = append(servers, server)
+	}
+	m.mu.RUnlock()
+
+	for _, server := range servers {
+		if ctx.Err() != nil {
+			return
+		}
+		h := health[server.Name]
+		if h == nil {
+			h = &serverHealth{}
+			health[server.Name] = h
+		}
+		m.checkServer(ctx, server, h)
+	}
+}
+
+// checkServer pings a connected server, or tries to reconnect a disconnected
+// one once its backoff has elapsed.
+func (m *Manager) checkServer(ctx context.Context, server Server, h *serverHealth) {
+	if client := m.GetClient(server.Name); client != nil {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := client.Ping(pingCtx)
+		cancel()
+		if err == nil {
+			h.failures = 0
+			return
+		}
+
+		log.Printf("MCP server %q is unhealthy: %v", server.Name, err)
+		m.dropClient(server.Name, client)
+	}
+
+	if time.Now().Before(h.nextAttempt) {
+		return
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	client, err := m.connect(connectCtx, server)
+	cancel()
+	if err != nil {
+		h.failures++
+		delay := min(retryMinDelay<<min(h.failures-1, 10), retryMaxDelay)
+		h.nextAttempt = time.Now().Add(delay)
+		log.Printf("MCP server %q: reconnect failed (retry in %s): %v", server.Name, delay, err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.servers[server.Name]; !exists {
+		// Removed while reconnecting
+		client.Disconnect()
+		return
+	}
+	m.clients[server.Name] = client
+	m.syncToolsLocked(server.Name)
+	h.failures = 0
+	h.nextAttempt = time.Time{}
+	log.Printf("Reconnected to MCP server %q (%d tools)", server.Name, len(client.GetCachedTools()))
+}
+
+// dropClient disconnects an unhealthy client and unregisters its tools.
+func (m *Manager) dropClient(name string, client *Client) {
+	m.mu.Lock()
+	if m.clients[name] == client {
+		delete(m.clients, name)
+		m.syncToolsLocked(name)
+	}
+	m.mu.Unlock()
+
+	client.Disconnect()
+}
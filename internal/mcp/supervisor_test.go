@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/tools"
+)
+
+func TestSuperviseReconnects(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch req.Method {
+		case "tools/list":
+			fmt.Fprint(w, rpcResult(*req.ID, testTools))
+		default:
+			fmt.Fprint(w, rpcResult(*req.ID, `{}`))
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	registry := tools.NewRegistry()
+	m := NewManager()
+	m.SetRegistry(registry)
+	defer m.Close()
+
+	// Initial connection fails but the server is remembered
+	if err := m.AddServer(ctx, Server{Name: "test", URL: srv.URL, Transport: "http"}); err == nil {
+		t.Fatal("AddServer should fail while the server is down")
+	}
+	health := make(map[string]*serverHealth)
+	if connected, ok := m.ServerStatus()["test"]; !ok || connected {
+		t.Fatalf("ServerStatus = %v, want test disconnected", m.ServerStatus())
+	}
+
+	// The supervisor connects once the server is up and registers its tools
+	healthy.Store(true)
+	m.checkServers(ctx, health)
+	if !registry.Has("mcp_test_echo") {
+		t.Fatalf("tools after reconnect = %v, want mcp_test_echo", registry.List())
+	}
+	if !m.ServerStatus()["test"] {
+		t.Errorf("ServerStatus = %v, want test connected", m.ServerStatus())
+	}
+
+	// A failing server is dropped and its tools unregistered
+	healthy.Store(false)
+	m.checkServers(ctx, health)
+	if m.GetClient("test") != nil {
+		t.Error("unhealthy server should be disconnected")
+	}
+	if registry.Has("mcp_test_echo") {
+		t.Error("tools of an unhealthy server should be unregistered")
+	}
+	if health["test"].failures != 1 || health["test"].nextAttempt.IsZero() {
+		t.Errorf("health = %+v, want one failure with a backoff", health["test"])
+	}
+
+	// Retries wait for the backoff to elapse
+	healthy.Store(true)
+	m.checkServers(ctx, health)
+	if m.GetClient("test") != nil {
+		t.Error("reconnect should wait for the backoff")
+	}
+}
@@ -1,15 +1,19 @@
 // Package mcp provides Model Context Protocol (MCP) client support for connecting
-// to external tool servers via stdio or HTTP transports.
+// to external tool servers via stdio, streamable HTTP or legacy SSE transports.
 package mcp
 
+import "encoding/json"
+
 // Server represents an MCP server configuration.
 type Server struct {
 	Name      string            `json:"name"`
 	Command   string            `json:"command"`   // For stdio: command to run
 	Args      []string          `json:"args"`      // Command arguments
 	URL       string            `json:"url"`       // For HTTP: server URL
-	Transport string            `json:"transport"` // "stdio" or "http"
+	Transport string            `json:"transport"` // "stdio", "http" (streamable HTTP) or "sse"
 	Env       map[string]string `json:"env"`       // Environment variables
+	Headers   map[string]string `json:"headers"`   // For HTTP/SSE: extra request headers, e.g. Authorization
+	Naming    Naming            `json:"naming"`    // Names the server's tools are registered under
 }
 
 // Tool represents an MCP tool definition.
@@ -35,6 +39,18 @@ type Response struct {
 	Error   *Error      `json:"error,omitempty"`
 }
 
+// Message is any JSON-RPC 2.0 message received from a server: a response
+// (ID with Result or Error), a notification (Method without ID) or a
+// server-initiated request (ID and Method).
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
 // Error represents a JSON-RPC 2.0 error.
 type Error struct {
 	Code    int         `json:"code"`
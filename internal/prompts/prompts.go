@@ -0,0 +1,206 @@
+// Package prompts renders system prompts from templates. Each template is a
+// persona; users can override the built-in ones or add their own as
+// <name>.md files in the prompts directory, which are reloaded whenever
+// they change.
+package prompts
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// fileExt is the extension of template files in the prompts directory.
+const fileExt = ".md"
+
+// Vars are the values a template can use, e.g. {{.Date}} or
+// {{if .UserName}}...{{end}}.
+type Vars struct {
+	Date      string // e.g. "Monday, 2 January 2026"
+	Time      string // e.g. "15:04 CET"
+	UserName  string // the user's name when the channel provides it
+	Channel   string // e.g. "telegram", "cli"
+	ChatID    string
+	Skills    string // summary of the available skills
+	Tools     string // what the tools can do
+	BuildNote string // subsystems this build leaves out, if any
+}
+
+// NewVars returns Vars with the date and time set to now.
+func NewVars(now time.Time) Vars {
+	return Vars{
+		Date: now.Format("Monday, 2 January 2006"),
+		Time: now.Format("15:04 MST"),
+	}
+}
+
+// Info describes an available template.
+type Info struct {
+	Name string
+	Path string // the file in the prompts directory; "" for a built-in
+}
+
+// cached is a parsed template file and the file state it was parsed from.
+type cached struct {
+	tmpl    *template.Template
+	modTime time.Time
+	size    int64
+}
+
+// Library loads templates from a directory, falling back to the built-in
+// ones.
+type Library struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]cached // by name
+}
+
+// NewLibrary creates a Library reading templates from dir.
+func NewLibrary(dir string) *Library {
+	return &Library{dir: dir, files: make(map[string]cached)}
+}
+
+// Dir returns the directory templates are read from.
+func (l *Library) Dir() string {
+	return l.dir
+}
+
+// Render executes the template called name with vars. A file in the
+// prompts directory takes precedence over a built-in template and is parsed
+// again when it changes.
+func (l *Library) Render(name string, vars Vars) (string, error) {
+	tmpl, err := l.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return "", fmt.Errorf("prompt %q: %w", name, err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// lookup returns the current template called name.
+func (l *Library) lookup(name string) (*template.Template, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid prompt name %q", name)
+	}
+
+	path := filepath.Join(l.dir, name+fileExt)
+	info, err := os.Stat(path)
+	if err != nil {
+		text, ok := builtin[name]
+		if !ok {
+			return nil, fmt.Errorf("prompt %q not found in %s", name, l.dir)
+		}
+		return parse(name, text)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.files[name]; ok && c.modTime.Equal(info.ModTime()) && c.size == info.Size() {
+		return c.tmpl, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("prompt %q: %w", name, err)
+	}
+	tmpl, err := parse(name, string(data))
+	if err != nil {
+		return nil, err
+	}
+	l.files[name] = cached{tmpl: tmpl, modTime: info.ModTime(), size: info.Size()}
+	return tmpl, nil
+}
+
+// parse parses a template, naming it for error messages.
+func parse(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("prompt %q: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// List returns the available templates, built-in and from the prompts
+// directory, sorted by name.
+func (l *Library) List() []Info {
+	byName := make(map[string]Info)
+	for name := range builtin {
+		byName[name] = Info{Name: name}
+	}
+	entries, _ := os.ReadDir(l.dir)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), fileExt) {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), fileExt)
+		byName[name] = Info{Name: name, Path: filepath.Join(l.dir, e.Name())}
+	}
+
+	infos := make([]Info, 0, len(byName))
+	for _, info := range byName {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// WriteBuiltins copies the built-in templates into the prompts directory
+// for editing, leaving existing files alone. It returns the files written.
+func (l *Library) WriteBuiltins() ([]string, error) {
+	if err := os.MkdirAll(l.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create prompts directory: %w", err)
+	}
+
+	names := make([]string, 0, len(builtin))
+	for name := range builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var written []string
+	for _, name := range names {
+		path := filepath.Join(l.dir, name+fileExt)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(builtin[name]+"\n"), 0o600); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// RenderPersona renders the template called name, or fallback when name is
+// empty. If that template is missing or broken it logs a warning and uses
+// the built-in fallback template, so a bad edit never leaves the agent
+// without a prompt.
+func (l *Library) RenderPersona(name, fallback string, vars Vars) string {
+	if name == "" {
+		name = fallback
+	}
+	text, err := l.Render(name, vars)
+	if err == nil {
+		return text
+	}
+	log.Printf("Warning: %v; using the built-in %q prompt", err, fallback)
+
+	tmpl, err := parse(fallback, builtin[fallback])
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,100 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuiltinTemplatesRender(t *testing.T) {
+	lib := NewLibrary(t.TempDir())
+	vars := NewVars(time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC))
+	vars.UserName = "Alex"
+	vars.Skills = "## Skills\n- code-review"
+	vars.Tools = "read files"
+
+	for _, name := range []string{Default, CLI, Rootchat} {
+		text, err := lib.Render(name, vars)
+		if err != nil {
+			t.Fatalf("Render(%q): %v", name, err)
+		}
+		if strings.Contains(text, "{{") || !strings.HasSuffix(text, "- code-review") {
+			t.Errorf("Render(%q) = %q", name, text)
+		}
+	}
+
+	text, _ := lib.Render(Default, vars)
+	if !strings.Contains(text, "Today is Monday, 2 March 2026. You are talking to Alex.") {
+		t.Errorf("default prompt lacks date and user name: %q", text)
+	}
+}
+
+func TestLibraryFileOverridesAndReloads(t *testing.T) {
+	dir := t.TempDir()
+	lib := NewLibrary(dir)
+	path := filepath.Join(dir, "pirate.md")
+
+	if _, err := lib.Render("pirate", Vars{}); err == nil {
+		t.Fatal("missing template rendered")
+	}
+
+	if err := os.WriteFile(path, []byte("Arr, {{.UserName}}!"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := lib.Render("pirate", Vars{UserName: "Sam"}); err != nil || got != "Arr, Sam!" {
+		t.Fatalf("Render = %q, %v", got, err)
+	}
+
+	// Edits are picked up without restarting
+	if err := os.WriteFile(path, []byte("Ahoy, {{.UserName}}! Today is {{.Date}}."), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := lib.Render("pirate", Vars{UserName: "Sam", Date: "Friday"}); got != "Ahoy, Sam! Today is Friday." {
+		t.Errorf("after edit Render = %q", got)
+	}
+
+	// A broken template falls back to the built-in one
+	if err := os.WriteFile(path, []byte("{{.NoSuchField}}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := lib.RenderPersona("pirate", CLI, Vars{Tools: "read files"}); !strings.HasPrefix(got, "You are uBot, a helpful AI assistant") {
+		t.Errorf("fallback = %q", got)
+	}
+
+	for _, bad := range []string{"../secret", ".hidden"} {
+		if _, err := lib.Render(bad, Vars{}); err == nil {
+			t.Errorf("Render(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestWriteBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	lib := NewLibrary(dir)
+	if err := os.WriteFile(filepath.Join(dir, "cli.md"), []byte("mine"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := lib.WriteBuiltins()
+	if err != nil {
+		t.Fatalf("WriteBuiltins: %v", err)
+	}
+	if len(written) != 2 {
+		t.Errorf("written = %v, want default and rootchat", written)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "cli.md")); string(data) != "mine" {
+		t.Errorf("existing template overwritten: %q", data)
+	}
+
+	var fromFiles int
+	for _, info := range lib.List() {
+		if info.Path != "" {
+			fromFiles++
+		}
+	}
+	if fromFiles != 3 {
+		t.Errorf("List() has %d templates from files, want 3", fromFiles)
+	}
+}
@@ -1,6 +1,9 @@
 package providers
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+)
 
 // ToolCall represents a tool invocation requested by the LLM.
 type ToolCall struct {
@@ -40,11 +43,46 @@ func (r *ChatResponse) HasToolCalls() bool {
 
 // ChatRequest represents a request to the LLM for chat completion.
 type ChatRequest struct {
-	Messages    []ChatMessage `json:"messages"`
-	Tools       interface{}   `json:"tools,omitempty"` // []ToolDefinition
-	Model       string        `json:"model"`
-	MaxTokens   int           `json:"max_tokens"`
-	Temperature float64       `json:"temperature"`
+	Messages       []ChatMessage   `json:"messages"`
+	Tools          interface{}     `json:"tools,omitempty"` // []ToolDefinition
+	Model          string          `json:"model"`
+	MaxTokens      int             `json:"max_tokens"`
+	Temperature    float64         `json:"temperature"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"` // nil for free text
+}
+
+// Response format types.
+const (
+	FormatJSONObject = "json_object" // any JSON object
+	FormatJSONSchema = "json_schema" // JSON matching Schema
+)
+
+// ResponseFormat asks the model to answer with JSON instead of free text.
+type ResponseFormat struct {
+	Type   string                 `json:"type"`             // FormatJSONObject or FormatJSONSchema
+	Name   string                 `json:"name,omitempty"`   // names the schema, e.g. "cron_job"
+	Schema map[string]interface{} `json:"schema,omitempty"` // JSON Schema of the answer, for FormatJSONSchema
+	Strict bool                   `json:"strict,omitempty"` // ask the API to enforce the schema exactly
+}
+
+// responseFormat converts f to the OpenAI response_format object, or nil
+// when f is nil.
+func responseFormat(f *ResponseFormat) map[string]interface{} {
+	if f == nil {
+		return nil
+	}
+	if f.Type != FormatJSONSchema {
+		return map[string]interface{}{"type": f.Type}
+	}
+	name := f.Name
+	if name == "" {
+		name = "response"
+	}
+	schema := map[string]interface{}{"name": name, "schema": f.Schema}
+	if f.Strict {
+		schema["strict"] = true
+	}
+	return map[string]interface{}{"type": FormatJSONSchema, "json_schema": schema}
 }
 
 // Provider defines the interface for LLM providers.
@@ -58,3 +96,30 @@ type Provider interface {
 	// DefaultModel returns the provider's default model identifier.
 	DefaultModel() string
 }
+
+// toolSchemas converts ChatRequest.Tools to the JSON objects sent to the
+// API. Typed definitions, such as []tools.ToolDefinition, are converted
+// through their JSON encoding.
+func toolSchemas(tools interface{}) []map[string]interface{} {
+	switch t := tools.(type) {
+	case nil:
+		return nil
+	case []map[string]interface{}:
+		return t
+	case []interface{}:
+		schemas := make([]map[string]interface{}, len(t))
+		for i, tool := range t {
+			if toolMap, ok := tool.(map[string]interface{}); ok {
+				schemas[i] = toolMap
+			}
+		}
+		return schemas
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return nil
+	}
+	var schemas []map[string]interface{}
+	json.Unmarshal(data, &schemas)
+	return schemas
+}
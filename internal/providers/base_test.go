@@ -0,0 +1,40 @@
+package providers
+
+import "testing"
+
+func TestToolSchemas(t *testing.T) {
+	type function struct {
+		Name string `json:"name"`
+	}
+	type definition struct {
+		Type     string   `json:"type"`
+		Function function `json:"function"`
+	}
+
+	schemas := toolSchemas([]definition{{Type: "function", Function: function{Name: "read_file"}}})
+	if len(schemas) != 1 || schemas[0]["type"] != "function" {
+		t.Fatalf("schemas = %v", schemas)
+	}
+	if fn, _ := schemas[0]["function"].(map[string]interface{}); fn["name"] != "read_file" {
+		t.Errorf("function = %v", schemas[0]["function"])
+	}
+
+	if schemas := toolSchemas(nil); schemas != nil {
+		t.Errorf("toolSchemas(nil) = %v", schemas)
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	if f := responseFormat(nil); f != nil {
+		t.Errorf("responseFormat(nil) = %v", f)
+	}
+	if f := responseFormat(&ResponseFormat{Type: FormatJSONObject}); f["type"] != "json_object" || len(f) != 1 {
+		t.Errorf("json_object = %v", f)
+	}
+	schema := map[string]interface{}{"type": "object"}
+	f := responseFormat(&ResponseFormat{Type: FormatJSONSchema, Schema: schema, Strict: true})
+	js, _ := f["json_schema"].(map[string]interface{})
+	if f["type"] != "json_schema" || js["name"] != "response" || js["strict"] != true || js["schema"] == nil {
+		t.Errorf("json_schema = %v", f)
+	}
+}
@@ -0,0 +1,182 @@
+package providers
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// Cache is a Provider that answers repeated requests from memory. A
+// response is reused for identical requests — same provider, model,
+// messages, tools and settings — sampled at or below the configured
+// temperature, until it expires. An expired response still answers while
+// the provider is unreachable, so a brief outage does not stop cron jobs
+// that keep asking the same thing.
+type Cache struct {
+	p        Provider
+	ttl      time.Duration
+	stale    time.Duration
+	maxTemp  float64
+	maxItems int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	resp    []byte // the JSON response, decoded afresh for each caller
+	expires time.Time
+}
+
+// WithCache returns p wrapped in a Cache configured by cfg, or p itself
+// when the cache is disabled.
+func WithCache(p Provider, cfg config.ResponseCacheConfig) Provider {
+	if !cfg.Enabled {
+		return p
+	}
+	return NewCache(p, cfg)
+}
+
+// NewCache returns a Cache in front of p.
+func NewCache(p Provider, cfg config.ResponseCacheConfig) *Cache {
+	return &Cache{
+		p:        p,
+		ttl:      cfg.Lifetime(),
+		stale:    cfg.StaleLifetime(),
+		maxTemp:  cfg.MaxTemperature,
+		maxItems: cfg.Size(),
+		now:      time.Now,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Name returns the cached provider's name.
+func (c *Cache) Name() string {
+	return c.p.Name()
+}
+
+// DefaultModel returns the cached provider's default model.
+func (c *Cache) DefaultModel() string {
+	return c.p.DefaultModel()
+}
+
+// Unwrap returns the cached provider.
+func (c *Cache) Unwrap() Provider {
+	return c.p
+}
+
+// Chat returns a cached response to req or sends it to the provider.
+func (c *Cache) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	return c.do(req, func() (*ChatResponse, error) { return c.p.Chat(ctx, req) }, nil)
+}
+
+// ChatStream works like Chat, streaming responses from the provider. A
+// cached response is passed to onDelta at once.
+func (c *Cache) ChatStream(ctx context.Context, req ChatRequest, onDelta func(text string)) (*ChatResponse, error) {
+	return c.do(req, func() (*ChatResponse, error) { return ChatStream(ctx, c.p, req, onDelta) }, onDelta)
+}
+
+func (c *Cache) do(req ChatRequest, send func() (*ChatResponse, error), onDelta func(string)) (*ChatResponse, error) {
+	if req.Temperature > c.maxTemp {
+		return send()
+	}
+	key, err := c.key(req)
+	if err != nil {
+		return send()
+	}
+
+	now := c.now()
+	cached, expires, ok := c.get(key)
+	if ok && now.Before(expires) {
+		if onDelta != nil && cached.Content != "" {
+			onDelta(cached.Content)
+		}
+		return cached, nil
+	}
+
+	resp, err := send()
+	if err != nil {
+		// A recent answer beats none while the provider is down
+		if ok && IsUnreachable(err) && now.Before(expires.Add(c.stale)) {
+			log.Printf("Warning: %s unreachable, answering from a response cached %s ago: %v",
+				c.p.Name(), now.Sub(expires.Add(-c.ttl)).Round(time.Second), err)
+			if onDelta != nil && cached.Content != "" {
+				onDelta(cached.Content)
+			}
+			return cached, nil
+		}
+		return nil, err
+	}
+	if resp != nil && (resp.Content != "" || resp.HasToolCalls()) && resp.FinishReason != "length" {
+		c.put(key, resp, now.Add(c.ttl))
+	}
+	return resp, nil
+}
+
+// key identifies req sent to this provider.
+func (c *Cache) key(req ChatRequest) (string, error) {
+	if req.Model == "" {
+		req.Model = c.p.DefaultModel()
+	}
+	data, err := json.Marshal(struct {
+		Provider string      `json:"provider"`
+		Request  ChatRequest `json:"request"`
+	}{c.p.Name(), req})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// get returns a copy of the response stored under key, without its usage,
+// and when it expires.
+func (c *Cache) get(key string) (*ChatResponse, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	entry := el.Value.(*cacheEntry)
+	var resp ChatResponse
+	if err := json.Unmarshal(entry.resp, &resp); err != nil {
+		return nil, time.Time{}, false
+	}
+	resp.Usage = Usage{} // no tokens are spent on a cached answer
+	c.order.MoveToFront(el)
+	return &resp, entry.expires, true
+}
+
+// put stores resp under key, dropping the least recently used responses
+// beyond the limit.
+func (c *Cache) put(key string, resp *ChatResponse, expires time.Time) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value = &cacheEntry{key: key, resp: data, expires: expires}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, resp: data, expires: expires})
+	for c.order.Len() > c.maxItems {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
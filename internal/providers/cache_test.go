@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// countingProvider answers with the number of calls made so far, or err.
+type countingProvider struct {
+	calls int
+	err   error
+}
+
+func (p *countingProvider) Name() string         { return "fake" }
+func (p *countingProvider) DefaultModel() string { return "m" }
+
+func (p *countingProvider) Chat(_ context.Context, _ ChatRequest) (*ChatResponse, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &ChatResponse{Content: string(rune('0' + p.calls)), FinishReason: "stop"}, nil
+}
+
+func TestCache(t *testing.T) {
+	inner := &countingProvider{}
+	c := NewCache(inner, config.ResponseCacheConfig{Enabled: true, TTL: 60, StaleTTL: 600})
+	now := time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	ask := func(req ChatRequest) string {
+		t.Helper()
+		resp, err := c.Chat(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		return resp.Content
+	}
+	req := ChatRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+
+	if got := ask(req); got != "1" {
+		t.Fatalf("first = %q", got)
+	}
+	// The default model spelled out is the same request
+	withModel := req
+	withModel.Model = "m"
+	if got := ask(withModel); got != "1" || inner.calls != 1 {
+		t.Errorf("repeat = %q after %d calls", got, inner.calls)
+	}
+	var streamed string
+	if resp, _ := c.ChatStream(context.Background(), req, func(s string) { streamed += s }); resp.Content != "1" || streamed != "1" {
+		t.Errorf("stream = %q, deltas %q", resp.Content, streamed)
+	}
+
+	other := ChatRequest{Messages: []ChatMessage{{Role: "user", Content: "bye"}}}
+	if got := ask(other); got != "2" {
+		t.Errorf("other request = %q", got)
+	}
+	sampled := req
+	sampled.Temperature = 0.7
+	if ask(sampled); ask(sampled) != "4" {
+		t.Errorf("sampled requests are cached")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got := ask(req); got != "5" {
+		t.Errorf("expired = %q", got)
+	}
+
+	// An outage is bridged by the expired answer, a rejection is not
+	now = now.Add(2 * time.Minute)
+	inner.err = &StatusError{API: "API", StatusCode: http.StatusServiceUnavailable}
+	if got := ask(req); got != "5" {
+		t.Errorf("stale = %q", got)
+	}
+	inner.err = &StatusError{API: "API", StatusCode: http.StatusBadRequest}
+	if _, err := c.Chat(context.Background(), req); err == nil {
+		t.Error("rejected request answered from cache")
+	}
+	now = now.Add(time.Hour)
+	inner.err = &StatusError{API: "API", StatusCode: http.StatusServiceUnavailable}
+	if _, err := c.Chat(context.Background(), req); err == nil {
+		t.Error("answer older than staleTtl used")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	inner := &countingProvider{}
+	c := NewCache(inner, config.ResponseCacheConfig{Enabled: true, MaxEntries: 2})
+	for _, text := range []string{"a", "b", "a", "c", "a", "b"} {
+		c.Chat(context.Background(), ChatRequest{Messages: []ChatMessage{{Role: "user", Content: text}}})
+	}
+	// b was the least recently used when c arrived
+	if inner.calls != 4 {
+		t.Errorf("%d calls, want 4", inner.calls)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"sync"
 	"time"
@@ -14,12 +16,30 @@ import (
 const (
 	// CopilotAPIEndpoint is the GitHub Copilot Chat API endpoint.
 	CopilotAPIEndpoint = "https://api.githubcopilot.com/chat/completions"
+	// CopilotModelsEndpoint lists the models a Copilot subscription offers.
+	CopilotModelsEndpoint = "https://api.githubcopilot.com/models"
+	// CopilotTokenEndpoint exchanges the OAuth token for a Copilot token.
+	CopilotTokenEndpoint = "https://api.github.com/copilot_internal/v2/token"
 	// CopilotIntegrationID identifies the integration to GitHub.
 	CopilotIntegrationID = "vscode-chat"
 	// CopilotDefaultModel is the default model for Copilot.
 	CopilotDefaultModel = "gpt-4o"
+
+	// copilotModelsTTL is how long the model list is kept before it is
+	// fetched again, and copilotModelsRetry how soon a failed fetch is
+	// retried.
+	copilotModelsTTL   = time.Hour
+	copilotModelsRetry = 5 * time.Minute
+	// copilotSignInTimeout bounds a sign-in started when the OAuth token
+	// stops working; device codes expire well before.
+	copilotSignInTimeout = 20 * time.Minute
 )
 
+// CopilotSignIn runs the device flow again when GitHub no longer accepts
+// the OAuth token, because it expired or was revoked. It tells the user
+// the code to enter, saves the new token and returns it.
+type CopilotSignIn func(ctx context.Context) (string, error)
+
 // CopilotProvider implements the Provider interface for GitHub Copilot.
 type CopilotProvider struct {
 	oauthToken   string // OAuth token from device flow (gho_xxx)
@@ -28,15 +48,25 @@ type CopilotProvider struct {
 	model        string
 	client       *http.Client
 	mu           sync.Mutex
+
+	signIn    CopilotSignIn // nil: report an expired sign-in without fixing it
+	signingIn bool          // a sign-in is waiting for the user
+
+	tokenURL, chatURL, modelsURL string
+
+	modelsMu   sync.Mutex
+	models     []string  // chat models offered, as last fetched
+	modelsNext time.Time // when to fetch the models again
 }
 
 // copilotRequest represents the request body for Copilot chat completions.
 type copilotRequest struct {
-	Model       string                   `json:"model"`
-	Messages    []copilotMessage         `json:"messages"`
-	MaxTokens   int                      `json:"max_tokens,omitempty"`
-	Temperature float64                  `json:"temperature,omitempty"`
-	Tools       []map[string]interface{} `json:"tools,omitempty"`
+	Model          string                   `json:"model"`
+	Messages       []copilotMessage         `json:"messages"`
+	MaxTokens      int                      `json:"max_tokens,omitempty"`
+	Temperature    float64                  `json:"temperature,omitempty"`
+	Tools          []map[string]interface{} `json:"tools,omitempty"`
+	ResponseFormat map[string]interface{}   `json:"response_format,omitempty"`
 }
 
 // copilotMessage represents a message in the Copilot format.
@@ -94,12 +124,21 @@ func NewCopilotProvider(oauthToken, model string) *CopilotProvider {
 	return &CopilotProvider{
 		oauthToken: oauthToken,
 		model:      model,
-		client: &http.Client{
-			Timeout: 120 * time.Second,
-		},
+		client:     NewHTTPClient("copilot", 120*time.Second),
+		tokenURL:   CopilotTokenEndpoint,
+		chatURL:    CopilotAPIEndpoint,
+		modelsURL:  CopilotModelsEndpoint,
 	}
 }
 
+// SetSignIn makes the provider run signIn when GitHub stops accepting the
+// OAuth token. Requests fail until the user has signed in again.
+func (p *CopilotProvider) SetSignIn(signIn CopilotSignIn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.signIn = signIn
+}
+
 // ensureValidToken ensures we have a valid Copilot token.
 // The OAuth token is exchanged for a short-lived Copilot token.
 func (p *CopilotProvider) ensureValidToken(ctx context.Context) error {
@@ -113,6 +152,10 @@ func (p *CopilotProvider) ensureValidToken(ctx context.Context) error {
 
 	// Exchange OAuth token for Copilot token
 	token, expiresAt, err := p.exchangeToken(ctx)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized {
+		return p.signInLocked(err)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get copilot token: %w", err)
 	}
@@ -122,10 +165,42 @@ func (p *CopilotProvider) ensureValidToken(ctx context.Context) error {
 	return nil
 }
 
+// signInLocked reports that GitHub no longer accepts the OAuth token and,
+// when a sign-in hook is set, runs it in the background unless it already
+// runs. Must be called with p.mu held.
+func (p *CopilotProvider) signInLocked(cause error) error {
+	p.copilotToken = ""
+	if p.signIn == nil {
+		return fmt.Errorf("GitHub Copilot sign-in expired or was revoked, run `ubot setup` to sign in again: %w", cause)
+	}
+	if !p.signingIn {
+		p.signingIn = true
+		go p.runSignIn(p.signIn)
+	}
+	return fmt.Errorf("GitHub Copilot sign-in expired or was revoked, waiting for the user to sign in again: %w", cause)
+}
+
+// runSignIn runs signIn and switches to the token it returns.
+func (p *CopilotProvider) runSignIn(signIn CopilotSignIn) {
+	ctx, cancel := context.WithTimeout(context.Background(), copilotSignInTimeout)
+	defer cancel()
+	token, err := signIn(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.signingIn = false
+	if err != nil {
+		log.Printf("Warning: GitHub Copilot sign-in failed: %v", err)
+		return
+	}
+	p.oauthToken = token
+	p.copilotToken = ""
+	log.Printf("GitHub Copilot: signed in again")
+}
+
 // exchangeToken exchanges the OAuth token for a Copilot API token.
 func (p *CopilotProvider) exchangeToken(ctx context.Context) (string, time.Time, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
-		"https://api.github.com/copilot_internal/v2/token", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.tokenURL, nil)
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -146,7 +221,7 @@ func (p *CopilotProvider) exchangeToken(ctx context.Context) (string, time.Time,
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", time.Time{}, fmt.Errorf("token exchange failed (status %d): %s", resp.StatusCode, string(body))
+		return "", time.Time{}, &StatusError{API: "Copilot token exchange", StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var tokenResp struct {
@@ -175,13 +250,117 @@ func (p *CopilotProvider) DefaultModel() string {
 	return p.model
 }
 
-// Chat sends a chat completion request to the GitHub Copilot API.
-func (p *CopilotProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
-	// Ensure we have a valid Copilot token
-	if err := p.ensureValidToken(ctx); err != nil {
+// do sends an authenticated request to the Copilot API and returns the
+// status and body of the response. When the API rejects the Copilot token
+// before it was due to expire, it is exchanged again and the request sent
+// once more.
+func (p *CopilotProvider) do(ctx context.Context, method, url string, body []byte) (int, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		if err := p.ensureValidToken(ctx); err != nil {
+			return 0, nil, err
+		}
+		p.mu.Lock()
+		token := p.copilotToken
+		p.mu.Unlock()
+
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, method, url, reader)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		// Set required headers for Copilot API
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		httpReq.Header.Set("Editor-Version", "vscode/1.85.0")
+		httpReq.Header.Set("Editor-Plugin-Version", "copilot-chat/0.12.0")
+		httpReq.Header.Set("User-Agent", "GitHubCopilotChat/0.12.0")
+		httpReq.Header.Set("Copilot-Integration-Id", CopilotIntegrationID)
+
+		resp, err := p.client.Do(httpReq)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			p.mu.Lock()
+			if p.copilotToken == token {
+				p.copilotToken = ""
+			}
+			p.mu.Unlock()
+			continue
+		}
+		return resp.StatusCode, respBody, nil
+	}
+}
+
+// ListModels returns the chat models the Copilot subscription offers in
+// its model picker. The list is fetched again after an hour; when that
+// fails, the last list is kept.
+func (p *CopilotProvider) ListModels(ctx context.Context) ([]string, error) {
+	p.modelsMu.Lock()
+	defer p.modelsMu.Unlock()
+
+	if time.Now().Before(p.modelsNext) {
+		return p.models, nil
+	}
+	models, err := p.fetchModels(ctx)
+	if err != nil {
+		p.modelsNext = time.Now().Add(copilotModelsRetry)
+		return p.models, err
+	}
+	p.models = models
+	p.modelsNext = time.Now().Add(copilotModelsTTL)
+	return models, nil
+}
+
+// fetchModels asks the models endpoint for the chat models offered.
+func (p *CopilotProvider) fetchModels(ctx context.Context) ([]string, error) {
+	status, body, err := p.do(ctx, http.MethodGet, p.modelsURL, nil)
+	if err != nil {
 		return nil, err
 	}
+	if status != http.StatusOK {
+		return nil, &StatusError{API: "Copilot API", StatusCode: status, Body: string(body)}
+	}
+
+	var list struct {
+		Data []struct {
+			ID                 string `json:"id"`
+			ModelPickerEnabled *bool  `json:"model_picker_enabled"`
+			Capabilities       struct {
+				Type string `json:"type"`
+			} `json:"capabilities"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse models: %w", err)
+	}
+
+	var models []string
+	seen := make(map[string]bool)
+	for _, m := range list.Data {
+		// Embedding models and ones hidden from the picker cannot chat
+		if m.ID == "" || seen[m.ID] || (m.Capabilities.Type != "" && m.Capabilities.Type != "chat") ||
+			(m.ModelPickerEnabled != nil && !*m.ModelPickerEnabled) {
+			continue
+		}
+		seen[m.ID] = true
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
 
+// Chat sends a chat completion request to the GitHub Copilot API.
+func (p *CopilotProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	// Convert messages to Copilot format
 	messages := make([]copilotMessage, len(req.Messages))
 	for i, msg := range req.Messages {
@@ -227,18 +406,8 @@ func (p *CopilotProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespo
 	}
 
 	// Convert tools if present
-	if req.Tools != nil {
-		if tools, ok := req.Tools.([]map[string]interface{}); ok {
-			copilotReq.Tools = tools
-		} else if tools, ok := req.Tools.([]interface{}); ok {
-			copilotReq.Tools = make([]map[string]interface{}, len(tools))
-			for i, t := range tools {
-				if toolMap, ok := t.(map[string]interface{}); ok {
-					copilotReq.Tools[i] = toolMap
-				}
-			}
-		}
-	}
+	copilotReq.Tools = toolSchemas(req.Tools)
+	copilotReq.ResponseFormat = responseFormat(req.ResponseFormat)
 
 	// Marshal request body
 	body, err := json.Marshal(copilotReq)
@@ -246,36 +415,15 @@ func (p *CopilotProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespo
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, CopilotAPIEndpoint, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set required headers for Copilot API
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.copilotToken))
-	httpReq.Header.Set("Editor-Version", "vscode/1.85.0")
-	httpReq.Header.Set("Editor-Plugin-Version", "copilot-chat/0.12.0")
-	httpReq.Header.Set("User-Agent", "GitHubCopilotChat/0.12.0")
-	httpReq.Header.Set("Copilot-Integration-Id", CopilotIntegrationID)
-
 	// Send request
-	resp, err := p.client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response body
-	respBody, err := io.ReadAll(resp.Body)
+	status, respBody, err := p.do(ctx, http.MethodPost, p.chatURL, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
 	// Check for HTTP errors
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Copilot API error (status %d): %s", resp.StatusCode, string(respBody))
+	if status != http.StatusOK {
+		return nil, &StatusError{API: "Copilot API", StatusCode: status, Body: string(respBody)}
 	}
 
 	// Parse response
@@ -56,7 +56,7 @@ func RequestDeviceCode(ctx context.Context) (*DeviceCodeResponse, error) {
 	req.Header.Set("Accept", "application/json")
 
 	// Send request
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := NewHTTPClient("github", 30*time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to request device code: %w", err)
@@ -92,7 +92,7 @@ func PollForAccessToken(ctx context.Context, deviceCode string, interval int) (s
 	ticker := time.NewTicker(time.Duration(interval) * time.Second)
 	defer ticker.Stop()
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := NewHTTPClient("github", 30*time.Second)
 
 	for {
 		select {
@@ -180,12 +180,29 @@ func requestAccessToken(ctx context.Context, client *http.Client, deviceCode str
 	}
 }
 
+// RunDeviceFlow signs in to GitHub with the device flow: it requests a
+// code, passes it to prompt to show the user and waits until they have
+// entered it or the code expires.
+func RunDeviceFlow(ctx context.Context, prompt func(code *DeviceCodeResponse)) (string, error) {
+	code, err := RequestDeviceCode(ctx)
+	if err != nil {
+		return "", err
+	}
+	prompt(code)
+
+	if code.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(code.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	return PollForAccessToken(ctx, code.DeviceCode, code.Interval)
+}
+
 // GetCopilotAccessToken retrieves a fresh access token from GitHub.
 // This exchanges the OAuth token for a Copilot-specific access token.
 func GetCopilotAccessToken(ctx context.Context, oauthToken string) (string, error) {
 	// Request a Copilot-specific token from GitHub
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
-		"https://api.github.com/copilot_internal/v2/token", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, CopilotTokenEndpoint, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create copilot token request: %w", err)
 	}
@@ -193,7 +210,7 @@ func GetCopilotAccessToken(ctx context.Context, oauthToken string) (string, erro
 	req.Header.Set("Authorization", fmt.Sprintf("token %s", oauthToken))
 	req.Header.Set("Accept", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := NewHTTPClient("github", 30*time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to request copilot token: %w", err)
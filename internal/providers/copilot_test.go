@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/failure"
+)
+
+func TestCopilotSignInAndModels(t *testing.T) {
+	var mu sync.Mutex
+	valid := "gho_new" // the only OAuth token GitHub accepts
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/token":
+			if r.Header.Get("Authorization") != "token "+valid {
+				http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"token":"cop","expires_at":%d}`, time.Now().Add(time.Hour).Unix())
+		case "/models":
+			fmt.Fprint(w, `{"data":[
+				{"id":"gpt-4o","model_picker_enabled":true,"capabilities":{"type":"chat"}},
+				{"id":"claude-sonnet-4","model_picker_enabled":true,"capabilities":{"type":"chat"}},
+				{"id":"gpt-4o","model_picker_enabled":true,"capabilities":{"type":"chat"}},
+				{"id":"text-embedding-3-small","capabilities":{"type":"embeddings"}},
+				{"id":"gpt-4o-mini-2024-07-18","model_picker_enabled":false,"capabilities":{"type":"chat"}}
+			]}`)
+		case "/chat":
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewCopilotProvider("gho_revoked", "")
+	p.tokenURL, p.chatURL, p.modelsURL = srv.URL+"/token", srv.URL+"/chat", srv.URL+"/models"
+	req := ChatRequest{Messages: []ChatMessage{{Role: "user", Content: "hello"}}}
+
+	// Without a sign-in hook the failure is only reported
+	_, err := p.Chat(context.Background(), req)
+	if failure.Of(err) != failure.ProviderAuth || !strings.Contains(err.Error(), "ubot setup") {
+		t.Fatalf("err = %v, want an auth failure pointing to setup", err)
+	}
+
+	signedIn := make(chan struct{})
+	p.SetSignIn(func(ctx context.Context) (string, error) {
+		defer close(signedIn)
+		return "gho_new", nil
+	})
+	if _, err := p.Chat(context.Background(), req); failure.Of(err) != failure.ProviderAuth {
+		t.Fatalf("err = %v, want an auth failure while signing in", err)
+	}
+	<-signedIn
+	for i := 0; ; i++ {
+		resp, err := p.Chat(context.Background(), req)
+		if err == nil {
+			if resp.Content != "hi" {
+				t.Errorf("content = %q", resp.Content)
+			}
+			break
+		}
+		if i == 100 {
+			t.Fatalf("still failing after sign-in: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	models, err := ListModels(context.Background(), NewRoutingProvider(NewSwitchable(p), config.AgentsConfig{Routing: config.RoutingConfig{Answer: "gpt-4o"}}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(models, " "); got != "gpt-4o claude-sonnet-4" {
+		t.Errorf("models = %s", got)
+	}
+}
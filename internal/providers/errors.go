@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/hkuds/ubot/internal/failure"
+)
+
+// StatusError is returned when a provider's API answers with an HTTP error.
+type StatusError struct {
+	API        string // e.g. "API", "Copilot API"
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s error (status %d): %s", e.API, e.StatusCode, e.Body)
+}
+
+// Category sorts the error for the user-facing message.
+func (e *StatusError) Category() failure.Category {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return failure.ProviderAuth
+	case http.StatusTooManyRequests:
+		return failure.RateLimit
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return failure.Timeout
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return failure.Unreachable
+	}
+	return failure.Internal
+}
+
+// IsUnreachable reports whether err means the provider could not be reached
+// at all — a network failure, a timeout or a gateway error in front of the
+// API, or its circuit being open — as opposed to the request itself being
+// rejected. Such requests are worth retrying later unchanged. Cancellation
+// is not counted.
+func IsUnreachable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
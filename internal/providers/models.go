@@ -0,0 +1,33 @@
+package providers
+
+import "context"
+
+// ModelLister is implemented by providers that can tell which models they
+// offer, such as GitHub Copilot.
+type ModelLister interface {
+	// ListModels returns the IDs of the chat models the provider offers.
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// Wrapper is implemented by providers that forward requests to another
+// provider, such as the response cache and the model router.
+type Wrapper interface {
+	// Unwrap returns the provider requests are forwarded to.
+	Unwrap() Provider
+}
+
+// ListModels returns the models p offers, looking through wrappers for a
+// ModelLister. It returns nil when the provider cannot tell.
+func ListModels(ctx context.Context, p Provider) ([]string, error) {
+	for p != nil {
+		if l, ok := p.(ModelLister); ok {
+			return l.ListModels(ctx)
+		}
+		w, ok := p.(Wrapper)
+		if !ok {
+			return nil, nil
+		}
+		p = w.Unwrap()
+	}
+	return nil, nil
+}
@@ -24,11 +24,13 @@ type OpenAIProvider struct {
 
 // openAIRequest represents the request body for OpenAI chat completions.
 type openAIRequest struct {
-	Model       string                   `json:"model"`
-	Messages    []openAIMessage          `json:"messages"`
-	MaxTokens   int                      `json:"max_tokens,omitempty"`
-	Temperature float64                  `json:"temperature,omitempty"`
-	Tools       []map[string]interface{} `json:"tools,omitempty"`
+	Model          string                   `json:"model"`
+	Messages       []openAIMessage          `json:"messages"`
+	MaxTokens      int                      `json:"max_tokens,omitempty"`
+	Temperature    float64                  `json:"temperature,omitempty"`
+	Tools          []map[string]interface{} `json:"tools,omitempty"`
+	ResponseFormat map[string]interface{}   `json:"response_format,omitempty"`
+	Stream         bool                     `json:"stream,omitempty"`
 }
 
 // openAIMessage represents a message in the OpenAI format.
@@ -86,9 +88,7 @@ func NewOpenAIProvider(name, apiKey, apiBase, defaultModel string) *OpenAIProvid
 		apiKey:       apiKey,
 		apiBase:      apiBase,
 		defaultModel: defaultModel,
-		client: &http.Client{
-			Timeout: 120 * time.Second,
-		},
+		client:       NewHTTPClient(name, 120*time.Second),
 	}
 }
 
@@ -102,8 +102,9 @@ func (p *OpenAIProvider) DefaultModel() string {
 	return p.defaultModel
 }
 
-// Chat sends a chat completion request to the OpenAI-compatible API.
-func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+// newHTTPRequest converts req to an OpenAI chat completions request, asking
+// for a streamed response when stream is set.
+func (p *OpenAIProvider) newHTTPRequest(ctx context.Context, req ChatRequest, stream bool) (*http.Request, error) {
 	// Convert messages to OpenAI format
 	messages := make([]openAIMessage, len(req.Messages))
 	for i, msg := range req.Messages {
@@ -146,21 +147,12 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 		Messages:    messages,
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
+		Stream:      stream,
 	}
 
 	// Convert tools if present
-	if req.Tools != nil {
-		if tools, ok := req.Tools.([]map[string]interface{}); ok {
-			openAIReq.Tools = tools
-		} else if tools, ok := req.Tools.([]interface{}); ok {
-			openAIReq.Tools = make([]map[string]interface{}, len(tools))
-			for i, t := range tools {
-				if toolMap, ok := t.(map[string]interface{}); ok {
-					openAIReq.Tools[i] = toolMap
-				}
-			}
-		}
-	}
+	openAIReq.Tools = toolSchemas(req.Tools)
+	openAIReq.ResponseFormat = responseFormat(req.ResponseFormat)
 
 	// Marshal request body
 	body, err := json.Marshal(openAIReq)
@@ -188,6 +180,16 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 		httpReq.Header.Set("anthropic-version", "2023-06-01")
 	}
 
+	return httpReq, nil
+}
+
+// Chat sends a chat completion request to the OpenAI-compatible API.
+func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	httpReq, err := p.newHTTPRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+
 	// Send request
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -203,7 +205,7 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 
 	// Check for HTTP errors
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &StatusError{API: "API", StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	// Parse response
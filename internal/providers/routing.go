@@ -0,0 +1,115 @@
+package providers
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// Phase is the part of a turn a chat request serves.
+type Phase string
+
+const (
+	PhasePlanning Phase = "planning" // choosing the tool calls
+	PhaseSummary  Phase = "summary"  // summarizing content, e.g. a screenshot
+	PhaseAnswer   Phase = "answer"   // writing the reply the user reads
+)
+
+type phaseKey struct{}
+
+// WithPhase marks the chat requests made with ctx as serving phase.
+func WithPhase(ctx context.Context, phase Phase) context.Context {
+	return context.WithValue(ctx, phaseKey{}, phase)
+}
+
+// PhaseFromContext returns the phase ctx was marked with, or "" when the
+// request is an ordinary step of a turn.
+func PhaseFromContext(ctx context.Context) Phase {
+	phase, _ := ctx.Value(phaseKey{}).(Phase)
+	return phase
+}
+
+// RoutingProvider sends each request to the model configured for its
+// phase in agents.routing. A step of a turn that offers tools goes to the
+// planning model first; if it calls tools, those calls are returned, and
+// if it is ready to answer, the answer model writes the reply instead.
+// Requests for a model a chat chose with /model are left alone.
+type RoutingProvider struct {
+	Provider
+	routing config.RoutingConfig
+	model   string                           // agents.defaults.model
+	channel func(ctx context.Context) string // the conversation's channel, "" when unknown
+}
+
+// NewRoutingProvider returns p routing requests as agents.routing says,
+// or p itself when no routes are configured. channel tells which channel
+// a request comes from, for the per-channel routes.
+func NewRoutingProvider(p Provider, agents config.AgentsConfig, channel func(ctx context.Context) string) Provider {
+	if !agents.Routing.Enabled() {
+		return p
+	}
+	return &RoutingProvider{Provider: p, routing: agents.Routing, model: agents.Defaults.Model, channel: channel}
+}
+
+// Unwrap returns the provider requests are routed to.
+func (r *RoutingProvider) Unwrap() Provider {
+	return r.Provider
+}
+
+// Chat sends req to the model for its phase.
+func (r *RoutingProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	return r.route(ctx, req, nil)
+}
+
+// ChatStream works like Chat, streaming the answer. The planning model's
+// output is not streamed, since it is replaced by the answer model's.
+func (r *RoutingProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(text string)) (*ChatResponse, error) {
+	return r.route(ctx, req, onDelta)
+}
+
+func (r *RoutingProvider) route(ctx context.Context, req ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	send := func(model string, stream bool) (*ChatResponse, error) {
+		if model != "" {
+			req.Model = model
+		}
+		if stream && onDelta != nil {
+			return ChatStream(ctx, r.Provider, req, onDelta)
+		}
+		return r.Provider.Chat(ctx, req)
+	}
+
+	// A chat that chose its model with /model keeps it
+	if req.Model != "" && req.Model != r.model && req.Model != r.Provider.DefaultModel() {
+		return send("", true)
+	}
+	channel := ""
+	if r.channel != nil {
+		channel = r.channel(ctx)
+	}
+	routes := r.routing.For(channel)
+
+	switch PhaseFromContext(ctx) {
+	case PhasePlanning:
+		return send(routes.Planning, true)
+	case PhaseSummary:
+		return send(routes.Summary, true)
+	case PhaseAnswer:
+		return send(routes.Answer, true)
+	}
+
+	if routes.Planning == "" || routes.Planning == routes.Answer || !offersTools(req.Tools) {
+		return send(routes.Answer, true)
+	}
+	plan, err := send(routes.Planning, false)
+	if err != nil || plan.HasToolCalls() {
+		return plan, err
+	}
+	return send(routes.Answer, true)
+}
+
+// offersTools reports whether tools, a ChatRequest's Tools, lists any.
+func offersTools(tools interface{}) bool {
+	v := reflect.ValueOf(tools)
+	return v.Kind() == reflect.Slice && v.Len() > 0
+}
@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// modelProvider records the models asked and calls tools when the
+// planner is asked.
+type modelProvider struct {
+	models []string
+}
+
+func (p *modelProvider) Name() string         { return "fake" }
+func (p *modelProvider) DefaultModel() string { return "default" }
+
+func (p *modelProvider) Chat(_ context.Context, req ChatRequest) (*ChatResponse, error) {
+	p.models = append(p.models, req.Model)
+	last := req.Messages[len(req.Messages)-1]
+	if req.Model == "cheap" && last.Role == "user" && last.Content == "look it up" {
+		return &ChatResponse{ToolCalls: []ToolCall{{ID: "1", Name: "web_search"}}}, nil
+	}
+	return &ChatResponse{Content: "from " + req.Model}, nil
+}
+
+func TestRoutingProvider(t *testing.T) {
+	agents := config.AgentsConfig{
+		Defaults: config.AgentDefaults{Model: "main"},
+		Routing: config.RoutingConfig{
+			Planning: "cheap",
+			Answer:   "strong",
+			Channels: map[string]config.ModelRoutes{"whatsapp": {Answer: "medium"}},
+		},
+	}
+	if p := NewRoutingProvider(&modelProvider{}, config.AgentsConfig{}, nil); p == nil {
+		t.Fatal("nil provider")
+	} else if _, ok := p.(*RoutingProvider); ok {
+		t.Error("wrapped without routes")
+	}
+
+	inner := &modelProvider{}
+	channel := ""
+	r := NewRoutingProvider(inner, agents, func(context.Context) string { return channel })
+	tools := []map[string]interface{}{{"name": "web_search"}}
+	ask := func(ctx context.Context, model, content string) *ChatResponse {
+		t.Helper()
+		inner.models = nil
+		resp, err := r.Chat(ctx, ChatRequest{Model: model, Tools: tools, Messages: []ChatMessage{{Role: "user", Content: content}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// The planner's tool calls are returned as they are
+	if resp := ask(context.Background(), "main", "look it up"); !resp.HasToolCalls() || strings.Join(inner.models, ",") != "cheap" {
+		t.Errorf("planning: %+v via %v", resp, inner.models)
+	}
+	// Once the planner is ready to answer, the answer model replies
+	if resp := ask(context.Background(), "main", "hello"); resp.Content != "from strong" || strings.Join(inner.models, ",") != "cheap,strong" {
+		t.Errorf("answer: %+v via %v", resp, inner.models)
+	}
+	channel = "whatsapp"
+	if resp := ask(context.Background(), "", "hello"); resp.Content != "from medium" {
+		t.Errorf("channel override: %+v via %v", resp, inner.models)
+	}
+	// A model chosen with /model is kept; an unrouted phase too
+	if resp := ask(context.Background(), "other", "look it up"); resp.Content != "from other" || len(inner.models) != 1 {
+		t.Errorf("chosen model: %+v via %v", resp, inner.models)
+	}
+	if resp := ask(WithPhase(context.Background(), PhaseSummary), "default", "describe"); resp.Content != "from default" {
+		t.Errorf("summary: %+v via %v", resp, inner.models)
+	}
+
+	var streamed string
+	inner.models = nil
+	resp, err := r.(*RoutingProvider).ChatStream(context.Background(), ChatRequest{Tools: tools, Messages: []ChatMessage{{Role: "user", Content: "hi"}}},
+		func(s string) { streamed += s })
+	if err != nil || resp.Content != "from medium" || streamed != "from medium" {
+		t.Errorf("stream = %+v, %v, deltas %q", resp, err, streamed)
+	}
+}
@@ -0,0 +1,166 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Streamer is implemented by providers that can stream a response while it
+// is generated.
+type Streamer interface {
+	// ChatStream works like Chat, passing response text to onDelta as it
+	// arrives.
+	ChatStream(ctx context.Context, req ChatRequest, onDelta func(text string)) (*ChatResponse, error)
+}
+
+// ChatStream sends req to p, streaming the response text to onDelta when p
+// supports it and passing the whole text at once otherwise.
+func ChatStream(ctx context.Context, p Provider, req ChatRequest, onDelta func(text string)) (*ChatResponse, error) {
+	if s, ok := p.(Streamer); ok {
+		return s.ChatStream(ctx, req, onDelta)
+	}
+	resp, err := p.Chat(ctx, req)
+	if err == nil && resp.Content != "" {
+		onDelta(resp.Content)
+	}
+	return resp, err
+}
+
+// openAIStreamChunk is one server-sent event of a streamed chat completion.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int                `json:"index"`
+				ID       string             `json:"id"`
+				Function openAIFunctionCall `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ChatStream sends a streamed chat completion request to the
+// OpenAI-compatible API. Tool calls are assembled from their fragments and
+// returned with the response.
+func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(text string)) (*ChatResponse, error) {
+	httpReq, err := p.newHTTPRequest(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{API: "API", StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	chatResp := &ChatResponse{}
+	var content strings.Builder
+	calls := make(map[int]*openAIToolCall)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse stream: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			chatResp.Usage = Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			onDelta(choice.Delta.Content)
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			call, ok := calls[tc.Index]
+			if !ok {
+				call = &openAIToolCall{Type: "function"}
+				calls[tc.Index] = call
+			}
+			if tc.ID != "" {
+				call.ID = tc.ID
+			}
+			call.Function.Name += tc.Function.Name
+			call.Function.Arguments += tc.Function.Arguments
+		}
+		if choice.FinishReason != "" {
+			chatResp.FinishReason = choice.FinishReason
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	chatResp.Content = content.String()
+	indexes := make([]int, 0, len(calls))
+	for i := range calls {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		tc := calls[i]
+		chatResp.ToolCalls = append(chatResp.ToolCalls, ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: toolArguments(tc.Function.Arguments),
+		})
+	}
+	return chatResp, nil
+}
+
+// toolArguments parses the JSON arguments of a tool call, keeping them as a
+// raw string in "_raw" if they are not valid JSON.
+func toolArguments(raw string) map[string]interface{} {
+	if strings.TrimSpace(raw) == "" {
+		return map[string]interface{}{}
+	}
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		return map[string]interface{}{"_raw": raw}
+	}
+	return args
+}
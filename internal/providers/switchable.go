@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"context"
+	"sync"
+)
+
+// Switchable is a Provider whose underlying provider can be replaced while
+// it is in use, e.g. when the configuration is reloaded. Requests already
+// sent finish on the provider they started with.
+type Switchable struct {
+	mu sync.RWMutex
+	p  Provider
+}
+
+// NewSwitchable returns a Switchable that starts out forwarding to p.
+func NewSwitchable(p Provider) *Switchable {
+	return &Switchable{p: p}
+}
+
+// Current returns the provider requests are forwarded to.
+func (s *Switchable) Current() Provider {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.p
+}
+
+// Switch forwards later requests to p.
+func (s *Switchable) Switch(p Provider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.p = p
+}
+
+// Name returns the current provider's name.
+func (s *Switchable) Name() string {
+	return s.Current().Name()
+}
+
+// DefaultModel returns the current provider's default model.
+func (s *Switchable) DefaultModel() string {
+	return s.Current().DefaultModel()
+}
+
+// Unwrap returns the current provider.
+func (s *Switchable) Unwrap() Provider {
+	return s.Current()
+}
+
+// Chat sends req to the current provider.
+func (s *Switchable) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	return s.Current().Chat(ctx, req)
+}
+
+// ChatStream streams req from the current provider if it supports
+// streaming.
+func (s *Switchable) ChatStream(ctx context.Context, req ChatRequest, onDelta func(text string)) (*ChatResponse, error) {
+	return ChatStream(ctx, s.Current(), req, onDelta)
+}
@@ -0,0 +1,239 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// trafficRotatedLayout is the timestamp format of rotated traffic logs,
+// named <provider>-<timestamp>.jsonl.
+const trafficRotatedLayout = "20060102-150405.000000000"
+
+// TrafficEntry is one request to the provider in the traffic log.
+// Request and Response are only filled in the hashed and full modes.
+type TrafficEntry struct {
+	Time          time.Time        `json:"time"`
+	Provider      string           `json:"provider"`
+	Model         string           `json:"model"`
+	Mode          string           `json:"mode"`
+	DurationMs    int64            `json:"durationMs"`
+	Messages      int              `json:"messages"`
+	Tools         int              `json:"tools"`
+	SentChars     int              `json:"sentChars"`
+	ReceivedChars int              `json:"receivedChars"`
+	Usage         *Usage           `json:"usage,omitempty"`
+	FinishReason  string           `json:"finishReason,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	Request       []TrafficMessage `json:"request,omitempty"`
+	Response      *TrafficMessage  `json:"response,omitempty"`
+}
+
+// TrafficMessage is a message as the traffic log keeps it: its text in the
+// full mode, a SHA-256 of it in the hashed mode.
+type TrafficMessage struct {
+	Role      string            `json:"role"`
+	Content   string            `json:"content,omitempty"`
+	SHA256    string            `json:"sha256,omitempty"`
+	Chars     int               `json:"chars"`
+	ToolCalls []TrafficToolCall `json:"toolCalls,omitempty"`
+}
+
+// TrafficToolCall is a tool call as the traffic log keeps it.
+type TrafficToolCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+}
+
+// TrafficLog is a Provider that writes every request it forwards, and the
+// response, to a JSONL file named after the provider. How much of the
+// messages is kept depends on the mode, one of the config.Traffic modes.
+type TrafficLog struct {
+	p        Provider
+	mode     string
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	mu sync.Mutex // one write at a time
+}
+
+// WithTrafficLog returns p writing its traffic to dir as cfg says, or p
+// itself when the log is off.
+func WithTrafficLog(p Provider, cfg config.TrafficLogConfig, dir string) Provider {
+	if !cfg.Enabled {
+		return p
+	}
+	mode := cfg.Mode
+	if mode == "" {
+		mode = config.TrafficMetadata
+	}
+	return &TrafficLog{p: p, mode: mode, dir: dir, maxSize: cfg.MaxBytes(), maxFiles: cfg.Keep()}
+}
+
+// Name returns the logged provider's name.
+func (t *TrafficLog) Name() string {
+	return t.p.Name()
+}
+
+// DefaultModel returns the logged provider's default model.
+func (t *TrafficLog) DefaultModel() string {
+	return t.p.DefaultModel()
+}
+
+// Unwrap returns the logged provider.
+func (t *TrafficLog) Unwrap() Provider {
+	return t.p
+}
+
+// Chat forwards req and logs it with the response.
+func (t *TrafficLog) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	start := time.Now()
+	resp, err := t.p.Chat(ctx, req)
+	t.record(start, req, resp, err)
+	return resp, err
+}
+
+// ChatStream forwards req, streaming the response when the provider can,
+// and logs it with the whole response.
+func (t *TrafficLog) ChatStream(ctx context.Context, req ChatRequest, onDelta func(text string)) (*ChatResponse, error) {
+	start := time.Now()
+	resp, err := ChatStream(ctx, t.p, req, onDelta)
+	t.record(start, req, resp, err)
+	return resp, err
+}
+
+// record writes the entry for a finished request. Write errors are logged
+// rather than failing the request.
+func (t *TrafficLog) record(start time.Time, req ChatRequest, resp *ChatResponse, err error) {
+	e := TrafficEntry{
+		Time:       start,
+		Provider:   t.p.Name(),
+		Model:      req.Model,
+		Mode:       t.mode,
+		DurationMs: time.Since(start).Milliseconds(),
+		Messages:   len(req.Messages),
+		Tools:      len(toolSchemas(req.Tools)),
+	}
+	if e.Model == "" {
+		e.Model = t.p.DefaultModel()
+	}
+	for _, msg := range req.Messages {
+		m := t.message(msg.Role, msg.Content, msg.ToolCalls)
+		e.SentChars += m.Chars
+		if t.mode != config.TrafficMetadata {
+			e.Request = append(e.Request, m)
+		}
+	}
+	if resp != nil {
+		m := t.message("assistant", resp.Content, resp.ToolCalls)
+		e.ReceivedChars = m.Chars
+		e.Usage = &resp.Usage
+		e.FinishReason = resp.FinishReason
+		if t.mode != config.TrafficMetadata {
+			e.Response = &m
+		}
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if err := t.write(e); err != nil {
+		log.Printf("Warning: failed to write traffic log: %v", err)
+	}
+}
+
+// message keeps what the mode allows of a message.
+func (t *TrafficLog) message(role string, content interface{}, calls []ToolCall) TrafficMessage {
+	text, ok := content.(string)
+	if !ok && content != nil {
+		// Images and other parts are kept as their JSON
+		data, _ := json.Marshal(content)
+		text = string(data)
+	}
+	m := TrafficMessage{Role: role, Chars: len([]rune(text))}
+	m.Content, m.SHA256 = t.keep(text)
+	for _, call := range calls {
+		args, _ := json.Marshal(call.Arguments)
+		tc := TrafficToolCall{Name: call.Name}
+		tc.Arguments, tc.SHA256 = t.keep(string(args))
+		m.ToolCalls = append(m.ToolCalls, tc)
+		m.Chars += len([]rune(string(args)))
+	}
+	return m
+}
+
+// keep returns text as the full mode keeps it, or its hash as the hashed
+// mode does.
+func (t *TrafficLog) keep(text string) (content, hash string) {
+	switch {
+	case text == "":
+		return "", ""
+	case t.mode == config.TrafficFull:
+		return text, ""
+	case t.mode == config.TrafficHashed:
+		sum := sha256.Sum256([]byte(text))
+		return "", hex.EncodeToString(sum[:])
+	}
+	return "", ""
+}
+
+// write appends e to the provider's log, rotating it first if it is full.
+func (t *TrafficLog) write(e TrafficEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := os.MkdirAll(t.dir, 0o700); err != nil {
+		return err
+	}
+	path := filepath.Join(t.dir, e.Provider+".jsonl")
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 && info.Size()+int64(len(data)) > t.maxSize {
+		if err := t.rotate(e.Provider, path); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// rotate renames the provider's log and removes the oldest rotated logs
+// beyond maxFiles.
+func (t *TrafficLog) rotate(provider, path string) error {
+	rotated := filepath.Join(t.dir, provider+"-"+time.Now().Format(trafficRotatedLayout)+".jsonl")
+	if err := os.Rename(path, rotated); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(t.dir, provider+"-*.jsonl"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for len(files) > t.maxFiles {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+func TestTrafficLog(t *testing.T) {
+	req := ChatRequest{Messages: []ChatMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "my secret plan"},
+	}}
+
+	for mode, want := range map[string]struct{ has, hasNot []string }{
+		config.TrafficMetadata: {has: []string{`"messages":2`, `"sentChars":22`, `"receivedChars":1`}, hasNot: []string{"secret", "sha256", `"request"`}},
+		config.TrafficHashed:   {has: []string{`"sha256":"`, `"chars":14`}, hasNot: []string{"secret"}},
+		config.TrafficFull:     {has: []string{`"content":"my secret plan"`, `"content":"1"`}},
+	} {
+		dir := t.TempDir()
+		p := WithTrafficLog(&countingProvider{}, config.TrafficLogConfig{Enabled: true, Mode: mode}, dir)
+		if _, err := p.Chat(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "fake.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range want.has {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("%s: log lacks %s: %s", mode, s, data)
+			}
+		}
+		for _, s := range want.hasNot {
+			if strings.Contains(string(data), s) {
+				t.Errorf("%s: log has %s: %s", mode, s, data)
+			}
+		}
+	}
+
+	if _, ok := WithTrafficLog(&countingProvider{}, config.TrafficLogConfig{}, t.TempDir()).(*TrafficLog); ok {
+		t.Error("disabled log wrapped the provider")
+	}
+
+	// Full logs are rotated, keeping maxFiles of them
+	dir := t.TempDir()
+	p := &TrafficLog{p: &countingProvider{}, mode: config.TrafficFull, dir: dir, maxSize: 200, maxFiles: 2}
+	for i := 0; i < 6; i++ {
+		p.Chat(context.Background(), req)
+	}
+	rotated, _ := filepath.Glob(filepath.Join(dir, "fake-*.jsonl"))
+	if len(rotated) != 2 {
+		t.Errorf("rotated logs = %v, want 2", rotated)
+	}
+}
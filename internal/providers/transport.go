@@ -0,0 +1,244 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Retries and the circuit breaker of provider HTTP clients. A request
+// failing transiently is tried retryAttempts times in all, waiting about
+// retryBaseDelay, then twice as long each time, up to retryMaxDelay. After
+// breakerThreshold requests in a row failed that way the provider's circuit
+// opens: requests fail at once for breakerCooldown, after which a single
+// request is let through to probe whether the provider is back.
+const (
+	retryAttempts    = 3
+	retryBaseDelay   = 500 * time.Millisecond
+	retryMaxDelay    = 8 * time.Second
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// Circuit states.
+const (
+	CircuitClosed   = "closed"    // requests go through
+	CircuitOpen     = "open"      // requests fail at once
+	CircuitHalfOpen = "half-open" // one request probes the provider
+)
+
+// ErrCircuitOpen is returned for requests to a provider whose circuit is
+// open. Like other failures to reach the provider, IsUnreachable reports it.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitState is the state of a provider's circuit breaker.
+type CircuitState struct {
+	Provider  string    `json:"provider"`
+	State     string    `json:"state"`               // one of the Circuit* states
+	Failures  int       `json:"failures"`            // transient failures in a row
+	OpenUntil time.Time `json:"openUntil,omitempty"` // while open
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// breaker is the circuit breaker shared by a provider's HTTP clients.
+type breaker struct {
+	mu        sync.Mutex
+	name      string
+	failures  int
+	openUntil time.Time
+	probing   bool // a half-open request is in flight
+	lastErr   string
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*breaker{}
+)
+
+// breakerFor returns the breaker of the named provider.
+func breakerFor(name string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[name]
+	if !ok {
+		b = &breaker{name: name}
+		breakers[name] = b
+	}
+	return b
+}
+
+// Circuits returns the state of the circuit breakers of the providers
+// requested so far, sorted by provider.
+func Circuits() []CircuitState {
+	breakersMu.Lock()
+	list := make([]*breaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	states := make([]CircuitState, 0, len(list))
+	for _, b := range list {
+		states = append(states, b.state())
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Provider < states[j].Provider })
+	return states
+}
+
+func (b *breaker) state() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := CircuitState{Provider: b.name, State: CircuitClosed, Failures: b.failures, LastError: b.lastErr}
+	switch {
+	case b.failures < breakerThreshold:
+	case time.Now().Before(b.openUntil):
+		st.State, st.OpenUntil = CircuitOpen, b.openUntil
+	default:
+		st.State = CircuitHalfOpen
+	}
+	return st
+}
+
+// allow reports whether a request may go out, letting a single one through
+// once an open circuit has cooled down.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < breakerThreshold {
+		return nil
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return fmt.Errorf("%s: %w until %s after %d failures (last: %s)",
+			b.name, ErrCircuitOpen, b.openUntil.Format("15:04:05"), b.failures, b.lastErr)
+	}
+	b.probing = true
+	return nil
+}
+
+// done records the outcome of a request let through by allow: a transient
+// failure counts towards opening the circuit, anything else closes it.
+func (b *breaker) done(transient error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if transient == nil {
+		b.failures = 0
+		b.lastErr = ""
+		return
+	}
+	b.failures++
+	b.lastErr = transient.Error()
+	if b.failures >= breakerThreshold {
+		b.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
+
+// NewHTTPClient returns the HTTP client for calls to the named provider:
+// transient failures are retried with backoff, and the provider's circuit
+// breaker stops requests while it keeps failing.
+func NewHTTPClient(name string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &resilientTransport{base: http.DefaultTransport, breaker: breakerFor(name)},
+	}
+}
+
+// resilientTransport retries transient failures and reports them to the
+// provider's breaker.
+type resilientTransport struct {
+	base    http.RoundTripper
+	breaker *breaker
+	sleep   func(ctx context.Context, d time.Duration) error // for tests
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.allow(); err != nil {
+		return nil, err
+	}
+	// A body that cannot be read again is sent only once
+	attempts := retryAttempts
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		attempts = 1
+	}
+
+	var transient error
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				t.breaker.done(transient)
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		transient = transientError(req.Context(), resp, err)
+		if transient == nil || attempt == attempts || req.Context().Err() != nil {
+			t.breaker.done(transient)
+			return resp, err
+		}
+
+		delay := backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		sleep := t.sleep
+		if sleep == nil {
+			sleep = sleepContext
+		}
+		if err := sleep(req.Context(), delay); err != nil {
+			t.breaker.done(transientError(req.Context(), nil, err))
+			return nil, err
+		}
+	}
+}
+
+// transientError returns why the attempt is worth retrying — a network
+// failure, a timeout, throttling or a gateway error in front of the API —
+// or nil. Requests the caller cancelled are not counted.
+func transientError(ctx context.Context, resp *http.Response, err error) error {
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil
+		}
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff returns how long to wait after the given failed attempt: the
+// delay the server asks for, or an exponential delay with jitter so that
+// clients do not retry in step.
+func backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, retryMaxDelay)
+		}
+	}
+	delay := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResilientTransport(t *testing.T) {
+	var calls, failing atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Add(-1) >= 0 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	b := &breaker{name: "test"}
+	var slept []time.Duration
+	client := &http.Client{Transport: &resilientTransport{base: http.DefaultTransport, breaker: b,
+		sleep: func(ctx context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		}}}
+	post := func() (int, error) {
+		resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	// Transient failures are retried, waiting longer each time
+	failing.Store(2)
+	if code, err := post(); err != nil || code != http.StatusOK {
+		t.Fatalf("post = %d, %v", code, err)
+	}
+	if calls.Load() != 3 || len(slept) != 2 || slept[1] < retryBaseDelay {
+		t.Errorf("calls = %d, slept %v", calls.Load(), slept)
+	}
+
+	// Requests failing every attempt open the circuit
+	failing.Store(1000)
+	for i := 0; i < breakerThreshold; i++ {
+		if code, err := post(); err != nil || code != http.StatusServiceUnavailable {
+			t.Fatalf("post = %d, %v", code, err)
+		}
+	}
+	calls.Store(0)
+	_, err := post()
+	if !errors.Is(err, ErrCircuitOpen) || !IsUnreachable(err) || calls.Load() != 0 {
+		t.Fatalf("err = %v after %d calls, want the circuit open", err, calls.Load())
+	}
+	if st := b.state(); st.State != CircuitOpen || st.Failures != breakerThreshold || st.LastError != "HTTP 503" {
+		t.Errorf("state = %+v", st)
+	}
+
+	// Once cooled down a probe that gets through closes it again
+	b.openUntil = time.Now()
+	failing.Store(0)
+	if st := b.state(); st.State != CircuitHalfOpen {
+		t.Errorf("state = %s, want half-open", st.State)
+	}
+	if code, err := post(); err != nil || code != http.StatusOK {
+		t.Fatalf("post = %d, %v", code, err)
+	}
+	if st := b.state(); st.State != CircuitClosed || st.Failures != 0 {
+		t.Errorf("state = %+v, want closed", st)
+	}
+
+	// Cancelled requests are not failures of the provider
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("cancelled request succeeded")
+	}
+	if st := b.state(); st.Failures != 0 {
+		t.Errorf("failures = %d after a cancelled request", st.Failures)
+	}
+}
@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// VisionDescriber turns images into text using a vision-capable chat model,
+// so that models without image input can still reason about them.
+type VisionDescriber struct {
+	provider Provider
+	model    string
+}
+
+// NewVisionDescriber creates a describer that sends images to provider. If
+// model is empty, the provider's default model is used.
+func NewVisionDescriber(provider Provider, model string) *VisionDescriber {
+	if model == "" {
+		model = provider.DefaultModel()
+	}
+	return &VisionDescriber{provider: provider, model: model}
+}
+
+// DescribeImage asks the vision model to describe image following prompt.
+func (v *VisionDescriber) DescribeImage(ctx context.Context, image []byte, mimeType, prompt string) (string, error) {
+	dataURL := fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(image))
+
+	req := ChatRequest{
+		Model:     v.model,
+		MaxTokens: 1024,
+		Messages: []ChatMessage{{
+			Role: "user",
+			Content: []map[string]interface{}{
+				{"type": "text", "text": prompt},
+				{"type": "image_url", "image_url": map[string]interface{}{"url": dataURL, "detail": "high"}},
+			},
+		}},
+	}
+
+	resp, err := v.provider.Chat(WithPhase(ctx, PhaseSummary), req)
+	if err != nil {
+		return "", fmt.Errorf("vision request failed: %w", err)
+	}
+	description := strings.TrimSpace(resp.Content)
+	if description == "" {
+		return "", fmt.Errorf("vision model returned an empty description")
+	}
+	return description, nil
+}
@@ -0,0 +1,259 @@
+// Package redis provides a minimal Redis client speaking the RESP2 protocol.
+// It implements just the handful of commands uBot needs for clustering
+// (lists for message hand-off and string keys for shared state), avoiding a
+// heavyweight third-party dependency.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNil is returned when Redis replies with a nil bulk string or array.
+var ErrNil = errors.New("redis: nil reply")
+
+// Client is a single-connection Redis client. Commands are serialized; use a
+// separate Client for blocking commands such as BRPOP.
+type Client struct {
+	addr     string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewClient creates a client from a URL of the form
+// redis://[:password@]host[:port][/db]. The connection is opened lazily.
+func NewClient(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("redis: invalid url: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("redis: unsupported scheme %q", u.Scheme)
+	}
+
+	c := &Client{addr: u.Host}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid db %q", db)
+		}
+		c.db = n
+	}
+	return c, nil
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeLocked()
+}
+
+func (c *Client) closeLocked() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.rd = nil
+	return err
+}
+
+// Do sends a command and returns the decoded reply: string, int64, []interface{}
+// or nil. Redis error replies are returned as errors.
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.connectLocked(ctx); err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	_ = c.conn.SetDeadline(deadline)
+
+	reply, err := c.roundTripLocked(args)
+	if err != nil {
+		var rerr replyError
+		if !errors.As(err, &rerr) {
+			// Connection state is unknown; drop it and redial next time.
+			c.closeLocked()
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (c *Client) connectLocked(ctx context.Context) error {
+	if c.conn != nil {
+		return nil
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("redis: dial %s: %w", c.addr, err)
+	}
+	c.conn = conn
+	c.rd = bufio.NewReader(conn)
+
+	if c.password != "" {
+		if _, err := c.roundTripLocked([]string{"AUTH", c.password}); err != nil {
+			c.closeLocked()
+			return err
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.roundTripLocked([]string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			c.closeLocked()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) roundTripLocked(args []string) (interface{}, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := c.conn.Write([]byte(sb.String())); err != nil {
+		return nil, fmt.Errorf("redis: write: %w", err)
+	}
+	return readReply(c.rd)
+}
+
+// replyError is an error reply sent by the server ("-ERR ...").
+type replyError string
+
+func (e replyError) Error() string { return "redis: " + string(e) }
+
+// readReply decodes a single RESP2 reply.
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: read: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, replyError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, fmt.Errorf("redis: read: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(rd); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+// Get returns the string value of key, or ErrNil if it does not exist.
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil {
+		return "", err
+	}
+	if reply == nil {
+		return "", ErrNil
+	}
+	s, _ := reply.(string)
+	return s, nil
+}
+
+// Set stores value under key. A positive ttl sets an expiry.
+func (c *Client) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	args := []string{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// Del removes keys.
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	_, err := c.Do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Push appends data to the head of the list name (LPUSH).
+func (c *Client) Push(ctx context.Context, name string, data []byte) error {
+	_, err := c.Do(ctx, "LPUSH", name, string(data))
+	return err
+}
+
+// Pop removes and returns the tail of the list name, blocking up to timeout
+// (BRPOP). It returns nil data when the timeout elapses with no item.
+func (c *Client) Pop(ctx context.Context, name string, timeout time.Duration) ([]byte, error) {
+	secs := strconv.FormatFloat(timeout.Seconds(), 'f', 3, 64)
+
+	// Allow the server-side timeout to elapse before the socket deadline.
+	popCtx, cancel := context.WithTimeout(ctx, timeout+5*time.Second)
+	defer cancel()
+
+	reply, err := c.Do(popCtx, "BRPOP", name, secs)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 2 {
+		return nil, nil
+	}
+	s, _ := items[1].(string)
+	return []byte(s), nil
+}
@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// storeTimeout bounds each KeyStore operation.
+const storeTimeout = 5 * time.Second
+
+// KeyStore exposes a Client as a simple blob store under a key prefix.
+// It satisfies session.Store.
+type KeyStore struct {
+	client *Client
+	prefix string
+	ttl    time.Duration // expiry of saved keys; 0 = keep forever
+}
+
+// NewKeyStore creates a KeyStore that namespaces keys with prefix.
+func NewKeyStore(client *Client, prefix string) *KeyStore {
+	return &KeyStore{client: client, prefix: prefix}
+}
+
+// SetTTL makes saved keys expire after ttl.
+func (s *KeyStore) SetTTL(ttl time.Duration) {
+	s.ttl = ttl
+}
+
+// Load returns the blob for key, or nil if it does not exist.
+func (s *KeyStore) Load(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	v, err := s.client.Get(ctx, s.prefix+key)
+	if errors.Is(err, ErrNil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(v), nil
+}
+
+// Save stores the blob for key.
+func (s *KeyStore) Save(key string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return s.client.Set(ctx, s.prefix+key, string(data), s.ttl)
+}
+
+// Delete removes key.
+func (s *KeyStore) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return s.client.Del(ctx, s.prefix+key)
+}
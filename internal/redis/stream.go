@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamField is the field holding an entry's payload.
+const streamField = "data"
+
+// StreamEntry is an entry read from a stream.
+type StreamEntry struct {
+	Stream string
+	ID     string
+	Data   []byte
+}
+
+// XAdd appends data to stream (XADD), trimming the stream to about maxLen
+// entries when maxLen is positive, and returns the new entry's ID.
+func (c *Client) XAdd(ctx context.Context, stream string, maxLen int, data []byte) (string, error) {
+	args := []string{"XADD", stream}
+	if maxLen > 0 {
+		args = append(args, "MAXLEN", "~", strconv.Itoa(maxLen))
+	}
+	reply, err := c.Do(ctx, append(args, "*", streamField, string(data))...)
+	if err != nil {
+		return "", err
+	}
+	id, _ := reply.(string)
+	return id, nil
+}
+
+// XGroupCreate creates the consumer group group on stream, creating the
+// stream if needed (XGROUP CREATE ... MKSTREAM). The group starts at the
+// beginning of the stream, so entries added before it existed are read
+// too. An existing group is left as it is.
+func (c *Client) XGroupCreate(ctx context.Context, stream, group string) error {
+	_, err := c.Do(ctx, "XGROUP", "CREATE", stream, group, "0", "MKSTREAM")
+	var rerr replyError
+	if errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// XReadGroup reads up to count entries for consumer in group from streams
+// (XREADGROUP), blocking up to timeout. With pending, it returns the
+// entries already delivered to consumer but not acknowledged instead of
+// new ones, without blocking. It returns no entries when the timeout
+// elapses.
+func (c *Client) XReadGroup(ctx context.Context, group, consumer string, streams []string, count int, pending bool, timeout time.Duration) ([]StreamEntry, error) {
+	args := []string{"XREADGROUP", "GROUP", group, consumer, "COUNT", strconv.Itoa(count)}
+	id := "0"
+	if !pending {
+		id = ">"
+		args = append(args, "BLOCK", strconv.FormatInt(timeout.Milliseconds(), 10))
+
+		// Allow the server-side timeout to elapse before the socket deadline.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout+5*time.Second)
+		defer cancel()
+	}
+	args = append(args, "STREAMS")
+	args = append(args, streams...)
+	for range streams {
+		args = append(args, id)
+	}
+
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var entries []StreamEntry
+	results, _ := reply.([]interface{})
+	for _, r := range results {
+		pair, ok := r.([]interface{})
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		stream, _ := pair[0].(string)
+		entries = append(entries, parseEntries(stream, pair[1])...)
+	}
+	return entries, nil
+}
+
+// XAutoClaim takes over up to count entries of stream that were delivered
+// to other consumers of group but not acknowledged for at least minIdle
+// (XAUTOCLAIM), for example because the consumer died. It needs Redis 6.2
+// or newer.
+func (c *Client) XAutoClaim(ctx context.Context, stream, group, consumer string, minIdle time.Duration, count int) ([]StreamEntry, error) {
+	reply, err := c.Do(ctx, "XAUTOCLAIM", stream, group, consumer,
+		strconv.FormatInt(minIdle.Milliseconds(), 10), "0", "COUNT", strconv.Itoa(count))
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok || len(items) < 2 {
+		return nil, nil
+	}
+	return parseEntries(stream, items[1]), nil
+}
+
+// XAck acknowledges entries of stream for group (XACK).
+func (c *Client) XAck(ctx context.Context, stream, group string, ids ...string) error {
+	_, err := c.Do(ctx, append([]string{"XACK", stream, group}, ids...)...)
+	return err
+}
+
+// parseEntries decodes a list of [id, [field, value, ...]] entries.
+// Entries deleted since they were delivered have no fields and are
+// returned without data.
+func parseEntries(stream string, reply interface{}) []StreamEntry {
+	list, _ := reply.([]interface{})
+	entries := make([]StreamEntry, 0, len(list))
+	for _, item := range list {
+		pair, ok := item.([]interface{})
+		if !ok || len(pair) != 2 {
+			continue
+		}
+		entry := StreamEntry{Stream: stream}
+		entry.ID, _ = pair[0].(string)
+		fields, _ := pair[1].([]interface{})
+		for i := 0; i+1 < len(fields); i += 2 {
+			if name, _ := fields[i].(string); name == streamField {
+				value, _ := fields[i+1].(string)
+				entry.Data = []byte(value)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
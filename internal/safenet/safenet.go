@@ -0,0 +1,181 @@
+// Package safenet keeps HTTP requests made on the model's behalf away from
+// internal networks. Its transport checks every address it dials, so a
+// redirect to an internal address or a hostname that resolves to one (even
+// after a first lookup said otherwise) is refused, not just the URL the
+// model asked for.
+package safenet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrBlocked is returned, wrapped, for connections to internal addresses.
+var ErrBlocked = errors.New("access to internal/private network addresses is blocked")
+
+// blockedPrefixes are ranges not covered by the netip.Addr predicates used
+// in IsInternal.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, also some cloud metadata services
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("fec0::/10"),     // deprecated IPv6 site-local
+}
+
+// IsInternal reports whether ip is loopback, private (including IPv6
+// unique local fc00::/7), link-local (including the 169.254.169.254 cloud
+// metadata address and IPv6 fe80::/10), multicast or unspecified. IPv4
+// addresses embedded in IPv6 ones (IPv4-mapped, NAT64 and 6to4) are judged
+// by the IPv4 address.
+func IsInternal(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if v4, ok := embeddedIPv4(ip); ok {
+		return IsInternal(v4)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFour   = netip.MustParsePrefix("2002::/16")
+)
+
+// embeddedIPv4 returns the IPv4 address inside a NAT64 or 6to4 address.
+func embeddedIPv4(ip netip.Addr) (netip.Addr, bool) {
+	b := ip.As16()
+	switch {
+	case nat64Prefix.Contains(ip):
+		return netip.AddrFrom4([4]byte(b[12:16])), true
+	case sixToFour.Contains(ip):
+		return netip.AddrFrom4([4]byte(b[2:6])), true
+	}
+	return netip.Addr{}, false
+}
+
+// IsInternalURL reports whether rawURL is unusable or its host resolves to
+// an internal address. A host that does not resolve is not reported: the
+// request fails anyway, with a clearer error, and the transport checks
+// the addresses it really dials.
+func IsInternalURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	hostname := parsed.Hostname()
+	if hostname == "" {
+		return true
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(context.Background(), "ip", hostname)
+	if err != nil {
+		return false
+	}
+	for _, ip := range ips {
+		if IsInternal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dialer dials only public addresses, except on the hosts it allows.
+type Dialer struct {
+	dialer net.Dialer
+	allow  map[string]bool
+}
+
+// NewDialer creates a Dialer. Connections to allowed hosts, given as they
+// appear in URLs (e.g. a configured "localhost"), are not checked.
+func NewDialer(allowHosts ...string) *Dialer {
+	d := &Dialer{
+		dialer: net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		allow:  make(map[string]bool),
+	}
+	for _, h := range allowHosts {
+		if h != "" {
+			d.allow[strings.ToLower(h)] = true
+		}
+	}
+	return d
+}
+
+// DialContext resolves addr's host itself and connects to the first
+// address that answers, refusing the host if any of its addresses is
+// internal. Dialing the checked address leaves no room for a second DNS
+// answer to differ from the first.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if d.allow[strings.ToLower(host)] {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if IsInternal(ip) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", ErrBlocked, host, ip.Unmap())
+		}
+	}
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no addresses for %s", host)
+	}
+	return nil, firstErr
+}
+
+// NewTransport returns a transport like http.DefaultTransport whose
+// connections go through a Dialer allowing allowHosts. It connects
+// directly, ignoring proxy environment variables, since through a proxy
+// the addresses reached could not be checked.
+func NewTransport(allowHosts ...string) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = NewDialer(allowHosts...).DialContext
+	return t
+}
+
+// NewClient returns a client with the given timeout (0 for none) using
+// NewTransport(allowHosts...).
+func NewClient(timeout time.Duration, allowHosts ...string) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: NewTransport(allowHosts...)}
+}
+
+// URLHost returns the host of rawURL, for allowing the host of a
+// configured server; it is empty when rawURL does not parse.
+func URLHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
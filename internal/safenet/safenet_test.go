@@ -0,0 +1,101 @@
+package safenet
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsInternal(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1":            true,
+		"10.1.2.3":             true,
+		"172.16.0.1":           true,
+		"192.168.1.1":          true,
+		"169.254.169.254":      true,
+		"100.100.100.200":      true,
+		"0.0.0.0":              true,
+		"224.0.0.1":            true,
+		"::1":                  true,
+		"::":                   true,
+		"fd00::1":              true, // unique local
+		"fc12:3456::1":         true,
+		"fe80::1":              true, // link-local
+		"fec0::1":              true, // site-local
+		"ff02::1":              true,
+		"::ffff:127.0.0.1":     true, // IPv4-mapped
+		"::ffff:10.0.0.1":      true,
+		"64:ff9b::a9fe:a9fe":   true, // NAT64 of 169.254.169.254
+		"2002:c0a8:101::1":     true, // 6to4 of 192.168.1.1
+		"93.184.216.34":        false,
+		"8.8.8.8":              false,
+		"172.32.0.1":           false,
+		"100.128.0.1":          false,
+		"2606:2800:220:1::248": false,
+		"::ffff:93.184.216.34": false,
+		"64:ff9b::5db8:d822":   false,
+		"2002:5db8:d822::1":    false,
+	} {
+		if got := IsInternal(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsInternal(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestTransportChecksEveryConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://"+r.Host+"/target", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/away" {
+			// Same server under another name, which is not allowed
+			http.Redirect(w, r, strings.Replace("http://"+r.Host, "localhost", "127.0.0.1", 1)+"/target", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	local := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	// A local address is refused even when the caller did not check it
+	_, err := NewClient(5 * time.Second).Get(srv.URL)
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("Get(%s): err = %v, want ErrBlocked", srv.URL, err)
+	}
+
+	// An allowed host works, also when it redirects to itself
+	client := NewClient(5*time.Second, "localhost")
+	resp, err := client.Get(local + "/redirect")
+	if err != nil {
+		t.Fatalf("allowed host: %v", err)
+	}
+	resp.Body.Close()
+
+	// A redirect to another internal host is refused
+	if _, err := client.Get(local + "/away"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("redirect to 127.0.0.1: err = %v, want ErrBlocked", err)
+	}
+}
+
+func TestIsInternalURL(t *testing.T) {
+	for rawURL, want := range map[string]bool{
+		"http://127.0.0.1:8080/":      true,
+		"http://[::1]/":               true,
+		"http://[fd00::1]/":           true,
+		"http://169.254.169.254/":     true,
+		"http://93.184.216.34/":       false,
+		"http://[2001:4860::8888]/":   false,
+		"not a url\x7f":               true,
+		"/relative/path":              true,
+		"http://no-such-host.invalid": false,
+	} {
+		if got := IsInternalURL(rawURL); got != want {
+			t.Errorf("IsInternalURL(%q) = %v, want %v", rawURL, got, want)
+		}
+	}
+}
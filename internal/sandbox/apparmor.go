@@ -0,0 +1,62 @@
+package sandbox
+
+import "strings"
+
+// DefaultAppArmorProfile is the name AppArmorProfile is usually loaded
+// under.
+const DefaultAppArmorProfile = "ubot-sandbox"
+
+// appArmorTemplate is Docker's docker-default profile made stricter: no
+// capabilities, ptrace, mounts, raw or packet sockets, and no access to
+// kernel interfaces under /proc and /sys.
+const appArmorTemplate = `#include <tunables/global>
+
+profile {{name}} flags=(attach_disconnected,mediate_deleted) {
+  #include <abstractions/base>
+
+  network inet stream,
+  network inet6 stream,
+  network inet dgram,
+  network inet6 dgram,
+  network unix,
+  deny network raw,
+  deny network packet,
+
+  file,
+  deny capability,
+  deny mount,
+  deny umount,
+  deny pivot_root,
+  deny ptrace,
+  signal (receive) peer=unconfined,
+  signal (send,receive) peer={{name}},
+
+  deny @{PROC}/* w,
+  deny @{PROC}/{[^1-9],[^1-9][^0-9],[^1-9s][^0-9y][^0-9s],[^1-9][^0-9][^0-9][^0-9/]*}/** w,
+  deny @{PROC}/sys/** w,
+  deny @{PROC}/sysrq-trigger rwklx,
+  deny @{PROC}/kcore rwklx,
+  deny @{PROC}/kmem rwklx,
+  deny @{PROC}/mem rwklx,
+  deny @{PROC}/kallsyms rwklx,
+  deny /sys/[^f]*/** wklx,
+  deny /sys/f[^s]*/** wklx,
+  deny /sys/fs/[^c]*/** wklx,
+  deny /sys/fs/c[^g]*/** wklx,
+  deny /sys/fs/cg[^r]*/** wklx,
+  deny /sys/firmware/** rwklx,
+  deny /sys/kernel/** rwklx,
+  deny /dev/mem rwklx,
+  deny /dev/kmem rwklx,
+}
+`
+
+// AppArmorProfile returns a restrictive AppArmor profile for sandbox
+// containers named name. Load it on the Docker host, e.g. with
+// "apparmor_parser -r", then set SandboxConfig.AppArmorProfile to name.
+func AppArmorProfile(name string) string {
+	if name == "" {
+		name = DefaultAppArmorProfile
+	}
+	return strings.ReplaceAll(appArmorTemplate, "{{name}}", name)
+}
@@ -13,6 +13,17 @@ const (
 	DefaultWorkDir      = "/workspace"
 )
 
+// RegistryHosts are the package registries sandboxed installs usually
+// need: PyPI, npm, the Go module proxy and Alpine packages.
+var RegistryHosts = []string{
+	"pypi.org",
+	"files.pythonhosted.org",
+	"registry.npmjs.org",
+	"proxy.golang.org",
+	"sum.golang.org",
+	"dl-cdn.alpinelinux.org",
+}
+
 // SandboxConfig holds configuration for the sandbox environment.
 type SandboxConfig struct {
 	// Image is the container image to use.
@@ -35,6 +46,26 @@ type SandboxConfig struct {
 	// Default: false (isolated)
 	NetworkEnabled bool
 
+	// AllowedHosts lets an otherwise isolated container reach these hosts,
+	// and nothing else, through an egress proxy. A leading "*." or "."
+	// matches subdomains too, e.g. "*.pythonhosted.org". Ignored when
+	// NetworkEnabled is true.
+	// Default: none
+	AllowedHosts []string
+
+	// SeccompProfile selects the seccomp profile limiting the system calls
+	// of the container: empty for uBot's restrictive profile (see
+	// SeccompProfileJSON), SeccompDocker for Docker's default profile,
+	// SeccompUnconfined for none, or the path of a JSON profile. The empty
+	// setting is ignored with gVisor, which filters system calls itself.
+	// Default: "" (restrictive)
+	SeccompProfile string
+
+	// AppArmorProfile is the name of an AppArmor profile loaded on the
+	// Docker host to confine the container, such as AppArmorProfile's.
+	// Default: "" (Docker's docker-default)
+	AppArmorProfile string
+
 	// UseGVisor enables gVisor runtime (runsc) if available.
 	// Provides additional kernel-level isolation.
 	// Default: false
@@ -50,6 +81,12 @@ type SandboxConfig struct {
 
 	// MountPaths specifies paths to mount into the container.
 	MountPaths []MountPath
+
+	// HostPaths translates the Source of MountPaths to the path on the
+	// Docker host, for when uBot itself runs in a container that uses the
+	// host's daemon (see DetectHostPaths).
+	// Default: none (uBot and the daemon see the same paths)
+	HostPaths HostPaths
 }
 
 // MountPath defines a bind mount configuration.
@@ -109,6 +146,39 @@ func (c SandboxConfig) WithNetwork(enabled bool) SandboxConfig {
 	return c
 }
 
+// WithAllowedHosts returns a copy of the config that can reach only the
+// given hosts, e.g. RegistryHosts.
+func (c SandboxConfig) WithAllowedHosts(hosts ...string) SandboxConfig {
+	c.AllowedHosts = append([]string(nil), hosts...)
+	return c
+}
+
+// NetworkMode returns how the container reaches the network: "full",
+// "allowlist" for AllowedHosts only, or "none".
+func (c SandboxConfig) NetworkMode() string {
+	switch {
+	case c.NetworkEnabled:
+		return "full"
+	case len(c.AllowedHosts) > 0:
+		return "allowlist"
+	}
+	return "none"
+}
+
+// WithSeccompProfile returns a copy of the config with the specified
+// seccomp profile.
+func (c SandboxConfig) WithSeccompProfile(profile string) SandboxConfig {
+	c.SeccompProfile = profile
+	return c
+}
+
+// WithAppArmorProfile returns a copy of the config with the specified
+// AppArmor profile.
+func (c SandboxConfig) WithAppArmorProfile(profile string) SandboxConfig {
+	c.AppArmorProfile = profile
+	return c
+}
+
 // WithGVisor returns a copy of the config with gVisor enabled or disabled.
 func (c SandboxConfig) WithGVisor(enabled bool) SandboxConfig {
 	c.UseGVisor = enabled
@@ -143,6 +213,13 @@ func (c SandboxConfig) AddMountPath(source, target string, readOnly bool) Sandbo
 	return c
 }
 
+// WithHostPaths returns a copy of the config translating mount sources with
+// paths.
+func (c SandboxConfig) WithHostPaths(paths HostPaths) SandboxConfig {
+	c.HostPaths = paths
+	return c
+}
+
 // Validate checks if the configuration is valid and applies defaults.
 func (c *SandboxConfig) Validate() {
 	if c.Image == "" {
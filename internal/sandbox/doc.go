@@ -10,6 +10,8 @@
 //   - NetworkMode "none": Network isolation when NetworkEnabled is false
 //   - CapDrop ALL: Drops all Linux capabilities
 //   - SecurityOpt "no-new-privileges": Prevents privilege escalation
+//   - Seccomp: A restrictive system call allowlist (see SeccompProfileJSON)
+//   - AppArmor: An optional profile loaded on the host (see AppArmorProfile)
 //   - Memory, CPU, and PID limits: Resource constraints
 //   - Tmpfs mounts: Writable areas without persistence
 //   - AutoRemove: Automatic cleanup when stopped
@@ -17,6 +19,24 @@
 //
 // Optional gVisor (runsc) runtime support provides additional kernel-level isolation.
 //
+// For hosts that cannot run gVisor, the seccomp profile narrows the kernel
+// interface further than Docker's default: no ptrace, no new namespaces and
+// none of a few rarely needed calls with a record of kernel exploits.
+// SandboxConfig.SeccompProfile switches to Docker's profile, no filtering
+// or a profile of your own, and SandboxConfig.AppArmorProfile names an
+// AppArmor profile, such as the output of AppArmorProfile once loaded with
+// apparmor_parser.
+//
+// # Allowlisted Egress
+//
+// Between no network and full networking, SandboxConfig.AllowedHosts lets a
+// container reach only the listed hosts, e.g. RegistryHosts for pip, npm and
+// go. The container joins EgressNetwork, an internal Docker network without a
+// route out, and HTTP_PROXY and HTTPS_PROXY point it at an EgressProxy on the
+// network's gateway. The proxy refuses other hosts, ports other than 80 and
+// 443, and allowed hosts that resolve to internal addresses. Tools that
+// ignore the proxy variables get no network at all.
+//
 // # Command Guard
 //
 // The GuardCommand function validates commands before execution, blocking
@@ -26,6 +46,8 @@
 //   - System shutdown/reboot commands
 //   - Fork bombs and resource exhaustion attacks
 //   - Direct writes to disk devices
+//   - Windows (rd /s, Remove-Item -Recurse, reg delete, Format-Volume) and
+//     macOS (diskutil erase, csrutil disable) equivalents
 //
 // # Sandbox Pool
 //
@@ -35,10 +57,32 @@
 //   - Automatic container lifecycle management
 //   - Thread-safe acquire/release operations
 //
+// NewPoolWithConfig takes a PoolConfig with a minimum and maximum size. A
+// background loop keeps MinSize containers running, closes containers idle
+// for longer than IdleTimeout and runs `true` in each idle container every
+// HealthInterval, replacing those that fail. Stats reports how long Acquire
+// waited and how many containers were reaped or replaced.
+//
 // # Fallback Executor
 //
 // The LocalExecutor provides command execution when Docker is not available.
 // It uses os/exec directly but still applies command guard checks.
+// ShellCommand picks the arguments for the shell found: -c for Unix
+// shells, /c for cmd.exe and -Command for PowerShell on Windows.
+//
+// # Streaming Output
+//
+// Both executors implement StreamExecutor: ExecuteStream and
+// ExecuteShellStream pass stdout and stderr chunks to a callback as they
+// arrive, and still return the whole output at the end.
+//
+// # Resource Usage
+//
+// Both executors also implement UsageExecutor, whose Run returns a Result
+// with the Usage of the command: wall and CPU time, peak memory and, in a
+// container, peak process count, read from the Docker stats API while the
+// command runs. Usage.Stopped says why a command was stopped, telling an
+// out-of-memory kill apart from a timeout.
 //
 // # Usage
 //
@@ -62,7 +106,12 @@
 //
 // Using the pool for multiple executions:
 //
-//	pool := sandbox.NewPool(sandbox.DefaultConfig(), 5)
+//	pool := sandbox.NewPoolWithConfig(sandbox.DefaultConfig(), sandbox.PoolConfig{
+//	    MinSize:        2,
+//	    MaxSize:        5,
+//	    IdleTimeout:    10 * time.Minute,
+//	    HealthInterval: 30 * time.Second,
+//	})
 //	defer pool.Close()
 //
 //	stdout, stderr, exitCode, err := pool.ExecuteShellInPool(ctx, "echo hello")
@@ -0,0 +1,65 @@
+//go:build !lite && !nodocker
+
+package sandbox
+
+import (
+	"context"
+	"time"
+)
+
+// Ensure Sandbox implements the UsageExecutor interface.
+var _ UsageExecutor = (*Sandbox)(nil)
+
+// NewExecutor creates the appropriate executor based on Docker availability.
+// If Docker is available and working, returns a Sandbox executor.
+// Otherwise, returns a LocalExecutor as fallback.
+func NewExecutor(cfg SandboxConfig) (Executor, error) {
+	// Try to create a sandbox
+	sandbox, err := New(cfg)
+	if err != nil {
+		// Docker client creation failed, use fallback
+		return NewLocalExecutorWithConfig(cfg.WorkDir, cfg.Timeout), nil
+	}
+
+	// Check if Docker daemon is accessible
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := sandbox.Ping(ctx); err != nil {
+		// Docker daemon not accessible, use fallback
+		_ = sandbox.Close()
+		return NewLocalExecutorWithConfig(cfg.WorkDir, cfg.Timeout), nil
+	}
+
+	// Docker is available, start the sandbox
+	if err := sandbox.Start(ctx); err != nil {
+		// Failed to start sandbox, use fallback
+		_ = sandbox.Close()
+		return NewLocalExecutorWithConfig(cfg.WorkDir, cfg.Timeout), nil
+	}
+
+	return sandbox, nil
+}
+
+// MustNewExecutor is like NewExecutor but panics on error.
+func MustNewExecutor(cfg SandboxConfig) Executor {
+	executor, err := NewExecutor(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return executor
+}
+
+// IsDockerAvailable checks if Docker is available and accessible.
+func IsDockerAvailable() bool {
+	sandbox, err := New(DefaultConfig())
+	if err != nil {
+		return false
+	}
+	defer sandbox.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return sandbox.Ping(ctx) == nil
+}
@@ -0,0 +1,41 @@
+//go:build lite || nodocker
+
+package sandbox
+
+import (
+	"context"
+	"os"
+)
+
+// NewExecutor returns a LocalExecutor: Docker support is not compiled into
+// this build.
+func NewExecutor(cfg SandboxConfig) (Executor, error) {
+	return NewLocalExecutorWithConfig(cfg.WorkDir, cfg.Timeout), nil
+}
+
+// MustNewExecutor is like NewExecutor but panics on error.
+func MustNewExecutor(cfg SandboxConfig) Executor {
+	executor, err := NewExecutor(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return executor
+}
+
+// IsDockerAvailable always reports false: Docker support is not compiled
+// into this build.
+func IsDockerAvailable() bool {
+	return false
+}
+
+// InContainer reports whether uBot runs in a Docker container.
+func InContainer() bool {
+	_, err := os.Stat("/.dockerenv")
+	return err == nil
+}
+
+// DetectHostPaths returns no mappings: Docker support is not compiled into
+// this build.
+func DetectHostPaths(ctx context.Context) (HostPaths, error) {
+	return nil, nil
+}
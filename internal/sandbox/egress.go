@@ -0,0 +1,195 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+// egressPorts are the ports sandboxed commands may reach on allowed hosts.
+var egressPorts = map[string]bool{"80": true, "443": true}
+
+// hopHeaders are removed from requests and responses passed on by the
+// proxy.
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// EgressProxy is an HTTP proxy that lets sandboxed commands reach the
+// allowed hosts only: HTTPS through CONNECT and plain HTTP requests, on
+// ports 80 and 443. Allowed hosts that resolve to internal addresses are
+// refused too, so a DNS answer cannot point the sandbox at the host.
+type EgressProxy struct {
+	hosts  []string
+	dial   func(ctx context.Context, network, addr string) (net.Conn, error)
+	server *http.Server
+	closed chan struct{} // closed by Close, ending the tunnels
+	wg     sync.WaitGroup
+}
+
+// NewEgressProxy creates a proxy allowing hosts, matched as in
+// HostAllowed.
+func NewEgressProxy(hosts []string) *EgressProxy {
+	p := &EgressProxy{
+		hosts:  hosts,
+		dial:   safenet.NewDialer().DialContext,
+		closed: make(chan struct{}),
+	}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: 30 * time.Second}
+	return p
+}
+
+// Serve accepts proxy connections on l until Close is called.
+func (p *EgressProxy) Serve(l net.Listener) error {
+	err := p.server.Serve(l)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Close stops the proxy and closes its tunnels.
+func (p *EgressProxy) Close() error {
+	err := p.server.Close()
+	close(p.closed)
+	p.wg.Wait()
+	return err
+}
+
+// HostAllowed reports whether host matches one of patterns: exactly, or as
+// a subdomain of a pattern starting with "*." or ".".
+func HostAllowed(host string, patterns []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			pattern = suffix
+		}
+		if strings.HasPrefix(pattern, ".") {
+			if strings.HasSuffix(host, pattern) || host == pattern[1:] {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP implements http.Handler.
+func (p *EgressProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	addr := r.Host
+	if r.Method != http.MethodConnect {
+		if r.URL.Host == "" {
+			http.Error(w, "sandbox egress proxy: only proxy requests are served", http.StatusBadRequest)
+			return
+		}
+		addr = r.URL.Host
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, "80"
+		if r.Method == http.MethodConnect {
+			port = "443"
+		}
+	}
+	if !HostAllowed(host, p.hosts) || !egressPorts[port] {
+		http.Error(w, fmt.Sprintf("sandbox egress proxy: %s is not an allowed host", net.JoinHostPort(host, port)), http.StatusForbidden)
+		return
+	}
+	target := net.JoinHostPort(host, port)
+
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r, target)
+		return
+	}
+	p.forward(w, r, target)
+}
+
+// tunnel connects the client to target for CONNECT.
+func (p *EgressProxy) tunnel(w http.ResponseWriter, r *http.Request, target string) {
+	upstream, err := p.dial(r.Context(), "tcp", target)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sandbox egress proxy: %v", err), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "sandbox egress proxy: tunnels are not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		finished := make(chan struct{})
+		go func() {
+			// Bytes the client sent along with the CONNECT are buffered
+			_, _ = io.Copy(upstream, io.MultiReader(buf.Reader, client))
+			if tcp, ok := upstream.(*net.TCPConn); ok {
+				_ = tcp.CloseWrite()
+			}
+		}()
+		go func() {
+			_, _ = io.Copy(client, upstream)
+			close(finished)
+		}()
+		select {
+		case <-finished:
+		case <-p.closed:
+		}
+		client.Close()
+		upstream.Close()
+	}()
+}
+
+// forward passes a plain HTTP request on to target.
+func (p *EgressProxy) forward(w http.ResponseWriter, r *http.Request, target string) {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return p.dial(ctx, network, target)
+		},
+		DisableKeepAlives: true,
+	}
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sandbox egress proxy: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for k, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
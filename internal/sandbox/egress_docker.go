@@ -0,0 +1,81 @@
+//go:build !lite && !nodocker
+
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// EgressNetwork is the internal Docker network of containers limited to
+// AllowedHosts. It has no route out; its only way out is the egress proxy
+// listening on the network's gateway, on the Docker host.
+const EgressNetwork = "ubot-sandbox-egress"
+
+// startEgress starts an egress proxy for the allowed hosts on the gateway
+// of EgressNetwork, creating the network if needed, and returns the
+// proxy's URL as seen from the container.
+func (s *Sandbox) startEgress(ctx context.Context) (string, error) {
+	gateway, err := egressGateway(ctx, s.client)
+	if err != nil {
+		return "", err
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(gateway, "0"))
+	if err != nil {
+		return "", fmt.Errorf("egress proxy: %w (the allowlist needs Docker on this host)", err)
+	}
+	s.egress = NewEgressProxy(s.config.AllowedHosts)
+	go func(p *EgressProxy) { _ = p.Serve(l) }(s.egress)
+	return "http://" + l.Addr().String(), nil
+}
+
+// stopEgress stops the sandbox's egress proxy, if any.
+func (s *Sandbox) stopEgress() {
+	if s.egress != nil {
+		_ = s.egress.Close()
+		s.egress = nil
+	}
+}
+
+// egressGateway returns the gateway address of EgressNetwork, creating the
+// network on first use.
+func egressGateway(ctx context.Context, cli *client.Client) (string, error) {
+	inspect, err := cli.NetworkInspect(ctx, EgressNetwork, network.InspectOptions{})
+	if client.IsErrNotFound(err) {
+		_, err = cli.NetworkCreate(ctx, EgressNetwork, network.CreateOptions{
+			Driver:   "bridge",
+			Internal: true,
+			Labels:   map[string]string{"ubot.sandbox": "egress"},
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to create network %s: %w", EgressNetwork, err)
+		}
+		inspect, err = cli.NetworkInspect(ctx, EgressNetwork, network.InspectOptions{})
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect network %s: %w", EgressNetwork, err)
+	}
+	if !inspect.Internal {
+		return "", fmt.Errorf("network %s exists but is not internal", EgressNetwork)
+	}
+	for _, cfg := range inspect.IPAM.Config {
+		if ip := net.ParseIP(cfg.Gateway); ip != nil && ip.To4() != nil {
+			return cfg.Gateway, nil
+		}
+	}
+	return "", fmt.Errorf("network %s has no IPv4 gateway", EgressNetwork)
+}
+
+// proxyEnv returns the environment that points a container's tools at the
+// egress proxy.
+func proxyEnv(proxyURL string) []string {
+	var env []string
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		env = append(env, name+"="+proxyURL)
+	}
+	return env
+}
@@ -0,0 +1,105 @@
+package sandbox
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHostAllowed(t *testing.T) {
+	patterns := []string{"pypi.org", "*.pythonhosted.org", ".golang.org"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"pypi.org", true},
+		{"PyPI.org.", true},
+		{"evil-pypi.org", false},
+		{"files.pythonhosted.org", true},
+		{"pythonhosted.org", true},
+		{"proxy.golang.org", true},
+		{"golang.org.evil.com", false},
+		{"example.com", false},
+	}
+	for _, tt := range tests {
+		if got := HostAllowed(tt.host, patterns); got != tt.want {
+			t.Errorf("HostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestSandboxConfigNetworkMode(t *testing.T) {
+	cfg := DefaultConfig()
+	if mode := cfg.NetworkMode(); mode != "none" {
+		t.Errorf("default mode = %q", mode)
+	}
+	if mode := cfg.WithAllowedHosts(RegistryHosts...).NetworkMode(); mode != "allowlist" {
+		t.Errorf("mode with allowed hosts = %q", mode)
+	}
+	if mode := cfg.WithAllowedHosts("pypi.org").WithNetwork(true).NetworkMode(); mode != "full" {
+		t.Errorf("mode with network = %q", mode)
+	}
+}
+
+func TestEgressProxy(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "plain "+r.URL.Path)
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secure "+r.URL.Path)
+	}))
+	defer secure.Close()
+
+	// The test servers listen on 127.0.0.1 under made-up names and ports
+	ports := map[string]string{"plain.test:80": plain.Listener.Addr().String(), "secure.test:443": secure.Listener.Addr().String()}
+	proxy := NewEgressProxy([]string{"plain.test", "secure.test"})
+	proxy.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, ports[addr])
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go proxy.Serve(l)
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse("http://" + l.Addr().String())
+	transport := secure.Client().Transport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	transport.TLSClientConfig.ServerName = "example.com" // in the test certificate
+	client := &http.Client{Transport: transport}
+
+	get := func(rawURL string) (int, string) {
+		t.Helper()
+		resp, err := client.Get(rawURL)
+		if err != nil {
+			t.Fatalf("GET %s: %v", rawURL, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := get("http://plain.test/simple"); code != http.StatusOK || body != "plain /simple" {
+		t.Errorf("plain: %d %q", code, body)
+	}
+	if code, body := get("https://secure.test/pkg"); code != http.StatusOK || body != "secure /pkg" {
+		t.Errorf("CONNECT: %d %q", code, body)
+	}
+	if code, body := get("http://other.test/"); code != http.StatusForbidden || !strings.Contains(body, "other.test:80 is not an allowed host") {
+		t.Errorf("other host: %d %q", code, body)
+	}
+	if code, _ := get("http://plain.test:8080/"); code != http.StatusForbidden {
+		t.Errorf("other port: %d", code)
+	}
+	if _, err := client.Get("https://other.test/"); err == nil {
+		t.Error("CONNECT to another host succeeded")
+	}
+}
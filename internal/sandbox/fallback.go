@@ -72,17 +72,26 @@ func NewLocalExecutorWithConfig(workDir string, timeout time.Duration) *LocalExe
 
 // defaultShells returns the default shells for the current platform.
 func defaultShells() []string {
-	if runtime.GOOS == "windows" {
-		return []string{"cmd", "powershell"}
-	}
-	return []string{"sh", "bash", "zsh"}
+	return DefaultShells(runtime.GOOS)
 }
 
 // Execute runs a command and returns the output.
 // The command guard is applied before execution.
 func (e *LocalExecutor) Execute(ctx context.Context, cmd []string) (stdout, stderr string, exitCode int, err error) {
+	return e.ExecuteStream(ctx, cmd, nil)
+}
+
+// ExecuteStream runs a command like Execute, passing its output to
+// onOutput as it arrives.
+func (e *LocalExecutor) ExecuteStream(ctx context.Context, cmd []string, onOutput OutputFunc) (stdout, stderr string, exitCode int, err error) {
+	result, err := e.Run(ctx, cmd, onOutput)
+	return result.Stdout, result.Stderr, result.ExitCode, err
+}
+
+// Run runs a command like ExecuteStream, measuring the resources it used.
+func (e *LocalExecutor) Run(ctx context.Context, cmd []string, onOutput OutputFunc) (Result, error) {
 	if len(cmd) == 0 {
-		return "", "", -1, errors.New("empty command")
+		return Result{ExitCode: -1}, errors.New("empty command")
 	}
 
 	// Build full command string for guard check
@@ -90,7 +99,7 @@ func (e *LocalExecutor) Execute(ctx context.Context, cmd []string) (stdout, stde
 
 	// Apply command guard
 	if reason := GuardCommand(fullCmd); reason != "" {
-		return "", "", -1, fmt.Errorf("command blocked: %s", reason)
+		return Result{ExitCode: -1}, fmt.Errorf("command blocked: %s", reason)
 	}
 
 	// Create context with timeout
@@ -99,15 +108,16 @@ func (e *LocalExecutor) Execute(ctx context.Context, cmd []string) (stdout, stde
 
 	// Create the command
 	command := exec.CommandContext(execCtx, cmd[0], cmd[1:]...)
+	setCmdLine(command, cmd)
 
 	// Set working directory if specified
 	if e.WorkDir != "" {
 		absWorkDir, err := filepath.Abs(e.WorkDir)
 		if err != nil {
-			return "", "", -1, fmt.Errorf("invalid working directory: %w", err)
+			return Result{ExitCode: -1}, fmt.Errorf("invalid working directory: %w", err)
 		}
 		if _, err := os.Stat(absWorkDir); err != nil {
-			return "", "", -1, fmt.Errorf("working directory does not exist: %w", err)
+			return Result{ExitCode: -1}, fmt.Errorf("working directory does not exist: %w", err)
 		}
 		command.Dir = absWorkDir
 	}
@@ -119,59 +129,64 @@ func (e *LocalExecutor) Execute(ctx context.Context, cmd []string) (stdout, stde
 
 	// Capture stdout and stderr
 	var stdoutBuf, stderrBuf bytes.Buffer
-	command.Stdout = &limitedWriter{w: &stdoutBuf, limit: e.MaxOutputLen}
-	command.Stderr = &limitedWriter{w: &stderrBuf, limit: e.MaxOutputLen}
+	command.Stdout, command.Stderr = streamWriters(
+		&limitedWriter{w: &stdoutBuf, limit: e.MaxOutputLen},
+		&limitedWriter{w: &stderrBuf, limit: e.MaxOutputLen},
+		onOutput,
+	)
 
 	// Run the command
-	err = command.Run()
-
-	stdout = stdoutBuf.String()
-	stderr = stderrBuf.String()
+	start := time.Now()
+	err := command.Run()
+
+	result := Result{
+		Stdout:   stdoutBuf.String(),
+		Stderr:   stderrBuf.String(),
+		ExitCode: -1,
+		Usage:    ProcessUsage(command.ProcessState, time.Since(start)),
+	}
 
 	// Check for timeout
 	if execCtx.Err() == context.DeadlineExceeded {
-		return stdout, stderr, -1, fmt.Errorf("command timed out after %v", e.Timeout)
+		result.Usage.Stopped = StoppedTimeout
+		return result, fmt.Errorf("command timed out after %v", e.Timeout)
 	}
 
 	// Check for context cancellation
 	if ctx.Err() != nil {
-		return stdout, stderr, -1, fmt.Errorf("command cancelled: %w", ctx.Err())
+		return result, fmt.Errorf("command cancelled: %w", ctx.Err())
 	}
 
 	// Get exit code
 	if err != nil {
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
-			return stdout, stderr, exitErr.ExitCode(), nil
+			result.ExitCode = exitErr.ExitCode()
+			return result, nil
 		}
-		return stdout, stderr, -1, fmt.Errorf("command execution failed: %w", err)
+		return result, fmt.Errorf("command execution failed: %w", err)
 	}
 
-	return stdout, stderr, 0, nil
+	result.ExitCode = 0
+	return result, nil
 }
 
 // ExecuteShell runs a shell command and returns the output.
 // The command is wrapped in a shell invocation.
 func (e *LocalExecutor) ExecuteShell(ctx context.Context, command string) (stdout, stderr string, exitCode int, err error) {
+	return e.ExecuteShellStream(ctx, command, nil)
+}
+
+// ExecuteShellStream runs a shell command like ExecuteShell, passing its
+// output to onOutput as it arrives.
+func (e *LocalExecutor) ExecuteShellStream(ctx context.Context, command string, onOutput OutputFunc) (stdout, stderr string, exitCode int, err error) {
 	// Find an available shell
 	shell := e.findShell()
 	if shell == "" {
 		return "", "", -1, errors.New("no suitable shell found")
 	}
 
-	// Build shell command based on platform
-	var cmd []string
-	if runtime.GOOS == "windows" {
-		if strings.Contains(shell, "powershell") {
-			cmd = []string{shell, "-Command", command}
-		} else {
-			cmd = []string{shell, "/c", command}
-		}
-	} else {
-		cmd = []string{shell, "-c", command}
-	}
-
-	return e.Execute(ctx, cmd)
+	return e.ExecuteStream(ctx, ShellCommand(shell, command), onOutput)
 }
 
 // findShell finds an available shell from the allowed list.
@@ -263,62 +278,5 @@ type Executor interface {
 	ExecuteShell(ctx context.Context, command string) (stdout, stderr string, exitCode int, err error)
 }
 
-// Ensure types implement Executor interface.
-var (
-	_ Executor = (*Sandbox)(nil)
-	_ Executor = (*LocalExecutor)(nil)
-)
-
-// NewExecutor creates the appropriate executor based on Docker availability.
-// If Docker is available and working, returns a Sandbox executor.
-// Otherwise, returns a LocalExecutor as fallback.
-func NewExecutor(cfg SandboxConfig) (Executor, error) {
-	// Try to create a sandbox
-	sandbox, err := New(cfg)
-	if err != nil {
-		// Docker client creation failed, use fallback
-		return NewLocalExecutorWithConfig(cfg.WorkDir, cfg.Timeout), nil
-	}
-
-	// Check if Docker daemon is accessible
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := sandbox.Ping(ctx); err != nil {
-		// Docker daemon not accessible, use fallback
-		_ = sandbox.Close()
-		return NewLocalExecutorWithConfig(cfg.WorkDir, cfg.Timeout), nil
-	}
-
-	// Docker is available, start the sandbox
-	if err := sandbox.Start(ctx); err != nil {
-		// Failed to start sandbox, use fallback
-		_ = sandbox.Close()
-		return NewLocalExecutorWithConfig(cfg.WorkDir, cfg.Timeout), nil
-	}
-
-	return sandbox, nil
-}
-
-// MustNewExecutor is like NewExecutor but panics on error.
-func MustNewExecutor(cfg SandboxConfig) Executor {
-	executor, err := NewExecutor(cfg)
-	if err != nil {
-		panic(err)
-	}
-	return executor
-}
-
-// IsDockerAvailable checks if Docker is available and accessible.
-func IsDockerAvailable() bool {
-	sandbox, err := New(DefaultConfig())
-	if err != nil {
-		return false
-	}
-	defer sandbox.Close()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	return sandbox.Ping(ctx) == nil
-}
+// Ensure LocalExecutor implements the UsageExecutor interface.
+var _ UsageExecutor = (*LocalExecutor)(nil)
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -92,6 +93,29 @@ func TestLocalExecutorExecuteShell(t *testing.T) {
 	}
 }
 
+func TestLocalExecutorExecuteShellStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on Windows")
+	}
+
+	e := NewLocalExecutor()
+	var chunks []string
+	stdout, stderr, exitCode, err := e.ExecuteShellStream(context.Background(), "echo one; echo two >&2; sleep 0.1; echo three",
+		func(stream Stream, chunk []byte) {
+			chunks = append(chunks, stream.String()+":"+string(chunk))
+		})
+	if err != nil || exitCode != 0 {
+		t.Fatalf("ExecuteShellStream = %d, %v", exitCode, err)
+	}
+	if stdout != "one\nthree\n" || stderr != "two\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout, stderr)
+	}
+	got := strings.Join(chunks, "")
+	if !strings.Contains(got, "stdout:one\n") || !strings.Contains(got, "stderr:two\n") || !strings.HasSuffix(got, "stdout:three\n") {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
 func TestLocalExecutorExecuteWithStderr(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("skipping on Windows")
@@ -68,6 +68,26 @@ var blockedCommandPatterns = []*regexp.Regexp{
 	// Dangerous network commands (curl/wget piped to shell)
 	regexp.MustCompile(`(?i)\bcurl\s+.*\|\s*(ba)?sh`),
 	regexp.MustCompile(`(?i)\bwget\s+.*\|\s*(ba)?sh`),
+
+	// Windows: recursive deletion with the flag after the path,
+	// PowerShell, registry, boot configuration and backups
+	regexp.MustCompile(`(?i)\b(rd|rmdir|del|erase)\s+.*\s/[a-z]*s\b`),
+	regexp.MustCompile(`(?i)\b(remove-item|ri)\s+.*-r(ecurse)?\b`),
+	regexp.MustCompile(`(?i)\breg(\.exe)?\s+delete\b`),
+	regexp.MustCompile(`(?i)\b(format-volume|clear-disk|initialize-disk|remove-partition)\b`),
+	regexp.MustCompile(`(?i)\b(stop-computer|restart-computer)\b`),
+	regexp.MustCompile(`(?i)\bbcdedit\b`),
+	regexp.MustCompile(`(?i)\bvssadmin\s+delete\b`),
+	regexp.MustCompile(`(?i)\bcipher\s+/w`),
+	regexp.MustCompile(`(?i)\b(iwr|irm|invoke-webrequest|invoke-restmethod|curl|wget)\b.*\|\s*(iex|invoke-expression)\b`),
+	regexp.MustCompile(`(?i)\b(iex|invoke-expression)\b.*\b(downloadstring|iwr|irm|invoke-webrequest|invoke-restmethod)\b`),
+	regexp.MustCompile(`(?i)\b(powershell|pwsh)(\.exe)?\s+(.*\s)?-(e|ec|enc|encodedcommand)\s`),
+
+	// macOS: disk utilities, System Integrity Protection and raw disks
+	regexp.MustCompile(`(?i)\bdiskutil\s+(erase\w*|zerodisk|randomdisk|secureerase|partitiondisk|reformat|apfs\s+delete\w*)`),
+	regexp.MustCompile(`(?i)\bcsrutil\s+disable\b`),
+	regexp.MustCompile(`(?i)>\s*/dev/r?disk\d`),
+	regexp.MustCompile(`(?i)\bsrm\b`),
 }
 
 // blockedPatternDescriptions provides human-readable descriptions for each pattern.
@@ -116,6 +136,21 @@ var blockedPatternDescriptions = map[int]string{
 	41: "removal of critical system files",
 	42: "curl piped to shell",
 	43: "wget piped to shell",
+	44: "Windows recursive deletion",
+	45: "PowerShell Remove-Item -Recurse (potentially dangerous)",
+	46: "reg delete (registry deletion)",
+	47: "PowerShell disk formatting or partitioning",
+	48: "PowerShell Stop-Computer/Restart-Computer",
+	49: "bcdedit command (boot configuration)",
+	50: "vssadmin delete (shadow copy deletion)",
+	51: "cipher /w (disk wiping)",
+	52: "download piped to Invoke-Expression",
+	53: "Invoke-Expression of downloaded code",
+	54: "PowerShell encoded command",
+	55: "diskutil erase or partition command",
+	56: "csrutil disable (System Integrity Protection)",
+	57: "redirect to /dev/disk* device",
+	58: "srm command (secure file deletion)",
 }
 
 // GuardCommand checks if a command is safe to execute.
@@ -0,0 +1,48 @@
+package sandbox
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// HostPath maps a directory uBot sees inside its container to the same
+// directory on the Docker host, e.g. a volume mounted at /home/ubot/.ubot.
+type HostPath struct {
+	Container string // mount point inside uBot's container
+	Host      string // source of the mount on the Docker host
+}
+
+// HostPaths are the mounts of uBot's own container. Sandboxes started on
+// the host's daemon can only bind-mount host paths, so paths under these
+// mounts are translated and other paths of uBot's container cannot be
+// mounted at all.
+type HostPaths []HostPath
+
+// Translate returns the host path of path. Without any mappings (uBot is
+// not in a container) path is returned as is.
+func (h HostPaths) Translate(path string) (string, error) {
+	if len(h) == 0 {
+		return path, nil
+	}
+	path = filepath.Clean(path)
+
+	// The deepest mount containing path wins, as mounts can be nested
+	best := -1
+	for i, m := range h {
+		if within(path, m.Container) && (best < 0 || len(m.Container) > len(h[best].Container)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", fmt.Errorf("%s is not on a volume or bind mount of the uBot container, so the Docker host cannot mount it into a sandbox", path)
+	}
+	rel, _ := filepath.Rel(h[best].Container, path)
+	return filepath.Join(h[best].Host, rel), nil
+}
+
+// within reports whether path is dir or below it.
+func within(path, dir string) bool {
+	dir = filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
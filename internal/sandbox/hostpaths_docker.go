@@ -0,0 +1,66 @@
+//go:build !lite && !nodocker
+
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/docker/docker/client"
+)
+
+// containerIDPattern finds uBot's container ID in /proc/self/mountinfo,
+// where Docker mounts /etc/hostname and friends from the container's
+// directory.
+var containerIDPattern = regexp.MustCompile(`/containers/([0-9a-f]{64})/`)
+
+// InContainer reports whether uBot runs in a Docker container.
+func InContainer() bool {
+	_, err := os.Stat("/.dockerenv")
+	return err == nil
+}
+
+// DetectHostPaths asks the Docker daemon for the mounts of uBot's own
+// container, so sandboxes it starts can mount the workspace. It returns no
+// mappings when uBot is not in a container.
+func DetectHostPaths(ctx context.Context) (HostPaths, error) {
+	if !InContainer() {
+		return nil, nil
+	}
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	// The hostname is the short container ID unless it was set explicitly
+	var ids []string
+	if hostname, err := os.Hostname(); err == nil {
+		ids = append(ids, hostname)
+	}
+	if data, err := os.ReadFile("/proc/self/mountinfo"); err == nil {
+		if m := containerIDPattern.FindSubmatch(data); m != nil {
+			ids = append(ids, string(m[1]))
+		}
+	}
+
+	var lastErr error
+	for _, id := range ids {
+		info, err := cli.ContainerInspect(ctx, id)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		paths := HostPaths{}
+		for _, m := range info.Mounts {
+			paths = append(paths, HostPath{Container: m.Destination, Host: m.Source})
+		}
+		return paths, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("container ID not found")
+	}
+	return nil, fmt.Errorf("failed to find uBot's own container: %w", lastErr)
+}
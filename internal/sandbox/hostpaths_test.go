@@ -0,0 +1,37 @@
+package sandbox
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostPathsTranslate(t *testing.T) {
+	paths := HostPaths{
+		{Container: "/home/ubot/.ubot", Host: "/var/lib/docker/volumes/ubot/_data"},
+		{Container: "/home/ubot/.ubot/workspace/projects", Host: "/srv/projects"},
+		{Container: "/var/run/docker.sock", Host: "/var/run/docker.sock"},
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{"/home/ubot/.ubot", "/var/lib/docker/volumes/ubot/_data"},
+		{"/home/ubot/.ubot/workspace/skills/pdf", "/var/lib/docker/volumes/ubot/_data/workspace/skills/pdf"},
+		{"/home/ubot/.ubot/workspace/projects/app/", "/srv/projects/app"},
+	}
+	for _, tt := range tests {
+		got, err := paths.Translate(tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("Translate(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	for _, path := range []string{"/tmp/skill", "/home/ubot/.ubotx"} {
+		if _, err := paths.Translate(path); err == nil || !strings.Contains(err.Error(), "not on a volume") {
+			t.Errorf("Translate(%q) error = %v", path, err)
+		}
+	}
+
+	if got, err := HostPaths(nil).Translate("/tmp/skill"); err != nil || got != "/tmp/skill" {
+		t.Errorf("without mappings: %q, %v", got, err)
+	}
+}
@@ -1,35 +1,115 @@
+//go:build !lite && !nodocker
+
 // Package sandbox provides a secure container-based execution environment.
 package sandbox
 
 import (
 	"context"
 	"fmt"
+	"log"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// Default pool configuration values.
+const (
+	DefaultPoolHealthInterval = 30 * time.Second
+	DefaultPoolIdleTimeout    = 10 * time.Minute
+
+	// poolHealthTimeout bounds one health check.
+	poolHealthTimeout = 10 * time.Second
+)
+
+// PoolConfig sizes a Pool and sets how it looks after its containers.
+type PoolConfig struct {
+	// MinSize is how many containers the pool keeps running, starting new
+	// ones when containers die or are reaped.
+	// Default: 0
+	MinSize int
+
+	// MaxSize is the most containers the pool runs at once, idle or in use.
+	// Default: 1
+	MaxSize int
+
+	// IdleTimeout is how long a container may stay unused before it is
+	// closed, as long as more than MinSize are running. Zero keeps idle
+	// containers.
+	IdleTimeout time.Duration
+
+	// HealthInterval is how often each idle container is checked by running
+	// `true` in it. Containers that fail are replaced. Zero disables the
+	// checks.
+	HealthInterval time.Duration
+}
+
+// DefaultPoolConfig returns a PoolConfig that keeps up to maxSize healthy
+// containers, reaping idle ones after DefaultPoolIdleTimeout.
+func DefaultPoolConfig(maxSize int) PoolConfig {
+	return PoolConfig{
+		MaxSize:        maxSize,
+		IdleTimeout:    DefaultPoolIdleTimeout,
+		HealthInterval: DefaultPoolHealthInterval,
+	}
+}
+
+// idleSandbox is a container waiting in the pool.
+type idleSandbox struct {
+	sandbox *Sandbox
+	since   time.Time
+}
+
 // Pool manages a pool of pre-warmed sandbox containers for faster execution.
 type Pool struct {
 	config    SandboxConfig
-	available chan *Sandbox
+	poolCfg   PoolConfig
+	available chan idleSandbox
 	maxSize   int
 	created   atomic.Int32
 	mu        sync.Mutex
 	closed    atomic.Bool
+
+	// Background maintenance, when configured
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	statsMu sync.Mutex
+	stats   PoolStats
 }
 
 // NewPool creates a new sandbox pool with the given configuration and maximum size.
 // The pool will pre-warm sandboxes up to maxSize in the background.
 func NewPool(cfg SandboxConfig, maxSize int) *Pool {
-	if maxSize <= 0 {
-		maxSize = 1
+	return NewPoolWithConfig(cfg, PoolConfig{MaxSize: maxSize})
+}
+
+// NewPoolWithConfig creates a sandbox pool sized and maintained as poolCfg
+// says. With a MinSize, IdleTimeout or HealthInterval set, a background
+// loop starts MinSize containers right away and then reaps, checks and
+// replaces them until the pool is closed.
+func NewPoolWithConfig(cfg SandboxConfig, poolCfg PoolConfig) *Pool {
+	if poolCfg.MaxSize <= 0 {
+		poolCfg.MaxSize = 1
+	}
+	if poolCfg.MinSize < 0 {
+		poolCfg.MinSize = 0
+	}
+	if poolCfg.MinSize > poolCfg.MaxSize {
+		poolCfg.MinSize = poolCfg.MaxSize
 	}
 
 	p := &Pool{
 		config:    cfg,
-		available: make(chan *Sandbox, maxSize),
-		maxSize:   maxSize,
+		poolCfg:   poolCfg,
+		available: make(chan idleSandbox, poolCfg.MaxSize),
+		maxSize:   poolCfg.MaxSize,
+	}
+
+	if poolCfg.MinSize > 0 || poolCfg.IdleTimeout > 0 || poolCfg.HealthInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancel = cancel
+		p.wg.Add(1)
+		go p.maintainLoop(ctx)
 	}
 
 	return p
@@ -57,15 +137,8 @@ func (p *Pool) Warmup(ctx context.Context, count int) error {
 				errCh <- err
 				return
 			}
-			// Try to add to pool, close if full
-			select {
-			case p.available <- sandbox:
-				// Added to pool
-			default:
-				// Pool is full, close this sandbox
-				_ = sandbox.Close()
-				p.created.Add(-1)
-			}
+			// Add to pool, closing it if full
+			p.put(idleSandbox{sandbox: sandbox, since: time.Now()})
 		}()
 	}
 
@@ -85,20 +158,31 @@ func (p *Pool) Warmup(ctx context.Context, count int) error {
 // Acquire gets a sandbox from the pool.
 // If no sandbox is available, it creates a new one (up to maxSize).
 // The caller must call Release() when done with the sandbox.
-func (p *Pool) Acquire(ctx context.Context) (*Sandbox, error) {
+func (p *Pool) Acquire(ctx context.Context) (sandbox *Sandbox, err error) {
 	if p.closed.Load() {
 		return nil, fmt.Errorf("pool is closed")
 	}
 
+	start := time.Now()
+	idle := false
+	defer func() {
+		if err == nil {
+			p.recordAcquire(time.Since(start), idle)
+		}
+	}()
+
 	// Try to get from available pool first
 	select {
-	case sandbox := <-p.available:
-		if sandbox.IsRunning() {
-			return sandbox, nil
+	case s, ok := <-p.available:
+		if !ok {
+			return nil, fmt.Errorf("pool is closed")
+		}
+		if s.sandbox.IsRunning() {
+			idle = true
+			return s.sandbox, nil
 		}
 		// Sandbox stopped unexpectedly, clean up and create new one
-		_ = sandbox.Close()
-		p.created.Add(-1)
+		p.discard(s.sandbox)
 	default:
 		// No sandbox available
 	}
@@ -110,13 +194,15 @@ func (p *Pool) Acquire(ctx context.Context) (*Sandbox, error) {
 		p.mu.Unlock()
 		// Wait for one to become available
 		select {
-		case sandbox := <-p.available:
-			if sandbox.IsRunning() {
-				return sandbox, nil
+		case s, ok := <-p.available:
+			if !ok {
+				return nil, fmt.Errorf("pool is closed")
+			}
+			if s.sandbox.IsRunning() {
+				return s.sandbox, nil
 			}
 			// Sandbox stopped, try to create new one
-			_ = sandbox.Close()
-			p.created.Add(-1)
+			p.discard(s.sandbox)
 			return p.createSandbox(ctx)
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -143,6 +229,28 @@ func (p *Pool) createSandbox(ctx context.Context) (*Sandbox, error) {
 	return sandbox, nil
 }
 
+// put makes a sandbox available again, closing it if the pool is full or
+// closed.
+func (p *Pool) put(s idleSandbox) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed.Load() {
+		select {
+		case p.available <- s:
+			return
+		default:
+			// Pool is full
+		}
+	}
+	p.discard(s.sandbox)
+}
+
+// discard closes a sandbox the pool created.
+func (p *Pool) discard(s *Sandbox) {
+	_ = s.Close()
+	p.created.Add(-1)
+}
+
 // Release returns a sandbox to the pool for reuse.
 // If the sandbox is not running or the pool is full, it will be closed.
 func (p *Pool) Release(s *Sandbox) {
@@ -152,20 +260,11 @@ func (p *Pool) Release(s *Sandbox) {
 
 	// If pool is closed or sandbox not running, just close it
 	if p.closed.Load() || !s.IsRunning() {
-		_ = s.Close()
-		p.created.Add(-1)
+		p.discard(s)
 		return
 	}
 
-	// Try to return to pool
-	select {
-	case p.available <- s:
-		// Successfully returned to pool
-	default:
-		// Pool is full, close the sandbox
-		_ = s.Close()
-		p.created.Add(-1)
-	}
+	p.put(idleSandbox{sandbox: s, since: time.Now()})
 }
 
 // ReleaseWithReset returns a sandbox to the pool after resetting it.
@@ -177,16 +276,14 @@ func (p *Pool) ReleaseWithReset(ctx context.Context, s *Sandbox) {
 
 	// If pool is closed, just close the sandbox
 	if p.closed.Load() {
-		_ = s.Close()
-		p.created.Add(-1)
+		p.discard(s)
 		return
 	}
 
 	// Reset the sandbox
 	if err := s.Reset(ctx); err != nil {
 		// Reset failed, close and don't return to pool
-		_ = s.Close()
-		p.created.Add(-1)
+		p.discard(s)
 		return
 	}
 
@@ -194,6 +291,100 @@ func (p *Pool) ReleaseWithReset(ctx context.Context, s *Sandbox) {
 	p.Release(s)
 }
 
+// maintainLoop keeps the pool at its minimum size and reaps and checks idle
+// containers until ctx is cancelled.
+func (p *Pool) maintainLoop(ctx context.Context) {
+	defer p.wg.Done()
+
+	interval := p.poolCfg.HealthInterval
+	if interval <= 0 {
+		interval = DefaultPoolHealthInterval
+	}
+	if p.poolCfg.IdleTimeout > 0 && p.poolCfg.IdleTimeout < interval {
+		interval = p.poolCfg.IdleTimeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.maintain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// maintain goes through the idle containers once, closing those idle for
+// longer than IdleTimeout and replacing those that fail their health
+// check, then starts containers until MinSize are running.
+func (p *Pool) maintain(ctx context.Context) {
+	dead := 0
+	for i, n := 0, len(p.available); i < n && ctx.Err() == nil; i++ {
+		var s idleSandbox
+		select {
+		case idle, ok := <-p.available:
+			if !ok {
+				return
+			}
+			s = idle
+		default:
+			// Acquired meanwhile
+		}
+		if s.sandbox == nil {
+			break
+		}
+
+		if p.poolCfg.IdleTimeout > 0 && time.Since(s.since) > p.poolCfg.IdleTimeout && p.Created() > p.poolCfg.MinSize {
+			p.discard(s.sandbox)
+			p.addStat(func(st *PoolStats) { st.Reaped++ })
+			continue
+		}
+		if !p.healthy(ctx, s.sandbox) {
+			p.discard(s.sandbox)
+			p.addStat(func(st *PoolStats) { st.Replaced++ })
+			dead++
+			continue
+		}
+		p.put(s)
+	}
+
+	// Replace dead containers and top up to MinSize
+	want := p.Created() + dead
+	if want < p.poolCfg.MinSize {
+		want = p.poolCfg.MinSize
+	}
+	if want > p.maxSize {
+		want = p.maxSize
+	}
+	for p.Created() < want && ctx.Err() == nil && !p.closed.Load() {
+		s, err := p.createSandbox(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Warning: failed to start a pooled sandbox: %v", err)
+			}
+			return
+		}
+		p.put(idleSandbox{sandbox: s, since: time.Now()})
+	}
+}
+
+// healthy reports whether s still runs commands. Without health checks
+// it only asks whether the container is running.
+func (p *Pool) healthy(ctx context.Context, s *Sandbox) bool {
+	if !s.IsRunning() {
+		return false
+	}
+	if p.poolCfg.HealthInterval <= 0 {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(ctx, poolHealthTimeout)
+	defer cancel()
+	_, _, exitCode, err := s.Execute(ctx, []string{"true"})
+	return err == nil && exitCode == 0
+}
+
 // Size returns the number of sandboxes currently available in the pool.
 func (p *Pool) Size() int {
 	return len(p.available)
@@ -215,11 +406,18 @@ func (p *Pool) Close() error {
 		return nil // Already closed
 	}
 
+	// Stop maintenance before closing the channel it sends on
+	if p.cancel != nil {
+		p.cancel()
+		p.wg.Wait()
+	}
+
 	// Close all available sandboxes
+	p.mu.Lock()
 	close(p.available)
-	for sandbox := range p.available {
-		_ = sandbox.Close()
-		p.created.Add(-1)
+	p.mu.Unlock()
+	for s := range p.available {
+		p.discard(s.sandbox)
 	}
 
 	return nil
@@ -230,22 +428,67 @@ func (p *Pool) Config() SandboxConfig {
 	return p.config
 }
 
+// PoolConfig returns the pool's size and maintenance settings.
+func (p *Pool) PoolConfig() PoolConfig {
+	return p.poolCfg
+}
+
 // PoolStats holds statistics about the pool.
 type PoolStats struct {
 	Available int
 	Created   int
+	MinSize   int
 	MaxSize   int
 	Closed    bool
+
+	Acquires       int64         // successful Acquire calls
+	IdleHits       int64         // acquires served by an idle container
+	AcquireWait    time.Duration // total time spent in Acquire
+	MaxAcquireWait time.Duration // longest single Acquire
+	Reaped         int64         // idle containers closed after IdleTimeout
+	Replaced       int64         // containers that failed a health check
+}
+
+// MeanAcquireWait returns the average time an Acquire took.
+func (s PoolStats) MeanAcquireWait() time.Duration {
+	if s.Acquires == 0 {
+		return 0
+	}
+	return s.AcquireWait / time.Duration(s.Acquires)
 }
 
 // Stats returns current pool statistics.
 func (p *Pool) Stats() PoolStats {
-	return PoolStats{
-		Available: len(p.available),
-		Created:   int(p.created.Load()),
-		MaxSize:   p.maxSize,
-		Closed:    p.closed.Load(),
-	}
+	p.statsMu.Lock()
+	st := p.stats
+	p.statsMu.Unlock()
+
+	st.Available = len(p.available)
+	st.Created = int(p.created.Load())
+	st.MinSize = p.poolCfg.MinSize
+	st.MaxSize = p.maxSize
+	st.Closed = p.closed.Load()
+	return st
+}
+
+// recordAcquire adds one Acquire that took wait to the statistics.
+func (p *Pool) recordAcquire(wait time.Duration, idle bool) {
+	p.addStat(func(st *PoolStats) {
+		st.Acquires++
+		if idle {
+			st.IdleHits++
+		}
+		st.AcquireWait += wait
+		if wait > st.MaxAcquireWait {
+			st.MaxAcquireWait = wait
+		}
+	})
+}
+
+func (p *Pool) addStat(fn func(st *PoolStats)) {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	fn(&p.stats)
 }
 
 // ExecuteInPool acquires a sandbox, executes the command, and releases the sandbox.
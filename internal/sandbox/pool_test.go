@@ -0,0 +1,41 @@
+//go:build !lite && !nodocker
+
+package sandbox
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewPoolWithConfig(t *testing.T) {
+	p := NewPoolWithConfig(DefaultConfig(), PoolConfig{MinSize: 3, MaxSize: 0})
+	defer p.Close()
+	if cfg := p.PoolConfig(); cfg.MaxSize != 1 || cfg.MinSize != 1 {
+		t.Errorf("PoolConfig() = %+v, want sizes clamped to 1", cfg)
+	}
+	if p.cancel == nil {
+		t.Error("maintenance not started for a minimum size")
+	}
+
+	p = NewPool(DefaultConfig(), 4)
+	if p.cancel != nil {
+		t.Error("maintenance started without a minimum size, idle timeout or health checks")
+	}
+	p.recordAcquire(10*time.Millisecond, true)
+	p.recordAcquire(30*time.Millisecond, false)
+	st := p.Stats()
+	if st.Acquires != 2 || st.IdleHits != 1 || st.MaxAcquireWait != 30*time.Millisecond || st.MeanAcquireWait() != 20*time.Millisecond {
+		t.Errorf("Stats() = %+v", st)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Acquire(context.Background()); err == nil {
+		t.Error("Acquire on a closed pool succeeded")
+	}
+	if !p.Stats().Closed {
+		t.Error("pool not reported closed")
+	}
+}
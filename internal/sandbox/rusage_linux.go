@@ -0,0 +1,15 @@
+package sandbox
+
+import (
+	"os"
+	"syscall"
+)
+
+// maxRSS returns the peak resident memory of a finished process in bytes,
+// which Linux reports in kilobytes.
+func maxRSS(state *os.ProcessState) int64 {
+	if ru, ok := state.SysUsage().(*syscall.Rusage); ok {
+		return int64(ru.Maxrss) * 1024
+	}
+	return 0
+}
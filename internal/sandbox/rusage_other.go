@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package sandbox
+
+import "os"
+
+// maxRSS returns 0: the peak memory of a process is not known on this
+// platform.
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
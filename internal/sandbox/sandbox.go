@@ -1,3 +1,5 @@
+//go:build !lite && !nodocker
+
 // Package sandbox provides a secure container-based execution environment.
 package sandbox
 
@@ -23,6 +25,7 @@ type Sandbox struct {
 	client      *client.Client
 	containerID string
 	running     bool
+	egress      *EgressProxy // for AllowedHosts, while running
 	mu          sync.RWMutex
 }
 
@@ -74,11 +77,25 @@ func (s *Sandbox) Start(ctx context.Context) error {
 	}
 
 	// Create container configuration
-	containerCfg, hostCfg, networkCfg := s.buildContainerConfig()
+	containerCfg, hostCfg, networkCfg, err := s.buildContainerConfig()
+	if err != nil {
+		return err
+	}
+
+	// Reach the allowed hosts only through the egress proxy
+	if s.config.NetworkMode() == "allowlist" {
+		proxyURL, err := s.startEgress(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start egress proxy: %w", err)
+		}
+		hostCfg.NetworkMode = container.NetworkMode(EgressNetwork)
+		containerCfg.Env = append(containerCfg.Env, proxyEnv(proxyURL)...)
+	}
 
 	// Create the container
 	resp, err := s.client.ContainerCreate(ctx, containerCfg, hostCfg, networkCfg, nil, "")
 	if err != nil {
+		s.stopEgress()
 		return fmt.Errorf("failed to create container: %w", err)
 	}
 	s.containerID = resp.ID
@@ -88,6 +105,7 @@ func (s *Sandbox) Start(ctx context.Context) error {
 		// Clean up the created container
 		_ = s.client.ContainerRemove(ctx, s.containerID, container.RemoveOptions{Force: true})
 		s.containerID = ""
+		s.stopEgress()
 		return fmt.Errorf("failed to start container: %w", err)
 	}
 
@@ -120,7 +138,13 @@ func (s *Sandbox) ensureImage(ctx context.Context) error {
 }
 
 // buildContainerConfig creates the container, host, and network configurations.
-func (s *Sandbox) buildContainerConfig() (*container.Config, *container.HostConfig, *network.NetworkingConfig) {
+func (s *Sandbox) buildContainerConfig() (*container.Config, *container.HostConfig, *network.NetworkingConfig, error) {
+	// No new privileges, seccomp and AppArmor
+	securityOpts, err := s.config.securityOpts()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// Container configuration
 	containerCfg := &container.Config{
 		Image:      s.config.Image,
@@ -139,8 +163,8 @@ func (s *Sandbox) buildContainerConfig() (*container.Config, *container.HostConf
 		// Drop all capabilities
 		CapDrop: []string{"ALL"},
 
-		// Prevent privilege escalation
-		SecurityOpt: []string{"no-new-privileges:true"},
+		// Prevent privilege escalation and limit system calls
+		SecurityOpt: securityOpts,
 
 		// Auto-remove container when stopped
 		AutoRemove: true,
@@ -175,11 +199,15 @@ func (s *Sandbox) buildContainerConfig() (*container.Config, *container.HostConf
 		hostCfg.Runtime = "runsc"
 	}
 
-	// Add mount paths
+	// Add mount paths, as the Docker host sees them
 	for _, mp := range s.config.MountPaths {
+		source, err := s.config.HostPaths.Translate(mp.Source)
+		if err != nil {
+			return nil, nil, nil, err
+		}
 		hostCfg.Mounts = append(hostCfg.Mounts, mount.Mount{
 			Type:     mount.TypeBind,
-			Source:   mp.Source,
+			Source:   source,
 			Target:   mp.Target,
 			ReadOnly: mp.ReadOnly,
 		})
@@ -188,7 +216,7 @@ func (s *Sandbox) buildContainerConfig() (*container.Config, *container.HostConf
 	// Network configuration
 	networkCfg := &network.NetworkingConfig{}
 
-	return containerCfg, hostCfg, networkCfg
+	return containerCfg, hostCfg, networkCfg, nil
 }
 
 // Stop stops the sandbox container.
@@ -207,6 +235,7 @@ func (s *Sandbox) Stop(ctx context.Context) error {
 		_ = s.client.ContainerRemove(ctx, s.containerID, container.RemoveOptions{Force: true})
 	}
 
+	s.stopEgress()
 	s.running = false
 	s.containerID = ""
 	return nil
@@ -214,10 +243,23 @@ func (s *Sandbox) Stop(ctx context.Context) error {
 
 // Execute runs a command inside the sandbox and returns the output.
 func (s *Sandbox) Execute(ctx context.Context, cmd []string) (stdout, stderr string, exitCode int, err error) {
+	return s.ExecuteStream(ctx, cmd, nil)
+}
+
+// ExecuteStream runs a command inside the sandbox like Execute, passing its
+// output to onOutput as it arrives.
+func (s *Sandbox) ExecuteStream(ctx context.Context, cmd []string, onOutput OutputFunc) (stdout, stderr string, exitCode int, err error) {
+	result, err := s.Run(ctx, cmd, onOutput)
+	return result.Stdout, result.Stderr, result.ExitCode, err
+}
+
+// Run runs a command inside the sandbox like ExecuteStream, measuring the
+// resources it used through the Docker stats API.
+func (s *Sandbox) Run(ctx context.Context, cmd []string, onOutput OutputFunc) (Result, error) {
 	s.mu.RLock()
 	if !s.running {
 		s.mu.RUnlock()
-		return "", "", -1, fmt.Errorf("sandbox is not running")
+		return Result{ExitCode: -1}, fmt.Errorf("sandbox is not running")
 	}
 	containerID := s.containerID
 	s.mu.RUnlock()
@@ -229,7 +271,7 @@ func (s *Sandbox) Execute(ctx context.Context, cmd []string) (stdout, stderr str
 			fullCmd += c + " "
 		}
 		if reason := GuardCommand(fullCmd); reason != "" {
-			return "", "", -1, fmt.Errorf("command guard: %s", reason)
+			return Result{ExitCode: -1}, fmt.Errorf("command guard: %s", reason)
 		}
 	}
 
@@ -249,13 +291,17 @@ func (s *Sandbox) Execute(ctx context.Context, cmd []string) (stdout, stderr str
 	// Create the exec instance
 	execResp, err := s.client.ContainerExecCreate(execCtx, containerID, execConfig)
 	if err != nil {
-		return "", "", -1, fmt.Errorf("failed to create exec: %w", err)
+		return Result{ExitCode: -1}, fmt.Errorf("failed to create exec: %w", err)
 	}
 
+	// Measure the command from just before it starts
+	meter := startMeter(execCtx, s.client, containerID, s.config)
+
 	// Attach to the exec instance
 	attachResp, err := s.client.ContainerExecAttach(execCtx, execResp.ID, container.ExecStartOptions{})
 	if err != nil {
-		return "", "", -1, fmt.Errorf("failed to attach to exec: %w", err)
+		meter.finish(-1)
+		return Result{ExitCode: -1}, fmt.Errorf("failed to attach to exec: %w", err)
 	}
 	defer attachResp.Close()
 
@@ -264,7 +310,8 @@ func (s *Sandbox) Execute(ctx context.Context, cmd []string) (stdout, stderr str
 	outputDone := make(chan error, 1)
 
 	go func() {
-		_, err := stdcopy.StdCopy(&stdoutBuf, &stderrBuf, attachResp.Reader)
+		stdoutW, stderrW := streamWriters(&stdoutBuf, &stderrBuf, onOutput)
+		_, err := stdcopy.StdCopy(stdoutW, stderrW, attachResp.Reader)
 		outputDone <- err
 	}()
 
@@ -272,19 +319,29 @@ func (s *Sandbox) Execute(ctx context.Context, cmd []string) (stdout, stderr str
 	select {
 	case err := <-outputDone:
 		if err != nil {
-			return stdoutBuf.String(), stderrBuf.String(), -1, fmt.Errorf("failed to read output: %w", err)
+			return Result{Stdout: stdoutBuf.String(), Stderr: stderrBuf.String(), ExitCode: -1, Usage: meter.finish(-1)},
+				fmt.Errorf("failed to read output: %w", err)
 		}
 	case <-execCtx.Done():
-		return stdoutBuf.String(), stderrBuf.String(), -1, fmt.Errorf("command timed out after %v", s.config.Timeout)
+		usage := meter.finish(-1)
+		usage.Stopped = StoppedTimeout
+		return Result{Stdout: stdoutBuf.String(), Stderr: stderrBuf.String(), ExitCode: -1, Usage: usage},
+			fmt.Errorf("command timed out after %v", s.config.Timeout)
 	}
 
 	// Get the exit code
 	inspectResp, err := s.client.ContainerExecInspect(execCtx, execResp.ID)
 	if err != nil {
-		return stdoutBuf.String(), stderrBuf.String(), -1, fmt.Errorf("failed to inspect exec: %w", err)
+		return Result{Stdout: stdoutBuf.String(), Stderr: stderrBuf.String(), ExitCode: -1, Usage: meter.finish(-1)},
+			fmt.Errorf("failed to inspect exec: %w", err)
 	}
 
-	return stdoutBuf.String(), stderrBuf.String(), inspectResp.ExitCode, nil
+	return Result{
+		Stdout:   stdoutBuf.String(),
+		Stderr:   stderrBuf.String(),
+		ExitCode: inspectResp.ExitCode,
+		Usage:    meter.finish(inspectResp.ExitCode),
+	}, nil
 }
 
 // ExecuteShell runs a shell command inside the sandbox.
@@ -293,6 +350,12 @@ func (s *Sandbox) ExecuteShell(ctx context.Context, command string) (stdout, std
 	return s.Execute(ctx, []string{"sh", "-c", command})
 }
 
+// ExecuteShellStream runs a shell command inside the sandbox like
+// ExecuteShell, passing its output to onOutput as it arrives.
+func (s *Sandbox) ExecuteShellStream(ctx context.Context, command string, onOutput OutputFunc) (stdout, stderr string, exitCode int, err error) {
+	return s.ExecuteStream(ctx, []string{"sh", "-c", command}, onOutput)
+}
+
 // IsRunning returns true if the sandbox container is running.
 func (s *Sandbox) IsRunning() bool {
 	s.mu.RLock()
@@ -0,0 +1,191 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Seccomp profiles besides uBot's own, for SandboxConfig.SeccompProfile.
+const (
+	SeccompDocker     = "docker"     // Docker's default profile
+	SeccompUnconfined = "unconfined" // no system call filtering
+)
+
+// Linux constants used in the seccomp profile.
+const (
+	afVsock        = 40         // socket family of VM sockets
+	namespaceFlags = 0x7E020000 // CLONE_NEW* flags of clone
+	errnoENOSYS    = 38
+)
+
+// seccompAllowed are the system calls the container may make without
+// conditions: Docker's default allowlist for a container without
+// capabilities, less ptrace, process_vm_readv and process_vm_writev, and
+// less calls ordinary programs do not need that keep turning up in kernel
+// exploits: vmsplice, name_to_handle_at, remap_file_pages, mincore and
+// fanotify_mark. Calls that do not exist on an architecture are skipped.
+var seccompAllowed = []string{
+	"_llseek", "_newselect", "accept", "accept4", "access", "adjtimex", "alarm",
+	"arch_prctl", "arm_fadvise64_64", "arm_sync_file_range", "bind",
+	"breakpoint", "brk", "cacheflush", "cachestat", "capget", "capset", "chdir",
+	"chmod", "chown", "chown32", "clock_adjtime", "clock_adjtime64",
+	"clock_getres", "clock_getres_time64", "clock_gettime", "clock_gettime64",
+	"clock_nanosleep", "clock_nanosleep_time64", "close", "close_range",
+	"connect", "copy_file_range", "creat", "dup", "dup2", "dup3",
+	"epoll_create", "epoll_create1", "epoll_ctl", "epoll_ctl_old",
+	"epoll_pwait", "epoll_pwait2", "epoll_wait", "epoll_wait_old", "eventfd",
+	"eventfd2", "execve", "execveat", "exit", "exit_group", "faccessat",
+	"faccessat2", "fadvise64", "fadvise64_64", "fallocate", "fchdir", "fchmod",
+	"fchmodat", "fchmodat2", "fchown", "fchown32", "fchownat", "fcntl",
+	"fcntl64", "fdatasync", "fgetxattr", "flistxattr", "flock", "fork",
+	"fremovexattr", "fsetxattr", "fstat", "fstat64", "fstatat64", "fstatfs",
+	"fstatfs64", "fsync", "ftruncate", "ftruncate64", "futex", "futex_requeue",
+	"futex_time64", "futex_wait", "futex_waitv", "futex_wake", "futimesat",
+	"get_robust_list", "get_thread_area", "getcpu", "getcwd", "getdents",
+	"getdents64", "getegid", "getegid32", "geteuid", "geteuid32", "getgid",
+	"getgid32", "getgroups", "getgroups32", "getitimer", "getpeername",
+	"getpgid", "getpgrp", "getpid", "getppid", "getpriority", "getrandom",
+	"getresgid", "getresgid32", "getresuid", "getresuid32", "getrlimit",
+	"getrusage", "getsid", "getsockname", "getsockopt", "gettid",
+	"gettimeofday", "getuid", "getuid32", "getxattr", "inotify_add_watch",
+	"inotify_init", "inotify_init1", "inotify_rm_watch", "io_cancel",
+	"io_destroy", "io_getevents", "io_pgetevents", "io_pgetevents_time64",
+	"io_setup", "io_submit", "ioctl", "ioprio_get", "ioprio_set", "ipc", "kill",
+	"landlock_add_rule", "landlock_create_ruleset", "landlock_restrict_self",
+	"lchown", "lchown32", "lgetxattr", "link", "linkat", "listen", "listxattr",
+	"llistxattr", "lremovexattr", "lseek", "lsetxattr", "lstat", "lstat64",
+	"madvise", "map_shadow_stack", "membarrier", "memfd_create", "memfd_secret",
+	"mkdir", "mkdirat", "mknod", "mknodat", "mlock", "mlock2", "mlockall",
+	"mmap", "mmap2", "modify_ldt", "mprotect", "mq_getsetattr", "mq_notify",
+	"mq_open", "mq_timedreceive", "mq_timedreceive_time64", "mq_timedsend",
+	"mq_timedsend_time64", "mq_unlink", "mremap", "msgctl", "msgget", "msgrcv",
+	"msgsnd", "msync", "munlock", "munlockall", "munmap", "nanosleep",
+	"newfstatat", "open", "openat", "openat2", "pause", "pidfd_open",
+	"pidfd_send_signal", "pipe", "pipe2", "pkey_alloc", "pkey_free",
+	"pkey_mprotect", "poll", "ppoll", "ppoll_time64", "prctl", "pread64",
+	"preadv", "preadv2", "prlimit64", "process_mrelease", "pselect6",
+	"pselect6_time64", "pwrite64", "pwritev", "pwritev2", "read", "readahead",
+	"readlink", "readlinkat", "readv", "recv", "recvfrom", "recvmmsg",
+	"recvmmsg_time64", "recvmsg", "removexattr", "rename", "renameat",
+	"renameat2", "restart_syscall", "riscv_flush_icache", "rmdir", "rseq",
+	"rt_sigaction", "rt_sigpending", "rt_sigprocmask", "rt_sigqueueinfo",
+	"rt_sigreturn", "rt_sigsuspend", "rt_sigtimedwait",
+	"rt_sigtimedwait_time64", "rt_tgsigqueueinfo", "sched_get_priority_max",
+	"sched_get_priority_min", "sched_getaffinity", "sched_getattr",
+	"sched_getparam", "sched_getscheduler", "sched_rr_get_interval",
+	"sched_rr_get_interval_time64", "sched_setaffinity", "sched_setattr",
+	"sched_setparam", "sched_setscheduler", "sched_yield", "seccomp", "select",
+	"semctl", "semget", "semop", "semtimedop", "semtimedop_time64", "send",
+	"sendfile", "sendfile64", "sendmmsg", "sendmsg", "sendto",
+	"set_robust_list", "set_thread_area", "set_tid_address", "set_tls",
+	"setfsgid", "setfsgid32", "setfsuid", "setfsuid32", "setgid", "setgid32",
+	"setgroups", "setgroups32", "setitimer", "setpgid", "setpriority",
+	"setregid", "setregid32", "setresgid", "setresgid32", "setresuid",
+	"setresuid32", "setreuid", "setreuid32", "setrlimit", "setsid",
+	"setsockopt", "setuid", "setuid32", "setxattr", "shmat", "shmctl", "shmdt",
+	"shmget", "shutdown", "sigaltstack", "signalfd", "signalfd4", "sigprocmask",
+	"sigreturn", "socketcall", "socketpair", "splice", "stat", "stat64",
+	"statfs", "statfs64", "statx", "symlink", "symlinkat", "sync",
+	"sync_file_range", "sync_file_range2", "syncfs", "sysinfo", "tee", "tgkill",
+	"time", "timer_create", "timer_delete", "timer_getoverrun", "timer_gettime",
+	"timer_gettime64", "timer_settime", "timer_settime64", "timerfd_create",
+	"timerfd_gettime", "timerfd_gettime64", "timerfd_settime",
+	"timerfd_settime64", "times", "tkill", "truncate", "truncate64",
+	"ugetrlimit", "umask", "uname", "unlink", "unlinkat", "utime", "utimensat",
+	"utimensat_time64", "utimes", "vfork", "wait4", "waitid", "waitpid",
+	"write", "writev",
+}
+
+// seccompProfile is a seccomp profile in the JSON format Docker reads.
+type seccompProfile struct {
+	DefaultAction   string        `json:"defaultAction"`
+	DefaultErrnoRet uint          `json:"defaultErrnoRet"`
+	ArchMap         []seccompArch `json:"archMap"`
+	Syscalls        []seccompRule `json:"syscalls"`
+}
+
+type seccompArch struct {
+	Architecture     string   `json:"architecture"`
+	SubArchitectures []string `json:"subArchitectures"`
+}
+
+type seccompRule struct {
+	Names    []string     `json:"names"`
+	Action   string       `json:"action"`
+	ErrnoRet uint         `json:"errnoRet,omitempty"`
+	Args     []seccompArg `json:"args,omitempty"`
+}
+
+type seccompArg struct {
+	Index    uint   `json:"index"`
+	Value    uint64 `json:"value"`
+	ValueTwo uint64 `json:"valueTwo"`
+	Op       string `json:"op"`
+}
+
+// SeccompProfileJSON returns uBot's seccomp profile for sandbox containers.
+// System calls not allowed fail with EPERM. Besides seccompAllowed, it
+// allows sockets other than VM sockets, the personality settings Docker
+// allows, and clone without flags for new namespaces; clone3 fails with
+// ENOSYS so that the C library falls back to clone.
+func SeccompProfileJSON() []byte {
+	allow := func(name string, args ...seccompArg) seccompRule {
+		return seccompRule{Names: []string{name}, Action: "SCMP_ACT_ALLOW", Args: args}
+	}
+	rules := []seccompRule{
+		{Names: seccompAllowed, Action: "SCMP_ACT_ALLOW"},
+		allow("socket", seccompArg{Index: 0, Value: afVsock, Op: "SCMP_CMP_NE"}),
+		allow("clone", seccompArg{Index: 0, Value: namespaceFlags, ValueTwo: 0, Op: "SCMP_CMP_MASKED_EQ"}),
+		{Names: []string{"clone3"}, Action: "SCMP_ACT_ERRNO", ErrnoRet: errnoENOSYS},
+	}
+	for _, persona := range []uint64{0x0, 0x8, 0x20000, 0x20008, 0xffffffff} {
+		rules = append(rules, allow("personality", seccompArg{Index: 0, Value: persona, Op: "SCMP_CMP_EQ"}))
+	}
+
+	data, err := json.Marshal(seccompProfile{
+		DefaultAction:   "SCMP_ACT_ERRNO",
+		DefaultErrnoRet: 1, // EPERM
+		ArchMap: []seccompArch{
+			{Architecture: "SCMP_ARCH_X86_64", SubArchitectures: []string{"SCMP_ARCH_X86", "SCMP_ARCH_X32"}},
+			{Architecture: "SCMP_ARCH_AARCH64", SubArchitectures: []string{"SCMP_ARCH_ARM"}},
+		},
+		Syscalls: rules,
+	})
+	if err != nil {
+		panic(err) // the profile is static
+	}
+	return data
+}
+
+// securityOpts returns the Docker security options for the container:
+// no-new-privileges, the seccomp profile and the AppArmor profile.
+func (c SandboxConfig) securityOpts() ([]string, error) {
+	opts := []string{"no-new-privileges:true"}
+
+	switch c.SeccompProfile {
+	case SeccompDocker:
+		// Docker applies its default profile
+	case SeccompUnconfined:
+		opts = append(opts, "seccomp=unconfined")
+	case "":
+		// gVisor filters system calls itself
+		if !c.UseGVisor {
+			opts = append(opts, "seccomp="+string(SeccompProfileJSON()))
+		}
+	default:
+		data, err := os.ReadFile(c.SeccompProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seccomp profile: %w", err)
+		}
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("seccomp profile %s is not valid JSON", c.SeccompProfile)
+		}
+		opts = append(opts, "seccomp="+string(data))
+	}
+
+	if c.AppArmorProfile != "" {
+		opts = append(opts, "apparmor="+c.AppArmorProfile)
+	}
+	return opts, nil
+}
@@ -0,0 +1,96 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSeccompProfileJSON(t *testing.T) {
+	var profile seccompProfile
+	if err := json.Unmarshal(SeccompProfileJSON(), &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.DefaultAction != "SCMP_ACT_ERRNO" {
+		t.Errorf("default action = %q", profile.DefaultAction)
+	}
+	allowed := map[string]bool{}
+	for _, rule := range profile.Syscalls {
+		for _, name := range rule.Names {
+			if rule.Action == "SCMP_ACT_ALLOW" && len(rule.Args) == 0 {
+				allowed[name] = true
+			}
+		}
+	}
+	for _, name := range []string{"read", "write", "execve", "openat", "arch_prctl"} {
+		if !allowed[name] {
+			t.Errorf("%s is not allowed", name)
+		}
+	}
+	for _, name := range []string{"ptrace", "mount", "unshare", "setns", "bpf", "keyctl", "vmsplice", "clone", "socket"} {
+		if allowed[name] {
+			t.Errorf("%s is allowed unconditionally", name)
+		}
+	}
+}
+
+func TestSecurityOpts(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "profile.json")
+	if err := os.WriteFile(profile, []byte(`{"defaultAction": "SCMP_ACT_ALLOW"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(t.TempDir(), "broken.json")
+	if err := os.WriteFile(broken, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	seccomp := func(opts []string) string {
+		i := slices.IndexFunc(opts, func(o string) bool { return strings.HasPrefix(o, "seccomp=") })
+		if i < 0 {
+			return ""
+		}
+		return opts[i]
+	}
+	cfg := DefaultConfig()
+	tests := []struct {
+		name string
+		cfg  SandboxConfig
+		want string
+	}{
+		{"restrictive", cfg, "seccomp=" + string(SeccompProfileJSON())},
+		{"gVisor", cfg.WithGVisor(true), ""},
+		{"docker", cfg.WithSeccompProfile(SeccompDocker), ""},
+		{"unconfined", cfg.WithSeccompProfile(SeccompUnconfined), "seccomp=unconfined"},
+		{"file", cfg.WithSeccompProfile(profile), `seccomp={"defaultAction": "SCMP_ACT_ALLOW"}`},
+	}
+	for _, tt := range tests {
+		opts, err := tt.cfg.securityOpts()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if opts[0] != "no-new-privileges:true" || seccomp(opts) != tt.want {
+			t.Errorf("%s: seccomp option = %.60q, want %.60q", tt.name, seccomp(opts), tt.want)
+		}
+	}
+
+	if _, err := cfg.WithSeccompProfile(broken).securityOpts(); err == nil {
+		t.Error("invalid profile accepted")
+	}
+	opts, err := cfg.WithAppArmorProfile(DefaultAppArmorProfile).securityOpts()
+	if err != nil || opts[len(opts)-1] != "apparmor=ubot-sandbox" {
+		t.Errorf("AppArmor options = %.80q, %v", opts, err)
+	}
+}
+
+func TestAppArmorProfile(t *testing.T) {
+	profile := AppArmorProfile("")
+	if !strings.Contains(profile, "profile ubot-sandbox flags=") || !strings.Contains(profile, "peer=ubot-sandbox,") {
+		t.Errorf("profile not named %s:\n%s", DefaultAppArmorProfile, profile)
+	}
+	if strings.Contains(AppArmorProfile("custom"), "ubot-sandbox") {
+		t.Error("custom name not used throughout")
+	}
+}
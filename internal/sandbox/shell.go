@@ -0,0 +1,70 @@
+package sandbox
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// DefaultShells returns the shells looked for, in order, on goos.
+func DefaultShells(goos string) []string {
+	if goos == "windows" {
+		return []string{"cmd", "pwsh", "powershell"}
+	}
+	return []string{"sh", "bash", "zsh"}
+}
+
+// shellName returns the lower-case name of shell without directory or
+// ".exe", e.g. "cmd" for C:\Windows\System32\cmd.exe.
+func shellName(shell string) string {
+	name := strings.ToLower(shell)
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// ShellCommand returns the arguments that run command with shell: /c for
+// cmd.exe, -Command for PowerShell and -c for Unix shells.
+func ShellCommand(shell, command string) []string {
+	switch shellName(shell) {
+	case "cmd":
+		return []string{shell, "/d", "/s", "/c", command}
+	case "powershell", "pwsh":
+		return []string{shell, "-NoProfile", "-NonInteractive", "-Command", command}
+	}
+	return []string{shell, "-c", command}
+}
+
+// ShellCmd returns a command that runs command with shell.
+func ShellCmd(ctx context.Context, shell, command string) *exec.Cmd {
+	args := ShellCommand(shell, command)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	setCmdLine(cmd, args)
+	return cmd
+}
+
+// cmdExeLine returns the command line for running args with cmd.exe, which
+// takes everything after /c as the command instead of parsing it as
+// arguments quoted the way Go quotes them. With /s it strips the outer
+// quotes added here and runs the rest verbatim.
+func cmdExeLine(args []string) (string, bool) {
+	n := len(args)
+	if n < 3 || shellName(args[0]) != "cmd" || !strings.EqualFold(args[n-2], "/c") {
+		return "", false
+	}
+	line := quoteArg(args[0])
+	for _, arg := range args[1 : n-1] {
+		line += " " + quoteArg(arg)
+	}
+	return line + ` "` + args[n-1] + `"`, true
+}
+
+// quoteArg quotes arg for a Windows command line if it has spaces, e.g.
+// for a shell under C:\Program Files.
+func quoteArg(arg string) string {
+	if strings.ContainsAny(arg, " \t") {
+		return `"` + arg + `"`
+	}
+	return arg
+}
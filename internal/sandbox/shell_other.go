@@ -0,0 +1,9 @@
+//go:build !windows
+
+package sandbox
+
+import "os/exec"
+
+// setCmdLine does nothing outside Windows, where arguments are passed as
+// they are.
+func setCmdLine(*exec.Cmd, []string) {}
@@ -0,0 +1,44 @@
+package sandbox
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShellCommand(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{"/bin/sh", []string{"/bin/sh", "-c", "echo hi"}},
+		{`C:\Windows\System32\cmd.exe`, []string{`C:\Windows\System32\cmd.exe`, "/d", "/s", "/c", "echo hi"}},
+		{`C:\Program Files\PowerShell\7\pwsh.exe`, []string{`C:\Program Files\PowerShell\7\pwsh.exe`, "-NoProfile", "-NonInteractive", "-Command", "echo hi"}},
+		{"powershell", []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "echo hi"}},
+	}
+	for _, tt := range tests {
+		if got := ShellCommand(tt.shell, "echo hi"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ShellCommand(%q) = %q, want %q", tt.shell, got, tt.want)
+		}
+	}
+}
+
+func TestCmdExeLine(t *testing.T) {
+	line, ok := cmdExeLine(ShellCommand(`C:\My Tools\cmd.exe`, `echo "a b" && dir C:\`))
+	if want := `"C:\My Tools\cmd.exe" /d /s /c "echo "a b" && dir C:\"`; !ok || line != want {
+		t.Errorf("cmdExeLine = %q, %v, want %q", line, ok, want)
+	}
+	if _, ok := cmdExeLine(ShellCommand("pwsh", "dir")); ok {
+		t.Error("PowerShell got a cmd.exe command line")
+	}
+}
+
+func TestDefaultShellsByPlatform(t *testing.T) {
+	if got := DefaultShells("windows"); got[0] != "cmd" {
+		t.Errorf("Windows shells = %v", got)
+	}
+	for _, goos := range []string{"linux", "darwin"} {
+		if got := DefaultShells(goos); got[0] != "sh" {
+			t.Errorf("%s shells = %v", goos, got)
+		}
+	}
+}
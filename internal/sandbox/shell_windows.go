@@ -0,0 +1,15 @@
+//go:build windows
+
+package sandbox
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCmdLine passes cmd.exe its command line as it is, see cmdExeLine.
+func setCmdLine(cmd *exec.Cmd, args []string) {
+	if line, ok := cmdExeLine(args); ok {
+		cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: line}
+	}
+}
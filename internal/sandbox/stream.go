@@ -0,0 +1,63 @@
+package sandbox
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Stream identifies the output a chunk of a streamed execution came from.
+type Stream int
+
+const (
+	Stdout Stream = iota
+	Stderr
+)
+
+func (s Stream) String() string {
+	if s == Stderr {
+		return "stderr"
+	}
+	return "stdout"
+}
+
+// OutputFunc receives the output of a command as it arrives. It is never
+// called for two chunks at once, and must not keep chunk after returning.
+type OutputFunc func(stream Stream, chunk []byte)
+
+// StreamExecutor is an Executor that can pass a command's output on while
+// the command runs, e.g. to show the progress of a long build. The full
+// output is still returned when the command ends.
+type StreamExecutor interface {
+	Executor
+	ExecuteStream(ctx context.Context, cmd []string, onOutput OutputFunc) (stdout, stderr string, exitCode int, err error)
+	ExecuteShellStream(ctx context.Context, command string, onOutput OutputFunc) (stdout, stderr string, exitCode int, err error)
+}
+
+// outputWriter writes to w and passes what it writes to onOutput. The
+// writers of one execution share mu, so that onOutput sees one chunk at a
+// time.
+type outputWriter struct {
+	w        io.Writer
+	stream   Stream
+	onOutput OutputFunc
+	mu       *sync.Mutex
+}
+
+// streamWriters returns the writers for the stdout and stderr of an
+// execution, passing output to onOutput if it is not nil.
+func streamWriters(stdout, stderr io.Writer, onOutput OutputFunc) (io.Writer, io.Writer) {
+	if onOutput == nil {
+		return stdout, stderr
+	}
+	mu := &sync.Mutex{}
+	return &outputWriter{w: stdout, stream: Stdout, onOutput: onOutput, mu: mu},
+		&outputWriter{w: stderr, stream: Stderr, onOutput: onOutput, mu: mu}
+}
+
+func (o *outputWriter) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onOutput(o.stream, p)
+	return o.w.Write(p)
+}
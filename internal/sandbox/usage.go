@@ -0,0 +1,116 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reasons a command was stopped, in Usage.Stopped.
+const (
+	StoppedTimeout     = "timeout"
+	StoppedMemoryLimit = "memory limit"
+	StoppedKilled      = "killed"
+)
+
+// Usage is the resources one command used. Zero fields are unknown or, for
+// limits, unlimited.
+type Usage struct {
+	WallMs      int64  `json:"wallMs"`
+	CPUMs       int64  `json:"cpuMs"`
+	PeakMemory  int64  `json:"peakMemory,omitempty"`  // bytes
+	MemoryLimit int64  `json:"memoryLimit,omitempty"` // bytes
+	PeakPIDs    int64  `json:"peakPids,omitempty"`
+	PIDLimit    int64  `json:"pidLimit,omitempty"`
+	Stopped     string `json:"stopped,omitempty"` // why the command was stopped, e.g. StoppedTimeout
+}
+
+// Result is the outcome of a command run with its resource usage.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Usage    Usage
+}
+
+// UsageExecutor is a StreamExecutor that also reports the resources each
+// command used.
+type UsageExecutor interface {
+	StreamExecutor
+	Run(ctx context.Context, cmd []string, onOutput OutputFunc) (Result, error)
+}
+
+// String summarizes u in one line, e.g. "2.1s wall, 0.8s CPU, peak memory
+// 45.2 MB of 128.0 MB, 3 of 50 processes".
+func (u Usage) String() string {
+	parts := []string{
+		fmt.Sprintf("%s wall", formatMs(u.WallMs)),
+		fmt.Sprintf("%s CPU", formatMs(u.CPUMs)),
+	}
+	if u.PeakMemory > 0 {
+		mem := "peak memory " + formatBytes(u.PeakMemory)
+		if u.MemoryLimit > 0 {
+			mem += " of " + formatBytes(u.MemoryLimit)
+		}
+		parts = append(parts, mem)
+	}
+	if u.PeakPIDs > 0 {
+		if u.PIDLimit > 0 {
+			parts = append(parts, fmt.Sprintf("%d of %d processes", u.PeakPIDs, u.PIDLimit))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d processes", u.PeakPIDs))
+		}
+	}
+	if u.Stopped != "" {
+		parts = append(parts, "stopped: "+u.Stopped)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// classify sets Stopped for a command that ended with exitCode, unless it
+// is already known. Exit code 137 is SIGKILL; near the memory limit that
+// is the kernel's OOM killer.
+func (u *Usage) classify(exitCode int) {
+	if u.Stopped != "" || exitCode != 137 {
+		return
+	}
+	if u.MemoryLimit > 0 && u.PeakMemory >= u.MemoryLimit*9/10 {
+		u.Stopped = StoppedMemoryLimit
+	} else {
+		u.Stopped = StoppedKilled
+	}
+}
+
+// ProcessUsage returns the resources a finished local process used, with
+// wall the time it ran. Peak memory is known on Linux and macOS only.
+func ProcessUsage(state *os.ProcessState, wall time.Duration) Usage {
+	u := Usage{WallMs: wall.Milliseconds()}
+	if state == nil {
+		return u
+	}
+	u.CPUMs = (state.UserTime() + state.SystemTime()).Milliseconds()
+	u.PeakMemory = maxRSS(state)
+	if !state.Exited() {
+		u.Stopped = StoppedKilled // by a signal
+	}
+	return u
+}
+
+func formatMs(ms int64) string {
+	if ms < 1000 {
+		return fmt.Sprintf("%dms", ms)
+	}
+	return fmt.Sprintf("%.1fs", float64(ms)/1000)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%d KB", n>>10)
+}
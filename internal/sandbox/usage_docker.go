@@ -0,0 +1,123 @@
+//go:build !lite && !nodocker
+
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// statsEvery is how often the container's stats are sampled while a
+// command runs, to catch its peak memory and process count.
+const statsEvery = 250 * time.Millisecond
+
+// usageMeter measures a command in a container through the Docker stats
+// API. The container only runs the command besides its idle init, so its
+// stats are the command's.
+type usageMeter struct {
+	client      *client.Client
+	containerID string
+	started     time.Time
+	stop        chan struct{}
+	done        chan struct{}
+
+	mu       sync.Mutex
+	usage    Usage
+	startCPU uint64 // container CPU time in ns before the command started
+	lastCPU  uint64
+}
+
+// startMeter samples the container once before the command starts and
+// then every statsEvery until finish is called.
+func startMeter(ctx context.Context, cli *client.Client, containerID string, cfg SandboxConfig) *usageMeter {
+	m := &usageMeter{
+		client:      cli,
+		containerID: containerID,
+		started:     time.Now(),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		usage:       Usage{MemoryLimit: cfg.MemoryMB << 20, PIDLimit: cfg.MaxProcesses},
+	}
+	if st, ok := m.sample(ctx); ok {
+		m.startCPU, m.lastCPU = st.CPUStats.CPUUsage.TotalUsage, st.CPUStats.CPUUsage.TotalUsage
+	}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(statsEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.sample(ctx)
+			}
+		}
+	}()
+	return m
+}
+
+// finish stops sampling and returns what the command used, taking a last
+// sample for its total CPU time.
+func (m *usageMeter) finish(exitCode int) Usage {
+	wall := time.Since(m.started)
+	close(m.stop)
+	<-m.done
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m.sample(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.usage
+	u.WallMs = wall.Milliseconds()
+	if m.lastCPU > m.startCPU {
+		u.CPUMs = int64((m.lastCPU - m.startCPU) / uint64(time.Millisecond))
+	}
+	u.classify(exitCode)
+	return u
+}
+
+// sample reads the container's stats once, recording the peaks.
+func (m *usageMeter) sample(ctx context.Context) (container.StatsResponse, bool) {
+	var st container.StatsResponse
+	resp, err := m.client.ContainerStatsOneShot(ctx, m.containerID)
+	if err != nil {
+		return st, false
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		return st, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mem := memoryInUse(st.MemoryStats); mem > m.usage.PeakMemory {
+		m.usage.PeakMemory = mem
+	}
+	if pids := int64(st.PidsStats.Current); pids > m.usage.PeakPIDs {
+		m.usage.PeakPIDs = pids
+	}
+	if cpu := st.CPUStats.CPUUsage.TotalUsage; cpu > m.lastCPU {
+		m.lastCPU = cpu
+	}
+	return st, true
+}
+
+// memoryInUse returns the memory a container uses without the page cache
+// it could give back, as "docker stats" counts it.
+func memoryInUse(mem container.MemoryStats) int64 {
+	used := mem.Usage
+	for _, key := range []string{"inactive_file", "total_inactive_file"} { // cgroup v2, v1
+		if cache, ok := mem.Stats[key]; ok && cache < used {
+			used -= cache
+			break
+		}
+	}
+	return int64(used)
+}
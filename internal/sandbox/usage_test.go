@@ -0,0 +1,57 @@
+package sandbox
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageString(t *testing.T) {
+	u := Usage{WallMs: 2100, CPUMs: 800, PeakMemory: 125 << 20, MemoryLimit: 128 << 20, PeakPIDs: 3, PIDLimit: 50}
+	u.classify(137)
+	if got, want := u.String(), "2.1s wall, 800ms CPU, peak memory 125.0 MB of 128.0 MB, 3 of 50 processes, stopped: memory limit"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// Far from the memory limit, SIGKILL came from elsewhere
+	u = Usage{WallMs: 50, PeakMemory: 512 << 10, MemoryLimit: 128 << 20}
+	u.classify(137)
+	if got, want := u.String(), "50ms wall, 0ms CPU, peak memory 512 KB of 128.0 MB, stopped: killed"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	u = Usage{Stopped: StoppedTimeout}
+	u.classify(137)
+	if u.Stopped != StoppedTimeout {
+		t.Errorf("Stopped = %q, want the timeout kept", u.Stopped)
+	}
+}
+
+func TestLocalExecutorRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on Windows")
+	}
+
+	e := NewLocalExecutor()
+	result, err := e.Run(context.Background(), []string{"sh", "-c", "sleep 0.1; echo done"}, nil)
+	if err != nil || result.ExitCode != 0 || result.Stdout != "done\n" {
+		t.Fatalf("Run = %+v, %v", result, err)
+	}
+	if result.Usage.WallMs < 100 || result.Usage.Stopped != "" {
+		t.Errorf("usage = %+v", result.Usage)
+	}
+	if runtime.GOOS == "linux" && result.Usage.PeakMemory == 0 {
+		t.Error("peak memory not measured")
+	}
+
+	e.SetTimeout(100 * time.Millisecond)
+	result, err = e.Run(context.Background(), []string{"sleep", "5"}, nil)
+	if err == nil || result.Usage.Stopped != StoppedTimeout {
+		t.Errorf("Run = %+v, %v, want a timeout", result, err)
+	}
+	if !strings.HasSuffix(result.Usage.String(), "stopped: timeout") {
+		t.Errorf("usage = %q", result.Usage.String())
+	}
+}
@@ -0,0 +1,193 @@
+// Package service renders the files that run the ubot gateway as a
+// background service of the current user: a systemd user unit on Linux and
+// a launchd agent on macOS.
+package service
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Name is the systemd unit name, without ".service".
+	Name = "ubot"
+	// Label is the launchd job label.
+	Label = "com.ubot.gateway"
+)
+
+// Restart policies.
+const (
+	RestartAlways    = "always"     // restart whenever the gateway exits
+	RestartOnFailure = "on-failure" // restart unless it exited cleanly (default)
+	RestartNever     = "no"         // never restart
+)
+
+// Spec describes the service.
+type Spec struct {
+	Executable   string            // absolute path of the ubot binary
+	Args         []string          // arguments, e.g. ["gateway"]
+	WorkingDir   string            // working directory; unset when empty
+	Env          map[string]string // extra environment variables
+	LogPath      string            // file receiving output; systemd uses the journal when empty
+	Restart      string            // restart policy; RestartOnFailure when empty
+	RestartDelay time.Duration     // wait before restarting; 5s when zero
+	StopTimeout  time.Duration     // how long a stop may take before the process is killed; unset when zero
+	// RestartExitCode is an exit code that always restarts the service
+	// unless the policy is RestartNever, such as a requested restart.
+	// Ignored when zero.
+	RestartExitCode int
+}
+
+func (s Spec) restart() string {
+	if s.Restart == "" {
+		return RestartOnFailure
+	}
+	return s.Restart
+}
+
+func (s Spec) restartDelay() time.Duration {
+	if s.RestartDelay <= 0 {
+		return 5 * time.Second
+	}
+	return s.RestartDelay
+}
+
+// Validate checks the restart policy.
+func (s Spec) Validate() error {
+	switch s.restart() {
+	case RestartAlways, RestartOnFailure, RestartNever:
+		return nil
+	}
+	return fmt.Errorf("unknown restart policy %q (use %s, %s or %s)", s.Restart, RestartAlways, RestartOnFailure, RestartNever)
+}
+
+// SystemdPath returns where the user unit is installed.
+func SystemdPath(home string) string {
+	return filepath.Join(home, ".config", "systemd", "user", Name+".service")
+}
+
+// LaunchdPath returns where the launchd agent is installed.
+func LaunchdPath(home string) string {
+	return filepath.Join(home, "Library", "LaunchAgents", Label+".plist")
+}
+
+// SystemdUnit renders s as a systemd user unit.
+func SystemdUnit(s Spec) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=uBot gateway\n")
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	b.WriteString("ExecStart=" + systemdCommand(s.Executable, s.Args) + "\n")
+	b.WriteString("ExecReload=/bin/kill -HUP $MAINPID\n")
+	if s.WorkingDir != "" {
+		b.WriteString("WorkingDirectory=" + systemdQuote(s.WorkingDir) + "\n")
+	}
+	for _, key := range sortedKeys(s.Env) {
+		b.WriteString("Environment=" + systemdQuote(key+"="+s.Env[key]) + "\n")
+	}
+	b.WriteString("Restart=" + s.restart() + "\n")
+	fmt.Fprintf(&b, "RestartSec=%d\n", int(s.restartDelay().Seconds()))
+	if s.RestartExitCode != 0 && s.restart() != RestartNever {
+		fmt.Fprintf(&b, "RestartForceExitStatus=%d\n", s.RestartExitCode)
+	}
+	if s.StopTimeout > 0 {
+		fmt.Fprintf(&b, "TimeoutStopSec=%d\n", int(s.StopTimeout.Seconds()))
+	}
+	if s.LogPath != "" {
+		b.WriteString("StandardOutput=append:" + s.LogPath + "\n")
+		b.WriteString("StandardError=append:" + s.LogPath + "\n")
+	}
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=default.target\n")
+	return b.String()
+}
+
+// LaunchdPlist renders s as a launchd agent property list. launchd always
+// waits at least RestartDelay (its ThrottleInterval) between starts.
+func LaunchdPlist(s Spec) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	plistString(&b, "Label", Label)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range append([]string{s.Executable}, s.Args...) {
+		b.WriteString("\t\t<string>" + xmlEscape(arg) + "</string>\n")
+	}
+	b.WriteString("\t</array>\n")
+	if s.WorkingDir != "" {
+		plistString(&b, "WorkingDirectory", s.WorkingDir)
+	}
+	if len(s.Env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, key := range sortedKeys(s.Env) {
+			b.WriteString("\t\t<key>" + xmlEscape(key) + "</key>\n")
+			b.WriteString("\t\t<string>" + xmlEscape(s.Env[key]) + "</string>\n")
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	switch s.restart() {
+	case RestartAlways:
+		b.WriteString("\t<key>KeepAlive</key>\n\t<true/>\n")
+	case RestartOnFailure:
+		// Any non-zero exit, including RestartExitCode, starts it again
+		b.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	}
+	fmt.Fprintf(&b, "\t<key>ThrottleInterval</key>\n\t<integer>%d</integer>\n", int(s.restartDelay().Seconds()))
+	if s.StopTimeout > 0 {
+		fmt.Fprintf(&b, "\t<key>ExitTimeOut</key>\n\t<integer>%d</integer>\n", int(s.StopTimeout.Seconds()))
+	}
+	if s.LogPath != "" {
+		plistString(&b, "StandardOutPath", s.LogPath)
+		plistString(&b, "StandardErrorPath", s.LogPath)
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+func plistString(b *strings.Builder, key, value string) {
+	b.WriteString("\t<key>" + key + "</key>\n\t<string>" + xmlEscape(value) + "</string>\n")
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// systemdCommand renders a command line for ExecStart.
+func systemdCommand(exe string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{exe}, args...) {
+		parts = append(parts, systemdQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// systemdQuote quotes s for a unit file when it contains spaces, quotes,
+// backslashes or specifiers.
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\$") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSpec() Spec {
+	return Spec{
+		Executable:      "/home/me/My Apps/ubot",
+		Args:            []string{"gateway"},
+		WorkingDir:      "/home/me",
+		Env:             map[string]string{"UBOT_SUPERVISED": "1", "NOTE": "100% <fine>"},
+		LogPath:         "/home/me/.ubot/logs/gateway.log",
+		StopTimeout:     55 * time.Second,
+		RestartExitCode: 75,
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	unit := SystemdUnit(testSpec())
+	for _, want := range []string{
+		`ExecStart="/home/me/My Apps/ubot" gateway` + "\n",
+		"WorkingDirectory=/home/me\n",
+		`Environment="NOTE=100%% <fine>"` + "\n",
+		"Environment=UBOT_SUPERVISED=1\n",
+		"Restart=on-failure\n",
+		"RestartSec=5\n",
+		"RestartForceExitStatus=75\n",
+		"TimeoutStopSec=55\n",
+		"StandardOutput=append:/home/me/.ubot/logs/gateway.log\n",
+		"WantedBy=default.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit lacks %q:\n%s", want, unit)
+		}
+	}
+
+	spec := testSpec()
+	spec.Restart = RestartNever
+	spec.LogPath = ""
+	unit = SystemdUnit(spec)
+	if strings.Contains(unit, "RestartForceExitStatus") || strings.Contains(unit, "StandardOutput") {
+		t.Errorf("unit restarts or redirects output:\n%s", unit)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	plist := LaunchdPlist(testSpec())
+	if err := xml.Unmarshal([]byte(plist), new(struct{})); err != nil {
+		t.Fatalf("plist is not valid XML: %v\n%s", err, plist)
+	}
+	for _, want := range []string{
+		"<string>" + Label + "</string>",
+		"<string>/home/me/My Apps/ubot</string>\n\t\t<string>gateway</string>",
+		"<key>NOTE</key>\n\t\t<string>100% &lt;fine&gt;</string>",
+		"<key>SuccessfulExit</key>\n\t\t<false/>",
+		"<key>ThrottleInterval</key>\n\t<integer>5</integer>",
+		"<key>ExitTimeOut</key>\n\t<integer>55</integer>",
+		"<key>StandardErrorPath</key>\n\t<string>/home/me/.ubot/logs/gateway.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist lacks %q:\n%s", want, plist)
+		}
+	}
+
+	spec := testSpec()
+	spec.Restart = RestartAlways
+	if plist := LaunchdPlist(spec); !strings.Contains(plist, "<key>KeepAlive</key>\n\t<true/>") {
+		t.Errorf("plist does not keep the gateway alive:\n%s", plist)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, policy := range []string{"", RestartAlways, RestartOnFailure, RestartNever} {
+		if err := (Spec{Restart: policy}).Validate(); err != nil {
+			t.Errorf("Validate(%q): %v", policy, err)
+		}
+	}
+	if err := (Spec{Restart: "sometimes"}).Validate(); err == nil {
+		t.Error("Validate accepted an unknown policy")
+	}
+}
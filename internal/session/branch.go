@@ -0,0 +1,213 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MainBranch is the name of a conversation's original branch, whose key is
+// the conversation's own key.
+const MainBranch = "main"
+
+// branchSeparator joins a conversation's key and a branch name into the
+// branch's key, e.g. "telegram:123#idea".
+const branchSeparator = "#"
+
+// branchNamePattern restricts branch names to what is easy to type in a
+// chat command.
+var branchNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Lineage links a session to the session it was forked from and the
+// branches forked from it. A conversation's branches form a tree rooted at
+// its main branch, which also records the branch the chat continues in.
+type Lineage struct {
+	Parent   string   `json:"parent,omitempty"`   // key of the session this one was forked from
+	Children []string `json:"children,omitempty"` // keys of the branches forked from this one
+	Active   string   `json:"active,omitempty"`   // main branch only: key of the current branch; "" for main
+}
+
+// IsZero reports whether the session has no parent, branches or current
+// branch.
+func (l Lineage) IsZero() bool {
+	return l.Parent == "" && len(l.Children) == 0 && l.Active == ""
+}
+
+// Branch describes one branch of a conversation.
+type Branch struct {
+	Name         string
+	Key          string
+	Parent       string // name of the branch it was forked from; "" for main
+	MessageCount int
+	UpdatedAt    time.Time
+	Active       bool // the chat continues in this branch
+}
+
+// RootKey returns the key of the main branch of the conversation key
+// belongs to ("telegram:123#idea" -> "telegram:123").
+func RootKey(key string) string {
+	root, _, _ := strings.Cut(key, branchSeparator)
+	return root
+}
+
+// BranchName returns the name of the branch whose key is key.
+func BranchName(key string) string {
+	if _, name, ok := strings.Cut(key, branchSeparator); ok {
+		return name
+	}
+	return MainBranch
+}
+
+// branchKey returns the key of the branch called name of the conversation
+// key belongs to.
+func branchKey(key, name string) string {
+	if name == MainBranch {
+		return RootKey(key)
+	}
+	return RootKey(key) + branchSeparator + name
+}
+
+// Undo removes the last exchange: the newest user message and everything
+// after it, such as tool calls and the reply. It returns the removed
+// messages, or nil when there is no user message to remove.
+func (s *Session) Undo() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role == "user" {
+			removed := append([]Message(nil), s.Messages[i:]...)
+			s.Messages = s.Messages[:i]
+			s.UpdatedAt = time.Now()
+			return removed
+		}
+	}
+	return nil
+}
+
+// GetLineage returns a copy of the session's links to its parent and
+// branches.
+func (s *Session) GetLineage() Lineage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l := s.Lineage
+	l.Children = append([]string(nil), l.Children...)
+	return l
+}
+
+// Active returns the session the conversation key belongs to continues in:
+// the branch last forked or switched to, or the main branch. It creates
+// the main branch if the conversation is new.
+func (m *Manager) Active(key string) *Session {
+	root := m.GetOrCreate(RootKey(key))
+	root.mu.RLock()
+	active := root.Lineage.Active
+	root.mu.RUnlock()
+
+	if active != "" {
+		if branch := m.Get(active); branch != nil {
+			return branch
+		}
+	}
+	return root
+}
+
+// Fork copies sess, with its messages, pins and model, into a new branch
+// called name and makes it the conversation's current branch. An empty name
+// picks the next free "branch-N".
+func (m *Manager) Fork(sess *Session, name string) (*Session, error) {
+	if name == "" {
+		for n := 2; ; n++ {
+			name = fmt.Sprintf("branch-%d", n)
+			if m.Get(branchKey(sess.Key, name)) == nil {
+				break
+			}
+		}
+	}
+	if !branchNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("branch names may only use letters, digits, - and _ (up to 32)")
+	}
+	key := branchKey(sess.Key, name)
+	if name == MainBranch || m.Get(key) != nil {
+		return nil, fmt.Errorf("branch %q already exists", name)
+	}
+
+	branch := NewSession(key)
+	branch.Source = sess.Source
+	sess.mu.Lock()
+	branch.Messages = append(branch.Messages, sess.Messages...)
+	branch.Pins = append([]Pin(nil), sess.Pins...)
+	branch.Model = sess.Model
+	branch.Settings = sess.Settings
+	branch.Lineage.Parent = sess.Key
+	sess.Lineage.Children = append(sess.Lineage.Children, key)
+	sess.UpdatedAt = time.Now()
+	sess.mu.Unlock()
+
+	if err := m.Save(branch); err != nil {
+		return nil, err
+	}
+	if err := m.Save(sess); err != nil {
+		return nil, err
+	}
+	if err := m.setActive(sess.Key, key); err != nil {
+		return nil, err
+	}
+	return branch, nil
+}
+
+// Switch makes the branch called name the current branch of the
+// conversation key belongs to and returns it.
+func (m *Manager) Switch(key, name string) (*Session, error) {
+	target := m.Get(branchKey(key, name))
+	if target == nil || !branchNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("branch %q not found", name)
+	}
+	if err := m.setActive(key, target.Key); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// setActive records branch as the current branch in the main branch of the
+// conversation key belongs to.
+func (m *Manager) setActive(key, branch string) error {
+	root := m.GetOrCreate(RootKey(key))
+	if branch == root.Key {
+		branch = ""
+	}
+	root.mu.Lock()
+	root.Lineage.Active = branch
+	root.mu.Unlock()
+	return m.Save(root)
+}
+
+// Branches lists the branches of the conversation key belongs to, each
+// after the branch it was forked from.
+func (m *Manager) Branches(key string) []Branch {
+	root := m.GetOrCreate(RootKey(key))
+	active := m.Active(key).Key
+
+	var branches []Branch
+	var walk func(s *Session, parent string)
+	walk = func(s *Session, parent string) {
+		info := s.Info()
+		branches = append(branches, Branch{
+			Name:         BranchName(s.Key),
+			Key:          s.Key,
+			Parent:       parent,
+			MessageCount: info.MessageCount,
+			UpdatedAt:    info.UpdatedAt,
+			Active:       s.Key == active,
+		})
+		for _, child := range s.GetLineage().Children {
+			if c := m.Get(child); c != nil {
+				walk(c, BranchName(s.Key))
+			}
+		}
+	}
+	walk(root, "")
+	return branches
+}
@@ -0,0 +1,80 @@
+package session
+
+import "testing"
+
+func TestUndo(t *testing.T) {
+	s := NewSession("telegram:1")
+	if s.Undo() != nil {
+		t.Error("Undo on an empty session removed something")
+	}
+
+	s.AddMessage("user", "hi")
+	s.AddMessage("assistant", "hello")
+	s.AddMessage("user", "list files")
+	s.AddToolCall([]ToolCallInfo{{ID: "c1", Name: "list_dir", Arguments: `{}`}})
+	s.AddToolResult("c1", "list_dir", "a.txt")
+	s.AddMessage("assistant", "a.txt")
+
+	removed := s.Undo()
+	if len(removed) != 4 || removed[0].Content != "list files" {
+		t.Fatalf("Undo removed %+v, want the last exchange", removed)
+	}
+	if msgs := s.GetMessages(); len(msgs) != 2 || msgs[1].Content != "hello" {
+		t.Errorf("messages after Undo = %+v", msgs)
+	}
+}
+
+func TestForkAndSwitch(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+	root := m.Active("telegram:1")
+	root.AddMessage("user", "plan a trip")
+	root.AddMessage("assistant", "where to?")
+	root.AddPin("budget: 1000 EUR")
+
+	branch, err := m.Fork(root, "italy")
+	if err != nil {
+		t.Fatalf("Fork: %v", err)
+	}
+	if branch.Key != "telegram:1#italy" || branch.MessageCount() != 2 || len(branch.GetPins()) != 1 {
+		t.Fatalf("branch = %+v", branch.Info())
+	}
+	branch.AddMessage("user", "Italy")
+	if root.MessageCount() != 2 {
+		t.Error("the branch shares messages with its parent")
+	}
+	if _, err := m.Fork(root, "italy"); err == nil {
+		t.Error("Fork reused an existing branch name")
+	}
+	if _, err := m.Fork(root, "no spaces"); err == nil {
+		t.Error("Fork accepted an invalid name")
+	}
+	unnamed, err := m.Fork(branch, "")
+	if err != nil || unnamed.Key != "telegram:1#branch-2" {
+		t.Fatalf("Fork without a name = %v, %v", unnamed, err)
+	}
+	if err := m.Save(branch); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A fresh manager continues in the last forked branch
+	m = NewManager(dir)
+	if got := m.Active("telegram:1").Key; got != "telegram:1#branch-2" {
+		t.Errorf("Active = %s, want the last fork", got)
+	}
+	branches := m.Branches("telegram:1")
+	if len(branches) != 3 || branches[1].Name != "italy" || branches[1].Parent != MainBranch ||
+		branches[2].Parent != "italy" || !branches[2].Active || branches[1].MessageCount != 3 {
+		t.Errorf("Branches = %+v", branches)
+	}
+
+	if _, err := m.Switch("telegram:1#branch-2", MainBranch); err != nil {
+		t.Fatalf("Switch: %v", err)
+	}
+	if got := m.Active("telegram:1").Key; got != "telegram:1" {
+		t.Errorf("Active after switching to main = %s", got)
+	}
+	if _, err := m.Switch("telegram:1", "spain"); err == nil {
+		t.Error("Switch to a missing branch succeeded")
+	}
+}
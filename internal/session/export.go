@@ -0,0 +1,181 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// exportVersion is the version of the JSON export format.
+const exportVersion = 1
+
+// ErrSessionExists is returned by Import when the target session exists and
+// may not be replaced.
+var ErrSessionExists = errors.New("session already exists")
+
+// Export is a complete conversation in a portable JSON form, including tool
+// calls and pins, for backups and moving sessions between machines.
+type Export struct {
+	Version   int       `json:"version"`
+	Key       string    `json:"key"`
+	Source    string    `json:"source,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Pins      []Pin     `json:"pins,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	Settings  *Settings `json:"settings,omitempty"`
+	Messages  []Message `json:"messages"`
+}
+
+// Export returns a copy of the stored session for key.
+func (m *Manager) Export(key string) (*Export, error) {
+	session := m.Get(key)
+	if session == nil {
+		return nil, fmt.Errorf("session %q not found", key)
+	}
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	exp := &Export{
+		Version:   exportVersion,
+		Key:       session.Key,
+		Source:    session.Source,
+		CreatedAt: session.CreatedAt,
+		UpdatedAt: session.UpdatedAt,
+		Pins:      append([]Pin(nil), session.Pins...),
+		Model:     session.Model,
+		Messages:  append([]Message(nil), session.Messages...),
+	}
+	if !session.Settings.IsZero() {
+		settings := session.Settings
+		exp.Settings = &settings
+	}
+	return exp, nil
+}
+
+// Import stores an exported conversation under key, or under its original
+// key when key is empty. An existing session with that key is only
+// replaced when overwrite is set.
+func (m *Manager) Import(exp *Export, key string, overwrite bool) (*Session, error) {
+	if exp.Version > exportVersion {
+		return nil, fmt.Errorf("unsupported export version %d", exp.Version)
+	}
+	if key == "" {
+		key = exp.Key
+	}
+	if key == "" {
+		return nil, fmt.Errorf("export has no session key; pass one")
+	}
+	if !overwrite && m.Get(key) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionExists, key)
+	}
+
+	session := NewSession(key)
+	session.Source = exp.Source
+	session.Pins = exp.Pins
+	session.Model = exp.Model
+	if exp.Settings != nil {
+		session.Settings = *exp.Settings
+	}
+	if exp.Messages != nil {
+		session.Messages = exp.Messages
+	}
+	if !exp.CreatedAt.IsZero() {
+		session.CreatedAt = exp.CreatedAt
+	}
+	if !exp.UpdatedAt.IsZero() {
+		session.UpdatedAt = exp.UpdatedAt
+	}
+
+	if err := m.Save(session); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.cache[key] = session
+	m.mu.Unlock()
+	return session, nil
+}
+
+// ReadExport decodes a JSON export.
+func ReadExport(r io.Reader) (*Export, error) {
+	var exp Export
+	if err := json.NewDecoder(r).Decode(&exp); err != nil {
+		return nil, fmt.Errorf("invalid session export: %w", err)
+	}
+	return &exp, nil
+}
+
+// WriteJSON writes the export as indented JSON.
+func (e *Export) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// Markdown renders the conversation as a readable transcript. Tool calls
+// are shown with their arguments and results.
+func (e *Export) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Conversation %s\n\n", e.Key)
+	fmt.Fprintf(&sb, "Started %s, last updated %s.\n",
+		e.CreatedAt.Format(time.DateTime), e.UpdatedAt.Format(time.DateTime))
+
+	if len(e.Pins) > 0 {
+		sb.WriteString("\n## Pinned\n\n")
+		for _, p := range e.Pins {
+			fmt.Fprintf(&sb, "- %s\n", p.Content)
+		}
+	}
+
+	for _, msg := range e.Messages {
+		sb.WriteString("\n")
+		switch msg.Role {
+		case "tool":
+			fmt.Fprintf(&sb, "#### Result: %s\n\n", msg.Name)
+			sb.WriteString(fence(msg.Content))
+		default:
+			fmt.Fprintf(&sb, "### %s", roleTitle(msg.Role))
+			if !msg.Timestamp.IsZero() {
+				fmt.Fprintf(&sb, " · %s", msg.Timestamp.Format(time.DateTime))
+			}
+			sb.WriteString("\n\n")
+			if msg.Content != "" {
+				sb.WriteString(strings.TrimSpace(msg.Content) + "\n")
+			}
+			for _, tc := range msg.ToolCalls {
+				fmt.Fprintf(&sb, "\n#### Tool call: %s\n\n", tc.Name)
+				sb.WriteString(fence(tc.Arguments))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// roleTitle returns the heading for a message role.
+func roleTitle(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	default:
+		return role
+	}
+}
+
+// fence wraps text in a code block, using a fence longer than any run of
+// backticks inside it.
+func fence(text string) string {
+	marker := "```"
+	for strings.Contains(text, marker) {
+		marker += "`"
+	}
+	return marker + "\n" + strings.TrimRight(text, "\n") + "\n" + marker + "\n"
+}
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := NewManager(t.TempDir())
+	s := src.GetOrCreate("telegram:42")
+	s.AddMessage("user", "what's in notes.txt?")
+	s.AddToolCall([]ToolCallInfo{{ID: "call_1", Name: "read_file", Arguments: `{"path":"notes.txt"}`}})
+	s.AddToolResult("call_1", "read_file", "buy milk\n```\ncode\n```")
+	s.AddMessage("assistant", "It says to buy milk.")
+	s.AddPin("User is in Berlin")
+	if err := src.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	exp, err := src.Export("telegram:42")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := exp.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+
+	md := exp.Markdown()
+	for _, want := range []string{"### User", "#### Tool call: read_file", `{"path":"notes.txt"}`, "#### Result: read_file", "````\nbuy milk", "User is in Berlin"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("transcript missing %q:\n%s", want, md)
+		}
+	}
+
+	// Import on another machine, under the original key
+	dstDir := t.TempDir()
+	dst := NewManager(dstDir)
+	read, err := ReadExport(&buf)
+	if err != nil {
+		t.Fatalf("ReadExport: %v", err)
+	}
+	imported, err := dst.Import(read, "", false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	msgs := imported.GetMessages()
+	if imported.Key != "telegram:42" || len(msgs) != 4 || msgs[1].ToolCalls[0].Name != "read_file" || msgs[2].ToolCallID != "call_1" {
+		t.Fatalf("imported session = %+v", imported)
+	}
+	if len(imported.GetPins()) != 1 {
+		t.Errorf("pins = %v, want 1", imported.GetPins())
+	}
+
+	// Existing sessions are only replaced on request
+	if _, err := dst.Import(read, "", false); !errors.Is(err, ErrSessionExists) {
+		t.Errorf("second import err = %v, want ErrSessionExists", err)
+	}
+	if _, err := dst.Import(read, "", true); err != nil {
+		t.Errorf("forced import: %v", err)
+	}
+	if got := NewManager(dstDir).Get("telegram:42"); got == nil || got.MessageCount() != 4 {
+		t.Errorf("imported session was not persisted")
+	}
+}
@@ -2,8 +2,10 @@ package session
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,6 +23,69 @@ type sessionMetadata struct {
 	Key       string    `json:"key"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	Pins      []Pin     `json:"pins,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	Settings  *Settings `json:"settings,omitempty"`
+	Lineage   *Lineage  `json:"lineage,omitempty"`
+}
+
+// Store is an optional shared backend for session data. When set on a
+// Manager it becomes the source of truth, so several gateway instances can
+// process messages for the same conversations.
+type Store interface {
+	// Load returns the encoded session for key, or nil if it does not exist.
+	Load(key string) ([]byte, error)
+	Save(key string, data []byte) error
+	Delete(key string) error
+}
+
+// Index is implemented by stores that can list and search conversations
+// without loading them, such as the SQLite store.
+type Index interface {
+	ListSessions(filter Filter) ([]SessionInfo, error)
+	Search(query string, filter Filter, limit int) ([]SearchHit, error)
+}
+
+// Filter narrows listings and searches to a conversation or channel and a
+// time range. Zero fields match everything.
+type Filter struct {
+	Key     string    // a single conversation, e.g. "telegram:123"
+	Channel string    // e.g. "telegram"
+	Since   time.Time // updated (or, for search hits, sent) at or after
+	Until   time.Time // updated (or sent) before
+}
+
+// Match reports whether a session with the given key and update time passes
+// the filter.
+func (f Filter) Match(key string, updated time.Time) bool {
+	if f.Key != "" && key != f.Key {
+		return false
+	}
+	if f.Channel != "" && ChannelOf(key) != f.Channel {
+		return false
+	}
+	if !f.Since.IsZero() && updated.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !updated.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// SearchHit is a message matching a full-text search.
+type SearchHit struct {
+	Key       string
+	Role      string
+	Timestamp time.Time
+	Snippet   string // the matching part of the message, terms in [brackets]
+}
+
+// ChannelOf returns the channel part of a session key ("telegram:123" ->
+// "telegram").
+func ChannelOf(key string) string {
+	channel, _, _ := strings.Cut(key, ":")
+	return channel
 }
 
 // Manager handles session storage and retrieval
@@ -29,6 +94,8 @@ type Manager struct {
 	cache       map[string]*Session
 	mu          sync.RWMutex
 	maxHistory  int
+	store       Store
+	noFiles     bool // the store is the only copy; no session files are written
 }
 
 // NewManager creates a new session manager with the given data directory
@@ -55,11 +122,36 @@ func (m *Manager) SetMaxHistory(max int) {
 	m.maxHistory = max
 }
 
+// SetStore configures a shared session store. Sessions are then always read
+// from and written to the store in addition to the local files, and the
+// in-memory cache is refreshed from the store on every lookup.
+func (m *Manager) SetStore(store Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store = store
+}
+
+// DisableFiles stops reading and writing session files, leaving the store
+// set with SetStore as the only copy of each session.
+func (m *Manager) DisableFiles() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.noFiles = true
+}
+
 // GetOrCreate returns an existing session or creates a new one
 func (m *Manager) GetOrCreate(key string) *Session {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// A shared store may have been updated by another instance
+	if m.store != nil {
+		if session := m.loadFromStore(key); session != nil {
+			m.cache[key] = session
+			return session
+		}
+	}
+
 	// Check cache first
 	if session, ok := m.cache[key]; ok {
 		return session
@@ -96,7 +188,13 @@ func (m *Manager) Get(key string) *Session {
 		return session
 	}
 
-	session := m.loadFromFile(key)
+	var session *Session
+	if m.store != nil {
+		session = m.loadFromStore(key)
+	}
+	if session == nil {
+		session = m.loadFromFile(key)
+	}
 	if session != nil {
 		m.cache[key] = session
 	}
@@ -112,28 +210,51 @@ func (m *Manager) Save(session *Session) error {
 	session.mu.RLock()
 	defer session.mu.RUnlock()
 
-	filePath := m.getFilePath(session.Key)
-
-	// Create file
-	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	data, err := m.encode(session)
 	if err != nil {
-		return fmt.Errorf("failed to create session file: %w", err)
+		return err
 	}
-	defer file.Close()
+
+	if m.store != nil {
+		if err := m.store.Save(session.Key, data); err != nil {
+			return fmt.Errorf("failed to save session to store: %w", err)
+		}
+	}
+
+	if m.noFiles {
+		return nil
+	}
+	if err := os.WriteFile(m.getFilePath(session.Key), data, 0600); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	return nil
+}
+
+// encode serializes a session as JSONL: a metadata line followed by the last
+// maxHistory messages. Caller must hold session.mu.
+func (m *Manager) encode(session *Session) ([]byte, error) {
+	var buf bytes.Buffer
 
 	// Write metadata as first line
 	meta := sessionMetadata{
 		Key:       session.Key,
 		CreatedAt: session.CreatedAt,
 		UpdatedAt: session.UpdatedAt,
+		Pins:      session.Pins,
+		Model:     session.Model,
+	}
+	if !session.Settings.IsZero() {
+		meta.Settings = &session.Settings
+	}
+	if !session.Lineage.IsZero() {
+		meta.Lineage = &session.Lineage
 	}
 	metaJSON, err := json.Marshal(meta)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
-	}
-	if _, err := file.Write(append(metaJSON, '\n')); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
 	}
+	buf.Write(append(metaJSON, '\n'))
 
 	// Write messages
 	// Only keep last maxHistory messages
@@ -145,14 +266,12 @@ func (m *Manager) Save(session *Session) error {
 	for _, msg := range messages {
 		msgJSON, err := json.Marshal(msg)
 		if err != nil {
-			return fmt.Errorf("failed to marshal message: %w", err)
-		}
-		if _, err := file.Write(append(msgJSON, '\n')); err != nil {
-			return fmt.Errorf("failed to write message: %w", err)
+			return nil, fmt.Errorf("failed to marshal message: %w", err)
 		}
+		buf.Write(append(msgJSON, '\n'))
 	}
 
-	return nil
+	return buf.Bytes(), nil
 }
 
 // Delete removes a session from cache and disk
@@ -163,6 +282,10 @@ func (m *Manager) Delete(key string) bool {
 	// Remove from cache
 	delete(m.cache, key)
 
+	if m.store != nil {
+		_ = m.store.Delete(key)
+	}
+
 	// Remove file
 	filePath := m.getFilePath(key)
 	if err := os.Remove(filePath); err != nil {
@@ -177,10 +300,50 @@ func (m *Manager) Delete(key string) bool {
 
 // List returns information about all sessions
 func (m *Manager) List() []SessionInfo {
+	sessions, _ := m.ListFiltered(Filter{})
+	return sessions
+}
+
+// ListFiltered returns information about the sessions that pass filter,
+// from the store's index when it has one, otherwise from the session files.
+func (m *Manager) ListFiltered(filter Filter) ([]SessionInfo, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	if index, ok := m.store.(Index); ok {
+		return index.ListSessions(filter)
+	}
+
+	var filtered []SessionInfo
+	for _, info := range m.listFiles() {
+		if filter.Match(info.Key, info.UpdatedAt) {
+			filtered = append(filtered, info)
+		}
+	}
+	return filtered, nil
+}
+
+// Search finds messages containing every word of query across all
+// conversations. A store with a full-text index searches its whole archive;
+// otherwise the session files are scanned, which covers only the history
+// each conversation still keeps.
+func (m *Manager) Search(query string, filter Filter, limit int) ([]SearchHit, error) {
+	m.mu.RLock()
+	index, ok := m.store.(Index)
+	m.mu.RUnlock()
+	if !ok {
+		return m.searchFiles(query, filter, limit)
+	}
+	return index.Search(query, filter, limit)
+}
+
+// listFiles returns information about the sessions stored in files. Caller
+// must hold m.mu.
+func (m *Manager) listFiles() []SessionInfo {
 	var sessions []SessionInfo
+	if m.noFiles {
+		return sessions
+	}
 
 	// Read session files from directory
 	entries, err := os.ReadDir(m.sessionsDir)
@@ -218,6 +381,13 @@ func (m *Manager) Clear(key string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// A session kept only in the store is cleared there
+	if _, ok := m.cache[key]; !ok && m.store != nil {
+		if session := m.loadFromStore(key); session != nil {
+			m.cache[key] = session
+		}
+	}
+
 	// Clear in cache if present
 	if session, ok := m.cache[key]; ok {
 		session.Clear()
@@ -268,6 +438,50 @@ func (m *Manager) ClearAll() error {
 	return lastErr
 }
 
+// MigrateFiles copies sessions from files into the store, skipping keys the
+// store already has, and returns how many were copied. The files are left
+// in place.
+func (m *Manager) MigrateFiles() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.store == nil {
+		return 0, fmt.Errorf("no session store configured")
+	}
+	entries, err := os.ReadDir(m.sessionsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	copied := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sessionFileExt) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.sessionsDir, entry.Name()))
+		if err != nil {
+			return copied, err
+		}
+		session := decodeSession(bytes.NewReader(data))
+		if session == nil {
+			continue
+		}
+		if existing, err := m.store.Load(session.Key); err != nil {
+			return copied, err
+		} else if existing != nil {
+			continue
+		}
+		if err := m.store.Save(session.Key, data); err != nil {
+			return copied, fmt.Errorf("failed to migrate session %s: %w", session.Key, err)
+		}
+		copied++
+	}
+	return copied, nil
+}
+
 // getFilePath returns the file path for a session key
 func (m *Manager) getFilePath(key string) string {
 	safeKey := m.safeKey(key)
@@ -298,15 +512,31 @@ func (m *Manager) keyFromFilename(filename string) string {
 
 // loadFromFile loads a session from disk
 func (m *Manager) loadFromFile(key string) *Session {
-	filePath := m.getFilePath(key)
-
-	file, err := os.Open(filePath)
+	if m.noFiles {
+		return nil
+	}
+	file, err := os.Open(m.getFilePath(key))
 	if err != nil {
 		return nil
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	return decodeSession(file)
+}
+
+// loadFromStore loads a session from the shared store
+func (m *Manager) loadFromStore(key string) *Session {
+	data, err := m.store.Load(key)
+	if err != nil || data == nil {
+		return nil
+	}
+	return decodeSession(bytes.NewReader(data))
+}
+
+// decodeSession parses a session in the JSONL format written by encode
+func decodeSession(r io.Reader) *Session {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
 
 	// Read metadata from first line
 	if !scanner.Scan() {
@@ -324,6 +554,14 @@ func (m *Manager) loadFromFile(key string) *Session {
 		CreatedAt: meta.CreatedAt,
 		UpdatedAt: meta.UpdatedAt,
 		Metadata:  make(map[string]interface{}),
+		Pins:      meta.Pins,
+		Model:     meta.Model,
+	}
+	if meta.Settings != nil {
+		session.Settings = *meta.Settings
+	}
+	if meta.Lineage != nil {
+		session.Lineage = *meta.Lineage
 	}
 
 	// Read messages
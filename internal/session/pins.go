@@ -0,0 +1,87 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Pin is a piece of context the user marked as always relevant. Pins are
+// stored separately from the message history, so they survive history
+// trimming and are re-injected into the system prompt on every turn.
+type Pin struct {
+	ID        int       `json:"id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AddPin pins content to the session and returns the new pin.
+func (s *Session) AddPin(content string) Pin {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := 1
+	for _, p := range s.Pins {
+		if p.ID >= id {
+			id = p.ID + 1
+		}
+	}
+	pin := Pin{ID: id, Content: content, CreatedAt: time.Now()}
+	s.Pins = append(s.Pins, pin)
+	s.UpdatedAt = time.Now()
+	return pin
+}
+
+// RemovePin removes the pin with the given ID. Returns false if not found.
+func (s *Session) RemovePin(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.Pins {
+		if p.ID == id {
+			s.Pins = append(s.Pins[:i], s.Pins[i+1:]...)
+			s.UpdatedAt = time.Now()
+			return true
+		}
+	}
+	return false
+}
+
+// GetPins returns a copy of the session's pins.
+func (s *Session) GetPins() []Pin {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Pin, len(s.Pins))
+	copy(result, s.Pins)
+	return result
+}
+
+// LastMessage returns the most recent message with the given role.
+func (s *Session) LastMessage(role string) (Message, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role == role {
+			return s.Messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// PinnedContext renders pins as a system prompt section, or "" if there are
+// none.
+func PinnedContext(pins []Pin) string {
+	if len(pins) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Pinned Context\n")
+	sb.WriteString("The user pinned the following facts and requirements. Always take them into account:\n")
+	for _, p := range pins {
+		sb.WriteString(fmt.Sprintf("- [%d] %s\n", p.ID, p.Content))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
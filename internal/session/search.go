@@ -0,0 +1,114 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// snippetWords is how many words of a message a search snippet shows.
+const snippetWords = 16
+
+// searchFiles scans the session files for messages containing every word
+// of query, ignoring case, newest first. It serves stores without a
+// full-text index.
+func (m *Manager) searchFiles(query string, filter Filter, limit int) ([]SearchHit, error) {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty search query")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	m.mu.RLock()
+	infos := m.listFiles()
+	var sessions []*Session
+	for _, info := range infos {
+		// Time filters apply to each message below
+		if !(Filter{Key: filter.Key, Channel: filter.Channel}).Match(info.Key, info.UpdatedAt) {
+			continue
+		}
+		if s, ok := m.cache[info.Key]; ok {
+			sessions = append(sessions, s)
+		} else if s := m.loadFromFile(info.Key); s != nil {
+			sessions = append(sessions, s)
+		}
+	}
+	m.mu.RUnlock()
+
+	var hits []SearchHit
+	for _, s := range sessions {
+		for _, msg := range s.GetMessages() {
+			if !filter.Match(s.Key, msg.Timestamp) || !containsAll(strings.ToLower(msg.Content), words) {
+				continue
+			}
+			hits = append(hits, SearchHit{
+				Key:       s.Key,
+				Role:      msg.Role,
+				Timestamp: msg.Timestamp,
+				Snippet:   snippet(msg.Content, words),
+			})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Timestamp.After(hits[j].Timestamp)
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// containsAll reports whether text contains every word.
+func containsAll(text string, words []string) bool {
+	for _, w := range words {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// snippet returns the part of content around the first matching word, with
+// matching words in [brackets] like the SQLite store's snippets.
+func snippet(content string, words []string) string {
+	fields := strings.Fields(content)
+	first := -1
+	marked := make([]string, len(fields))
+	for i, f := range fields {
+		marked[i] = f
+		lower := strings.ToLower(f)
+		for _, w := range words {
+			if strings.Contains(lower, w) {
+				marked[i] = "[" + f + "]"
+				if first < 0 {
+					first = i
+				}
+				break
+			}
+		}
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	start := first - snippetWords/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWords
+	if end > len(marked) {
+		end = len(marked)
+	}
+
+	text := strings.Join(marked[start:end], " ")
+	if start > 0 {
+		text = "…" + text
+	}
+	if end < len(marked) {
+		text += "…"
+	}
+	return text
+}
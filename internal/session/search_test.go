@@ -0,0 +1,57 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchFiles(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+	for key, content := range map[string]string{
+		"telegram:1":  "We decided to run the Backups every night at 3am",
+		"telegram:2":  "backups are someone else's problem",
+		"cli:default": "nothing relevant here",
+	} {
+		s := m.GetOrCreate(key)
+		s.AddMessage("user", content)
+		if err := m.Save(s); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	// A fresh manager reads the sessions from their files
+	fresh := NewManager(dir)
+	hits, err := fresh.Search("backups NIGHT", Filter{}, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Key != "telegram:1" || hits[0].Role != "user" {
+		t.Fatalf("hits = %+v", hits)
+	}
+	if !strings.Contains(hits[0].Snippet, "[Backups]") || !strings.Contains(hits[0].Snippet, "[night]") {
+		t.Errorf("snippet = %q, want matching words in brackets", hits[0].Snippet)
+	}
+
+	if hits, _ := fresh.Search("backups", Filter{Key: "telegram:2"}, 10); len(hits) != 1 || hits[0].Key != "telegram:2" {
+		t.Errorf("hits for telegram:2 = %+v", hits)
+	}
+	if hits, _ := fresh.Search("backups", Filter{Since: time.Now().Add(time.Hour)}, 10); len(hits) != 0 {
+		t.Errorf("hits from the future = %+v", hits)
+	}
+	if _, err := fresh.Search("  ", Filter{}, 10); err == nil {
+		t.Error("empty query succeeded")
+	}
+}
+
+func TestSnippetTrimsLongMessages(t *testing.T) {
+	content := strings.Repeat("filler ", 30) + "needle " + strings.Repeat("filler ", 30)
+	got := snippet(content, []string{"needle"})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "[needle]") {
+		t.Errorf("snippet = %q", got)
+	}
+	if n := len(strings.Fields(got)); n > snippetWords {
+		t.Errorf("snippet has %d words, want at most %d", n, snippetWords)
+	}
+}
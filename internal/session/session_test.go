@@ -247,3 +247,72 @@ func TestTrimPreservingSystemMessages(t *testing.T) {
 		t.Errorf("TrimPreservingSystemMessages(empty) len = %d, want 0", len(result))
 	}
 }
+
+func TestPinsPersist(t *testing.T) {
+	dir := t.TempDir()
+	mgr := NewManager(dir)
+	s := mgr.GetOrCreate("telegram:42")
+	s.AddMessage("user", "hi")
+
+	first := s.AddPin("Use PostgreSQL 15")
+	second := s.AddPin("Reply in German")
+	if first.ID != 1 || second.ID != 2 {
+		t.Fatalf("pin IDs = %d, %d, want 1, 2", first.ID, second.ID)
+	}
+	if !s.RemovePin(first.ID) {
+		t.Fatal("RemovePin(1) = false, want true")
+	}
+	if s.RemovePin(first.ID) {
+		t.Error("RemovePin on a removed pin should return false")
+	}
+	if err := mgr.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded := NewManager(dir).GetOrCreate("telegram:42")
+	pins := reloaded.GetPins()
+	if len(pins) != 1 || pins[0].Content != "Reply in German" {
+		t.Fatalf("reloaded pins = %+v, want one pin 'Reply in German'", pins)
+	}
+	if got := reloaded.AddPin("next").ID; got != 3 {
+		t.Errorf("next pin ID = %d, want 3", got)
+	}
+	if ctx := PinnedContext(pins); ctx == "" {
+		t.Error("PinnedContext should render non-empty pins")
+	}
+}
+
+func TestModelPersists(t *testing.T) {
+	dir := t.TempDir()
+	mgr := NewManager(dir)
+	s := mgr.GetOrCreate("telegram:42")
+	s.SetModel("gpt-4o-mini")
+	s.AddMessage("user", "hi")
+	s.Clear()
+	if err := mgr.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if got := NewManager(dir).GetOrCreate("telegram:42").GetModel(); got != "gpt-4o-mini" {
+		t.Errorf("reloaded model = %q, want gpt-4o-mini (kept across /reset)", got)
+	}
+}
+
+func TestSettingsPersist(t *testing.T) {
+	dir := t.TempDir()
+	mgr := NewManager(dir)
+	s := mgr.GetOrCreate("telegram:42")
+	if s.GetSettings().TemperatureOr(0.7) != 0.7 {
+		t.Fatal("new session does not use the default temperature")
+	}
+	zero := 0.0
+	s.SetSettings(Settings{Temperature: &zero, Persona: "friendly"})
+	if err := mgr.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got := NewManager(dir).GetOrCreate("telegram:42").GetSettings()
+	if got.Temperature == nil || *got.Temperature != 0 || got.Persona != "friendly" {
+		t.Errorf("reloaded settings = %+v, want temperature 0 and persona friendly", got)
+	}
+}
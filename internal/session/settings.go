@@ -0,0 +1,41 @@
+package session
+
+import "time"
+
+// Settings override agents.defaults for one conversation, as set with
+// /settings. The model is kept apart, in Session.Model.
+type Settings struct {
+	Temperature *float64 `json:"temperature,omitempty"` // nil = agents.defaults.temperature
+	Persona     string   `json:"persona,omitempty"`     // prompt template; "" = the one the prompts config picks
+}
+
+// IsZero reports whether no setting is overridden.
+func (s Settings) IsZero() bool {
+	return s.Temperature == nil && s.Persona == ""
+}
+
+// TemperatureOr returns the conversation's temperature, or def when it
+// uses the default.
+func (s Settings) TemperatureOr(def float64) float64 {
+	if s.Temperature != nil {
+		return *s.Temperature
+	}
+	return def
+}
+
+// SetSettings replaces the settings of this conversation.
+func (s *Session) SetSettings(settings Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Settings = settings
+	s.UpdatedAt = time.Now()
+}
+
+// GetSettings returns the settings of this conversation.
+func (s *Session) GetSettings() Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.Settings
+}
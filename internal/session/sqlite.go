@@ -0,0 +1,449 @@
+//go:build !lite && !nosqlite
+
+package session
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	key           TEXT PRIMARY KEY,
+	channel       TEXT NOT NULL,
+	created_at    INTEGER NOT NULL,
+	updated_at    INTEGER NOT NULL,
+	pins          TEXT NOT NULL DEFAULT '[]',
+	model         TEXT NOT NULL DEFAULT '',
+	settings      TEXT NOT NULL DEFAULT '{}',
+	lineage       TEXT NOT NULL DEFAULT '{}',
+	history_start INTEGER NOT NULL DEFAULT 0,
+	history_size  INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS sessions_channel ON sessions (channel, updated_at);
+CREATE TABLE IF NOT EXISTS messages (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_key  TEXT NOT NULL,
+	role         TEXT NOT NULL,
+	content      TEXT NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	tool_calls   TEXT,
+	tool_call_id TEXT,
+	name         TEXT
+);
+CREATE INDEX IF NOT EXISTS messages_session ON messages (session_key, id);
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(content);
+`
+
+// sqliteAddedColumns are sessions columns added after the table was first
+// created; databases made before them get them when opened.
+var sqliteAddedColumns = []struct{ name, def string }{
+	{"model", "TEXT NOT NULL DEFAULT ''"},
+	{"lineage", "TEXT NOT NULL DEFAULT '{}'"},
+	{"settings", "TEXT NOT NULL DEFAULT '{}'"},
+}
+
+// SQLiteStore keeps conversations in a single SQLite database. Each save is
+// one transaction, so a crash never leaves a half-written session. Messages
+// are archived rather than replaced: the session history seen by the agent
+// is the latest window, while older and cleared messages stay searchable.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the database at path.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+	// A single connection serializes writers within the process
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create session database: %w", err)
+	}
+	if err := addMissingColumns(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to upgrade session database: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// addMissingColumns adds the sqliteAddedColumns a database lacks.
+func addMissingColumns(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('sessions')`)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		have[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, col := range sqliteAddedColumns {
+		if have[col.name] {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE sessions ADD COLUMN ` + col.name + ` ` + col.def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Load returns the session for key encoded like a session file, or nil if
+// it does not exist.
+func (s *SQLiteStore) Load(key string) ([]byte, error) {
+	var created, updated, start int64
+	var pins, model, settings, lineage string
+	var size int
+	err := s.db.QueryRow(`SELECT created_at, updated_at, pins, model, settings, lineage, history_start, history_size FROM sessions WHERE key = ?`, key).
+		Scan(&created, &updated, &pins, &model, &settings, &lineage, &start, &size)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	meta := sessionMetadata{
+		Key:       key,
+		CreatedAt: time.Unix(0, created),
+		UpdatedAt: time.Unix(0, updated),
+		Model:     model,
+	}
+	if err := json.Unmarshal([]byte(pins), &meta.Pins); err != nil {
+		return nil, fmt.Errorf("failed to decode pins: %w", err)
+	}
+	var chosen Settings
+	if err := json.Unmarshal([]byte(settings), &chosen); err != nil {
+		return nil, fmt.Errorf("failed to decode settings: %w", err)
+	}
+	if !chosen.IsZero() {
+		meta.Settings = &chosen
+	}
+	var links Lineage
+	if err := json.Unmarshal([]byte(lineage), &links); err != nil {
+		return nil, fmt.Errorf("failed to decode lineage: %w", err)
+	}
+	if !links.IsZero() {
+		meta.Lineage = &links
+	}
+
+	rows, err := s.db.Query(`SELECT role, content, timestamp, tool_calls, tool_call_id, name FROM (
+		SELECT * FROM messages WHERE session_key = ? AND id >= ? ORDER BY id DESC LIMIT ?
+	) ORDER BY id`, key, start, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	if err := writeJSONLine(&buf, meta); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeJSONLine(&buf, msg); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Save stores an encoded session. Messages not yet in the archive are
+// appended; when the history no longer continues the archived one (it was
+// cleared or replaced) a new history starts and the old messages are kept
+// for search only.
+func (s *SQLiteStore) Save(key string, data []byte) error {
+	session := decodeSession(bytes.NewReader(data))
+	if session == nil {
+		return fmt.Errorf("invalid session data")
+	}
+	pins, err := json.Marshal(session.Pins)
+	if err != nil {
+		return err
+	}
+	settings, err := json.Marshal(session.Settings)
+	if err != nil {
+		return err
+	}
+	lineage, err := json.Marshal(session.Lineage)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var start int64
+	err = tx.QueryRow(`SELECT history_start FROM sessions WHERE key = ?`, key).Scan(&start)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read session: %w", err)
+	}
+
+	fresh, restart, err := newMessages(tx, key, start, session.Messages)
+	if err != nil {
+		return err
+	}
+	if restart {
+		if err := tx.QueryRow(`SELECT COALESCE(MAX(id), 0) + 1 FROM messages`).Scan(&start); err != nil {
+			return fmt.Errorf("failed to start history: %w", err)
+		}
+	}
+
+	for _, msg := range fresh {
+		if err := insertMessage(tx, key, msg); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO sessions (key, channel, created_at, updated_at, pins, model, settings, lineage, history_start, history_size)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET created_at = excluded.created_at, updated_at = excluded.updated_at,
+			pins = excluded.pins, model = excluded.model, settings = excluded.settings, lineage = excluded.lineage,
+			history_start = excluded.history_start, history_size = excluded.history_size`,
+		key, ChannelOf(key), session.CreatedAt.UnixNano(), session.UpdatedAt.UnixNano(), string(pins), session.Model, string(settings), string(lineage), start, len(session.Messages))
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return tx.Commit()
+}
+
+// newMessages returns the messages of history that are not archived yet.
+// restart reports that history does not continue the archived history.
+func newMessages(tx *sql.Tx, key string, start int64, history []Message) (fresh []Message, restart bool, err error) {
+	var role, content string
+	var ts int64
+	err = tx.QueryRow(`SELECT role, content, timestamp FROM messages WHERE session_key = ? AND id >= ? ORDER BY id DESC LIMIT 1`, key, start).
+		Scan(&role, &content, &ts)
+	if err == sql.ErrNoRows {
+		return history, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		m := history[i]
+		if m.Role == role && m.Content == content && m.Timestamp.UnixNano() == ts {
+			return history[i+1:], false, nil
+		}
+	}
+	return history, true, nil
+}
+
+// insertMessage archives a message and indexes it for search.
+func insertMessage(tx *sql.Tx, key string, msg Message) error {
+	var toolCalls []byte
+	if len(msg.ToolCalls) > 0 {
+		var err error
+		if toolCalls, err = json.Marshal(msg.ToolCalls); err != nil {
+			return err
+		}
+	}
+	res, err := tx.Exec(`INSERT INTO messages (session_key, role, content, timestamp, tool_calls, tool_call_id, name)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		key, msg.Role, msg.Content, msg.Timestamp.UnixNano(), nullString(string(toolCalls)), nullString(msg.ToolCallID), nullString(msg.Name))
+	if err != nil {
+		return fmt.Errorf("failed to save message: %w", err)
+	}
+	if msg.Content == "" {
+		return nil
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO messages_fts (rowid, content) VALUES (?, ?)`, id, msg.Content); err != nil {
+		return fmt.Errorf("failed to index message: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a session and its archived messages.
+func (s *SQLiteStore) Delete(key string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`DELETE FROM messages_fts WHERE rowid IN (SELECT id FROM messages WHERE session_key = ?)`,
+		`DELETE FROM messages WHERE session_key = ?`,
+		`DELETE FROM sessions WHERE key = ?`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt, key); err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListSessions returns the sessions that pass filter, most recently updated
+// first.
+func (s *SQLiteStore) ListSessions(filter Filter) ([]SessionInfo, error) {
+	where, args := filterClause(filter, "key", "channel", "updated_at")
+	rows, err := s.db.Query(`SELECT key, history_size, created_at, updated_at FROM sessions`+where+` ORDER BY updated_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var infos []SessionInfo
+	for rows.Next() {
+		var info SessionInfo
+		var created, updated int64
+		if err := rows.Scan(&info.Key, &info.MessageCount, &created, &updated); err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		info.CreatedAt = time.Unix(0, created)
+		info.UpdatedAt = time.Unix(0, updated)
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+// Search returns up to limit archived messages containing all words of
+// query, best matches first.
+func (s *SQLiteStore) Search(query string, filter Filter, limit int) ([]SearchHit, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, fmt.Errorf("empty search query")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	where, args := filterClause(filter, "s.key", "s.channel", "m.timestamp")
+	if where == "" {
+		where = " WHERE"
+	} else {
+		where += " AND"
+	}
+	args = append([]interface{}{}, args...)
+	args = append(args, match, limit)
+
+	rows, err := s.db.Query(`SELECT m.session_key, m.role, m.timestamp, snippet(messages_fts, 0, '[', ']', '…', 16)
+		FROM messages_fts
+		JOIN messages m ON m.id = messages_fts.rowid
+		JOIN sessions s ON s.key = m.session_key`+where+` messages_fts MATCH ?
+		ORDER BY rank LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var hit SearchHit
+		var ts int64
+		if err := rows.Scan(&hit.Key, &hit.Role, &ts, &hit.Snippet); err != nil {
+			return nil, fmt.Errorf("failed to search sessions: %w", err)
+		}
+		hit.Timestamp = time.Unix(0, ts)
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// filterClause builds a WHERE clause for filter on the given key, channel
+// and time columns.
+func filterClause(filter Filter, keyCol, channelCol, timeCol string) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if filter.Key != "" {
+		conds = append(conds, keyCol+" = ?")
+		args = append(args, filter.Key)
+	}
+	if filter.Channel != "" {
+		conds = append(conds, channelCol+" = ?")
+		args = append(args, filter.Channel)
+	}
+	if !filter.Since.IsZero() {
+		conds = append(conds, timeCol+" >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, timeCol+" < ?")
+		args = append(args, filter.Until.UnixNano())
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// ftsQuery turns user input into an FTS5 query matching all its words, so
+// that punctuation is never parsed as query syntax.
+func ftsQuery(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// scanMessage reads a message row selected by Load.
+func scanMessage(rows *sql.Rows) (Message, error) {
+	var msg Message
+	var ts int64
+	var toolCalls, toolCallID, name sql.NullString
+	if err := rows.Scan(&msg.Role, &msg.Content, &ts, &toolCalls, &toolCallID, &name); err != nil {
+		return msg, fmt.Errorf("failed to load message: %w", err)
+	}
+	msg.Timestamp = time.Unix(0, ts)
+	msg.ToolCallID = toolCallID.String
+	msg.Name = name.String
+	if toolCalls.Valid {
+		if err := json.Unmarshal([]byte(toolCalls.String), &msg.ToolCalls); err != nil {
+			return msg, fmt.Errorf("failed to decode tool calls: %w", err)
+		}
+	}
+	return msg, nil
+}
+
+func writeJSONLine(buf *bytes.Buffer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(append(data, '\n'))
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,26 @@
+//go:build lite || nosqlite
+
+package session
+
+import "fmt"
+
+// SQLiteStore is not available: SQLite support is not compiled into this
+// build.
+type SQLiteStore struct{}
+
+// OpenSQLite reports that SQLite support is not compiled into this build.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	return nil, fmt.Errorf("the sqlite session store is not included in this build")
+}
+
+// Close does nothing.
+func (s *SQLiteStore) Close() error { return nil }
+
+// Load is never called; OpenSQLite does not return a store.
+func (s *SQLiteStore) Load(key string) ([]byte, error) { return nil, nil }
+
+// Save is never called; OpenSQLite does not return a store.
+func (s *SQLiteStore) Save(key string, data []byte) error { return nil }
+
+// Delete is never called; OpenSQLite does not return a store.
+func (s *SQLiteStore) Delete(key string) error { return nil }
@@ -0,0 +1,237 @@
+//go:build !lite && !nosqlite
+
+package session
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newSQLiteManager(t *testing.T) (*Manager, *SQLiteStore) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := OpenSQLite(filepath.Join(dir, "sessions.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	m := NewManager(dir)
+	m.SetStore(store)
+	m.DisableFiles()
+	return m, store
+}
+
+func TestSQLiteStoreRoundTrip(t *testing.T) {
+	m, store := newSQLiteManager(t)
+
+	s := m.GetOrCreate("telegram:1")
+	s.AddMessage("user", "what is the capital of Peru?")
+	s.AddToolCall([]ToolCallInfo{{ID: "c1", Name: "web_search", Arguments: `{"query":"capital of Peru"}`}})
+	s.AddToolResult("c1", "web_search", "Lima is the capital")
+	s.AddMessage("assistant", "Lima.")
+	s.AddPin("User likes geography")
+	s.SetModel("gpt-4o")
+	temperature := 0.2
+	s.SetSettings(Settings{Temperature: &temperature, Persona: "coder"})
+	if err := m.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	s.AddMessage("user", "thanks")
+	if err := m.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A fresh manager reads the session back from the database
+	other := NewManager(t.TempDir())
+	other.SetStore(store)
+	other.DisableFiles()
+	got := other.Get("telegram:1")
+	if got == nil {
+		t.Fatal("session not found in store")
+	}
+	msgs := got.GetMessages()
+	if len(msgs) != 5 || msgs[1].ToolCalls[0].Name != "web_search" || msgs[2].ToolCallID != "c1" || msgs[4].Content != "thanks" {
+		t.Fatalf("loaded messages = %+v", msgs)
+	}
+	if len(got.GetPins()) != 1 {
+		t.Errorf("pins = %v, want 1", got.GetPins())
+	}
+	if got.GetModel() != "gpt-4o" {
+		t.Errorf("model = %q, want gpt-4o", got.GetModel())
+	}
+	if settings := got.GetSettings(); settings.TemperatureOr(1) != 0.2 || settings.Persona != "coder" {
+		t.Errorf("settings = %+v, want temperature 0.2 and persona coder", settings)
+	}
+	if !msgs[0].Timestamp.Equal(s.Messages[0].Timestamp) {
+		t.Errorf("timestamp = %v, want %v", msgs[0].Timestamp, s.Messages[0].Timestamp)
+	}
+
+	// Appending did not duplicate archived messages
+	var count int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&count); err != nil || count != 5 {
+		t.Errorf("archived messages = %d (%v), want 5", count, err)
+	}
+}
+
+func TestSQLiteStoreBranches(t *testing.T) {
+	m, store := newSQLiteManager(t)
+
+	root := m.Active("telegram:1")
+	root.AddMessage("user", "hi")
+	if _, err := m.Fork(root, "idea"); err != nil {
+		t.Fatalf("Fork: %v", err)
+	}
+
+	other := NewManager(t.TempDir())
+	other.SetStore(store)
+	other.DisableFiles()
+	if got := other.Active("telegram:1"); got.Key != "telegram:1#idea" || got.GetLineage().Parent != "telegram:1" {
+		t.Fatalf("Active = %s with lineage %+v, want the idea branch", got.Key, got.GetLineage())
+	}
+}
+
+func TestSQLiteStoreClearKeepsArchiveSearchable(t *testing.T) {
+	m, _ := newSQLiteManager(t)
+
+	s := m.GetOrCreate("telegram:1")
+	s.AddMessage("user", "remind me about the dentist appointment")
+	if err := m.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := m.Clear("telegram:1"); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	s.AddMessage("user", "hello again")
+	if err := m.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	other := NewManager(t.TempDir())
+	other.SetStore(m.store)
+	if msgs := other.Get("telegram:1").GetMessages(); len(msgs) != 1 || msgs[0].Content != "hello again" {
+		t.Fatalf("history after clear = %+v", msgs)
+	}
+
+	hits, err := m.Search("dentist", Filter{}, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Key != "telegram:1" || hits[0].Role != "user" {
+		t.Fatalf("hits = %+v", hits)
+	}
+	if hits[0].Snippet == "" {
+		t.Error("empty snippet")
+	}
+
+	// Query syntax in user input is matched literally
+	if _, err := m.Search(`dentist" OR (`, Filter{}, 10); err != nil {
+		t.Errorf("Search with punctuation: %v", err)
+	}
+}
+
+func TestSQLiteStoreListAndSearchFilters(t *testing.T) {
+	m, _ := newSQLiteManager(t)
+
+	for _, key := range []string{"telegram:1", "whatsapp:2", "telegram:3"} {
+		s := m.GetOrCreate(key)
+		s.AddMessage("user", "weekly report for "+key)
+		if err := m.Save(s); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	infos, err := m.ListFiltered(Filter{Channel: "telegram"})
+	if err != nil {
+		t.Fatalf("ListFiltered: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("telegram sessions = %+v, want 2", infos)
+	}
+	if infos, _ := m.ListFiltered(Filter{Since: time.Now().Add(time.Hour)}); len(infos) != 0 {
+		t.Errorf("sessions updated in the future = %+v", infos)
+	}
+
+	hits, err := m.Search("weekly report", Filter{Channel: "whatsapp"}, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Key != "whatsapp:2" {
+		t.Errorf("whatsapp hits = %+v", hits)
+	}
+
+	if err := m.store.Delete("telegram:1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if hits, _ := m.Search("telegram:1", Filter{}, 10); len(hits) != 0 {
+		t.Errorf("deleted session still searchable: %+v", hits)
+	}
+}
+
+func TestMigrateFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := NewManager(dir)
+	s := files.GetOrCreate("cli:default")
+	s.AddMessage("user", "an old conversation")
+	if err := files.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	store, err := OpenSQLite(filepath.Join(dir, "sessions.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer store.Close()
+	m := NewManager(dir)
+	m.SetStore(store)
+	m.DisableFiles()
+
+	for want := 1; want >= 0; want-- {
+		n, err := m.MigrateFiles()
+		if err != nil || n != want {
+			t.Fatalf("MigrateFiles = %d, %v; want %d", n, err, want)
+		}
+	}
+	if got := m.Get("cli:default"); got == nil || got.MessageCount() != 1 {
+		t.Fatalf("migrated session = %+v", got)
+	}
+}
+
+func TestOpenSQLiteAddsColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sessions table as first released, without the model column
+	_, err = db.Exec(`CREATE TABLE sessions (
+		key TEXT PRIMARY KEY, channel TEXT NOT NULL, created_at INTEGER NOT NULL, updated_at INTEGER NOT NULL,
+		pins TEXT NOT NULL DEFAULT '[]', history_start INTEGER NOT NULL DEFAULT 0, history_size INTEGER NOT NULL DEFAULT 0)`)
+	if err == nil {
+		_, err = db.Exec(`INSERT INTO sessions (key, channel, created_at, updated_at) VALUES ('telegram:1', 'telegram', 1, 1)`)
+	}
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer store.Close()
+	m := NewManager(t.TempDir())
+	m.SetStore(store)
+	m.DisableFiles()
+
+	s := m.Get("telegram:1")
+	if s == nil {
+		t.Fatal("existing session not loaded")
+	}
+	s.SetModel("gpt-4o")
+	if err := m.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+}
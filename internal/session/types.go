@@ -30,6 +30,10 @@ type Session struct {
 	CreatedAt time.Time              `json:"createdAt"`
 	UpdatedAt time.Time              `json:"updatedAt"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Pins      []Pin                  `json:"pins,omitempty"`
+	Lineage   Lineage                `json:"lineage"`
+	Model     string                 `json:"model,omitempty"` // chosen with /model; "" uses the default
+	Settings  Settings               `json:"settings"`        // chosen with /settings
 	mu        sync.RWMutex
 }
 
@@ -59,6 +63,20 @@ func (s *Session) AddMessage(role, content string) {
 	s.UpdatedAt = time.Now()
 }
 
+// RemoveLastMessage removes the newest message, such as a user message
+// that will be answered later, and reports whether there was one.
+func (s *Session) RemoveLastMessage() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.Messages) == 0 {
+		return false
+	}
+	s.Messages = s.Messages[:len(s.Messages)-1]
+	s.UpdatedAt = time.Now()
+	return true
+}
+
 // AddToolCall adds an assistant message with tool calls
 func (s *Session) AddToolCall(toolCalls []ToolCallInfo) {
 	s.mu.Lock()
@@ -124,6 +142,25 @@ func (s *Session) Clear() {
 	s.UpdatedAt = time.Now()
 }
 
+// SetModel sets the model used in this conversation; "" returns to the
+// default model.
+func (s *Session) SetModel(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Model = model
+	s.UpdatedAt = time.Now()
+}
+
+// GetModel returns the model chosen for this conversation, or "" for the
+// default model.
+func (s *Session) GetModel() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.Model
+}
+
 // MessageCount returns the number of messages in the session
 func (s *Session) MessageCount() int {
 	s.mu.RLock()
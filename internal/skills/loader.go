@@ -21,6 +21,9 @@ type Skill struct {
 	Content     string   // Full markdown content
 	Path        string   // Path to SKILL.md
 	AlwaysLoad  bool     // Load in every context
+	Version     string   // From frontmatter, if any
+	Requires    []string // Binaries, or "pip:<package>", the skill needs
+	Scripts     []string // Setup scripts to run on install, relative to the skill
 }
 
 // Loader manages skill discovery and loading
@@ -4,6 +4,8 @@
 package skills
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -23,29 +25,55 @@ const (
 
 // AvailableSkill represents a skill available in the source repository.
 type AvailableSkill struct {
-	Name        string // Directory name (skill identifier)
-	Title       string // From # heading
-	Description string // First paragraph
-	Category    string // Parent directory (e.g., "product-management")
-	Path        string // Full path to SKILL.md in cache
+	Name        string   // Directory name (skill identifier)
+	Title       string   // From # heading
+	Description string   // First paragraph
+	Category    string   // Parent directory (e.g., "product-management")
+	Repo        string   // Name of the source repository
+	Version     string   // From frontmatter, if any
+	Requires    []string // Binaries, or "pip:<package>", the skill needs
+	Scripts     []string // Setup scripts, relative to the skill directory
+	Path        string   // Full path to SKILL.md in cache
+}
+
+// Source is a repository of skills: a git URL, cloned into the cache, or a
+// local directory.
+type Source struct {
+	Name string // prefixes the names of its skills, except for the first source
+	URL  string
+	Path string
+}
+
+// repo is a Source with the directory its skills are read from.
+type repo struct {
+	Source
+	root string
+}
+
+// isGit reports whether the repository is cloned from a URL.
+func (r repo) isGit() bool {
+	return r.Path == ""
 }
 
 // Manager handles skill repository cloning and installation.
 type Manager struct {
-	repoURL       string
-	cacheDir      string // ~/.ubot/cache/skills-repo
-	workspaceDir  string // ~/.ubot/workspace/skills
-	available     map[string]*AvailableSkill
-	mu            sync.RWMutex
+	repoURL      string
+	cacheDir     string   // ~/.ubot/cache/skills-repo, for the default repository
+	configDir    string   // ~/.ubot
+	workspaceDir string   // ~/.ubot/workspace/skills
+	sources      []Source // nil = the default repository only
+	available    map[string]*AvailableSkill
+	mu           sync.RWMutex
 }
 
-// NewManager creates a new skill manager.
+// NewManager creates a new skill manager using the default repository.
 // configDir is typically ~/.ubot
 // workspacePath is typically ~/.ubot/workspace
 func NewManager(configDir, workspacePath string) *Manager {
 	return &Manager{
 		repoURL:      DefaultSkillsRepo,
 		cacheDir:     filepath.Join(configDir, DefaultCacheDir),
+		configDir:    configDir,
 		workspaceDir: filepath.Join(workspacePath, "skills"),
 		available:    make(map[string]*AvailableSkill),
 	}
@@ -58,7 +86,82 @@ func (m *Manager) SetRepoURL(url string) {
 	m.repoURL = url
 }
 
-// GetCacheDir returns the cache directory path.
+// SetSources replaces the default repository with sources. Skills from the
+// first source keep their names; the others are named "<source>.<skill>".
+// Sources without a name are named after their URL or path.
+func (m *Manager) SetSources(sources []Source) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sources = make([]Source, 0, len(sources))
+	seen := make(map[string]bool)
+	for _, src := range sources {
+		if src.Name == "" {
+			src.Name = sourceName(src)
+		}
+		// Keep names unique so their caches and skills do not mix
+		name := src.Name
+		for i := 2; seen[name]; i++ {
+			name = fmt.Sprintf("%s%d", src.Name, i)
+		}
+		src.Name = name
+		seen[name] = true
+		m.sources = append(m.sources, src)
+	}
+}
+
+// sourceName derives a name from the last element of a source's URL or path.
+func sourceName(src Source) string {
+	loc := src.Path
+	if loc == "" {
+		loc = src.URL
+	}
+	base := strings.TrimSuffix(filepath.Base(strings.TrimRight(loc, "/")), ".git")
+	base = strings.Map(func(r rune) rune {
+		if r == '.' || r == '/' || r == '\\' || r == ':' {
+			return '-'
+		}
+		return r
+	}, base)
+	if base == "" || base == "-" {
+		return "repo"
+	}
+	return base
+}
+
+// repos returns the sources with the directories their skills are in.
+// Callers must hold m.mu.
+func (m *Manager) repos() []repo {
+	if m.sources == nil {
+		return []repo{{Source: Source{Name: "community", URL: m.repoURL}, root: m.cacheDir}}
+	}
+	repos := make([]repo, len(m.sources))
+	for i, src := range m.sources {
+		r := repo{Source: src}
+		switch {
+		case src.Path != "":
+			r.root = expandHome(src.Path)
+		case src.URL == DefaultSkillsRepo:
+			r.root = m.cacheDir // share the default repository's cache
+		default:
+			r.root = filepath.Join(m.configDir, "cache", "skill-repos", src.Name)
+		}
+		repos[i] = r
+	}
+	return repos
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// GetCacheDir returns the cache directory path of the default repository.
 func (m *Manager) GetCacheDir() string {
 	return m.cacheDir
 }
@@ -68,47 +171,71 @@ func (m *Manager) GetWorkspaceDir() string {
 	return m.workspaceDir
 }
 
-// IsCached checks if the skills repo is already cached.
+// IsCached checks if every git repository has been cloned.
 func (m *Manager) IsCached() bool {
-	gitDir := filepath.Join(m.cacheDir, ".git")
-	_, err := os.Stat(gitDir)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.isCachedLocked()
+}
+
+func (m *Manager) isCachedLocked() bool {
+	for _, r := range m.repos() {
+		if r.isGit() && !isClone(r.root) {
+			return false
+		}
+	}
+	return true
+}
+
+// isClone reports whether dir is a git checkout.
+func isClone(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
 	return err == nil
 }
 
-// EnsureRepo clones or updates the skills repository.
-// Returns true if repo was freshly cloned, false if updated.
+// EnsureRepo clones or updates the git repositories.
+// Returns true if a repository was freshly cloned. A repository that fails
+// to clone does not stop the others; the errors are returned together.
 func (m *Manager) EnsureRepo() (bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Check if already cloned
-	if m.IsCached() {
-		// Pull latest changes
-		if err := m.pullRepo(); err != nil {
-			// Pull failed, but repo exists - continue anyway
-			return false, nil
+	cloned := false
+	var errs []error
+	for _, r := range m.repos() {
+		if !r.isGit() {
+			continue
+		}
+
+		// Check if already cloned
+		if isClone(r.root) {
+			// Pull latest changes; if that fails the repo exists, so
+			// continue anyway
+			_ = pullRepo(r.root)
+			continue
 		}
-		return false, nil
-	}
 
-	// Clone the repository
-	if err := m.cloneRepo(); err != nil {
-		return false, fmt.Errorf("failed to clone skills repo: %w", err)
+		// Clone the repository
+		if err := cloneRepo(r.URL, r.root); err != nil {
+			errs = append(errs, fmt.Errorf("failed to clone skills repo %s: %w", r.Name, err))
+			continue
+		}
+		cloned = true
 	}
 
-	return true, nil
+	return cloned, errors.Join(errs...)
 }
 
-// cloneRepo clones the repository to the cache directory.
-func (m *Manager) cloneRepo() error {
+// cloneRepo clones the repository at url to dir.
+func cloneRepo(url, dir string) error {
 	// Ensure parent directory exists
-	parentDir := filepath.Dir(m.cacheDir)
+	parentDir := filepath.Dir(dir)
 	if err := os.MkdirAll(parentDir, 0755); err != nil {
 		return fmt.Errorf("failed to create cache parent directory: %w", err)
 	}
 
 	// Clone with depth 1 for faster download
-	cmd := exec.Command("git", "clone", "--depth", "1", m.repoURL, m.cacheDir)
+	cmd := exec.Command("git", "clone", "--depth", "1", url, dir)
 	cmd.Stdout = io.Discard
 	cmd.Stderr = io.Discard
 
@@ -119,9 +246,9 @@ func (m *Manager) cloneRepo() error {
 	return nil
 }
 
-// pullRepo pulls the latest changes from the remote.
-func (m *Manager) pullRepo() error {
-	cmd := exec.Command("git", "-C", m.cacheDir, "pull", "--ff-only")
+// pullRepo pulls the latest changes into the clone at dir.
+func pullRepo(dir string) error {
+	cmd := exec.Command("git", "-C", dir, "pull", "--ff-only")
 	cmd.Stdout = io.Discard
 	cmd.Stderr = io.Discard
 
@@ -132,8 +259,9 @@ func (m *Manager) pullRepo() error {
 	return nil
 }
 
-// DiscoverAvailable scans the cached repo for available skills.
-// Must call EnsureRepo() first.
+// DiscoverAvailable scans the repositories for available skills.
+// Must call EnsureRepo() first; repositories that were not cloned are
+// skipped.
 func (m *Manager) DiscoverAvailable() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -141,15 +269,39 @@ func (m *Manager) DiscoverAvailable() error {
 	// Clear existing
 	m.available = make(map[string]*AvailableSkill)
 
-	// Check if cache exists
-	if !m.IsCached() {
+	found := false
+	for i, r := range m.repos() {
+		if r.isGit() && !isClone(r.root) {
+			continue
+		}
+		if info, err := os.Stat(r.root); err != nil || !info.IsDir() {
+			continue
+		}
+		found = true
+
+		// Skills from every repository but the first are namespaced
+		prefix := ""
+		if i > 0 {
+			prefix = r.Name + "."
+		}
+		if err := m.discoverIn(r, prefix); err != nil {
+			return err
+		}
+	}
+
+	if !found {
 		return fmt.Errorf("skills repo not cached, call EnsureRepo() first")
 	}
+	return nil
+}
 
-	// Walk the cache directory looking for SKILL.md files
-	// Structure: cache/skills-repo/<category>/<skill-name>/SKILL.md
-	// or: cache/skills-repo/<skill-name>/SKILL.md
-	err := filepath.Walk(m.cacheDir, func(path string, info os.FileInfo, err error) error {
+// discoverIn adds the skills in a repository, naming each prefix plus its
+// directory name. Callers must hold m.mu.
+func (m *Manager) discoverIn(r repo, prefix string) error {
+	// Walk the repository looking for SKILL.md files
+	// Structure: <root>/<category>/<skill-name>/SKILL.md
+	// or: <root>/<skill-name>/SKILL.md
+	return filepath.Walk(r.root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
@@ -171,7 +323,7 @@ func (m *Manager) DiscoverAvailable() error {
 		}
 
 		// Determine skill name and category from path
-		relPath, _ := filepath.Rel(m.cacheDir, path)
+		relPath, _ := filepath.Rel(r.root, path)
 		parts := strings.Split(filepath.Dir(relPath), string(filepath.Separator))
 
 		var skillName, category string
@@ -191,18 +343,21 @@ func (m *Manager) DiscoverAvailable() error {
 			return nil
 		}
 
-		m.available[skillName] = &AvailableSkill{
-			Name:        skillName,
+		name := prefix + skillName
+		m.available[name] = &AvailableSkill{
+			Name:        name,
 			Title:       skill.Title,
 			Description: skill.Description,
 			Category:    category,
+			Repo:        r.Name,
+			Version:     skill.Version,
+			Requires:    skill.Requires,
+			Scripts:     skill.Scripts,
 			Path:        path,
 		}
 
 		return nil
 	})
-
-	return err
 }
 
 // DiscoverBundled scans a local bundled skills directory and adds them
@@ -253,6 +408,10 @@ func (m *Manager) DiscoverBundled(bundledPath string) error {
 			Title:       skill.Title,
 			Description: skill.Description,
 			Category:    "bundled",
+			Repo:        "bundled",
+			Version:     skill.Version,
+			Requires:    skill.Requires,
+			Scripts:     skill.Scripts,
 			Path:        skillFile,
 		}
 	}
@@ -291,6 +450,28 @@ func (m *Manager) ListAvailableNames() []string {
 	return names
 }
 
+// Search returns the available skills whose name, title, description or
+// category contains every word of query, ignoring case, sorted like
+// ListAvailable. An empty query matches every skill.
+func (m *Manager) Search(query string) []*AvailableSkill {
+	words := strings.Fields(strings.ToLower(query))
+	var found []*AvailableSkill
+	for _, s := range m.ListAvailable() {
+		text := strings.ToLower(strings.Join([]string{s.Name, s.Title, s.Description, s.Category}, " "))
+		match := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
 // GetAvailable returns an available skill by name.
 func (m *Manager) GetAvailable(name string) *AvailableSkill {
 	m.mu.RLock()
@@ -298,21 +479,21 @@ func (m *Manager) GetAvailable(name string) *AvailableSkill {
 	return m.available[name]
 }
 
-// Install copies a skill from the cache to the workspace.
+// Install copies a skill from the cache to the workspace. It returns a
+// *MissingError if requirements the skill declares are not installed. Setup
+// scripts are not run; use InstallWith for that.
 func (m *Manager) Install(skillName string) error {
-	m.mu.RLock()
-	skill, exists := m.available[skillName]
-	m.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("skill %q not found in available skills", skillName)
-	}
+	_, err := m.InstallWith(context.Background(), skillName, InstallOptions{})
+	return err
+}
 
+// install copies skill from the cache to the workspace.
+func (m *Manager) install(skill *AvailableSkill) error {
 	// Source directory (containing SKILL.md)
 	srcDir := filepath.Dir(skill.Path)
 
 	// Destination directory
-	dstDir := filepath.Join(m.workspaceDir, skillName)
+	dstDir := filepath.Join(m.workspaceDir, skill.Name)
 
 	// Ensure workspace skills directory exists
 	if err := os.MkdirAll(m.workspaceDir, 0755); err != nil {
@@ -329,6 +510,11 @@ func (m *Manager) Install(skillName string) error {
 		return fmt.Errorf("failed to copy skill: %w", err)
 	}
 
+	// Record what was installed, for upgrades
+	if err := m.recordVersion(skill, dstDir); err != nil {
+		return fmt.Errorf("failed to record skill version: %w", err)
+	}
+
 	return nil
 }
 
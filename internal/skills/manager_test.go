@@ -3,6 +3,7 @@ package skills
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -331,3 +332,66 @@ func TestCopyDir(t *testing.T) {
 		t.Error("subdir/file2.txt not copied correctly")
 	}
 }
+
+func TestSourcesNamespaceSkills(t *testing.T) {
+	tmpDir := t.TempDir()
+	personal := filepath.Join(tmpDir, "personal")
+	work := filepath.Join(tmpDir, "work-skills")
+	writeSkill(t, "Deploy", personal, "deploy")
+	writeSkill(t, "Deploy at work", work, "ops", "deploy")
+
+	m := NewManager(tmpDir, tmpDir)
+	m.SetSources([]Source{
+		{Name: "personal", Path: personal},
+		{Path: work + "/"}, // named after the directory
+	})
+
+	// Local directories need no cloning
+	if !m.IsCached() {
+		t.Error("expected local sources to count as cached")
+	}
+	if err := m.DiscoverAvailable(); err != nil {
+		t.Fatalf("DiscoverAvailable failed: %v", err)
+	}
+
+	if s := m.GetAvailable("deploy"); s == nil || s.Title != "Deploy" || s.Repo != "personal" {
+		t.Errorf("first source's skill = %+v, want plain name from personal", s)
+	}
+	s := m.GetAvailable("work-skills.deploy")
+	if s == nil || s.Title != "Deploy at work" || s.Category != "ops" {
+		t.Fatalf("second source's skill = %+v, want namespaced work-skills.deploy", s)
+	}
+
+	// Both install side by side
+	for _, name := range []string{"deploy", "work-skills.deploy"} {
+		if err := m.Install(name); err != nil {
+			t.Fatalf("Install(%s) failed: %v", name, err)
+		}
+	}
+	installed, _ := m.ListInstalled()
+	if len(installed) != 2 {
+		t.Errorf("installed = %v, want both skills", installed)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	m := newAdvisorManager(t)
+
+	tests := map[string][]string{
+		"":                {"spreadsheets", "calendar"},
+		"excel":           {"spreadsheets"},
+		"CSV chart":       {"spreadsheets"},
+		"productivity":    {"calendar"},
+		"excel meetings":  nil,
+		"nothing-matches": nil,
+	}
+	for query, want := range tests {
+		var got []string
+		for _, s := range m.Search(query) {
+			got = append(got, s.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("Search(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 // - Description from the first paragraph after the title
 // - Tool names from the ## Tools section
 // - AlwaysLoad flag from <!-- always-load --> comment
+// - Version, Requires and Scripts from YAML frontmatter, if any
 func ParseSkillFile(path string) (*Skill, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -37,6 +38,12 @@ func ParseSkillContent(content string, path string) (*Skill, error) {
 	skill.Description = parseDescription(lines)
 	skill.Tools = parseTools(lines)
 	skill.AlwaysLoad = parseAlwaysLoad(content)
+	meta := parseFrontmatter(lines)
+	if v := meta["version"]; len(v) > 0 {
+		skill.Version = v[0]
+	}
+	skill.Requires = meta["requires"]
+	skill.Scripts = meta["scripts"]
 
 	return skill, nil
 }
@@ -157,3 +164,64 @@ func parseAlwaysLoad(content string) bool {
 
 	return false
 }
+
+// parseFrontmatter reads the simple YAML frontmatter at the top of a skill
+// file: scalar values and lists, written inline or one item per line.
+//
+//	---
+//	version: 1.2.0
+//	requires: [ffmpeg, "pip:openai-whisper"]
+//	scripts:
+//	  - scripts/setup.sh
+//	---
+//
+// Each key maps to its values; a scalar is a one-item list.
+func parseFrontmatter(lines []string) map[string][]string {
+	meta := make(map[string][]string)
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return meta
+	}
+
+	key := ""
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "---" {
+			break
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// An item of the list under the last key
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok && key != "" && line != trimmed {
+			meta[key] = append(meta[key], unquote(item))
+			continue
+		}
+
+		k, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			key = ""
+			continue
+		}
+		key = strings.TrimSpace(k)
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			// A list follows on the next lines
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = unquote(item); item != "" {
+					meta[key] = append(meta[key], item)
+				}
+			}
+		default:
+			meta[key] = []string{unquote(value)}
+		}
+	}
+	return meta
+}
+
+// unquote trims spaces and surrounding quotes from a frontmatter value.
+func unquote(s string) string {
+	return strings.Trim(strings.TrimSpace(s), `"'`)
+}
@@ -0,0 +1,152 @@
+package skills
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// knownSkillsFile records the skills seen by previous refreshes, next to the
+// repository cache.
+const knownSkillsFile = "skills-known.json"
+
+// Refresh updates the cached skills repository, re-discovers the available
+// skills and returns the ones that appeared since the previous refresh in
+// categories of installed skills. The first refresh only records what is
+// available and returns nothing.
+func (m *Manager) Refresh() ([]*AvailableSkill, error) {
+	if _, err := m.EnsureRepo(); err != nil {
+		return nil, err
+	}
+	if err := m.DiscoverAvailable(); err != nil {
+		return nil, err
+	}
+
+	known, err := m.loadKnown()
+	firstRun := os.IsNotExist(err)
+	if err != nil && !firstRun {
+		return nil, fmt.Errorf("failed to read known skills: %w", err)
+	}
+
+	available := m.ListAvailable()
+	names := make([]string, len(available))
+	for i, s := range available {
+		names[i] = s.Name
+	}
+	if err := m.saveKnown(names); err != nil {
+		return nil, fmt.Errorf("failed to save known skills: %w", err)
+	}
+	if firstRun {
+		return nil, nil
+	}
+
+	categories, err := m.installedCategories()
+	if err != nil {
+		return nil, err
+	}
+	var fresh []*AvailableSkill
+	for _, s := range available {
+		if !known[s.Name] && categories[s.Category] && !m.IsInstalled(s.Name) {
+			fresh = append(fresh, s)
+		}
+	}
+	return fresh, nil
+}
+
+// RunMaintenance refreshes an existing skills cache every interval and
+// passes newly available skills to notify. A cache that was never created
+// (no "ubot skills" command run yet) is left alone. It returns when ctx is
+// done.
+func (m *Manager) RunMaintenance(ctx context.Context, interval time.Duration, notify func([]*AvailableSkill)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !m.IsCached() {
+			continue
+		}
+		fresh, err := m.Refresh()
+		if err != nil {
+			log.Printf("Warning: skills cache refresh failed: %v", err)
+			continue
+		}
+		if len(fresh) > 0 && notify != nil {
+			notify(fresh)
+		}
+	}
+}
+
+// FormatNewSkills describes newly available skills for the user.
+func FormatNewSkills(fresh []*AvailableSkill) string {
+	var sb strings.Builder
+	sb.WriteString("New skills are available in categories you use:\n")
+	for _, s := range fresh {
+		fmt.Fprintf(&sb, "\n- %s (%s)", s.Name, s.Category)
+		if s.Description != "" {
+			fmt.Fprintf(&sb, ": %s", s.Description)
+		}
+	}
+	sb.WriteString("\n\nInstall one with: ubot skills install <name>")
+	return sb.String()
+}
+
+// installedCategories returns the repository categories of installed
+// skills. Bundled and uncategorized skills are not counted.
+func (m *Manager) installedCategories() (map[string]bool, error) {
+	installed, err := m.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+	categories := make(map[string]bool)
+	for _, name := range installed {
+		if s := m.GetAvailable(name); s != nil && s.Category != "" && s.Category != "bundled" {
+			categories[s.Category] = true
+		}
+	}
+	return categories, nil
+}
+
+func (m *Manager) knownPath() string {
+	return filepath.Join(filepath.Dir(m.cacheDir), knownSkillsFile)
+}
+
+// loadKnown returns the skill names recorded by the previous refresh.
+func (m *Manager) loadKnown() (map[string]bool, error) {
+	data, err := os.ReadFile(m.knownPath())
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(names))
+	for _, n := range names {
+		known[n] = true
+	}
+	return known, nil
+}
+
+func (m *Manager) saveKnown(names []string) error {
+	sort.Strings(names)
+	data, err := json.MarshalIndent(names, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.knownPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.knownPath(), data, 0644)
+}
@@ -0,0 +1,68 @@
+package skills
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSkill creates a SKILL.md under dir/parts...
+func writeSkill(t *testing.T, title string, parts ...string) {
+	t.Helper()
+	dir := filepath.Join(parts...)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create skill dir: %v", err)
+	}
+	content := "# " + title + "\n\nA skill for testing.\n"
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write SKILL.md: %v", err)
+	}
+}
+
+func TestRefreshReportsNewSkillsInInstalledCategories(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir, tmpDir)
+
+	// Fake cache; pulling fails and is ignored
+	if err := os.MkdirAll(filepath.Join(m.cacheDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeSkill(t, "Roadmap", m.cacheDir, "product", "roadmap")
+	writeSkill(t, "Invoices", m.cacheDir, "finance", "invoices")
+
+	// The first refresh only records what exists
+	fresh, err := m.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if len(fresh) != 0 {
+		t.Fatalf("first refresh reported %d skills, want 0", len(fresh))
+	}
+
+	if err := m.Install("roadmap"); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	writeSkill(t, "Spec", m.cacheDir, "product", "spec")
+	writeSkill(t, "Budget", m.cacheDir, "finance", "budget")
+
+	fresh, err = m.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if len(fresh) != 1 || fresh[0].Name != "spec" {
+		t.Fatalf("fresh = %v, want only spec (category of an installed skill)", fresh)
+	}
+	if msg := FormatNewSkills(fresh); !strings.Contains(msg, "spec (product)") {
+		t.Errorf("unexpected message: %q", msg)
+	}
+
+	// Skills are reported once
+	fresh, err = m.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if len(fresh) != 0 {
+		t.Errorf("third refresh reported %d skills, want 0", len(fresh))
+	}
+}
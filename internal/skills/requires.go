@@ -0,0 +1,137 @@
+package skills
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pipPrefix marks a requirement as a Python package rather than a binary.
+const pipPrefix = "pip:"
+
+// ScriptTimeout limits how long a setup script may run.
+const ScriptTimeout = 10 * time.Minute
+
+// lookPath and pipInstalled are variables so tests can fake the machine.
+var (
+	lookPath     = exec.LookPath
+	pipInstalled = func(pkg string) bool {
+		return exec.Command("python3", "-m", "pip", "show", "-q", pkg).Run() == nil
+	}
+)
+
+// MissingError is returned by Install when requirements a skill declares are
+// not installed on this machine.
+type MissingError struct {
+	Skill   string
+	Missing []string
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("skill %q needs %s, which %s not installed", e.Skill, strings.Join(e.Missing, ", "), plural(len(e.Missing), "is", "are"))
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// CheckRequirements returns the requirements that are not met: binaries not
+// found in PATH and, for "pip:<package>", Python packages pip does not know.
+func CheckRequirements(requires []string) []string {
+	var missing []string
+	for _, req := range requires {
+		if pkg, ok := strings.CutPrefix(req, pipPrefix); ok {
+			if !pipInstalled(pkg) {
+				missing = append(missing, req)
+			}
+			continue
+		}
+		if _, err := lookPath(req); err != nil {
+			missing = append(missing, req)
+		}
+	}
+	return missing
+}
+
+// ScriptRunner runs a setup script, given relative to the skill directory
+// dir, and returns its output.
+type ScriptRunner func(ctx context.Context, dir, script string) (string, error)
+
+// InstallOptions change how InstallWith installs a skill.
+type InstallOptions struct {
+	IgnoreMissing bool         // install even if requirements are not met
+	Scripts       ScriptRunner // runs setup scripts; nil leaves them for the user
+}
+
+// ScriptResult is the outcome of a setup script.
+type ScriptResult struct {
+	Script string
+	Output string
+}
+
+// InstallReport describes what InstallWith did besides copying the skill.
+type InstallReport struct {
+	Missing []string       // requirements not met, with IgnoreMissing
+	Ran     []ScriptResult // setup scripts that ran
+	Skipped []string       // setup scripts not run, for lack of a runner
+}
+
+// InstallWith copies a skill from the cache to the workspace like Install,
+// after checking its requirements, and then runs its setup scripts.
+func (m *Manager) InstallWith(ctx context.Context, skillName string, opts InstallOptions) (*InstallReport, error) {
+	skill := m.GetAvailable(skillName)
+	if skill == nil {
+		return nil, fmt.Errorf("skill %q not found in available skills", skillName)
+	}
+
+	report := &InstallReport{}
+	if missing := CheckRequirements(skill.Requires); len(missing) > 0 {
+		if !opts.IgnoreMissing {
+			return nil, &MissingError{Skill: skillName, Missing: missing}
+		}
+		report.Missing = missing
+	}
+
+	if err := m.install(skill); err != nil {
+		return nil, err
+	}
+
+	dstDir := filepath.Join(m.workspaceDir, skillName)
+	for _, script := range skill.Scripts {
+		if !validScript(dstDir, script) {
+			return report, fmt.Errorf("skill %q declares an invalid setup script %q", skillName, script)
+		}
+		if opts.Scripts == nil {
+			report.Skipped = append(report.Skipped, script)
+			continue
+		}
+		scriptCtx, cancel := context.WithTimeout(ctx, ScriptTimeout)
+		out, err := opts.Scripts(scriptCtx, dstDir, script)
+		cancel()
+		if err != nil {
+			return report, fmt.Errorf("setup script %s failed: %w\n%s", script, err, out)
+		}
+		report.Ran = append(report.Ran, ScriptResult{Script: script, Output: out})
+	}
+	return report, nil
+}
+
+// validScript reports whether script is a file inside the skill directory.
+func validScript(dir, script string) bool {
+	if filepath.IsAbs(script) {
+		return false
+	}
+	clean := filepath.Clean(script)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, clean))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,132 @@
+package skills
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// fakeMachine makes CheckRequirements see only the given binaries and pip
+// packages for the rest of the test.
+func fakeMachine(t *testing.T, binaries, packages []string) {
+	t.Helper()
+	oldLook, oldPip := lookPath, pipInstalled
+	t.Cleanup(func() { lookPath, pipInstalled = oldLook, oldPip })
+
+	lookPath = func(name string) (string, error) {
+		for _, b := range binaries {
+			if b == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", exec.ErrNotFound
+	}
+	pipInstalled = func(pkg string) bool {
+		for _, p := range packages {
+			if p == pkg {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// newRequiringManager returns a Manager whose cache holds one skill, pdf,
+// with the given frontmatter and a setup.sh script.
+func newRequiringManager(t *testing.T, frontmatter string) *Manager {
+	t.Helper()
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir, tmpDir)
+	if err := os.MkdirAll(filepath.Join(m.cacheDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(m.cacheDir, "documents", "pdf")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "---\n" + frontmatter + "---\n# PDF\n\nReads PDF files.\n"
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "setup.sh"), []byte("echo ok\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DiscoverAvailable(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestInstallStopsOnMissingRequirements(t *testing.T) {
+	fakeMachine(t, []string{"pdftotext"}, nil)
+	m := newRequiringManager(t, "requires:\n  - pdftotext\n  - qpdf\n  - pip:pypdf\n")
+
+	_, err := m.InstallWith(context.Background(), "pdf", InstallOptions{})
+	var missing *MissingError
+	if !errors.As(err, &missing) {
+		t.Fatalf("InstallWith error = %v, want MissingError", err)
+	}
+	if len(missing.Missing) != 2 || missing.Missing[0] != "qpdf" || missing.Missing[1] != "pip:pypdf" {
+		t.Errorf("Missing = %v, want [qpdf pip:pypdf]", missing.Missing)
+	}
+	if m.IsInstalled("pdf") {
+		t.Error("skill was installed despite missing requirements")
+	}
+
+	report, err := m.InstallWith(context.Background(), "pdf", InstallOptions{IgnoreMissing: true})
+	if err != nil {
+		t.Fatalf("InstallWith with IgnoreMissing: %v", err)
+	}
+	if len(report.Missing) != 2 || !m.IsInstalled("pdf") {
+		t.Errorf("report.Missing = %v, installed = %v", report.Missing, m.IsInstalled("pdf"))
+	}
+}
+
+func TestInstallRunsOrSkipsScripts(t *testing.T) {
+	fakeMachine(t, nil, []string{"pypdf"})
+	m := newRequiringManager(t, "requires: [pip:pypdf]\nscripts: [setup.sh]\n")
+
+	report, err := m.InstallWith(context.Background(), "pdf", InstallOptions{})
+	if err != nil {
+		t.Fatalf("InstallWith: %v", err)
+	}
+	if len(report.Skipped) != 1 || len(report.Ran) != 0 {
+		t.Errorf("without a runner: skipped %v, ran %v", report.Skipped, report.Ran)
+	}
+
+	var gotDir, gotScript string
+	runner := func(ctx context.Context, dir, script string) (string, error) {
+		gotDir, gotScript = dir, script
+		return "ok", nil
+	}
+	report, err = m.InstallWith(context.Background(), "pdf", InstallOptions{Scripts: runner})
+	if err != nil {
+		t.Fatalf("InstallWith: %v", err)
+	}
+	if len(report.Ran) != 1 || report.Ran[0].Output != "ok" {
+		t.Errorf("Ran = %+v, want setup.sh with output ok", report.Ran)
+	}
+	if gotDir != filepath.Join(m.workspaceDir, "pdf") || gotScript != "setup.sh" {
+		t.Errorf("runner called with %q, %q", gotDir, gotScript)
+	}
+}
+
+func TestInstallRejectsScriptOutsideSkill(t *testing.T) {
+	fakeMachine(t, nil, nil)
+	m := newRequiringManager(t, "scripts: [../../../evil.sh]\n")
+
+	ran := false
+	runner := func(ctx context.Context, dir, script string) (string, error) {
+		ran = true
+		return "", nil
+	}
+	if _, err := m.InstallWith(context.Background(), "pdf", InstallOptions{Scripts: runner}); err == nil {
+		t.Fatal("InstallWith accepted a script outside the skill directory")
+	}
+	if ran {
+		t.Error("runner was called for an invalid script")
+	}
+}
@@ -0,0 +1,273 @@
+package skills
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// failureWindow is how long a failure counts towards a suggestion.
+	failureWindow = 24 * time.Hour
+	// minMatchScore is the score a skill needs to be suggested: one word of
+	// its name or title, or three of its description.
+	minMatchScore = 3
+)
+
+// CategoryUnfinished is the failure category of turns that ran out of tool
+// iterations without an answer.
+const CategoryUnfinished = "unfinished"
+
+// Failure is a turn in which the agent failed at a task.
+type Failure struct {
+	SessionKey string
+	Channel    string // where a suggestion is sent
+	ChatID     string
+	Category   string // the tool that failed, or CategoryUnfinished
+	Request    string // the user's message
+}
+
+// Suggestion proposes installing a skill after repeated failures.
+type Suggestion struct {
+	Failure  // the failure that completed the series
+	Skill    *AvailableSkill
+	Failures int
+}
+
+// failedTurn is a recorded Failure.
+type failedTurn struct {
+	at       time.Time
+	category string
+	request  string
+}
+
+// Advisor watches for kinds of task the agent keeps failing at in a chat
+// and, in the background, looks for an available skill that covers them.
+// Each skill is suggested at most once per chat, and only suggested skills
+// can be installed through HandleReply. A nil Advisor does nothing.
+type Advisor struct {
+	mgr       *Manager
+	threshold int
+	suggest   func(Suggestion)
+
+	mu        sync.Mutex
+	failures  map[string][]failedTurn    // by session key
+	suggested map[string]map[string]bool // session key -> skills suggested
+	pending   map[string]map[string]bool // session key -> skills awaiting an answer
+	now       func() time.Time
+}
+
+// NewAdvisor creates an Advisor that calls suggest when a chat has failed
+// threshold times at the same kind of task and mgr has a matching skill.
+func NewAdvisor(mgr *Manager, threshold int, suggest func(Suggestion)) *Advisor {
+	return &Advisor{
+		mgr:       mgr,
+		threshold: threshold,
+		suggest:   suggest,
+		failures:  make(map[string][]failedTurn),
+		suggested: make(map[string]map[string]bool),
+		pending:   make(map[string]map[string]bool),
+		now:       time.Now,
+	}
+}
+
+// Observe records a failure. Once the chat has failed threshold times in
+// the same category within a day, the matching runs in a new goroutine.
+func (a *Advisor) Observe(f Failure) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	now := a.now()
+	var kept, series []failedTurn
+	for _, t := range a.failures[f.SessionKey] {
+		if now.Sub(t.at) > failureWindow {
+			continue
+		}
+		kept = append(kept, t)
+		if t.category == f.Category {
+			series = append(series, t)
+		}
+	}
+	turn := failedTurn{at: now, category: f.Category, request: f.Request}
+	series = append(series, turn)
+	if len(series) < a.threshold {
+		a.failures[f.SessionKey] = append(kept, turn)
+		a.mu.Unlock()
+		return
+	}
+
+	// Start a new series for this category whatever the outcome
+	var rest []failedTurn
+	for _, t := range kept {
+		if t.category != f.Category {
+			rest = append(rest, t)
+		}
+	}
+	a.failures[f.SessionKey] = rest
+	exclude := make(map[string]bool)
+	for name := range a.suggested[f.SessionKey] {
+		exclude[name] = true
+	}
+	a.mu.Unlock()
+
+	requests := make([]string, len(series))
+	for i, t := range series {
+		requests[i] = t.request
+	}
+	go a.analyze(f, requests, exclude)
+}
+
+// analyze looks for a skill covering a series of failures and suggests it.
+func (a *Advisor) analyze(f Failure, requests []string, exclude map[string]bool) {
+	if !a.mgr.IsCached() {
+		return
+	}
+	if len(a.mgr.ListAvailable()) == 0 {
+		if err := a.mgr.DiscoverAvailable(); err != nil {
+			log.Printf("Warning: skill suggestion skipped: %v", err)
+			return
+		}
+	}
+
+	skill := a.Match(f.Category, requests, exclude)
+	if skill == nil {
+		return
+	}
+
+	a.mu.Lock()
+	if a.suggested[f.SessionKey] == nil {
+		a.suggested[f.SessionKey] = make(map[string]bool)
+		a.pending[f.SessionKey] = make(map[string]bool)
+	}
+	if a.suggested[f.SessionKey][skill.Name] {
+		a.mu.Unlock()
+		return
+	}
+	a.suggested[f.SessionKey][skill.Name] = true
+	a.pending[f.SessionKey][skill.Name] = true
+	a.mu.Unlock()
+
+	if a.suggest != nil {
+		a.suggest(Suggestion{Failure: f, Skill: skill, Failures: len(requests)})
+	}
+}
+
+// Match returns the available skill, not installed and not in exclude, that
+// best matches the words of the failed requests and the failure category,
+// or nil if none matches well enough.
+func (a *Advisor) Match(category string, requests []string, exclude map[string]bool) *AvailableSkill {
+	query := keywords(category)
+	for _, r := range requests {
+		for w := range keywords(r) {
+			query[w] = true
+		}
+	}
+
+	var best *AvailableSkill
+	bestScore := 0
+	for _, s := range a.mgr.ListAvailable() {
+		if exclude[s.Name] || a.mgr.IsInstalled(s.Name) {
+			continue
+		}
+		strong := keywords(s.Name + " " + s.Title)
+		weak := keywords(s.Description + " " + s.Category)
+		score := 0
+		for w := range query {
+			switch {
+			case strong[w]:
+				score += 3
+			case weak[w]:
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	if bestScore < minMatchScore {
+		return nil
+	}
+	return best
+}
+
+// HandleReply answers "/install-skill <name>" and "/dismiss-skill <name>"
+// for a skill suggested in the chat. It reports whether content was such a
+// reply.
+func (a *Advisor) HandleReply(ctx context.Context, sessionKey, content string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	fields := strings.Fields(content)
+	if len(fields) != 2 || (fields[0] != "/install-skill" && fields[0] != "/dismiss-skill") {
+		return "", false
+	}
+	name := fields[1]
+
+	a.mu.Lock()
+	offered := a.pending[sessionKey][name]
+	delete(a.pending[sessionKey], name)
+	a.mu.Unlock()
+	if !offered {
+		return fmt.Sprintf("Skill %q was not suggested here, or was already answered.", name), true
+	}
+
+	if fields[0] == "/dismiss-skill" {
+		return fmt.Sprintf("OK, I won't suggest %q again in this chat.", name), true
+	}
+	report, err := a.mgr.InstallWith(ctx, name, InstallOptions{})
+	if err != nil {
+		return fmt.Sprintf("Failed to install skill %q: %v", name, err), true
+	}
+	reply := fmt.Sprintf("Installed skill %q. It is available from your next message.", name)
+	if len(report.Skipped) > 0 {
+		reply += fmt.Sprintf("\nIts setup scripts were not run; run 'ubot skills install %s' on the server to run them in the sandbox.", name)
+	}
+	return reply, true
+}
+
+// FormatSuggestion describes a suggestion for the user.
+func FormatSuggestion(s Suggestion) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "I've struggled with %d similar requests here. The skill %q might help", s.Failures, s.Skill.Name)
+	if s.Skill.Description != "" {
+		fmt.Fprintf(&sb, ": %s", s.Skill.Description)
+	} else {
+		sb.WriteString(".")
+	}
+	sb.WriteString("\n\nInstall it?")
+	return sb.String()
+}
+
+// stopWords are common words that say nothing about a task.
+var stopWords = map[string]bool{
+	"about": true, "after": true, "again": true, "also": true, "could": true,
+	"does": true, "from": true, "have": true, "help": true, "into": true,
+	"just": true, "make": true, "need": true, "please": true, "should": true,
+	"some": true, "that": true, "their": true, "them": true, "then": true,
+	"there": true, "these": true, "this": true, "what": true, "when": true,
+	"where": true, "which": true, "with": true, "would": true, "your": true,
+	"skill": true, "skills": true,
+}
+
+// keywords returns the lower-case words of at least four letters in text,
+// without common words and with a plural "s" removed.
+func keywords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) < 4 || stopWords[w] {
+			continue
+		}
+		if len(w) > 4 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = strings.TrimSuffix(w, "s")
+		}
+		words[w] = true
+	}
+	return words
+}
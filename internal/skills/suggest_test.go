@@ -0,0 +1,111 @@
+package skills
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAdvisorManager returns a Manager whose cache holds a spreadsheet and a
+// calendar skill.
+func newAdvisorManager(t *testing.T) *Manager {
+	t.Helper()
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir, tmpDir)
+	if err := os.MkdirAll(filepath.Join(m.cacheDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for dir, content := range map[string]string{
+		"data/spreadsheets":     "# Spreadsheets\n\nRead, clean and chart CSV and Excel files.\n",
+		"productivity/calendar": "# Calendar\n\nSchedule meetings and find free time.\n",
+	} {
+		path := filepath.Join(m.cacheDir, dir)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "SKILL.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.DiscoverAvailable(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestAdvisorMatch(t *testing.T) {
+	a := NewAdvisor(newAdvisorManager(t), 3, nil)
+
+	requests := []string{"chart the sales in q3.csv", "why can't you read my Excel files?"}
+	if s := a.Match("exec", requests, nil); s == nil || s.Name != "spreadsheets" {
+		t.Errorf("Match = %v, want spreadsheets", s)
+	}
+	if s := a.Match("exec", requests, map[string]bool{"spreadsheets": true}); s != nil {
+		t.Errorf("Match with spreadsheets excluded = %s, want none", s.Name)
+	}
+	if s := a.Match("web_fetch", []string{"what's the weather in Paris?"}, nil); s != nil {
+		t.Errorf("Match for an unrelated request = %s, want none", s.Name)
+	}
+}
+
+func TestAdvisorSuggestsAfterRepeatedFailures(t *testing.T) {
+	m := newAdvisorManager(t)
+	suggestions := make(chan Suggestion, 1)
+	a := NewAdvisor(m, 3, func(s Suggestion) { suggestions <- s })
+
+	fail := func(category, request string) {
+		a.Observe(Failure{SessionKey: "telegram:1", Channel: "telegram", ChatID: "1", Category: category, Request: request})
+	}
+	fail("exec", "chart my spreadsheet")
+	fail("read_file", "open the Excel file")
+	fail("exec", "convert the CSV")
+	select {
+	case s := <-suggestions:
+		t.Fatalf("suggested %s after two failures of one kind", s.Skill.Name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fail("exec", "plot the CSV columns")
+	var s Suggestion
+	select {
+	case s = <-suggestions:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no suggestion after three failures")
+	}
+	if s.Skill.Name != "spreadsheets" || s.Failures != 3 || s.ChatID != "1" {
+		t.Errorf("suggestion = %s after %d failures in chat %s", s.Skill.Name, s.Failures, s.ChatID)
+	}
+	if !strings.Contains(FormatSuggestion(s), "Install it?") {
+		t.Errorf("FormatSuggestion = %q", FormatSuggestion(s))
+	}
+
+	if reply, ok := a.HandleReply(context.Background(), "telegram:2", "/install-skill spreadsheets"); !ok || m.IsInstalled("spreadsheets") {
+		t.Fatalf("another chat installed the skill: %q", reply)
+	}
+	reply, ok := a.HandleReply(context.Background(), "telegram:1", "/install-skill spreadsheets")
+	if !ok || !m.IsInstalled("spreadsheets") {
+		t.Fatalf("HandleReply = %q, %v; skill not installed", reply, ok)
+	}
+	if reply, _ := a.HandleReply(context.Background(), "telegram:1", "/install-skill spreadsheets"); !strings.Contains(reply, "not suggested") {
+		t.Errorf("second answer = %q, want it refused", reply)
+	}
+	if _, ok := a.HandleReply(context.Background(), "telegram:1", "install the spreadsheets skill"); ok {
+		t.Error("HandleReply took an ordinary message")
+	}
+}
+
+func TestAdvisorForgetsOldFailures(t *testing.T) {
+	now := time.Now()
+	a := NewAdvisor(newAdvisorManager(t), 2, func(s Suggestion) {
+		t.Errorf("suggested %s from a stale failure", s.Skill.Name)
+	})
+	a.now = func() time.Time { return now }
+
+	a.Observe(Failure{SessionKey: "s", Category: "exec", Request: "chart my spreadsheet"})
+	now = now.Add(2 * failureWindow)
+	a.Observe(Failure{SessionKey: "s", Category: "exec", Request: "chart my spreadsheet"})
+	time.Sleep(50 * time.Millisecond)
+}
@@ -0,0 +1,239 @@
+package skills
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// versionFile records, in an installed skill's directory, which version of
+// the skill was installed.
+const versionFile = ".ubot-version.json"
+
+// InstalledVersion is the content of versionFile.
+type InstalledVersion struct {
+	Version     string    `json:"version,omitempty"` // from frontmatter
+	Commit      string    `json:"commit,omitempty"`  // last repository commit touching the skill
+	Hash        string    `json:"hash"`              // content hash of the skill directory
+	InstalledAt time.Time `json:"installedAt"`
+}
+
+// Label returns a short name for the version: the frontmatter version, else
+// the commit, else the start of the content hash.
+func (v InstalledVersion) Label() string {
+	switch {
+	case v.Version != "":
+		return v.Version
+	case v.Commit != "":
+		return v.Commit
+	case len(v.Hash) > 7:
+		return v.Hash[:7]
+	}
+	return v.Hash
+}
+
+// SkillStatus compares an installed skill with the copy in the cache.
+type SkillStatus struct {
+	Name      string
+	Installed InstalledVersion // what was installed; zero Hash if not recorded
+	Available InstalledVersion // what the cache has now
+	Outdated  bool             // the cache has a different version
+	Modified  bool             // the installed copy was edited since it was installed
+}
+
+// Status compares the installed skill called name with the cache. Call
+// DiscoverAvailable first.
+func (m *Manager) Status(name string) (*SkillStatus, error) {
+	available := m.GetAvailable(name)
+	if available == nil {
+		return nil, fmt.Errorf("skill %q not found in available skills", name)
+	}
+	if !m.IsInstalled(name) {
+		return nil, fmt.Errorf("skill %q not installed", name)
+	}
+
+	dstDir := filepath.Join(m.workspaceDir, name)
+	current, err := hashDir(dstDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installed skill: %w", err)
+	}
+	latest, err := m.versionOf(available)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &SkillStatus{Name: name, Available: latest}
+	if rec, err := readVersion(dstDir); err == nil {
+		st.Installed = rec
+		st.Modified = current != rec.Hash
+		st.Outdated = latest.Hash != rec.Hash
+	} else {
+		// Installed before versions were recorded: all we can tell is
+		// whether it differs from the cache
+		st.Installed = InstalledVersion{Hash: current}
+		st.Outdated = latest.Hash != current
+	}
+	return st, nil
+}
+
+// Outdated returns the status of each installed skill that differs from the
+// cache. Skills that are not in the repository are left out. Call
+// DiscoverAvailable first.
+func (m *Manager) Outdated() ([]*SkillStatus, error) {
+	installed, err := m.ListInstalled()
+	if err != nil {
+		return nil, err
+	}
+	var outdated []*SkillStatus
+	for _, name := range installed {
+		if m.GetAvailable(name) == nil {
+			continue
+		}
+		st, err := m.Status(name)
+		if err != nil {
+			return nil, err
+		}
+		if st.Outdated {
+			outdated = append(outdated, st)
+		}
+	}
+	return outdated, nil
+}
+
+// Update installs the cached version of an outdated skill. It reports
+// whether the skill was updated. A skill edited since it was installed is
+// only overwritten with force.
+func (m *Manager) Update(name string, force bool) (bool, error) {
+	st, err := m.Status(name)
+	if err != nil {
+		return false, err
+	}
+	if !st.Outdated {
+		return false, nil
+	}
+	if st.Modified && !force {
+		return false, fmt.Errorf("skill %q has local changes; use --force to overwrite them", name)
+	}
+	if err := m.Install(name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateAll updates every outdated skill. It returns a map of the skills
+// that were outdated to errors (nil for success).
+func (m *Manager) UpdateAll(force bool) (map[string]error, error) {
+	outdated, err := m.Outdated()
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]error)
+	for _, st := range outdated {
+		_, results[st.Name] = m.Update(st.Name, force)
+	}
+	return results, nil
+}
+
+// recordVersion writes the version file for a skill just copied to dstDir.
+func (m *Manager) recordVersion(skill *AvailableSkill, dstDir string) error {
+	v, err := m.versionOf(skill)
+	if err != nil {
+		return err
+	}
+	v.InstalledAt = time.Now()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dstDir, versionFile), data, 0644)
+}
+
+// versionOf describes the cached copy of a skill.
+func (m *Manager) versionOf(skill *AvailableSkill) (InstalledVersion, error) {
+	srcDir := filepath.Dir(skill.Path)
+	hash, err := hashDir(srcDir)
+	if err != nil {
+		return InstalledVersion{}, fmt.Errorf("failed to read skill %q: %w", skill.Name, err)
+	}
+	return InstalledVersion{
+		Version: skill.Version,
+		Commit:  m.lastCommit(srcDir),
+		Hash:    hash,
+	}, nil
+}
+
+// lastCommit returns the short hash of the last commit touching dir in the
+// git repository it belongs to, or "" if it is not in one.
+func (m *Manager) lastCommit(dir string) string {
+	m.mu.RLock()
+	repos := m.repos()
+	m.mu.RUnlock()
+
+	for _, r := range repos {
+		rel, err := filepath.Rel(r.root, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		out, err := exec.Command("git", "-C", r.root, "log", "-1", "--format=%h", "--", rel).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	return ""
+}
+
+// readVersion reads the version file of an installed skill.
+func readVersion(dir string) (InstalledVersion, error) {
+	var v InstalledVersion
+	data, err := os.ReadFile(filepath.Join(dir, versionFile))
+	if err != nil {
+		return v, err
+	}
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+// hashDir returns a hash of the files in dir, by relative path and content,
+// leaving out the version file.
+func hashDir(dir string) (string, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && info.Name() != versionFile {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		rel, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
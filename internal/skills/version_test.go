@@ -0,0 +1,124 @@
+package skills
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newCachedManager returns a Manager whose cache holds one skill, roadmap,
+// discovered and installed.
+func newCachedManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir, tmpDir)
+	if err := os.MkdirAll(filepath.Join(m.cacheDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeSkill(t, "Roadmap", m.cacheDir, "product", "roadmap")
+	if err := m.DiscoverAvailable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Install("roadmap"); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	return m, filepath.Join(m.cacheDir, "product", "roadmap", "SKILL.md")
+}
+
+func TestStatusAfterInstallIsCurrent(t *testing.T) {
+	m, _ := newCachedManager(t)
+
+	st, err := m.Status("roadmap")
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if st.Outdated || st.Modified {
+		t.Errorf("fresh install reported outdated=%v modified=%v", st.Outdated, st.Modified)
+	}
+	if st.Installed.Hash == "" || st.Installed.InstalledAt.IsZero() {
+		t.Errorf("install did not record a version: %+v", st.Installed)
+	}
+
+	// The version file must not show up as a change or a skill
+	if outdated, err := m.Outdated(); err != nil || len(outdated) != 0 {
+		t.Errorf("Outdated = %v, %v; want none", outdated, err)
+	}
+}
+
+func TestUpdateInstallsNewVersion(t *testing.T) {
+	m, cached := newCachedManager(t)
+
+	content := "---\nversion: 2.0\n---\n# Roadmap\n\nPlans the next quarter.\n"
+	if err := os.WriteFile(cached, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DiscoverAvailable(); err != nil {
+		t.Fatal(err)
+	}
+
+	outdated, err := m.Outdated()
+	if err != nil || len(outdated) != 1 {
+		t.Fatalf("Outdated = %v, %v; want roadmap", outdated, err)
+	}
+	if got := outdated[0].Available.Label(); got != "2.0" {
+		t.Errorf("available version = %q, want 2.0", got)
+	}
+
+	results, err := m.UpdateAll(false)
+	if err != nil || results["roadmap"] != nil {
+		t.Fatalf("UpdateAll = %v, %v", results, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(m.workspaceDir, "roadmap", "SKILL.md"))
+	if !strings.Contains(string(data), "next quarter") {
+		t.Errorf("installed copy was not updated:\n%s", data)
+	}
+	if st, _ := m.Status("roadmap"); st.Outdated || st.Installed.Version != "2.0" {
+		t.Errorf("after update: outdated=%v version=%q", st.Outdated, st.Installed.Version)
+	}
+}
+
+func TestUpdateKeepsLocalChanges(t *testing.T) {
+	m, cached := newCachedManager(t)
+
+	installed := filepath.Join(m.workspaceDir, "roadmap", "SKILL.md")
+	if err := os.WriteFile(installed, []byte("# Roadmap\n\nMy own notes.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cached, []byte("# Roadmap\n\nUpstream change.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Update("roadmap", false); err == nil {
+		t.Fatal("Update overwrote local changes without force")
+	}
+	data, _ := os.ReadFile(installed)
+	if !strings.Contains(string(data), "My own notes") {
+		t.Error("local changes were lost")
+	}
+
+	if updated, err := m.Update("roadmap", true); err != nil || !updated {
+		t.Fatalf("forced Update = %v, %v", updated, err)
+	}
+	data, _ = os.ReadFile(installed)
+	if !strings.Contains(string(data), "Upstream change") {
+		t.Error("forced update did not install the cached copy")
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]string{
+		"---\nname: x\nversion: \"1.4\"\n---\n# X\n": "1.4",
+		"# X\n\nversion: 3\n":                        "",
+		"---\nname: x\n---\nversion: 3\n":            "",
+	}
+	for content, want := range tests {
+		got := ""
+		if v := parseFrontmatter(strings.Split(content, "\n"))["version"]; len(v) > 0 {
+			got = v[0]
+		}
+		if got != want {
+			t.Errorf("version of %q = %q, want %q", content, got, want)
+		}
+	}
+}
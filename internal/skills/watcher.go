@@ -0,0 +1,93 @@
+package skills
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the skills directory must be quiet before the
+// loader re-discovers, so an editor's save or an install of several files
+// causes one reload.
+const watchDebounce = 500 * time.Millisecond
+
+// Watch re-discovers skills whenever a SKILL.md in the workspace skills
+// directory is added, removed or edited, until ctx is cancelled. The
+// directory is created if it does not exist yet. onReload, if not nil, is
+// called after each reload.
+func (l *Loader) Watch(ctx context.Context, onReload func()) error {
+	if l.workspacePath == "" {
+		return nil
+	}
+	root := filepath.Join(l.workspacePath, "skills")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create skills directory: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch skills: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the skills directory and each skill directory in it
+	if err := watcher.Add(root); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", root, err)
+	}
+	entries, _ := os.ReadDir(root)
+	for _, e := range entries {
+		if e.IsDir() {
+			l.watchDir(watcher, filepath.Join(root, e.Name()))
+		}
+	}
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Dir(event.Name) == root {
+				// A skill directory was added, removed or renamed
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						l.watchDir(watcher, event.Name)
+					}
+				}
+			} else if filepath.Base(event.Name) != "SKILL.md" {
+				continue
+			}
+			reload = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("Warning: skills watcher: %v", err)
+		case <-reload:
+			reload = nil
+			if err := l.Discover(); err != nil {
+				log.Printf("Warning: failed to reload skills: %v", err)
+				continue
+			}
+			log.Printf("Skills reloaded: %d available", l.Count())
+			if onReload != nil {
+				onReload()
+			}
+		}
+	}
+}
+
+// watchDir adds a skill directory to watcher.
+func (l *Loader) watchDir(watcher *fsnotify.Watcher, dir string) {
+	if err := watcher.Add(dir); err != nil {
+		log.Printf("Warning: failed to watch %s: %v", dir, err)
+	}
+}
@@ -0,0 +1,71 @@
+package skills
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or a few seconds pass.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWatchReloadsSkills(t *testing.T) {
+	workspace := t.TempDir()
+	l := NewLoader(workspace)
+	if err := l.Discover(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Watch(ctx, nil) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch: %v", err)
+		}
+	}()
+
+	// Wait for the watcher to create the skills directory
+	root := filepath.Join(workspace, "skills")
+	waitFor(t, "skills directory", func() bool {
+		_, err := os.Stat(root)
+		return err == nil
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	// Added
+	writeSkill(t, "Roadmap", root, "roadmap")
+	waitFor(t, "new skill", func() bool { return l.Get("roadmap") != nil })
+	if !strings.Contains(l.GetSummary(), "roadmap") {
+		t.Errorf("summary does not list the new skill:\n%s", l.GetSummary())
+	}
+
+	// Edited
+	path := filepath.Join(root, "roadmap", "SKILL.md")
+	if err := os.WriteFile(path, []byte("# Roadmap\n\nPlans the next quarter.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "edited skill", func() bool {
+		s := l.Get("roadmap")
+		return s != nil && strings.Contains(s.Content, "next quarter")
+	})
+
+	// Removed
+	if err := os.RemoveAll(filepath.Join(root, "roadmap")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "removed skill", func() bool { return l.Count() == 0 })
+}
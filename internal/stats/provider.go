@@ -0,0 +1,38 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/hkuds/ubot/internal/providers"
+)
+
+// provider records the latency and errors of chat requests per model.
+type provider struct {
+	providers.Provider
+	recorder *Recorder
+}
+
+// WrapProvider returns a Provider that records every chat request made
+// through p under CategoryLLM.
+func WrapProvider(p providers.Provider, r *Recorder) providers.Provider {
+	return &provider{Provider: p, recorder: r}
+}
+
+// Chat forwards the request and records its outcome.
+func (p *provider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	start := time.Now()
+	resp, err := p.Provider.Chat(ctx, req)
+
+	model := req.Model
+	if model == "" {
+		model = p.DefaultModel()
+	}
+	p.recorder.Record(CategoryLLM, model, time.Since(start), err)
+	return resp, err
+}
+
+// Unwrap returns the provider whose requests are recorded.
+func (p *provider) Unwrap() providers.Provider {
+	return p.Provider
+}
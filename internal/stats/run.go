@@ -0,0 +1,39 @@
+package stats
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// checkInterval is how often statistics are saved and the period checked.
+const checkInterval = time.Hour
+
+// Run periodically saves the statistics and, once a period ends, passes its
+// report to deliver. It returns when ctx is done, saving one last time.
+func (r *Recorder) Run(ctx context.Context, deliver func(report string)) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		r.tick(time.Now(), deliver)
+		select {
+		case <-ctx.Done():
+			if err := r.Save(); err != nil {
+				log.Printf("Warning: failed to save usage stats: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick delivers the report of a finished period and saves the statistics.
+func (r *Recorder) tick(now time.Time, deliver func(report string)) {
+	if done, ok := r.Rotate(now); ok && deliver != nil {
+		deliver(done.Report(now))
+	}
+	if err := r.Save(); err != nil {
+		log.Printf("Warning: failed to save usage stats: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+// Package stats aggregates anonymous local usage statistics (tool
+// popularity, error rates and latency) into a weekly report. Only counters
+// and durations are kept — never parameters, message content or chat IDs —
+// and nothing leaves the machine.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Period is the length of one reporting period.
+	Period = 7 * 24 * time.Hour
+	// maxSamples caps the latency samples kept per name for percentiles.
+	maxSamples = 500
+)
+
+// Categories of recorded events.
+const (
+	CategoryTool = "tool" // tool executions
+	CategoryLLM  = "llm"  // provider chat requests, keyed by model
+)
+
+// Counter aggregates the events recorded under one name.
+type Counter struct {
+	Calls   int     `json:"calls"`
+	Errors  int     `json:"errors"`
+	Samples []int64 `json:"samples"` // latencies in milliseconds, most recent last
+}
+
+// Snapshot holds the statistics for one period.
+type Snapshot struct {
+	Start    time.Time                      `json:"start"`
+	Counters map[string]map[string]*Counter `json:"counters"` // category -> name -> counter
+}
+
+// Recorder collects usage statistics and persists them to a local file.
+// The zero value is not usable; create one with NewRecorder.
+type Recorder struct {
+	path string
+
+	mu      sync.Mutex
+	current Snapshot
+	dirty   bool
+}
+
+// NewRecorder creates a Recorder persisting to path, restoring the current
+// period from it if present.
+func NewRecorder(path string) *Recorder {
+	r := &Recorder{path: path}
+	if err := r.load(); err != nil {
+		r.current = newSnapshot(time.Now())
+	}
+	return r
+}
+
+func newSnapshot(start time.Time) Snapshot {
+	return Snapshot{Start: start, Counters: make(map[string]map[string]*Counter)}
+}
+
+// Record adds one event to the current period.
+func (r *Recorder) Record(category, name string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byName, ok := r.current.Counters[category]
+	if !ok {
+		byName = make(map[string]*Counter)
+		r.current.Counters[category] = byName
+	}
+	c, ok := byName[name]
+	if !ok {
+		c = &Counter{}
+		byName[name] = c
+	}
+	c.Calls++
+	if err != nil {
+		c.Errors++
+	}
+	c.Samples = append(c.Samples, d.Milliseconds())
+	if len(c.Samples) > maxSamples {
+		c.Samples = c.Samples[len(c.Samples)-maxSamples:]
+	}
+	r.dirty = true
+}
+
+// ObserveTool records a tool execution. It satisfies tools.Observer.
+func (r *Recorder) ObserveTool(name string, d time.Duration, err error) {
+	r.Record(CategoryTool, name, d, err)
+}
+
+// Snapshot returns a copy of the current period's statistics.
+func (r *Recorder) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current.clone()
+}
+
+// Rotate ends the current period if it is at least Period old, returning
+// its statistics and starting a new one.
+func (r *Recorder) Rotate(now time.Time) (Snapshot, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.current.Start) < Period {
+		return Snapshot{}, false
+	}
+	done := r.current
+	r.current = newSnapshot(now)
+	r.dirty = true
+	return done, true
+}
+
+// Save writes the current period to disk if it changed since the last save.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(r.current)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, data, 0o600); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
+
+func (r *Recorder) load() error {
+	snap, err := Load(r.path)
+	if err != nil {
+		return err
+	}
+	r.current = snap
+	return nil
+}
+
+// Load reads a persisted period from path.
+func Load(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return Snapshot{}, err
+	}
+	if snap.Counters == nil {
+		snap.Counters = make(map[string]map[string]*Counter)
+	}
+	return snap, nil
+}
+
+func (s Snapshot) clone() Snapshot {
+	cp := newSnapshot(s.Start)
+	for category, byName := range s.Counters {
+		m := make(map[string]*Counter, len(byName))
+		for name, c := range byName {
+			cc := *c
+			cc.Samples = append([]int64(nil), c.Samples...)
+			m[name] = &cc
+		}
+		cp.Counters[category] = m
+	}
+	return cp
+}
+
+// Percentile returns the p-th percentile (0-100) of the latency samples.
+func (c *Counter) Percentile(p float64) time.Duration {
+	if len(c.Samples) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), c.Samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(p/100*float64(len(sorted)) + 0.5)
+	if i > 0 {
+		i--
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return time.Duration(sorted[i]) * time.Millisecond
+}
+
+// Report formats the statistics as a plain-text report covering the period
+// from s.Start to end.
+func (s Snapshot) Report(end time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("uBot usage report (%s – %s)\n", s.Start.Format("Jan 2"), end.Format("Jan 2")))
+
+	sections := []struct{ category, title string }{
+		{CategoryTool, "Tools"},
+		{CategoryLLM, "Model requests"},
+	}
+	empty := true
+	for _, sec := range sections {
+		byName := s.Counters[sec.category]
+		if len(byName) == 0 {
+			continue
+		}
+		empty = false
+
+		names := make([]string, 0, len(byName))
+		total, errors := 0, 0
+		for name, c := range byName {
+			names = append(names, name)
+			total += c.Calls
+			errors += c.Errors
+		}
+		// Most used first
+		sort.Slice(names, func(i, j int) bool {
+			ci, cj := byName[names[i]], byName[names[j]]
+			if ci.Calls != cj.Calls {
+				return ci.Calls > cj.Calls
+			}
+			return names[i] < names[j]
+		})
+
+		sb.WriteString(fmt.Sprintf("\n%s: %d calls, %s errors\n", sec.title, total, rate(errors, total)))
+		for _, name := range names {
+			c := byName[name]
+			sb.WriteString(fmt.Sprintf("- %s: %d calls, %s errors, p50 %s, p95 %s\n",
+				name, c.Calls, rate(c.Errors, c.Calls), c.Percentile(50), c.Percentile(95)))
+		}
+	}
+	if empty {
+		sb.WriteString("\nNo activity recorded.\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// rate formats n out of total as a percentage.
+func rate(n, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.0f%%", float64(n)*100/float64(total))
+}
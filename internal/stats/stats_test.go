@@ -0,0 +1,77 @@
+package stats
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorderReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	r := NewRecorder(path)
+
+	for i := 1; i <= 10; i++ {
+		r.ObserveTool("exec", time.Duration(i*100)*time.Millisecond, nil)
+	}
+	r.ObserveTool("read_file", 5*time.Millisecond, errors.New("boom"))
+	r.Record(CategoryLLM, "gpt-4o", 2*time.Second, nil)
+
+	c := r.Snapshot().Counters[CategoryTool]["exec"]
+	if got := c.Percentile(50); got != 500*time.Millisecond {
+		t.Errorf("p50 = %s, want 500ms", got)
+	}
+	if got := c.Percentile(95); got != time.Second {
+		t.Errorf("p95 = %s, want 1s", got)
+	}
+
+	report := r.Snapshot().Report(time.Now())
+	for _, want := range []string{
+		"Tools: 11 calls, 9% errors",
+		"- exec: 10 calls, 0% errors, p50 500ms, p95 1s",
+		"- read_file: 1 calls, 100% errors",
+		"- gpt-4o: 1 calls",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Index(report, "exec") > strings.Index(report, "read_file") {
+		t.Error("most used tool should be listed first")
+	}
+}
+
+func TestRecorderPersistsAndRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	r := NewRecorder(path)
+	r.ObserveTool("exec", time.Millisecond, nil)
+
+	var reports []string
+	deliver := func(report string) { reports = append(reports, report) }
+
+	// Within the period: saved, nothing delivered
+	start := r.Snapshot().Start
+	r.tick(start.Add(time.Hour), deliver)
+	if len(reports) != 0 {
+		t.Fatalf("delivered %d reports before the period ended", len(reports))
+	}
+
+	// A restart keeps the current period
+	r = NewRecorder(path)
+	if got := r.Snapshot().Counters[CategoryTool]["exec"]; got == nil || got.Calls != 1 {
+		t.Fatalf("restored counter = %+v, want 1 call", got)
+	}
+
+	// After a week the report is delivered and a new period starts
+	r.tick(start.Add(Period), deliver)
+	if len(reports) != 1 || !strings.Contains(reports[0], "exec: 1 calls") {
+		t.Fatalf("reports = %q", reports)
+	}
+	if len(r.Snapshot().Counters) != 0 {
+		t.Error("new period should start empty")
+	}
+	if snap, err := Load(path); err != nil || len(snap.Counters) != 0 {
+		t.Errorf("saved period after rotation = %+v, %v", snap, err)
+	}
+}
@@ -0,0 +1,74 @@
+package testenv
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubImage is the image the stubs run in when UBOT_TESTENV=docker.
+const stubImage = "ubot-testenv:latest"
+
+var (
+	buildOnce sync.Once
+	buildErr  error
+)
+
+// buildImage builds the stub image from the Dockerfile next to this file,
+// once per test binary.
+func buildImage() error {
+	buildOnce.Do(func() {
+		_, file, _, _ := runtime.Caller(0)
+		root := filepath.Join(filepath.Dir(file), "..", "..")
+		out, err := exec.Command("docker", "build", "-q", "-t", stubImage,
+			"-f", filepath.Join(root, "internal", "testenv", "Dockerfile"), root).CombinedOutput()
+		if err != nil {
+			buildErr = fmt.Errorf("failed to build %s: %v\n%s", stubImage, err, out)
+		}
+	})
+	return buildErr
+}
+
+// startContainer runs a stub in a container, published on a random
+// loopback port, and returns its URL once it is ready.
+func startContainer(t testing.TB, kind string) string {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Fatalf("UBOT_TESTENV=docker but docker is not installed: %v", err)
+	}
+	if err := buildImage(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("docker", "run", "-d", "--rm", "-p", "127.0.0.1::8080", stubImage, "-kind", kind).Output()
+	if err != nil {
+		t.Fatalf("failed to start %s stub: %v", kind, err)
+	}
+	id := strings.TrimSpace(string(out))
+	t.Cleanup(func() { exec.Command("docker", "rm", "-f", id).Run() })
+
+	out, err = exec.Command("docker", "port", id, "8080/tcp").Output()
+	if err != nil {
+		t.Fatalf("failed to find the port of the %s stub: %v", kind, err)
+	}
+	addr, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	url := "http://" + addr
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c := newControl(url)
+	err = waitFor(ctx, func() (bool, error) {
+		return c.get("/control/health", nil) == nil, nil
+	})
+	if err != nil {
+		logs, _ := exec.Command("docker", "logs", id).CombinedOutput()
+		t.Fatalf("%s stub did not become ready: %v\n%s", kind, err, logs)
+	}
+	return url
+}
@@ -0,0 +1,6 @@
+// Package e2e holds end-to-end suites that build the ubot binary and run
+// it against the testenv stubs. They only build with the e2e tag:
+//
+//	go test -tags e2e ./internal/testenv/e2e/
+//	UBOT_TESTENV=docker go test -tags e2e ./internal/testenv/e2e/
+package e2e
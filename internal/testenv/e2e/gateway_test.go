@@ -0,0 +1,452 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/control"
+	"github.com/hkuds/ubot/internal/testenv"
+)
+
+// The Telegram user the tests chat as; the gateway allows only them.
+const (
+	userID   = 4242
+	username = "tester"
+)
+
+// ubotBin is the ubot binary built for the suite.
+var ubotBin string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "ubot-e2e")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	ubotBin = filepath.Join(dir, "ubot")
+	build := exec.Command("go", "build", "-o", ubotBin, "github.com/hkuds/ubot/cmd/ubot")
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build ubot: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// syncBuffer collects process output written from several goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// gateway is a running 'ubot gateway' with its own home directory.
+type gateway struct {
+	home    string
+	out     *syncBuffer
+	control string // address of the control API
+}
+
+// workspace returns the gateway's workspace directory.
+func (g *gateway) workspace() string {
+	return filepath.Join(g.home, ".ubot", "workspace")
+}
+
+// startGateway runs 'ubot gateway' with args against env's stubs until the
+// test ends.
+func startGateway(t *testing.T, env *testenv.Env, args ...string) *gateway {
+	t.Helper()
+	g := &gateway{home: t.TempDir(), out: &syncBuffer{}}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	g.control = fmt.Sprintf("127.0.0.1:%d", port)
+
+	cfg := map[string]interface{}{
+		"agents": map[string]interface{}{
+			"defaults": map[string]interface{}{
+				"model":              "stub-model",
+				"models":             []string{"other-model"},
+				"maxToolDefinitions": 0,
+			},
+		},
+		"providers": map[string]interface{}{
+			"vllm": map[string]interface{}{"apiKey": "test", "apiBase": env.Ollama.BaseURL()},
+		},
+		"channels": map[string]interface{}{
+			"telegram": map[string]interface{}{
+				"enabled":     true,
+				"token":       "123456:TEST",
+				"allowFrom":   []string{fmt.Sprint(userID)},
+				"apiEndpoint": env.Telegram.APIEndpoint(),
+			},
+		},
+		"mcp": map[string]interface{}{
+			"servers": []interface{}{
+				map[string]interface{}{"name": "fake", "transport": "http", "url": env.MCP.ServerURL()},
+			},
+		},
+		"skills":  map[string]interface{}{"refreshHours": -1, "suggestAfter": -1},
+		"gateway": map[string]interface{}{"host": "127.0.0.1", "port": port, "controlApi": true},
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDir := filepath.Join(g.home, ".ubot")
+	if err := os.MkdirAll(configDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(ubotBin, append([]string{"gateway"}, args...)...)
+	cmd.Env = append(os.Environ(), "HOME="+g.home)
+	cmd.Stdout, cmd.Stderr = g.out, g.out
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start the gateway: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	t.Cleanup(func() {
+		cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-exited:
+		case <-time.After(15 * time.Second):
+			cmd.Process.Kill()
+			<-exited
+			t.Errorf("gateway did not stop on SIGTERM\n%s", g.out)
+		}
+		if t.Failed() {
+			t.Logf("gateway output:\n%s", g.out)
+		}
+	})
+
+	deadline := time.After(30 * time.Second)
+	for !strings.Contains(g.out.String(), "Gateway is running") {
+		select {
+		case err := <-exited:
+			t.Fatalf("gateway exited during startup: %v\n%s", err, g.out)
+		case <-deadline:
+			t.Fatalf("gateway did not start\n%s", g.out)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return g
+}
+
+// chat sends text to the bot as the test user and returns the first new
+// reply that contains want.
+func chat(t *testing.T, env *testenv.Env, text, want string) testenv.SentMessage {
+	t.Helper()
+	return send(t, env, testenv.UserMessage{ChatID: userID, UserID: userID, Username: username, Text: text}, want)
+}
+
+// send sends msg to the bot and returns the first new reply that contains
+// want.
+func send(t *testing.T, env *testenv.Env, msg testenv.UserMessage, want string) testenv.SentMessage {
+	t.Helper()
+	text := msg.Text
+	before, err := env.Telegram.Sent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Telegram.SendMessage(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	reply, err := env.Telegram.WaitForMessage(ctx, func(m testenv.SentMessage) bool {
+		return m.ID > lastID(before) && m.ChatID == userID && strings.Contains(m.Text, want)
+	})
+	if err != nil {
+		sent, _ := env.Telegram.Sent()
+		t.Fatalf("no reply containing %q to %q: %v\nsent: %+v", want, text, err, sent)
+	}
+	return reply
+}
+
+func lastID(sent []testenv.SentMessage) int {
+	if len(sent) == 0 {
+		return 0
+	}
+	return sent[len(sent)-1].ID
+}
+
+func TestGatewayStartup(t *testing.T) {
+	env := testenv.Start(t)
+	g := startGateway(t, env)
+
+	out := g.out.String()
+	for _, want := range []string{`MCP server "fake": connected`, "Channel telegram: enabled", "Provider: vllm"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("startup output lacks %q", want)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	health, err := control.NewClient(g.control, "").Health(ctx)
+	if err != nil || health.Status != control.HealthOK {
+		t.Errorf("health = %+v, %v; want ok", health, err)
+	}
+	for _, name := range []string{"provider", "channels.telegram", "mcp.fake", "cron"} {
+		if health.Checks[name].Status != control.CheckOK {
+			t.Errorf("health check %s = %+v, want ok", name, health.Checks[name])
+		}
+	}
+
+	var calls map[string]int
+	for ctx.Err() == nil {
+		if calls, _ = env.Telegram.Calls(); calls["getMe"] > 0 && calls["getUpdates"] > 0 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Errorf("Bot API calls = %v, want getMe and getUpdates", calls)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	env := testenv.Start(t)
+	startGateway(t, env)
+
+	reply := chat(t, env, "hello there", "echo: hello there")
+
+	// The reply quotes the message, after "typing…" was shown
+	if reply.ReplyTo == 0 || reply.ReplyTo >= reply.ID {
+		t.Errorf("reply quotes message %d, want the user's message", reply.ReplyTo)
+	}
+	if calls, _ := env.Telegram.Calls(); calls["sendChatAction"] == 0 {
+		t.Errorf("Bot API calls = %v, want sendChatAction", calls)
+	}
+
+	reqs, err := env.Ollama.Requests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var turn *testenv.RecordedRequest
+	for i := range reqs {
+		if last, ok := reqs[i].LastMessage("user"); ok && last.Content == "hello there" {
+			turn = &reqs[i]
+			break
+		}
+	}
+	if turn == nil {
+		t.Fatalf("no model request carried the message: %+v", reqs)
+	}
+	if turn.Model != "stub-model" || turn.Messages[0].Role != "system" {
+		t.Errorf("request model = %q, first role = %q", turn.Model, turn.Messages[0].Role)
+	}
+	if !slices.Contains(turn.Tools, "mcp_fake_echo") || !slices.Contains(turn.Tools, "read_file") {
+		t.Errorf("tools offered = %v, want built-in and MCP tools", turn.Tools)
+	}
+
+	// The conversation continues in the same session
+	chat(t, env, "second message", "echo: second message")
+	reqs, _ = env.Ollama.Requests()
+	history := reqs[len(reqs)-1].Messages
+	if !slices.ContainsFunc(history, func(m testenv.RecordedMessage) bool { return m.Content == "echo: hello there" }) {
+		t.Errorf("second turn lacks the first reply: %+v", history)
+	}
+}
+
+func TestToolExecution(t *testing.T) {
+	env := testenv.Start(t)
+	g := startGateway(t, env)
+
+	t.Run("mcp", func(t *testing.T) {
+		chat(t, env, `call mcp_fake_echo {"text":"ping"}`, "Tool result: ping")
+
+		calls, err := env.MCP.Calls()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(calls) != 1 || calls[0].Tool != "echo" || calls[0].Arguments["text"] != "ping" {
+			t.Errorf("MCP calls = %+v", calls)
+		}
+	})
+
+	t.Run("builtin", func(t *testing.T) {
+		path := filepath.Join(g.workspace(), "e2e.txt")
+		args, _ := json.Marshal(map[string]string{"path": path, "content": "written end to end"})
+		chat(t, env, "call write_file "+string(args), "Tool result:")
+
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != "written end to end" {
+			t.Errorf("file = %q, %v", data, err)
+		}
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		args, _ := json.Marshal(map[string]string{"path": filepath.Join(g.home, ".ssh", "id_rsa")})
+		reply := chat(t, env, "call read_file "+string(args), "Tool result:")
+		if !strings.Contains(reply.Text, "Error") {
+			t.Errorf("reading a private key was not refused: %q", reply.Text)
+		}
+	})
+}
+
+func TestConfigReload(t *testing.T) {
+	env := testenv.Start(t)
+	g := startGateway(t, env)
+	chat(t, env, "before the reload", "echo: before the reload")
+
+	path := filepath.Join(g.home, ".ubot", "config.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg["agents"].(map[string]interface{})["defaults"].(map[string]interface{})["model"] = "reloaded-model"
+	if data, err = json.MarshalIndent(cfg, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !strings.Contains(g.out.String(), "Config reloaded (config file changed): agents.defaults") {
+		if time.Now().After(deadline) {
+			t.Fatalf("gateway did not reload the config\n%s", g.out)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	chat(t, env, "after the reload", "echo: after the reload")
+	reqs, err := env.Ollama.Requests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model := reqs[len(reqs)-1].Model; model != "reloaded-model" {
+		t.Errorf("model after reload = %q, want reloaded-model", model)
+	}
+}
+
+func TestBotCommands(t *testing.T) {
+	env := testenv.Start(t)
+	startGateway(t, env)
+
+	chat(t, env, "/help@test_bot", "/model - Choose the model")
+	if calls, _ := env.Telegram.Calls(); calls["setMyCommands"] == 0 {
+		t.Errorf("Bot API calls = %v, want setMyCommands", calls)
+	}
+
+	// Pick a model from the inline keyboard; later turns use it
+	picker := chat(t, env, "/model", "Choose the model")
+	if len(picker.Buttons) != 2 || picker.Buttons[1].Data != "/model other-model" {
+		t.Fatalf("model buttons = %+v", picker.Buttons)
+	}
+	if err := env.Telegram.PressButton(userID, userID, picker.Buttons[1].Data); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := env.Telegram.WaitForMessage(ctx, func(m testenv.SentMessage) bool {
+		return m.ID > picker.ID && strings.Contains(m.Text, "Using other-model")
+	}); err != nil {
+		t.Fatalf("no reply to the button press: %v", err)
+	}
+
+	chat(t, env, "which model?", "echo: which model?")
+	reqs, err := env.Ollama.Requests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model := reqs[len(reqs)-1].Model; model != "other-model" {
+		t.Errorf("model after /model = %q, want other-model", model)
+	}
+
+	chat(t, env, "/jobs", "No jobs are scheduled")
+	chat(t, env, "/reset", "Started a new conversation")
+}
+
+func TestFileExchange(t *testing.T) {
+	env := testenv.Start(t)
+	g := startGateway(t, env)
+
+	// A document the user sends is saved in the chat's files directory and
+	// its path given to the model
+	path := filepath.Join(g.workspace(), "files", fmt.Sprintf("telegram_%d", userID), "numbers.csv")
+	send(t, env, testenv.UserMessage{
+		ChatID: userID, UserID: userID, Username: username, Text: "sum these",
+		File: &testenv.File{Name: "numbers.csv", Content: "a,b\n1,2\n"},
+	}, "[Attached file: "+path+"]")
+	if data, err := os.ReadFile(path); err != nil || string(data) != "a,b\n1,2\n" {
+		t.Errorf("saved file = %q, %v", data, err)
+	}
+
+	// The agent sends a file back with send_file
+	args, _ := json.Marshal(map[string]string{"path": path, "caption": "your numbers"})
+	chat(t, env, "call send_file "+string(args), "Tool result: Sent numbers.csv")
+	sent, err := env.Telegram.Sent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(sent, func(m testenv.SentMessage) bool {
+		return m.Method == "sendDocument" && m.File == "numbers.csv" && m.Text == "your numbers"
+	}) {
+		t.Errorf("no document sent: %+v", sent)
+	}
+}
+
+func TestSupervisedRestart(t *testing.T) {
+	env := testenv.Start(t)
+	g := startGateway(t, env, "--supervised")
+	chat(t, env, "before the restart", "echo: before the restart")
+
+	if err := control.NewClient(g.control, "").Restart(context.Background()); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for strings.Count(g.out.String(), "Gateway is running") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("gateway did not come back\n%s", g.out)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !strings.Contains(g.out.String(), "restarting gateway on request") {
+		t.Errorf("supervisor did not log the restart\n%s", g.out)
+	}
+
+	chat(t, env, "after the restart", "echo: after the restart")
+}
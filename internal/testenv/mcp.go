@@ -0,0 +1,172 @@
+package testenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// MCPCall is a tool call received by the MCP stub.
+type MCPCall struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// mcpTools are the tools the MCP stub offers.
+var mcpTools = []map[string]interface{}{
+	{
+		"name":        "echo",
+		"description": "Echo the given text back",
+		"inputSchema": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"text": map[string]string{"type": "string"}},
+			"required":   []string{"text"},
+		},
+	},
+	{
+		"name":        "add",
+		"description": "Add two numbers",
+		"inputSchema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"a": map[string]string{"type": "number"},
+				"b": map[string]string{"type": "number"},
+			},
+			"required": []string{"a", "b"},
+		},
+	},
+}
+
+// MCPServer is a fake MCP server speaking streamable HTTP at /mcp, with
+// the tools "echo" and "add". GET /control/calls returns the tool calls it
+// received.
+type MCPServer struct {
+	mu       sync.Mutex
+	calls    []MCPCall
+	sessions int
+	mux      *http.ServeMux
+}
+
+// NewMCPServer creates an MCP stub.
+func NewMCPServer() *MCPServer {
+	s := &MCPServer{mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /control/health", handleHealth)
+	s.mux.HandleFunc("GET /control/calls", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		writeJSON(w, append([]MCPCall{}, s.calls...))
+	})
+	s.mux.HandleFunc("/mcp", s.handleMCP)
+	return s
+}
+
+func (s *MCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *MCPServer) handleMCP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		return
+	default:
+		// No stream for server-initiated messages
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID     *json.RawMessage `json:"id"`
+		Method string           `json:"method"`
+		Params json.RawMessage  `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ID == nil {
+		// A notification
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var result interface{}
+	var rpcErr map[string]interface{}
+	switch req.Method {
+	case "initialize":
+		s.mu.Lock()
+		s.sessions++
+		w.Header().Set("Mcp-Session-Id", fmt.Sprintf("testenv-%d", s.sessions))
+		s.mu.Unlock()
+		result = map[string]interface{}{
+			"protocolVersion": "2025-06-18",
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "testenv", "version": "1.0.0"},
+		}
+	case "ping":
+		result = map[string]interface{}{}
+	case "tools/list":
+		result = map[string]interface{}{"tools": mcpTools}
+	case "tools/call":
+		result = s.callTool(req.Params)
+	default:
+		rpcErr = map[string]interface{}{"code": -32601, "message": "method not found: " + req.Method}
+	}
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	writeJSON(w, resp)
+}
+
+// callTool runs a tool call and returns its result.
+func (s *MCPServer) callTool(params json.RawMessage) map[string]interface{} {
+	var call struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	json.Unmarshal(params, &call)
+
+	s.mu.Lock()
+	s.calls = append(s.calls, MCPCall{Tool: call.Name, Arguments: call.Arguments})
+	s.mu.Unlock()
+
+	var text string
+	isError := false
+	switch call.Name {
+	case "echo":
+		text = fmt.Sprint(call.Arguments["text"])
+	case "add":
+		a, _ := call.Arguments["a"].(float64)
+		b, _ := call.Arguments["b"].(float64)
+		text = fmt.Sprint(a + b)
+	default:
+		text, isError = "unknown tool: "+call.Name, true
+	}
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// MCPControl drives an MCP stub.
+type MCPControl struct {
+	control
+}
+
+// ServerURL returns the streamable HTTP endpoint to configure an MCP
+// server with.
+func (c *MCPControl) ServerURL() string {
+	return c.URL + "/mcp"
+}
+
+// Calls returns the tool calls the stub received.
+func (c *MCPControl) Calls() ([]MCPCall, error) {
+	var calls []MCPCall
+	err := c.get("/control/calls", &calls)
+	return calls, err
+}
@@ -0,0 +1,237 @@
+package testenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordedMessage is a message of a request to the Ollama stub.
+type RecordedMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	Name    string `json:"name,omitempty"`
+}
+
+// RecordedRequest is a chat completion request received by the Ollama stub.
+type RecordedRequest struct {
+	Model    string            `json:"model"`
+	Messages []RecordedMessage `json:"messages"`
+	Tools    []string          `json:"tools"` // names of the tools offered
+	Stream   bool              `json:"stream"`
+}
+
+// LastMessage returns the last message of the request with the given role.
+func (r RecordedRequest) LastMessage(role string) (RecordedMessage, bool) {
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		if r.Messages[i].Role == role {
+			return r.Messages[i], true
+		}
+	}
+	return RecordedMessage{}, false
+}
+
+// Ollama is a stand-in for the OpenAI-compatible API of a local Ollama
+// server at /v1. Its replies are scripted by the last message of each
+// request, so tests can steer the agent:
+//
+//   - after a tool result, it answers "Tool result: <content>"
+//   - for "call <tool> <JSON arguments>", it calls that tool
+//   - otherwise it answers "echo: <message>"
+//
+// GET /control/requests returns the requests it received.
+type Ollama struct {
+	mu       sync.Mutex
+	requests []RecordedRequest
+	mux      *http.ServeMux
+}
+
+// NewOllama creates an Ollama stub.
+func NewOllama() *Ollama {
+	o := &Ollama{mux: http.NewServeMux()}
+	o.mux.HandleFunc("GET /control/health", handleHealth)
+	o.mux.HandleFunc("GET /control/requests", func(w http.ResponseWriter, r *http.Request) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		writeJSON(w, append([]RecordedRequest{}, o.requests...))
+	})
+	o.mux.HandleFunc("POST /v1/chat/completions", o.handleChat)
+	o.mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"object": "list", "data": []interface{}{}})
+	})
+	o.mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"models": []interface{}{}})
+	})
+	o.mux.HandleFunc("GET /api/version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"version": "0.0.0-testenv"})
+	})
+	return o
+}
+
+func (o *Ollama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mux.ServeHTTP(w, r)
+}
+
+// ollamaToolCall is a tool call in an OpenAI-compatible response.
+type ollamaToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+func (o *Ollama) handleChat(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+			Name    string          `json:"name"`
+		} `json:"messages"`
+		Tools []struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tools"`
+		Stream bool `json:"stream"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"error":{"message":"invalid request"}}`, http.StatusBadRequest)
+		return
+	}
+
+	req := RecordedRequest{Model: body.Model, Stream: body.Stream, Tools: []string{}}
+	for _, m := range body.Messages {
+		req.Messages = append(req.Messages, RecordedMessage{Role: m.Role, Content: messageText(m.Content), Name: m.Name})
+	}
+	for _, t := range body.Tools {
+		req.Tools = append(req.Tools, t.Function.Name)
+	}
+	o.mu.Lock()
+	o.requests = append(o.requests, req)
+	n := len(o.requests)
+	o.mu.Unlock()
+
+	content, call := scriptedReply(req)
+	var calls []ollamaToolCall
+	finish := "stop"
+	if call != nil {
+		call.ID = fmt.Sprintf("call_%d", n)
+		calls = []ollamaToolCall{*call}
+		finish = "tool_calls"
+	}
+
+	if !body.Stream {
+		writeJSON(w, map[string]interface{}{
+			"id":     fmt.Sprintf("chatcmpl-%d", n),
+			"object": "chat.completion",
+			"model":  body.Model,
+			"choices": []interface{}{map[string]interface{}{
+				"index":         0,
+				"message":       map[string]interface{}{"role": "assistant", "content": content, "tool_calls": calls},
+				"finish_reason": finish,
+			}},
+			"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+		})
+		return
+	}
+
+	// Stream the content a word at a time, then the tool call
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+	event := func(delta map[string]interface{}, finish interface{}) {
+		chunk, _ := json.Marshal(map[string]interface{}{
+			"id":      fmt.Sprintf("chatcmpl-%d", n),
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   body.Model,
+			"choices": []interface{}{map[string]interface{}{"index": 0, "delta": delta, "finish_reason": finish}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	for i, word := range strings.SplitAfter(content, " ") {
+		delta := map[string]interface{}{"content": word}
+		if i == 0 {
+			delta["role"] = "assistant"
+		}
+		event(delta, nil)
+	}
+	if len(calls) > 0 {
+		event(map[string]interface{}{"tool_calls": calls}, nil)
+	}
+	event(map[string]interface{}{}, finish)
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// scriptedReply returns the reply to req: text, or a tool call.
+func scriptedReply(req RecordedRequest) (string, *ollamaToolCall) {
+	if len(req.Messages) == 0 {
+		return "echo: ", nil
+	}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role == "tool" {
+		return "Tool result: " + last.Content, nil
+	}
+
+	text := strings.TrimSpace(last.Content)
+	if rest, ok := strings.CutPrefix(text, "call "); ok {
+		name, args, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		args = strings.TrimSpace(args)
+		if args == "" {
+			args = "{}"
+		}
+		call := &ollamaToolCall{Type: "function"}
+		call.Function.Name = name
+		call.Function.Arguments = args
+		return "", call
+	}
+	return "echo: " + text, nil
+}
+
+// messageText returns the text of a message's content, which is either a
+// string or a list of parts.
+func messageText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	json.Unmarshal(raw, &parts)
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// OllamaControl drives an Ollama stub.
+type OllamaControl struct {
+	control
+}
+
+// BaseURL returns the OpenAI-compatible API URL to configure a provider
+// with.
+func (c *OllamaControl) BaseURL() string {
+	return c.URL + "/v1"
+}
+
+// Requests returns the chat requests the stub received.
+func (c *OllamaControl) Requests() ([]RecordedRequest, error) {
+	var reqs []RecordedRequest
+	err := c.get("/control/requests", &reqs)
+	return reqs, err
+}
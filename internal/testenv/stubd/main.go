@@ -0,0 +1,24 @@
+// Command stubd serves one of the testenv stubs. It is the entry point of
+// the stub image used when end-to-end tests run with UBOT_TESTENV=docker.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/hkuds/ubot/internal/testenv"
+)
+
+func main() {
+	kind := flag.String("kind", "", "stub to serve: telegram, mcp or ollama")
+	addr := flag.String("addr", ":8080", "address to listen on")
+	flag.Parse()
+
+	handler, err := testenv.NewStub(*kind)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Serving the %s stub on %s", *kind, *addr)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
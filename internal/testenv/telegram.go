@@ -0,0 +1,322 @@
+package testenv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPollWait caps how long a getUpdates call waits for updates, so a bot
+// being stopped is not held up by a long poll.
+const maxPollWait = 2 * time.Second
+
+// SentMessage is a message a bot sent through the Telegram stub.
+type SentMessage struct {
+	ID      int      `json:"id"`
+	Method  string   `json:"method"` // e.g. "sendMessage" or "sendDocument"
+	ChatID  int64    `json:"chatId"`
+	Text    string   `json:"text"`
+	Buttons []Button `json:"buttons,omitempty"`
+	ReplyTo int      `json:"replyTo,omitempty"` // ID of the message quoted
+	File    string   `json:"file,omitempty"`    // name of the document or photo sent
+}
+
+// Button is an inline keyboard button of a sent message.
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"data"`
+}
+
+// UserMessage is a message from a user, injected through the control API.
+type UserMessage struct {
+	ChatID   int64  `json:"chatId"`
+	UserID   int64  `json:"userId"`
+	Username string `json:"username,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"` // set for a button press instead of Text
+	File     *File  `json:"file,omitempty"` // a document, captioned with Text
+}
+
+// File is a document a user sends; bots download it with getFile.
+type File struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// TelegramAPI is a fake Telegram Bot API. Bots call it at
+// /bot<token>/<method>. Tests queue user messages with POST
+// /control/messages and read what bots sent from GET /control/sent; GET
+// /control/calls counts the Bot API calls by method. Documents users send
+// are served at /file/bot<token>/<path>.
+type TelegramAPI struct {
+	mu       sync.Mutex
+	updates  []map[string]interface{}
+	files    map[string]File // sent by users, by file ID
+	arrived  chan struct{}   // closed when an update is queued
+	sent     []SentMessage
+	calls    map[string]int
+	nextID   int
+	mux      *http.ServeMux
+	username string
+}
+
+// NewTelegramAPI creates a Telegram stub whose bot is called @test_bot.
+func NewTelegramAPI() *TelegramAPI {
+	api := &TelegramAPI{
+		arrived:  make(chan struct{}),
+		calls:    make(map[string]int),
+		files:    make(map[string]File),
+		mux:      http.NewServeMux(),
+		username: "test_bot",
+	}
+	api.mux.HandleFunc("GET /control/health", handleHealth)
+	api.mux.HandleFunc("POST /control/messages", api.handleUserMessage)
+	api.mux.HandleFunc("GET /control/sent", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		writeJSON(w, append([]SentMessage{}, api.sent...))
+	})
+	api.mux.HandleFunc("GET /control/calls", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		writeJSON(w, api.calls)
+	})
+	api.mux.HandleFunc("GET /file/{token}/{id}", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		f, ok := api.files[r.PathValue("id")]
+		api.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(f.Content))
+	})
+	api.mux.HandleFunc("/", api.handleBotAPI)
+	return api
+}
+
+func (api *TelegramAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mux.ServeHTTP(w, r)
+}
+
+// handleUserMessage queues a message or button press from a user.
+func (api *TelegramAPI) handleUserMessage(w http.ResponseWriter, r *http.Request) {
+	var msg UserMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.nextID++
+	from := map[string]interface{}{"id": msg.UserID, "is_bot": false, "first_name": "Test", "username": msg.Username}
+	message := map[string]interface{}{
+		"message_id": api.nextID,
+		"from":       from,
+		"chat":       map[string]interface{}{"id": msg.ChatID, "type": "private"},
+		"date":       time.Now().Unix(),
+		"text":       msg.Text,
+	}
+	if msg.File != nil {
+		fileID := "file" + strconv.Itoa(api.nextID)
+		api.files[fileID] = *msg.File
+		delete(message, "text")
+		message["caption"] = msg.Text
+		message["document"] = map[string]interface{}{
+			"file_id":        fileID,
+			"file_unique_id": fileID,
+			"file_name":      msg.File.Name,
+			"file_size":      len(msg.File.Content),
+		}
+	}
+	update := map[string]interface{}{"update_id": len(api.updates) + 1}
+	if msg.Data != "" {
+		update["callback_query"] = map[string]interface{}{
+			"id":      strconv.Itoa(api.nextID),
+			"from":    from,
+			"message": message,
+			"data":    msg.Data,
+		}
+	} else {
+		update["message"] = message
+	}
+	api.updates = append(api.updates, update)
+	close(api.arrived)
+	api.arrived = make(chan struct{})
+	writeJSON(w, map[string]interface{}{"updateId": update["update_id"]})
+}
+
+// handleBotAPI answers Bot API calls.
+func (api *TelegramAPI) handleBotAPI(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "bot") {
+		http.NotFound(w, r)
+		return
+	}
+	method := parts[1]
+
+	api.mu.Lock()
+	api.calls[method]++
+	api.mu.Unlock()
+
+	switch method {
+	case "getMe":
+		botResult(w, map[string]interface{}{"id": 1, "is_bot": true, "first_name": "Test Bot", "username": api.username})
+	case "getUpdates":
+		offset, _ := strconv.Atoi(r.FormValue("offset"))
+		timeout, _ := strconv.Atoi(r.FormValue("timeout"))
+		botResult(w, api.waitForUpdates(r.Context(), offset, time.Duration(timeout)*time.Second))
+	case "sendMessage", "sendDocument", "sendPhoto", "editMessageText":
+		botResult(w, api.recordSent(method, r))
+	case "getFile":
+		fileID := r.FormValue("file_id")
+		api.mu.Lock()
+		f, ok := api.files[fileID]
+		api.mu.Unlock()
+		if !ok {
+			writeJSON(w, map[string]interface{}{"ok": false, "error_code": 400, "description": "Bad Request: invalid file_id"})
+			return
+		}
+		botResult(w, map[string]interface{}{"file_id": fileID, "file_unique_id": fileID, "file_size": len(f.Content), "file_path": fileID})
+	default:
+		// sendChatAction, answerCallbackQuery, deleteWebhook and the like
+		botResult(w, true)
+	}
+}
+
+// waitForUpdates returns the updates from offset on, waiting up to wait
+// (at most maxPollWait) for one to arrive.
+func (api *TelegramAPI) waitForUpdates(ctx context.Context, offset int, wait time.Duration) []map[string]interface{} {
+	timer := time.NewTimer(min(wait, maxPollWait))
+	defer timer.Stop()
+	for {
+		api.mu.Lock()
+		var pending []map[string]interface{}
+		for _, u := range api.updates {
+			if u["update_id"].(int) >= offset {
+				pending = append(pending, u)
+			}
+		}
+		arrived := api.arrived
+		api.mu.Unlock()
+		if len(pending) > 0 {
+			return pending
+		}
+
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return []map[string]interface{}{}
+		case <-ctx.Done():
+			return []map[string]interface{}{}
+		}
+	}
+}
+
+// recordSent records a message sent by the bot and returns it as the Bot
+// API does.
+func (api *TelegramAPI) recordSent(method string, r *http.Request) map[string]interface{} {
+	chatID, _ := strconv.ParseInt(r.FormValue("chat_id"), 10, 64)
+	text := r.FormValue("text")
+	if text == "" {
+		text = r.FormValue("caption")
+	}
+
+	var buttons []Button
+	var markup struct {
+		InlineKeyboard [][]struct {
+			Text         string `json:"text"`
+			CallbackData string `json:"callback_data"`
+		} `json:"inline_keyboard"`
+	}
+	if json.Unmarshal([]byte(r.FormValue("reply_markup")), &markup) == nil {
+		for _, row := range markup.InlineKeyboard {
+			for _, b := range row {
+				buttons = append(buttons, Button{Text: b.Text, Data: b.CallbackData})
+			}
+		}
+	}
+
+	replyTo, _ := strconv.Atoi(r.FormValue("reply_to_message_id"))
+	var file string
+	if r.MultipartForm != nil {
+		for _, field := range []string{"document", "photo"} {
+			if fhs := r.MultipartForm.File[field]; len(fhs) > 0 {
+				file = fhs[0].Filename
+			}
+		}
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.nextID++
+	api.sent = append(api.sent, SentMessage{ID: api.nextID, Method: method, ChatID: chatID, Text: text, Buttons: buttons, ReplyTo: replyTo, File: file})
+	return map[string]interface{}{
+		"message_id": api.nextID,
+		"from":       map[string]interface{}{"id": 1, "is_bot": true, "first_name": "Test Bot", "username": api.username},
+		"chat":       map[string]interface{}{"id": chatID, "type": "private"},
+		"date":       time.Now().Unix(),
+		"text":       text,
+	}
+}
+
+// botResult writes a successful Bot API response.
+func botResult(w http.ResponseWriter, result interface{}) {
+	writeJSON(w, map[string]interface{}{"ok": true, "result": result})
+}
+
+// TelegramControl drives a Telegram stub.
+type TelegramControl struct {
+	control
+}
+
+// APIEndpoint returns the Bot API URL format to configure a bot with, as
+// in the channels.telegram.apiEndpoint setting.
+func (c *TelegramControl) APIEndpoint() string {
+	return c.URL + "/bot%s/%s"
+}
+
+// SendMessage delivers a text message from a user to the bot.
+func (c *TelegramControl) SendMessage(msg UserMessage) error {
+	return c.post("/control/messages", msg, nil)
+}
+
+// PressButton delivers a press of the inline button carrying data.
+func (c *TelegramControl) PressButton(chatID, userID int64, data string) error {
+	return c.post("/control/messages", UserMessage{ChatID: chatID, UserID: userID, Data: data}, nil)
+}
+
+// Sent returns the messages the bot has sent.
+func (c *TelegramControl) Sent() ([]SentMessage, error) {
+	var sent []SentMessage
+	err := c.get("/control/sent", &sent)
+	return sent, err
+}
+
+// Calls returns how often the bot called each Bot API method.
+func (c *TelegramControl) Calls() (map[string]int, error) {
+	calls := make(map[string]int)
+	err := c.get("/control/calls", &calls)
+	return calls, err
+}
+
+// WaitForMessage waits until the bot has sent a message matching match.
+func (c *TelegramControl) WaitForMessage(ctx context.Context, match func(SentMessage) bool) (SentMessage, error) {
+	var found SentMessage
+	err := waitFor(ctx, func() (bool, error) {
+		sent, err := c.Sent()
+		for _, m := range sent {
+			if match(m) {
+				found = m
+				return true, nil
+			}
+		}
+		return false, err
+	})
+	return found, err
+}
@@ -0,0 +1,157 @@
+// Package testenv runs fake versions of the services uBot depends on — the
+// Telegram Bot API, an MCP server and an Ollama model server — so that
+// end-to-end tests can drive a real gateway without network access or
+// accounts.
+//
+// Each stub is an HTTP server with a /control API through which tests
+// inject input and inspect what uBot sent. By default the stubs run in the
+// test process; set UBOT_TESTENV=docker to run each in its own container,
+// built from the Dockerfile in this directory. Tests talk to them the same
+// way in both modes.
+package testenv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// Stub kinds.
+const (
+	KindTelegram = "telegram"
+	KindMCP      = "mcp"
+	KindOllama   = "ollama"
+)
+
+// NewStub returns the HTTP handler of the stub of the given kind.
+func NewStub(kind string) (http.Handler, error) {
+	switch kind {
+	case KindTelegram:
+		return NewTelegramAPI(), nil
+	case KindMCP:
+		return NewMCPServer(), nil
+	case KindOllama:
+		return NewOllama(), nil
+	}
+	return nil, fmt.Errorf("unknown stub %q (want %s, %s or %s)", kind, KindTelegram, KindMCP, KindOllama)
+}
+
+// Env is a running set of stubs.
+type Env struct {
+	Telegram *TelegramControl
+	MCP      *MCPControl
+	Ollama   *OllamaControl
+}
+
+// Start runs one stub of each kind for the duration of the test, in
+// containers when UBOT_TESTENV=docker and in-process otherwise.
+func Start(t testing.TB) *Env {
+	t.Helper()
+	start := startInProcess
+	if os.Getenv("UBOT_TESTENV") == "docker" {
+		start = startContainer
+	}
+	return &Env{
+		Telegram: &TelegramControl{control: newControl(start(t, KindTelegram))},
+		MCP:      &MCPControl{control: newControl(start(t, KindMCP))},
+		Ollama:   &OllamaControl{control: newControl(start(t, KindOllama))},
+	}
+}
+
+// startInProcess serves a stub from the test process and returns its URL.
+func startInProcess(t testing.TB, kind string) string {
+	t.Helper()
+	h, err := NewStub(kind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// control is a client for a stub's /control API.
+type control struct {
+	// URL is the base URL of the stub.
+	URL    string
+	client *http.Client
+}
+
+func newControl(url string) control {
+	return control{URL: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// get decodes the JSON response to GET path into v.
+func (c control) get(path string, v interface{}) error {
+	resp, err := c.client.Get(c.URL + path)
+	if err != nil {
+		return fmt.Errorf("testenv: %w", err)
+	}
+	return decodeResponse(resp, v)
+}
+
+// post sends body as JSON to path and decodes the response into v, if v is
+// not nil.
+func (c control) post(path string, body, v interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("testenv: %w", err)
+	}
+	resp, err := c.client.Post(c.URL+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("testenv: %w", err)
+	}
+	return decodeResponse(resp, v)
+}
+
+func decodeResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("testenv: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("testenv: invalid response: %w", err)
+	}
+	return nil
+}
+
+// waitFor polls check every 50ms until it returns true, an error, or ctx
+// is done.
+func waitFor(ctx context.Context, check func() (bool, error)) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		ok, err := check()
+		if err != nil || ok {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("testenv: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleHealth answers the readiness check every stub serves at
+// /control/health.
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]bool{"ok": true})
+}
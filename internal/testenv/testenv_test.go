@@ -0,0 +1,115 @@
+package testenv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/hkuds/ubot/internal/mcp"
+	"github.com/hkuds/ubot/internal/providers"
+)
+
+func TestTelegramStub(t *testing.T) {
+	env := Start(t)
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint("123:TEST", env.Telegram.APIEndpoint())
+	if err != nil {
+		t.Fatalf("NewBotAPI: %v", err)
+	}
+	if bot.Self.UserName != "test_bot" {
+		t.Errorf("bot username = %q", bot.Self.UserName)
+	}
+
+	if err := env.Telegram.SendMessage(UserMessage{ChatID: 42, UserID: 42, Username: "tester", Text: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	updates, err := bot.GetUpdates(tgbotapi.UpdateConfig{Timeout: 1})
+	if err != nil {
+		t.Fatalf("GetUpdates: %v", err)
+	}
+	if len(updates) != 1 || updates[0].Message.Text != "hello" || updates[0].Message.From.UserName != "tester" {
+		t.Fatalf("updates = %+v", updates)
+	}
+
+	msg := tgbotapi.NewMessage(42, "hi there")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Approve", "/approve 1")))
+	if _, err := bot.Send(msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sent, err := env.Telegram.WaitForMessage(ctx, func(m SentMessage) bool { return m.ChatID == 42 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.Text != "hi there" || len(sent.Buttons) != 1 || sent.Buttons[0].Data != "/approve 1" {
+		t.Errorf("sent = %+v", sent)
+	}
+}
+
+func TestOllamaStub(t *testing.T) {
+	env := Start(t)
+	p := providers.NewOpenAIProvider("vllm", "", env.Ollama.BaseURL(), "stub-model")
+	ctx := context.Background()
+
+	resp, err := p.Chat(ctx, providers.ChatRequest{Messages: []providers.ChatMessage{{Role: "user", Content: "hello"}}})
+	if err != nil || resp.Content != "echo: hello" {
+		t.Fatalf("Chat = %+v, %v; want an echo", resp, err)
+	}
+
+	messages := []providers.ChatMessage{{Role: "user", Content: `call add {"a":1,"b":2}`}}
+	var streamed string
+	resp, err = p.ChatStream(ctx, providers.ChatRequest{Messages: messages}, func(text string) { streamed += text })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "add" || resp.ToolCalls[0].Arguments["b"] != 2.0 {
+		t.Fatalf("tool calls = %+v", resp.ToolCalls)
+	}
+
+	messages = append(messages,
+		providers.ChatMessage{Role: "assistant", ToolCalls: resp.ToolCalls},
+		providers.ChatMessage{Role: "tool", Content: "3", ToolCallID: resp.ToolCalls[0].ID, Name: "add"})
+	resp, err = p.ChatStream(ctx, providers.ChatRequest{Messages: messages}, func(text string) { streamed += text })
+	if err != nil || resp.Content != "Tool result: 3" || streamed != "Tool result: 3" {
+		t.Fatalf("reply to tool result = %+v (streamed %q), %v", resp, streamed, err)
+	}
+
+	reqs, err := env.Ollama.Requests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 3 || reqs[0].Model != "stub-model" || !reqs[1].Stream {
+		t.Errorf("requests = %+v", reqs)
+	}
+}
+
+func TestMCPStub(t *testing.T) {
+	env := Start(t)
+	client := mcp.NewClient(mcp.Server{Name: "fake", URL: env.MCP.ServerURL(), Transport: "http"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Disconnect()
+
+	tools, err := client.ListTools(ctx)
+	if err != nil || len(tools) != 2 {
+		t.Fatalf("ListTools = %+v, %v", tools, err)
+	}
+	result, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "ping"})
+	if err != nil || result != "ping" {
+		t.Fatalf("CallTool = %v, %v", result, err)
+	}
+
+	calls, err := env.MCP.Calls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0].Tool != "echo" || calls[0].Arguments["text"] != "ping" {
+		t.Errorf("calls = %+v", calls)
+	}
+}
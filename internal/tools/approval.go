@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/failure"
+	"github.com/hkuds/ubot/internal/watch"
+)
+
+// Tool execution policies.
+const (
+	PolicyAuto = "auto" // run without asking
+	PolicyAsk  = "ask"  // ask the user and run only after approval
+	PolicyDeny = "deny" // never run
+)
+
+// maxApprovalValue caps how much of each parameter is shown in a prompt.
+const maxApprovalValue = 300
+
+// ErrToolDenied is returned when a tool call is blocked by policy or
+// rejected by the user.
+type ErrToolDenied struct {
+	Name   string
+	Reason string
+}
+
+func (e ErrToolDenied) Error() string {
+	return fmt.Sprintf("tool %s was not executed: %s", e.Name, e.Reason)
+}
+
+// Category marks the error as a blocked tool call.
+func (e ErrToolDenied) Category() failure.Category {
+	return failure.ToolBlocked
+}
+
+// ApprovalRequest describes a tool call awaiting the user's decision.
+type ApprovalRequest struct {
+	Tool   string
+	Params map[string]interface{}
+	Reason string // why approval is needed when the tool usually runs without it
+}
+
+// Prompt returns a human-readable question asking to approve the call.
+func (r ApprovalRequest) Prompt() string {
+	if r.Reason != "" {
+		return fmt.Sprintf("Allow %s(%s)? Asking because %s.", r.Tool, describeParams(r.Params), r.Reason)
+	}
+	return fmt.Sprintf("Allow %s(%s)?", r.Tool, describeParams(r.Params))
+}
+
+// Approver asks the user whether a tool call may run.
+type Approver interface {
+	Approve(ctx context.Context, req ApprovalRequest) (bool, error)
+}
+
+type approverKey struct{}
+
+// WithApprover returns a context whose tool calls are confirmed through a.
+func WithApprover(ctx context.Context, a Approver) context.Context {
+	return context.WithValue(ctx, approverKey{}, a)
+}
+
+// ApproverFromContext returns the Approver attached to ctx, if any.
+func ApproverFromContext(ctx context.Context) (Approver, bool) {
+	a, ok := ctx.Value(approverKey{}).(Approver)
+	return a, ok
+}
+
+// describeParams formats params as key=value pairs in key order, shortening
+// long values.
+func describeParams(params map[string]interface{}) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		var v string
+		if s, ok := params[k].(string); ok {
+			v = s
+		} else {
+			data, _ := json.Marshal(params[k])
+			v = string(data)
+		}
+		if r := []rune(v); len(r) > maxApprovalValue {
+			v = string(r[:maxApprovalValue]) + fmt.Sprintf("... [%d chars]", len(r))
+		}
+		parts = append(parts, fmt.Sprintf("%s=%q", k, v))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ChatApprovals asks for approval in the conversation a tool call came from
+// and resolves it when the user replies with /approve or /deny (sent by
+// inline buttons where the channel supports them).
+type ChatApprovals struct {
+	timeout time.Duration
+	send    func(req RequestInfo, id, prompt string)
+
+	mu      sync.Mutex
+	pending map[string]*pendingApproval
+}
+
+type pendingApproval struct {
+	sessionKey string
+	requester  string // "" when anyone in the conversation may answer
+	decision   chan bool
+}
+
+// NewChatApprovals creates a ChatApprovals that delivers prompts with send
+// and denies calls left unanswered for timeout.
+func NewChatApprovals(timeout time.Duration, send func(req RequestInfo, id, prompt string)) *ChatApprovals {
+	return &ChatApprovals{
+		timeout: timeout,
+		send:    send,
+		pending: make(map[string]*pendingApproval),
+	}
+}
+
+// Approve sends the prompt to the originating conversation and waits for
+// the user's answer.
+func (a *ChatApprovals) Approve(ctx context.Context, req ApprovalRequest) (bool, error) {
+	info, ok := RequestFromContext(ctx)
+	if !ok || info.SessionKey == "" {
+		return false, fmt.Errorf("no conversation to ask for approval")
+	}
+
+	id, err := newApprovalID()
+	if err != nil {
+		return false, err
+	}
+	// In a group, only the person whose message led to the call may answer;
+	// calls for a cron job or a watch are answered by anyone in the chat
+	requester := info.SenderID
+	if requester == watch.Sender {
+		requester = ""
+	}
+	p := &pendingApproval{sessionKey: info.SessionKey, requester: requester, decision: make(chan bool, 1)}
+	a.mu.Lock()
+	a.pending[id] = p
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.pending, id)
+		a.mu.Unlock()
+	}()
+
+	a.send(info, id, fmt.Sprintf("%s\nReply /approve %s or /deny %s.", req.Prompt(), id, id))
+
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+	select {
+	case approved := <-p.decision:
+		return approved, nil
+	case <-timer.C:
+		return false, fmt.Errorf("no answer within %s", a.timeout)
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// HandleReply resolves a pending approval if input, sent by senderID, is an
+// /approve or /deny command. Only the conversation that was asked can
+// answer, and within it only the sender whose message led to the call. It
+// returns the reply to show and whether input was such a command.
+func (a *ChatApprovals) HandleReply(sessionKey, senderID, input string) (string, bool) {
+	if !IsApprovalReply(input) {
+		return "", false
+	}
+	fields := strings.Fields(input)
+	if len(fields) != 2 {
+		return fmt.Sprintf("Usage: %s <id>", fields[0]), true
+	}
+	approved := fields[0] == "/approve"
+	id := fields[1]
+
+	a.mu.Lock()
+	p, ok := a.pending[id]
+	ok = ok && p.sessionKey == sessionKey
+	mine := ok && (p.requester == "" || p.requester == senderID)
+	if mine {
+		delete(a.pending, id)
+	}
+	a.mu.Unlock()
+	if !ok {
+		return fmt.Sprintf("No pending approval %s (it may have expired).", id), true
+	}
+	if !mine {
+		return fmt.Sprintf("Only the person who asked can answer approval %s.", id), true
+	}
+
+	p.decision <- approved
+	if approved {
+		return "Approved.", true
+	}
+	return "Denied.", true
+}
+
+// IsApprovalReply reports whether input is an /approve or /deny command.
+// These answer a turn that is waiting for them, so they must not wait for
+// that turn to finish.
+func IsApprovalReply(input string) bool {
+	fields := strings.Fields(input)
+	return len(fields) > 0 && (fields[0] == "/approve" || fields[0] == "/deny")
+}
+
+// newApprovalID returns a short random identifier for an approval.
+func newApprovalID() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate approval id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
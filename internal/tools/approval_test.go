@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hkuds/ubot/internal/watch"
+)
+
+// approverFunc adapts a function to the Approver interface.
+type approverFunc func(ctx context.Context, req ApprovalRequest) (bool, error)
+
+func (f approverFunc) Approve(ctx context.Context, req ApprovalRequest) (bool, error) {
+	return f(ctx, req)
+}
+
+func TestSecureRegistry_ApprovalPolicy(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(NewListDirTool())
+	reg.Register(NewRequestToolTool())
+	reg.Register(NewWebFetchTool(1000))
+	secure := NewSecureRegistry(reg)
+	secure.SetApprovalPolicy(PolicyAuto, map[string]string{
+		"list_dir":  PolicyAsk,
+		"web_fetch": PolicyDeny,
+	})
+
+	dir := t.TempDir()
+	params := map[string]interface{}{"path": dir}
+
+	var denied ErrToolDenied
+	if _, err := secure.Execute(context.Background(), "web_fetch", map[string]interface{}{"url": "https://example.com"}); !errors.As(err, &denied) {
+		t.Errorf("deny policy: err = %v, want ErrToolDenied", err)
+	}
+
+	// Without an approver, "ask" tools are refused
+	if _, err := secure.Execute(context.Background(), "list_dir", params); !errors.As(err, &denied) {
+		t.Errorf("ask without approver: err = %v, want ErrToolDenied", err)
+	}
+
+	var asked []ApprovalRequest
+	answer := false
+	ctx := WithApprover(context.Background(), approverFunc(func(ctx context.Context, req ApprovalRequest) (bool, error) {
+		asked = append(asked, req)
+		return answer, nil
+	}))
+
+	if _, err := secure.Execute(ctx, "list_dir", params); !errors.As(err, &denied) || denied.Reason != "denied by the user" {
+		t.Errorf("rejected call: err = %v", err)
+	}
+	answer = true
+	if _, err := secure.Execute(ctx, "list_dir", params); err != nil {
+		t.Errorf("approved call: %v", err)
+	}
+	if len(asked) != 2 || asked[0].Tool != "list_dir" || !strings.Contains(asked[0].Prompt(), dir) {
+		t.Errorf("approval requests = %+v", asked)
+	}
+
+	// Auto tools never ask
+	if _, err := secure.Execute(ctx, "request_tool", map[string]interface{}{"query": "x"}); err != nil {
+		t.Errorf("auto tool: %v", err)
+	}
+	if len(asked) != 2 {
+		t.Errorf("auto tool asked for approval")
+	}
+}
+
+func TestChatApprovals(t *testing.T) {
+	prompts := make(chan string, 1)
+	approvals := NewChatApprovals(time.Second, func(req RequestInfo, id, prompt string) {
+		if req.ChatID != "42" {
+			t.Errorf("prompt sent to chat %q, want 42", req.ChatID)
+		}
+		prompts <- id
+	})
+
+	ctx := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "42", SenderID: "100|alice", SessionKey: "telegram:42"})
+	result := make(chan bool, 1)
+	go func() {
+		approved, err := approvals.Approve(ctx, ApprovalRequest{Tool: "exec", Params: map[string]interface{}{"command": "ls"}})
+		if err != nil {
+			t.Errorf("Approve: %v", err)
+		}
+		result <- approved
+	}()
+	id := <-prompts
+
+	if _, ok := approvals.HandleReply("telegram:42", "100|alice", "hello"); ok {
+		t.Error("ordinary message handled as an approval reply")
+	}
+	// Another conversation cannot answer
+	if reply, ok := approvals.HandleReply("telegram:7", "100|alice", "/approve "+id); !ok || !strings.Contains(reply, "No pending approval") {
+		t.Errorf("reply from other chat = %q, %v", reply, ok)
+	}
+	// Nor can another member of a group
+	if reply, ok := approvals.HandleReply("telegram:42", "200|mallory", "/approve "+id); !ok || !strings.Contains(reply, "Only the person who asked") {
+		t.Errorf("reply from another member = %q, %v", reply, ok)
+	}
+	if reply, ok := approvals.HandleReply("telegram:42", "100|alice", "/approve "+id); !ok || reply != "Approved." {
+		t.Errorf("reply = %q, %v", reply, ok)
+	}
+	if !<-result {
+		t.Error("call should be approved")
+	}
+
+	// Calls for a watch can be answered by anyone in the chat
+	watchCtx := WithRequest(ctx, RequestInfo{Channel: "telegram", ChatID: "42", SenderID: watch.Sender, SessionKey: "telegram:42"})
+	go func() {
+		approved, _ := approvals.Approve(watchCtx, ApprovalRequest{Tool: "exec"})
+		result <- approved
+	}()
+	id = <-prompts
+	if reply, _ := approvals.HandleReply("telegram:42", "200|mallory", "/deny "+id); reply != "Denied." {
+		t.Errorf("reply to a watch's call = %q", reply)
+	}
+	if <-result {
+		t.Error("call should be denied")
+	}
+
+	// Unanswered requests are denied after the timeout
+	approvals = NewChatApprovals(10*time.Millisecond, func(RequestInfo, string, string) {})
+	if approved, err := approvals.Approve(ctx, ApprovalRequest{Tool: "exec"}); approved || err == nil {
+		t.Errorf("timed out approval = %v, %v", approved, err)
+	}
+}
+
+func TestDescribeParamsCutsByRunes(t *testing.T) {
+	got := describeParams(map[string]interface{}{"content": strings.Repeat("я", maxApprovalValue+10)})
+	want := fmt.Sprintf("content=%q", strings.Repeat("я", maxApprovalValue)+fmt.Sprintf("... [%d chars]", maxApprovalValue+10))
+	if got != want {
+		t.Errorf("describeParams = %s, want %s", got, want)
+	}
+	if !utf8.ValidString(got) {
+		t.Error("describeParams split a character")
+	}
+}
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArtifactScheme prefixes references to stored tool results, such as
+// artifact://res_1a2b3c4d5e6f708192a3. A tool parameter set to a reference
+// receives the stored result in full, so a large page fetched with
+// web_fetch reaches write_file without the model repeating it.
+const ArtifactScheme = "artifact://"
+
+// artifactTagLen is the length of the part of a handle naming the
+// conversation that owns it.
+const artifactTagLen = 8
+
+// ArtifactDropper is implemented by overflow stores that can remove the
+// results of one conversation at once. Other stores keep them until they
+// expire.
+type ArtifactDropper interface {
+	// DeletePrefix removes every result whose key starts with prefix.
+	DeletePrefix(prefix string) error
+}
+
+// artifactTag returns the hex tag that handles of results stored for the
+// conversation with sessionKey start with.
+func artifactTag(sessionKey string) string {
+	sum := sha256.Sum256([]byte(sessionKey))
+	return hex.EncodeToString(sum[:])[:artifactTagLen]
+}
+
+// sessionKeyOf returns the session key of the conversation ctx belongs to,
+// or "" outside of one.
+func sessionKeyOf(ctx context.Context) string {
+	info, _ := RequestFromContext(ctx)
+	return info.SessionKey
+}
+
+// ownsHandle reports whether handle names a result stored for the
+// conversation ctx belongs to. Conversations cannot read each other's
+// results.
+func ownsHandle(ctx context.Context, handle string) bool {
+	return strings.HasPrefix(handle, resultHandlePrefix+artifactTag(sessionKeyOf(ctx)))
+}
+
+// validHandle reports whether handle is well formed, so it is safe to use
+// as a file name or key.
+func validHandle(handle string) bool {
+	return strings.HasPrefix(handle, resultHandlePrefix) && !strings.ContainsAny(handle, `/\.`)
+}
+
+// artifactHandle returns the handle s refers to if s is an artifact
+// reference.
+func artifactHandle(s string) (string, bool) {
+	handle, ok := strings.CutPrefix(strings.TrimSpace(s), ArtifactScheme)
+	return handle, ok && handle != ""
+}
+
+// resolveArtifacts returns params with every string value that is an
+// artifact reference, also inside objects and arrays, replaced by the
+// stored result. params itself is left as is.
+func (s *SecureRegistry) resolveArtifacts(ctx context.Context, params map[string]interface{}) (map[string]interface{}, error) {
+	if s.overflowStore == nil || !hasArtifactRef(params) {
+		return params, nil
+	}
+	resolved, err := s.resolveValue(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]interface{}), nil
+}
+
+// resolveValue resolves the artifact references in v.
+func (s *SecureRegistry) resolveValue(ctx context.Context, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		handle, ok := artifactHandle(v)
+		if !ok {
+			return v, nil
+		}
+		if !validHandle(handle) || !ownsHandle(ctx, handle) {
+			return nil, fmt.Errorf("unknown artifact %s%s", ArtifactScheme, handle)
+		}
+		data, err := s.overflowStore.Load(handle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load artifact %s%s: %w", ArtifactScheme, handle, err)
+		}
+		if data == nil {
+			return nil, fmt.Errorf("unknown artifact %s%s (it may have expired)", ArtifactScheme, handle)
+		}
+		return string(data), nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			r, err := s.resolveValue(ctx, val)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			r, err := s.resolveValue(ctx, val)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// hasArtifactRef reports whether v holds an artifact reference anywhere.
+func hasArtifactRef(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		_, ok := artifactHandle(v)
+		return ok
+	case map[string]interface{}:
+		for _, val := range v {
+			if hasArtifactRef(val) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, val := range v {
+			if hasArtifactRef(val) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DropArtifacts removes the results stored for the conversation with
+// sessionKey, e.g. when its history is cleared. Stores that cannot do so
+// keep them until they expire.
+func (s *SecureRegistry) DropArtifacts(sessionKey string) error {
+	dropper, ok := s.overflowStore.(ArtifactDropper)
+	if !ok {
+		return nil
+	}
+	return dropper.DeletePrefix(resultHandlePrefix + artifactTag(sessionKey))
+}
+
+// DeletePrefix removes the results whose keys start with prefix.
+func (s *DirStore) DeletePrefix(prefix string) error {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) {
+			if err := os.Remove(filepath.Join(s.dir, e.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
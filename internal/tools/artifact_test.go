@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("line of output\n", 500) // 7500 chars
+	if err := os.WriteFile(filepath.Join(dir, "big.txt"), []byte(big), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewDirStore(filepath.Join(dir, "results"), time.Hour)
+	reg := NewRegistry()
+	reg.Register(NewReadFileTool())
+	reg.Register(NewWriteFileTool())
+	reg.Register(NewFetchMoreTool(store))
+	secure := NewSecureRegistry(reg)
+	secure.SetOverflow(store, 5000)
+
+	alice := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1"})
+	bob := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "2", SessionKey: "telegram:2"})
+
+	stub, err := secure.Execute(alice, "read_file", map[string]interface{}{"path": filepath.Join(dir, "big.txt")})
+	if err != nil {
+		t.Fatalf("read_file: %v", err)
+	}
+	ref := regexp.MustCompile(`artifact://res_[0-9a-f]+`).FindString(stub)
+	if ref == "" {
+		t.Fatalf("stub has no artifact reference: %q", stub[:300])
+	}
+
+	// The reference passes the whole result to another tool
+	copied := filepath.Join(dir, "copy.txt")
+	if _, err := secure.Execute(alice, "write_file", map[string]interface{}{"path": copied, "content": ref}); err != nil {
+		t.Fatalf("write_file: %v", err)
+	}
+	if data, _ := os.ReadFile(copied); string(data) != big {
+		t.Errorf("copy has %d chars, want the original %d", len(data), len(big))
+	}
+	if part, err := secure.Execute(alice, FetchMoreName, map[string]interface{}{"token": ref, "limit": float64(10)}); err != nil || !strings.HasPrefix(part, big[:10]) {
+		t.Errorf("fetch_more(%s) = %q, %v", ref, part, err)
+	}
+
+	// Other conversations cannot use it
+	if _, err := secure.Execute(bob, "write_file", map[string]interface{}{"path": filepath.Join(dir, "stolen.txt"), "content": ref}); err == nil {
+		t.Error("another conversation resolved the artifact")
+	}
+	if _, err := secure.Execute(bob, FetchMoreName, map[string]interface{}{"token": ref}); err == nil {
+		t.Error("another conversation fetched the artifact")
+	}
+
+	// Clearing the conversation drops its artifacts
+	if err := secure.DropArtifacts("telegram:2"); err != nil {
+		t.Fatalf("DropArtifacts: %v", err)
+	}
+	if _, err := secure.Execute(alice, FetchMoreName, map[string]interface{}{"token": ref}); err != nil {
+		t.Errorf("dropping another conversation's artifacts removed this one: %v", err)
+	}
+	if err := secure.DropArtifacts("telegram:1"); err != nil {
+		t.Fatalf("DropArtifacts: %v", err)
+	}
+	if _, err := secure.Execute(alice, "write_file", map[string]interface{}{"path": copied, "content": ref}); err == nil || !strings.Contains(err.Error(), "unknown artifact") {
+		t.Errorf("dropped artifact resolved: %v", err)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !lite && !nobrowser
+
 // Package tools provides browser automation via headless Chrome/Chromium.
 package tools
 
@@ -5,6 +7,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -18,12 +21,14 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/hkuds/ubot/internal/bus"
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/safenet"
 )
 
 const (
 	browserActionTimeout   = 30 * time.Second
-	maxBrowserContentChars = 50000
+	maxBrowserContentChars = MaxResultChars
 )
 
 // Common desktop User-Agent strings for stealth rotation.
@@ -73,6 +78,32 @@ type browserInstance struct {
 	sessionName string // empty = temp dir (no persistence)
 	userDataDir string // path to user-data-dir (temp or persistent)
 	userAgent   string // user-agent used for this instance
+
+	// Tabs, guarded by mu; actions work on the active one
+	tabs      []*browserTab
+	activeTab int
+	nextTab   int
+}
+
+// ImageDescriber turns an image into text, e.g. using a vision-capable model.
+type ImageDescriber interface {
+	DescribeImage(ctx context.Context, image []byte, mimeType, prompt string) (string, error)
+}
+
+// screenshotPrompt asks the vision model for a description a text-only model
+// can act on.
+const screenshotPrompt = `This is a screenshot of a web page. Describe it for an assistant that cannot see images:
+1. One or two sentences on what the page is and its current state (e.g. logged in, error shown, form filled).
+2. An element map: list the visible interactive elements (buttons, links, inputs, menus) with their visible label or placeholder and approximate position (top/middle/bottom, left/center/right).
+3. Any visible error messages, dialogs, or captchas.
+Be concise and factual.`
+
+// browserActions lists every action of the browser tool.
+var browserActions = []string{
+	"browse_page", "click_element", "type_text", "fill_form", "extract_text", "screenshot",
+	"wait_for_selector", "wait_for_navigation", "go_back",
+	"new_tab", "switch_tab", "close_tab", "download",
+	"list_sessions", "delete_session", "export_cookies", "import_cookies",
 }
 
 // BrowserTool provides browser automation capabilities using headless Chrome.
@@ -81,6 +112,11 @@ type BrowserTool struct {
 	browser    *browserInstance
 	mu         sync.Mutex
 	browserCfg config.BrowserConfig
+	workspace  string // downloads are saved below it
+	describer  ImageDescriber
+	send       func(bus.OutboundMessage) // delivers screenshots to the chat; nil = not sent
+	allowed    map[string]bool           // permitted actions; nil = all
+	polite     *Politeness               // limits shared with web_fetch
 }
 
 // NewBrowserTool creates a new BrowserTool with the given config.
@@ -91,24 +127,63 @@ func NewBrowserTool(cfg config.BrowserConfig) *BrowserTool {
 			"action": map[string]interface{}{
 				"type":        "string",
 				"description": "Browser action to perform",
-				"enum":        []string{"browse_page", "click_element", "type_text", "extract_text", "screenshot", "list_sessions", "delete_session"},
+				"enum":        browserActions,
 			},
 			"url": map[string]interface{}{
 				"type":        "string",
-				"description": "URL to navigate to (required for browse_page)",
+				"description": "URL to navigate to (required for browse_page, optional for new_tab). For download: the file to download, when not clicking selector",
 			},
 			"selector": map[string]interface{}{
 				"type":        "string",
-				"description": "CSS selector for the target element (required for click_element, type_text, extract_text)",
+				"description": "CSS selector for the target element (required for click_element, type_text, extract_text, wait_for_selector). For download: the link or button that starts the download. For screenshot: capture only this element. For fill_form: the button that submits the form, if not its own submit button",
 			},
 			"text": map[string]interface{}{
 				"type":        "string",
-				"description": "Text to type into the element (required for type_text)",
+				"description": "Text to type into the element (required for type_text). For import_cookies: the cookies themselves, when not given as path",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "For import_cookies: file in the workspace with the cookies, e.g. one the user sent in the chat",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"json", "netscape"},
+				"description": "For export_cookies: json (default) or netscape (cookies.txt). import_cookies detects the format",
+			},
+			"fields": map[string]interface{}{
+				"type":                 "object",
+				"description":          "For fill_form: CSS selector → value of each field. Text fields are typed into, checkboxes and radio buttons take true/false, selects take an option's value or label",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"submit": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For fill_form: submit the form once filled. Default: true",
+			},
+			"tab": map[string]interface{}{
+				"type":        "integer",
+				"description": "Tab number as listed by new_tab (required for switch_tab; close_tab closes the active tab without it)",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "integer",
+				"description": "Seconds wait_for_selector and wait_for_navigation wait (default 10, max 30), or download waits for the file (default 60, max 300)",
+			},
+			"extract_mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"page", "article"},
+				"description": "For browse_page: 'article' returns only the main article of a news or blog page, with its author and date, instead of the page's text and interactive elements. Default: page",
 			},
 			"session": map[string]interface{}{
 				"type":        "string",
 				"description": "Named browser session for cookie/login persistence across restarts. If set, profile is saved to disk. If empty, a temporary profile is used.",
 			},
+			"send": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For screenshot: also send the image to the chat. Default: true",
+			},
+			"describe": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For screenshot: also return a text description of the page and its interactive elements (requires a configured vision model). Default: true when available.",
+			},
 		},
 		"required": []string{"action"},
 	}
@@ -126,19 +201,87 @@ func NewBrowserTool(cfg config.BrowserConfig) *BrowserTool {
 	return &BrowserTool{
 		BaseTool: NewBaseTool(
 			"browser_use",
-			"Automate a headless Chrome browser. Actions: browse_page (navigate to URL and return content), click_element (click a CSS selector), type_text (type into an input), extract_text (get text from selector), screenshot (capture the page), list_sessions (show saved browser sessions), delete_session (remove a named session). Use the 'session' parameter to persist cookies/logins across restarts.",
+			"Automate a headless Chrome browser. Actions: browse_page (navigate to URL and return content), click_element (click a CSS selector), type_text (type into an input key by key), fill_form (fill several fields and submit), extract_text (get text from selector), screenshot (capture the page or an element and send it to the chat as an image), wait_for_selector (wait until a CSS selector is on the page), wait_for_navigation (wait until the tab has loaded another page, e.g. after clicking a link or submitting a form), go_back (previous page), new_tab (open a tab, optionally at url), switch_tab and close_tab (by tab number), download (click selector or open url and save the file that downloads, returning its path), list_sessions (show saved browser sessions), delete_session (remove a named session), export_cookies and import_cookies (save a named session's cookies to a file, or add cookies from a JSON or Netscape cookies.txt file exported from another browser). Use the 'session' parameter to persist cookies/logins across restarts.",
 			parameters,
 		),
 		browserCfg: cfg,
+		workspace:  expandTilde("~/.ubot/workspace"),
+		polite:     sharedPoliteness,
 	}
 }
 
+// SetWorkspace sets the workspace that downloads are saved in, so that the
+// file tools can reach them.
+func (t *BrowserTool) SetWorkspace(dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.workspace = dir
+}
+
+// SetImageDescriber enables screenshot descriptions using d.
+func (t *BrowserTool) SetImageDescriber(d ImageDescriber) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.describer = d
+}
+
+// SetSender makes screenshots go to the chat they were taken for as
+// images, through send.
+func (t *BrowserTool) SetSender(send func(bus.OutboundMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.send = send
+}
+
+// SetAllowedActions restricts the tool to the given actions, e.g. to allow
+// reading pages but not clicking or typing. An empty list allows every
+// action. Unknown names are returned so the caller can warn about them.
+func (t *BrowserTool) SetAllowedActions(actions []string) (unknown []string) {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(actions))
+	for _, a := range actions {
+		if !containsString(browserActions, a) {
+			unknown = append(unknown, a)
+			continue
+		}
+		allowed[a] = true
+	}
+	enum := make([]string, 0, len(allowed))
+	for _, a := range browserActions {
+		if allowed[a] {
+			enum = append(enum, a)
+		}
+	}
+
+	t.mu.Lock()
+	t.allowed = allowed
+	t.mu.Unlock()
+
+	// Offer the model only the permitted actions
+	props := t.Parameters()["properties"].(map[string]interface{})
+	props["action"].(map[string]interface{})["enum"] = enum
+	return unknown
+}
+
+// actionAllowed reports whether the deployment permits action.
+func (t *BrowserTool) actionAllowed(action string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.allowed == nil || t.allowed[action]
+}
+
 // Execute runs the specified browser action.
 func (t *BrowserTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
 	action, err := GetStringParam(params, "action")
 	if err != nil {
 		return "", fmt.Errorf("browser_use: %w", err)
 	}
+	if containsString(browserActions, action) && !t.actionAllowed(action) {
+		return "", fmt.Errorf("browser_use: action %q is not allowed on this deployment (security.browser.allowedActions)", action)
+	}
 
 	// Create a timeout context for this action.
 	actionCtx, cancel := context.WithTimeout(ctx, browserActionTimeout)
@@ -151,16 +294,38 @@ func (t *BrowserTool) Execute(ctx context.Context, params map[string]interface{}
 		return t.clickElement(actionCtx, params)
 	case "type_text":
 		return t.typeText(actionCtx, params)
+	case "fill_form":
+		return t.fillForm(actionCtx, params)
 	case "extract_text":
 		return t.extractText(actionCtx, params)
 	case "screenshot":
-		return t.screenshot(actionCtx, params)
+		// Uses its own timeouts so the optional description gets a full budget.
+		return t.screenshot(ctx, params)
+	case "wait_for_selector":
+		return t.waitForSelector(actionCtx, params)
+	case "wait_for_navigation":
+		return t.waitForNavigation(actionCtx, params)
+	case "go_back":
+		return t.goBack(actionCtx, params)
+	case "new_tab":
+		return t.newTab(actionCtx, params)
+	case "switch_tab":
+		return t.switchTab(actionCtx, params)
+	case "close_tab":
+		return t.closeTab(actionCtx, params)
+	case "download":
+		// Large files may take longer than other actions.
+		return t.download(ctx, params)
 	case "list_sessions":
 		return t.listSessions()
 	case "delete_session":
 		return t.deleteSession(params)
+	case "export_cookies":
+		return t.exportCookies(actionCtx, params)
+	case "import_cookies":
+		return t.importCookies(actionCtx, params)
 	default:
-		return "", fmt.Errorf("browser_use: unknown action %q, must be one of: browse_page, click_element, type_text, extract_text, screenshot, list_sessions, delete_session", action)
+		return "", fmt.Errorf("browser_use: unknown action %q, must be one of: %s", action, strings.Join(browserActions, ", "))
 	}
 }
 
@@ -228,8 +393,8 @@ func isValidSessionName(name string) bool {
 	return true
 }
 
-// findChromeBinary locates a Chrome/Chromium binary on the system.
-func findChromeBinary() (string, error) {
+// FindChromeBinary locates a Chrome/Chromium binary on the system.
+func FindChromeBinary() (string, error) {
 	candidates := []string{
 		"google-chrome",
 		"google-chrome-stable",
@@ -287,7 +452,7 @@ func (t *BrowserTool) ensureBrowser(sessionName string) (*browserInstance, error
 		t.browser = nil
 	}
 
-	chromePath, err := findChromeBinary()
+	chromePath, err := FindChromeBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -332,6 +497,7 @@ func (t *BrowserTool) ensureBrowser(sessionName string) (*browserInstance, error
 		"--disable-translate",
 		"--mute-audio",
 		"--no-sandbox",
+		"--remote-allow-origins=" + cdpOrigin,
 		fmt.Sprintf("--user-data-dir=%s", userDataDir),
 		fmt.Sprintf("--user-agent=%s", ua),
 		fmt.Sprintf("--window-size=%d,%d", vpW, vpH),
@@ -505,41 +671,43 @@ func (t *BrowserTool) Close() {
 type cdpTargetInfo struct {
 	ID                   string `json:"id"`
 	Type                 string `json:"type"`
+	URL                  string `json:"url"`
 	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
 }
 
-// getPageTargetID gets the first page target ID from CDP.
-func (t *BrowserTool) getPageTargetID(bi *browserInstance) (string, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(bi.cdpURL + "/json/list")
+// getPageTargetID gets the target ID of the active tab.
+func (t *BrowserTool) getPageTargetID(ctx context.Context, bi *browserInstance) (string, error) {
+	tab, err := t.currentTab(ctx, bi)
 	if err != nil {
-		return "", fmt.Errorf("browser_use: failed to list CDP targets: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var targets []cdpTargetInfo
-	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
-		return "", fmt.Errorf("browser_use: failed to parse CDP targets: %w", err)
+		return "", err
 	}
+	return tab.targetID, nil
+}
 
-	for _, t := range targets {
-		if t.Type == "page" {
-			return t.ID, nil
-		}
+// currentTab returns the active tab for an action, noting where the tab is
+// before the action so wait_for_navigation can tell it moved on.
+func (t *BrowserTool) currentTab(ctx context.Context, bi *browserInstance) (*browserTab, error) {
+	tab, err := t.activeTab(ctx, bi)
+	if err != nil {
+		return nil, err
 	}
-
-	// Create a new page target.
-	resp2, err := client.Get(bi.cdpURL + "/json/new?about:blank")
+	targets, err := listCDPTargets(bi.cdpURL)
 	if err != nil {
-		return "", fmt.Errorf("browser_use: failed to create new page: %w", err)
+		return nil, err
 	}
-	defer resp2.Body.Close()
-
-	var newTarget cdpTargetInfo
-	if err := json.NewDecoder(resp2.Body).Decode(&newTarget); err != nil {
-		return "", fmt.Errorf("browser_use: failed to parse new target: %w", err)
+	bi.mu.Lock()
+	for _, target := range targets {
+		if target.ID == tab.targetID {
+			tab.url = target.URL
+		}
 	}
-	return newTarget.ID, nil
+	pageURL := tab.url
+	bi.mu.Unlock()
+	// A click may have taken the tab to a site it may not visit
+	if err := t.leaveDisallowed(ctx, tab, pageURL); err != nil {
+		return nil, fmt.Errorf("browser_use: %w", err)
+	}
+	return tab, nil
 }
 
 // cdpSend sends a CDP command via the HTTP endpoint and returns the result.
@@ -586,9 +754,12 @@ func (t *BrowserTool) browsePage(ctx context.Context, params map[string]interfac
 	}
 
 	// SSRF protection: block requests to internal/private network addresses
-	if isInternalURL(urlStr) {
+	if safenet.IsInternalURL(urlStr) {
 		return "", fmt.Errorf("browser_use browse_page: access to internal/private network addresses is blocked")
 	}
+	if err := t.checkDomain(urlStr); err != nil {
+		return "", fmt.Errorf("browser_use browse_page: %w", err)
+	}
 
 	sessionName := getSessionParam(params)
 	bi, err := t.ensureBrowser(sessionName)
@@ -596,21 +767,28 @@ func (t *BrowserTool) browsePage(ctx context.Context, params map[string]interfac
 		return "", err
 	}
 
-	targetID, err := t.getPageTargetID(bi)
+	targetID, err := t.getPageTargetID(ctx, bi)
 	if err != nil {
 		return "", err
 	}
 
 	// Navigate via CDP HTTP API.
-	client := &http.Client{Timeout: browserActionTimeout}
+	client := safenet.NewClient(browserActionTimeout)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return t.checkDomain(req.URL.String())
+	}
 	navURL := fmt.Sprintf("%s/json/navigate?%s", bi.cdpURL, targetID)
 	_ = navURL
 
-	return t.fetchAndParsePage(ctx, client, urlStr, bi.userAgent)
+	return t.fetchAndParsePage(ctx, client, urlStr, bi.userAgent, GetStringParamOr(params, "extract_mode", ""))
 }
 
-// fetchAndParsePage fetches a URL and extracts content using goquery.
-func (t *BrowserTool) fetchAndParsePage(ctx context.Context, client *http.Client, urlStr string, userAgent string) (string, error) {
+// fetchAndParsePage fetches a URL and extracts content using goquery. In
+// article mode only the page's main article is returned.
+func (t *BrowserTool) fetchAndParsePage(ctx context.Context, client *http.Client, urlStr string, userAgent string, mode string) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
 	if err != nil {
 		return "", fmt.Errorf("browser_use browse_page: failed to create request: %w", err)
@@ -622,7 +800,7 @@ func (t *BrowserTool) fetchAndParsePage(ctx context.Context, client *http.Client
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 
-	resp, err := client.Do(req)
+	resp, err := t.polite.Do(client, req)
 	if err != nil {
 		return "", fmt.Errorf("browser_use browse_page: request failed: %w", err)
 	}
@@ -632,7 +810,24 @@ func (t *BrowserTool) fetchAndParsePage(ctx context.Context, client *http.Client
 		return "", fmt.Errorf("browser_use browse_page: HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body := io.Reader(resp.Body)
+	if mode == "article" {
+		page, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("browser_use browse_page: failed to read the page: %w", err)
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+		if err != nil {
+			return "", fmt.Errorf("browser_use browse_page: failed to parse HTML: %w", err)
+		}
+		if a, ok := extractArticle(doc); ok {
+			return formatArticle(urlStr, a), nil
+		}
+		// Without an article, the page is returned as usual
+		body = bytes.NewReader(page)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(body)
 	if err != nil {
 		return "", fmt.Errorf("browser_use browse_page: failed to parse HTML: %w", err)
 	}
@@ -708,76 +903,26 @@ func (t *BrowserTool) fetchAndParsePage(ctx context.Context, client *http.Client
 	return result.String(), nil
 }
 
-// clickElement clicks an element on the current page.
-func (t *BrowserTool) clickElement(ctx context.Context, params map[string]interface{}) (string, error) {
-	selector, err := GetStringParam(params, "selector")
-	if err != nil {
-		return "", fmt.Errorf("browser_use click_element: %w", err)
-	}
-	if selector == "" {
-		return "", fmt.Errorf("browser_use click_element: selector cannot be empty")
-	}
-
-	sessionName := getSessionParam(params)
-	bi, err := t.ensureBrowser(sessionName)
-	if err != nil {
-		return "", err
-	}
-
-	result, err := t.executeJSOnPage(ctx, bi, fmt.Sprintf(`
-		(function() {
-			var el = document.querySelector(%q);
-			if (!el) return JSON.stringify({error: "Element not found: %s"});
-			el.click();
-			return JSON.stringify({success: true, tag: el.tagName, text: el.textContent.trim().substring(0, 100)});
-		})()
-	`, selector, selector))
-	if err != nil {
-		return "", err
-	}
-
-	return fmt.Sprintf("Clicked element %q: %s", selector, result), nil
-}
-
-// typeText types text into an input element.
-func (t *BrowserTool) typeText(ctx context.Context, params map[string]interface{}) (string, error) {
-	selector, err := GetStringParam(params, "selector")
-	if err != nil {
-		return "", fmt.Errorf("browser_use type_text: %w", err)
-	}
-	if selector == "" {
-		return "", fmt.Errorf("browser_use type_text: selector cannot be empty")
+// formatArticle returns an article found by browse_page.
+func formatArticle(urlStr string, a article) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Page: %s\n", urlStr))
+	if a.Title != "" {
+		result.WriteString(fmt.Sprintf("Title: %s\n", a.Title))
 	}
-
-	text, err := GetStringParam(params, "text")
-	if err != nil {
-		return "", fmt.Errorf("browser_use type_text: %w", err)
+	if a.Byline != "" {
+		result.WriteString(fmt.Sprintf("Byline: %s\n", a.Byline))
 	}
-
-	sessionName := getSessionParam(params)
-	bi, err := t.ensureBrowser(sessionName)
-	if err != nil {
-		return "", err
+	if a.Published != "" {
+		result.WriteString(fmt.Sprintf("Published: %s\n", a.Published))
 	}
-
-	escapedText, _ := json.Marshal(text)
-
-	result, err := t.executeJSOnPage(ctx, bi, fmt.Sprintf(`
-		(function() {
-			var el = document.querySelector(%q);
-			if (!el) return JSON.stringify({error: "Element not found: %s"});
-			el.focus();
-			el.value = %s;
-			el.dispatchEvent(new Event('input', {bubbles: true}));
-			el.dispatchEvent(new Event('change', {bubbles: true}));
-			return JSON.stringify({success: true, tag: el.tagName, value: el.value.substring(0, 100)});
-		})()
-	`, selector, selector, string(escapedText)))
-	if err != nil {
-		return "", err
+	content := a.Content
+	if len(content) > maxBrowserContentChars {
+		content = content[:maxBrowserContentChars] + "\n... [content truncated]"
 	}
-
-	return fmt.Sprintf("Typed into %q: %s", selector, result), nil
+	result.WriteString("\n--- Article ---\n")
+	result.WriteString(content)
+	return result.String()
 }
 
 // extractText extracts text content from an element.
@@ -810,88 +955,10 @@ func (t *BrowserTool) extractText(ctx context.Context, params map[string]interfa
 	return result, nil
 }
 
-// screenshot captures a screenshot of the current page.
-func (t *BrowserTool) screenshot(ctx context.Context, params map[string]interface{}) (string, error) {
-	sessionName := getSessionParam(params)
-	bi, err := t.ensureBrowser(sessionName)
-	if err != nil {
-		return "", err
-	}
-
-	// Use Chrome's headless screenshot mode via a new process.
-	chromePath, err := findChromeBinary()
-	if err != nil {
-		return "", err
-	}
-
-	// Ensure workspace screenshots directory exists.
-	home, _ := os.UserHomeDir()
-	screenshotDir := filepath.Join(home, ".ubot", "workspace", "screenshots")
-	if err := os.MkdirAll(screenshotDir, 0755); err != nil {
-		return "", fmt.Errorf("browser_use screenshot: failed to create directory: %w", err)
-	}
-
-	filename := fmt.Sprintf("screenshot-%d.png", time.Now().Unix())
-	screenshotPath := filepath.Join(screenshotDir, filename)
-
-	// Get the current page URL from CDP.
-	pageURL, err := t.getCurrentPageURL(bi)
-	if err != nil || pageURL == "" || pageURL == "about:blank" {
-		return "", fmt.Errorf("browser_use screenshot: no page loaded, use browse_page first")
-	}
-
-	// Take screenshot using a separate headless Chrome invocation.
-	cmd := exec.CommandContext(ctx, chromePath,
-		"--headless=new",
-		"--disable-gpu",
-		"--no-sandbox",
-		"--screenshot="+screenshotPath,
-		"--window-size=1280,720",
-		pageURL,
-	)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("browser_use screenshot: Chrome failed: %v (%s)", err, stderr.String())
-	}
-
-	if _, err := os.Stat(screenshotPath); err != nil {
-		return "", fmt.Errorf("browser_use screenshot: file not created")
-	}
-
-	return fmt.Sprintf("Screenshot saved to %s", screenshotPath), nil
-}
-
-// getCurrentPageURL gets the URL of the current page from CDP.
-func (t *BrowserTool) getCurrentPageURL(bi *browserInstance) (string, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(bi.cdpURL + "/json/list")
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var targets []struct {
-		URL  string `json:"url"`
-		Type string `json:"type"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
-		return "", err
-	}
-
-	for _, t := range targets {
-		if t.Type == "page" {
-			return t.URL, nil
-		}
-	}
-	return "", nil
-}
-
 // executeJSOnPage executes JavaScript on the current page via CDP.
 func (t *BrowserTool) executeJSOnPage(ctx context.Context, bi *browserInstance, js string) (string, error) {
 	// Get a page target.
-	targetID, err := t.getPageTargetID(bi)
+	targetID, err := t.getPageTargetID(ctx, bi)
 	if err != nil {
 		return "", err
 	}
@@ -978,3 +1045,13 @@ func expandTilde(path string) string {
 	}
 	return path
 }
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,375 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCookieFileBytes bounds the cookie files import_cookies reads.
+const maxCookieFileBytes = 5 << 20
+
+// browserCookie is a cookie as the DevTools protocol describes it, which is
+// also the JSON export format.
+type browserCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"` // seconds since the epoch; -1 = until the browser closes
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	SameSite string  `json:"sameSite,omitempty"` // Strict, Lax or None
+}
+
+// parseCookies reads cookies in JSON or Netscape (cookies.txt) format and
+// returns them with the name of the format.
+func parseCookies(data []byte) ([]browserCookie, string, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("no cookies given")
+	}
+	if data[0] == '[' || data[0] == '{' {
+		cookies, err := parseCookieJSON(data)
+		return cookies, "json", err
+	}
+	cookies, err := parseNetscapeCookies(data)
+	return cookies, "netscape", err
+}
+
+// parseCookieJSON reads a JSON array of cookies, as exported by browser
+// extensions such as Cookie-Editor, or an object with a "cookies" array, as
+// in Playwright's storage state.
+func parseCookieJSON(data []byte) ([]browserCookie, error) {
+	type jsonCookie struct {
+		browserCookie
+		ExpirationDate float64 `json:"expirationDate"` // browser extensions
+		Session        bool    `json:"session"`
+	}
+	var list []jsonCookie
+	if data[0] == '{' {
+		var state struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("invalid cookie JSON: %w", err)
+		}
+		list = state.Cookies
+	} else if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid cookie JSON: %w", err)
+	}
+
+	cookies := make([]browserCookie, 0, len(list))
+	for i, jc := range list {
+		c := jc.browserCookie
+		if c.Name == "" || c.Domain == "" {
+			return nil, fmt.Errorf("cookie %d has no name or domain", i+1)
+		}
+		if c.Expires == 0 {
+			c.Expires = jc.ExpirationDate
+		}
+		if jc.Session || c.Expires <= 0 {
+			c.Expires = -1
+		}
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		c.SameSite = normalizeSameSite(c.SameSite)
+		cookies = append(cookies, c)
+	}
+	return cookies, nil
+}
+
+// normalizeSameSite maps the SameSite spellings of browser extensions to
+// the protocol's.
+func normalizeSameSite(s string) string {
+	switch strings.ToLower(s) {
+	case "strict":
+		return "Strict"
+	case "lax":
+		return "Lax"
+	case "none", "no_restriction":
+		return "None"
+	default:
+		return ""
+	}
+}
+
+// parseNetscapeCookies reads a cookies.txt file: one cookie per line as
+// domain, include subdomains, path, secure, expiry, name and value separated
+// by tabs. Lines starting with #HttpOnly_ are HTTP-only cookies; other lines
+// starting with # are comments.
+func parseNetscapeCookies(data []byte) ([]browserCookie, error) {
+	var cookies []browserCookie
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		if httpOnly {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+		} else if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d is not a Netscape cookie: want 7 tab-separated fields, got %d", n+1, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d has an invalid expiry %q", n+1, fields[4])
+		}
+		c := browserCookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Expires:  float64(expires),
+			Name:     fields[5],
+			Value:    fields[6],
+			HTTPOnly: httpOnly,
+		}
+		if expires <= 0 {
+			c.Expires = -1
+		}
+		cookies = append(cookies, c)
+	}
+	if len(cookies) == 0 {
+		return nil, fmt.Errorf("no cookies found")
+	}
+	return cookies, nil
+}
+
+// formatCookies writes cookies in the given format, "json" or "netscape".
+func formatCookies(cookies []browserCookie, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(cookies, "", "  ")
+	case "netscape":
+		var b strings.Builder
+		b.WriteString("# Netscape HTTP Cookie File\n")
+		b.WriteString("# Exported by ubot. These cookies log in as you: keep the file private.\n\n")
+		for _, c := range cookies {
+			if c.HTTPOnly {
+				b.WriteString("#HttpOnly_")
+			}
+			expires := int64(c.Expires)
+			if expires < 0 {
+				expires = 0
+			}
+			fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				c.Domain, netscapeBool(strings.HasPrefix(c.Domain, ".")), c.Path,
+				netscapeBool(c.Secure), expires, c.Name, c.Value)
+		}
+		return []byte(b.String()), nil
+	default:
+		return nil, fmt.Errorf("unknown cookie format %q, must be json or netscape", format)
+	}
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// cookieSites counts the sites cookies belong to.
+func cookieSites(cookies []browserCookie) int {
+	sites := make(map[string]bool)
+	for _, c := range cookies {
+		sites[strings.TrimPrefix(c.Domain, ".")] = true
+	}
+	return len(sites)
+}
+
+// requiredSession returns the session parameter of an action that only
+// makes sense for a named session.
+func requiredSession(params map[string]interface{}, action string) (string, error) {
+	session := GetStringParamOr(params, "session", "")
+	if session == "" {
+		return "", fmt.Errorf("browser_use %s: 'session' parameter is required", action)
+	}
+	if !isValidSessionName(session) {
+		return "", fmt.Errorf("browser_use %s: invalid session name %q (use alphanumeric, dash, underscore)", action, session)
+	}
+	return session, nil
+}
+
+// exportCookies saves the cookies of a named session to a file in the
+// workspace.
+func (t *BrowserTool) exportCookies(ctx context.Context, params map[string]interface{}) (string, error) {
+	session, err := requiredSession(params, "export_cookies")
+	if err != nil {
+		return "", err
+	}
+	format := GetStringParamOr(params, "format", "json")
+	if format != "json" && format != "netscape" {
+		return "", fmt.Errorf("browser_use export_cookies: unknown format %q, must be json or netscape", format)
+	}
+
+	bi, err := t.ensureBrowser(session)
+	if err != nil {
+		return "", err
+	}
+	browser, err := dialBrowser(ctx, bi.cdpURL)
+	if err != nil {
+		return "", fmt.Errorf("browser_use export_cookies: %w", err)
+	}
+	defer browser.close()
+	res, err := browser.call(ctx, "Storage.getCookies", nil)
+	if err != nil {
+		return "", fmt.Errorf("browser_use export_cookies: %w", err)
+	}
+	var jar struct {
+		Cookies []browserCookie `json:"cookies"`
+	}
+	if err := json.Unmarshal(res, &jar); err != nil {
+		return "", fmt.Errorf("browser_use export_cookies: failed to parse cookies: %w", err)
+	}
+	if len(jar.Cookies) == 0 {
+		return fmt.Sprintf("Session %q has no cookies.", session), nil
+	}
+	sort.SliceStable(jar.Cookies, func(i, j int) bool { return jar.Cookies[i].Domain < jar.Cookies[j].Domain })
+
+	data, err := formatCookies(jar.Cookies, format)
+	if err != nil {
+		return "", fmt.Errorf("browser_use export_cookies: %w", err)
+	}
+	t.mu.Lock()
+	dir := filepath.Join(t.workspace, "cookies")
+	t.mu.Unlock()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("browser_use export_cookies: failed to create directory: %w", err)
+	}
+	ext := ".json"
+	if format == "netscape" {
+		ext = ".txt"
+	}
+	path := filepath.Join(dir, session+ext)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("browser_use export_cookies: %w", err)
+	}
+	return fmt.Sprintf("Exported %d cookies for %d sites of session %q to %s. They log in as this session: keep the file private.",
+		len(jar.Cookies), cookieSites(jar.Cookies), session, path), nil
+}
+
+// cookieFile resolves the path of a cookie file to import, which must be
+// in the workspace, like the files users send, and not a sensitive file.
+func (t *BrowserTool) cookieFile(p string) (string, error) {
+	t.mu.Lock()
+	workspace := t.workspace
+	t.mu.Unlock()
+
+	p = expandTilde(p)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(workspace, p)
+	}
+	p = filepath.Clean(p)
+	if rel, err := filepath.Rel(workspace, p); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is outside the workspace %s", p, workspace)
+	}
+	real, err := followsOutside(workspace, p)
+	if err != nil {
+		return "", err
+	}
+	if real != "" {
+		return "", fmt.Errorf("%s is outside the workspace %s: it leads to %s through a symlink", p, workspace, real)
+	}
+	if isSensitiveName(filepath.Base(p)) {
+		return "", ErrBlockedPath{Path: p, Reason: "sensitive filename"}
+	}
+	return p, nil
+}
+
+// importCookies adds cookies, read from the file at path or given as text,
+// to a named session, e.g. to continue a login made in a desktop browser.
+func (t *BrowserTool) importCookies(ctx context.Context, params map[string]interface{}) (string, error) {
+	session, err := requiredSession(params, "import_cookies")
+	if err != nil {
+		return "", err
+	}
+
+	var data []byte
+	if path := GetStringParamOr(params, "path", ""); path != "" {
+		path, err := t.cookieFile(path)
+		if err != nil {
+			return "", fmt.Errorf("browser_use import_cookies: %w", err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return "", fmt.Errorf("browser_use import_cookies: %w", err)
+		}
+		data, err = io.ReadAll(io.LimitReader(f, maxCookieFileBytes+1))
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("browser_use import_cookies: %w", err)
+		}
+		if len(data) > maxCookieFileBytes {
+			return "", fmt.Errorf("browser_use import_cookies: %s is larger than %d MB", path, maxCookieFileBytes>>20)
+		}
+	} else {
+		data = []byte(GetStringParamOr(params, "text", ""))
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return "", fmt.Errorf("browser_use import_cookies: 'path' or 'text' with the cookies is required")
+	}
+
+	cookies, format, err := parseCookies(data)
+	if err != nil {
+		return "", fmt.Errorf("browser_use import_cookies: %w", err)
+	}
+	now := float64(time.Now().Unix())
+	var cookieParams []map[string]interface{}
+	var valid []browserCookie
+	for _, c := range cookies {
+		if c.Expires > 0 && c.Expires < now {
+			continue
+		}
+		p := map[string]interface{}{
+			"name":     c.Name,
+			"value":    c.Value,
+			"domain":   c.Domain,
+			"path":     c.Path,
+			"secure":   c.Secure,
+			"httpOnly": c.HTTPOnly,
+		}
+		if c.Expires > 0 {
+			p["expires"] = c.Expires
+		}
+		if c.SameSite != "" {
+			p["sameSite"] = c.SameSite
+		}
+		cookieParams = append(cookieParams, p)
+		valid = append(valid, c)
+	}
+	if len(valid) == 0 {
+		return "", fmt.Errorf("browser_use import_cookies: all %d cookies have expired", len(cookies))
+	}
+
+	bi, err := t.ensureBrowser(session)
+	if err != nil {
+		return "", err
+	}
+	browser, err := dialBrowser(ctx, bi.cdpURL)
+	if err != nil {
+		return "", fmt.Errorf("browser_use import_cookies: %w", err)
+	}
+	defer browser.close()
+	if _, err := browser.call(ctx, "Storage.setCookies", map[string]interface{}{"cookies": cookieParams}); err != nil {
+		return "", fmt.Errorf("browser_use import_cookies: %w", err)
+	}
+
+	result := fmt.Sprintf("Imported %d cookies (%s) for %d sites into session %q.", len(valid), format, cookieSites(valid), session)
+	if skipped := len(cookies) - len(valid); skipped > 0 {
+		result += fmt.Sprintf(" Skipped %d expired cookies.", skipped)
+	}
+	return result, nil
+}
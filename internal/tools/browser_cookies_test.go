@@ -0,0 +1,106 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+func TestParseCookies(t *testing.T) {
+	want := []browserCookie{
+		{Name: "sid", Value: "abc", Domain: ".bank.example", Path: "/", Expires: 1893456000, HTTPOnly: true, Secure: true, SameSite: "Lax"},
+		{Name: "lang", Value: "en", Domain: "bank.example", Path: "/app", Expires: -1},
+	}
+
+	for name, input := range map[string]string{
+		// Browser extensions such as Cookie-Editor
+		"extension": `[
+			{"name": "sid", "value": "abc", "domain": ".bank.example", "path": "/", "expirationDate": 1893456000, "httpOnly": true, "secure": true, "sameSite": "lax"},
+			{"name": "lang", "value": "en", "domain": "bank.example", "path": "/app", "session": true, "sameSite": "unspecified"}
+		]`,
+		// Playwright's storage state
+		"storage state": `{"cookies": [
+			{"name": "sid", "value": "abc", "domain": ".bank.example", "path": "/", "expires": 1893456000, "httpOnly": true, "secure": true, "sameSite": "Lax"},
+			{"name": "lang", "value": "en", "domain": "bank.example", "path": "/app", "expires": -1}
+		], "origins": []}`,
+		"netscape": "# Netscape HTTP Cookie File\r\n\r\n" +
+			"#HttpOnly_.bank.example\tTRUE\t/\tTRUE\t1893456000\tsid\tabc\r\n" +
+			"bank.example\tFALSE\t/app\tFALSE\t0\tlang\ten\r\n",
+	} {
+		cookies, _, err := parseCookies([]byte(input))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if name == "netscape" {
+			// cookies.txt has no SameSite
+			cookies[0].SameSite = "Lax"
+		}
+		if !reflect.DeepEqual(cookies, want) {
+			t.Errorf("%s: cookies = %+v", name, cookies)
+		}
+	}
+
+	// Exports read back as they were written
+	for _, format := range []string{"json", "netscape"} {
+		data, err := formatCookies(want, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cookies, detected, err := parseCookies(data)
+		if err != nil || detected != format {
+			t.Fatalf("%s: detected %s, %v", format, detected, err)
+		}
+		if format == "netscape" {
+			cookies[0].SameSite = "Lax"
+		}
+		if !reflect.DeepEqual(cookies, want) {
+			t.Errorf("%s round trip = %+v", format, cookies)
+		}
+	}
+
+	for _, bad := range []string{"", "sid=abc; lang=en", `[{"value": "abc"}]`, "a\tb\tc\td\tsoon\tf\tg"} {
+		if _, _, err := parseCookies([]byte(bad)); err == nil {
+			t.Errorf("parsed %q", bad)
+		}
+	}
+	if data, _ := formatCookies(want, "netscape"); !strings.Contains(string(data), "#HttpOnly_.bank.example\tTRUE\t/\tTRUE\t1893456000\tsid\tabc\n") {
+		t.Errorf("netscape export =\n%s", data)
+	}
+}
+
+func TestCookieFile(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(workspace, "link")); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewBrowserTool(config.BrowserConfig{})
+	tool.SetWorkspace(workspace)
+
+	got, err := tool.cookieFile("files/telegram_1/cookies.txt")
+	if err != nil {
+		t.Fatalf("cookieFile: %v", err)
+	}
+	if want := filepath.Join(workspace, "files", "telegram_1", "cookies.txt"); got != want {
+		t.Errorf("cookieFile = %q, want %q", got, want)
+	}
+
+	for _, p := range []string{
+		"/etc/passwd",
+		"../cookies.txt",
+		filepath.Join(outside, "cookies.txt"),
+		"link/cookies.txt",
+		".env",
+	} {
+		if _, err := tool.cookieFile(p); err == nil {
+			t.Errorf("cookieFile(%q) succeeded, want an error", p)
+		}
+	}
+}
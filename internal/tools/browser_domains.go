@@ -0,0 +1,54 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/hkuds/ubot/internal/sandbox"
+)
+
+// checkDomain refuses pages outside tools.browser.allowedDomains or on
+// tools.browser.blockedDomains. about: pages such as about:blank are always
+// allowed; other pages without a web address only when no allowed domains
+// are configured.
+func (t *BrowserTool) checkDomain(rawURL string) error {
+	allowed, blocked := t.browserCfg.AllowedDomains, t.browserCfg.BlockedDomains
+	if len(allowed) == 0 && len(blocked) == 0 {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q", rawURL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		if u.Scheme == "about" || len(allowed) == 0 {
+			return nil
+		}
+		return fmt.Errorf("%s pages are not allowed when tools.browser.allowedDomains is set", u.Scheme)
+	}
+	host := u.Hostname()
+	if sandbox.HostAllowed(host, blocked) {
+		return fmt.Errorf("%s is blocked by tools.browser.blockedDomains", host)
+	}
+	if len(allowed) > 0 && !sandbox.HostAllowed(host, allowed) {
+		return fmt.Errorf("%s is not in tools.browser.allowedDomains", host)
+	}
+	return nil
+}
+
+// leaveDisallowed checks the page tab is on and, when it is not allowed,
+// takes the tab to about:blank so no action reads or acts on it.
+func (t *BrowserTool) leaveDisallowed(ctx context.Context, tab *browserTab, pageURL string) error {
+	err := t.checkDomain(pageURL)
+	if err == nil {
+		return nil
+	}
+	if conn, dialErr := dialCDP(ctx, tab.wsURL); dialErr == nil {
+		conn.call(ctx, "Page.navigate", map[string]interface{}{"url": "about:blank"})
+		conn.close()
+	}
+	return fmt.Errorf("tab %d ended up on %s, which is not allowed: %w", tab.id, pageURL, err)
+}
@@ -0,0 +1,52 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBrowserDomains(t *testing.T) {
+	cfg := testBrowserConfig(t)
+	cfg.AllowedDomains = []string{"*.corp.example", "wiki.example"}
+	cfg.BlockedDomains = []string{"admin.corp.example"}
+	tool := NewBrowserTool(cfg)
+
+	for rawURL, allowed := range map[string]bool{
+		"https://corp.example/":             true,
+		"https://jira.corp.example/browse":  true,
+		"https://wiki.example/page":         true,
+		"https://docs.wiki.example/":        false,
+		"https://admin.corp.example/users":  false,
+		"https://ADMIN.corp.example./users": false,
+		"https://evil.example/":             false,
+		"about:blank":                       true,
+		"file:///etc/passwd":                false,
+	} {
+		if err := tool.checkDomain(rawURL); (err == nil) != allowed {
+			t.Errorf("checkDomain(%s) = %v, want allowed %v", rawURL, err, allowed)
+		}
+	}
+	if err := NewBrowserTool(testBrowserConfig(t)).checkDomain("file:///tmp/x"); err != nil {
+		t.Errorf("without domain lists: %v", err)
+	}
+
+	// A tab a click took to a site outside the list is left before any
+	// action works on it
+	b := newFakeBrowser(t)
+	bi := &browserInstance{cdpURL: b.srv.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := tool.currentTab(ctx, bi); err == nil || !strings.Contains(err.Error(), "app.example is not in tools.browser.allowedDomains") {
+		t.Errorf("currentTab on app.example = %v", err)
+	}
+	b.mu.Lock()
+	b.targets[0].URL = "https://jira.corp.example/"
+	b.mu.Unlock()
+	if _, err := tool.currentTab(ctx, bi); err != nil {
+		t.Errorf("currentTab on jira.corp.example = %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+const (
+	// downloadStartTimeout is how long a click or link has to start a
+	// download.
+	downloadStartTimeout = 10 * time.Second
+	// A download may take downloadTimeout unless the timeout parameter says
+	// otherwise, up to maxDownloadTimeout.
+	downloadTimeout    = 60 * time.Second
+	maxDownloadTimeout = 5 * time.Minute
+)
+
+// browserDownload is a file the browser downloaded.
+type browserDownload struct {
+	path string
+	url  string
+	size int64
+}
+
+// browserWSURL returns the debugger URL of the browser itself, which
+// receives the browser-wide download events.
+func browserWSURL(cdpURL string) (string, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(cdpURL + "/json/version")
+	if err != nil {
+		return "", fmt.Errorf("failed to reach Chrome: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var version struct {
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil || version.WebSocketDebuggerURL == "" {
+		return "", errors.New("failed to find the browser's debugger URL")
+	}
+	return version.WebSocketDebuggerURL, nil
+}
+
+// dialBrowser connects to the browser itself rather than to one of its tabs.
+func dialBrowser(ctx context.Context, cdpURL string) (*cdpConn, error) {
+	wsURL, err := browserWSURL(cdpURL)
+	if err != nil {
+		return nil, err
+	}
+	return dialCDP(ctx, wsURL)
+}
+
+// downloadDir returns where downloads of a browser session are saved.
+func (t *BrowserTool) downloadDir(session string) string {
+	t.mu.Lock()
+	dir := filepath.Join(t.workspace, "downloads")
+	t.mu.Unlock()
+	if session != "" {
+		dir = filepath.Join(dir, session)
+	}
+	return dir
+}
+
+// captureDownload lets the browser behind conn download into dir, calls
+// start to set off a download and waits until it has finished. The file is
+// saved under the name the site suggested, made unique in dir.
+func captureDownload(ctx context.Context, conn *cdpConn, dir string, start func() error) (*browserDownload, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	// Files are saved by their download id and renamed once complete, so a
+	// partial file never has the final name.
+	if _, err := conn.call(ctx, "Browser.setDownloadBehavior", map[string]interface{}{
+		"behavior":      "allowAndName",
+		"downloadPath":  dir,
+		"eventsEnabled": true,
+	}); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Downloads outside the action are refused again, as by default
+		resetCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn.call(resetCtx, "Browser.setDownloadBehavior", map[string]interface{}{"behavior": "default"})
+	}()
+
+	if err := start(); err != nil {
+		return nil, err
+	}
+
+	var guid, name string
+	dl := &browserDownload{}
+	begun := time.NewTimer(downloadStartTimeout)
+	defer begun.Stop()
+	for {
+		select {
+		case ev := <-conn.events:
+			var p struct {
+				GUID              string `json:"guid"`
+				URL               string `json:"url"`
+				SuggestedFilename string `json:"suggestedFilename"`
+				State             string `json:"state"`
+			}
+			json.Unmarshal(ev.Params, &p)
+			switch {
+			case ev.Method == "Browser.downloadWillBegin" && guid == "":
+				guid, name, dl.url = p.GUID, p.SuggestedFilename, p.URL
+				begun.Stop()
+			case ev.Method == "Browser.downloadProgress" && p.GUID == guid && p.State == "completed":
+				path, err := uniquePath(dir, name)
+				if err != nil {
+					return nil, err
+				}
+				if err := os.Rename(filepath.Join(dir, guid), path); err != nil {
+					return nil, fmt.Errorf("failed to save the download: %w", err)
+				}
+				if info, err := os.Stat(path); err == nil {
+					dl.size = info.Size()
+				}
+				dl.path = path
+				return dl, nil
+			case ev.Method == "Browser.downloadProgress" && p.GUID == guid && p.State == "canceled":
+				return nil, fmt.Errorf("the download of %s was canceled", name)
+			}
+		case <-begun.C:
+			return nil, fmt.Errorf("no download started within %s", downloadStartTimeout)
+		case <-conn.done:
+			return nil, fmt.Errorf("connection to Chrome closed: %v", conn.err)
+		case <-ctx.Done():
+			if guid != "" {
+				cancelCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				conn.call(cancelCtx, "Browser.cancelDownload", map[string]interface{}{"guid": guid})
+				cancel()
+				os.Remove(filepath.Join(dir, guid))
+				return nil, fmt.Errorf("the download of %s did not finish in time", name)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// uniquePath returns a path in dir for a file the site called name. A name
+// already taken gets a number, such as statement-2.pdf.
+func uniquePath(dir, name string) (string, error) {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		name = "download"
+	}
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 1; i < 1000; i++ {
+		candidate := name
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d%s", stem, i, ext)
+		}
+		path := filepath.Join(dir, candidate)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("too many downloads named %s", name)
+}
+
+// download clicks the element matching selector, or opens url in the
+// active tab, and saves the file that starts downloading.
+func (t *BrowserTool) download(ctx context.Context, params map[string]interface{}) (string, error) {
+	selector := GetStringParamOr(params, "selector", "")
+	urlStr := GetStringParamOr(params, "url", "")
+	if selector == "" && urlStr == "" {
+		return "", fmt.Errorf("browser_use download: 'selector' or 'url' is required")
+	}
+	if urlStr != "" {
+		if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
+			urlStr = "https://" + urlStr
+		}
+		if safenet.IsInternalURL(urlStr) {
+			return "", fmt.Errorf("browser_use download: access to internal/private network addresses is blocked")
+		}
+		if err := t.checkDomain(urlStr); err != nil {
+			return "", fmt.Errorf("browser_use download: %w", err)
+		}
+	}
+
+	timeout := time.Duration(GetIntParamOr(params, "timeout", int(downloadTimeout/time.Second))) * time.Second
+	if timeout <= 0 || timeout > maxDownloadTimeout {
+		timeout = maxDownloadTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	session := getSessionParam(params)
+	bi, err := t.ensureBrowser(session)
+	if err != nil {
+		return "", err
+	}
+	tab, err := t.currentTab(ctx, bi)
+	if err != nil {
+		return "", err
+	}
+	browser, err := dialBrowser(ctx, bi.cdpURL)
+	if err != nil {
+		return "", fmt.Errorf("browser_use download: %w", err)
+	}
+	defer browser.close()
+	conn, err := dialCDP(ctx, tab.wsURL)
+	if err != nil {
+		return "", fmt.Errorf("browser_use download: %w", err)
+	}
+	defer conn.close()
+
+	dl, err := captureDownload(ctx, browser, t.downloadDir(session), func() error {
+		if selector != "" {
+			el, err := locateElement(ctx, conn, selector)
+			if err != nil {
+				return err
+			}
+			return clickAt(ctx, conn, el.X, el.Y)
+		}
+		res, err := conn.call(ctx, "Page.navigate", map[string]interface{}{"url": urlStr})
+		if err != nil {
+			return err
+		}
+		var nav struct {
+			ErrorText string `json:"errorText"`
+		}
+		json.Unmarshal(res, &nav)
+		// A navigation that turns into a download is reported as aborted
+		if nav.ErrorText != "" && !strings.Contains(nav.ErrorText, "ERR_ABORTED") {
+			return fmt.Errorf("navigation failed: %s", nav.ErrorText)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("browser_use download: %w", err)
+	}
+	if safenet.IsInternalURL(dl.url) {
+		os.Remove(dl.path)
+		return "", fmt.Errorf("browser_use download: the download came from an internal/private network address, which is blocked")
+	}
+	if err := t.checkDomain(dl.url); err != nil {
+		os.Remove(dl.path)
+		return "", fmt.Errorf("browser_use download: the download came from %s: %w", dl.url, err)
+	}
+	return fmt.Sprintf("Downloaded %s (%s) to %s", dl.url, formatSize(dl.size), dl.path), nil
+}
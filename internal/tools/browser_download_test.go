@@ -0,0 +1,104 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// fakeDownloads is a browser that downloads statement.pdf into the
+// directory it is given as soon as downloads are allowed.
+func fakeDownloads(t *testing.T) *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var cmd struct {
+				ID     int                    `json:"id"`
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			}
+			if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+				return
+			}
+			websocket.JSON.Send(ws, map[string]interface{}{"id": cmd.ID, "result": map[string]interface{}{}})
+			if cmd.Method != "Browser.setDownloadBehavior" || cmd.Params["behavior"] != "allowAndName" {
+				continue
+			}
+			dir := cmd.Params["downloadPath"].(string)
+			os.WriteFile(filepath.Join(dir, "g1"), []byte("%PDF-1.7"), 0o644)
+			for _, ev := range []map[string]interface{}{
+				{"method": "Browser.downloadWillBegin", "params": map[string]string{"guid": "g1", "url": "https://bank.example/statement", "suggestedFilename": "statement.pdf"}},
+				{"method": "Browser.downloadProgress", "params": map[string]string{"guid": "g1", "state": "inProgress"}},
+				{"method": "Browser.downloadProgress", "params": map[string]string{"guid": "g1", "state": "completed"}},
+			} {
+				websocket.JSON.Send(ws, ev)
+			}
+		}
+	}))
+}
+
+func TestCaptureDownload(t *testing.T) {
+	srv := fakeDownloads(t)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialCDP(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.close()
+
+	// The file is saved under the site's name, next to an earlier download
+	// of the same name
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "statement.pdf"), []byte("old"), 0o644)
+	started := false
+	dl, err := captureDownload(ctx, conn, dir, func() error {
+		started = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !started || dl.path != filepath.Join(dir, "statement-2.pdf") || dl.size != 8 || dl.url != "https://bank.example/statement" {
+		t.Errorf("download = %+v", dl)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "g1")); !os.IsNotExist(err) {
+		t.Error("the download was left under its id")
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	for name, want := range map[string]string{
+		"report.csv":       "report.csv",
+		"../../etc/passwd": "passwd",
+		`..\..\evil.exe`:   "evil.exe",
+		"":                 "download",
+		"archive.tar.gz":   "archive.tar.gz",
+		"/":                "download",
+	} {
+		got, err := uniquePath(dir, name)
+		if err != nil || got != filepath.Join(dir, want) {
+			t.Errorf("uniquePath(%q) = %s, %v; want %s", name, got, err, want)
+		}
+	}
+}
+
+func TestDownloadDirInWorkspace(t *testing.T) {
+	tool := NewBrowserTool(config.BrowserConfig{})
+	workspace := t.TempDir()
+	tool.SetWorkspace(workspace)
+	if got, want := tool.downloadDir("bank"), filepath.Join(workspace, "downloads", "bank"); got != want {
+		t.Errorf("downloadDir = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,417 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pauses between keystrokes and between form fields when stealth is on, so
+// typing does not arrive at machine speed.
+const (
+	keystrokeDelayMin = 40 * time.Millisecond
+	keystrokeDelayMax = 160 * time.Millisecond
+	fieldDelayMin     = 200 * time.Millisecond
+	fieldDelayMax     = 600 * time.Millisecond
+)
+
+// pageElement is an element found by locateElement.
+type pageElement struct {
+	Found   bool    `json:"found"`
+	X       float64 `json:"x"` // center, in viewport coordinates
+	Y       float64 `json:"y"`
+	Tag     string  `json:"tag"`
+	Type    string  `json:"type"` // of an input
+	Text    string  `json:"text"`
+	Checked bool    `json:"checked"`
+	Order   int     `json:"order"` // position in the document
+}
+
+// locateJS scrolls the element matching %s into view and describes it.
+const locateJS = `(function() {
+	var el = document.querySelector(%s);
+	if (!el) return JSON.stringify({found: false});
+	el.scrollIntoView({block: "center", inline: "center"});
+	var r = el.getBoundingClientRect();
+	return JSON.stringify({
+		found: true,
+		x: r.left + r.width / 2,
+		y: r.top + r.height / 2,
+		tag: el.tagName.toLowerCase(),
+		type: (el.type || "").toLowerCase(),
+		text: (el.innerText || el.value || "").trim().substring(0, 100),
+		checked: !!el.checked,
+		order: Array.prototype.indexOf.call(document.getElementsByTagName("*"), el)
+	});
+})()`
+
+// connectTab connects to the active tab for an action.
+func (t *BrowserTool) connectTab(ctx context.Context, params map[string]interface{}) (*cdpConn, error) {
+	bi, err := t.ensureBrowser(getSessionParam(params))
+	if err != nil {
+		return nil, err
+	}
+	tab, err := t.currentTab(ctx, bi)
+	if err != nil {
+		return nil, err
+	}
+	return dialCDP(ctx, tab.wsURL)
+}
+
+// locateElement finds the element matching selector and scrolls it into
+// view.
+func locateElement(ctx context.Context, conn *cdpConn, selector string) (pageElement, error) {
+	quoted, _ := json.Marshal(selector)
+	var el pageElement
+	if err := evaluate(ctx, conn, fmt.Sprintf(locateJS, quoted), &el); err != nil {
+		return el, err
+	}
+	if !el.Found {
+		return el, fmt.Errorf("element not found: %s", selector)
+	}
+	return el, nil
+}
+
+// clickAt moves the mouse to x, y and clicks there.
+func clickAt(ctx context.Context, conn *cdpConn, x, y float64) error {
+	for _, event := range []map[string]interface{}{
+		{"type": "mouseMoved", "x": x, "y": y},
+		{"type": "mousePressed", "x": x, "y": y, "button": "left", "clickCount": 1},
+		{"type": "mouseReleased", "x": x, "y": y, "button": "left", "clickCount": 1},
+	} {
+		if _, err := conn.call(ctx, "Input.dispatchMouseEvent", event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typeKeys types text key by key into the focused element, pausing
+// between keystrokes when stealth is on. Newlines press Enter and tabs
+// press Tab.
+func (t *BrowserTool) typeKeys(ctx context.Context, conn *cdpConn, text string) error {
+	for _, r := range text {
+		down := map[string]interface{}{"type": "keyDown", "key": string(r), "text": string(r), "unmodifiedText": string(r)}
+		switch r {
+		case '\n':
+			down = map[string]interface{}{"type": "keyDown", "key": "Enter", "code": "Enter", "windowsVirtualKeyCode": 13, "text": "\r"}
+		case '\t':
+			down = map[string]interface{}{"type": "keyDown", "key": "Tab", "code": "Tab", "windowsVirtualKeyCode": 9}
+		}
+		if _, err := conn.call(ctx, "Input.dispatchKeyEvent", down); err != nil {
+			return err
+		}
+		up := map[string]interface{}{"type": "keyUp", "key": down["key"]}
+		if code, ok := down["code"]; ok {
+			up["code"], up["windowsVirtualKeyCode"] = code, down["windowsVirtualKeyCode"]
+		}
+		if _, err := conn.call(ctx, "Input.dispatchKeyEvent", up); err != nil {
+			return err
+		}
+		if err := t.pause(ctx, keystrokeDelayMin, keystrokeDelayMax); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pause waits a random time between lo and hi when stealth is on.
+func (t *BrowserTool) pause(ctx context.Context, lo, hi time.Duration) error {
+	if !t.browserCfg.Stealth {
+		return nil
+	}
+	return sleepCtx(ctx, lo+time.Duration(rand.Int63n(int64(hi-lo))))
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// focusAndClear clicks the element to focus it, then selects what it
+// holds so typing replaces it.
+func focusAndClear(ctx context.Context, conn *cdpConn, selector string, el pageElement) error {
+	if err := clickAt(ctx, conn, el.X, el.Y); err != nil {
+		return err
+	}
+	quoted, _ := json.Marshal(selector)
+	var ok bool
+	return evaluate(ctx, conn, fmt.Sprintf(`(function() {
+		var el = document.querySelector(%s);
+		if (!el) return "false";
+		if (document.activeElement !== el) el.focus();
+		if (typeof el.select === "function") el.select();
+		else if (el.isContentEditable) window.getSelection().selectAllChildren(el);
+		return "true";
+	})()`, quoted), &ok)
+}
+
+// clickElement clicks an element on the active tab with real mouse events.
+func (t *BrowserTool) clickElement(ctx context.Context, params map[string]interface{}) (string, error) {
+	selector, err := GetStringParam(params, "selector")
+	if err != nil {
+		return "", fmt.Errorf("browser_use click_element: %w", err)
+	}
+	if selector == "" {
+		return "", fmt.Errorf("browser_use click_element: selector cannot be empty")
+	}
+
+	conn, err := t.connectTab(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("browser_use click_element: %w", err)
+	}
+	defer conn.close()
+
+	el, err := locateElement(ctx, conn, selector)
+	if err != nil {
+		return "", fmt.Errorf("browser_use click_element: %w", err)
+	}
+	if err := clickAt(ctx, conn, el.X, el.Y); err != nil {
+		return "", fmt.Errorf("browser_use click_element: %w", err)
+	}
+	return fmt.Sprintf("Clicked element %q: <%s> %q", selector, el.Tag, el.Text), nil
+}
+
+// typeText types text into an input element key by key, replacing what it
+// held.
+func (t *BrowserTool) typeText(ctx context.Context, params map[string]interface{}) (string, error) {
+	selector, err := GetStringParam(params, "selector")
+	if err != nil {
+		return "", fmt.Errorf("browser_use type_text: %w", err)
+	}
+	if selector == "" {
+		return "", fmt.Errorf("browser_use type_text: selector cannot be empty")
+	}
+
+	text, err := GetStringParam(params, "text")
+	if err != nil {
+		return "", fmt.Errorf("browser_use type_text: %w", err)
+	}
+
+	conn, err := t.connectTab(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("browser_use type_text: %w", err)
+	}
+	defer conn.close()
+
+	el, err := locateElement(ctx, conn, selector)
+	if err != nil {
+		return "", fmt.Errorf("browser_use type_text: %w", err)
+	}
+	if err := focusAndClear(ctx, conn, selector, el); err != nil {
+		return "", fmt.Errorf("browser_use type_text: %w", err)
+	}
+	if err := t.typeKeys(ctx, conn, text); err != nil {
+		return "", fmt.Errorf("browser_use type_text: %w", err)
+	}
+	return fmt.Sprintf("Typed %d characters into %q (<%s>).", len([]rune(text)), selector, el.Tag), nil
+}
+
+// formField is a field of fill_form in the order the page has them.
+type formField struct {
+	selector string
+	value    string
+	order    int
+}
+
+// fillForm fills the fields, given as selector→value, in page order and
+// submits their form unless told not to.
+func (t *BrowserTool) fillForm(ctx context.Context, params map[string]interface{}) (string, error) {
+	values, err := GetMapParam(params, "fields")
+	if err != nil {
+		return "", fmt.Errorf("browser_use fill_form: %w", err)
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("browser_use fill_form: fields cannot be empty")
+	}
+
+	conn, err := t.connectTab(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("browser_use fill_form: %w", err)
+	}
+	defer conn.close()
+
+	fields := make([]formField, 0, len(values))
+	for selector, v := range values {
+		el, err := locateElement(ctx, conn, selector)
+		if err != nil {
+			return "", fmt.Errorf("browser_use fill_form: %w", err)
+		}
+		value, ok := v.(string)
+		if !ok {
+			value = fmt.Sprint(v)
+		}
+		fields = append(fields, formField{selector: selector, value: value, order: el.Order})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].order < fields[j].order })
+
+	var report strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			if err := t.pause(ctx, fieldDelayMin, fieldDelayMax); err != nil {
+				return "", err
+			}
+		}
+		done, err := t.fillField(ctx, conn, f)
+		if err != nil {
+			return "", fmt.Errorf("browser_use fill_form: %s: %w", f.selector, err)
+		}
+		fmt.Fprintf(&report, "- %s: %s\n", f.selector, done)
+	}
+
+	if !GetBoolParamOr(params, "submit", true) {
+		return "Filled the form:\n" + report.String(), nil
+	}
+	how, err := submitForm(ctx, conn, GetStringParamOr(params, "selector", ""), fields[len(fields)-1].selector)
+	if err != nil {
+		return "", fmt.Errorf("browser_use fill_form: %w", err)
+	}
+	return fmt.Sprintf("Filled the form:\n%sSubmitted it %s. Use wait_for_navigation to wait for the next page.", report.String(), how), nil
+}
+
+// fillField sets one field the way a user would: typing into text fields,
+// clicking checkboxes and radio buttons, choosing options of selects.
+func (t *BrowserTool) fillField(ctx context.Context, conn *cdpConn, f formField) (string, error) {
+	el, err := locateElement(ctx, conn, f.selector)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case el.Tag == "input" && (el.Type == "checkbox" || el.Type == "radio"):
+		want := isTruthy(f.value)
+		if el.Checked != want {
+			if err := clickAt(ctx, conn, el.X, el.Y); err != nil {
+				return "", err
+			}
+		}
+		if want {
+			return "checked", nil
+		}
+		return "unchecked", nil
+	case el.Tag == "input" && el.Type == "file":
+		return "", fmt.Errorf("file inputs cannot be filled")
+	case el.Tag == "select":
+		return selectOption(ctx, conn, f.selector, f.value)
+	}
+
+	if err := focusAndClear(ctx, conn, f.selector, el); err != nil {
+		return "", err
+	}
+	if err := t.typeKeys(ctx, conn, f.value); err != nil {
+		return "", err
+	}
+	if el.Type == "password" {
+		return fmt.Sprintf("typed %d characters", len([]rune(f.value))), nil
+	}
+	return fmt.Sprintf("typed %q", f.value), nil
+}
+
+// selectOption chooses the option of a select whose value or label is
+// value. Selects cannot be driven by key events reliably, so the option is
+// set by script with the events a user's choice fires.
+func selectOption(ctx context.Context, conn *cdpConn, selector, value string) (string, error) {
+	quotedSel, _ := json.Marshal(selector)
+	quotedVal, _ := json.Marshal(value)
+	var chosen struct {
+		Found bool   `json:"found"`
+		Label string `json:"label"`
+	}
+	err := evaluate(ctx, conn, fmt.Sprintf(`(function() {
+		var el = document.querySelector(%s), want = %s;
+		for (var i = 0; i < el.options.length; i++) {
+			var o = el.options[i];
+			if (o.value === want || o.text.trim() === want) {
+				el.focus();
+				el.value = o.value;
+				el.dispatchEvent(new Event("input", {bubbles: true}));
+				el.dispatchEvent(new Event("change", {bubbles: true}));
+				return JSON.stringify({found: true, label: o.text.trim()});
+			}
+		}
+		return JSON.stringify({found: false});
+	})()`, quotedSel, quotedVal), &chosen)
+	if err != nil {
+		return "", err
+	}
+	if !chosen.Found {
+		return "", fmt.Errorf("no option %q", value)
+	}
+	return fmt.Sprintf("chose %q", chosen.Label), nil
+}
+
+// submitForm submits the form holding the field at last: by clicking the
+// button at selector, or else the form's submit button, or else by
+// pressing Enter in the field.
+func submitForm(ctx context.Context, conn *cdpConn, selector, last string) (string, error) {
+	if selector != "" {
+		el, err := locateElement(ctx, conn, selector)
+		if err != nil {
+			return "", err
+		}
+		if err := clickAt(ctx, conn, el.X, el.Y); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("by clicking %q", selector), nil
+	}
+
+	quoted, _ := json.Marshal(last)
+	var button pageElement
+	err := evaluate(ctx, conn, fmt.Sprintf(`(function() {
+		var el = document.querySelector(%s);
+		var form = el && (el.form || el.closest("form"));
+		var b = form && form.querySelector("button[type=submit], input[type=submit], button:not([type])");
+		if (!b) return JSON.stringify({found: false});
+		b.scrollIntoView({block: "center", inline: "center"});
+		var r = b.getBoundingClientRect();
+		return JSON.stringify({found: true, x: r.left + r.width / 2, y: r.top + r.height / 2,
+			text: (b.innerText || b.value || "").trim().substring(0, 100)});
+	})()`, quoted), &button)
+	if err != nil {
+		return "", err
+	}
+	if button.Found {
+		if err := clickAt(ctx, conn, button.X, button.Y); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("with its %q button", button.Text), nil
+	}
+
+	el, err := locateElement(ctx, conn, last)
+	if err != nil {
+		return "", err
+	}
+	if err := clickAt(ctx, conn, el.X, el.Y); err != nil {
+		return "", err
+	}
+	for _, event := range []map[string]interface{}{
+		{"type": "keyDown", "key": "Enter", "code": "Enter", "windowsVirtualKeyCode": 13, "text": "\r"},
+		{"type": "keyUp", "key": "Enter", "code": "Enter", "windowsVirtualKeyCode": 13},
+	} {
+		if _, err := conn.call(ctx, "Input.dispatchKeyEvent", event); err != nil {
+			return "", err
+		}
+	}
+	return "by pressing Enter", nil
+}
+
+// isTruthy reports whether a form value means checked.
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "on", "1", "checked", "x":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// fakeForm is a tab showing a login form. It records the input events it
+// receives as "mouse x,y" and "key <text or key>".
+type fakeForm struct {
+	mu     sync.Mutex
+	events []string
+}
+
+// formElements are the elements of the fake form by selector.
+var formElements = map[string]pageElement{
+	"#user":     {Found: true, X: 100, Y: 50, Tag: "input", Type: "text", Order: 12},
+	"#pass":     {Found: true, X: 100, Y: 90, Tag: "input", Type: "password", Order: 13},
+	"#remember": {Found: true, X: 20, Y: 130, Tag: "input", Type: "checkbox", Order: 14},
+	"#lang":     {Found: true, X: 100, Y: 170, Tag: "select", Order: 15},
+}
+
+func (f *fakeForm) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var cmd struct {
+				ID     int                    `json:"id"`
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			}
+			if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+				return
+			}
+			result := map[string]interface{}{}
+			switch cmd.Method {
+			case "Input.dispatchMouseEvent":
+				if cmd.Params["type"] == "mousePressed" {
+					f.record(fmt.Sprintf("mouse %v,%v", cmd.Params["x"], cmd.Params["y"]))
+				}
+			case "Input.dispatchKeyEvent":
+				if cmd.Params["type"] == "keyDown" {
+					f.record(fmt.Sprintf("key %q", cmd.Params["key"]))
+				}
+			case "Runtime.evaluate":
+				result["result"] = map[string]string{"type": "string", "value": f.evaluate(cmd.Params["expression"].(string))}
+			}
+			websocket.JSON.Send(ws, map[string]interface{}{"id": cmd.ID, "result": result})
+		}
+	}))
+}
+
+func (f *fakeForm) record(event string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+}
+
+func (f *fakeForm) evaluate(expr string) string {
+	switch {
+	case strings.Contains(expr, "el.options"):
+		return `{"found": true, "label": "English"}`
+	case strings.Contains(expr, "form.querySelector"):
+		return `{"found": true, "x": 100, "y": 210, "text": "Log in"}`
+	case strings.Contains(expr, "el.select()"):
+		return "true"
+	}
+	for selector, el := range formElements {
+		quoted, _ := json.Marshal(selector)
+		if strings.Contains(expr, string(quoted)) {
+			data, _ := json.Marshal(el)
+			return string(data)
+		}
+	}
+	return `{"found": false}`
+}
+
+func TestBrowserInput(t *testing.T) {
+	f := &fakeForm{}
+	srv := f.serve(t)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialCDP(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.close()
+
+	tool := NewBrowserTool(testBrowserConfig(t))
+	tool.browserCfg.Stealth = false
+
+	for _, field := range []formField{
+		{selector: "#user", value: "ann"},
+		{selector: "#pass", value: "p\n"},
+		{selector: "#remember", value: "true"},
+		{selector: "#lang", value: "en"},
+	} {
+		if _, err := tool.fillField(ctx, conn, field); err != nil {
+			t.Fatalf("%s: %v", field.selector, err)
+		}
+	}
+	how, err := submitForm(ctx, conn, "", "#pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if how != `with its "Log in" button` {
+		t.Errorf("submitted %s", how)
+	}
+	if _, err := locateElement(ctx, conn, "#missing"); err == nil {
+		t.Error("found a missing element")
+	}
+
+	// Fields are clicked into and typed key by key; the checkbox is
+	// clicked, the select is not typed into
+	want := []string{
+		"mouse 100,50", `key "a"`, `key "n"`, `key "n"`,
+		"mouse 100,90", `key "p"`, `key "Enter"`,
+		"mouse 20,130",
+		"mouse 100,210",
+	}
+	if got := strings.Join(f.events, " | "); got != strings.Join(want, " | ") {
+		t.Errorf("events =\n%s\nwant\n%s", got, strings.Join(want, " | "))
+	}
+
+	// With stealth, keystrokes are spaced out
+	tool.browserCfg.Stealth = true
+	start := time.Now()
+	if err := tool.typeKeys(ctx, conn, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 3*keystrokeDelayMin {
+		t.Errorf("typed 3 keys in %s with stealth", elapsed)
+	}
+}
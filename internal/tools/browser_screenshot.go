@@ -0,0 +1,222 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/tracing"
+)
+
+const (
+	// maxScreenshotBytes is the largest screenshot sent to a chat; Telegram
+	// takes photos of up to 10 MB. Larger PNGs are taken again as JPEG.
+	maxScreenshotBytes = 10 << 20
+	// screenshotJPEGQuality is the quality of those JPEGs.
+	screenshotJPEGQuality = 75
+	// Screenshots are removed once older than screenshotRetention, and
+	// beyond the newest maxScreenshots.
+	screenshotRetention = 24 * time.Hour
+	maxScreenshots      = 50
+	// screenshotLayout names screenshot files by the time they were taken.
+	screenshotLayout = "20060102-150405.000"
+)
+
+// screenshotClip returns the clip rect, in page coordinates, of the element
+// matching selector.
+func screenshotClip(ctx context.Context, conn *cdpConn, selector string) (map[string]interface{}, error) {
+	quoted, _ := json.Marshal(selector)
+	var rect struct {
+		Found  bool    `json:"found"`
+		X      float64 `json:"x"`
+		Y      float64 `json:"y"`
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	}
+	err := evaluate(ctx, conn, fmt.Sprintf(`(function() {
+		var el = document.querySelector(%s);
+		if (!el) return JSON.stringify({found: false});
+		var r = el.getBoundingClientRect();
+		return JSON.stringify({found: true, x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height});
+	})()`, quoted), &rect)
+	if err != nil {
+		return nil, err
+	}
+	if !rect.Found {
+		return nil, fmt.Errorf("element not found: %s", selector)
+	}
+	if rect.Width < 1 || rect.Height < 1 {
+		return nil, fmt.Errorf("element %s is not visible", selector)
+	}
+	return map[string]interface{}{"x": rect.X, "y": rect.Y, "width": rect.Width, "height": rect.Height, "scale": 1}, nil
+}
+
+// captureScreenshot takes a screenshot of conn's page, of clip when set.
+// It returns the image and its MIME type, taking a JPEG when a PNG would be
+// larger than maxScreenshotBytes.
+func captureScreenshot(ctx context.Context, conn *cdpConn, clip map[string]interface{}) ([]byte, string, error) {
+	capture := func(params map[string]interface{}) ([]byte, error) {
+		if clip != nil {
+			params["clip"] = clip
+			params["captureBeyondViewport"] = true
+		}
+		res, err := conn.call(ctx, "Page.captureScreenshot", params)
+		if err != nil {
+			return nil, err
+		}
+		var shot struct {
+			Data string `json:"data"`
+		}
+		if err := json.Unmarshal(res, &shot); err != nil {
+			return nil, fmt.Errorf("failed to parse the screenshot: %w", err)
+		}
+		return base64.StdEncoding.DecodeString(shot.Data)
+	}
+
+	data, err := capture(map[string]interface{}{"format": "png"})
+	if err != nil || len(data) <= maxScreenshotBytes {
+		return data, "image/png", err
+	}
+	data, err = capture(map[string]interface{}{"format": "jpeg", "quality": screenshotJPEGQuality})
+	return data, "image/jpeg", err
+}
+
+// saveScreenshot writes data to dir and removes old screenshots.
+func saveScreenshot(dir string, data []byte, mimeType string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	ext := ".png"
+	if mimeType == "image/jpeg" {
+		ext = ".jpg"
+	}
+	path := filepath.Join(dir, "screenshot-"+time.Now().Format(screenshotLayout)+ext)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	pruneScreenshots(dir, time.Now())
+	return path, nil
+}
+
+// pruneScreenshots removes screenshots older than screenshotRetention and
+// all but the newest maxScreenshots.
+func pruneScreenshots(dir string, now time.Time) {
+	files, _ := filepath.Glob(filepath.Join(dir, "screenshot-*"))
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	for i, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if i >= maxScreenshots || now.Sub(info.ModTime()) > screenshotRetention {
+			os.Remove(f)
+		}
+	}
+}
+
+// sendScreenshot sends the screenshot to the chat the call belongs to,
+// reporting why it was not sent.
+func (t *BrowserTool) sendScreenshot(ctx context.Context, path string, data []byte, caption string) error {
+	t.mu.Lock()
+	send := t.send
+	t.mu.Unlock()
+	req, ok := RequestFromContext(ctx)
+	switch {
+	case send == nil || !ok || req.Channel == "" || req.Channel == "cli":
+		return errors.New("this conversation cannot receive images")
+	case len(data) > maxScreenshotBytes:
+		return fmt.Errorf("it is %d MB, more than the %d MB that can be sent", len(data)>>20, maxScreenshotBytes>>20)
+	}
+	send(bus.OutboundMessage{
+		Channel: req.Channel,
+		ChatID:  req.ChatID,
+		Files:   []bus.File{{Name: filepath.Base(path), Data: data, Caption: caption}},
+		Trace:   tracing.Inject(ctx),
+	})
+	return nil
+}
+
+// screenshot captures the active tab, or the element matching selector,
+// saves it, sends it to the chat as an image and, if a describer is set,
+// appends a text description of it.
+func (t *BrowserTool) screenshot(ctx context.Context, params map[string]interface{}) (string, error) {
+	captureCtx, cancel := context.WithTimeout(ctx, browserActionTimeout)
+	defer cancel()
+
+	conn, err := t.connectTab(captureCtx, params)
+	if err != nil {
+		return "", fmt.Errorf("browser_use screenshot: %w", err)
+	}
+	defer conn.close()
+
+	var page struct {
+		Href string `json:"href"`
+	}
+	if err := evaluate(captureCtx, conn, `JSON.stringify({href: location.href})`, &page); err != nil {
+		return "", fmt.Errorf("browser_use screenshot: %w", err)
+	}
+	if page.Href == "" || page.Href == "about:blank" {
+		return "", fmt.Errorf("browser_use screenshot: no page loaded in the active tab, open one with new_tab")
+	}
+
+	var clip map[string]interface{}
+	selector := GetStringParamOr(params, "selector", "")
+	if selector != "" {
+		if clip, err = screenshotClip(captureCtx, conn, selector); err != nil {
+			return "", fmt.Errorf("browser_use screenshot: %w", err)
+		}
+	}
+	data, mimeType, err := captureScreenshot(captureCtx, conn, clip)
+	if err != nil {
+		return "", fmt.Errorf("browser_use screenshot: %w", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	path, err := saveScreenshot(filepath.Join(home, ".ubot", "workspace", "screenshots"), data, mimeType)
+	if err != nil {
+		return "", fmt.Errorf("browser_use screenshot: %w", err)
+	}
+	result := fmt.Sprintf("Screenshot saved to %s", path)
+
+	if GetBoolParamOr(params, "send", true) {
+		caption := page.Href
+		if selector != "" {
+			caption = fmt.Sprintf("%s on %s", selector, page.Href)
+		}
+		if err := t.sendScreenshot(ctx, path, data, caption); err != nil {
+			result += fmt.Sprintf(" (not sent to the chat: %v)", err)
+		} else {
+			result += " and sent to the chat"
+		}
+	}
+
+	t.mu.Lock()
+	describer := t.describer
+	t.mu.Unlock()
+	if describer == nil || !GetBoolParamOr(params, "describe", true) {
+		return result, nil
+	}
+
+	description, err := t.describeScreenshot(ctx, describer, data, mimeType)
+	if err != nil {
+		// The screenshot itself succeeded; report the description failure inline.
+		return fmt.Sprintf("%s\n\n(Page description unavailable: %v)", result, err), nil
+	}
+	return fmt.Sprintf("%s\n\n--- Page Description ---\n%s", result, description), nil
+}
+
+// describeScreenshot sends the screenshot to the vision model.
+func (t *BrowserTool) describeScreenshot(ctx context.Context, describer ImageDescriber, image []byte, mimeType string) (string, error) {
+	descCtx, cancel := context.WithTimeout(ctx, browserActionTimeout)
+	defer cancel()
+	return describer.DescribeImage(descCtx, image, mimeType, screenshotPrompt)
+}
@@ -0,0 +1,122 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+func TestBrowserScreenshot(t *testing.T) {
+	var mu sync.Mutex
+	var clip map[string]interface{}
+	srv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var cmd struct {
+				ID     int                    `json:"id"`
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			}
+			if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+				return
+			}
+			result := map[string]interface{}{}
+			switch cmd.Method {
+			case "Runtime.evaluate":
+				value := `{"found": true, "x": 10, "y": 1200, "width": 300, "height": 40}`
+				if strings.Contains(cmd.Params["expression"].(string), "#hidden") {
+					value = `{"found": true, "x": 0, "y": 0, "width": 0, "height": 0}`
+				}
+				result["result"] = map[string]string{"type": "string", "value": value}
+			case "Page.captureScreenshot":
+				mu.Lock()
+				clip, _ = cmd.Params["clip"].(map[string]interface{})
+				mu.Unlock()
+				result["data"] = base64.StdEncoding.EncodeToString([]byte("PNG " + fmt.Sprint(cmd.Params["format"])))
+			}
+			websocket.JSON.Send(ws, map[string]interface{}{"id": cmd.ID, "result": result})
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialCDP(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.close()
+
+	// An element is captured by its rect on the page
+	rect, err := screenshotClip(ctx, conn, "#total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, mimeType, err := captureScreenshot(ctx, conn, rect)
+	if err != nil || string(data) != "PNG png" || mimeType != "image/png" {
+		t.Fatalf("capture = %q, %s, %v", data, mimeType, err)
+	}
+	mu.Lock()
+	if clip["y"] != float64(1200) || clip["width"] != float64(300) {
+		t.Errorf("clip = %v", clip)
+	}
+	mu.Unlock()
+	if _, err := screenshotClip(ctx, conn, "#hidden"); err == nil {
+		t.Error("clipped an invisible element")
+	}
+
+	// Screenshots go to the chat the call belongs to
+	tool := NewBrowserTool(testBrowserConfig(t))
+	if err := tool.sendScreenshot(ctx, "shot.png", data, "page"); err == nil {
+		t.Error("sent without a sender")
+	}
+	var sent []bus.OutboundMessage
+	tool.SetSender(func(msg bus.OutboundMessage) { sent = append(sent, msg) })
+	chat := WithRequest(ctx, RequestInfo{Channel: "telegram", ChatID: "42"})
+	if err := tool.sendScreenshot(chat, "shot.png", data, "page"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tool.sendScreenshot(WithRequest(ctx, RequestInfo{Channel: "cli"}), "shot.png", data, "page"); err == nil {
+		t.Error("sent to the CLI")
+	}
+	if err := tool.sendScreenshot(chat, "shot.png", make([]byte, maxScreenshotBytes+1), "page"); err == nil {
+		t.Error("sent an oversized screenshot")
+	}
+	if len(sent) != 1 || sent[0].ChatID != "42" || sent[0].Files[0].Name != "shot.png" || sent[0].Files[0].Caption != "page" {
+		t.Errorf("sent = %+v", sent)
+	}
+}
+
+func TestPruneScreenshots(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i := 0; i < maxScreenshots+5; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("screenshot-%03d.png", i))
+		os.WriteFile(path, []byte("x"), 0o644)
+		if i == maxScreenshots+4 {
+			// The newest by name, but left over from two days ago
+			os.Chtimes(path, now.Add(-48*time.Hour), now.Add(-48*time.Hour))
+		}
+	}
+	pruneScreenshots(dir, now)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "screenshot-*"))
+	if len(files) != maxScreenshots-1 {
+		t.Fatalf("kept %d screenshots, want %d", len(files), maxScreenshots-1)
+	}
+	if filepath.Base(files[0]) != "screenshot-005.png" {
+		t.Errorf("oldest kept = %s", files[0])
+	}
+}
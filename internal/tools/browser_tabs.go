@@ -0,0 +1,473 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+const (
+	// browserWaitTimeout is how long the wait actions wait by default.
+	browserWaitTimeout = 10 * time.Second
+	// browserPollInterval is how often the wait actions look at the page.
+	browserPollInterval = 200 * time.Millisecond
+)
+
+// browserTab is a tab of the browser the tool keeps track of. Tabs are
+// numbered from 1 in the order they were opened.
+type browserTab struct {
+	id       int
+	targetID string
+	wsURL    string
+	url      string // where the tab was when an action last looked at it
+}
+
+// listCDPTargets lists the browser's targets.
+func listCDPTargets(cdpURL string) ([]cdpTargetInfo, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(cdpURL + "/json/list")
+	if err != nil {
+		return nil, fmt.Errorf("browser_use: failed to list CDP targets: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var targets []cdpTargetInfo
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("browser_use: failed to parse CDP targets: %w", err)
+	}
+	return targets, nil
+}
+
+// activeTab returns the tab actions work on. Tabs closed in the browser
+// are forgotten; without tabs, the browser's first page becomes tab 1.
+func (t *BrowserTool) activeTab(ctx context.Context, bi *browserInstance) (*browserTab, error) {
+	targets, err := listCDPTargets(bi.cdpURL)
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]cdpTargetInfo)
+	var first *cdpTargetInfo
+	for i, target := range targets {
+		if target.Type == "page" {
+			pages[target.ID] = target
+			if first == nil {
+				first = &targets[i]
+			}
+		}
+	}
+
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
+	open := bi.tabs[:0]
+	for _, tab := range bi.tabs {
+		if target, ok := pages[tab.targetID]; ok {
+			tab.wsURL = target.WebSocketDebuggerURL
+			open = append(open, tab)
+		}
+	}
+	bi.tabs = open
+
+	if len(bi.tabs) == 0 {
+		if first == nil {
+			created, err := newCDPTarget(ctx, bi.cdpURL)
+			if err != nil {
+				return nil, fmt.Errorf("browser_use: %w", err)
+			}
+			first = created
+		}
+		bi.addTabLocked(*first)
+	}
+	for _, tab := range bi.tabs {
+		if tab.id == bi.activeTab {
+			return tab, nil
+		}
+	}
+	// The active tab was closed; the one opened last takes over
+	tab := bi.tabs[len(bi.tabs)-1]
+	bi.activeTab = tab.id
+	return tab, nil
+}
+
+// addTabLocked tracks target as a new tab and makes it the active one.
+// Must be called with bi.mu held.
+func (bi *browserInstance) addTabLocked(target cdpTargetInfo) *browserTab {
+	bi.nextTab++
+	tab := &browserTab{id: bi.nextTab, targetID: target.ID, wsURL: target.WebSocketDebuggerURL, url: target.URL}
+	bi.tabs = append(bi.tabs, tab)
+	bi.activeTab = tab.id
+	return tab
+}
+
+// tabsSummary lists the tabs, marking the active one.
+func (bi *browserInstance) tabsSummary() string {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+	var sb strings.Builder
+	sb.WriteString("Tabs:")
+	for _, tab := range bi.tabs {
+		mark := " "
+		if tab.id == bi.activeTab {
+			mark = "*"
+		}
+		fmt.Fprintf(&sb, "\n%s %d %s", mark, tab.id, tab.url)
+	}
+	return sb.String()
+}
+
+// activateCDPTarget brings a tab to the front.
+func activateCDPTarget(cdpURL, id string) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	if resp, err := client.Get(cdpURL + "/json/activate/" + id); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// newTab opens a tab, loads url in it when given and makes it the active
+// tab.
+func (t *BrowserTool) newTab(ctx context.Context, params map[string]interface{}) (string, error) {
+	urlStr := GetStringParamOr(params, "url", "")
+	if urlStr != "" {
+		if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
+			urlStr = "https://" + urlStr
+		}
+		if safenet.IsInternalURL(urlStr) {
+			return "", fmt.Errorf("browser_use new_tab: access to internal/private network addresses is blocked")
+		}
+		if err := t.checkDomain(urlStr); err != nil {
+			return "", fmt.Errorf("browser_use new_tab: %w", err)
+		}
+	}
+
+	bi, err := t.ensureBrowser(getSessionParam(params))
+	if err != nil {
+		return "", err
+	}
+	// Adopt the browser's first page before adding to it
+	if _, err := t.activeTab(ctx, bi); err != nil {
+		return "", err
+	}
+	target, err := newCDPTarget(ctx, bi.cdpURL)
+	if err != nil {
+		return "", fmt.Errorf("browser_use new_tab: %w", err)
+	}
+	bi.mu.Lock()
+	tab := bi.addTabLocked(*target)
+	bi.mu.Unlock()
+	activateCDPTarget(bi.cdpURL, tab.targetID)
+
+	if urlStr != "" {
+		landed, err := t.navigateTab(ctx, tab, func(conn *cdpConn) error {
+			res, err := conn.call(ctx, "Page.navigate", map[string]interface{}{"url": urlStr})
+			if err != nil {
+				return err
+			}
+			var nav struct {
+				ErrorText string `json:"errorText"`
+			}
+			json.Unmarshal(res, &nav)
+			if nav.ErrorText != "" {
+				return fmt.Errorf("navigation failed: %s", nav.ErrorText)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("browser_use new_tab: %w", err)
+		}
+		bi.mu.Lock()
+		tab.url = landed
+		bi.mu.Unlock()
+	}
+	return fmt.Sprintf("Opened tab %d.\n%s", tab.id, bi.tabsSummary()), nil
+}
+
+// switchTab makes the given tab the active one.
+func (t *BrowserTool) switchTab(ctx context.Context, params map[string]interface{}) (string, error) {
+	id, err := GetIntParam(params, "tab")
+	if err != nil {
+		return "", fmt.Errorf("browser_use switch_tab: %w", err)
+	}
+	bi, err := t.ensureBrowser(getSessionParam(params))
+	if err != nil {
+		return "", err
+	}
+	if _, err := t.activeTab(ctx, bi); err != nil {
+		return "", err
+	}
+
+	bi.mu.Lock()
+	var tab *browserTab
+	for _, tb := range bi.tabs {
+		if tb.id == id {
+			tab = tb
+		}
+	}
+	if tab != nil {
+		bi.activeTab = id
+	}
+	bi.mu.Unlock()
+	if tab == nil {
+		return "", fmt.Errorf("browser_use switch_tab: no tab %d\n%s", id, bi.tabsSummary())
+	}
+	activateCDPTarget(bi.cdpURL, tab.targetID)
+	return fmt.Sprintf("Switched to tab %d.\n%s", id, bi.tabsSummary()), nil
+}
+
+// closeTab closes the given tab, or the active one. The tab opened last
+// becomes the active one.
+func (t *BrowserTool) closeTab(ctx context.Context, params map[string]interface{}) (string, error) {
+	bi, err := t.ensureBrowser(getSessionParam(params))
+	if err != nil {
+		return "", err
+	}
+	active, err := t.activeTab(ctx, bi)
+	if err != nil {
+		return "", err
+	}
+	id := GetIntParamOr(params, "tab", active.id)
+
+	bi.mu.Lock()
+	var tab *browserTab
+	for i, tb := range bi.tabs {
+		if tb.id == id {
+			tab = tb
+			bi.tabs = append(bi.tabs[:i], bi.tabs[i+1:]...)
+			break
+		}
+	}
+	if tab != nil && id == bi.activeTab && len(bi.tabs) > 0 {
+		bi.activeTab = bi.tabs[len(bi.tabs)-1].id
+	}
+	bi.mu.Unlock()
+	if tab == nil {
+		return "", fmt.Errorf("browser_use close_tab: no tab %d\n%s", id, bi.tabsSummary())
+	}
+	closeCDPTarget(bi.cdpURL, tab.targetID)
+	return fmt.Sprintf("Closed tab %d.\n%s", id, bi.tabsSummary()), nil
+}
+
+// goBack goes back to the previous page in the active tab's history.
+func (t *BrowserTool) goBack(ctx context.Context, params map[string]interface{}) (string, error) {
+	bi, err := t.ensureBrowser(getSessionParam(params))
+	if err != nil {
+		return "", err
+	}
+	tab, err := t.activeTab(ctx, bi)
+	if err != nil {
+		return "", err
+	}
+
+	landed, err := t.navigateTab(ctx, tab, func(conn *cdpConn) error {
+		res, err := conn.call(ctx, "Page.getNavigationHistory", nil)
+		if err != nil {
+			return err
+		}
+		var history struct {
+			CurrentIndex int `json:"currentIndex"`
+			Entries      []struct {
+				ID int `json:"id"`
+			} `json:"entries"`
+		}
+		if err := json.Unmarshal(res, &history); err != nil {
+			return fmt.Errorf("failed to parse the history: %w", err)
+		}
+		if history.CurrentIndex <= 0 || history.CurrentIndex >= len(history.Entries) {
+			return fmt.Errorf("no previous page in tab %d", tab.id)
+		}
+		_, err = conn.call(ctx, "Page.navigateToHistoryEntry", map[string]interface{}{
+			"entryId": history.Entries[history.CurrentIndex-1].ID,
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("browser_use go_back: %w", err)
+	}
+	bi.mu.Lock()
+	tab.url = landed
+	bi.mu.Unlock()
+	return fmt.Sprintf("Went back to %s in tab %d.", landed, tab.id), nil
+}
+
+// navigateTab connects to tab, starts a navigation with navigate and waits
+// until the page has loaded and the network is idle. It returns the URL the
+// tab landed on, refusing internal addresses and sites the domain lists
+// exclude.
+func (t *BrowserTool) navigateTab(ctx context.Context, tab *browserTab, navigate func(conn *cdpConn) error) (string, error) {
+	conn, err := dialCDP(ctx, tab.wsURL)
+	if err != nil {
+		return "", err
+	}
+	defer conn.close()
+
+	for _, method := range []string{"Page.enable", "Network.enable"} {
+		if _, err := conn.call(ctx, method, nil); err != nil {
+			return "", err
+		}
+	}
+	if err := navigate(conn); err != nil {
+		return "", err
+	}
+	if err := conn.waitIdle(ctx); err != nil {
+		return "", err
+	}
+
+	var page struct {
+		Href string `json:"href"`
+	}
+	if err := evaluate(ctx, conn, `JSON.stringify({href: location.href})`, &page); err != nil {
+		return "", err
+	}
+	if safenet.IsInternalURL(page.Href) {
+		conn.call(ctx, "Page.navigate", map[string]interface{}{"url": "about:blank"})
+		return "", fmt.Errorf("the page ended up on an internal/private network address, which is blocked")
+	}
+	if err := t.checkDomain(page.Href); err != nil {
+		conn.call(ctx, "Page.navigate", map[string]interface{}{"url": "about:blank"})
+		return "", fmt.Errorf("the page ended up on %s, which is not allowed: %w", page.Href, err)
+	}
+	return page.Href, nil
+}
+
+// waitForSelector waits until an element matching the selector is on the
+// active tab's page.
+func (t *BrowserTool) waitForSelector(ctx context.Context, params map[string]interface{}) (string, error) {
+	selector, err := GetStringParam(params, "selector")
+	if err != nil {
+		return "", fmt.Errorf("browser_use wait_for_selector: %w", err)
+	}
+	if selector == "" {
+		return "", fmt.Errorf("browser_use wait_for_selector: selector cannot be empty")
+	}
+
+	bi, err := t.ensureBrowser(getSessionParam(params))
+	if err != nil {
+		return "", err
+	}
+	tab, err := t.activeTab(ctx, bi)
+	if err != nil {
+		return "", err
+	}
+
+	timeout := waitTimeout(params)
+	quoted, _ := json.Marshal(selector)
+	found, err := pollTab(ctx, bi, tab, timeout,
+		fmt.Sprintf(`JSON.stringify({done: !!document.querySelector(%s), href: location.href})`, quoted))
+	if err != nil {
+		return "", fmt.Errorf("browser_use wait_for_selector: %w", err)
+	}
+	if !found {
+		return "", fmt.Errorf("browser_use wait_for_selector: %q did not appear in tab %d within %s", selector, tab.id, timeout)
+	}
+	return fmt.Sprintf("Element %q is on the page in tab %d.", selector, tab.id), nil
+}
+
+// waitForNavigation waits until the active tab has moved on from the page
+// the last action saw there and the new page has loaded.
+func (t *BrowserTool) waitForNavigation(ctx context.Context, params map[string]interface{}) (string, error) {
+	bi, err := t.ensureBrowser(getSessionParam(params))
+	if err != nil {
+		return "", err
+	}
+	tab, err := t.activeTab(ctx, bi)
+	if err != nil {
+		return "", err
+	}
+	bi.mu.Lock()
+	from := tab.url
+	bi.mu.Unlock()
+
+	timeout := waitTimeout(params)
+	quoted, _ := json.Marshal(from)
+	found, err := pollTab(ctx, bi, tab, timeout,
+		fmt.Sprintf(`JSON.stringify({done: location.href !== %s && document.readyState === "complete", href: location.href})`, quoted))
+	if err != nil {
+		return "", fmt.Errorf("browser_use wait_for_navigation: %w", err)
+	}
+	if !found {
+		return "", fmt.Errorf("browser_use wait_for_navigation: tab %d did not leave %s within %s", tab.id, from, timeout)
+	}
+	bi.mu.Lock()
+	landed := tab.url
+	bi.mu.Unlock()
+	if err := t.leaveDisallowed(ctx, tab, landed); err != nil {
+		return "", fmt.Errorf("browser_use wait_for_navigation: %w", err)
+	}
+	return fmt.Sprintf("Tab %d loaded %s.", tab.id, landed), nil
+}
+
+// pollTab evaluates check on tab's page until it reports done or timeout
+// passes. check must return JSON with "done" and "href"; the tab is
+// recorded at the href it was seen at when done.
+func pollTab(ctx context.Context, bi *browserInstance, tab *browserTab, timeout time.Duration, check string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := dialCDP(ctx, tab.wsURL)
+	if err != nil {
+		return false, err
+	}
+	defer conn.close()
+
+	ticker := time.NewTicker(browserPollInterval)
+	defer ticker.Stop()
+	for {
+		var state struct {
+			Done bool   `json:"done"`
+			Href string `json:"href"`
+		}
+		// A page being replaced may not answer; the next poll asks the new one
+		if err := evaluate(ctx, conn, check, &state); err == nil && state.Done {
+			bi.mu.Lock()
+			tab.url = state.Href
+			bi.mu.Unlock()
+			return true, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-conn.done:
+			return false, fmt.Errorf("connection to Chrome closed: %v", conn.err)
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return false, nil
+			}
+			return false, ctx.Err()
+		}
+	}
+}
+
+// waitTimeout returns the timeout parameter of the wait actions, bounded
+// by the action timeout.
+func waitTimeout(params map[string]interface{}) time.Duration {
+	timeout := time.Duration(GetIntParamOr(params, "timeout", int(browserWaitTimeout/time.Second))) * time.Second
+	if timeout <= 0 || timeout > browserActionTimeout {
+		return browserActionTimeout
+	}
+	return timeout
+}
+
+// evaluate runs expression, which must return a JSON string, on conn's page
+// and decodes the result into out.
+func evaluate(ctx context.Context, conn *cdpConn, expression string, out interface{}) error {
+	res, err := conn.call(ctx, "Runtime.evaluate", map[string]interface{}{
+		"expression":    expression,
+		"returnByValue": true,
+	})
+	if err != nil {
+		return err
+	}
+	var eval struct {
+		Result struct {
+			Value string `json:"value"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(res, &eval); err != nil || json.Unmarshal([]byte(eval.Result.Value), out) != nil {
+		return fmt.Errorf("failed to read the page")
+	}
+	return nil
+}
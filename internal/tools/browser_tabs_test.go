@@ -0,0 +1,182 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// fakeBrowser serves the CDP endpoints for listing, opening and closing
+// tabs. Each tab's page is at href, which moves to next after the page
+// has been asked about it a few times.
+type fakeBrowser struct {
+	srv *httptest.Server
+
+	mu      sync.Mutex
+	targets []cdpTargetInfo
+	polls   int
+	next    string
+}
+
+func newFakeBrowser(t *testing.T) *fakeBrowser {
+	b := &fakeBrowser{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/list", func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		json.NewEncoder(w).Encode(b.targets)
+	})
+	mux.HandleFunc("/json/new", func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		json.NewEncoder(w).Encode(b.addLocked("about:blank"))
+	})
+	mux.HandleFunc("/json/activate/", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/json/close/", func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		id := strings.TrimPrefix(r.URL.Path, "/json/close/")
+		for i, target := range b.targets {
+			if target.ID == id {
+				b.targets = append(b.targets[:i], b.targets[i+1:]...)
+				break
+			}
+		}
+	})
+	mux.Handle("/devtools/", websocket.Handler(func(ws *websocket.Conn) {
+		id := strings.TrimPrefix(ws.Request().URL.Path, "/devtools/")
+		for {
+			var cmd struct {
+				ID     int                    `json:"id"`
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			}
+			if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+				return
+			}
+			result := map[string]interface{}{}
+			if cmd.Method == "Runtime.evaluate" {
+				expr, _ := cmd.Params["expression"].(string)
+				result["result"] = map[string]string{"type": "string", "value": b.evaluate(id, expr)}
+			}
+			websocket.JSON.Send(ws, map[string]interface{}{"id": cmd.ID, "result": result})
+		}
+	}))
+	b.srv = httptest.NewServer(mux)
+	t.Cleanup(b.srv.Close)
+	b.addLocked("https://app.example/login")
+	return b
+}
+
+func (b *fakeBrowser) addLocked(url string) cdpTargetInfo {
+	id := fmt.Sprintf("T%d", len(b.targets)+1)
+	target := cdpTargetInfo{ID: id, Type: "page", URL: url,
+		WebSocketDebuggerURL: "ws" + strings.TrimPrefix(b.srv.URL, "http") + "/devtools/" + id}
+	b.targets = append(b.targets, target)
+	return target
+}
+
+// evaluate answers the wait actions' checks: the page has a #report
+// element and moves on to next after three polls.
+func (b *fakeBrowser) evaluate(id, expr string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var href string
+	for i, target := range b.targets {
+		if target.ID == id {
+			if b.polls++; b.polls > 3 && b.next != "" {
+				b.targets[i].URL, b.next = b.next, ""
+			}
+			href = b.targets[i].URL
+		}
+	}
+	done := strings.Contains(expr, "#report")
+	if strings.Contains(expr, "location.href !==") {
+		done = !strings.Contains(expr, fmt.Sprintf("%q", href))
+	}
+	state, _ := json.Marshal(map[string]interface{}{"done": done, "href": href})
+	return string(state)
+}
+
+func TestBrowserTabs(t *testing.T) {
+	b := newFakeBrowser(t)
+	tool := NewBrowserTool(testBrowserConfig(t))
+	bi := &browserInstance{cdpURL: b.srv.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The browser's first page becomes tab 1
+	tab, err := tool.activeTab(ctx, bi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tab.id != 1 || tab.url != "https://app.example/login" {
+		t.Fatalf("tab = %+v", tab)
+	}
+
+	// A click on tab 1 moves it to the dashboard; waiting notices once the
+	// page has moved on
+	b.mu.Lock()
+	b.next = "https://app.example/dashboard"
+	b.mu.Unlock()
+	found, err := pollTab(ctx, bi, tab, time.Second, `JSON.stringify({done: location.href !== "https://app.example/login"})`)
+	if err != nil || !found {
+		t.Fatalf("navigation not seen: %v", err)
+	}
+	if tab.url != "https://app.example/dashboard" {
+		t.Errorf("tab url = %s", tab.url)
+	}
+	found, err = pollTab(ctx, bi, tab, 300*time.Millisecond, `JSON.stringify({done: location.href !== "https://app.example/dashboard"})`)
+	if err != nil || found {
+		t.Errorf("waiting on a page that stays = %v, %v; want a timeout", found, err)
+	}
+	if found, err := pollTab(ctx, bi, tab, time.Second, `document.querySelector("#report")`); err != nil || !found {
+		t.Errorf("selector not found: %v", err)
+	}
+
+	// Opened tabs become active; closing the active one falls back to the
+	// one opened last
+	target, err := newCDPTarget(ctx, b.srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bi.mu.Lock()
+	bi.addTabLocked(*target)
+	bi.mu.Unlock()
+	if tab, _ := tool.activeTab(ctx, bi); tab.id != 2 {
+		t.Errorf("active tab = %d, want 2", tab.id)
+	}
+	if got := bi.tabsSummary(); got != "Tabs:\n  1 https://app.example/dashboard\n* 2 about:blank" {
+		t.Errorf("summary = %q", got)
+	}
+	closeCDPTarget(b.srv.URL, target.ID)
+	if tab, _ := tool.activeTab(ctx, bi); tab.id != 1 || len(bi.tabs) != 1 {
+		t.Errorf("active tab = %d of %d, want 1 of 1", tab.id, len(bi.tabs))
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		params map[string]interface{}
+		want   time.Duration
+	}{
+		{map[string]interface{}{}, browserWaitTimeout},
+		{map[string]interface{}{"timeout": float64(3)}, 3 * time.Second},
+		{map[string]interface{}{"timeout": float64(600)}, browserActionTimeout},
+		{map[string]interface{}{"timeout": float64(-1)}, browserActionTimeout},
+	} {
+		if got := waitTimeout(tc.params); got != tc.want {
+			t.Errorf("waitTimeout(%v) = %s, want %s", tc.params, got, tc.want)
+		}
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !lite && !nobrowser
+
 package tools
 
 import (
@@ -52,7 +54,7 @@ func TestBrowserTool_ParameterSchema(t *testing.T) {
 		t.Fatal("properties should be a map")
 	}
 
-	expectedFields := []string{"action", "url", "selector", "text", "session"}
+	expectedFields := []string{"action", "url", "selector", "text", "tab", "timeout", "session"}
 	for _, field := range expectedFields {
 		if _, exists := properties[field]; !exists {
 			t.Errorf("missing expected field %q in properties", field)
@@ -70,13 +72,23 @@ func TestBrowserTool_ParameterSchema(t *testing.T) {
 	}
 
 	expectedActions := map[string]bool{
-		"browse_page":    false,
-		"click_element":  false,
-		"type_text":      false,
-		"extract_text":   false,
-		"screenshot":     false,
-		"list_sessions":  false,
-		"delete_session": false,
+		"browse_page":         false,
+		"click_element":       false,
+		"type_text":           false,
+		"fill_form":           false,
+		"extract_text":        false,
+		"screenshot":          false,
+		"wait_for_selector":   false,
+		"wait_for_navigation": false,
+		"go_back":             false,
+		"new_tab":             false,
+		"switch_tab":          false,
+		"close_tab":           false,
+		"download":            false,
+		"list_sessions":       false,
+		"delete_session":      false,
+		"export_cookies":      false,
+		"import_cookies":      false,
 	}
 	for _, a := range enum {
 		if _, exists := expectedActions[a]; !exists {
@@ -131,6 +143,32 @@ func TestBrowserTool_ValidationInvalidAction(t *testing.T) {
 	}
 }
 
+func TestBrowserTool_AllowedActions(t *testing.T) {
+	tool := NewBrowserTool(testBrowserConfig(t))
+	unknown := tool.SetAllowedActions([]string{"browse_page", "extract_text", "list_sessions", "submit_form"})
+	if len(unknown) != 1 || unknown[0] != "submit_form" {
+		t.Errorf("unknown = %v, want [submit_form]", unknown)
+	}
+
+	props := tool.Parameters()["properties"].(map[string]interface{})
+	enum := props["action"].(map[string]interface{})["enum"].([]string)
+	if strings.Join(enum, ",") != "browse_page,extract_text,list_sessions" {
+		t.Errorf("schema enum = %v, want only the allowed actions", enum)
+	}
+
+	for _, action := range []string{"click_element", "type_text"} {
+		_, err := tool.Execute(context.Background(), map[string]interface{}{
+			"action": action, "selector": "#submit", "text": "x",
+		})
+		if err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("%s: err = %v, want not allowed", action, err)
+		}
+	}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"action": "list_sessions"}); err != nil {
+		t.Errorf("allowed action failed: %v", err)
+	}
+}
+
 func TestBrowserTool_BrowsePageMissingURL(t *testing.T) {
 	tool := NewBrowserTool(testBrowserConfig(t))
 	_, err := tool.Execute(context.Background(), map[string]interface{}{
@@ -291,12 +329,12 @@ func TestCollapseWhitespace(t *testing.T) {
 func TestFindChromeBinary(t *testing.T) {
 	// This test just verifies the function doesn't panic.
 	// The result depends on the system's Chrome installation.
-	path, err := findChromeBinary()
+	path, err := FindChromeBinary()
 	if err != nil {
 		t.Skipf("no Chrome binary found (expected in CI): %v", err)
 	}
 	if path == "" {
-		t.Error("findChromeBinary returned empty path without error")
+		t.Error("FindChromeBinary returned empty path without error")
 	}
 }
 
@@ -0,0 +1,358 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/calendar"
+)
+
+const (
+	// defaultCalendarDays is how far ahead list and freebusy look by default.
+	defaultCalendarDays = 7
+	// defaultReminderMinutes is how far ahead upcoming looks by default.
+	defaultReminderMinutes = 15
+	// maxCalendarEvents limits how many events list shows.
+	maxCalendarEvents = 100
+)
+
+// CalendarTool lists and creates events in the user's calendar and checks
+// when they are free. Its upcoming action reports each event once per
+// conversation, for cron jobs that remind the user before meetings.
+type CalendarTool struct {
+	BaseTool
+	cal       calendar.Calendar
+	reminders *calendar.Reminders
+	now       func() time.Time
+}
+
+// NewCalendarTool creates a CalendarTool for cal, recording reminders in
+// reminders.
+func NewCalendarTool(cal calendar.Calendar, reminders *calendar.Reminders) *CalendarTool {
+	return &CalendarTool{
+		BaseTool: NewBaseTool(
+			"calendar",
+			"Use the user's calendar. 'list' shows events between from and to (default the next 7 days), 'create' adds an event, 'freebusy' shows busy and free times, and 'upcoming' returns events starting within the next minutes that this chat has not been reminded of yet. To remind the user before meetings, schedule a cron job every 5 minutes whose instruction says to call upcoming and tell the user about any meeting it returns. Times are local unless they include an offset; use forms like '2026-03-14 15:00' or '2026-03-14'.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"list", "create", "freebusy", "upcoming"},
+						"description": "The action to perform: list, create, freebusy, or upcoming.",
+					},
+					"from": map[string]interface{}{
+						"type":        "string",
+						"description": "Start of the range for 'list' and 'freebusy'. Default: now.",
+					},
+					"to": map[string]interface{}{
+						"type":        "string",
+						"description": "End of the range for 'list' and 'freebusy'. Default: 7 days after from; a date means the end of that day.",
+					},
+					"title": map[string]interface{}{
+						"type":        "string",
+						"description": "The event title. Required for 'create'.",
+					},
+					"start": map[string]interface{}{
+						"type":        "string",
+						"description": "When the event starts, for 'create'. A date alone makes an all-day event.",
+					},
+					"end": map[string]interface{}{
+						"type":        "string",
+						"description": "When the event ends, for 'create'. Default: start plus duration_minutes.",
+					},
+					"duration_minutes": map[string]interface{}{
+						"type":        "integer",
+						"description": "Length of the event for 'create' when end is omitted. Default: 60.",
+					},
+					"location": map[string]interface{}{
+						"type":        "string",
+						"description": "Where the event takes place, for 'create'.",
+					},
+					"description": map[string]interface{}{
+						"type":        "string",
+						"description": "Notes for the event, for 'create'.",
+					},
+					"within_minutes": map[string]interface{}{
+						"type":        "integer",
+						"description": "For 'upcoming', how many minutes ahead to look. Default: 15.",
+					},
+				},
+				"required": []string{"action"},
+			},
+		),
+		cal:       cal,
+		reminders: reminders,
+		now:       time.Now,
+	}
+}
+
+// Execute runs the calendar tool action.
+func (t *CalendarTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	action, err := GetStringParam(params, "action")
+	if err != nil {
+		return "", fmt.Errorf("calendar: %w", err)
+	}
+
+	switch action {
+	case "list":
+		return t.list(ctx, params)
+	case "create":
+		return t.create(ctx, params)
+	case "freebusy":
+		return t.freeBusy(ctx, params)
+	case "upcoming":
+		return t.upcoming(ctx, params)
+	default:
+		return "", fmt.Errorf("calendar: unknown action %q (use list, create, freebusy, or upcoming)", action)
+	}
+}
+
+func (t *CalendarTool) list(ctx context.Context, params map[string]interface{}) (string, error) {
+	from, to, err := t.timeRange(params)
+	if err != nil {
+		return "", fmt.Errorf("calendar list: %w", err)
+	}
+	events, err := t.cal.Events(ctx, from, to)
+	if err != nil {
+		return "", fmt.Errorf("calendar list: %w", err)
+	}
+	if len(events) == 0 {
+		return fmt.Sprintf("No events between %s and %s.", formatCalendarTime(from), formatCalendarTime(to)), nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d event(s) between %s and %s:\n", len(events), formatCalendarTime(from), formatCalendarTime(to))
+	for _, ev := range events[:min(len(events), maxCalendarEvents)] {
+		sb.WriteString("- ")
+		writeCalendarEvent(&sb, ev)
+	}
+	if len(events) > maxCalendarEvents {
+		fmt.Fprintf(&sb, "(%d more not shown; narrow the range)\n", len(events)-maxCalendarEvents)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+func (t *CalendarTool) create(ctx context.Context, params map[string]interface{}) (string, error) {
+	title, err := GetStringParam(params, "title")
+	if err != nil || strings.TrimSpace(title) == "" {
+		return "", errors.New("calendar create: title is required")
+	}
+	startStr, err := GetStringParam(params, "start")
+	if err != nil {
+		return "", fmt.Errorf("calendar create: %w", err)
+	}
+	start, allDay, err := parseCalendarTime(startStr)
+	if err != nil {
+		return "", fmt.Errorf("calendar create: %w", err)
+	}
+
+	ev := calendar.Event{
+		Title:       title,
+		Start:       start,
+		AllDay:      allDay,
+		Location:    GetStringParamOr(params, "location", ""),
+		Description: GetStringParamOr(params, "description", ""),
+	}
+	switch endStr := GetStringParamOr(params, "end", ""); {
+	case endStr != "":
+		end, endDate, err := parseCalendarTime(endStr)
+		if err != nil {
+			return "", fmt.Errorf("calendar create: %w", err)
+		}
+		if endDate && allDay {
+			end = end.AddDate(0, 0, 1) // the last day is included
+		}
+		ev.End = end
+	case allDay:
+		ev.End = start.AddDate(0, 0, 1)
+	default:
+		minutes := GetIntParamOr(params, "duration_minutes", 60)
+		if minutes <= 0 {
+			return "", errors.New("calendar create: duration_minutes must be positive")
+		}
+		ev.End = start.Add(time.Duration(minutes) * time.Minute)
+	}
+	if !ev.End.After(ev.Start) {
+		return "", errors.New("calendar create: end must be after start")
+	}
+
+	created, err := t.cal.Create(ctx, ev)
+	if err != nil {
+		return "", fmt.Errorf("calendar create: %w", err)
+	}
+	var sb strings.Builder
+	sb.WriteString("Created: ")
+	writeCalendarEvent(&sb, created)
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+func (t *CalendarTool) freeBusy(ctx context.Context, params map[string]interface{}) (string, error) {
+	from, to, err := t.timeRange(params)
+	if err != nil {
+		return "", fmt.Errorf("calendar freebusy: %w", err)
+	}
+	busy, err := t.cal.Busy(ctx, from, to)
+	if err != nil {
+		return "", fmt.Errorf("calendar freebusy: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Between %s and %s:\n", formatCalendarTime(from), formatCalendarTime(to))
+	if len(busy) == 0 {
+		sb.WriteString("Free the whole time.")
+		return sb.String(), nil
+	}
+	sb.WriteString("Busy:\n")
+	for _, p := range busy {
+		fmt.Fprintf(&sb, "- %s\n", formatCalendarSpan(p.Start, p.End))
+	}
+	sb.WriteString("Free:\n")
+	free := from
+	for _, p := range busy {
+		if p.Start.After(free) {
+			fmt.Fprintf(&sb, "- %s\n", formatCalendarSpan(free, p.Start))
+		}
+		if p.End.After(free) {
+			free = p.End
+		}
+	}
+	if to.After(free) {
+		fmt.Fprintf(&sb, "- %s\n", formatCalendarSpan(free, to))
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// upcoming lists the timed events starting within the next minutes that
+// the conversation has not been told about, and remembers them.
+func (t *CalendarTool) upcoming(ctx context.Context, params map[string]interface{}) (string, error) {
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.SessionKey == "" {
+		return "", errors.New("calendar upcoming: no active conversation")
+	}
+	minutes := GetIntParamOr(params, "within_minutes", defaultReminderMinutes)
+	if minutes <= 0 {
+		minutes = defaultReminderMinutes
+	}
+	now := t.now()
+	until := now.Add(time.Duration(minutes) * time.Minute)
+	events, err := t.cal.Events(ctx, now, until)
+	if err != nil {
+		return "", fmt.Errorf("calendar upcoming: %w", err)
+	}
+
+	var starting []calendar.Event
+	for _, ev := range events {
+		if !ev.AllDay && !ev.Start.Before(now) && ev.Start.Before(until) {
+			starting = append(starting, ev)
+		}
+	}
+	fresh, err := t.reminders.Claim(req.SessionKey, starting, now)
+	if err != nil {
+		return "", fmt.Errorf("calendar upcoming: %w", err)
+	}
+	if len(fresh) == 0 {
+		return fmt.Sprintf("No new events starting in the next %d minutes.", minutes), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Starting soon:\n")
+	for _, ev := range fresh {
+		fmt.Fprintf(&sb, "- in %d min, ", int(ev.Start.Sub(now).Round(time.Minute).Minutes()))
+		writeCalendarEvent(&sb, ev)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// timeRange reads from and to, defaulting to the next seven days. A date
+// as to means the end of that day.
+func (t *CalendarTool) timeRange(params map[string]interface{}) (time.Time, time.Time, error) {
+	from := t.now()
+	if s := GetStringParamOr(params, "from", ""); s != "" {
+		var err error
+		if from, _, err = parseCalendarTime(s); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	to := from.AddDate(0, 0, defaultCalendarDays)
+	if s := GetStringParamOr(params, "to", ""); s != "" {
+		end, date, err := parseCalendarTime(s)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if date {
+			end = end.AddDate(0, 0, 1)
+		}
+		to = end
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, errors.New("to must be after from")
+	}
+	return from, to, nil
+}
+
+// calendarLayouts are the accepted forms of times, in local time unless
+// they carry an offset.
+var calendarLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// parseCalendarTime parses s and reports whether it is a date alone.
+func parseCalendarTime(s string) (time.Time, bool, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return d, true, nil
+	}
+	for _, layout := range calendarLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("cannot read time %q; use a form like 2026-03-14 15:00 or 2026-03-14", s)
+}
+
+func formatCalendarTime(t time.Time) string {
+	return t.Format("Mon 2006-01-02 15:04")
+}
+
+func formatCalendarSpan(start, end time.Time) string {
+	if start.Year() == end.Year() && start.YearDay() == end.YearDay() {
+		return formatCalendarTime(start) + "-" + end.Format("15:04")
+	}
+	return formatCalendarTime(start) + " - " + formatCalendarTime(end)
+}
+
+// writeCalendarEvent writes ev on a line, with its description indented
+// on the next.
+func writeCalendarEvent(sb *strings.Builder, ev calendar.Event) {
+	if ev.AllDay {
+		last := ev.End.AddDate(0, 0, -1)
+		if last.After(ev.Start) {
+			fmt.Fprintf(sb, "%s - %s (all day): %s", ev.Start.Format("Mon 2006-01-02"), last.Format("Mon 2006-01-02"), ev.Title)
+		} else {
+			fmt.Fprintf(sb, "%s (all day): %s", ev.Start.Format("Mon 2006-01-02"), ev.Title)
+		}
+	} else {
+		fmt.Fprintf(sb, "%s: %s", formatCalendarSpan(ev.Start, ev.End), ev.Title)
+	}
+	if ev.Location != "" {
+		fmt.Fprintf(sb, " @ %s", ev.Location)
+	}
+	if ev.Free {
+		sb.WriteString(" [free]")
+	}
+	sb.WriteString("\n")
+	if ev.Description != "" {
+		desc := strings.Join(strings.Fields(ev.Description), " ")
+		if len(desc) > 200 {
+			desc = strings.ToValidUTF8(desc[:200], "") + "..."
+		}
+		fmt.Fprintf(sb, "  %s\n", desc)
+	}
+}
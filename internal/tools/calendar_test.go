@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/calendar"
+)
+
+// fakeCalendar holds events in memory.
+type fakeCalendar struct {
+	events []calendar.Event
+}
+
+func (f *fakeCalendar) Events(_ context.Context, from, to time.Time) ([]calendar.Event, error) {
+	var out []calendar.Event
+	for _, ev := range f.events {
+		if ev.End.After(from) && ev.Start.Before(to) {
+			out = append(out, ev)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCalendar) Create(_ context.Context, ev calendar.Event) (calendar.Event, error) {
+	ev.ID = "new"
+	f.events = append(f.events, ev)
+	return ev, nil
+}
+
+func (f *fakeCalendar) Busy(ctx context.Context, from, to time.Time) ([]calendar.Period, error) {
+	events, _ := f.Events(ctx, from, to)
+	var busy []calendar.Period
+	for _, ev := range events {
+		busy = append(busy, calendar.Period{Start: ev.Start, End: ev.End})
+	}
+	return busy, nil
+}
+
+func TestCalendarTool(t *testing.T) {
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 3, day, hour, min, 0, 0, time.Local) }
+	cal := &fakeCalendar{events: []calendar.Event{
+		{ID: "1", Title: "Standup", Start: at(16, 9, 0), End: at(16, 9, 15), Location: "Room 1"},
+		{ID: "2", Title: "Review", Start: at(16, 14, 0), End: at(16, 15, 0)},
+	}}
+	tool := NewCalendarTool(cal, calendar.NewReminders(filepath.Join(t.TempDir(), "reminders.json")))
+	tool.now = func() time.Time { return at(16, 8, 50) }
+	ctx := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "42", SessionKey: "telegram:42"})
+	run := func(params map[string]interface{}) string {
+		t.Helper()
+		out, err := tool.Execute(ctx, params)
+		if err != nil {
+			t.Fatalf("%v: %v", params, err)
+		}
+		return out
+	}
+
+	out := run(map[string]interface{}{"action": "list", "to": "2026-03-16"})
+	if !strings.Contains(out, "2 event(s)") || !strings.Contains(out, "- Mon 2026-03-16 09:00-09:15: Standup @ Room 1") {
+		t.Errorf("list = %q", out)
+	}
+
+	out = run(map[string]interface{}{"action": "freebusy", "from": "2026-03-16 08:00", "to": "2026-03-16 18:00"})
+	if !strings.Contains(out, "Busy:\n- Mon 2026-03-16 09:00-09:15\n- Mon 2026-03-16 14:00-15:00\nFree:\n- Mon 2026-03-16 08:00-09:00\n- Mon 2026-03-16 09:15-14:00\n- Mon 2026-03-16 15:00-18:00") {
+		t.Errorf("freebusy = %q", out)
+	}
+
+	out = run(map[string]interface{}{"action": "create", "title": "Lunch", "start": "2026-03-16 12:00", "duration_minutes": 45.0})
+	if out != "Created: Mon 2026-03-16 12:00-12:45: Lunch" {
+		t.Errorf("create = %q", out)
+	}
+	out = run(map[string]interface{}{"action": "create", "title": "Trip", "start": "2026-03-20", "end": "2026-03-22"})
+	if out != "Created: Fri 2026-03-20 - Sun 2026-03-22 (all day): Trip" {
+		t.Errorf("create all day = %q", out)
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "create", "title": "x", "start": "tomorrow"}); err == nil || !strings.Contains(err.Error(), "cannot read time") {
+		t.Errorf("bad time: %v", err)
+	}
+
+	// upcoming reports each event once per chat
+	out = run(map[string]interface{}{"action": "upcoming"})
+	if out != "Starting soon:\n- in 10 min, Mon 2026-03-16 09:00-09:15: Standup @ Room 1" {
+		t.Errorf("upcoming = %q", out)
+	}
+	if out := run(map[string]interface{}{"action": "upcoming"}); !strings.HasPrefix(out, "No new events") {
+		t.Errorf("second upcoming = %q", out)
+	}
+}
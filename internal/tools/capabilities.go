@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Capabilities describes what this deployment of uBot can do. The agent
+// reads it through the capabilities tool instead of guessing, so it does
+// not promise features that are not available.
+type Capabilities struct {
+	Build       string            `json:"build"` // e.g. "full" or "lite (without docker)"
+	Provider    string            `json:"provider"`
+	Model       string            `json:"model"`
+	OtherModels map[string]string `json:"otherModels,omitempty"` // by purpose, e.g. "vision"
+	Channels    []string          `json:"channels"`              // enabled channels
+	Channel     string            `json:"currentChannel,omitempty"`
+	Tools       []string          `json:"tools"`
+	Limits      CapabilityLimits  `json:"limits"`
+	Sandbox     SandboxInfo       `json:"sandbox"`
+	Unsupported []string          `json:"unsupported,omitempty"` // things users might ask for that cannot be done
+}
+
+// CapabilityLimits are the limits a turn runs under.
+type CapabilityLimits struct {
+	MaxTokens          int `json:"maxTokens"`
+	MaxToolIterations  int `json:"maxToolIterations"`
+	ToolTimeoutSeconds int `json:"toolTimeoutSeconds"`
+	ExecTimeoutSeconds int `json:"execTimeoutSeconds"`
+}
+
+// SandboxInfo describes how commands run by exec are isolated.
+type SandboxInfo struct {
+	Isolation           string `json:"isolation"` // e.g. "docker container", "none"
+	RestrictToWorkspace bool   `json:"restrictToWorkspace"`
+	Workspace           string `json:"workspace"`
+	CommandGuard        bool   `json:"commandGuard"` // dangerous commands are blocked
+}
+
+// CapabilitiesTool reports the deployment's capabilities to the LLM.
+type CapabilitiesTool struct {
+	BaseTool
+	caps     Capabilities
+	registry *ToolRegistry
+}
+
+// NewCapabilitiesTool creates a CapabilitiesTool describing caps. The tool
+// list is read from registry on each call, so tools registered later, such
+// as MCP tools, are included.
+func NewCapabilitiesTool(caps Capabilities, registry *ToolRegistry) *CapabilitiesTool {
+	return &CapabilitiesTool{
+		BaseTool: NewBaseTool(
+			"capabilities",
+			"Describe what this uBot deployment supports: enabled tools, model, channels, limits, sandbox isolation, and features that are not available (e.g. sending files). Check it before promising the user something you are not sure you can do.",
+			map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		),
+		caps:     caps,
+		registry: registry,
+	}
+}
+
+// Execute returns the capabilities as JSON.
+func (t *CapabilitiesTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	caps := t.caps
+	caps.Tools = t.registry.List()
+	if req, ok := RequestFromContext(ctx); ok {
+		caps.Channel = req.Channel
+	}
+	data, err := json.MarshalIndent(caps, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("capabilities: %w", err)
+	}
+	return string(data), nil
+}
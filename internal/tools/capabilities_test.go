@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestCapabilitiesToolListsCurrentTools(t *testing.T) {
+	registry := NewRegistry()
+	tool := NewCapabilitiesTool(Capabilities{Model: "gpt-4", Channels: []string{"cli"}}, registry)
+	registry.Register(tool)
+	registry.Register(NewReadFileTool()) // registered after the tool was created
+
+	ctx := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "1"})
+	out, err := tool.Execute(ctx, nil)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	var caps Capabilities
+	if err := json.Unmarshal([]byte(out), &caps); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	if caps.Model != "gpt-4" || caps.Channel != "telegram" {
+		t.Errorf("model %q, channel %q; want gpt-4, telegram", caps.Model, caps.Channel)
+	}
+	want := []string{"capabilities", "read_file"}
+	if len(caps.Tools) != len(want) || caps.Tools[0] != want[0] || caps.Tools[1] != want[1] {
+		t.Errorf("tools = %v, want %v", caps.Tools, want)
+	}
+}
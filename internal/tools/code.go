@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hkuds/ubot/internal/codeindex"
+)
+
+const (
+	// defaultSymbolResults is the default number of symbol_search results.
+	defaultSymbolResults = 20
+	// maxDefinitionLines caps the source returned by open_definition.
+	maxDefinitionLines = 150
+)
+
+// SymbolSearchTool finds definitions by name in the indexed project.
+type SymbolSearchTool struct {
+	BaseTool
+	index *codeindex.Index
+}
+
+// NewSymbolSearchTool creates a new SymbolSearchTool over index.
+func NewSymbolSearchTool(index *codeindex.Index) *SymbolSearchTool {
+	return &SymbolSearchTool{
+		BaseTool: NewBaseTool(
+			"symbol_search",
+			fmt.Sprintf("Search the project at %s for functions, methods, types and other definitions by name (exact, prefix or substring match; 'Type.method' narrows to a type). Returns file:line locations; use open_definition to read the code.", index.Root()),
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Symbol name or part of it, e.g. 'NewClient' or 'Client.Close'",
+					},
+					"kind": map[string]interface{}{
+						"type":        "string",
+						"description": "Only return this kind of symbol",
+						"enum":        []string{"func", "method", "type", "class", "interface", "const", "var"},
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of results (default 20)",
+					},
+				},
+				"required": []string{"query"},
+			},
+		),
+		index: index,
+	}
+}
+
+// Execute searches the index.
+func (t *SymbolSearchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	query, err := GetStringParam(params, "query")
+	if err != nil || strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("symbol_search: query is required")
+	}
+	kind := GetStringParamOr(params, "kind", "")
+	limit := GetIntParamOr(params, "limit", defaultSymbolResults)
+
+	syms, err := t.index.Search(query, kind, limit)
+	if err != nil {
+		return "", fmt.Errorf("symbol_search: %w", err)
+	}
+	if len(syms) == 0 {
+		return fmt.Sprintf("No symbols matching %q.", query), nil
+	}
+
+	var sb strings.Builder
+	for _, s := range syms {
+		sb.WriteString(fmt.Sprintf("%s (%s) %s:%d\n    %s\n", s.QualifiedName(), s.Kind, s.File, s.Line, s.Signature))
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// OpenDefinitionTool returns the source code of a definition.
+type OpenDefinitionTool struct {
+	BaseTool
+	index *codeindex.Index
+}
+
+// NewOpenDefinitionTool creates a new OpenDefinitionTool over index.
+func NewOpenDefinitionTool(index *codeindex.Index) *OpenDefinitionTool {
+	return &OpenDefinitionTool{
+		BaseTool: NewBaseTool(
+			"open_definition",
+			"Show the source code of a function, method, type or other definition in the indexed project, found by exact name ('Type.method' for methods).",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Exact symbol name, e.g. 'NewClient' or 'Client.Close'",
+					},
+					"file": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional path prefix (relative to the project) to disambiguate, e.g. 'internal/mcp'",
+					},
+				},
+				"required": []string{"name"},
+			},
+		),
+		index: index,
+	}
+}
+
+// Execute looks up the definition and returns its source.
+func (t *OpenDefinitionTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	name, err := GetStringParam(params, "name")
+	if err != nil || strings.TrimSpace(name) == "" {
+		return "", fmt.Errorf("open_definition: name is required")
+	}
+	file := GetStringParamOr(params, "file", "")
+
+	defs, err := t.index.Definitions(name, file)
+	if err != nil {
+		return "", fmt.Errorf("open_definition: %w", err)
+	}
+	if len(defs) == 0 {
+		return fmt.Sprintf("No definition named %q. Use symbol_search to find similar names.", name), nil
+	}
+
+	var sb strings.Builder
+	if len(defs) > 1 {
+		sb.WriteString(fmt.Sprintf("%d definitions found; showing the first. Others:\n", len(defs)))
+		for _, d := range defs[1:] {
+			sb.WriteString(fmt.Sprintf("- %s (%s) %s:%d\n", d.QualifiedName(), d.Kind, d.File, d.Line))
+		}
+		sb.WriteString("\n")
+	}
+
+	def := defs[0]
+	src, truncated, err := t.index.Source(def, maxDefinitionLines)
+	if err != nil {
+		return "", fmt.Errorf("open_definition: %w", err)
+	}
+	sb.WriteString(fmt.Sprintf("%s:%d-%d\n%s", def.File, def.Line, def.EndLine, src))
+	if truncated {
+		sb.WriteString(fmt.Sprintf("\n... (truncated at %d lines; use read_file for the rest)", maxDefinitionLines))
+	}
+	return sb.String(), nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hkuds/ubot/internal/cron"
 )
@@ -19,34 +20,34 @@ func NewCronTool(scheduler *cron.Scheduler) *CronTool {
 	return &CronTool{
 		BaseTool: NewBaseTool(
 			"cron",
-			"Manage proactive scheduled reminders. Use 'add' to create a new recurring reminder with a cron schedule or interval (e.g. '@every 5m', '0 9 * * 1-5'). Use 'remove' to delete a reminder by ID. Use 'list' to see all active reminders.",
+			"Manage proactive scheduled reminders. Use 'add' to create a new recurring reminder with a cron schedule or interval (e.g. '@every 5m', '@daily', '0 9 * * 1-5'). Use 'update' to change the schedule, instruction or destination of an existing reminder in place, keeping its ID (pass only the fields to change). Use 'remove' to delete a reminder by ID. Use 'list' to see all active reminders and their last run status.",
 			map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"action": map[string]interface{}{
 						"type":        "string",
-						"enum":        []string{"add", "remove", "list"},
-						"description": "The action to perform: add, remove, or list.",
+						"enum":        []string{"add", "update", "remove", "list"},
+						"description": "The action to perform: add, update, remove, or list.",
 					},
 					"schedule": map[string]interface{}{
 						"type":        "string",
-						"description": "Cron expression (e.g. '*/5 * * * *', '0 9 * * 1-5') or interval (e.g. '@every 5m', '@every 1h'). Required for 'add'.",
+						"description": "Cron expression (e.g. '*/5 * * * *', '0 9 * * 1-5') or interval (e.g. '@every 5m', '@every 1h'). Required for 'add'; optional for 'update'.",
 					},
 					"instruction": map[string]interface{}{
 						"type":        "string",
-						"description": "What the reminder should do when it fires. This becomes the LLM prompt. Required for 'add'.",
+						"description": "What the reminder should do when it fires. This becomes the LLM prompt. Required for 'add'; optional for 'update'.",
 					},
 					"channel": map[string]interface{}{
 						"type":        "string",
-						"description": "The channel to send the reminder to (e.g. 'telegram', 'cli'). Required for 'add'.",
+						"description": "The channel to send the reminder to (e.g. 'telegram', 'cli'). Required for 'add'; optional for 'update'.",
 					},
 					"chat_id": map[string]interface{}{
 						"type":        "string",
-						"description": "The chat/conversation ID to send the reminder to. Required for 'add'.",
+						"description": "The chat/conversation ID to send the reminder to. Required for 'add'; optional for 'update'.",
 					},
 					"job_id": map[string]interface{}{
 						"type":        "string",
-						"description": "The job ID to remove. Required for 'remove'.",
+						"description": "The job ID to update or remove. Required for 'update' and 'remove'.",
 					},
 				},
 				"required": []string{"action"},
@@ -66,12 +67,14 @@ func (t *CronTool) Execute(ctx context.Context, params map[string]interface{}) (
 	switch action {
 	case "add":
 		return t.add(params)
+	case "update":
+		return t.update(params)
 	case "remove":
 		return t.remove(params)
 	case "list":
 		return t.list()
 	default:
-		return "", fmt.Errorf("cron: unknown action %q (use add, remove, or list)", action)
+		return "", fmt.Errorf("cron: unknown action %q (use add, update, remove, or list)", action)
 	}
 }
 
@@ -101,6 +104,28 @@ func (t *CronTool) add(params map[string]interface{}) (string, error) {
 	return fmt.Sprintf("Reminder added (ID: %s). Schedule: %s", id, schedule), nil
 }
 
+func (t *CronTool) update(params map[string]interface{}) (string, error) {
+	jobID, err := GetStringParam(params, "job_id")
+	if err != nil {
+		return "", fmt.Errorf("cron update: %w", err)
+	}
+	schedule := GetStringParamOr(params, "schedule", "")
+	instruction := GetStringParamOr(params, "instruction", "")
+	channel := GetStringParamOr(params, "channel", "")
+	chatID := GetStringParamOr(params, "chat_id", "")
+	if schedule == "" && instruction == "" && channel == "" && chatID == "" {
+		return "", fmt.Errorf("cron update: nothing to change (pass schedule, instruction, channel or chat_id)")
+	}
+
+	job, err := t.scheduler.UpdateJob(jobID, schedule, instruction, channel, chatID)
+	if err != nil {
+		return "", fmt.Errorf("cron update: %w", err)
+	}
+
+	return fmt.Sprintf("Reminder %s updated. Schedule: %s | Channel: %s | Chat: %s\nInstruction: %s",
+		job.ID, job.Schedule, job.Channel, job.ChatID, job.Instruction), nil
+}
+
 func (t *CronTool) remove(params map[string]interface{}) (string, error) {
 	jobID, err := GetStringParam(params, "job_id")
 	if err != nil {
@@ -125,6 +150,25 @@ func (t *CronTool) list() (string, error) {
 	for _, j := range jobs {
 		sb.WriteString(fmt.Sprintf("- ID: %s | Schedule: %s | Channel: %s | Chat: %s\n  Instruction: %s\n",
 			j.ID, j.Schedule, j.Channel, j.ChatID, j.Instruction))
+		sb.WriteString("  " + formatLastRun(t.scheduler.History(j.ID)) + "\n")
 	}
 	return sb.String(), nil
 }
+
+// formatLastRun renders a one-line summary of a job's most recent run.
+func formatLastRun(h *cron.JobHistory) string {
+	last := h.LastRun()
+	if last == nil {
+		return "Last run: never"
+	}
+	status := "ok"
+	if !last.Success {
+		status = "failed: " + last.Error
+	}
+	line := fmt.Sprintf("Last run: %s (%s, %s)",
+		last.StartedAt.Format("2006-01-02 15:04"), last.Duration.Round(time.Millisecond), status)
+	if h.ConsecutiveFailures > 1 {
+		line += fmt.Sprintf(" | %d consecutive failures", h.ConsecutiveFailures)
+	}
+	return line
+}
@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// desktopTimeout limits how long a clipboard or notification program may
+// run.
+const desktopTimeout = 10 * time.Second
+
+// desktopCommand is a program a desktop tool runs.
+type desktopCommand struct {
+	name string
+	args []string
+	env  []string // added to the environment, e.g. text passed to a script
+}
+
+// desktop runs the clipboard and notification programs of the platform
+// uBot runs on.
+type desktop struct {
+	goos     string
+	getenv   func(string) string
+	lookPath func(string) (string, error)
+	run      func(ctx context.Context, cmd desktopCommand, stdin string) (string, error)
+}
+
+// newDesktop returns a desktop for this machine.
+func newDesktop() *desktop {
+	return &desktop{goos: runtime.GOOS, getenv: os.Getenv, lookPath: exec.LookPath, run: runDesktopCommand}
+}
+
+// runDesktopCommand runs cmd with stdin and returns its output.
+func runDesktopCommand(ctx context.Context, cmd desktopCommand, stdin string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, desktopTimeout)
+	defer cancel()
+	c := exec.CommandContext(ctx, cmd.name, cmd.args...)
+	c.Env = append(os.Environ(), cmd.env...)
+	c.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	c.Stdout, c.Stderr = &stdout, &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", cmd.name, err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", cmd.name, err)
+	}
+	return stdout.String(), nil
+}
+
+// first returns the first of cmds whose program is installed, or an error
+// naming the programs to install.
+func (d *desktop) first(what, install string, cmds ...desktopCommand) (desktopCommand, error) {
+	for _, cmd := range cmds {
+		if _, err := d.lookPath(cmd.name); err == nil {
+			return cmd, nil
+		}
+	}
+	return desktopCommand{}, fmt.Errorf("no %s found; install %s", what, install)
+}
+
+// clipboard returns the command that reads the clipboard, or writes it
+// from standard input when write is set.
+func (d *desktop) clipboard(write bool) (desktopCommand, error) {
+	switch d.goos {
+	case "darwin":
+		if write {
+			return desktopCommand{name: "pbcopy"}, nil
+		}
+		return desktopCommand{name: "pbpaste"}, nil
+	case "windows":
+		script := "Get-Clipboard -Raw"
+		if write {
+			script = "Set-Clipboard -Value ([Console]::In.ReadToEnd())"
+		}
+		return desktopCommand{name: "powershell", args: []string{"-NoProfile", "-NonInteractive", "-Command", script}}, nil
+	}
+
+	var cmds []desktopCommand
+	if d.getenv("WAYLAND_DISPLAY") != "" {
+		if write {
+			cmds = append(cmds, desktopCommand{name: "wl-copy"})
+		} else {
+			cmds = append(cmds, desktopCommand{name: "wl-paste", args: []string{"--no-newline"}})
+		}
+	}
+	if write {
+		cmds = append(cmds,
+			desktopCommand{name: "xclip", args: []string{"-selection", "clipboard", "-in"}},
+			desktopCommand{name: "xsel", args: []string{"--clipboard", "--input"}})
+	} else {
+		cmds = append(cmds,
+			desktopCommand{name: "xclip", args: []string{"-selection", "clipboard", "-out"}},
+			desktopCommand{name: "xsel", args: []string{"--clipboard", "--output"}})
+	}
+	return d.first("clipboard program", "wl-clipboard, xclip or xsel", cmds...)
+}
+
+// windowsToast shows a toast notification with the text in the UBOT_TITLE
+// and UBOT_MESSAGE environment variables.
+const windowsToast = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:UBOT_TITLE)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:UBOT_MESSAGE)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('uBot').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+
+// notification returns the command that shows a notification. The text is
+// passed as arguments or environment variables, never inside a script.
+func (d *desktop) notification(title, message string) (desktopCommand, error) {
+	env := []string{"UBOT_TITLE=" + title, "UBOT_MESSAGE=" + message}
+	switch d.goos {
+	case "darwin":
+		return desktopCommand{name: "osascript", args: []string{"-e",
+			`display notification (system attribute "UBOT_MESSAGE") with title (system attribute "UBOT_TITLE")`}, env: env}, nil
+	case "windows":
+		return desktopCommand{name: "powershell", args: []string{"-NoProfile", "-NonInteractive", "-Command", windowsToast}, env: env}, nil
+	}
+	return d.first("notification program", "notify-send (libnotify)", desktopCommand{name: "notify-send", args: []string{"--app-name=uBot", "--", title, message}})
+}
+
+// NewDesktopTools returns the tools that reach the desktop of the machine
+// uBot runs on: get_clipboard, set_clipboard and
+// send_desktop_notification. SecureRegistry runs them only for the CLI.
+func NewDesktopTools() []Tool {
+	d := newDesktop()
+	return []Tool{NewGetClipboardTool(d), NewSetClipboardTool(d), NewDesktopNotificationTool(d)}
+}
+
+// GetClipboardTool returns the text on the clipboard.
+type GetClipboardTool struct {
+	BaseTool
+	desktop *desktop
+}
+
+// NewGetClipboardTool creates a new GetClipboardTool.
+func NewGetClipboardTool(d *desktop) *GetClipboardTool {
+	return &GetClipboardTool{
+		BaseTool: NewBaseTool(
+			"get_clipboard",
+			"Read the text on the user's clipboard, e.g. when they say 'look at what I copied'.",
+			map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		),
+		desktop: d,
+	}
+}
+
+// Execute reads the clipboard.
+func (t *GetClipboardTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	cmd, err := t.desktop.clipboard(false)
+	if err != nil {
+		return "", fmt.Errorf("get_clipboard: %w", err)
+	}
+	text, err := t.desktop.run(ctx, cmd, "")
+	if err != nil {
+		return "", fmt.Errorf("get_clipboard: %w", err)
+	}
+	if text == "" {
+		return "The clipboard is empty or holds no text.", nil
+	}
+	if len(text) > maxReadBytes {
+		return strings.ToValidUTF8(text[:maxReadBytes], "") + fmt.Sprintf("\n\n[clipboard truncated: %s of %s shown]", formatSize(maxReadBytes), formatSize(int64(len(text)))), nil
+	}
+	return text, nil
+}
+
+// SetClipboardTool puts text on the clipboard.
+type SetClipboardTool struct {
+	BaseTool
+	desktop *desktop
+}
+
+// NewSetClipboardTool creates a new SetClipboardTool.
+func NewSetClipboardTool(d *desktop) *SetClipboardTool {
+	return &SetClipboardTool{
+		BaseTool: NewBaseTool(
+			"set_clipboard",
+			"Copy text to the user's clipboard, replacing what is there, so they can paste it elsewhere.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"text": map[string]interface{}{
+						"type":        "string",
+						"description": "The text to copy.",
+					},
+				},
+				"required": []string{"text"},
+			},
+		),
+		desktop: d,
+	}
+}
+
+// Execute writes the clipboard.
+func (t *SetClipboardTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	text, err := GetStringParam(params, "text")
+	if err != nil {
+		return "", fmt.Errorf("set_clipboard: %w", err)
+	}
+	cmd, err := t.desktop.clipboard(true)
+	if err != nil {
+		return "", fmt.Errorf("set_clipboard: %w", err)
+	}
+	if _, err := t.desktop.run(ctx, cmd, text); err != nil {
+		return "", fmt.Errorf("set_clipboard: %w", err)
+	}
+	return fmt.Sprintf("Copied %d characters to the clipboard.", len([]rune(text))), nil
+}
+
+// DesktopNotificationTool shows a notification on the desktop.
+type DesktopNotificationTool struct {
+	BaseTool
+	desktop *desktop
+}
+
+// NewDesktopNotificationTool creates a new DesktopNotificationTool.
+func NewDesktopNotificationTool(d *desktop) *DesktopNotificationTool {
+	return &DesktopNotificationTool{
+		BaseTool: NewBaseTool(
+			"send_desktop_notification",
+			"Show a notification on the user's desktop, e.g. when a long task they are not watching has finished.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"title": map[string]interface{}{
+						"type":        "string",
+						"description": "Short title. Default: uBot",
+					},
+					"message": map[string]interface{}{
+						"type":        "string",
+						"description": "The notification text.",
+					},
+				},
+				"required": []string{"message"},
+			},
+		),
+		desktop: d,
+	}
+}
+
+// Execute shows the notification.
+func (t *DesktopNotificationTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	message, err := GetStringParam(params, "message")
+	if err != nil || strings.TrimSpace(message) == "" {
+		return "", errors.New("send_desktop_notification: message is required")
+	}
+	title := GetStringParamOr(params, "title", "")
+	if strings.TrimSpace(title) == "" {
+		title = "uBot"
+	}
+	cmd, err := t.desktop.notification(title, message)
+	if err != nil {
+		return "", fmt.Errorf("send_desktop_notification: %w", err)
+	}
+	if _, err := t.desktop.run(ctx, cmd, ""); err != nil {
+		return "", fmt.Errorf("send_desktop_notification: %w", err)
+	}
+	return "Notification shown.", nil
+}
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeDesktop records the commands desktop tools run on goos with the
+// given programs installed.
+func fakeDesktop(goos string, env map[string]string, installed ...string) (*desktop, *[]desktopCommand) {
+	var ran []desktopCommand
+	return &desktop{
+		goos:   goos,
+		getenv: func(key string) string { return env[key] },
+		lookPath: func(name string) (string, error) {
+			for _, p := range installed {
+				if p == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		},
+		run: func(ctx context.Context, cmd desktopCommand, stdin string) (string, error) {
+			ran = append(ran, cmd)
+			return "copied text", nil
+		},
+	}, &ran
+}
+
+func TestDesktopCommands(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		goos      string
+		env       map[string]string
+		installed []string
+		read      string // program that reads the clipboard; "" = error
+		write     string
+		notify    string
+	}{
+		{"wayland", "linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, []string{"wl-paste", "wl-copy", "xclip", "notify-send"}, "wl-paste", "wl-copy", "notify-send"},
+		{"x11", "linux", nil, []string{"wl-paste", "wl-copy", "xsel"}, "xsel", "xsel", ""},
+		{"nothing installed", "linux", nil, nil, "", "", ""},
+		{"macos", "darwin", nil, nil, "pbpaste", "pbcopy", "osascript"},
+		{"windows", "windows", nil, nil, "powershell", "powershell", "powershell"},
+	} {
+		d, _ := fakeDesktop(tc.goos, tc.env, tc.installed...)
+		check := func(what string, cmd desktopCommand, err error, want string) {
+			if want == "" {
+				if err == nil || !strings.Contains(err.Error(), "install") {
+					t.Errorf("%s %s: got %s, %v; want an install hint", tc.name, what, cmd.name, err)
+				}
+			} else if err != nil || cmd.name != want {
+				t.Errorf("%s %s: got %s, %v; want %s", tc.name, what, cmd.name, err, want)
+			}
+		}
+		cmd, err := d.clipboard(false)
+		check("read", cmd, err, tc.read)
+		cmd, err = d.clipboard(true)
+		check("write", cmd, err, tc.write)
+		cmd, err = d.notification("Build", "done")
+		check("notify", cmd, err, tc.notify)
+	}
+
+	// Text never becomes part of a script
+	d, _ := fakeDesktop("darwin", nil)
+	cmd, _ := d.notification(`"); do shell script "rm -rf ~`, "body")
+	if strings.Contains(strings.Join(cmd.args, " "), "rm -rf") || cmd.env[0] != `UBOT_TITLE="); do shell script "rm -rf ~` {
+		t.Errorf("osascript command = %+v", cmd)
+	}
+}
+
+func TestDesktopToolsOnlyForCLI(t *testing.T) {
+	d, ran := fakeDesktop("linux", nil, "xclip", "notify-send")
+	registry := NewRegistry()
+	registry.MustRegister(NewGetClipboardTool(d))
+	registry.MustRegister(NewSetClipboardTool(d))
+	registry.MustRegister(NewDesktopNotificationTool(d))
+	secure := NewSecureRegistry(registry)
+
+	telegram := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "42"})
+	if _, err := secure.Execute(telegram, "get_clipboard", map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "local CLI") {
+		t.Errorf("telegram read the clipboard: %v", err)
+	}
+	if _, err := secure.Execute(context.Background(), "set_clipboard", map[string]interface{}{"text": "x"}); err == nil {
+		t.Error("a call outside a conversation set the clipboard")
+	}
+	if len(*ran) != 0 {
+		t.Errorf("blocked calls ran %+v", *ran)
+	}
+
+	cli := WithRequest(context.Background(), RequestInfo{Channel: "cli", ChatID: "direct"})
+	if out, err := secure.Execute(cli, "get_clipboard", map[string]interface{}{}); err != nil || out != "copied text" {
+		t.Errorf("get_clipboard = %q, %v", out, err)
+	}
+	if out, err := secure.Execute(cli, "set_clipboard", map[string]interface{}{"text": "héllo"}); err != nil || out != "Copied 5 characters to the clipboard." {
+		t.Errorf("set_clipboard = %q, %v", out, err)
+	}
+	if _, err := secure.Execute(cli, "send_desktop_notification", map[string]interface{}{"message": "Tests passed"}); err != nil {
+		t.Errorf("send_desktop_notification: %v", err)
+	}
+	if last := (*ran)[len(*ran)-1]; last.name != "notify-send" || strings.Join(last.args, " ") != "--app-name=uBot -- uBot Tests passed" {
+		t.Errorf("notification ran %+v", last)
+	}
+}
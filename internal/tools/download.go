@@ -0,0 +1,420 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+// downloadProgressEvery is how often a running download reports how far
+// it got; downloads that finish sooner report nothing.
+const downloadProgressEvery = 10 * time.Second
+
+// executableTypes are content types of programs and scripts, which are not
+// saved unless the configuration allows it.
+var executableTypes = []string{
+	"application/java-archive",
+	"application/vnd.android.package-archive",
+	"application/vnd.microsoft.portable-executable",
+	"application/x-dosexec",
+	"application/x-elf",
+	"application/x-executable",
+	"application/x-mach-binary",
+	"application/x-msdos-program",
+	"application/x-msdownload",
+	"application/x-msi",
+	"application/x-sh",
+	"application/x-sharedlib",
+	"text/x-shellscript",
+}
+
+// executableExts are file extensions that run when opened on some system.
+var executableExts = []string{
+	".apk", ".app", ".bat", ".cmd", ".com", ".dll", ".exe", ".jar", ".msi",
+	".ps1", ".scr", ".sh", ".vbs",
+}
+
+// DownloadFileTool saves a file from the web into the workspace. It stops
+// at a size limit, refuses programs and unwanted content types, and
+// reports the file's SHA-256 so the agent has no reason to fall back to
+// exec with curl.
+type DownloadFileTool struct {
+	BaseTool
+	cfg       config.DownloadConfig
+	workspace string
+	client    *http.Client
+	polite    *Politeness
+	// blocked reports URLs that must not be fetched; tests allow local
+	// servers.
+	blocked func(string) bool
+}
+
+// NewDownloadFileTool creates a DownloadFileTool saving into workspace
+// within the limits of cfg.
+func NewDownloadFileTool(workspace string, cfg config.DownloadConfig) *DownloadFileTool {
+	return &DownloadFileTool{
+		BaseTool: NewBaseTool(
+			"download_file",
+			fmt.Sprintf("Download a file from a URL into the workspace, e.g. a PDF, dataset or archive, up to %s. Reports where it was saved, its size, type and SHA-256. Use this instead of curl or wget.", formatSize(cfg.MaxBytes())),
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL of the file (http or https only)",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": fmt.Sprintf("Where to save the file, relative to the workspace. Default: %s/ with the file's own name.", cfg.Folder()),
+					},
+					"sha256": map[string]interface{}{
+						"type":        "string",
+						"description": "Expected SHA-256 checksum in hex; the file is not saved if it does not match.",
+					},
+					"overwrite": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace an existing file at path. Default: false",
+					},
+				},
+				"required": []string{"url"},
+			},
+		),
+		cfg:       cfg,
+		workspace: workspace,
+		client:    safenet.NewClient(0),
+		polite:    sharedPoliteness,
+		blocked:   safenet.IsInternalURL,
+	}
+}
+
+// Execute downloads the file.
+func (t *DownloadFileTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	rawURL, err := GetStringParam(params, "url")
+	if err != nil {
+		return "", fmt.Errorf("download_file: %w", err)
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return "", errors.New("download_file: only http and https URLs are supported")
+	}
+	if t.blocked != nil && t.blocked(rawURL) {
+		return "", fmt.Errorf("download_file: %w", safenet.ErrBlocked)
+	}
+	want := strings.ToLower(strings.TrimSpace(GetStringParamOr(params, "sha256", "")))
+	if want != "" && (len(want) != sha256.Size*2 || !isHex(want)) {
+		return "", errors.New("download_file: sha256 must be 64 hex digits")
+	}
+	overwrite, _ := params["overwrite"].(bool)
+
+	dest := ""
+	if p := GetStringParamOr(params, "path", ""); p != "" {
+		if dest, err = t.resolve(p); err != nil {
+			return "", fmt.Errorf("download_file: %w", err)
+		}
+		if err := checkFree(dest, overwrite); err != nil {
+			return "", fmt.Errorf("download_file: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("download_file: failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; uBot/1.0)")
+	resp, err := t.polite.Do(t.client, req)
+	if err != nil {
+		return "", fmt.Errorf("download_file: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download_file: HTTP error %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	maxBytes := t.cfg.MaxBytes()
+	if resp.ContentLength > maxBytes {
+		return "", fmt.Errorf("download_file: file is %s, more than the %s limit (tools.download.maxSizeMb)", formatSize(resp.ContentLength), formatSize(maxBytes))
+	}
+
+	// The start of the file tells its real type when the server's is
+	// missing or generic
+	head := make([]byte, 512)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("download_file: failed to read response: %w", err)
+	}
+	head = head[:n]
+	contentType := mediaType(resp.Header.Get("Content-Type"))
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = mediaType(http.DetectContentType(head))
+	}
+
+	if dest == "" {
+		name := downloadName(resp)
+		if dest, err = t.resolve(filepath.Join(t.cfg.Folder(), name)); err != nil {
+			return "", fmt.Errorf("download_file: %w", err)
+		}
+		if !overwrite {
+			dest = freeName(dest)
+		}
+	}
+	if err := t.checkType(contentType, head, dest); err != nil {
+		return "", fmt.Errorf("download_file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", fmt.Errorf("download_file: %w", err)
+	}
+	// Data goes to a hidden part file first, so a failed or refused
+	// download never leaves a file under the real name
+	part, err := os.CreateTemp(filepath.Dir(dest), ".download-*.part")
+	if err != nil {
+		return "", fmt.Errorf("download_file: %w", err)
+	}
+	defer os.Remove(part.Name())
+
+	hash := sha256.New()
+	progress := &downloadProgress{ctx: ctx, name: filepath.Base(dest), total: resp.ContentLength, last: time.Now()}
+	body := io.MultiReader(bytes.NewReader(head), resp.Body)
+	size, err := io.Copy(io.MultiWriter(part, hash, progress), io.LimitReader(body, maxBytes+1))
+	if cerr := part.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("download_file: download failed after %s: %w", formatSize(size), err)
+	}
+	if size > maxBytes {
+		return "", fmt.Errorf("download_file: file is larger than the %s limit (tools.download.maxSizeMb)", formatSize(maxBytes))
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if want != "" && sum != want {
+		return "", fmt.Errorf("download_file: checksum mismatch: got SHA-256 %s, want %s; the file was not saved", sum, want)
+	}
+	// Downloads are never executable
+	if err := os.Chmod(part.Name(), 0o644); err != nil {
+		return "", fmt.Errorf("download_file: %w", err)
+	}
+	if err := checkFree(dest, overwrite); err != nil {
+		return "", fmt.Errorf("download_file: %w", err)
+	}
+	if err := os.Rename(part.Name(), dest); err != nil {
+		return "", fmt.Errorf("download_file: %w", err)
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Saved %s (%s, %s)\n", dest, formatSize(size), contentType)
+	if final := resp.Request.URL.String(); final != rawURL {
+		fmt.Fprintf(&out, "Redirected to: %s\n", final)
+	}
+	fmt.Fprintf(&out, "SHA-256: %s", sum)
+	if want != "" {
+		out.WriteString(" (matches)")
+	}
+	return out.String(), nil
+}
+
+// Paths returns where a call would save the file, when it names a path,
+// for the registry's path checks; the path is relative to the workspace.
+func (t *DownloadFileTool) Paths(params map[string]interface{}) []string {
+	p := GetStringParamOr(params, "path", "")
+	if p == "" {
+		return nil
+	}
+	p, err := expandPath(p)
+	if err != nil {
+		return nil
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(t.workspace, p)
+	}
+	return []string{p}
+}
+
+// resolve returns the absolute path of p, which must be inside the
+// workspace, also once symlinks are followed, and not a sensitive file.
+func (t *DownloadFileTool) resolve(p string) (string, error) {
+	p, err := expandPath(p)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(t.workspace, p)
+	}
+	p = filepath.Clean(p)
+	rel, err := filepath.Rel(t.workspace, p)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is outside the workspace %s", p, t.workspace)
+	}
+	if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("%s is a symlink; not saving through it", p)
+	}
+	real, err := followsOutside(t.workspace, p)
+	if err != nil {
+		return "", err
+	}
+	if real != "" {
+		return "", fmt.Errorf("%s is outside the workspace %s: it leads to %s through a symlink", p, t.workspace, real)
+	}
+	if isSensitiveName(filepath.Base(p)) {
+		return "", ErrBlockedPath{Path: p, Reason: "sensitive filename"}
+	}
+	return p, nil
+}
+
+// checkType refuses programs and scripts, unless allowed, and types
+// outside the configured list.
+func (t *DownloadFileTool) checkType(contentType string, head []byte, dest string) error {
+	if len(t.cfg.AllowedTypes) > 0 && !typeAllowed(contentType, t.cfg.AllowedTypes) {
+		return fmt.Errorf("content type %s is not allowed (tools.download.allowedTypes: %s)", contentType, strings.Join(t.cfg.AllowedTypes, ", "))
+	}
+	if t.cfg.AllowExecutables {
+		return nil
+	}
+	if isExecutable(contentType, head, dest) {
+		return fmt.Errorf("refusing to save %s: it is a program or script (%s); set tools.download.allowExecutables to allow this", filepath.Base(dest), contentType)
+	}
+	return nil
+}
+
+// typeAllowed reports whether contentType matches an entry of allowed:
+// exactly, or by prefix for entries ending in "/" such as "image/".
+func typeAllowed(contentType string, allowed []string) bool {
+	for _, a := range allowed {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if contentType == a || (strings.HasSuffix(a, "/") && strings.HasPrefix(contentType, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExecutable reports whether a file looks like a program or script, by
+// its content type, its first bytes or its name.
+func isExecutable(contentType string, head []byte, name string) bool {
+	for _, t := range executableTypes {
+		if contentType == t {
+			return true
+		}
+	}
+	for _, magic := range [][]byte{
+		[]byte("\x7fELF"),        // Linux
+		[]byte("MZ"),             // Windows
+		{0xcf, 0xfa, 0xed, 0xfe}, // macOS, 64-bit
+		{0xce, 0xfa, 0xed, 0xfe}, // macOS, 32-bit
+		{0xca, 0xfe, 0xba, 0xbe}, // macOS universal binary
+		[]byte("#!"),             // scripts
+	} {
+		if bytes.HasPrefix(head, magic) {
+			return true
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range executableExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType returns the media type of a Content-Type value, lower case and
+// without parameters.
+func mediaType(value string) string {
+	mt, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// downloadName picks a file name for resp: the one the server suggests, or
+// the last part of the URL.
+func downloadName(resp *http.Response) string {
+	name := ""
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = path.Base(resp.Request.URL.Path)
+	}
+	// Keep the base name only, without leading dots that would hide it
+	name = strings.TrimLeft(filepath.Base(filepath.FromSlash(strings.ReplaceAll(name, "\\", "/"))), ". ")
+	if name == "" || name == "/" {
+		name = "download"
+	}
+	return name
+}
+
+// freeName returns p, or p with " (2)", " (3)" and so on before its
+// extension when p exists.
+func freeName(p string) string {
+	ext := filepath.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(p); os.IsNotExist(err) {
+			return p
+		}
+		p = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+}
+
+// checkFree fails when p exists and may not be replaced.
+func checkFree(p string, overwrite bool) error {
+	info, err := os.Lstat(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", p)
+	}
+	if !overwrite {
+		return fmt.Errorf("%s already exists; set overwrite to replace it", p)
+	}
+	return nil
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// downloadProgress reports the bytes written through it every
+// downloadProgressEvery.
+type downloadProgress struct {
+	ctx   context.Context
+	name  string
+	total int64 // -1 when the size is unknown
+	done  int64
+	last  time.Time
+}
+
+func (p *downloadProgress) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if time.Since(p.last) >= downloadProgressEvery {
+		p.last = time.Now()
+		if p.total > 0 {
+			ReportProgress(p.ctx, fmt.Sprintf("Downloading %s: %s of %s (%d%%)", p.name, formatSize(p.done), formatSize(p.total), p.done*100/p.total))
+		} else {
+			ReportProgress(p.ctx, fmt.Sprintf("Downloading %s: %s so far", p.name, formatSize(p.done)))
+		}
+	}
+	return len(b), nil
+}
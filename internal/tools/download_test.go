@@ -0,0 +1,157 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+func TestDownloadFileTool(t *testing.T) {
+	report := []byte("%PDF-1.4 quarterly report")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/report":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", `attachment; filename="../.q3 report.pdf"`)
+			w.Write(report)
+		case "/tool.bin":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("\x7fELF\x02\x01\x01 binary"))
+		case "/big":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Length", strconv.Itoa(3<<20))
+			w.Write([]byte(strings.Repeat("x", 3<<20)))
+		case "/stream":
+			// No Content-Length: the limit applies while reading
+			w.Header().Set("Content-Type", "text/plain")
+			for i := 0; i < 3; i++ {
+				w.Write([]byte(strings.Repeat("y", 1<<20)))
+				w.(http.Flusher).Flush()
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	workspace := t.TempDir()
+	newTool := func(cfg config.DownloadConfig) *DownloadFileTool {
+		tool := NewDownloadFileTool(workspace, cfg)
+		tool.client, tool.blocked = srv.Client(), nil
+		tool.polite = NewPoliteness(config.WebPolitenessConfig{})
+		return tool
+	}
+	download := func(tool *DownloadFileTool, params map[string]interface{}) (string, error) {
+		return tool.Execute(context.Background(), params)
+	}
+	tool := newTool(config.DownloadConfig{MaxSizeMB: 2})
+
+	sum := sha256.Sum256(report)
+	want := hex.EncodeToString(sum[:])
+	out, err := download(tool, map[string]interface{}{"url": srv.URL + "/report", "sha256": strings.ToUpper(want)})
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	saved := filepath.Join(workspace, "downloads", "q3 report.pdf")
+	if !strings.Contains(out, "Saved "+saved) || !strings.Contains(out, "application/pdf") || !strings.Contains(out, "SHA-256: "+want+" (matches)") {
+		t.Errorf("output = %q", out)
+	}
+	if info, err := os.Stat(saved); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("saved file: %v, %v", info, err)
+	}
+
+	// A second copy gets a new name; an explicit path needs overwrite
+	out, err = download(tool, map[string]interface{}{"url": srv.URL + "/report"})
+	if err != nil || !strings.Contains(out, "q3 report (2).pdf") {
+		t.Errorf("second download: %q, %v", out, err)
+	}
+	if _, err := download(tool, map[string]interface{}{"url": srv.URL + "/report", "path": "downloads/q3 report.pdf"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("existing path: err = %v", err)
+	}
+	if _, err := download(tool, map[string]interface{}{"url": srv.URL + "/report", "path": "downloads/q3 report.pdf", "overwrite": true}); err != nil {
+		t.Errorf("overwrite: %v", err)
+	}
+
+	// Symlinks in the workspace do not lead out of it
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(workspace, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "target.pdf"), filepath.Join(workspace, "file.pdf")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		tool    *DownloadFileTool
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{"outside workspace", tool, map[string]interface{}{"url": srv.URL + "/report", "path": "../escape.pdf"}, "outside the workspace"},
+		{"symlinked directory", tool, map[string]interface{}{"url": srv.URL + "/report", "path": "link/sub/escape.pdf"}, "through a symlink"},
+		{"symlinked file", tool, map[string]interface{}{"url": srv.URL + "/report", "path": "file.pdf", "overwrite": true}, "is a symlink"},
+		{"sensitive name", tool, map[string]interface{}{"url": srv.URL + "/report", "path": "config/.env"}, "sensitive filename"},
+		{"checksum", tool, map[string]interface{}{"url": srv.URL + "/report", "path": "bad.pdf", "sha256": strings.Repeat("0", 64)}, "checksum mismatch"},
+		{"executable", tool, map[string]interface{}{"url": srv.URL + "/tool.bin"}, "program or script"},
+		{"announced size", tool, map[string]interface{}{"url": srv.URL + "/big"}, "more than the 2.0 MB limit"},
+		{"streamed size", tool, map[string]interface{}{"url": srv.URL + "/stream"}, "larger than the 2.0 MB limit"},
+		{"type", newTool(config.DownloadConfig{AllowedTypes: []string{"image/"}}), map[string]interface{}{"url": srv.URL + "/report"}, "application/pdf is not allowed"},
+		{"scheme", tool, map[string]interface{}{"url": "file:///etc/passwd"}, "only http and https"},
+	} {
+		if _, err := download(tc.tool, tc.params); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.wantErr)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "bad.pdf")); !os.IsNotExist(err) {
+		t.Error("file with a wrong checksum was kept")
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Errorf("saved outside the workspace: %v", entries)
+	}
+
+	// Programs are saved when allowed, but never executable
+	out, err = download(newTool(config.DownloadConfig{AllowExecutables: true}), map[string]interface{}{"url": srv.URL + "/tool.bin"})
+	if err != nil {
+		t.Fatalf("allowed executable: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(workspace, "downloads", "tool.bin")); err != nil || info.Mode()&0o111 != 0 {
+		t.Errorf("executable saved as %v, %v", info, err)
+	}
+
+	// Refused and failed downloads leave no part files behind
+	entries, _ := os.ReadDir(filepath.Join(workspace, "downloads"))
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".part") {
+			t.Errorf("left behind %s", e.Name())
+		}
+	}
+}
+
+func TestIsExecutable(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		head        string
+		name        string
+		want        bool
+	}{
+		{"application/x-msdownload", "", "setup", true},
+		{"application/octet-stream", "MZ\x90\x00", "setup", true},
+		{"text/plain", "#!/bin/sh\nrm -rf ~", "notes.txt", true},
+		{"application/zip", "PK\x03\x04", "installer.EXE", true},
+		{"application/pdf", "%PDF-1.7", "paper.pdf", false},
+		{"text/csv", "a,b\n1,2", "data.csv", false},
+	} {
+		if got := isExecutable(tc.contentType, []byte(tc.head), tc.name); got != tc.want {
+			t.Errorf("isExecutable(%q, %q, %q) = %v, want %v", tc.contentType, tc.head, tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// envNamePattern matches valid environment variable names.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// blockedEnvPrefixes are variables that change how every program is loaded
+// and may not be set from a conversation.
+var blockedEnvPrefixes = []string{"LD_", "DYLD_"}
+
+// SessionEnv holds environment variables per conversation. They are kept in
+// memory only, so secrets never reach the session files, and are lost when
+// the process exits.
+type SessionEnv struct {
+	mu   sync.RWMutex
+	vars map[string]map[string]string // session key -> name -> value
+}
+
+// NewSessionEnv creates an empty SessionEnv.
+func NewSessionEnv() *SessionEnv {
+	return &SessionEnv{vars: make(map[string]map[string]string)}
+}
+
+// Set sets a variable for the session.
+func (e *SessionEnv) Set(sessionKey, name, value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.vars[sessionKey] == nil {
+		e.vars[sessionKey] = make(map[string]string)
+	}
+	e.vars[sessionKey][name] = value
+}
+
+// Unset removes a variable from the session and reports whether it was set.
+func (e *SessionEnv) Unset(sessionKey, name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.vars[sessionKey][name]; !ok {
+		return false
+	}
+	delete(e.vars[sessionKey], name)
+	return true
+}
+
+// Names returns the sorted names of the session's variables.
+func (e *SessionEnv) Names(sessionKey string) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, 0, len(e.vars[sessionKey]))
+	for name := range e.vars[sessionKey] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Environ returns the session's variables as NAME=value pairs.
+func (e *SessionEnv) Environ(sessionKey string) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	env := make([]string, 0, len(e.vars[sessionKey]))
+	for name, value := range e.vars[sessionKey] {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// Redact replaces the values of the session's variables in text with
+// $NAME, so command output does not leak them into the transcript.
+func (e *SessionEnv) Redact(sessionKey, text string) string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for name, value := range e.vars[sessionKey] {
+		if len(value) >= 4 { // shorter values would garble unrelated output
+			text = strings.ReplaceAll(text, value, "$"+name)
+		}
+	}
+	return text
+}
+
+// SetEnvTool lets the LLM store variables, such as tokens or paths, that
+// are passed to every exec command in the conversation.
+type SetEnvTool struct {
+	BaseTool
+	env *SessionEnv
+}
+
+// NewSetEnvTool creates a new SetEnvTool backed by env.
+func NewSetEnvTool(env *SessionEnv) *SetEnvTool {
+	return &SetEnvTool{
+		BaseTool: NewBaseTool(
+			"set_env",
+			"Store an environment variable for this conversation. Every later exec command gets it, so refer to it as $NAME instead of writing tokens or long paths into commands; its value is hidden in command output. Use 'set' with name and value, 'unset' with name, or 'list' to see the names.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"set", "unset", "list"},
+						"description": "The action to perform: set, unset, or list.",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "The variable name, e.g. GITHUB_TOKEN. Required for 'set' and 'unset'.",
+					},
+					"value": map[string]interface{}{
+						"type":        "string",
+						"description": "The value. Required for 'set'.",
+					},
+				},
+				"required": []string{"action"},
+			},
+		),
+		env: env,
+	}
+}
+
+// Execute runs the set_env tool action against the current session.
+func (t *SetEnvTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	action, err := GetStringParam(params, "action")
+	if err != nil {
+		return "", fmt.Errorf("set_env: %w", err)
+	}
+
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.SessionKey == "" {
+		return "", errors.New("set_env: no active conversation")
+	}
+
+	switch action {
+	case "set":
+		name, err := envName(params)
+		if err != nil {
+			return "", fmt.Errorf("set_env set: %w", err)
+		}
+		value, err := GetStringParam(params, "value")
+		if err != nil {
+			return "", fmt.Errorf("set_env set: %w", err)
+		}
+		t.env.Set(req.SessionKey, name, value)
+		return fmt.Sprintf("Set $%s for this conversation.", name), nil
+	case "unset":
+		name, err := envName(params)
+		if err != nil {
+			return "", fmt.Errorf("set_env unset: %w", err)
+		}
+		if !t.env.Unset(req.SessionKey, name) {
+			return "", fmt.Errorf("set_env unset: $%s is not set", name)
+		}
+		return fmt.Sprintf("Removed $%s.", name), nil
+	case "list":
+		names := t.env.Names(req.SessionKey)
+		if len(names) == 0 {
+			return "No variables set.", nil
+		}
+		return "Variables set: $" + strings.Join(names, ", $"), nil
+	default:
+		return "", fmt.Errorf("set_env: unknown action %q (use set, unset, or list)", action)
+	}
+}
+
+// envName returns the validated "name" parameter.
+func envName(params map[string]interface{}) (string, error) {
+	name, err := GetStringParam(params, "name")
+	if err != nil {
+		return "", err
+	}
+	name = strings.TrimPrefix(strings.TrimSpace(name), "$")
+	if !envNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid variable name %q", name)
+	}
+	for _, prefix := range blockedEnvPrefixes {
+		if strings.HasPrefix(strings.ToUpper(name), prefix) {
+			return "", fmt.Errorf("$%s may not be set", name)
+		}
+	}
+	return name, nil
+}
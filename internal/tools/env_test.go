@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSetEnvTool_ExecUsesSessionVariables(t *testing.T) {
+	env := NewSessionEnv()
+	setEnv := NewSetEnvTool(env)
+	execTool := NewExecTool()
+	execTool.SetSessionEnv(env)
+
+	alice := WithRequest(context.Background(), RequestInfo{SessionKey: "telegram:1"})
+	bob := WithRequest(context.Background(), RequestInfo{SessionKey: "telegram:2"})
+
+	if _, err := setEnv.Execute(alice, map[string]interface{}{"action": "set", "name": "API_TOKEN", "value": "s3cr3t-value"}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	out, err := execTool.Execute(alice, map[string]interface{}{"command": `echo "token=$API_TOKEN"`})
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if strings.Contains(out, "s3cr3t-value") || !strings.Contains(out, "token=$API_TOKEN") {
+		t.Errorf("output = %q, want the value replaced by $API_TOKEN", out)
+	}
+
+	// Other conversations do not see the variable
+	out, err = execTool.Execute(bob, map[string]interface{}{"command": `echo "token=[$API_TOKEN]"`})
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if !strings.Contains(out, "token=[]") {
+		t.Errorf("other session output = %q, want the variable unset", out)
+	}
+
+	list, _ := setEnv.Execute(alice, map[string]interface{}{"action": "list"})
+	if !strings.Contains(list, "$API_TOKEN") || strings.Contains(list, "s3cr3t") {
+		t.Errorf("list = %q, want names only", list)
+	}
+
+	for _, name := range []string{"LD_PRELOAD", "1BAD", "A-B"} {
+		if _, err := setEnv.Execute(alice, map[string]interface{}{"action": "set", "name": name, "value": "x"}); err == nil {
+			t.Errorf("set %q succeeded", name)
+		}
+	}
+
+	if _, err := setEnv.Execute(alice, map[string]interface{}{"action": "unset", "name": "API_TOKEN"}); err != nil {
+		t.Fatalf("unset: %v", err)
+	}
+	if names := env.Names("telegram:1"); len(names) != 0 {
+		t.Errorf("names after unset = %v", names)
+	}
+}
+
+func TestRedactParamMap_SetEnvValue(t *testing.T) {
+	got := redactParamMap(map[string]interface{}{"action": "set", "name": "TOKEN", "value": "abc"})
+	if got["value"] != "[redacted]" || got["name"] != "TOKEN" {
+		t.Errorf("redacted = %v", got)
+	}
+}
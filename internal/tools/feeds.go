@@ -0,0 +1,252 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/feeds"
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+// defaultFeedItems is how many new items check lists per feed by default.
+const defaultFeedItems = 10
+
+// FeedsTool follows RSS and Atom feeds for the current conversation. Each
+// check returns only the items that are new since the last one, which makes
+// it the building block for scheduled digests.
+type FeedsTool struct {
+	BaseTool
+	store  *feeds.Store
+	client *http.Client
+
+	// blocked reports URLs that must not be fetched; tests allow local
+	// servers.
+	blocked func(string) bool
+}
+
+// NewFeedsTool creates a FeedsTool keeping subscriptions in store.
+func NewFeedsTool(store *feeds.Store) *FeedsTool {
+	return &FeedsTool{
+		BaseTool: NewBaseTool(
+			"feeds",
+			"Follow RSS/Atom feeds (news, blogs, releases) for this chat. Use 'subscribe' with a feed URL, 'list' to see the feeds, 'unsubscribe' to stop following one, and 'check' to get only the items published since the last check. For a regular digest, schedule a cron job whose instruction says to check the feeds and summarize the new items.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"subscribe", "unsubscribe", "list", "check"},
+						"description": "The action to perform: subscribe, unsubscribe, list, or check.",
+					},
+					"url": map[string]interface{}{
+						"type":        "string",
+						"description": "The RSS or Atom feed URL. Required for 'subscribe'.",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "A short name for the feed. Optional for 'subscribe' (defaults to the feed title). For 'unsubscribe' and 'check', the feed's name or URL; 'check' checks every feed when omitted.",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "The most new items to list per feed for 'check' (default 10).",
+					},
+				},
+				"required": []string{"action"},
+			},
+		),
+		store:   store,
+		client:  safenet.NewClient(30 * time.Second),
+		blocked: safenet.IsInternalURL,
+	}
+}
+
+// Execute runs the feeds tool action for the conversation the call
+// belongs to.
+func (t *FeedsTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	action, err := GetStringParam(params, "action")
+	if err != nil {
+		return "", fmt.Errorf("feeds: %w", err)
+	}
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.SessionKey == "" {
+		return "", errors.New("feeds: no active conversation")
+	}
+	owner := req.SessionKey
+
+	switch action {
+	case "subscribe":
+		return t.subscribe(ctx, owner, params)
+	case "unsubscribe":
+		return t.unsubscribe(owner, params)
+	case "list":
+		return t.list(owner)
+	case "check":
+		return t.check(ctx, owner, params)
+	default:
+		return "", fmt.Errorf("feeds: unknown action %q (use subscribe, unsubscribe, list, or check)", action)
+	}
+}
+
+func (t *FeedsTool) subscribe(ctx context.Context, owner string, params map[string]interface{}) (string, error) {
+	feedURL, err := GetStringParam(params, "url")
+	if err != nil {
+		return "", fmt.Errorf("feeds subscribe: %w", err)
+	}
+	feed, err := t.fetch(ctx, feedURL)
+	if err != nil {
+		return "", fmt.Errorf("feeds subscribe: %w", err)
+	}
+
+	name := strings.TrimSpace(GetStringParamOr(params, "name", ""))
+	if name == "" {
+		name = feed.Title
+	}
+	if name == "" {
+		name = feedURL
+	}
+
+	// What the feed lists now counts as seen; checks report what comes next
+	sub := feeds.Subscription{Name: name, URL: feedURL, Owner: owner}
+	if err := t.store.Add(sub); err != nil {
+		return "", fmt.Errorf("feeds subscribe: %w", err)
+	}
+	if err := t.store.MarkSeen(owner, name, feed.Items, time.Now()); err != nil {
+		return "", fmt.Errorf("feeds subscribe: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Subscribed to %s (%d items listed now). Checks will report items published from now on.", name, len(feed.Items))
+	if len(feed.Items) > 0 {
+		sb.WriteString("\nLatest:")
+		for _, it := range feed.Items[:min(3, len(feed.Items))] {
+			fmt.Fprintf(&sb, "\n- %s", it.Title)
+		}
+	}
+	return sb.String(), nil
+}
+
+func (t *FeedsTool) unsubscribe(owner string, params map[string]interface{}) (string, error) {
+	ref, err := GetStringParam(params, "name")
+	if err != nil {
+		return "", fmt.Errorf("feeds unsubscribe: %w", err)
+	}
+	sub, err := t.store.Remove(owner, ref)
+	if err != nil {
+		return "", fmt.Errorf("feeds unsubscribe: %w", err)
+	}
+	return fmt.Sprintf("Unsubscribed from %s.", sub.Name), nil
+}
+
+func (t *FeedsTool) list(owner string) (string, error) {
+	subs, err := t.store.List(owner)
+	if err != nil {
+		return "", fmt.Errorf("feeds list: %w", err)
+	}
+	if len(subs) == 0 {
+		return "No feeds followed in this chat.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Feeds:\n")
+	for _, sub := range subs {
+		checked := "never"
+		if !sub.LastChecked.IsZero() {
+			checked = sub.LastChecked.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(&sb, "- %s: %s (last checked %s)\n", sub.Name, sub.URL, checked)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// check fetches the named feed, or all of the owner's feeds, and lists the
+// items not seen before, marking them seen. A feed that cannot be fetched
+// is reported without stopping the others.
+func (t *FeedsTool) check(ctx context.Context, owner string, params map[string]interface{}) (string, error) {
+	limit := GetIntParamOr(params, "limit", defaultFeedItems)
+	if limit <= 0 {
+		limit = defaultFeedItems
+	}
+
+	var subs []feeds.Subscription
+	if ref := GetStringParamOr(params, "name", ""); ref != "" {
+		sub, ok, err := t.store.Find(owner, ref)
+		if err != nil {
+			return "", fmt.Errorf("feeds check: %w", err)
+		}
+		if !ok {
+			return "", fmt.Errorf("feeds check: no feed named %q", ref)
+		}
+		subs = append(subs, sub)
+	} else {
+		var err error
+		if subs, err = t.store.List(owner); err != nil {
+			return "", fmt.Errorf("feeds check: %w", err)
+		}
+	}
+	if len(subs) == 0 {
+		return "No feeds followed in this chat. Subscribe to one first.", nil
+	}
+
+	var sb strings.Builder
+	total := 0
+	for _, sub := range subs {
+		feed, err := t.fetch(ctx, sub.URL)
+		if err != nil {
+			fmt.Fprintf(&sb, "## %s\nFailed to check: %v\n\n", sub.Name, err)
+			continue
+		}
+		fresh := sub.NewItems(feed.Items)
+		if err := t.store.MarkSeen(owner, sub.Name, feed.Items, time.Now()); err != nil {
+			return "", fmt.Errorf("feeds check: %w", err)
+		}
+		if len(fresh) == 0 {
+			continue
+		}
+		total += len(fresh)
+
+		fmt.Fprintf(&sb, "## %s (%d new)\n", sub.Name, len(fresh))
+		for _, it := range fresh[:min(limit, len(fresh))] {
+			writeFeedItem(&sb, it)
+		}
+		if len(fresh) > limit {
+			fmt.Fprintf(&sb, "(%d more not shown)\n", len(fresh)-limit)
+		}
+		sb.WriteString("\n")
+	}
+
+	if total == 0 {
+		return strings.TrimSpace(fmt.Sprintf("No new items in %d feed(s).\n\n%s", len(subs), sb.String())), nil
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func writeFeedItem(sb *strings.Builder, it feeds.Item) {
+	fmt.Fprintf(sb, "- %s", it.Title)
+	if !it.Published.IsZero() {
+		fmt.Fprintf(sb, " (%s)", it.Published.Format("2006-01-02"))
+	}
+	if it.Link != "" {
+		fmt.Fprintf(sb, "\n  %s", it.Link)
+	}
+	if it.Summary != "" {
+		fmt.Fprintf(sb, "\n  %s", it.Summary)
+	}
+	sb.WriteString("\n")
+}
+
+// fetch downloads a feed, refusing non-HTTP and internal addresses.
+func (t *FeedsTool) fetch(ctx context.Context, feedURL string) (*feeds.Feed, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("%q is not an http(s) URL", feedURL)
+	}
+	if t.blocked != nil && t.blocked(feedURL) {
+		return nil, errors.New("access to internal/private network addresses is blocked")
+	}
+	return feeds.Fetch(ctx, t.client, feedURL)
+}
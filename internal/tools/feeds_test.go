@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/feeds"
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+func TestFeedsTool(t *testing.T) {
+	var mu sync.Mutex
+	titles := []string{"First"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>News</title>`)
+		for i := len(titles) - 1; i >= 0; i-- {
+			fmt.Fprintf(w, `<item><title>%s</title><link>https://news.example/%d</link></item>`, titles[i], i)
+		}
+		fmt.Fprint(w, `</channel></rss>`)
+	}))
+	defer srv.Close()
+
+	tool := NewFeedsTool(feeds.NewStore(filepath.Join(t.TempDir(), "feeds.json")))
+	tool.client, tool.blocked = srv.Client(), nil
+	ctx := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "42", SessionKey: "telegram:42"})
+	run := func(params map[string]interface{}) string {
+		t.Helper()
+		out, err := tool.Execute(ctx, params)
+		if err != nil {
+			t.Fatalf("Execute(%v): %v", params, err)
+		}
+		return out
+	}
+
+	if out := run(map[string]interface{}{"action": "subscribe", "url": srv.URL}); !strings.Contains(out, "Subscribed to News") {
+		t.Errorf("subscribe = %q", out)
+	}
+	// Items listed when subscribing are not new
+	if out := run(map[string]interface{}{"action": "check"}); !strings.HasPrefix(out, "No new items") {
+		t.Errorf("first check = %q", out)
+	}
+
+	mu.Lock()
+	titles = append(titles, "Second", "Third")
+	mu.Unlock()
+	out := run(map[string]interface{}{"action": "check", "name": "news", "limit": float64(1)})
+	if !strings.Contains(out, "News (2 new)") || !strings.Contains(out, "Third") || strings.Contains(out, "Second") || strings.Contains(out, "First") {
+		t.Errorf("check = %q", out)
+	}
+	if !strings.Contains(out, "1 more not shown") {
+		t.Errorf("check does not mention the hidden item: %q", out)
+	}
+	if out := run(map[string]interface{}{"action": "check"}); !strings.HasPrefix(out, "No new items") {
+		t.Errorf("check after reporting = %q", out)
+	}
+
+	// Subscriptions belong to the conversation
+	other := WithRequest(context.Background(), RequestInfo{Channel: "cli", SessionKey: "cli:default"})
+	if out, _ := tool.Execute(other, map[string]interface{}{"action": "list"}); !strings.Contains(out, "No feeds") {
+		t.Errorf("other chat list = %q", out)
+	}
+
+	if out := run(map[string]interface{}{"action": "unsubscribe", "name": srv.URL}); out != "Unsubscribed from News." {
+		t.Errorf("unsubscribe = %q", out)
+	}
+
+	for name, params := range map[string]map[string]interface{}{
+		"bad scheme":    {"action": "subscribe", "url": "file:///etc/passwd"},
+		"not a feed":    {"action": "subscribe", "url": srv.URL + "/missing\x7f"},
+		"unknown feed":  {"action": "check", "name": "nope"},
+		"unknown verb":  {"action": "refresh"},
+		"missing owner": nil,
+	} {
+		c := ctx
+		if params == nil {
+			c, params = context.Background(), map[string]interface{}{"action": "list"}
+		}
+		if _, err := tool.Execute(c, params); err == nil {
+			t.Errorf("%s: Execute succeeded", name)
+		}
+	}
+
+	tool.blocked = safenet.IsInternalURL
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "subscribe", "url": srv.URL}); err == nil {
+		t.Error("subscribing to a local address succeeded")
+	}
+}
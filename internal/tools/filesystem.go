@@ -2,10 +2,16 @@
 package tools
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -21,6 +27,13 @@ func expandPath(path string) (string, error) {
 	return filepath.Clean(path), nil
 }
 
+const (
+	// defaultReadLines is how many lines read_file returns without a limit.
+	defaultReadLines = 2000
+	// maxReadBytes caps the text read_file returns in one call.
+	maxReadBytes = 50000
+)
+
 // ReadFileTool reads the contents of a file.
 type ReadFileTool struct {
 	BaseTool
@@ -31,7 +44,7 @@ func NewReadFileTool() *ReadFileTool {
 	return &ReadFileTool{
 		BaseTool: NewBaseTool(
 			"read_file",
-			"Read the contents of a file at the specified path. Supports ~ expansion for home directory.",
+			fmt.Sprintf("Read the contents of a file at the specified path. Supports ~ expansion for home directory. Returns up to %d lines or %s at a time; longer files come with their line count and the offset to continue from. Binary files are described instead of shown.", defaultReadLines, formatSize(maxReadBytes)),
 			map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -39,6 +52,14 @@ func NewReadFileTool() *ReadFileTool {
 						"type":        "string",
 						"description": "The path to the file to read. Supports ~ for home directory.",
 					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Line to start at, counting from 1; negative counts from the end, e.g. -100 for the last 100 lines of a log.",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum number of lines to return (default %d).", defaultReadLines),
+					},
 				},
 				"required": []string{"path"},
 			},
@@ -52,6 +73,11 @@ func (t *ReadFileTool) Execute(ctx context.Context, params map[string]interface{
 	if err != nil {
 		return "", fmt.Errorf("read_file: %w", err)
 	}
+	offset := GetIntParamOr(params, "offset", 1)
+	limit := GetIntParamOr(params, "limit", defaultReadLines)
+	if limit <= 0 {
+		limit = defaultReadLines
+	}
 
 	expandedPath, err := expandPath(path)
 	if err != nil {
@@ -71,15 +97,133 @@ func (t *ReadFileTool) Execute(ctx context.Context, params map[string]interface{
 		return "", fmt.Errorf("read_file: path is a directory, not a file: %s", expandedPath)
 	}
 
-	content, err := os.ReadFile(expandedPath)
+	f, err := os.Open(expandedPath)
 	if err != nil {
 		if os.IsPermission(err) {
 			return "", fmt.Errorf("read_file: permission denied: %s", expandedPath)
 		}
 		return "", fmt.Errorf("read_file: failed to read file %s: %w", expandedPath, err)
 	}
+	defer f.Close()
 
-	return string(content), nil
+	// Binary files would only fill the context with garbage
+	head := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("read_file: failed to read file %s: %w", expandedPath, err)
+	}
+	head = head[:n]
+	if bytes.IndexByte(head, 0) >= 0 {
+		return fmt.Sprintf("%s is a binary file (%s, %s); its contents cannot be shown as text.",
+			expandedPath, mediaType(http.DetectContentType(head)), formatSize(info.Size())), nil
+	}
+
+	reader := bufio.NewReader(io.MultiReader(bytes.NewReader(head), f))
+	if offset < 0 {
+		// Counting from the end needs the line count first
+		total, err := countLines(reader)
+		if err != nil {
+			return "", fmt.Errorf("read_file: failed to read file %s: %w", expandedPath, err)
+		}
+		offset = max(total+offset+1, 1)
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return "", fmt.Errorf("read_file: %w", err)
+		}
+		reader.Reset(f)
+	}
+	offset = max(offset, 1)
+
+	page, err := readLines(reader, offset, limit)
+	if err != nil {
+		return "", fmt.Errorf("read_file: failed to read file %s: %w", expandedPath, err)
+	}
+
+	// A whole file is returned as it is
+	if offset == 1 && page.last == page.total && !page.cut {
+		return page.text, nil
+	}
+	if offset > page.total {
+		return "", fmt.Errorf("read_file: offset %d is past the end of %s, which has %d lines", offset, expandedPath, page.total)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("[%s: lines %d-%d of %d]\n", expandedPath, offset, page.last, page.total))
+	sb.WriteString(page.text)
+	if !strings.HasSuffix(page.text, "\n") {
+		sb.WriteString("\n")
+	}
+	if page.cut {
+		sb.WriteString(fmt.Sprintf("[line %d is longer than %s and was cut]\n", page.last, formatSize(maxReadBytes)))
+	}
+	if page.last < page.total {
+		sb.WriteString(fmt.Sprintf("[%d more lines; call read_file with offset=%d to continue]", page.total-page.last, page.last+1))
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// linePage is the part of a file read_file returns.
+type linePage struct {
+	text  string
+	last  int  // last line in text
+	total int  // lines in the file
+	cut   bool // the only line in text was longer than maxReadBytes
+}
+
+// readLines reads limit lines from offset, stopping early at maxReadBytes,
+// and counts the lines of the whole file.
+func readLines(r *bufio.Reader, offset, limit int) (linePage, error) {
+	page := linePage{last: offset - 1}
+	var sb strings.Builder
+	full := false
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			page.total++
+			n := page.total
+			if n >= offset && n < offset+limit && !full {
+				switch {
+				case sb.Len()+len(line) <= maxReadBytes:
+					sb.WriteString(line)
+					page.last = n
+				case sb.Len() == 0:
+					sb.WriteString(strings.ToValidUTF8(line[:maxReadBytes], ""))
+					page.last, page.cut, full = n, true, true
+				default:
+					full = true
+				}
+			}
+		}
+		if err == io.EOF {
+			page.text = sb.String()
+			return page, nil
+		}
+		if err != nil {
+			return page, err
+		}
+	}
+}
+
+// countLines counts the lines read from r, including a last one without
+// a newline.
+func countLines(r io.Reader) (int, error) {
+	buf := make([]byte, 32*1024)
+	total, partial := 0, false
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			total += bytes.Count(buf[:n], []byte("\n"))
+			partial = buf[n-1] != '\n'
+		}
+		if err == io.EOF {
+			if partial {
+				total++
+			}
+			return total, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
 }
 
 // WriteFileTool writes content to a file.
@@ -157,7 +301,7 @@ func NewEditFileTool() *EditFileTool {
 	return &EditFileTool{
 		BaseTool: NewBaseTool(
 			"edit_file",
-			"Edit a file by replacing exact text. Finds and replaces the specified old_text with new_text. Only replaces the first occurrence unless the text appears multiple times (will warn).",
+			"Edit a file by replacing exact text, or a regular expression with regex. Replaces the first match unless occurrence says otherwise (warns when there are more). Returns a diff of the change.",
 			map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -167,11 +311,23 @@ func NewEditFileTool() *EditFileTool {
 					},
 					"old_text": map[string]interface{}{
 						"type":        "string",
-						"description": "The exact text to find and replace.",
+						"description": "The exact text to find and replace, or a regular expression (Go RE2 syntax) with regex.",
 					},
 					"new_text": map[string]interface{}{
 						"type":        "string",
-						"description": "The text to replace old_text with.",
+						"description": "The text to replace old_text with. With regex, $1 or ${name} insert capture groups ($$ for a literal $).",
+					},
+					"occurrence": map[string]interface{}{
+						"type":        "string",
+						"description": "Which matches to replace: 'first' (default), 'last', 'all', or the number of a match counting from 1, e.g. '3'.",
+					},
+					"regex": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Treat old_text as a regular expression.",
+					},
+					"expected_count": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of matches old_text must have; nothing is changed when the file has a different number.",
 					},
 				},
 				"required": []string{"path", "old_text", "new_text"},
@@ -180,6 +336,12 @@ func NewEditFileTool() *EditFileTool {
 	}
 }
 
+// editSpan is a replaced part of a file.
+type editSpan struct {
+	start, end int // byte offsets of the old text
+	text       string
+}
+
 // Execute performs the text replacement in the file.
 func (t *EditFileTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
 	path, err := GetStringParam(params, "path")
@@ -201,6 +363,18 @@ func (t *EditFileTool) Execute(ctx context.Context, params map[string]interface{
 		return "", fmt.Errorf("edit_file: old_text cannot be empty")
 	}
 
+	useRegex := GetBoolParamOr(params, "regex", false)
+	var re *regexp.Regexp
+	if useRegex {
+		if re, err = regexp.Compile(oldText); err != nil {
+			return "", fmt.Errorf("edit_file: invalid regular expression: %w", err)
+		}
+	}
+	occurrence, err := editOccurrence(params["occurrence"])
+	if err != nil {
+		return "", fmt.Errorf("edit_file: %w", err)
+	}
+
 	expandedPath, err := expandPath(path)
 	if err != nil {
 		return "", fmt.Errorf("edit_file: %w", err)
@@ -229,14 +403,52 @@ func (t *EditFileTool) Execute(ctx context.Context, params map[string]interface{
 
 	contentStr := string(content)
 
-	// Count occurrences
-	count := strings.Count(contentStr, oldText)
+	// Find all matches
+	var matches [][]int
+	if useRegex {
+		matches = re.FindAllStringSubmatchIndex(contentStr, -1)
+	} else {
+		for i := 0; ; {
+			j := strings.Index(contentStr[i:], oldText)
+			if j < 0 {
+				break
+			}
+			matches = append(matches, []int{i + j, i + j + len(oldText)})
+			i += j + len(oldText)
+		}
+	}
+	count := len(matches)
 	if count == 0 {
 		return "", fmt.Errorf("edit_file: old_text not found in file %s", expandedPath)
 	}
+	if _, ok := params["expected_count"]; ok {
+		if want := GetIntParamOr(params, "expected_count", 0); want != count {
+			return "", fmt.Errorf("edit_file: expected %d matches of old_text but found %d in %s; nothing was changed", want, count, expandedPath)
+		}
+	}
+
+	switch {
+	case occurrence == "first":
+		matches = matches[:1]
+	case occurrence == "last":
+		matches = matches[count-1:]
+	case occurrence != "all":
+		n, _ := strconv.Atoi(occurrence)
+		if n > count {
+			return "", fmt.Errorf("edit_file: occurrence %d requested but old_text has only %d matches in %s", n, count, expandedPath)
+		}
+		matches = matches[n-1 : n]
+	}
 
-	// Perform replacement (only first occurrence)
-	newContent := strings.Replace(contentStr, oldText, newText, 1)
+	spans := make([]editSpan, len(matches))
+	for i, m := range matches {
+		replacement := newText
+		if useRegex {
+			replacement = string(re.ExpandString(nil, newText, contentStr, m))
+		}
+		spans[i] = editSpan{start: m[0], end: m[1], text: replacement}
+	}
+	newContent := applySpans(contentStr, spans)
 
 	if err := os.WriteFile(expandedPath, []byte(newContent), 0644); err != nil {
 		if os.IsPermission(err) {
@@ -245,11 +457,138 @@ func (t *EditFileTool) Execute(ctx context.Context, params map[string]interface{
 		return "", fmt.Errorf("edit_file: failed to write file %s: %w", expandedPath, err)
 	}
 
-	if count > 1 {
-		return fmt.Sprintf("Warning: Found %d matches of old_text, replaced only the first occurrence in %s", count, expandedPath), nil
+	var result string
+	switch {
+	case occurrence == "first" && count > 1:
+		result = fmt.Sprintf("Warning: Found %d matches of old_text, replaced only the first occurrence in %s", count, expandedPath)
+	case len(spans) > 1 || count > 1:
+		result = fmt.Sprintf("Replaced %d of %d matches in %s", len(spans), count, expandedPath)
+	default:
+		result = fmt.Sprintf("Successfully replaced text in %s", expandedPath)
+	}
+	return result + "\n\n" + editPreview(expandedPath, contentStr, spans), nil
+}
+
+// editOccurrence checks the occurrence parameter, which may also come as a
+// JSON number, and returns it as "first", "last", "all" or a positive
+// number.
+func editOccurrence(v interface{}) (string, error) {
+	s := "first"
+	switch v := v.(type) {
+	case nil:
+	case float64:
+		s = strconv.Itoa(int(v))
+	case string:
+		if v != "" {
+			s = strings.ToLower(strings.TrimSpace(v))
+		}
+	default:
+		return "", fmt.Errorf("occurrence must be first, last, all or a number")
+	}
+	if s == "first" || s == "last" || s == "all" {
+		return s, nil
+	}
+	if n, err := strconv.Atoi(s); err != nil || n < 1 {
+		return "", fmt.Errorf("occurrence must be first, last, all or a number from 1, got %q", s)
+	}
+	return s, nil
+}
+
+// applySpans returns content with the spans, which are in order and do
+// not overlap, replaced.
+func applySpans(content string, spans []editSpan) string {
+	var sb strings.Builder
+	last := 0
+	for _, sp := range spans {
+		sb.WriteString(content[last:sp.start])
+		sb.WriteString(sp.text)
+		last = sp.end
+	}
+	sb.WriteString(content[last:])
+	return sb.String()
+}
+
+const (
+	// editPreviewContext is the number of unchanged lines shown around
+	// each change in an edit_file diff.
+	editPreviewContext = 2
+	// maxEditPreviewLines caps the diff edit_file returns.
+	maxEditPreviewLines = 60
+)
+
+// editPreview returns a unified diff of replacing spans in content.
+// Changes close together share a hunk; lines between them are shown as
+// removed and added again.
+func editPreview(path, content string, spans []editSpan) string {
+	type group struct {
+		lo, hi int // byte range of the whole lines changed
+		spans  []editSpan
+	}
+	var groups []*group
+	for _, sp := range spans {
+		lo := strings.LastIndex(content[:sp.start], "\n") + 1
+		hi := sp.end
+		if hi == sp.start || content[hi-1] != '\n' {
+			if i := strings.Index(content[hi:], "\n"); i >= 0 {
+				hi += i + 1
+			} else {
+				hi = len(content)
+			}
+		}
+		if n := len(groups); n > 0 {
+			g := groups[n-1]
+			if lo <= g.hi || strings.Count(content[g.hi:lo], "\n") <= 2*editPreviewContext {
+				g.hi, g.spans = max(g.hi, hi), append(g.spans, sp)
+				continue
+			}
+		}
+		groups = append(groups, &group{lo: lo, hi: hi, spans: []editSpan{sp}})
+	}
+
+	var lines []string
+	shift := 0 // lines added before the current group
+	for _, g := range groups {
+		local := make([]editSpan, len(g.spans))
+		for i, sp := range g.spans {
+			local[i] = editSpan{start: sp.start - g.lo, end: sp.end - g.lo, text: sp.text}
+		}
+		oldLines := previewLines(content[g.lo:g.hi])
+		newLines := previewLines(applySpans(content[g.lo:g.hi], local))
+		before := previewLines(content[:g.lo])
+		before = before[max(len(before)-editPreviewContext, 0):]
+		after := previewLines(content[g.hi:])
+		after = after[:min(len(after), editPreviewContext)]
+
+		first := strings.Count(content[:g.lo], "\n") + 1 - len(before)
+		ctxLen := len(before) + len(after)
+		lines = append(lines, fmt.Sprintf("@@ -%d,%d +%d,%d @@", first, len(oldLines)+ctxLen, first+shift, len(newLines)+ctxLen))
+		for _, l := range before {
+			lines = append(lines, " "+l)
+		}
+		for _, l := range oldLines {
+			lines = append(lines, "-"+l)
+		}
+		for _, l := range newLines {
+			lines = append(lines, "+"+l)
+		}
+		for _, l := range after {
+			lines = append(lines, " "+l)
+		}
+		shift += len(newLines) - len(oldLines)
 	}
+	if len(lines) > maxEditPreviewLines {
+		more := len(lines) - maxEditPreviewLines
+		lines = append(lines[:maxEditPreviewLines], fmt.Sprintf("... (%d more lines)", more))
+	}
+	return fmt.Sprintf("--- %s\n+++ %s\n%s", path, path, strings.Join(lines, "\n"))
+}
 
-	return fmt.Sprintf("Successfully replaced text in %s", expandedPath), nil
+// previewLines splits text into lines without their newlines.
+func previewLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
 }
 
 // ListDirTool lists the contents of a directory.
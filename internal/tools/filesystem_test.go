@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEditFileTool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	const original = "name: app\nport: 8080\nhost: a\n\n\n\n\n\nport: 9090\nhost: b\n"
+	tool := NewEditFileTool()
+	edit := func(params map[string]interface{}) (string, error) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		params["path"] = path
+		return tool.Execute(context.Background(), params)
+	}
+	read := func() string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		params map[string]interface{}
+		want   string // the changed lines
+	}{
+		{"first", map[string]interface{}{"old_text": "port", "new_text": "listen"}, "listen: 8080\nhost: a\n\n\n\n\n\nport: 9090"},
+		{"last", map[string]interface{}{"old_text": "port", "new_text": "listen", "occurrence": "last"}, "port: 8080\nhost: a\n\n\n\n\n\nlisten: 9090"},
+		{"all", map[string]interface{}{"old_text": "port", "new_text": "listen", "occurrence": "all", "expected_count": 2.0}, "listen: 8080\nhost: a\n\n\n\n\n\nlisten: 9090"},
+		{"index", map[string]interface{}{"old_text": "host", "new_text": "addr", "occurrence": 2.0}, "host: a\n\n\n\n\n\nport: 9090\naddr: b"},
+		{"regex", map[string]interface{}{"old_text": `port: (\d+)`, "new_text": "port: \"$1\"", "regex": true, "occurrence": "all"}, "port: \"8080\"\nhost: a\n\n\n\n\n\nport: \"9090\""},
+	} {
+		out, err := edit(tc.params)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got := read(); !strings.Contains(got, tc.want) {
+			t.Errorf("%s: file = %q, want it to contain %q", tc.name, got, tc.want)
+		}
+		if !strings.Contains(out, "--- "+path+"\n+++ "+path+"\n@@ ") {
+			t.Errorf("%s: no diff in %q", tc.name, out)
+		}
+	}
+
+	// Changes far apart get their own hunks with numbered lines
+	out, _ := edit(map[string]interface{}{"old_text": "host", "new_text": "addr", "occurrence": "all"})
+	if !strings.Contains(out, "Replaced 2 of 2 matches") || !strings.Contains(out, "@@ -1,5 +1,5 @@\n name: app\n port: 8080\n-host: a\n+addr: a\n \n") ||
+		!strings.Contains(out, "@@ -8,3 +8,3 @@\n \n port: 9090\n-host: b\n+addr: b") {
+		t.Errorf("diff:\n%s", out)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{"expected count", map[string]interface{}{"old_text": "port", "new_text": "x", "occurrence": "all", "expected_count": 1.0}, "expected 1 matches of old_text but found 2"},
+		{"index too large", map[string]interface{}{"old_text": "port", "new_text": "x", "occurrence": "3"}, "only 2 matches"},
+		{"bad occurrence", map[string]interface{}{"old_text": "port", "new_text": "x", "occurrence": "middle"}, "occurrence must be"},
+		{"bad regex", map[string]interface{}{"old_text": "port(", "new_text": "x", "regex": true}, "invalid regular expression"},
+		{"no match", map[string]interface{}{"old_text": "user", "new_text": "x"}, "not found"},
+	} {
+		if _, err := edit(tc.params); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.wantErr)
+		}
+		if got := read(); got != original {
+			t.Errorf("%s: file changed to %q", tc.name, got)
+		}
+	}
+}
+
+func TestReadFileTool(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	var log strings.Builder
+	for i := 1; i <= 2500; i++ {
+		log.WriteString("entry " + strconv.Itoa(i) + "\n")
+	}
+	logPath := write("app.log", log.String())
+	small := write("notes.txt", "one\ntwo")
+	tool := NewReadFileTool()
+	read := func(params map[string]interface{}) string {
+		t.Helper()
+		out, err := tool.Execute(context.Background(), params)
+		if err != nil {
+			t.Fatalf("read %v: %v", params, err)
+		}
+		return out
+	}
+
+	// Small files come back unchanged
+	if out := read(map[string]interface{}{"path": small}); out != "one\ntwo" {
+		t.Errorf("small file = %q", out)
+	}
+
+	out := read(map[string]interface{}{"path": logPath})
+	if !strings.HasPrefix(out, "["+logPath+": lines 1-2000 of 2500]\nentry 1\n") ||
+		!strings.HasSuffix(out, "entry 2000\n[500 more lines; call read_file with offset=2001 to continue]") {
+		t.Errorf("first page: %q ... %q", out[:60], out[len(out)-80:])
+	}
+	out = read(map[string]interface{}{"path": logPath, "offset": 2001.0, "limit": 2.0})
+	if out != "["+logPath+": lines 2001-2002 of 2500]\nentry 2001\nentry 2002\n[498 more lines; call read_file with offset=2003 to continue]" {
+		t.Errorf("range = %q", out)
+	}
+	out = read(map[string]interface{}{"path": logPath, "offset": -2.0})
+	if out != "["+logPath+": lines 2499-2500 of 2500]\nentry 2499\nentry 2500" {
+		t.Errorf("tail = %q", out)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"path": small, "offset": 5.0}); err == nil || !strings.Contains(err.Error(), "past the end") {
+		t.Errorf("offset past the end: err = %v", err)
+	}
+
+	// Pages stop at the byte limit, and a longer line is cut
+	long := write("long.txt", strings.Repeat("x", maxReadBytes+10)+"\nnext\n")
+	out = read(map[string]interface{}{"path": long})
+	if !strings.Contains(out, "[line 1 is longer than") || !strings.HasSuffix(out, "[1 more lines; call read_file with offset=2 to continue]") {
+		t.Errorf("long line: %q", out[len(out)-120:])
+	}
+
+	png := write("logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if out := read(map[string]interface{}{"path": png}); !strings.Contains(out, "binary file (image/png") {
+		t.Errorf("binary = %q", out)
+	}
+}
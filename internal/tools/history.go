@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/session"
+)
+
+// defaultHistoryResults is how many messages search_history returns unless
+// asked for a different number.
+const defaultHistoryResults = 10
+
+// SearchHistoryTool lets the LLM find earlier messages, in this and other
+// conversations, that contain a phrase.
+type SearchHistoryTool struct {
+	BaseTool
+	sessions *session.Manager
+	adminKey string // session key of the admin chat; "" when there is none
+}
+
+// NewSearchHistoryTool creates a new SearchHistoryTool backed by the given
+// session manager. Searches from the CLI and from the admin chat, whose
+// session key is adminKey, cover every conversation; searches from other
+// chats only their own.
+func NewSearchHistoryTool(sessions *session.Manager, adminKey string) *SearchHistoryTool {
+	return &SearchHistoryTool{
+		BaseTool: NewBaseTool(
+			"search_history",
+			"Search previous conversations for messages containing all the given words. Use it when the user refers to something discussed earlier that is no longer in the conversation, e.g. \"what did we decide last week about X?\". Returns the conversation, time and an excerpt of each matching message, newest or best matches first.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Words or a phrase to look for, e.g. 'database migration'.",
+					},
+					"channel": map[string]interface{}{
+						"type":        "string",
+						"description": "Only search conversations on this channel, e.g. 'telegram' or 'cli'.",
+					},
+					"days": map[string]interface{}{
+						"type":        "integer",
+						"description": "Only search messages from the last N days.",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of messages to return (default 10).",
+					},
+				},
+				"required": []string{"query"},
+			},
+		),
+		sessions: sessions,
+		adminKey: adminKey,
+	}
+}
+
+// Execute runs the search.
+func (t *SearchHistoryTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	query, err := GetStringParam(params, "query")
+	if err != nil || strings.TrimSpace(query) == "" {
+		return "", errors.New("search_history: 'query' is required")
+	}
+
+	current := ""
+	if req, ok := RequestFromContext(ctx); ok {
+		current = req.SessionKey
+	}
+	filter := HistoryFilter(current, t.adminKey)
+	filter.Channel = GetStringParamOr(params, "channel", "")
+	if days := GetIntParamOr(params, "days", 0); days > 0 {
+		filter.Since = time.Now().AddDate(0, 0, -days)
+	}
+
+	hits, err := t.sessions.Search(query, filter, GetIntParamOr(params, "limit", defaultHistoryResults))
+	if err != nil {
+		return "", fmt.Errorf("search_history: %w", err)
+	}
+	return FormatSearchHits(hits, current), nil
+}
+
+// HistoryFilter returns the filter limiting a history search made from the
+// conversation current. The CLI, the admin chat and callers outside any
+// conversation (current is "") may search every conversation; other chats
+// only their own.
+func HistoryFilter(current, adminKey string) session.Filter {
+	if current == "" || session.ChannelOf(current) == "cli" || current == adminKey {
+		return session.Filter{}
+	}
+	return session.Filter{Key: current}
+}
+
+// FormatSearchHits formats search results, one message per line, marking
+// those from the conversation current.
+func FormatSearchHits(hits []session.SearchHit, current string) string {
+	if len(hits) == 0 {
+		return "No matching messages found."
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d matching message(s):\n", len(hits))
+	for _, hit := range hits {
+		where := hit.Key
+		if hit.Key == current {
+			where += " (this conversation)"
+		}
+		fmt.Fprintf(&sb, "- %s, %s, %s: %s\n", where, hit.Timestamp.Format("2006-01-02 15:04"), hit.Role, hit.Snippet)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
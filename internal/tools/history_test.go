@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/session"
+)
+
+func TestSearchHistoryToolScope(t *testing.T) {
+	sessions := session.NewManager(t.TempDir())
+	for _, key := range []string{"telegram:1", "telegram:2", "telegram:99"} {
+		s := sessions.GetOrCreate(key)
+		s.AddMessage("assistant", "The deploy window is Friday for "+key)
+		if err := sessions.Save(s); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	tool := NewSearchHistoryTool(sessions, "telegram:99")
+	params := map[string]interface{}{"query": "deploy window"}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantHit int
+	}{
+		{"other chat sees only itself", WithRequest(context.Background(), RequestInfo{SessionKey: "telegram:1"}), 1},
+		{"admin chat sees all", WithRequest(context.Background(), RequestInfo{SessionKey: "telegram:99"}), 3},
+		{"cli sees all", WithRequest(context.Background(), RequestInfo{Channel: "cli", SessionKey: "cli:default"}), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tool.Execute(tt.ctx, params)
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if got := strings.Count(out, "\n- "); got != tt.wantHit {
+				t.Errorf("got %d hits, want %d:\n%s", got, tt.wantHit, out)
+			}
+		})
+	}
+
+	out, _ := tool.Execute(WithRequest(context.Background(), RequestInfo{SessionKey: "telegram:1"}), params)
+	if !strings.Contains(out, "telegram:1 (this conversation)") || !strings.Contains(out, "[deploy]") {
+		t.Errorf("output = %q", out)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{}); err == nil {
+		t.Error("missing query succeeded")
+	}
+}
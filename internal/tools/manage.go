@@ -5,18 +5,43 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"sync"
 
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/control"
 )
 
+// errCLIOnly is returned for actions requested from a chat by someone who
+// is not one of the channel's admin users.
+var errCLIOnly = errors.New("manage_ubot: this action is only available from the CLI")
+
+// remoteActions are the actions a channel's admin users may run from a
+// chat. Changes are confirmed in the chat before they are made.
+var remoteActions = map[string]bool{
+	"show_config":   true,
+	"update_config": true,
+	"restart":       true,
+	"reload":        true,
+}
+
+// confirmedActions need the admin's confirmation when requested from a
+// chat, so that instructions injected into the conversation cannot change
+// the bot on their own.
+var confirmedActions = map[string]bool{
+	"update_config": true,
+	"restart":       true,
+}
+
 // ManageUbotTool provides self-management capabilities for ubot.
-// Actions are restricted to CLI-only access for security.
+// Actions are restricted to the CLI, except that a channel's admin users
+// (channels.<name>.adminUsers) may run remoteActions from a chat.
 type ManageUbotTool struct {
 	BaseTool
 	source     string
 	configPath string
+	restarter  func() error
 	mu         sync.RWMutex
 }
 
@@ -33,7 +58,11 @@ func NewManageUbotTool(configPath string) *ManageUbotTool {
 			"action": map[string]interface{}{
 				"type":        "string",
 				"description": "The management action to perform",
-				"enum":        []string{"restart", "update_config", "show_config"},
+				"enum":        []string{"restart", "reload", "update_config", "show_config", "list_channels", "start_channel", "stop_channel"},
+			},
+			"channel": map[string]interface{}{
+				"type":        "string",
+				"description": "The channel name (for start_channel/stop_channel actions, e.g. 'telegram')",
 			},
 			"key": map[string]interface{}{
 				"type":        "string",
@@ -50,7 +79,7 @@ func NewManageUbotTool(configPath string) *ManageUbotTool {
 	return &ManageUbotTool{
 		BaseTool: NewBaseTool(
 			"manage_ubot",
-			"Manage ubot configuration and lifecycle. Actions: show_config (display current config), update_config (change a config value), restart (request a restart). Only available from CLI.",
+			"Manage ubot configuration and lifecycle. Actions: show_config (display current config), update_config (change a config value), reload (apply the config file to the running gateway without a restart), restart (request a restart), list_channels/start_channel/stop_channel (control channel connectors of the running gateway). Only available from the CLI, and to the admin users of a channel for show_config, update_config, restart and reload (changes are confirmed in the chat first).",
 			parameters,
 		),
 		configPath: configPath,
@@ -79,7 +108,9 @@ func (t *ManageUbotTool) Execute(ctx context.Context, params map[string]interfac
 	t.mu.RUnlock()
 
 	if source != "cli" {
-		return "", errors.New("manage_ubot: this action is only available from the CLI")
+		if err := t.authorizeRemote(ctx, params); err != nil {
+			return "", err
+		}
 	}
 
 	// Extract action (required)
@@ -101,72 +132,73 @@ func (t *ManageUbotTool) Execute(ctx context.Context, params map[string]interfac
 	case "update_config":
 		return t.updateConfig(params)
 	case "restart":
-		return t.restart()
+		return t.restart(ctx)
+	case "reload":
+		return t.reload(ctx)
+	case "list_channels":
+		return t.listChannels(ctx)
+	case "start_channel", "stop_channel":
+		return t.toggleChannel(ctx, action, params)
 	default:
-		return "", fmt.Errorf("manage_ubot: unknown action %q, expected one of: restart, update_config, show_config", action)
+		return "", fmt.Errorf("manage_ubot: unknown action %q, expected one of: restart, reload, update_config, show_config, list_channels, start_channel, stop_channel", action)
 	}
 }
 
-// showConfig reads and returns the current configuration with sensitive fields redacted.
-func (t *ManageUbotTool) showConfig() (string, error) {
+// authorizeRemote allows an action requested from a chat if the sender is
+// one of the channel's admin users, asking them to confirm changes first.
+func (t *ManageUbotTool) authorizeRemote(ctx context.Context, params map[string]interface{}) error {
+	action, _ := params["action"].(string)
+	info, ok := RequestFromContext(ctx)
+	if !ok || !remoteActions[action] {
+		return errCLIOnly
+	}
 	cfg, err := config.LoadConfig(t.configPath)
-	if err != nil {
-		return "", fmt.Errorf("manage_ubot: failed to load config: %w", err)
+	if err != nil || !cfg.Channels.IsAdminUser(info.Channel, info.SenderID) {
+		return errCLIOnly
+	}
+	log.Printf("[security] tool=manage_ubot action=%s admin=%s:%s", action, info.Channel, info.SenderID)
+	if !confirmedActions[action] {
+		return nil
 	}
 
-	// Convert config to a generic map for redaction
-	cfgData, err := json.Marshal(cfg)
+	approver, ok := ApproverFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("manage_ubot: %s needs confirmation, but no one can be asked here", action)
+	}
+	shown := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		shown[k] = v
+	}
+	if key, ok := params["key"].(string); ok {
+		if value, ok := params["value"].(string); ok {
+			shown["value"] = displayValue(key, value)
+		}
+	}
+	approved, err := approver.Approve(ctx, ApprovalRequest{Tool: t.Name(), Params: shown, Reason: "it changes uBot from a chat"})
 	if err != nil {
-		return "", fmt.Errorf("manage_ubot: failed to marshal config: %w", err)
+		return fmt.Errorf("manage_ubot: confirmation failed: %w", err)
 	}
-
-	var cfgMap map[string]interface{}
-	if err := json.Unmarshal(cfgData, &cfgMap); err != nil {
-		return "", fmt.Errorf("manage_ubot: failed to unmarshal config: %w", err)
+	if !approved {
+		return fmt.Errorf("manage_ubot: %s was not confirmed", action)
 	}
+	return nil
+}
 
-	// Redact sensitive fields
-	redactSensitiveFields(cfgMap)
+// showConfig reads and returns the current configuration with sensitive fields redacted.
+func (t *ManageUbotTool) showConfig() (string, error) {
+	cfg, err := config.LoadConfig(t.configPath)
+	if err != nil {
+		return "", fmt.Errorf("manage_ubot: failed to load config: %w", err)
+	}
 
 	// Return pretty-printed JSON
-	redacted, err := json.MarshalIndent(cfgMap, "", "  ")
+	redacted, err := json.MarshalIndent(cfg.Redacted(), "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("manage_ubot: failed to marshal redacted config: %w", err)
 	}
 	return string(redacted), nil
 }
 
-// redactSensitiveFields recursively walks a map and redacts values for keys
-// that look like API keys, tokens, secrets, or passwords.
-func redactSensitiveFields(m map[string]interface{}) {
-	for k, v := range m {
-		keyLower := strings.ToLower(k)
-		isSensitive := strings.Contains(keyLower, "key") ||
-			strings.Contains(keyLower, "token") ||
-			strings.Contains(keyLower, "secret") ||
-			strings.Contains(keyLower, "password")
-
-		switch val := v.(type) {
-		case string:
-			if isSensitive && val != "" {
-				if len(val) > 4 {
-					m[k] = "****" + val[len(val)-4:]
-				} else {
-					m[k] = "****"
-				}
-			}
-		case map[string]interface{}:
-			redactSensitiveFields(val)
-		case []interface{}:
-			for _, item := range val {
-				if nested, ok := item.(map[string]interface{}); ok {
-					redactSensitiveFields(nested)
-				}
-			}
-		}
-	}
-}
-
 // updateConfig updates a config key with a new value.
 func (t *ManageUbotTool) updateConfig(params map[string]interface{}) (string, error) {
 	key, err := GetStringParam(params, "key")
@@ -183,8 +215,9 @@ func (t *ManageUbotTool) updateConfig(params map[string]interface{}) (string, er
 		return "", errors.New("manage_ubot: key cannot be empty")
 	}
 
-	// Load current config
-	cfg, err := config.LoadConfig(t.configPath)
+	// Load the file as written, so environment overrides and secrets read
+	// from files are not saved into it
+	cfg, err := config.LoadRawConfig(t.configPath)
 	if err != nil {
 		return "", fmt.Errorf("manage_ubot: failed to load config: %w", err)
 	}
@@ -205,40 +238,152 @@ func (t *ManageUbotTool) updateConfig(params map[string]interface{}) (string, er
 		return "", fmt.Errorf("manage_ubot: %w", err)
 	}
 
-	// Convert back to Config struct
+	// Convert back to Config struct, refusing unknown keys and invalid values
 	updatedData, err := json.Marshal(cfgMap)
 	if err != nil {
 		return "", fmt.Errorf("manage_ubot: failed to marshal updated config: %w", err)
 	}
 
-	var updatedCfg config.Config
-	if err := json.Unmarshal(updatedData, &updatedCfg); err != nil {
-		return "", fmt.Errorf("manage_ubot: failed to parse updated config: %w", err)
+	if _, err := config.Parse(updatedData); err != nil {
+		return "", fmt.Errorf("manage_ubot: not updating %s: %w", key, err)
+	}
+	updatedCfg, err := config.Decode(updatedData)
+	if err != nil {
+		return "", fmt.Errorf("manage_ubot: not updating %s: %w", key, err)
 	}
 
 	// Save the updated config
-	if err := config.SaveConfig(&updatedCfg, t.configPath); err != nil {
+	if err := config.SaveConfig(updatedCfg, t.configPath); err != nil {
 		return "", fmt.Errorf("manage_ubot: failed to save config: %w", err)
 	}
 
-	// Avoid echoing sensitive values (API keys, tokens, secrets) in the response
-	displayValue := value
-	keyLower := strings.ToLower(key)
-	if strings.Contains(keyLower, "key") || strings.Contains(keyLower, "token") || strings.Contains(keyLower, "secret") || strings.Contains(keyLower, "password") {
-		if len(value) > 4 {
-			displayValue = value[:4] + "****"
-		} else {
-			displayValue = "****"
-		}
+	return fmt.Sprintf("Config updated: %s = %s", key, displayValue(key, value)), nil
+}
+
+// displayValue returns value for display, masked if key names a secret
+// (API keys, tokens, secrets), so it is not echoed into the conversation.
+func displayValue(key, value string) string {
+	if config.IsSecretKey(key) {
+		return config.MaskSecret(value)
 	}
-	return fmt.Sprintf("Config updated: %s = %s", key, displayValue), nil
+	return config.RedactURL(value)
+}
+
+// SetRestarter sets the function restarting the gateway the tool runs in.
+// Without one, restart asks the running gateway through its control API.
+func (t *ManageUbotTool) SetRestarter(restart func() error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.restarter = restart
 }
 
-// restart returns a message indicating restart was requested.
-func (t *ManageUbotTool) restart() (string, error) {
+// restart schedules a restart of the gateway.
+func (t *ManageUbotTool) restart(ctx context.Context) (string, error) {
+	t.mu.RLock()
+	restarter := t.restarter
+	t.mu.RUnlock()
+
+	if restarter != nil {
+		if err := restarter(); err != nil {
+			return "", fmt.Errorf("manage_ubot: %w", err)
+		}
+	} else {
+		client, err := t.controlClient()
+		if err != nil {
+			return "", err
+		}
+		if err := client.Restart(ctx); err != nil {
+			return "", fmt.Errorf("manage_ubot: %w", err)
+		}
+	}
 	return "Restart requested. The gateway will restart shortly.", nil
 }
 
+// reload makes the running gateway apply the config file and reports what
+// changed.
+func (t *ManageUbotTool) reload(ctx context.Context) (string, error) {
+	client, err := t.controlClient()
+	if err != nil {
+		return "", err
+	}
+	result, err := client.Reload(ctx)
+	if err != nil {
+		return "", fmt.Errorf("manage_ubot: %w", err)
+	}
+
+	var sb strings.Builder
+	if len(result.Applied) == 0 {
+		sb.WriteString("Config reloaded; nothing changed.")
+	} else {
+		sb.WriteString("Config reloaded. Applied: " + strings.Join(result.Applied, ", ") + ".")
+	}
+	if len(result.NeedsRestart) > 0 {
+		sb.WriteString(" Restart the gateway to apply: " + strings.Join(result.NeedsRestart, ", ") + ".")
+	}
+	return sb.String(), nil
+}
+
+// controlClient returns a client for the running gateway's control API.
+func (t *ManageUbotTool) controlClient() (*control.Client, error) {
+	cfg, err := config.LoadConfig(t.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("manage_ubot: failed to load config: %w", err)
+	}
+	if !cfg.Gateway.ControlAPI {
+		return nil, errors.New("manage_ubot: gateway control API is disabled (set gateway.controlApi to true and restart the gateway)")
+	}
+	return control.NewClient(cfg.Gateway.Addr(), cfg.Gateway.Token), nil
+}
+
+// listChannels reports the channel connectors of the running gateway.
+func (t *ManageUbotTool) listChannels(ctx context.Context) (string, error) {
+	client, err := t.controlClient()
+	if err != nil {
+		return "", err
+	}
+	statuses, err := client.Channels(ctx)
+	if err != nil {
+		return "", fmt.Errorf("manage_ubot: %w", err)
+	}
+	if len(statuses) == 0 {
+		return "No channels registered in the running gateway.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Channels:\n")
+	for _, st := range statuses {
+		state := "stopped"
+		if st.Running {
+			state = "running"
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", st.Name, state))
+	}
+	return sb.String(), nil
+}
+
+// toggleChannel starts or stops a channel connector in the running gateway.
+func (t *ManageUbotTool) toggleChannel(ctx context.Context, action string, params map[string]interface{}) (string, error) {
+	name, err := GetStringParam(params, "channel")
+	if err != nil || name == "" {
+		return "", fmt.Errorf("manage_ubot: %s requires 'channel' parameter", action)
+	}
+	client, err := t.controlClient()
+	if err != nil {
+		return "", err
+	}
+
+	if action == "start_channel" {
+		if err := client.StartChannel(ctx, name); err != nil {
+			return "", fmt.Errorf("manage_ubot: %w", err)
+		}
+		return fmt.Sprintf("Channel %s started.", name), nil
+	}
+	if err := client.StopChannel(ctx, name); err != nil {
+		return "", fmt.Errorf("manage_ubot: %w", err)
+	}
+	return fmt.Sprintf("Channel %s stopped.", name), nil
+}
+
 // setNestedValue sets a value in a nested map using a dot-separated key path.
 func setNestedValue(m map[string]interface{}, key, value string) error {
 	parts := splitDotPath(key)
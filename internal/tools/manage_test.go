@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -61,6 +62,7 @@ func TestManageUbotTool_CLISourceAllowed(t *testing.T) {
 	}
 
 	// restart should work
+	tool.SetRestarter(func() error { return nil })
 	result, err = tool.Execute(ctx, map[string]interface{}{
 		"action": "restart",
 	})
@@ -110,6 +112,75 @@ func TestManageUbotTool_EmptySourceDenied(t *testing.T) {
 	}
 }
 
+func TestManageUbotTool_AdminUsers(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	cfg := config.DefaultConfig()
+	cfg.Channels.Telegram.AdminUsers = []string{"42"}
+	cfg.Providers.OpenRouter.APIKey = "sk-or-secret-1234"
+	cfg.Cluster.RedisURL = "redis://:hunter2@localhost:6379/0"
+	if err := config.SaveConfig(cfg, cfgPath); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	tool := NewManageUbotTool(cfgPath)
+	tool.SetSource("telegram")
+	defer tool.ClearSource()
+
+	var prompts []string
+	approve := true
+	approver := approverFunc(func(ctx context.Context, req ApprovalRequest) (bool, error) {
+		prompts = append(prompts, req.Prompt())
+		return approve, nil
+	})
+	as := func(sender string) context.Context {
+		ctx := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "1", SenderID: sender, SessionKey: "telegram:1"})
+		return WithApprover(ctx, approver)
+	}
+
+	// Other users, and a username instead of the ID, are denied
+	for _, sender := range []string{"7|mallory", "0|42"} {
+		if _, err := tool.Execute(as(sender), map[string]interface{}{"action": "show_config"}); err == nil {
+			t.Errorf("show_config as %s should be denied", sender)
+		}
+	}
+
+	// show_config runs without confirmation, with secrets redacted
+	out, err := tool.Execute(as("42|admin"), map[string]interface{}{"action": "show_config"})
+	if err != nil {
+		t.Fatalf("show_config as admin: %v", err)
+	}
+	if strings.Contains(out, "sk-or-secret") || strings.Contains(out, "hunter2") || len(prompts) != 0 {
+		t.Errorf("show_config leaked a secret or asked for confirmation (prompts %v):\n%s", prompts, out)
+	}
+
+	// Channel control stays CLI-only
+	if _, err := tool.Execute(as("42"), map[string]interface{}{"action": "stop_channel", "channel": "telegram"}); err == nil {
+		t.Error("stop_channel from a chat should be denied")
+	}
+
+	// update_config is confirmed in the chat, with secrets masked
+	update := map[string]interface{}{"action": "update_config", "key": "providers.openai.apiKey", "value": "sk-new-secret"}
+	if _, err := tool.Execute(as("42"), update); err != nil {
+		t.Fatalf("confirmed update_config: %v", err)
+	}
+	if len(prompts) != 1 || strings.Contains(prompts[0], "sk-new-secret") {
+		t.Errorf("confirmation prompts = %q", prompts)
+	}
+
+	approve = false
+	update["value"] = "sk-rejected"
+	if _, err := tool.Execute(as("42"), update); err == nil || !strings.Contains(err.Error(), "not confirmed") {
+		t.Errorf("rejected update_config: err = %v", err)
+	}
+	saved, err := config.LoadRawConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Providers.OpenAI.APIKey != "sk-new-secret" {
+		t.Errorf("apiKey = %q, want the confirmed value", saved.Providers.OpenAI.APIKey)
+	}
+}
+
 func TestManageUbotTool_WhatsAppSourceDenied(t *testing.T) {
 	tool := NewManageUbotTool("/nonexistent/config.json")
 	tool.SetSource("whatsapp")
@@ -284,6 +355,64 @@ func TestManageUbotTool_UpdateConfigInvalidKeyPath(t *testing.T) {
 	}
 }
 
+func TestManageUbotTool_UpdateConfigUnknownKey(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	if err := config.SaveConfig(config.DefaultConfig(), cfgPath); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	tool := NewManageUbotTool(cfgPath)
+	tool.SetSource("cli")
+	defer tool.ClearSource()
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "update_config",
+		"key":    "agents.defaults.modle",
+		"value":  "gpt-4o",
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("update_config with a misspelled key = %v, want an unknown key error", err)
+	}
+}
+
+func TestManageUbotTool_UpdateConfigKeepsEnvironmentOut(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	secret := filepath.Join(dir, "openrouter")
+	if err := os.WriteFile(secret, []byte("sk-or-secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Providers.OpenRouter.APIKey = config.SecretFilePrefix + secret
+	if err := config.SaveConfig(cfg, cfgPath); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+	t.Setenv("UBOT_GATEWAY_PORT", "9090")
+
+	tool := NewManageUbotTool(cfgPath)
+	tool.SetSource("cli")
+	defer tool.ClearSource()
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "update_config",
+		"key":    "agents.defaults.model",
+		"value":  "gpt-4o",
+	}); err != nil {
+		t.Fatalf("update_config: %v", err)
+	}
+
+	saved, err := config.LoadRawConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Agents.Defaults.Model != "gpt-4o" {
+		t.Errorf("model = %q, want gpt-4o", saved.Agents.Defaults.Model)
+	}
+	if saved.Providers.OpenRouter.APIKey != config.SecretFilePrefix+secret || saved.Gateway.Port != 8080 {
+		t.Errorf("saved apiKey = %q, port = %d; the environment was written to the file", saved.Providers.OpenRouter.APIKey, saved.Gateway.Port)
+	}
+}
+
 func TestManageUbotTool_ToolMetadata(t *testing.T) {
 	tool := NewManageUbotTool("")
 	if tool.Name() != "manage_ubot" {
@@ -316,6 +445,11 @@ func TestManageUbotTool_RestartMessage(t *testing.T) {
 	tool := NewManageUbotTool("")
 	tool.SetSource("cli")
 	defer tool.ClearSource()
+	restarts := 0
+	tool.SetRestarter(func() error {
+		restarts++
+		return nil
+	})
 
 	ctx := context.Background()
 
@@ -328,6 +462,25 @@ func TestManageUbotTool_RestartMessage(t *testing.T) {
 	if result != "Restart requested. The gateway will restart shortly." {
 		t.Errorf("unexpected restart message: %q", result)
 	}
+	if restarts != 1 {
+		t.Errorf("restarter called %d times, want 1", restarts)
+	}
+}
+
+func TestManageUbotTool_RestartUnsupervised(t *testing.T) {
+	tool := NewManageUbotTool("")
+	tool.SetSource("cli")
+	defer tool.ClearSource()
+	tool.SetRestarter(func() error {
+		return errors.New("the gateway is not supervised")
+	})
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "restart",
+	})
+	if err == nil || !strings.Contains(err.Error(), "not supervised") {
+		t.Errorf("expected the restarter's error, got %v", err)
+	}
 }
 
 func TestSplitDotPath(t *testing.T) {
@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FetchMoreName is the name of the tool that pages through stored tool
+// results.
+const FetchMoreName = "fetch_more"
+
+// MaxResultChars is the most a tool returns. It only guards against
+// runaway output: results longer than the registry's threshold are stored
+// and previewed long before this.
+const MaxResultChars = 1 << 20
+
+const (
+	// previewHeadChars and previewTailChars are how much of the start and
+	// the end of a long result stay inline.
+	previewHeadChars = 1500
+	previewTailChars = 500
+	// defaultFetchLimit is how many characters fetch_more returns by default.
+	defaultFetchLimit = 8000
+	// resultHandlePrefix marks handles of stored results.
+	resultHandlePrefix = "res_"
+	// tokenSeparator separates the handle and the offset in a continuation
+	// token, e.g. res_1a2b3c4d5e6f708192a3@1500.
+	tokenSeparator = "@"
+)
+
+// OverflowStore persists tool results too large to keep in the transcript,
+// e.g. as local files or Redis keys shared by a cluster.
+type OverflowStore interface {
+	// Load returns the stored result for key, or nil if it does not exist.
+	Load(key string) ([]byte, error)
+	Save(key string, data []byte) error
+}
+
+// DirStore is an OverflowStore keeping each result in a file. Results older
+// than maxAge are removed as new ones are saved.
+type DirStore struct {
+	dir    string
+	maxAge time.Duration
+}
+
+// NewDirStore creates a DirStore in dir.
+func NewDirStore(dir string, maxAge time.Duration) *DirStore {
+	return &DirStore{dir: dir, maxAge: maxAge}
+}
+
+// Load returns the result stored under key, or nil if it does not exist.
+func (s *DirStore) Load(key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.Base(key)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save stores data under key and prunes expired results.
+func (s *DirStore) Save(key string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, filepath.Base(key)), data, 0o600); err != nil {
+		return err
+	}
+
+	if s.maxAge > 0 {
+		entries, _ := os.ReadDir(s.dir)
+		cutoff := time.Now().Add(-s.maxAge)
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(filepath.Join(s.dir, e.Name()))
+			}
+		}
+	}
+	return nil
+}
+
+// SetOverflow sets the registry's policy for results longer than
+// threshold characters: the model sees their start and end, and the whole
+// result is kept in store, to be paged through with fetch_more or passed to
+// other tools as an artifact. Without a store such results are cut to the
+// preview. It must be set before the registry is used.
+func (s *SecureRegistry) SetOverflow(store OverflowStore, threshold int) {
+	s.overflowStore = store
+	s.overflowThreshold = threshold
+}
+
+// overflow applies the large result policy, returning what replaces
+// result in the transcript. Results that cannot be stored are cut to the
+// preview.
+func (s *SecureRegistry) overflow(ctx context.Context, name, result string) string {
+	if s.overflowThreshold <= 0 || name == FetchMoreName || len(result) <= s.overflowThreshold {
+		return result
+	}
+	head := truncateRunes(result, previewHeadChars)
+	tail := tailRunes(result, previewTailChars)
+	omitted := len(result) - len(head) - len(tail)
+	preview := fmt.Sprintf("%s\n\n[... %d characters omitted ...]\n\n%s", head, omitted, tail)
+
+	handle := ""
+	if s.overflowStore != nil {
+		var err error
+		handle, err = newResultHandle(sessionKeyOf(ctx))
+		if err == nil {
+			err = s.overflowStore.Save(handle, []byte(result))
+		}
+		if err != nil {
+			log.Printf("Warning: failed to store large %s result: %v", name, err)
+			handle = ""
+		}
+	}
+	if handle == "" {
+		return fmt.Sprintf("[%s returned %d characters; the first %d and the last %d are shown. Narrow the request to see the rest.]\n\n%s",
+			name, len(result), len(head), len(tail), preview)
+	}
+
+	// Make sure the model can fetch the rest in this turn
+	if sel, ok := ctx.Value(toolSelectionKey{}).(*ToolSelection); ok {
+		sel.Add(FetchMoreName)
+	}
+
+	return fmt.Sprintf("[%s returned %d characters, stored as %s%s. The first %d and the last %d are shown; call %s with token %q to read on from where the preview stops. To pass the whole result to another tool, e.g. as write_file content, give %s%s as the parameter's value instead of repeating it.]\n\n%s",
+		name, len(result), ArtifactScheme, handle, len(head), len(tail), FetchMoreName, continuationToken(handle, len(head)), ArtifactScheme, handle, preview)
+}
+
+// continuationToken returns the token that reads the result stored as
+// handle from offset on.
+func continuationToken(handle string, offset int) string {
+	return handle + tokenSeparator + strconv.Itoa(offset)
+}
+
+// parseContinuationToken returns the handle and offset of token, which may
+// also be a bare handle or an artifact reference to read from the start.
+func parseContinuationToken(token string) (string, int, error) {
+	token = strings.TrimPrefix(strings.TrimSpace(token), ArtifactScheme)
+	handle, offsetStr, found := strings.Cut(token, tokenSeparator)
+	if !validHandle(handle) {
+		return "", 0, fmt.Errorf("invalid token %q", token)
+	}
+	offset := 0
+	if found {
+		var err error
+		if offset, err = strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			return "", 0, fmt.Errorf("invalid token %q", token)
+		}
+	}
+	return handle, offset, nil
+}
+
+// truncateRunes cuts s to at most n bytes without splitting a UTF-8 character.
+func truncateRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !isRuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// tailRunes returns at most the last n bytes of s without splitting a
+// UTF-8 character.
+func tailRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !isRuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
+}
+
+// isRuneStart reports whether b begins a UTF-8 character.
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// newResultHandle returns a random handle for a result stored for the
+// conversation with sessionKey.
+func newResultHandle(sessionKey string) (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate result handle: %w", err)
+	}
+	return resultHandlePrefix + artifactTag(sessionKey) + hex.EncodeToString(b), nil
+}
+
+// FetchMoreTool pages through tool results that were too large to keep in
+// the transcript.
+type FetchMoreTool struct {
+	BaseTool
+	store OverflowStore
+}
+
+// NewFetchMoreTool creates a FetchMoreTool reading from store.
+func NewFetchMoreTool(store OverflowStore) *FetchMoreTool {
+	return &FetchMoreTool{
+		BaseTool: NewBaseTool(
+			FetchMoreName,
+			"Read more of a large tool result that was stored out of the conversation. Pass the continuation token from the result notice; each page ends with the token for the next one.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"token": map[string]interface{}{
+						"type":        "string",
+						"description": "Continuation token, e.g. res_1a2b3c4d5e6f708192a3@1500, or an artifact://res_… reference to read from the start",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum number of characters to return (default %d)", defaultFetchLimit),
+					},
+				},
+				"required": []string{"token"},
+			},
+		),
+		store: store,
+	}
+}
+
+// Execute returns the page of a stored result the token points at.
+func (t *FetchMoreTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	token, err := GetStringParam(params, "token")
+	if err != nil {
+		return "", fmt.Errorf("fetch_more: %w", err)
+	}
+	handle, offset, err := parseContinuationToken(token)
+	if err != nil {
+		return "", fmt.Errorf("fetch_more: %w", err)
+	}
+	limit := GetIntParamOr(params, "limit", defaultFetchLimit)
+	if limit <= 0 {
+		return "", fmt.Errorf("fetch_more: limit must be > 0")
+	}
+
+	var data []byte
+	if ownsHandle(ctx, handle) {
+		if data, err = t.store.Load(handle); err != nil {
+			return "", fmt.Errorf("fetch_more: %w", err)
+		}
+	}
+	if data == nil {
+		return "", fmt.Errorf("fetch_more: no stored result %s (it may have expired)", handle)
+	}
+
+	result := string(data)
+	if offset >= len(result) {
+		return fmt.Sprintf("[%s has %d characters; nothing is left after %d]", handle, len(result), offset), nil
+	}
+	end := offset + limit
+	if end >= len(result) {
+		return result[offset:], nil
+	}
+	chunk := truncateRunes(result[offset:], limit)
+	next := offset + len(chunk)
+	return fmt.Sprintf("%s\n\n[characters %d-%d of %d; continue with token %q]",
+		chunk, offset, next, len(result), continuationToken(handle, next)), nil
+}
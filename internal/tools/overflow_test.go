@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecureRegistry_Overflow(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("line of output\n", 500) + "the end\n" // 7508 chars
+	if err := os.WriteFile(filepath.Join(dir, "big.txt"), []byte(big), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewDirStore(filepath.Join(dir, "results"), time.Hour)
+	reg := NewRegistry()
+	reg.Register(NewReadFileTool())
+	reg.Register(NewFetchMoreTool(store))
+	secure := NewSecureRegistry(reg)
+	secure.SetOverflow(store, 5000)
+
+	ctx := context.Background()
+	stub, err := secure.Execute(ctx, "read_file", map[string]interface{}{"path": filepath.Join(dir, "big.txt")})
+	if err != nil {
+		t.Fatalf("read_file: %v", err)
+	}
+	if len(stub) >= len(big) {
+		t.Fatalf("large result was not replaced by a stub (%d chars)", len(stub))
+	}
+	// The preview keeps the start and the end
+	if !strings.Contains(stub, big[:previewHeadChars]) || !strings.HasSuffix(stub, "the end\n") || !strings.Contains(stub, "characters omitted") {
+		t.Errorf("stub lacks the head or tail:\n%s", stub)
+	}
+	token := regexp.MustCompile(`res_[0-9a-f]+@\d+`).FindString(stub)
+	if token == "" {
+		t.Fatalf("stub has no continuation token: %q", stub[:300])
+	}
+
+	// Page through the rest with the tokens each page ends with
+	got := big[:previewHeadChars]
+	for i := 0; i < 10 && token != ""; i++ {
+		part, err := secure.Execute(ctx, FetchMoreName, map[string]interface{}{"token": token, "limit": float64(3000)})
+		if err != nil {
+			t.Fatalf("fetch_more: %v", err)
+		}
+		chunk, hint, more := strings.Cut(part, "\n\n[characters ")
+		got += chunk
+		token = ""
+		if more {
+			token = regexp.MustCompile(`res_[0-9a-f]+@\d+`).FindString(hint)
+		}
+	}
+	if got != big {
+		t.Errorf("fetched %d chars, want the original %d", len(got), len(big))
+	}
+
+	for _, bad := range []string{"res_missing", "../config.json", "res_abc@-5", "res_abc@x"} {
+		if _, err := secure.Execute(ctx, FetchMoreName, map[string]interface{}{"token": bad}); err == nil {
+			t.Errorf("fetch_more(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSecureRegistry_OverflowWithoutStore(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("x", 9000) + "the end"
+	if err := os.WriteFile(filepath.Join(dir, "big.txt"), []byte(big), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	reg := NewRegistry()
+	reg.Register(NewReadFileTool())
+	secure := NewSecureRegistry(reg)
+	secure.SetOverflow(nil, 5000)
+
+	stub, err := secure.Execute(context.Background(), "read_file", map[string]interface{}{"path": filepath.Join(dir, "big.txt")})
+	if err != nil {
+		t.Fatalf("read_file: %v", err)
+	}
+	if len(stub) > previewHeadChars+previewTailChars+300 || !strings.HasSuffix(stub, "the end") || strings.Contains(stub, FetchMoreName) {
+		t.Errorf("stub = %q", stub)
+	}
+}
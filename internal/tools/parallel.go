@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// concurrentTools are read-only tools whose calls may run at the same time.
+// Calls to any other tool, including MCP tools, run on their own and in
+// order relative to the calls around them.
+var concurrentTools = map[string]bool{
+	"read_file":       true,
+	"list_dir":        true,
+	"search_files":    true,
+	"web_fetch":       true,
+	"web_search":      true,
+	"list_skills":     true,
+	"read_skill":      true,
+	"symbol_search":   true,
+	"open_definition": true,
+	"capabilities":    true,
+	FetchMoreName:     true,
+}
+
+// Call is a tool call requested by the model.
+type Call struct {
+	ID     string
+	Name   string
+	Params map[string]interface{}
+}
+
+// CallResult is the outcome of a Call.
+type CallResult struct {
+	Call   Call
+	Result string
+	Err    error
+}
+
+// SetParallelism sets how many tool calls ExecuteAll runs at once and how
+// long a single call may run; timeouts overrides the limit per tool. A
+// worker count of one or less runs calls one at a time, and a zero timeout
+// means no limit. It must be set before the registry is used.
+func (s *SecureRegistry) SetParallelism(workers int, timeout time.Duration, timeouts map[string]time.Duration) {
+	s.workers = workers
+	s.timeout = timeout
+	s.timeouts = timeouts
+}
+
+// ExecuteAll executes the tool calls of one model turn and returns their
+// results in the order of calls. Consecutive calls to read-only tools run
+// concurrently on up to the configured number of workers; any other call
+// waits for the calls before it and runs alone.
+func (s *SecureRegistry) ExecuteAll(ctx context.Context, calls []Call) []CallResult {
+	results := make([]CallResult, len(calls))
+	var batch []int
+	for i, call := range calls {
+		if concurrentTools[call.Name] {
+			batch = append(batch, i)
+			continue
+		}
+		s.executeBatch(ctx, calls, batch, results)
+		batch = nil
+		results[i] = s.executeCall(ctx, call)
+	}
+	s.executeBatch(ctx, calls, batch, results)
+	return results
+}
+
+// executeBatch runs calls[i] for each index in batch concurrently and
+// stores the results at the same indexes.
+func (s *SecureRegistry) executeBatch(ctx context.Context, calls []Call, batch []int, results []CallResult) {
+	workers := s.workers
+	if workers > len(batch) {
+		workers = len(batch)
+	}
+	if workers <= 1 {
+		for _, i := range batch {
+			results[i] = s.executeCall(ctx, calls[i])
+		}
+		return
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = s.executeCall(ctx, calls[i])
+			}
+		}()
+	}
+	for _, i := range batch {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// executeCall runs one call, recovering from panics in the tool.
+func (s *SecureRegistry) executeCall(ctx context.Context, call Call) (res CallResult) {
+	res.Call = call
+	defer func() {
+		if r := recover(); r != nil {
+			res.Err = fmt.Errorf("tool %s panicked: %v", call.Name, r)
+		}
+	}()
+	res.Result, res.Err = s.Execute(ctx, call.Name, call.Params)
+	return res
+}
+
+// timeoutFor returns the time limit for a call to the named tool; zero
+// means no limit.
+func (s *SecureRegistry) timeoutFor(name string) time.Duration {
+	if d, ok := s.timeouts[name]; ok {
+		return d
+	}
+	return s.timeout
+}
+
+// runTool runs the tool on the inner registry within its time limit. A tool
+// that ignores cancellation is left to finish in the background; its result
+// is discarded.
+func (s *SecureRegistry) runTool(ctx context.Context, name string, params map[string]interface{}) (string, error) {
+	limit := s.timeoutFor(name)
+	if limit <= 0 {
+		return s.inner.Execute(ctx, name, params)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("tool %s panicked: %v", name, r)}
+			}
+		}()
+		result, err := s.inner.Execute(ctx, name, params)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", ErrToolExecution{Name: name, Err: fmt.Errorf("timed out after %s", limit)}
+		}
+		return "", ctx.Err()
+	}
+}
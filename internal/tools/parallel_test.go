@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// finishLog records the order in which tool calls finish.
+type finishLog struct {
+	mu    sync.Mutex
+	names []string
+}
+
+// sleepTool waits for the "ms" parameter and returns its name.
+type sleepTool struct {
+	BaseTool
+	finished *finishLog
+}
+
+func newSleepTool(name string, finished *finishLog) *sleepTool {
+	return &sleepTool{
+		BaseTool: NewBaseTool(name, "sleeps", map[string]interface{}{"type": "object"}),
+		finished: finished,
+	}
+}
+
+func (t *sleepTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	ms, _ := params["ms"].(float64)
+	select {
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	t.finished.mu.Lock()
+	t.finished.names = append(t.finished.names, t.Name())
+	t.finished.mu.Unlock()
+	return t.Name(), nil
+}
+
+func TestSecureRegistry_ExecuteAll(t *testing.T) {
+	finished := &finishLog{}
+	reg := NewRegistry()
+	reg.Register(newSleepTool("web_fetch", finished))
+	reg.Register(newSleepTool("read_file", finished))
+	reg.Register(newSleepTool("exec", finished))
+	secure := NewSecureRegistry(reg)
+	secure.SetParallelism(4, 0, nil)
+
+	calls := []Call{
+		{ID: "1", Name: "web_fetch", Params: map[string]interface{}{"ms": float64(150)}},
+		{ID: "2", Name: "read_file", Params: map[string]interface{}{"ms": float64(150)}},
+		{ID: "3", Name: "exec", Params: map[string]interface{}{"ms": float64(10)}},
+		{ID: "4", Name: "read_file", Params: map[string]interface{}{"ms": float64(10)}},
+	}
+	start := time.Now()
+	results := secure.ExecuteAll(context.Background(), calls)
+	if elapsed := time.Since(start); elapsed > 280*time.Millisecond {
+		t.Errorf("ExecuteAll took %s; read-only calls did not run concurrently", elapsed)
+	}
+
+	for i, res := range results {
+		if res.Err != nil || res.Call.ID != calls[i].ID || res.Result != calls[i].Name {
+			t.Errorf("result %d = %+v, want call %s", i, res, calls[i].ID)
+		}
+	}
+	// exec waits for the reads before it and runs before the read after it
+	if got := finished.names; len(got) != 4 || got[2] != "exec" || got[3] != "read_file" {
+		t.Errorf("finish order = %v, want exec third", got)
+	}
+}
+
+func TestSecureRegistry_ExecuteAllTimeout(t *testing.T) {
+	finished := &finishLog{}
+	reg := NewRegistry()
+	reg.Register(newSleepTool("web_fetch", finished))
+	reg.Register(newSleepTool("web_search", finished))
+	secure := NewSecureRegistry(reg)
+	secure.SetParallelism(2, 50*time.Millisecond, map[string]time.Duration{"web_search": time.Second})
+
+	results := secure.ExecuteAll(context.Background(), []Call{
+		{ID: "a", Name: "web_fetch", Params: map[string]interface{}{"ms": float64(500)}},
+		{ID: "b", Name: "web_search", Params: map[string]interface{}{"ms": float64(100)}},
+	})
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "timed out") {
+		t.Errorf("web_fetch err = %v, want timeout", results[0].Err)
+	}
+	if results[1].Err != nil || results[1].Result != "web_search" {
+		t.Errorf("web_search = %+v, want success under its own limit", results[1])
+	}
+}
@@ -0,0 +1,551 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches "@@ -12,5 +12,6 @@"; the counts are optional.
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// ApplyPatchTool applies unified diffs to files. Every hunk of every file
+// is checked before anything is written, so a patch applies completely or
+// not at all.
+type ApplyPatchTool struct {
+	BaseTool
+	root string
+}
+
+// NewApplyPatchTool creates a new ApplyPatchTool for the files below root.
+// Paths in patches are relative to root and cannot leave it.
+func NewApplyPatchTool(root string) *ApplyPatchTool {
+	return &ApplyPatchTool{
+		BaseTool: NewBaseTool(
+			"apply_patch",
+			fmt.Sprintf("Apply a unified diff (as made by diff -u or git diff) to files below %s. Can change, create (--- /dev/null), delete (+++ /dev/null) and rename files, several at once. Hunks are found by their context lines even when line numbers are off. If any hunk does not apply, nothing is changed and the conflicts are reported; use dry_run to only check.", root),
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"patch": map[string]interface{}{
+						"type":        "string",
+						"description": "The unified diff, with '--- a/path' and '+++ b/path' lines, '@@' hunk headers and at least 2 lines of context around each change",
+					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Only check that the patch applies, without changing files",
+					},
+				},
+				"required": []string{"patch"},
+			},
+		),
+		root: root,
+	}
+}
+
+// filePatch is the part of a patch for one file.
+type filePatch struct {
+	oldPath string // "" for a created file
+	newPath string // "" for a deleted file
+	hunks   []hunk
+}
+
+// hunk is one "@@" section of a filePatch.
+type hunk struct {
+	oldStart int      // 1-based line from the header; 0 when unknown
+	old      []string // context and removed lines
+	new      []string // context and added lines
+	newNoEOL bool     // "\ No newline at end of file" after the new side
+	added    int
+	removed  int
+}
+
+// patchedFile is a file as the patch has changed it so far.
+type patchedFile struct {
+	path    string
+	existed bool
+	orig    []byte
+	mode    fs.FileMode
+	lines   []string
+	eol     bool // ends with a newline
+	crlf    bool
+	deleted bool
+}
+
+// content returns the file's new content.
+func (f *patchedFile) content() []byte {
+	if len(f.lines) == 0 {
+		return nil
+	}
+	sep := "\n"
+	if f.crlf {
+		sep = "\r\n"
+	}
+	s := strings.Join(f.lines, sep)
+	if f.eol {
+		s += sep
+	}
+	return []byte(s)
+}
+
+// Execute applies the patch.
+func (t *ApplyPatchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	text, err := GetStringParam(params, "patch")
+	if err != nil {
+		return "", fmt.Errorf("apply_patch: %w", err)
+	}
+	dryRun := GetBoolParamOr(params, "dry_run", false)
+
+	patches, err := parsePatch(text)
+	if err != nil {
+		return "", fmt.Errorf("apply_patch: %w", err)
+	}
+
+	files := make(map[string]*patchedFile)
+	var order []*patchedFile
+	load := func(rel string) (*patchedFile, error) {
+		p, err := t.resolve(rel)
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := files[p]; ok {
+			return f, nil
+		}
+		f := &patchedFile{path: p, mode: 0o644}
+		data, err := os.ReadFile(p)
+		switch {
+		case err == nil:
+			info, err := os.Stat(p)
+			if err != nil {
+				return nil, err
+			}
+			f.existed, f.orig, f.mode = true, data, info.Mode().Perm()
+			f.lines, f.eol, f.crlf = splitLines(data)
+		case errors.Is(err, fs.ErrNotExist):
+			f.deleted = true
+		default:
+			return nil, err
+		}
+		files[p] = f
+		order = append(order, f)
+		return f, nil
+	}
+
+	var conflicts, notes, summary []string
+	for _, fp := range patches {
+		name := fp.newPath
+		if name == "" {
+			name = fp.oldPath
+		}
+		src, dst, err := t.files(fp, load)
+		if err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		lines, eol, hunkNotes, hunkConflicts := applyHunks(src.lines, src.eol, fp.hunks)
+		for _, n := range hunkNotes {
+			notes = append(notes, name+": "+n)
+		}
+		if len(hunkConflicts) > 0 {
+			for _, c := range hunkConflicts {
+				conflicts = append(conflicts, name+": "+c)
+			}
+			continue
+		}
+
+		added, removed := 0, 0
+		for _, h := range fp.hunks {
+			added, removed = added+h.added, removed+h.removed
+		}
+		switch {
+		case dst == nil:
+			if len(lines) > 0 {
+				conflicts = append(conflicts, fmt.Sprintf("%s: the file would not be empty after removing the patch's lines; not deleted", name))
+				continue
+			}
+			src.lines, src.deleted = nil, true
+			summary = append(summary, "D "+fp.oldPath)
+		case src != dst:
+			dst.lines, dst.eol, dst.crlf, dst.mode, dst.deleted = lines, eol, src.crlf, src.mode, false
+			if fp.oldPath == "" {
+				summary = append(summary, fmt.Sprintf("A %s (+%d)", fp.newPath, added))
+			} else {
+				src.lines, src.deleted = nil, true
+				summary = append(summary, fmt.Sprintf("R %s -> %s (+%d -%d)", fp.oldPath, fp.newPath, added, removed))
+			}
+		default:
+			src.lines, src.eol = lines, eol
+			summary = append(summary, fmt.Sprintf("M %s (+%d -%d)", fp.newPath, added, removed))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return "", fmt.Errorf("apply_patch: the patch does not apply, no files were changed:\n  %s", strings.Join(conflicts, "\n  "))
+	}
+
+	var out strings.Builder
+	if dryRun {
+		out.WriteString("The patch applies cleanly (dry run, no files were changed):\n")
+	} else {
+		if err := writePatched(order); err != nil {
+			return "", fmt.Errorf("apply_patch: %w", err)
+		}
+		out.WriteString("Applied the patch:\n")
+	}
+	out.WriteString("  " + strings.Join(summary, "\n  "))
+	if len(notes) > 0 {
+		out.WriteString("\nNotes:\n  " + strings.Join(notes, "\n  "))
+	}
+	return out.String(), nil
+}
+
+// files returns the file fp changes and the file it leaves, which differ
+// for created and renamed files; dst is nil when fp deletes the file.
+func (t *ApplyPatchTool) files(fp filePatch, load func(string) (*patchedFile, error)) (src, dst *patchedFile, err error) {
+	if fp.oldPath != "" {
+		if src, err = load(fp.oldPath); err != nil {
+			return nil, nil, err
+		}
+		if src.deleted {
+			return nil, nil, fmt.Errorf("file not found")
+		}
+	}
+	if fp.newPath == "" {
+		return src, nil, nil
+	}
+	if dst, err = load(fp.newPath); err != nil {
+		return nil, nil, err
+	}
+	if src == nil {
+		// A created file starts empty
+		if !dst.deleted {
+			return nil, nil, fmt.Errorf("file already exists")
+		}
+		return &patchedFile{eol: true, mode: 0o644}, dst, nil
+	}
+	if src != dst && !dst.deleted {
+		return nil, nil, fmt.Errorf("cannot rename %s: the new name already exists", fp.oldPath)
+	}
+	return src, dst, nil
+}
+
+// Paths returns the files a patch would create, change, delete or rename,
+// for the registry's path checks.
+func (t *ApplyPatchTool) Paths(params map[string]interface{}) []string {
+	text, err := GetStringParam(params, "patch")
+	if err != nil {
+		return nil
+	}
+	patches, err := parsePatch(text)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, fp := range patches {
+		for _, rel := range []string{fp.oldPath, fp.newPath} {
+			if rel != "" {
+				paths = append(paths, t.abs(rel))
+			}
+		}
+	}
+	return paths
+}
+
+// abs returns the absolute path of a path in a patch.
+func (t *ApplyPatchTool) abs(rel string) string {
+	if filepath.IsAbs(rel) {
+		return filepath.Clean(rel)
+	}
+	return filepath.Join(t.root, filepath.FromSlash(rel))
+}
+
+// resolve returns the absolute path of rel, which must be below the
+// tool's root, also once symlinks are followed, and not a sensitive file.
+func (t *ApplyPatchTool) resolve(rel string) (string, error) {
+	p := t.abs(rel)
+	r, err := filepath.Rel(t.root, p)
+	if err != nil || !filepath.IsLocal(r) {
+		return "", fmt.Errorf("outside %s", t.root)
+	}
+	// A symlink below the root may point anywhere
+	real, err := followsOutside(t.root, p)
+	if err != nil {
+		return "", err
+	}
+	if real != "" {
+		return "", fmt.Errorf("outside %s: leads to %s through a symlink", t.root, real)
+	}
+	if isSensitiveName(filepath.Base(p)) {
+		return "", ErrBlockedPath{Path: rel, Reason: "sensitive filename"}
+	}
+	return p, nil
+}
+
+// splitLines splits data into lines without their line endings.
+func splitLines(data []byte) (lines []string, eol, crlf bool) {
+	if len(data) == 0 {
+		return nil, true, false
+	}
+	s := string(data)
+	crlf = strings.Contains(s, "\r\n")
+	if crlf {
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+	}
+	eol = strings.HasSuffix(s, "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n"), eol, crlf
+}
+
+// parsePatch parses a unified diff. Text around the file sections, such
+// as "diff --git" and "index" lines or a commit message, is ignored.
+func parsePatch(text string) ([]filePatch, error) {
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	isFileHeader := func(i int) bool {
+		return strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+	}
+
+	var patches []filePatch
+	for i := 0; i < len(lines); {
+		if !isFileHeader(i) {
+			i++
+			continue
+		}
+		fp := filePatch{oldPath: patchPath(lines[i][4:]), newPath: patchPath(lines[i+1][4:])}
+		if strings.HasPrefix(fp.oldPath, "a/") && (fp.newPath == "" || strings.HasPrefix(fp.newPath, "b/")) ||
+			fp.oldPath == "" && strings.HasPrefix(fp.newPath, "b/") {
+			fp.oldPath, fp.newPath = strings.TrimPrefix(fp.oldPath, "a/"), strings.TrimPrefix(fp.newPath, "b/")
+		}
+		if fp.oldPath == "" && fp.newPath == "" {
+			return nil, fmt.Errorf("line %d: both file names are /dev/null", i+1)
+		}
+		i += 2
+
+		for i < len(lines) && strings.HasPrefix(lines[i], "@@") {
+			h := hunk{}
+			if m := hunkHeader.FindStringSubmatch(lines[i]); m != nil {
+				h.oldStart, _ = strconv.Atoi(m[1])
+			}
+			i++
+			last := byte(0)
+		body:
+			for ; i < len(lines) && !isFileHeader(i); i++ {
+				line := lines[i]
+				if line == "" {
+					// Editors and models often drop the space of an
+					// empty context line; a blank line between files
+					// ends the hunk
+					if !blankInHunk(lines, i) {
+						break
+					}
+					line = " "
+				}
+				switch line[0] {
+				case ' ':
+					h.old, h.new = append(h.old, line[1:]), append(h.new, line[1:])
+				case '-':
+					h.old = append(h.old, line[1:])
+					h.removed++
+				case '+':
+					h.new = append(h.new, line[1:])
+					h.added++
+				case '\\':
+					if last != '-' {
+						h.newNoEOL = true
+					}
+				default:
+					break body
+				}
+				last = line[0]
+			}
+			fp.hunks = append(fp.hunks, h)
+		}
+		if len(fp.hunks) == 0 && fp.oldPath != "" && fp.newPath != "" {
+			return nil, fmt.Errorf("no hunks for %s", fp.newPath)
+		}
+		patches = append(patches, fp)
+	}
+	if len(patches) == 0 {
+		return nil, errors.New("no file changes found; the patch needs '--- a/path' and '+++ b/path' lines followed by '@@' hunks")
+	}
+	return patches, nil
+}
+
+// blankInHunk reports whether the blank line i is followed by more lines
+// of the same hunk.
+func blankInHunk(lines []string, i int) bool {
+	for _, next := range lines[i+1:] {
+		if next == "" {
+			continue
+		}
+		return strings.ContainsRune(" +-\\", rune(next[0])) && !strings.HasPrefix(next, "--- ")
+	}
+	return false
+}
+
+// patchPath returns the file name of a "---" or "+++" line, without a
+// timestamp, or "" for /dev/null.
+func patchPath(s string) string {
+	s, _, _ = strings.Cut(s, "\t")
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	return s
+}
+
+// applyHunks applies hunks to lines in order. A hunk is looked for at the
+// line its header names, adjusted by where earlier hunks applied, then
+// ever further away; trailing whitespace is ignored when no exact match
+// exists.
+func applyHunks(lines []string, eol bool, hunks []hunk) (result []string, resultEOL bool, notes, conflicts []string) {
+	result = append([]string(nil), lines...)
+	pos, offset := 0, 0
+	for k, h := range hunks {
+		expected := max(h.oldStart-1, 0)
+		if len(h.old) == 0 {
+			// Pure insertion: "-12,0" means after line 12
+			expected = h.oldStart
+		}
+		hint := expected + offset
+		if h.oldStart == 0 {
+			hint = pos
+		}
+
+		at := -1
+		if len(h.old) == 0 {
+			at = min(max(hint, pos), len(result))
+		} else {
+			at = findHunk(result, h.old, pos, hint, false)
+			if at < 0 {
+				at = findHunk(result, h.old, pos, hint, true)
+				if at >= 0 {
+					notes = append(notes, fmt.Sprintf("hunk %d matched only when ignoring trailing whitespace", k+1))
+				}
+			}
+		}
+		if at < 0 {
+			conflicts = append(conflicts, fmt.Sprintf("hunk %d does not apply: %s", k+1, describeMismatch(result, h.old, min(max(hint, pos), len(result)))))
+			continue
+		}
+		if h.oldStart != 0 && at != hint {
+			notes = append(notes, fmt.Sprintf("hunk %d applied at line %d instead of %d", k+1, at+1, hint+1))
+		}
+
+		end := at + len(h.old)
+		touchesEnd := end == len(result)
+		result = append(result[:at], append(append([]string(nil), h.new...), result[end:]...)...)
+		if touchesEnd {
+			eol = !h.newNoEOL
+		}
+		offset = at + len(h.new) - (expected + len(h.old))
+		pos = at + len(h.new)
+	}
+	return result, eol, notes, conflicts
+}
+
+// findHunk returns the index in lines, at or after pos, where old starts,
+// nearest to hint, or -1.
+func findHunk(lines, old []string, pos, hint int, loose bool) int {
+	last := len(lines) - len(old)
+	if last < pos {
+		return -1
+	}
+	hint = min(max(hint, pos), last)
+	for d := 0; hint-d >= pos || hint+d <= last; d++ {
+		if i := hint - d; i >= pos && matchAt(lines, old, i, loose) {
+			return i
+		}
+		if i := hint + d; d > 0 && i <= last && matchAt(lines, old, i, loose) {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchAt reports whether old matches lines starting at i.
+func matchAt(lines, old []string, i int, loose bool) bool {
+	for j, want := range old {
+		got := lines[i+j]
+		if loose {
+			got, want = strings.TrimRight(got, " \t"), strings.TrimRight(want, " \t")
+		}
+		if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// describeMismatch tells how old differs from lines at i.
+func describeMismatch(lines, old []string, i int) string {
+	for j, want := range old {
+		if i+j >= len(lines) {
+			return fmt.Sprintf("expected %q at line %d, but the file has only %d lines", want, i+j+1, len(lines))
+		}
+		if got := lines[i+j]; got != want {
+			return fmt.Sprintf("expected %q at line %d, found %q (and the hunk's lines are nowhere else in the file)", want, i+j+1, got)
+		}
+	}
+	return "its lines are not in the file"
+}
+
+// writePatched writes the changed files. If a write fails, the files
+// already written are restored.
+func writePatched(files []*patchedFile) error {
+	var done []*patchedFile
+	restore := func() {
+		for _, f := range done {
+			if f.existed {
+				os.WriteFile(f.path, f.orig, f.mode)
+			} else {
+				os.Remove(f.path)
+			}
+		}
+	}
+	for _, f := range files {
+		if !f.existed && f.deleted {
+			continue
+		}
+		var err error
+		if f.deleted {
+			err = os.Remove(f.path)
+		} else {
+			err = writeFileAtomic(f.path, f.content(), f.mode)
+		}
+		if err != nil {
+			restore()
+			return err
+		}
+		done = append(done, f)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at p with data through a temporary
+// file, so readers never see it half written.
+func writeFileAtomic(p string, data []byte, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".patch-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatchTool(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	write("main.go", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n")
+	write("old.txt", "remove me\n")
+	write("notes.md", "# Notes\n")
+	tool := NewApplyPatchTool(root)
+	apply := func(patch string, dryRun bool) (string, error) {
+		return tool.Execute(context.Background(), map[string]interface{}{"patch": patch, "dry_run": dryRun})
+	}
+
+	// Line numbers off by two and a blank context line without its space
+	patch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -5,5 +5,5 @@
+ import "fmt"
+
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hello, world")
+ }
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1,2 @@
++# New
++text
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-remove me
+`
+	out, err := apply(patch, true)
+	if err != nil || !strings.Contains(out, "dry run") {
+		t.Fatalf("dry run: %q, %v", out, err)
+	}
+	if strings.Contains(read("main.go"), "world") {
+		t.Fatal("dry run changed main.go")
+	}
+
+	out, err = apply(patch, false)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	for _, want := range []string{"M main.go (+1 -1)", "A docs/new.md (+2)", "D old.txt", "hunk 1 applied at line 3 instead of 5"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if got := read("main.go"); !strings.Contains(got, "\tfmt.Println(\"hello, world\")\n}\n") {
+		t.Errorf("main.go = %q", got)
+	}
+	if got := read("docs/new.md"); got != "# New\ntext\n" {
+		t.Errorf("docs/new.md = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "old.txt")); !os.IsNotExist(err) {
+		t.Error("old.txt was not deleted")
+	}
+
+	// One conflicting hunk keeps every file unchanged
+	_, err = apply(`--- a/notes.md
++++ b/notes.md
+@@ -1 +1,2 @@
+ # Notes
++added
+--- a/main.go
++++ b/main.go
+@@ -6 +6 @@
+-	fmt.Println("goodbye")
++	fmt.Println("bye")
+`, false)
+	if err == nil || !strings.Contains(err.Error(), "main.go: hunk 1 does not apply") || !strings.Contains(err.Error(), `found "\tfmt.Println(\"hello, world\")"`) {
+		t.Errorf("conflict: err = %v", err)
+	}
+	if got := read("notes.md"); got != "# Notes\n" {
+		t.Errorf("notes.md changed by a failed patch: %q", got)
+	}
+
+	// Renames keep the content, and paths cannot leave the root
+	out, err = apply("--- a/notes.md\n+++ b/notes/index.md\n@@ -1 +1 @@\n-# Notes\n+# Index\n", false)
+	if err != nil || !strings.Contains(out, "R notes.md -> notes/index.md") || read("notes/index.md") != "# Index\n" {
+		t.Errorf("rename: %q, %v", out, err)
+	}
+	if _, err := apply("--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n", false); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("escape: err = %v", err)
+	}
+	if _, err := apply("just some text", false); err == nil || !strings.Contains(err.Error(), "no file changes") {
+		t.Errorf("not a patch: err = %v", err)
+	}
+}
+
+func TestApplyHunksEndOfFile(t *testing.T) {
+	lines, eol, _ := splitLines([]byte("a\r\nb"))
+	hunks := []hunk{{oldStart: 2, old: []string{"b"}, new: []string{"b", "c"}, added: 1}}
+	result, eol, _, conflicts := applyHunks(lines, eol, hunks)
+	if len(conflicts) > 0 {
+		t.Fatalf("conflicts: %v", conflicts)
+	}
+	f := &patchedFile{lines: result, eol: eol, crlf: true}
+	if got := string(f.content()); got != "a\r\nb\r\nc\r\n" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+func TestApplyPatchSymlinkEscape(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	tool := NewApplyPatchTool(root)
+
+	for _, path := range []string{"link/new.txt", "link/sub/new.txt"} {
+		patch := "--- /dev/null\n+++ b/" + path + "\n@@ -0,0 +1 @@\n+x\n"
+		if _, err := tool.Execute(context.Background(), map[string]interface{}{"patch": patch}); err == nil || !strings.Contains(err.Error(), "through a symlink") {
+			t.Errorf("%s: err = %v, want it to leave the root through a symlink", path, err)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Errorf("the patch wrote outside the root: %v", entries)
+	}
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hkuds/ubot/internal/session"
+)
+
+// PinTool lets the LLM pin important facts to the current session so they
+// are always included in the system context.
+type PinTool struct {
+	BaseTool
+	sessions *session.Manager
+}
+
+// NewPinTool creates a new PinTool backed by the given session manager.
+func NewPinTool(sessions *session.Manager) *PinTool {
+	return &PinTool{
+		BaseTool: NewBaseTool(
+			"pin",
+			"Pin important facts or requirements to this conversation so they are always remembered, even after old messages are dropped. Use 'add' with content, 'remove' with pin_id, or 'list'.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"add", "remove", "list"},
+						"description": "The action to perform: add, remove, or list.",
+					},
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "The fact or requirement to pin. Required for 'add'.",
+					},
+					"pin_id": map[string]interface{}{
+						"type":        "integer",
+						"description": "The pin ID to remove. Required for 'remove'.",
+					},
+				},
+				"required": []string{"action"},
+			},
+		),
+		sessions: sessions,
+	}
+}
+
+// Execute runs the pin tool action against the current session.
+func (t *PinTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	action, err := GetStringParam(params, "action")
+	if err != nil {
+		return "", fmt.Errorf("pin: %w", err)
+	}
+
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.SessionKey == "" {
+		return "", errors.New("pin: no active conversation")
+	}
+	sess := t.sessions.Active(req.SessionKey)
+
+	switch action {
+	case "add":
+		content, err := GetStringParam(params, "content")
+		if err != nil || strings.TrimSpace(content) == "" {
+			return "", errors.New("pin add: 'content' is required")
+		}
+		pin := sess.AddPin(strings.TrimSpace(content))
+		if err := t.sessions.Save(sess); err != nil {
+			return "", fmt.Errorf("pin add: %w", err)
+		}
+		return fmt.Sprintf("Pinned (ID: %d).", pin.ID), nil
+	case "remove":
+		id, err := GetIntParam(params, "pin_id")
+		if err != nil {
+			return "", fmt.Errorf("pin remove: %w", err)
+		}
+		if !sess.RemovePin(id) {
+			return "", fmt.Errorf("pin remove: pin %d not found", id)
+		}
+		if err := t.sessions.Save(sess); err != nil {
+			return "", fmt.Errorf("pin remove: %w", err)
+		}
+		return fmt.Sprintf("Pin %d removed.", id), nil
+	case "list":
+		pins := sess.GetPins()
+		if len(pins) == 0 {
+			return "No pinned context.", nil
+		}
+		return session.PinnedContext(pins), nil
+	default:
+		return "", fmt.Errorf("pin: unknown action %q (use add, remove, or list)", action)
+	}
+}
@@ -0,0 +1,341 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+const (
+	// robotsAgent is the name uBot's web tools answer to in robots.txt.
+	robotsAgent = "ubot"
+	// robotsTTL is how long a site's robots.txt is kept before it is
+	// fetched again.
+	robotsTTL = time.Hour
+	// maxRobotsBytes is the most of a robots.txt file that is read.
+	maxRobotsBytes = 512 << 10
+	// maxCrawlDelay caps the Crawl-delay a robots.txt may ask for.
+	maxCrawlDelay = 30 * time.Second
+	// defaultRetryAfter is how long a site that answered 429 without a
+	// Retry-After header is left alone.
+	defaultRetryAfter = 30 * time.Second
+	// maxRetryAfter caps the pause a site may ask for.
+	maxRetryAfter = time.Hour
+)
+
+// Politeness keeps the web tools from overloading sites. Requests to a
+// host go out one at a time, at least the configured delay (or the site's
+// Crawl-delay) apart, not before a Retry-After the site sent with a 429,
+// and only for pages its robots.txt allows.
+type Politeness struct {
+	mu     sync.Mutex
+	cfg    config.WebPolitenessConfig
+	sites  map[string]*politeSite
+	client *http.Client // fetches robots.txt
+}
+
+// politeSite is the state kept for one host. Its fields other than slot
+// are only used by the request holding the slot.
+type politeSite struct {
+	slot      chan struct{} // held while a request to the host is open
+	last      time.Time     // when the last request started
+	retryAt   time.Time     // no requests before this, after a 429
+	robots    *robotsRules
+	robotsAge time.Time
+}
+
+// sharedPoliteness is used by web_fetch and browser_use, so both count
+// towards the same limits.
+var sharedPoliteness = NewPoliteness(config.DefaultConfig().Tools.Web.Politeness)
+
+// ConfigurePoliteness applies cfg to the limits shared by the web tools.
+func ConfigurePoliteness(cfg config.WebPolitenessConfig) {
+	sharedPoliteness.Configure(cfg)
+}
+
+// NewPoliteness creates a Politeness applying cfg.
+func NewPoliteness(cfg config.WebPolitenessConfig) *Politeness {
+	return &Politeness{
+		cfg:    cfg,
+		sites:  make(map[string]*politeSite),
+		client: safenet.NewClient(10 * time.Second),
+	}
+}
+
+// Configure replaces the settings; requests already waiting keep theirs.
+func (p *Politeness) Configure(cfg config.WebPolitenessConfig) {
+	p.mu.Lock()
+	p.cfg = cfg
+	p.mu.Unlock()
+}
+
+// Do sends req with client once the host's limits allow it. The host stays
+// busy for other requests until the response body is closed. Pages
+// disallowed by robots.txt, a wait longer than the configured maximum and
+// 429 responses are returned as errors telling the model what to do.
+func (p *Politeness) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	site, release, err := p.wait(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		wait := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		site.retryAt = time.Now().Add(wait)
+		resp.Body.Close()
+		release()
+		return nil, fmt.Errorf("%s is rate limiting requests (HTTP 429); try again in %s", req.URL.Host, wait.Round(time.Second))
+	}
+	resp.Body = &politeBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// Wait blocks until the host's limits allow req to be sent some other way,
+// such as by a browser, and returns a function that frees the host again.
+func (p *Politeness) Wait(req *http.Request) (release func(), err error) {
+	_, release, err = p.wait(req)
+	return release, err
+}
+
+// wait takes the host's slot for req once robots.txt and the delays allow
+// it. On success the caller must call release when done with the host.
+func (p *Politeness) wait(req *http.Request) (*politeSite, func(), error) {
+	ctx := req.Context()
+	host := strings.ToLower(req.URL.Host)
+
+	p.mu.Lock()
+	cfg := p.cfg
+	site, ok := p.sites[host]
+	if !ok {
+		site = &politeSite{slot: make(chan struct{}, 1)}
+		p.sites[host] = site
+	}
+	p.mu.Unlock()
+
+	select {
+	case site.slot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	release := func() { <-site.slot }
+
+	delay := cfg.MinDelay()
+	if cfg.RespectRobots {
+		rules := p.robots(ctx, site, req)
+		if !rules.allowed(req.URL.RequestURI()) {
+			release()
+			return nil, nil, fmt.Errorf("robots.txt of %s does not allow fetching %s; use another source", host, req.URL.Path)
+		}
+		delay = max(delay, rules.delay)
+	}
+
+	start := site.last.Add(delay)
+	if site.retryAt.After(start) {
+		start = site.retryAt
+	}
+	if wait := time.Until(start); wait > 0 {
+		if wait > cfg.MaxWaitDuration() {
+			release()
+			return nil, nil, fmt.Errorf("%s asked for fewer requests; try again in %s", host, wait.Round(time.Second))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	site.last = time.Now()
+	return site, release, nil
+}
+
+// robots returns the robots.txt rules of req's host, fetching them when
+// they are missing or old. A robots.txt that cannot be read allows
+// everything.
+func (p *Politeness) robots(ctx context.Context, site *politeSite, req *http.Request) *robotsRules {
+	if site.robots != nil && time.Since(site.robotsAge) < robotsTTL {
+		return site.robots
+	}
+
+	rules := &robotsRules{}
+	robotsURL := req.URL.Scheme + "://" + req.URL.Host + "/robots.txt"
+	if r, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil); err == nil {
+		r.Header.Set("User-Agent", req.Header.Get("User-Agent"))
+		if resp, err := p.client.Do(r); err == nil {
+			if resp.StatusCode == http.StatusOK {
+				data, _ := io.ReadAll(io.LimitReader(resp.Body, maxRobotsBytes))
+				rules = parseRobots(string(data), robotsAgent)
+			}
+			resp.Body.Close()
+		}
+	}
+	site.robots, site.robotsAge = rules, time.Now()
+	return rules
+}
+
+// politeBody frees the host for the next request when it is closed.
+type politeBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *politeBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// retryAfter reads a Retry-After header, given in seconds or as a date,
+// and returns how long to wait from now.
+func retryAfter(value string, now time.Time) time.Duration {
+	wait := defaultRetryAfter
+	if secs, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		wait = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		wait = t.Sub(now)
+	}
+	return min(max(wait, time.Second), maxRetryAfter)
+}
+
+// robotsRules are the rules of a robots.txt that apply to one agent.
+type robotsRules struct {
+	rules []robotsRule
+	delay time.Duration // Crawl-delay
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// parseRobots returns the rules of robots.txt data for agent: those of the
+// groups naming it, or else of the groups for "*".
+func parseRobots(data, agent string) *robotsRules {
+	type group struct {
+		agents []string
+		robotsRules
+	}
+	var groups []*group
+	var cur *group
+	inRules := false // a rule was seen since the last User-agent line
+
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if cur == nil || inRules {
+				cur = &group{}
+				groups = append(groups, cur)
+				inRules = false
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if cur == nil {
+				continue
+			}
+			inRules = true
+			if value != "" {
+				cur.rules = append(cur.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if cur == nil {
+				continue
+			}
+			inRules = true
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				cur.delay = min(time.Duration(secs*float64(time.Second)), maxCrawlDelay)
+			}
+		}
+	}
+
+	pick := func(match func(string) bool) *robotsRules {
+		var out *robotsRules
+		for _, g := range groups {
+			for _, a := range g.agents {
+				if match(a) {
+					if out == nil {
+						out = &robotsRules{}
+					}
+					out.rules = append(out.rules, g.rules...)
+					out.delay = max(out.delay, g.delay)
+					break
+				}
+			}
+		}
+		return out
+	}
+	if rules := pick(func(a string) bool { return a != "*" && strings.Contains(agent, a) }); rules != nil {
+		return rules
+	}
+	if rules := pick(func(a string) bool { return a == "*" }); rules != nil {
+		return rules
+	}
+	return &robotsRules{}
+}
+
+// allowed reports whether path (with its query) may be fetched. The
+// longest matching rule decides, and Allow wins a tie.
+func (r *robotsRules) allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	allow, best := true, -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			allow, best = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches path against a robots.txt pattern, in which "*"
+// stands for any characters and a final "$" anchors the end.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return len(path)-pos >= len(part) && strings.HasSuffix(path, part)
+		}
+		j := strings.Index(path[pos:], part)
+		if j < 0 {
+			return false
+		}
+		pos += j + len(part)
+	}
+	return !anchored || pos == len(path)
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := `
+# comments are ignored
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+Disallow: /*.pdf$
+
+User-agent: Googlebot
+User-agent: uBot
+Disallow: /no-bots
+Crawl-delay: 2.5
+
+User-agent: OtherBot
+Disallow: /
+`
+	ours := parseRobots(robots, robotsAgent)
+	if ours.delay != 2500*time.Millisecond {
+		t.Errorf("crawl delay = %v, want 2.5s", ours.delay)
+	}
+	everyone := parseRobots(robots, "somebot")
+
+	for _, tc := range []struct {
+		rules *robotsRules
+		path  string
+		want  bool
+	}{
+		{ours, "/no-bots/page", false},
+		{ours, "/private/secret", true}, // only the uBot group applies
+		{everyone, "/private/secret", false},
+		{everyone, "/private/public.html", true},
+		{everyone, "/docs/guide.pdf", false},
+		{everyone, "/docs/guide.pdf?download=1", true},
+		{everyone, "/robots.txt", true},
+		{everyone, "/", true},
+	} {
+		if got := tc.rules.allowed(tc.path); got != tc.want {
+			t.Errorf("allowed(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+
+	if rules := parseRobots("User-agent: *\nDisallow:\n", robotsAgent); !rules.allowed("/anything") {
+		t.Error("an empty Disallow blocked a page")
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish.html", false},
+		{"/fish*.php", "/fish/salmon.php", true},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/a*b*c", "/a-x-b-y-c-z", true},
+		{"/a*b*c", "/a-x-c-y-b", false},
+		{"/exact$", "/exact", true},
+		{"/exact$", "/exactly", false},
+	} {
+		if got := robotsMatch(tc.pattern, tc.path); got != tc.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tc.pattern, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"120":                           2 * time.Minute,
+		"Sun, 18 Oct 2026 12:00:45 GMT": 45 * time.Second,
+		"":                              defaultRetryAfter,
+		"soon":                          defaultRetryAfter,
+		"0":                             time.Second,
+		"999999":                        maxRetryAfter,
+	} {
+		if got := retryAfter(value, now); got != want {
+			t.Errorf("retryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestPolitenessDo(t *testing.T) {
+	var inFlight, most, requests atomic.Int32
+	var rateLimited atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			io.WriteString(w, "User-agent: *\nDisallow: /admin\n")
+			return
+		case "/busy":
+			if rateLimited.Load() {
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		}
+		requests.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	polite := NewPoliteness(config.WebPolitenessConfig{RespectRobots: true, MinDelayMs: 30, MaxWait: 5})
+	polite.client = srv.Client()
+	get := func(path string) error {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+path, nil)
+		resp, err := polite.Do(srv.Client(), req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		return resp.Body.Close()
+	}
+
+	// Requests to one site go one at a time, spaced out
+	started := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := get("/page"); err != nil {
+				t.Errorf("get: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if most.Load() != 1 {
+		t.Errorf("%d requests ran at once, want 1", most.Load())
+	}
+	if elapsed := time.Since(started); elapsed < 90*time.Millisecond {
+		t.Errorf("4 requests took %v, want at least 3 delays of 30ms", elapsed)
+	}
+
+	if err := get("/admin/users"); err == nil || !strings.Contains(err.Error(), "robots.txt") {
+		t.Errorf("disallowed page: err = %v", err)
+	}
+
+	// A 429 keeps the site off limits for its Retry-After
+	rateLimited.Store(true)
+	if err := get("/busy"); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("rate limited page: err = %v", err)
+	}
+	before := requests.Load()
+	if err := get("/page"); err == nil || !strings.Contains(err.Error(), "try again in 2m0s") {
+		t.Errorf("request during Retry-After: err = %v", err)
+	}
+	if requests.Load() != before {
+		t.Error("a request was sent during Retry-After")
+	}
+
+	// Without robots.txt checks the page is fetched
+	other := NewPoliteness(config.WebPolitenessConfig{})
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/admin/users", nil)
+	resp, err := other.Do(srv.Client(), req)
+	if err != nil {
+		t.Fatalf("robots.txt ignored: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,22 @@
+package tools
+
+import "context"
+
+// ProgressFunc shows a short status line, such as how much of a download
+// has arrived, to whoever is waiting for a tool call.
+type ProgressFunc func(status string)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose long-running tool calls report
+// their progress to fn.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress passes status to the ProgressFunc attached to ctx, if any.
+func ReportProgress(ctx context.Context, status string) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(status)
+	}
+}
@@ -0,0 +1,343 @@
+package tools
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// article is the main text of a page, as found by extractArticle.
+type article struct {
+	Title     string
+	Byline    string
+	Published string // YYYY-MM-DD when the date parses, else as the page gives it
+	Content   string // markdown
+}
+
+// minArticleChars is the least text extractArticle takes for an article;
+// with less, the page is extracted like any other.
+const minArticleChars = 140
+
+var (
+	// Classes and ids of page parts that are rarely the article, unless
+	// they also look like it.
+	unlikelyArticle = regexp.MustCompile(`(?i)-ad-|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|menu|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental`)
+	maybeArticle    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+
+	positiveArticle = regexp.MustCompile(`(?i)article|blog|body|content|entry|hentry|h-entry|main|page|post|story|text`)
+	negativeArticle = regexp.MustCompile(`(?i)-ad-|hidden|banner|combx|comment|com-|contact|cookie|foot|masthead|media|meta|newsletter|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|subscribe|tags|tool|widget`)
+)
+
+// extractArticle finds the main article of doc the way readability does:
+// it drops boilerplate, scores the blocks holding paragraphs of text by
+// their length, commas and links, and takes the best one with the siblings
+// that score close to it. ok is false when no block has enough text.
+func extractArticle(doc *goquery.Document) (a article, ok bool) {
+	a = articleMetadata(doc)
+
+	doc.Find("script, style, noscript, iframe, form, nav, footer, aside, svg, button, input, select, textarea, object, embed").Remove()
+	doc.Find(`[hidden], [aria-hidden="true"], [style*="display:none"], [style*="display: none"]`).Remove()
+	doc.Find("header").Each(func(_ int, s *goquery.Selection) {
+		// The site's header, not the article's
+		if s.Closest("article").Length() == 0 {
+			s.Remove()
+		}
+	})
+	doc.Find("body *").Each(func(_ int, s *goquery.Selection) {
+		switch goquery.NodeName(s) {
+		case "article", "main", "table", "tbody", "tr", "td", "th", "pre", "code":
+			return
+		}
+		match := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if unlikelyArticle.MatchString(match) && !maybeArticle.MatchString(match) {
+			s.Remove()
+		}
+	})
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*goquery.Selection
+	addScore := func(s *goquery.Selection, score float64) {
+		node := s.Get(0)
+		if _, seen := scores[node]; !seen {
+			scores[node] = initialArticleScore(s)
+			candidates = append(candidates, s)
+		}
+		scores[node] += score
+	}
+	doc.Find("p, pre, td, blockquote, div").Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "div" && s.Find("div, p, pre, table, ul, ol, blockquote, section, article, h1, h2, h3, h4, h5, h6").Length() > 0 {
+			return // only divs used as paragraphs
+		}
+		text := cleanText(s.Text())
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text)/100), 3)
+		for level, ancestor := 1, s.Parent(); level <= 3 && ancestor.Length() > 0; level, ancestor = level+1, ancestor.Parent() {
+			if goquery.NodeName(ancestor) == "html" {
+				break
+			}
+			switch level {
+			case 1:
+				addScore(ancestor, score)
+			case 2:
+				addScore(ancestor, score/2)
+			default:
+				addScore(ancestor, score/float64(level*3))
+			}
+		}
+	})
+
+	var top *goquery.Selection
+	var topScore float64
+	for _, s := range candidates {
+		node := s.Get(0)
+		scores[node] *= 1 - linkDensity(s)
+		if top == nil || scores[node] > topScore {
+			top, topScore = s, scores[node]
+		}
+	}
+	if top == nil || len(cleanText(top.Text())) < minArticleChars {
+		return a, false
+	}
+
+	// Siblings that score close to the best block, or are paragraphs of
+	// prose, belong to the article too
+	threshold := max(10, topScore*0.2)
+	container := top.Parent()
+	if goquery.NodeName(container) == "html" {
+		container = top
+	}
+	if container != top {
+		container.Contents().Each(func(_ int, s *goquery.Selection) {
+			node := s.Get(0)
+			if node == top.Get(0) || (node.Type == html.ElementNode && scores[node] >= threshold) {
+				return
+			}
+			if goquery.NodeName(s) == "p" {
+				text := cleanText(s.Text())
+				density := linkDensity(s)
+				if (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.ContainsAny(text, ".!?")) {
+					return
+				}
+			}
+			s.Remove()
+		})
+	}
+
+	// Lists of links inside the article are navigation, not text
+	container.Find("ul, ol, div, section, table").Each(func(_ int, s *goquery.Selection) {
+		if linkDensity(s) > 0.5 {
+			s.Remove()
+		}
+	})
+	if a.Title != "" {
+		container.Find("h1").Each(func(_ int, s *goquery.Selection) {
+			if cleanText(s.Text()) == a.Title {
+				s.Remove()
+			}
+		})
+	}
+
+	a.Content = htmlToMarkdown(container)
+	if len(a.Content) < minArticleChars {
+		return a, false
+	}
+	return a, true
+}
+
+// initialArticleScore favours the elements that usually hold articles and
+// classes that say so.
+func initialArticleScore(s *goquery.Selection) float64 {
+	var score float64
+	switch goquery.NodeName(s) {
+	case "article":
+		score = 10
+	case "div", "main", "section":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+	for _, attr := range []string{"class", "id"} {
+		value := s.AttrOr(attr, "")
+		if value == "" {
+			continue
+		}
+		if negativeArticle.MatchString(value) {
+			score -= 25
+		}
+		if positiveArticle.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity is the share of s's text that is link text.
+func linkDensity(s *goquery.Selection) float64 {
+	textLen := len(cleanText(s.Text()))
+	if textLen == 0 {
+		return 0
+	}
+	var linkLen int
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLen += len(cleanText(a.Text()))
+	})
+	return float64(linkLen) / float64(textLen)
+}
+
+// articleMetadata reads the title, author and publication date from the
+// page's meta tags, schema.org markup and JSON-LD.
+func articleMetadata(doc *goquery.Document) article {
+	var a article
+	meta := func(selectors ...string) string {
+		for _, sel := range selectors {
+			if v := cleanText(doc.Find(sel).First().AttrOr("content", "")); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	ld := jsonLDArticle(doc)
+
+	a.Title = meta(`meta[property="og:title"]`, `meta[name="twitter:title"]`)
+	if a.Title == "" {
+		a.Title = ld.Headline
+	}
+	if a.Title == "" {
+		a.Title = cleanText(doc.Find("title").First().Text())
+	}
+
+	a.Byline = meta(`meta[name="author"]`, `meta[name="byl"]`, `meta[name="parsely-author"]`)
+	if a.Byline == "" {
+		a.Byline = ld.author()
+	}
+	if a.Byline == "" {
+		doc.Find(`[itemprop~="author"], [rel="author"], .byline, .author`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			text := s.AttrOr("content", "")
+			if name := s.Find(`[itemprop="name"]`); text == "" && name.Length() > 0 {
+				text = name.First().Text()
+			}
+			if text == "" {
+				text = s.Text()
+			}
+			text = strings.TrimPrefix(cleanText(text), "By ")
+			if text != "" && len(text) < 100 {
+				a.Byline = text
+				return false
+			}
+			return true
+		})
+	}
+
+	published := meta(`meta[property="article:published_time"]`, `meta[itemprop="datePublished"]`,
+		`meta[name="date"]`, `meta[name="pubdate"]`, `meta[name="publishdate"]`, `meta[name="publish-date"]`,
+		`meta[name="DC.date.issued"]`, `meta[name="dc.date"]`, `meta[name="sailthru.date"]`)
+	if published == "" {
+		published = ld.DatePublished
+	}
+	if published == "" {
+		published = doc.Find(`[itemprop="datePublished"]`).First().AttrOr("datetime", "")
+	}
+	if published == "" {
+		times := doc.Find("article time[datetime]")
+		if times.Length() == 0 {
+			times = doc.Find("time[datetime]")
+		}
+		published = times.First().AttrOr("datetime", "")
+	}
+	a.Published = normalizeDate(published)
+	return a
+}
+
+// ldArticle holds the fields of a JSON-LD article extractArticle uses.
+type ldArticle struct {
+	Type          interface{}     `json:"@type"`
+	Headline      string          `json:"headline"`
+	DatePublished string          `json:"datePublished"`
+	Author        json.RawMessage `json:"author"`
+	Graph         []ldArticle     `json:"@graph"`
+}
+
+// author returns the names of the article's authors.
+func (ld ldArticle) author() string {
+	type person struct {
+		Name string `json:"name"`
+	}
+	var one person
+	if json.Unmarshal(ld.Author, &one) == nil && one.Name != "" {
+		return cleanText(one.Name)
+	}
+	var many []person
+	if json.Unmarshal(ld.Author, &many) == nil {
+		var names []string
+		for _, p := range many {
+			if p.Name != "" {
+				names = append(names, cleanText(p.Name))
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	var name string
+	json.Unmarshal(ld.Author, &name)
+	return cleanText(name)
+}
+
+// isArticle reports whether the JSON-LD object is an article of some kind.
+func (ld ldArticle) isArticle() bool {
+	types, ok := ld.Type.([]interface{})
+	if !ok {
+		types = []interface{}{ld.Type}
+	}
+	for _, t := range types {
+		if s, _ := t.(string); strings.HasSuffix(s, "Article") || s == "BlogPosting" || s == "Report" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonLDArticle returns the first article in the page's JSON-LD.
+func jsonLDArticle(doc *goquery.Document) ldArticle {
+	var found ldArticle
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		data := []byte(strings.TrimSpace(s.Text()))
+		var items []ldArticle
+		if len(data) > 0 && data[0] == '[' {
+			json.Unmarshal(data, &items)
+		} else {
+			var item ldArticle
+			if json.Unmarshal(data, &item) == nil {
+				items = append(append(items, item), item.Graph...)
+			}
+		}
+		for _, item := range items {
+			if item.isArticle() {
+				found = item
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// normalizeDate turns the common forms of a publication date into
+// YYYY-MM-DD, leaving other forms as they are.
+func normalizeDate(s string) string {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02", time.RFC1123, time.RFC1123Z} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return s
+}
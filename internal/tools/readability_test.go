@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const newsPage = `<html><head>
+<title>Rivers rise after storm | Daily Example</title>
+<meta property="og:title" content="Rivers rise after storm">
+<meta property="article:published_time" content="2026-03-14T08:30:00+01:00">
+<script type="application/ld+json">{"@context": "https://schema.org", "@type": "NewsArticle", "author": [{"@type": "Person", "name": "Ann Lee"}, {"@type": "Person", "name": "Bo Chen"}]}</script>
+</head><body>
+<header class="site-header"><a href="/">Daily Example</a> <a href="/world">World</a> <a href="/sport">Sport</a></header>
+<div class="cookie-banner">We use cookies to improve your experience, please accept them all.</div>
+<div class="layout">
+  <div class="sidebar"><p>Most read: a very long list of other stories that are popular today, click them all.</p></div>
+  <div class="story-body">
+    <h1>Rivers rise after storm</h1>
+    <p>Rivers across the region rose sharply on Saturday, after a storm brought two days of heavy rain, and several towns were evacuated.</p>
+    <p>The river at Millbrook reached its highest level in forty years, officials said, and crews worked through the night to shore up defences.</p>
+    <ul class="share"><li><a href="/s/fb">Share on Facebook</a></li><li><a href="/s/x">Share on X</a></li></ul>
+    <p>More rain is expected on Monday, but forecasters say it should be lighter, giving the ground time to recover.</p>
+  </div>
+  <div class="related"><a href="/a">Storm hits coast</a> <a href="/b">What to do in a flood, a guide for residents</a></div>
+</div>
+<footer>Copyright Daily Example. All rights reserved, everywhere and always.</footer>
+</body></html>`
+
+func TestExtractArticle(t *testing.T) {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(newsPage))
+	a, ok := extractArticle(doc)
+	if !ok {
+		t.Fatal("no article found")
+	}
+	if a.Title != "Rivers rise after storm" || a.Byline != "Ann Lee, Bo Chen" || a.Published != "2026-03-14" {
+		t.Errorf("metadata = %q, %q, %q", a.Title, a.Byline, a.Published)
+	}
+	for _, want := range []string{"Rivers across the region rose sharply", "highest level in forty years", "More rain is expected on Monday"} {
+		if !strings.Contains(a.Content, want) {
+			t.Errorf("article lacks %q:\n%s", want, a.Content)
+		}
+	}
+	for _, boilerplate := range []string{"cookies", "Most read", "Share on", "Storm hits coast", "Copyright", "World"} {
+		if strings.Contains(a.Content, boilerplate) {
+			t.Errorf("article has %q:\n%s", boilerplate, a.Content)
+		}
+	}
+
+	// A page of links and buttons has no article
+	doc, _ = goquery.NewDocumentFromReader(strings.NewReader(`<html><body><div><a href="/1">One</a> <a href="/2">Two</a></div><p>Sign in</p></body></html>`))
+	if _, ok := extractArticle(doc); ok {
+		t.Error("found an article on a page without one")
+	}
+}
+
+func TestArticleMetadata(t *testing.T) {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(`<html><head><title>Notes</title></head><body>
+<article><span class="byline">By Cleo Park</span><time datetime="2025-11-02">2 November</time></article></body></html>`))
+	a := articleMetadata(doc)
+	if a.Title != "Notes" || a.Byline != "Cleo Park" || a.Published != "2025-11-02" {
+		t.Errorf("metadata = %+v", a)
+	}
+	if got := normalizeDate("Sun, 02 Nov 2025 10:00:00 GMT"); got != "2025-11-02" {
+		t.Errorf("normalizeDate = %s", got)
+	}
+	if got := normalizeDate("last Tuesday"); got != "last Tuesday" {
+		t.Errorf("normalizeDate = %s", got)
+	}
+}
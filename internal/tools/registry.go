@@ -75,6 +75,24 @@ func (r *ToolRegistry) Register(t Tool) error {
 	return nil
 }
 
+// Replace adds a tool to the registry, replacing any tool with the same
+// name, e.g. to apply new settings while the registry is in use.
+func (r *ToolRegistry) Replace(t Tool) error {
+	if t == nil {
+		return fmt.Errorf("cannot register nil tool")
+	}
+
+	name := t.Name()
+	if name == "" {
+		return fmt.Errorf("cannot register tool with empty name")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[name] = t
+	return nil
+}
+
 // MustRegister adds a tool to the registry, panicking on error.
 // This is useful for registering tools during initialization.
 func (r *ToolRegistry) MustRegister(t Tool) {
@@ -0,0 +1,302 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+const (
+	// renderTimeout bounds loading one page, including waiting for it to
+	// settle.
+	renderTimeout = 30 * time.Second
+	// renderIdle is how long a page must make no requests to count as
+	// loaded.
+	renderIdle = 500 * time.Millisecond
+	// renderSettleTimeout is the longest wait for network idle after the
+	// load event; pages that keep polling are read as they are then.
+	renderSettleTimeout = 10 * time.Second
+	// cdpOrigin is the Origin the CDP websocket is opened with; Chrome is
+	// started allowing it.
+	cdpOrigin = "http://127.0.0.1"
+)
+
+// ChromeRenderer renders pages for web_fetch in a headless Chrome of its
+// own, started on first use and closed when idle like browser_use's.
+type ChromeRenderer struct {
+	browser *BrowserTool
+}
+
+// NewChromeRenderer creates a ChromeRenderer launching Chrome with cfg.
+func NewChromeRenderer(cfg config.BrowserConfig) *ChromeRenderer {
+	return &ChromeRenderer{browser: NewBrowserTool(cfg)}
+}
+
+// Render opens url in a new tab, waits for the network to go idle and
+// returns the rendered DOM.
+func (r *ChromeRenderer) Render(ctx context.Context, url string) (string, string, error) {
+	bi, err := r.browser.ensureBrowser("")
+	if err != nil {
+		return "", "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, renderTimeout)
+	defer cancel()
+
+	target, err := newCDPTarget(ctx, bi.cdpURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer closeCDPTarget(bi.cdpURL, target.ID)
+
+	return renderTarget(ctx, target.WebSocketDebuggerURL, url)
+}
+
+// Close shuts down the renderer's Chrome.
+func (r *ChromeRenderer) Close() {
+	r.browser.Close()
+}
+
+// newCDPTarget opens a blank tab.
+func newCDPTarget(ctx context.Context, cdpURL string) (*cdpTargetInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, cdpURL+"/json/new?about:blank", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a tab: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var target cdpTargetInfo
+	if err := json.NewDecoder(resp.Body).Decode(&target); err != nil {
+		return nil, fmt.Errorf("failed to parse new tab: %w", err)
+	}
+	if target.WebSocketDebuggerURL == "" {
+		return nil, errors.New("new tab has no debugger URL")
+	}
+	return &target, nil
+}
+
+// closeCDPTarget closes a tab opened by newCDPTarget.
+func closeCDPTarget(cdpURL, id string) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	if resp, err := client.Get(cdpURL + "/json/close/" + id); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// renderTarget drives the tab at wsURL: it navigates to pageURL, waits
+// until the page has loaded and made no requests for renderIdle, then
+// reads the DOM.
+func renderTarget(ctx context.Context, wsURL, pageURL string) (string, string, error) {
+	conn, err := dialCDP(ctx, wsURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer conn.close()
+
+	for _, method := range []string{"Page.enable", "Network.enable"} {
+		if _, err := conn.call(ctx, method, nil); err != nil {
+			return "", "", err
+		}
+	}
+	res, err := conn.call(ctx, "Page.navigate", map[string]interface{}{"url": pageURL})
+	if err != nil {
+		return "", "", err
+	}
+	var nav struct {
+		ErrorText string `json:"errorText"`
+	}
+	json.Unmarshal(res, &nav)
+	if nav.ErrorText != "" {
+		return "", "", fmt.Errorf("navigation failed: %s", nav.ErrorText)
+	}
+
+	if err := conn.waitIdle(ctx); err != nil {
+		return "", "", err
+	}
+
+	res, err = conn.call(ctx, "Runtime.evaluate", map[string]interface{}{
+		"expression":    "JSON.stringify([document.documentElement.outerHTML, location.href])",
+		"returnByValue": true,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	var eval struct {
+		Result struct {
+			Value string `json:"value"`
+		} `json:"result"`
+	}
+	var page [2]string
+	if err := json.Unmarshal(res, &eval); err != nil || json.Unmarshal([]byte(eval.Result.Value), &page) != nil {
+		return "", "", errors.New("failed to read the rendered page")
+	}
+	return page[0], page[1], nil
+}
+
+// cdpConn is a connection to one tab over the Chrome DevTools Protocol.
+// Replies are matched to calls by id; events go to the events channel.
+type cdpConn struct {
+	ws      *websocket.Conn
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan cdpMessage
+	events  chan cdpMessage
+	done    chan struct{}
+	err     error // why the connection ended, once done is closed
+}
+
+type cdpMessage struct {
+	ID     int             `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func dialCDP(ctx context.Context, wsURL string) (*cdpConn, error) {
+	cfg, err := websocket.NewConfig(wsURL, cdpOrigin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid debugger URL: %w", err)
+	}
+	ws, err := cfg.DialContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Chrome: %w", err)
+	}
+	// Pages may produce many events; the payload limit only protects
+	// against a broken peer.
+	ws.MaxPayloadBytes = 64 << 20
+
+	c := &cdpConn{
+		ws:      ws,
+		pending: make(map[int]chan cdpMessage),
+		events:  make(chan cdpMessage, 1024),
+		done:    make(chan struct{}),
+	}
+	go c.read()
+	return c, nil
+}
+
+// read delivers messages until the connection fails or is closed.
+func (c *cdpConn) read() {
+	defer close(c.done)
+	for {
+		var msg cdpMessage
+		if err := websocket.JSON.Receive(c.ws, &msg); err != nil {
+			c.err = err
+			return
+		}
+		if msg.ID == 0 {
+			select {
+			case c.events <- msg:
+			default:
+				// Nobody is waiting for events; waitIdle copes with
+				// missed ones by giving up after renderSettleTimeout.
+			}
+			continue
+		}
+		c.mu.Lock()
+		ch := c.pending[msg.ID]
+		delete(c.pending, msg.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- msg
+		}
+	}
+}
+
+// call sends a command and waits for its result.
+func (c *cdpConn) call(ctx context.Context, method string, params map[string]interface{}) (json.RawMessage, error) {
+	ch := make(chan cdpMessage, 1)
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	if err := websocket.JSON.Send(c.ws, map[string]interface{}{"id": id, "method": method, "params": params}); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return nil, fmt.Errorf("%s: %s", method, msg.Error.Message)
+		}
+		return msg.Result, nil
+	case <-c.done:
+		return nil, fmt.Errorf("%s: connection closed: %v", method, c.err)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// waitIdle returns once the page has fired its load event and then made
+// no requests for renderIdle, or renderSettleTimeout after the load event.
+func (c *cdpConn) waitIdle(ctx context.Context) error {
+	inFlight := make(map[string]bool)
+	loaded := false
+	var settle <-chan time.Time
+	idle := time.NewTimer(renderIdle)
+	defer idle.Stop()
+
+	for {
+		select {
+		case ev := <-c.events:
+			var p struct {
+				RequestID string `json:"requestId"`
+			}
+			json.Unmarshal(ev.Params, &p)
+			switch ev.Method {
+			case "Page.loadEventFired":
+				loaded = true
+				settle = time.After(renderSettleTimeout)
+			case "Network.requestWillBeSent":
+				inFlight[p.RequestID] = true
+			case "Network.loadingFinished", "Network.loadingFailed":
+				delete(inFlight, p.RequestID)
+			default:
+				continue
+			}
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(renderIdle)
+		case <-idle.C:
+			if loaded && len(inFlight) == 0 {
+				return nil
+			}
+			idle.Reset(renderIdle)
+		case <-settle:
+			return nil
+		case <-c.done:
+			return fmt.Errorf("connection to Chrome closed: %v", c.err)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *cdpConn) close() {
+	c.ws.Close()
+	<-c.done
+}
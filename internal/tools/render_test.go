@@ -0,0 +1,86 @@
+//go:build !lite && !nobrowser
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// fakeTab answers CDP commands like a tab whose page loads a script that
+// fetches its content after the load event.
+func fakeTab(t *testing.T) *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		send := func(v map[string]interface{}) {
+			if err := websocket.JSON.Send(ws, v); err != nil {
+				t.Errorf("send: %v", err)
+			}
+		}
+		event := func(method, requestID string) {
+			send(map[string]interface{}{"method": method, "params": map[string]string{"requestId": requestID}})
+		}
+		var rendered atomic.Bool
+		pageURL := ""
+		for {
+			var cmd struct {
+				ID     int                    `json:"id"`
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			}
+			if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+				return
+			}
+			switch cmd.Method {
+			case "Page.navigate":
+				pageURL, _ = cmd.Params["url"].(string)
+				send(map[string]interface{}{"id": cmd.ID, "result": map[string]string{"frameId": "f1"}})
+				event("Network.requestWillBeSent", "doc")
+				event("Network.loadingFinished", "doc")
+				event("Page.loadEventFired", "")
+				event("Network.requestWillBeSent", "api")
+				go func() {
+					time.Sleep(200 * time.Millisecond)
+					rendered.Store(true)
+					event("Network.loadingFinished", "api")
+				}()
+			case "Runtime.evaluate":
+				body := "<body></body>"
+				if rendered.Load() {
+					body = "<body><article>Loaded by script</article></body>"
+				}
+				page, _ := json.Marshal([]string{"<html>" + body + "</html>", pageURL + "#app"})
+				send(map[string]interface{}{"id": cmd.ID, "result": map[string]interface{}{
+					"result": map[string]string{"type": "string", "value": string(page)},
+				}})
+			default:
+				send(map[string]interface{}{"id": cmd.ID, "result": map[string]string{}})
+			}
+		}
+	}))
+}
+
+func TestRenderTarget(t *testing.T) {
+	srv := fakeTab(t)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	html, finalURL, err := renderTarget(ctx, wsURL, "https://app.example/")
+	if err != nil {
+		t.Fatalf("renderTarget: %v", err)
+	}
+	if finalURL != "https://app.example/#app" {
+		t.Errorf("final URL = %q", finalURL)
+	}
+	if !strings.Contains(html, "Loaded by script") {
+		t.Errorf("page read before the network went idle: %s", html)
+	}
+}
@@ -0,0 +1,26 @@
+package tools
+
+import "context"
+
+// RequestInfo identifies the conversation a tool call belongs to. The agent
+// attaches it to the context passed to Execute so tools can act on the
+// originating session without shared mutable state.
+type RequestInfo struct {
+	Channel    string // e.g. "telegram", "cli"
+	ChatID     string
+	SenderID   string
+	SessionKey string // channel:chatId
+}
+
+type requestInfoKey struct{}
+
+// WithRequest returns a context carrying info.
+func WithRequest(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestFromContext returns the RequestInfo attached to ctx, if any.
+func RequestFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
@@ -0,0 +1,278 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+// SearchResult is one web page found by a SearchProvider.
+type SearchResult struct {
+	Title       string
+	URL         string
+	Description string
+}
+
+// SearchProvider runs the queries of the web_search tool against a search
+// engine.
+type SearchProvider interface {
+	// Name returns the engine's name as shown to the model, e.g. "Brave Search".
+	Name() string
+	// Search returns up to count results for query.
+	Search(ctx context.Context, query string, count int) ([]SearchResult, error)
+}
+
+// NewSearchProvider creates the search provider selected in cfg. It
+// returns nil when search is turned off.
+func NewSearchProvider(cfg config.WebSearchConfig) (SearchProvider, error) {
+	client := safenet.NewClient(30 * time.Second)
+	switch name := cfg.ProviderName(); name {
+	case config.SearchBrave:
+		return &braveSearch{apiKey: cfg.APIKey, endpoint: braveEndpoint, client: client}, nil
+	case config.SearchDuckDuckGo:
+		return &duckDuckGoSearch{endpoint: duckDuckGoEndpoint, client: client}, nil
+	case config.SearchSearxNG:
+		if cfg.URL == "" {
+			return nil, errors.New("searxng needs the URL of an instance (tools.web.search.url)")
+		}
+		// Instances are often self-hosted on the local network
+		client = safenet.NewClient(30*time.Second, safenet.URLHost(cfg.URL))
+		return &searxngSearch{baseURL: strings.TrimSuffix(cfg.URL, "/"), client: client}, nil
+	case config.SearchGoogle:
+		return &googleSearch{apiKey: cfg.APIKey, engineID: cfg.EngineID, endpoint: googleEndpoint, client: client}, nil
+	case config.SearchNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown search provider %q", name)
+	}
+}
+
+const (
+	braveEndpoint      = "https://api.search.brave.com/res/v1/web/search"
+	duckDuckGoEndpoint = "https://html.duckduckgo.com/html/"
+	googleEndpoint     = "https://www.googleapis.com/customsearch/v1"
+)
+
+// braveSearch uses the Brave Search API.
+type braveSearch struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+func (b *braveSearch) Name() string { return "Brave Search" }
+
+func (b *braveSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	if b.apiKey == "" {
+		return nil, errors.New("Brave Search API key not configured (set tools.web.search.apiKey, or choose another provider)")
+	}
+	u := b.endpoint + "?" + url.Values{"q": {query}, "count": {strconv.Itoa(count)}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+
+	var braveResp BraveSearchResponse
+	if err := getJSON(b.client, req, &braveResp); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(braveResp.Web.Results))
+	for _, r := range braveResp.Web.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Description: r.Description})
+	}
+	return results, nil
+}
+
+// duckDuckGoSearch reads DuckDuckGo's HTML results page, which needs no
+// API key.
+type duckDuckGoSearch struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (d *duckDuckGoSearch) Name() string { return "DuckDuckGo" }
+
+func (d *duckDuckGoSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	form := url.Values{"q": {query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; uBot/1.0)")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DuckDuckGo returned status %d", resp.StatusCode)
+	}
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	var results []SearchResult
+	doc.Find(".result").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if s.HasClass("result--ad") {
+			return true
+		}
+		link := s.Find("a.result__a").First()
+		href, _ := link.Attr("href")
+		if href = duckDuckGoTarget(href); href == "" {
+			return true
+		}
+		results = append(results, SearchResult{
+			Title:       cleanText(link.Text()),
+			URL:         href,
+			Description: cleanText(s.Find(".result__snippet").First().Text()),
+		})
+		return len(results) < count
+	})
+	return results, nil
+}
+
+// duckDuckGoTarget returns the page a DuckDuckGo result links to; links
+// usually go through a redirect such as //duckduckgo.com/l/?uddg=<url>.
+func duckDuckGoTarget(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		return href
+	}
+	return ""
+}
+
+// searxngSearch queries a SearxNG instance, which must have the JSON
+// format enabled (search.formats in its settings.yml).
+type searxngSearch struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (s *searxngSearch) Name() string { return "SearxNG" }
+
+func (s *searxngSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	u := s.baseURL + "/search?" + url.Values{"q": {query}, "format": {"json"}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var searxResp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := getJSON(s.client, req, &searxResp); err != nil {
+		if strings.Contains(err.Error(), "status 403") {
+			return nil, fmt.Errorf("%w (enable the json format in the instance's settings.yml)", err)
+		}
+		return nil, err
+	}
+	results := make([]SearchResult, 0, min(count, len(searxResp.Results)))
+	for _, r := range searxResp.Results {
+		if len(results) == count {
+			break
+		}
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Description: r.Content})
+	}
+	return results, nil
+}
+
+// googleSearch uses the Google Custom Search JSON API with a Programmable
+// Search Engine.
+type googleSearch struct {
+	apiKey   string
+	engineID string
+	endpoint string
+	client   *http.Client
+}
+
+func (g *googleSearch) Name() string { return "Google" }
+
+func (g *googleSearch) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	if g.apiKey == "" || g.engineID == "" {
+		return nil, errors.New("Google Custom Search needs tools.web.search.apiKey and tools.web.search.engineId")
+	}
+	params := url.Values{"key": {g.apiKey}, "cx": {g.engineID}, "q": {query}, "num": {strconv.Itoa(count)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var googleResp struct {
+		Items []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"items"`
+	}
+	if err := getJSON(g.client, req, &googleResp); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(googleResp.Items))
+	for _, r := range googleResp.Items {
+		results = append(results, SearchResult{Title: r.Title, URL: r.Link, Description: r.Snippet})
+	}
+	return results, nil
+}
+
+// getJSON sends req and decodes the JSON response into v.
+func getJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", redactQuery(err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, truncateText(string(body), 500))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// redactQuery drops the query from the URL in a request error, which may
+// hold an API key.
+func redactQuery(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, perr := url.Parse(urlErr.URL); perr == nil {
+			u.RawQuery = ""
+			return &url.Error{Op: urlErr.Op, URL: u.String(), Err: urlErr.Err}
+		}
+	}
+	return err
+}
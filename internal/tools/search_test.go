@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+const duckDuckGoPage = `<html><body>
+<div class="result results_links result--ad">
+  <a class="result__a" href="https://ads.example/buy">Buy now</a>
+</div>
+<div class="result results_links">
+  <h2><a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F&amp;rut=abc">The Go
+    Programming Language</a></h2>
+  <a class="result__snippet" href="#">Documentation for <b>Go</b>.</a>
+</div>
+<div class="result results_links">
+  <a class="result__a" href="https://pkg.go.dev/">Go Packages</a>
+</div>
+</body></html>`
+
+func TestSearchProviders(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r
+		switch r.URL.Path {
+		case "/brave":
+			w.Write([]byte(`{"web":{"results":[{"title":"Go","url":"https://go.dev/","description":"Build fast"}]}}`))
+		case "/ddg":
+			w.Write([]byte(duckDuckGoPage))
+		case "/searx/search":
+			w.Write([]byte(`{"results":[{"title":"Go","url":"https://go.dev/","content":"Build fast"},{"title":"More","url":"https://more.example/"}]}`))
+		case "/google":
+			w.Write([]byte(`{"items":[{"title":"Go","link":"https://go.dev/","snippet":"Build fast"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := srv.Client()
+
+	for _, tc := range []struct {
+		provider SearchProvider
+		check    func(*http.Request) bool
+		want     []SearchResult
+	}{
+		{
+			&braveSearch{apiKey: "bsa", endpoint: srv.URL + "/brave", client: client},
+			func(r *http.Request) bool {
+				return r.Header.Get("X-Subscription-Token") == "bsa" && r.Form.Get("count") == "1"
+			},
+			[]SearchResult{{Title: "Go", URL: "https://go.dev/", Description: "Build fast"}},
+		},
+		{
+			&duckDuckGoSearch{endpoint: srv.URL + "/ddg", client: client},
+			func(r *http.Request) bool { return r.Method == http.MethodPost && r.Form.Get("q") == "golang" },
+			[]SearchResult{{Title: "The Go Programming Language", URL: "https://go.dev/doc/", Description: "Documentation for Go."}},
+		},
+		{
+			&searxngSearch{baseURL: srv.URL + "/searx", client: client},
+			func(r *http.Request) bool { return r.Form.Get("format") == "json" },
+			[]SearchResult{{Title: "Go", URL: "https://go.dev/", Description: "Build fast"}},
+		},
+		{
+			&googleSearch{apiKey: "gk", engineID: "cx1", endpoint: srv.URL + "/google", client: client},
+			func(r *http.Request) bool {
+				return r.Form.Get("key") == "gk" && r.Form.Get("cx") == "cx1" && r.Form.Get("num") == "1"
+			},
+			[]SearchResult{{Title: "Go", URL: "https://go.dev/", Description: "Build fast"}},
+		},
+	} {
+		t.Run(tc.provider.Name(), func(t *testing.T) {
+			results, err := tc.provider.Search(context.Background(), "golang", 1)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if !tc.check(got) {
+				t.Errorf("unexpected request %s %s", got.Method, got.URL)
+			}
+			if len(results) != len(tc.want) || results[0] != tc.want[0] {
+				t.Errorf("results = %+v, want %+v", results, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewSearchProvider(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  config.WebSearchConfig
+		want string // provider name, "" for none
+	}{
+		{config.WebSearchConfig{}, "DuckDuckGo"},
+		{config.WebSearchConfig{APIKey: "bsa"}, "Brave Search"},
+		{config.WebSearchConfig{Provider: config.SearchSearxNG, URL: "https://searx.example/"}, "SearxNG"},
+		{config.WebSearchConfig{Provider: config.SearchGoogle, APIKey: "k", EngineID: "cx"}, "Google"},
+		{config.WebSearchConfig{Provider: config.SearchNone, APIKey: "bsa"}, ""},
+	} {
+		p, err := NewSearchProvider(tc.cfg)
+		if err != nil {
+			t.Errorf("NewSearchProvider(%+v): %v", tc.cfg, err)
+			continue
+		}
+		name := ""
+		if p != nil {
+			name = p.Name()
+		}
+		if name != tc.want {
+			t.Errorf("NewSearchProvider(%+v) = %q, want %q", tc.cfg, name, tc.want)
+		}
+	}
+
+	if _, err := NewSearchProvider(config.WebSearchConfig{Provider: "bing"}); err == nil {
+		t.Error("unknown provider accepted")
+	}
+}
+
+func TestWebSearchToolReportsProvider(t *testing.T) {
+	tool := NewWebSearchTool(&braveSearch{client: http.DefaultClient}, 5)
+	if !strings.Contains(tool.Description(), "Brave Search") {
+		t.Errorf("description = %q", tool.Description())
+	}
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"query": "go"})
+	if err == nil || !strings.Contains(err.Error(), "API key not configured") {
+		t.Errorf("err = %v, want a missing key error", err)
+	}
+}
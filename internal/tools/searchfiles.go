@@ -0,0 +1,404 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hkuds/ubot/internal/codeindex"
+)
+
+const (
+	// defaultSearchResults is the default number of matching lines returned
+	// by search_files.
+	defaultSearchResults = 100
+	// maxSearchResults caps the max_results parameter.
+	maxSearchResults = 1000
+	// maxSearchContext caps the context parameter.
+	maxSearchContext = 10
+	// maxSearchFiles caps how many files one search reads, so a search
+	// started at / or ~ ends.
+	maxSearchFiles = 50000
+	// maxSearchFileSize skips files too large to be source or text.
+	maxSearchFileSize = 4 << 20
+	// maxSearchLineLength shortens long lines, e.g. in minified files.
+	maxSearchLineLength = 300
+	// binarySniffSize is how much of a file is checked for NUL bytes.
+	binarySniffSize = 8000
+)
+
+// SearchFilesTool searches file contents below a directory for a regular
+// expression or literal text, like ripgrep but without needing it
+// installed. Dependency, build and hidden directories, binary files and
+// sensitive files such as .env are skipped.
+type SearchFilesTool struct {
+	BaseTool
+	root string
+}
+
+// NewSearchFilesTool creates a new SearchFilesTool searching root unless a
+// call names another path. Relative paths are taken from root.
+func NewSearchFilesTool(root string) *SearchFilesTool {
+	return &SearchFilesTool{
+		BaseTool: NewBaseTool(
+			"search_files",
+			fmt.Sprintf("Search the contents of files for a regular expression (Go RE2 syntax) or literal text, recursively below a directory (default %s). Returns matching lines grouped by file with line numbers. Skips .git, node_modules, vendor, build output, hidden and binary files. Use read_file to see more of a file.", root),
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "Regular expression to search for, e.g. 'func New\\w+' (or plain text with literal)",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Directory or file to search, relative to the default directory or absolute. Supports ~ for home directory.",
+					},
+					"literal": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Treat pattern as plain text instead of a regular expression",
+					},
+					"ignore_case": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Match regardless of upper and lower case",
+					},
+					"globs": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only search files matching these globs, e.g. ['*.go'] or ['src/**/*.ts']; a leading '!' excludes, e.g. ['!*_test.go']. Globs without '/' match the file name.",
+					},
+					"context": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Lines to show before and after each match (default 0, at most %d)", maxSearchContext),
+					},
+					"max_results": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Maximum number of matching lines (default %d, at most %d)", defaultSearchResults, maxSearchResults),
+					},
+				},
+				"required": []string{"pattern"},
+			},
+		),
+		root: root,
+	}
+}
+
+// fileMatches holds the matching lines of one file.
+type fileMatches struct {
+	lines   [][]byte
+	matches []int // 0-based indexes into lines
+}
+
+// Execute runs the search.
+func (t *SearchFilesTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	pattern, err := GetStringParam(params, "pattern")
+	if err != nil || pattern == "" {
+		return "", fmt.Errorf("search_files: pattern is required")
+	}
+	if GetBoolParamOr(params, "literal", false) {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if GetBoolParamOr(params, "ignore_case", false) {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("search_files: invalid pattern (set literal to search for plain text): %w", err)
+	}
+
+	var globs []string
+	if raw, err := GetSliceParam(params, "globs"); err == nil {
+		for _, g := range raw {
+			if s, ok := g.(string); ok && s != "" {
+				globs = append(globs, s)
+			}
+		}
+	}
+	for _, g := range globs {
+		if _, err := path.Match(strings.TrimPrefix(g, "!"), ""); err != nil {
+			return "", fmt.Errorf("search_files: invalid glob %q: %w", g, err)
+		}
+	}
+	contextLines := min(max(GetIntParamOr(params, "context", 0), 0), maxSearchContext)
+	limit := GetIntParamOr(params, "max_results", defaultSearchResults)
+	if limit <= 0 {
+		limit = defaultSearchResults
+	}
+	limit = min(limit, maxSearchResults)
+
+	dir, err := t.resolve(GetStringParamOr(params, "path", ""))
+	if err != nil {
+		return "", fmt.Errorf("search_files: %w", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("search_files: path not found: %s", dir)
+		}
+		return "", fmt.Errorf("search_files: cannot access path %s: %w", dir, err)
+	}
+
+	files, truncatedWalk, err := searchCandidates(ctx, dir, info, globs)
+	if err != nil {
+		return "", fmt.Errorf("search_files: %w", err)
+	}
+	results, err := searchAll(ctx, files, re, limit)
+	if err != nil {
+		return "", fmt.Errorf("search_files: %w", err)
+	}
+
+	base := dir
+	if !info.IsDir() {
+		base = filepath.Dir(dir)
+	}
+	var sb strings.Builder
+	total, matchedFiles := 0, 0
+	for i, fm := range results {
+		if fm == nil || len(fm.matches) == 0 || total >= limit {
+			continue
+		}
+		if len(fm.matches) > limit-total {
+			fm.matches = fm.matches[:limit-total]
+		}
+		total += len(fm.matches)
+		matchedFiles++
+		rel, err := filepath.Rel(base, files[i])
+		if err != nil {
+			rel = files[i]
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(filepath.ToSlash(rel) + "\n")
+		writeMatches(&sb, fm, contextLines)
+	}
+
+	if total == 0 {
+		return fmt.Sprintf("No matches for %q in %s (%d files searched).", GetStringParamOr(params, "pattern", ""), dir, len(files)), nil
+	}
+	sb.WriteString(fmt.Sprintf("\n%d matching lines in %d files under %s", total, matchedFiles, base))
+	if total >= limit {
+		sb.WriteString(fmt.Sprintf("; stopped at %d, narrow the search or raise max_results", limit))
+	}
+	if truncatedWalk {
+		sb.WriteString(fmt.Sprintf("; only the first %d files were searched, pass a narrower path", maxSearchFiles))
+	}
+	return sb.String(), nil
+}
+
+// resolve returns the absolute path to search for the path parameter p.
+func (t *SearchFilesTool) resolve(p string) (string, error) {
+	if p == "" {
+		return t.root, nil
+	}
+	expanded, err := expandPath(p)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(expanded) {
+		expanded = filepath.Join(t.root, expanded)
+	}
+	return expanded, nil
+}
+
+// searchCandidates lists the files below dir to search, in walk order. It
+// reports whether the list stopped at maxSearchFiles.
+func searchCandidates(ctx context.Context, dir string, info fs.FileInfo, globs []string) ([]string, bool, error) {
+	if !info.IsDir() {
+		return []string{dir}, false, nil
+	}
+	var files []string
+	truncated := false
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip unreadable entries
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if p != dir && codeindex.SkipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symlinks are not followed, so a link cannot lead the search
+		// outside dir
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") || isSensitiveName(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || !matchGlobs(globs, filepath.ToSlash(rel)) {
+			return nil
+		}
+		if len(files) >= maxSearchFiles {
+			truncated = true
+			return filepath.SkipAll
+		}
+		files = append(files, p)
+		return nil
+	})
+	return files, truncated, err
+}
+
+// searchAll searches files on several goroutines. The result for a file is
+// nil if it was not searched, because it is binary or too large or because
+// limit matching lines were already found in files before it.
+func searchAll(ctx context.Context, files []string, re *regexp.Regexp, limit int) ([]*fileMatches, error) {
+	results := make([]*fileMatches, len(files))
+	var next, found atomic.Int64
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil && found.Load() < int64(limit) {
+				// Files are handed out in order, so once the limit is
+				// reached every file left is after the ones searched
+				i := int(next.Add(1)) - 1
+				if i >= len(files) {
+					return
+				}
+				if fm := searchFile(files[i], re); fm != nil {
+					results[i] = fm
+					found.Add(int64(len(fm.matches)))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return results, ctx.Err()
+}
+
+// searchFile returns the matching lines of the file at p, or nil if it
+// cannot be read, is too large or looks binary.
+func searchFile(p string, re *regexp.Regexp) *fileMatches {
+	info, err := os.Stat(p)
+	if err != nil || info.Size() > maxSearchFileSize {
+		return nil
+	}
+	data, err := os.ReadFile(p)
+	if err != nil || bytes.IndexByte(data[:min(len(data), binarySniffSize)], 0) >= 0 {
+		return nil
+	}
+	if !re.Match(data) {
+		return &fileMatches{}
+	}
+	fm := &fileMatches{lines: bytes.Split(data, []byte("\n"))}
+	for i, line := range fm.lines {
+		if re.Match(bytes.TrimSuffix(line, []byte("\r"))) {
+			fm.matches = append(fm.matches, i)
+		}
+	}
+	return fm
+}
+
+// writeMatches writes the matching lines of fm with their line numbers,
+// ripgrep style: "12:" marks a match, "13-" a context line and "--" a gap
+// between groups.
+func writeMatches(sb *strings.Builder, fm *fileMatches, contextLines int) {
+	last := -1 // last line written
+	for _, m := range fm.matches {
+		from := max(m-contextLines, last+1)
+		to := min(m+contextLines, len(fm.lines)-1)
+		if last >= 0 && from > last+1 {
+			sb.WriteString("--\n")
+		}
+		for i := from; i <= to; i++ {
+			sep := "-"
+			if _, ok := slices.BinarySearch(fm.matches, i); ok {
+				sep = ":"
+			}
+			sb.WriteString(fmt.Sprintf("%d%s %s\n", i+1, sep, searchLine(fm.lines[i])))
+		}
+		last = max(last, to)
+	}
+}
+
+// searchLine returns line trimmed for output.
+func searchLine(line []byte) string {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) > maxSearchLineLength {
+		return strings.ToValidUTF8(string(line[:maxSearchLineLength]), "") + " ..."
+	}
+	return string(line)
+}
+
+// matchGlobs reports whether the slash-separated relative path rel matches
+// at least one include glob, if there are any, and no '!' exclude glob.
+func matchGlobs(globs []string, rel string) bool {
+	included, hasInclude := false, false
+	for _, g := range globs {
+		if ex, ok := strings.CutPrefix(g, "!"); ok {
+			if matchGlob(ex, rel) {
+				return false
+			}
+			continue
+		}
+		hasInclude = true
+		if matchGlob(g, rel) {
+			included = true
+		}
+	}
+	return included || !hasInclude
+}
+
+// matchGlob matches rel against glob. A glob without '/' matches the file
+// name; otherwise it matches the whole path, with '**' standing for any
+// number of directories.
+func matchGlob(glob, rel string) bool {
+	if !strings.Contains(glob, "/") {
+		ok, _ := path.Match(glob, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(glob, "/"), "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against glob segments.
+func matchSegments(glob, segs []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(glob[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(glob[0], segs[0]); !ok {
+			return false
+		}
+		glob, segs = glob[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// isSensitiveName reports whether a file name is one the filesystem tools
+// refuse to read, e.g. .env or a private key.
+func isSensitiveName(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, sensitiveExt := range sensitiveExtensions {
+		if ext == sensitiveExt {
+			return true
+		}
+	}
+	for _, sensitiveBase := range sensitiveBasenames {
+		if strings.EqualFold(name, sensitiveBase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearchFilesTool(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":                   "package main\n\nfunc main() {\n\tNewClient()\n}\n",
+		"client/client.go":          "package client\n\n// NewClient creates a client.\nfunc NewClient() *Client {\n\treturn &Client{}\n}\n",
+		"client/client_test.go":     "package client\n\nfunc TestNewClient(t *testing.T) {}\n",
+		"web/src/app.ts":            "const client = newClient();\n",
+		"node_modules/lib/index.js": "NewClient()\n",
+		".git/config":               "NewClient\n",
+		".env":                      "TOKEN=NewClient\n",
+		"logo.png":                  "\x89PNG\x00NewClient",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := NewSearchFilesTool(root)
+	search := func(params map[string]interface{}) string {
+		t.Helper()
+		out, err := tool.Execute(context.Background(), params)
+		if err != nil {
+			t.Fatalf("search %v: %v", params, err)
+		}
+		return out
+	}
+
+	out := search(map[string]interface{}{"pattern": `func \w+Client`})
+	for _, want := range []string{"client/client.go\n4: func NewClient() *Client {", "client/client_test.go\n3: func TestNewClient", "2 matching lines in 2 files"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	for _, skipped := range []string{"node_modules", ".git", ".env", "logo.png"} {
+		if strings.Contains(out, skipped) {
+			t.Errorf("searched %s:\n%s", skipped, out)
+		}
+	}
+
+	// Globs, context lines and a path relative to the root
+	out = search(map[string]interface{}{"pattern": "NewClient", "path": "client", "globs": []interface{}{"*.go", "!*_test.go"}, "context": 1})
+	want := "client.go\n2- \n3: // NewClient creates a client.\n4: func NewClient() *Client {\n5- \treturn &Client{}\n"
+	if !strings.HasPrefix(out, want) {
+		t.Errorf("output = %q, want prefix %q", out, want)
+	}
+	out = search(map[string]interface{}{"pattern": "newclient", "ignore_case": true, "globs": []interface{}{"web/**/*.ts"}})
+	if !strings.Contains(out, "web/src/app.ts\n1: const client") || strings.Contains(out, ".go") {
+		t.Errorf("ts glob:\n%s", out)
+	}
+
+	// Literal text, the result cap and no matches
+	out = search(map[string]interface{}{"pattern": "&Client{}", "literal": true})
+	if !strings.Contains(out, "5: \treturn &Client{}") {
+		t.Errorf("literal:\n%s", out)
+	}
+	out = search(map[string]interface{}{"pattern": "Client", "max_results": 2})
+	if !strings.Contains(out, "2 matching lines") || !strings.Contains(out, "stopped at 2") {
+		t.Errorf("capped:\n%s", out)
+	}
+	if out := search(map[string]interface{}{"pattern": "NoSuchThing"}); !strings.HasPrefix(out, "No matches") {
+		t.Errorf("no matches: %s", out)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"pattern": "func("}); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("invalid pattern: err = %v", err)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		glob, rel string
+		want      bool
+	}{
+		{"*.go", "internal/tools/web.go", true},
+		{"*.go", "README.md", false},
+		{"internal/*/web.go", "internal/tools/web.go", true},
+		{"internal/**/*.go", "internal/tools/web.go", true},
+		{"internal/**/*.go", "internal/web.go", true},
+		{"**/testdata/*", "a/b/testdata/x.json", true},
+		{"internal/*.go", "internal/tools/web.go", false},
+	} {
+		if got := matchGlob(tc.glob, tc.rel); got != tc.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.glob, tc.rel, got, tc.want)
+		}
+	}
+}
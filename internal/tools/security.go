@@ -7,9 +7,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/hkuds/ubot/internal/failure"
 	"github.com/hkuds/ubot/internal/sandbox"
+	"github.com/hkuds/ubot/internal/tracing"
 )
 
 // ErrBlockedPath is returned when a tool tries to access a sensitive path.
@@ -22,6 +27,11 @@ func (e ErrBlockedPath) Error() string {
 	return fmt.Sprintf("access denied: %s (%s)", e.Path, e.Reason)
 }
 
+// Category marks the error as a blocked tool call.
+func (e ErrBlockedPath) Category() failure.Category {
+	return failure.ToolBlocked
+}
+
 // sensitiveDirectories are directory prefixes that should never be accessed.
 var sensitiveDirectories = []string{
 	".ssh",
@@ -66,10 +76,63 @@ var sensitiveBasenames = []string{
 
 // filesystemTools are tool names that operate on file paths.
 var filesystemTools = map[string]bool{
-	"read_file":  true,
-	"write_file": true,
-	"edit_file":  true,
-	"list_dir":   true,
+	"read_file":     true,
+	"write_file":    true,
+	"edit_file":     true,
+	"list_dir":      true,
+	"search_files":  true,
+	"send_file":     true,
+	"watch_path":    true,
+	"apply_patch":   true,
+	"download_file": true,
+}
+
+// multiPathTool is a filesystem tool whose call names several files, such
+// as the files a patch changes, rather than one "path".
+type multiPathTool interface {
+	// Paths returns the files a call with params would touch.
+	Paths(params map[string]interface{}) []string
+}
+
+// toolPaths returns the file paths a call to a filesystem tool names.
+func toolPaths(tool Tool, params map[string]interface{}) []string {
+	if t, ok := tool.(multiPathTool); ok {
+		return t.Paths(params)
+	}
+	if pathStr, err := GetStringParam(params, "path"); err == nil {
+		return []string{pathStr}
+	}
+	return nil // No path param; let the tool itself handle the error
+}
+
+// desktopTools reach the desktop of the machine uBot runs on. Only the
+// local CLI user sits in front of it, so other channels may not use them.
+var desktopTools = map[string]bool{
+	"get_clipboard":             true,
+	"set_clipboard":             true,
+	"send_desktop_notification": true,
+}
+
+// Observer is notified after every tool execution, e.g. to collect usage
+// statistics.
+type Observer interface {
+	ObserveTool(name string, duration time.Duration, err error)
+}
+
+// ToolCall describes a finished tool call for the audit log.
+type ToolCall struct {
+	Name       string
+	Params     map[string]string // redacted parameters
+	Request    RequestInfo       // zero when not called from a conversation
+	Duration   time.Duration
+	ResultSize int
+	Usage      *sandbox.Usage // resources used by a command the tool ran, if any
+	Err        error
+}
+
+// Auditor records every tool call, including blocked and denied ones.
+type Auditor interface {
+	AuditTool(call ToolCall)
 }
 
 // SecureRegistry wraps a ToolRegistry and intercepts Execute calls
@@ -77,6 +140,21 @@ var filesystemTools = map[string]bool{
 type SecureRegistry struct {
 	inner        *ToolRegistry
 	blockedPaths []string
+	observer     Observer
+	auditor      Auditor
+
+	overflowStore     OverflowStore // nil = keep large results inline
+	overflowThreshold int
+
+	skillRestrictions SkillRestrictions // applied once a turn reads an untrusted skill
+
+	defaultPolicy string            // policy for tools not in policies; "" = PolicyAuto
+	policies      map[string]string // per-tool execution policy
+	approvalMu    sync.Mutex        // one approval prompt at a time
+
+	workers  int                      // concurrent calls in ExecuteAll
+	timeout  time.Duration            // per-call time limit; 0 = none
+	timeouts map[string]time.Duration // per-tool overrides of timeout
 }
 
 // NewSecureRegistry creates a new SecureRegistry wrapping the given ToolRegistry.
@@ -124,8 +202,30 @@ func buildBlockedPaths() []string {
 }
 
 // Execute runs security checks and then delegates to the inner registry.
-func (s *SecureRegistry) Execute(ctx context.Context, name string, params map[string]interface{}) (string, error) {
+func (s *SecureRegistry) Execute(ctx context.Context, name string, params map[string]interface{}) (result string, err error) {
+	// The span includes checks and any wait for approval
+	ctx, span := tracing.Start(ctx, "execute_tool "+name, attribute.String("gen_ai.tool.name", name))
+	defer func() { tracing.End(span, err) }()
+
 	start := time.Now()
+	resultSize := 0
+	logged := params
+	if s.auditor != nil {
+		var usage *usageSlot
+		ctx, usage = withUsageSlot(ctx)
+		defer func() {
+			info, _ := RequestFromContext(ctx)
+			s.auditor.AuditTool(ToolCall{
+				Name:       name,
+				Params:     redactParamMap(logged),
+				Request:    info,
+				Duration:   time.Since(start),
+				ResultSize: resultSize,
+				Usage:      usage.usage,
+				Err:        err,
+			})
+		}()
+	}
 
 	// Look up the tool to validate params against its schema
 	tool := s.inner.Get(name)
@@ -133,6 +233,14 @@ func (s *SecureRegistry) Execute(ctx context.Context, name string, params map[st
 		return "", ErrToolNotFound{Name: name}
 	}
 
+	// Arguments given as artifact references get the stored results; the
+	// audit log and the log lines keep the references
+	if name != FetchMoreName {
+		if params, err = s.resolveArtifacts(ctx, params); err != nil {
+			return "", err
+		}
+	}
+
 	// Validate parameters against the tool's JSON schema
 	if errs := ValidateParams(params, tool.Parameters()); len(errs) > 0 {
 		log.Printf("[security] tool=%s action=param_validation_failed errors=%v", name, errs)
@@ -140,42 +248,76 @@ func (s *SecureRegistry) Execute(ctx context.Context, name string, params map[st
 	}
 
 	// Path validation for filesystem tools
+	var paths []string
 	if filesystemTools[name] {
-		if err := s.validatePath(params); err != nil {
-			log.Printf("[security] tool=%s action=blocked_path path=%s", name, redactParams(params))
-			return "", err
+		paths = toolPaths(tool, params)
+		for _, pathStr := range paths {
+			if err := s.validatePath(pathStr); err != nil {
+				log.Printf("[security] tool=%s action=blocked_path path=%s", name, redactParams(logged))
+				return "", err
+			}
+		}
+	}
+
+	// Turns that read untrusted skill content keep file tools in the workspace
+	untrusted := s.untrustedSkills(ctx)
+	if len(untrusted) > 0 {
+		for _, pathStr := range paths {
+			if err := s.validateSkillPath(pathStr, untrusted); err != nil {
+				log.Printf("[security] tool=%s action=blocked_path reason=untrusted_skill path=%s", name, redactParams(logged))
+				return "", err
+			}
+		}
+	}
+
+	// Desktop tools only serve the user at the local CLI
+	if desktopTools[name] {
+		if info, _ := RequestFromContext(ctx); info.Channel != "cli" {
+			log.Printf("[security] tool=%s action=denied reason=channel channel=%q", name, info.Channel)
+			return "", ErrToolDenied{Name: name, Reason: "desktop tools only work in the local CLI"}
 		}
 	}
 
 	// Command validation for exec tool using sandbox.GuardCommand
 	if name == "exec" {
 		if err := s.validateExecCommand(params); err != nil {
-			log.Printf("[security] tool=%s action=blocked_command params=%s", name, redactParams(params))
+			log.Printf("[security] tool=%s action=blocked_command params=%s", name, redactParams(logged))
 			return "", err
 		}
 	}
 
+	// Apply the tool's execution policy, asking the user if required
+	if err := s.checkPolicy(ctx, name, params, untrusted); err != nil {
+		log.Printf("[security] tool=%s action=denied reason=%q params=%s", name, err, redactParams(logged))
+		return "", err
+	}
+	start = time.Now() // exclude time spent waiting for approval
+
 	// Delegate to the inner registry
-	result, err := s.inner.Execute(ctx, name, params)
+	result, err = s.runTool(ctx, name, params)
+	if name == "read_skill" && err == nil {
+		s.markSkillRead(ctx, params)
+	}
 
 	// Audit log
 	status := "ok"
 	if err != nil {
 		status = "error"
 	}
+	duration := time.Since(start)
 	log.Printf("[security] tool=%s status=%s duration=%s params=%s",
-		name, status, time.Since(start).Round(time.Millisecond), redactParams(params))
-
-	return result, err
-}
+		name, status, duration.Round(time.Millisecond), redactParams(logged))
 
-// validatePath checks that the file path in params does not point to a sensitive location.
-func (s *SecureRegistry) validatePath(params map[string]interface{}) error {
-	pathStr, err := GetStringParam(params, "path")
-	if err != nil {
-		return nil // No path param; let the tool itself handle the error
+	if s.observer != nil {
+		s.observer.ObserveTool(name, duration, err)
 	}
 
+	resultSize = len(result)
+	return s.overflow(ctx, name, result), err
+}
+
+// validatePath checks that a file path does not point to a sensitive location.
+func (s *SecureRegistry) validatePath(pathStr string) error {
 	resolved, err := resolvePath(pathStr)
 	if err != nil {
 		return fmt.Errorf("cannot resolve path %q: %w", pathStr, err)
@@ -245,24 +387,46 @@ func resolvePath(path string) (string, error) {
 	}
 
 	// Try to resolve symlinks. If the file doesn't exist yet (e.g., write_file),
-	// resolve the parent directory instead.
-	resolved, err := filepath.EvalSymlinks(path)
-	if err != nil {
-		// File may not exist yet; resolve parent
-		parent := filepath.Dir(path)
-		resolvedParent, parentErr := filepath.EvalSymlinks(parent)
-		if parentErr != nil {
-			// Parent doesn't exist either; use the cleaned absolute path
+	// resolve the nearest directory above it that does, since the missing
+	// directories will be created below wherever it points.
+	missing := ""
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+		if filepath.Dir(dir) == dir {
+			// Nothing resolves; use the cleaned absolute path
 			return path, nil
 		}
-		return filepath.Join(resolvedParent, filepath.Base(path)), nil
+		missing = filepath.Join(filepath.Base(dir), missing)
 	}
+}
 
-	return resolved, nil
+// followsOutside reports where p leads through symlinks when that is not
+// below root, or "" when it stays below root. Paths that do not exist yet
+// are judged by the directories above them that do.
+func followsOutside(root, p string) (string, error) {
+	real, err := resolvePath(p)
+	if err != nil {
+		return "", err
+	}
+	realRoot, err := resolvePath(root)
+	if err != nil {
+		return "", err
+	}
+	if r, err := filepath.Rel(realRoot, real); err != nil || !filepath.IsLocal(r) {
+		return real, nil
+	}
+	return "", nil
 }
 
 // redactParams returns a string representation of params with sensitive values redacted.
 func redactParams(params map[string]interface{}) string {
+	return fmt.Sprintf("%v", redactParamMap(params))
+}
+
+// redactParamMap returns params as strings with sensitive values redacted.
+func redactParamMap(params map[string]interface{}) map[string]string {
 	redacted := make(map[string]string, len(params))
 	for k, v := range params {
 		switch k {
@@ -276,11 +440,87 @@ func redactParams(params map[string]interface{}) string {
 			} else {
 				redacted[k] = "[redacted]"
 			}
+		case "value": // set_env values are often secrets
+			redacted[k] = "[redacted]"
 		default:
 			redacted[k] = fmt.Sprintf("%v", v)
 		}
 	}
-	return fmt.Sprintf("%v", redacted)
+	return redacted
+}
+
+// SetApprovalPolicy sets the execution policy (PolicyAuto, PolicyAsk or
+// PolicyDeny) applied to each tool; tools without an entry use
+// defaultPolicy. It must be set before the registry is used.
+func (s *SecureRegistry) SetApprovalPolicy(defaultPolicy string, policies map[string]string) {
+	s.defaultPolicy = defaultPolicy
+	s.policies = policies
+}
+
+// policyFor returns the execution policy for a tool. Unknown policy values
+// are treated as PolicyAsk.
+func (s *SecureRegistry) policyFor(name string) string {
+	policy, ok := s.policies[name]
+	if !ok {
+		policy = s.defaultPolicy
+	}
+	return normalizePolicy(policy)
+}
+
+// normalizePolicy maps "" to PolicyAuto and unknown values to PolicyAsk.
+func normalizePolicy(policy string) string {
+	switch policy {
+	case "", PolicyAuto:
+		return PolicyAuto
+	case PolicyDeny:
+		return PolicyDeny
+	default:
+		return PolicyAsk
+	}
+}
+
+// checkPolicy enforces the tool's execution policy, made stricter when the
+// turn read untrusted skills. Calls that need approval are confirmed through
+// the Approver in ctx and denied when there is none.
+func (s *SecureRegistry) checkPolicy(ctx context.Context, name string, params map[string]interface{}, untrusted []string) error {
+	policy, reason := s.skillPolicy(name, s.policyFor(name), untrusted)
+	switch policy {
+	case PolicyAuto:
+		return nil
+	case PolicyDeny:
+		if reason != "" {
+			return ErrToolDenied{Name: name, Reason: "disabled because " + reason}
+		}
+		return ErrToolDenied{Name: name, Reason: "disabled by policy"}
+	}
+
+	approver, ok := ApproverFromContext(ctx)
+	if !ok {
+		return ErrToolDenied{Name: name, Reason: "requires approval, but no one can be asked here"}
+	}
+	// Calls run in parallel, but the user answers one prompt at a time
+	s.approvalMu.Lock()
+	approved, err := approver.Approve(ctx, ApprovalRequest{Tool: name, Params: params, Reason: reason})
+	s.approvalMu.Unlock()
+	if err != nil {
+		return ErrToolDenied{Name: name, Reason: "approval failed: " + err.Error()}
+	}
+	if !approved {
+		return ErrToolDenied{Name: name, Reason: "denied by the user"}
+	}
+	return nil
+}
+
+// SetObserver sets the observer notified of tool executions. It must be set
+// before the registry is used.
+func (s *SecureRegistry) SetObserver(o Observer) {
+	s.observer = o
+}
+
+// SetAuditor sets the auditor that records every tool call. It must be set
+// before the registry is used.
+func (s *SecureRegistry) SetAuditor(a Auditor) {
+	s.auditor = a
 }
 
 // GetDefinitions delegates to the inner registry.
@@ -2,10 +2,13 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
 )
 
 func TestSecureRegistry_BlockedPaths(t *testing.T) {
@@ -309,6 +312,45 @@ func TestSecureRegistry_EditFileBlockedPath(t *testing.T) {
 	}
 }
 
+func TestSecureRegistry_ApplyPatchBlockedPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+	registry := NewRegistry()
+	registry.MustRegister(NewApplyPatchTool(home))
+	secure := NewSecureRegistry(registry)
+
+	// Every file of the patch is checked, not only its base name
+	params := map[string]interface{}{
+		"patch":   "--- a/notes.txt\n+++ b/notes.txt\n@@ -1 +1 @@\n-a\n+b\n--- /dev/null\n+++ b/.ssh/authorized_keys\n@@ -0,0 +1 @@\n+ssh-rsa AAAA...\n",
+		"dry_run": true,
+	}
+	_, err = secure.Execute(context.Background(), "apply_patch", params)
+	var blocked ErrBlockedPath
+	if !errors.As(err, &blocked) || blocked.Path != filepath.Join(home, ".ssh", "authorized_keys") {
+		t.Errorf("patching ~/.ssh/authorized_keys: err = %v, want ErrBlockedPath", err)
+	}
+}
+
+func TestSecureRegistry_DownloadFileBlockedPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+	registry := NewRegistry()
+	registry.MustRegister(NewDownloadFileTool(home, config.DownloadConfig{}))
+	secure := NewSecureRegistry(registry)
+
+	// The path is relative to the tool's workspace, not the current directory
+	params := map[string]interface{}{"url": "https://example.invalid/key", "path": ".ssh/authorized_keys"}
+	_, err = secure.Execute(context.Background(), "download_file", params)
+	var blocked ErrBlockedPath
+	if !errors.As(err, &blocked) || blocked.Path != filepath.Join(home, ".ssh", "authorized_keys") {
+		t.Errorf("downloading to ~/.ssh/authorized_keys: err = %v, want ErrBlockedPath", err)
+	}
+}
+
 func TestBuildBlockedPaths(t *testing.T) {
 	paths := buildBlockedPaths()
 	if len(paths) == 0 {
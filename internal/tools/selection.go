@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// RequestToolName is the name of the escape-hatch tool the model uses to ask
+// for tools that were not selected for the current turn.
+const RequestToolName = "request_tool"
+
+// ToolSelection tracks which tool definitions are sent to the model during
+// one turn. It starts with the tools most relevant to the user message and
+// grows when the model asks for more through request_tool.
+type ToolSelection struct {
+	mu       sync.Mutex
+	all      []ToolDefinition
+	selected map[string]bool
+	limited  bool
+}
+
+// SelectTools picks up to limit definitions from all by keyword overlap with
+// query. If limit is zero or all tools fit, every tool is selected and
+// request_tool is left out.
+func SelectTools(all []ToolDefinition, query string, limit int) *ToolSelection {
+	candidates := make([]ToolDefinition, 0, len(all))
+	for _, def := range all {
+		if def.Function.Name != RequestToolName {
+			candidates = append(candidates, def)
+		}
+	}
+
+	s := &ToolSelection{all: candidates, selected: make(map[string]bool)}
+	if limit <= 0 || len(candidates) <= limit {
+		for _, def := range candidates {
+			s.selected[def.Function.Name] = true
+		}
+		return s
+	}
+
+	s.limited = true
+	for _, name := range rankTools(candidates, query, limit, true) {
+		s.selected[name] = true
+	}
+	return s
+}
+
+// Definitions returns the selected definitions in registry order, followed
+// by request_tool when the selection is limited.
+func (s *ToolSelection) Definitions() []ToolDefinition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defs := make([]ToolDefinition, 0, len(s.selected)+1)
+	for _, def := range s.all {
+		if s.selected[def.Function.Name] {
+			defs = append(defs, def)
+		}
+	}
+	if s.limited {
+		defs = append(defs, ToDefinition(NewRequestToolTool()))
+	}
+	return defs
+}
+
+// Add selects the tool named query, or else the best keyword matches for it.
+// It returns the names of newly selected tools.
+func (s *ToolSelection) Add(query string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matches []string
+	for _, def := range s.all {
+		if strings.EqualFold(def.Function.Name, strings.TrimSpace(query)) {
+			matches = []string{def.Function.Name}
+			break
+		}
+	}
+	if matches == nil {
+		matches = rankTools(s.all, query, 3, false)
+	}
+
+	var added []string
+	for _, name := range matches {
+		if !s.selected[name] {
+			s.selected[name] = true
+			added = append(added, name)
+		}
+	}
+	return added
+}
+
+// Unselected returns the names of tools not yet offered to the model.
+func (s *ToolSelection) Unselected() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for _, def := range s.all {
+		if !s.selected[def.Function.Name] {
+			names = append(names, def.Function.Name)
+		}
+	}
+	return names
+}
+
+// rankTools returns up to limit tool names ordered by relevance to query.
+// With fill set, tools without any keyword match pad the result up to limit
+// in registry order, so the model always has a baseline set to work with.
+func rankTools(defs []ToolDefinition, query string, limit int, fill bool) []string {
+	queryWords := keywords(query)
+
+	type scored struct {
+		name  string
+		score int
+	}
+	ranked := make([]scored, len(defs))
+	for i, def := range defs {
+		nameWords := keywords(strings.ReplaceAll(def.Function.Name, "_", " "))
+		descWords := keywords(def.Function.Description)
+		score := 0
+		for _, q := range queryWords {
+			if matchesAny(q, nameWords) {
+				score += 3
+			} else if matchesAny(q, descWords) {
+				score++
+			}
+		}
+		ranked[i] = scored{name: def.Function.Name, score: score}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	if limit > len(ranked) {
+		limit = len(ranked)
+	}
+	names := make([]string, 0, limit)
+	for _, r := range ranked[:limit] {
+		if r.score == 0 && !fill {
+			break
+		}
+		names = append(names, r.name)
+	}
+	return names
+}
+
+// stopWords are common words that carry no signal for tool matching.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true,
+	"this": true, "from": true, "you": true, "your": true, "can": true,
+	"please": true, "what": true, "how": true, "are": true, "use": true,
+	"into": true, "about": true, "have": true, "will": true, "tool": true,
+}
+
+// keywords splits text into lowercase words of three or more letters,
+// dropping stop words.
+func keywords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len([]rune(f)) >= 3 && !stopWords[f] {
+			words = append(words, f)
+		}
+	}
+	return words
+}
+
+// matchesAny reports whether word matches one of words exactly or shares a
+// prefix of at least four letters with it that differs by no more than a
+// short suffix (so "files" matches "file" and "scheduling" matches
+// "schedule").
+func matchesAny(word string, words []string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+		p := commonPrefix(w, word)
+		if p >= 4 && p >= min(len(w), len(word))-2 {
+			return true
+		}
+	}
+	return false
+}
+
+// commonPrefix returns the length of the common byte prefix of a and b.
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+type toolSelectionKey struct{}
+
+// WithToolSelection returns a context carrying the turn's tool selection so
+// request_tool can extend it.
+func WithToolSelection(ctx context.Context, s *ToolSelection) context.Context {
+	return context.WithValue(ctx, toolSelectionKey{}, s)
+}
+
+// RequestToolTool lets the model ask for tool definitions that were left out
+// of the current turn to save tokens.
+type RequestToolTool struct {
+	BaseTool
+}
+
+// NewRequestToolTool creates a new RequestToolTool.
+func NewRequestToolTool() *RequestToolTool {
+	return &RequestToolTool{
+		BaseTool: NewBaseTool(
+			RequestToolName,
+			"Only a subset of tools is shown to save tokens. Call this to make more tools available: pass an exact tool name, or keywords describing the capability you need (e.g. 'schedule reminder'). The tools can be used from the next step.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "A tool name or keywords describing the needed capability",
+					},
+				},
+				"required": []string{"query"},
+			},
+		),
+	}
+}
+
+// Execute adds matching tools to the current turn's selection.
+func (t *RequestToolTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	query, err := GetStringParam(params, "query")
+	if err != nil {
+		return "", fmt.Errorf("request_tool: %w", err)
+	}
+
+	s, ok := ctx.Value(toolSelectionKey{}).(*ToolSelection)
+	if !ok {
+		return "All tools are already available.", nil
+	}
+
+	added := s.Add(query)
+	if len(added) == 0 {
+		remaining := s.Unselected()
+		if len(remaining) == 0 {
+			return "All tools are already available.", nil
+		}
+		return fmt.Sprintf("No new tools matched %q. Tools not yet available: %s", query, strings.Join(remaining, ", ")), nil
+	}
+	return fmt.Sprintf("Now available: %s", strings.Join(added, ", ")), nil
+}
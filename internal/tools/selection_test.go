@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func selectionDefs() []ToolDefinition {
+	def := func(name, desc string) ToolDefinition {
+		return ToolDefinition{Type: "function", Function: FunctionDefinition{Name: name, Description: desc}}
+	}
+	return []ToolDefinition{
+		def("browser_use", "Browse websites with a headless browser"),
+		def("cron", "Schedule recurring jobs and reminders"),
+		def("exec", "Execute shell commands"),
+		def("read_file", "Read file contents"),
+		def("web_search", "Search the web"),
+		def("write_file", "Write content to a file"),
+		def(RequestToolName, "escape hatch"),
+	}
+}
+
+func names(defs []ToolDefinition) []string {
+	var out []string
+	for _, d := range defs {
+		out = append(out, d.Function.Name)
+	}
+	return out
+}
+
+func TestSelectToolsRanksByKeywords(t *testing.T) {
+	s := SelectTools(selectionDefs(), "Please read the files in my project", 2)
+	got := names(s.Definitions())
+
+	if len(got) != 3 || got[2] != RequestToolName {
+		t.Fatalf("definitions = %v, want 2 tools plus %s", got, RequestToolName)
+	}
+	if got[0] != "read_file" {
+		t.Errorf("top tool = %q, want read_file (got %v)", got[0], got)
+	}
+}
+
+func TestSelectToolsUnlimited(t *testing.T) {
+	got := names(SelectTools(selectionDefs(), "anything", 0).Definitions())
+	if len(got) != 6 {
+		t.Errorf("definitions = %v, want all 6 tools without %s", got, RequestToolName)
+	}
+	if strings.Contains(strings.Join(got, ","), RequestToolName) {
+		t.Errorf("request_tool should not be offered when nothing is trimmed")
+	}
+}
+
+func TestRequestToolAddsTools(t *testing.T) {
+	s := SelectTools(selectionDefs(), "read the file", 1)
+	ctx := WithToolSelection(context.Background(), s)
+	tool := NewRequestToolTool()
+
+	out, err := tool.Execute(ctx, map[string]interface{}{"query": "schedule a reminder"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(out, "cron") {
+		t.Errorf("result = %q, want cron to be added", out)
+	}
+
+	out, _ = tool.Execute(ctx, map[string]interface{}{"query": "exec"})
+	if out != "Now available: exec" {
+		t.Errorf("result = %q, want exact name match for exec", out)
+	}
+
+	got := strings.Join(names(s.Definitions()), ",")
+	for _, want := range []string{"read_file", "cron", "exec", RequestToolName} {
+		if !strings.Contains(got, want) {
+			t.Errorf("definitions %s missing %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/tracing"
+)
+
+// maxSendFileBytes is the largest file send_file sends; Telegram accepts
+// documents of up to 50 MB from bots.
+const maxSendFileBytes = 50 << 20
+
+// SendFileTool sends a file from disk to the current conversation, such as
+// a report or chart the agent generated. Pictures are shown as photos where
+// the channel supports it.
+type SendFileTool struct {
+	BaseTool
+	send func(bus.OutboundMessage)
+}
+
+// NewSendFileTool creates a SendFileTool that delivers files through send,
+// usually the message bus.
+func NewSendFileTool(send func(bus.OutboundMessage)) *SendFileTool {
+	return &SendFileTool{
+		BaseTool: NewBaseTool(
+			"send_file",
+			"Send a file to the user in this chat as an attachment, e.g. a generated report, CSV export, chart or image. Write the file first, then send it.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "The path of the file to send. Supports ~ for home directory.",
+					},
+					"caption": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional text shown with the file.",
+					},
+				},
+				"required": []string{"path"},
+			},
+		),
+		send: send,
+	}
+}
+
+// Execute reads the file and sends it to the conversation the call belongs
+// to.
+func (t *SendFileTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	pathStr, err := GetStringParam(params, "path")
+	if err != nil {
+		return "", fmt.Errorf("send_file: %w", err)
+	}
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.Channel == "" {
+		return "", errors.New("send_file: no active conversation")
+	}
+	if req.Channel == "cli" {
+		return "", errors.New("send_file: the CLI cannot receive files; tell the user the path instead")
+	}
+
+	path, err := expandPath(pathStr)
+	if err != nil {
+		return "", fmt.Errorf("send_file: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("send_file: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("send_file: %s is a directory", pathStr)
+	}
+	if info.Size() > maxSendFileBytes {
+		return "", fmt.Errorf("send_file: %s is %d MB, more than the %d MB that can be sent", pathStr, info.Size()>>20, maxSendFileBytes>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("send_file: %w", err)
+	}
+
+	name := filepath.Base(path)
+	t.send(bus.OutboundMessage{
+		Channel: req.Channel,
+		ChatID:  req.ChatID,
+		Files:   []bus.File{{Name: name, Data: data, Caption: GetStringParamOr(params, "caption", "")}},
+		Trace:   tracing.Inject(ctx),
+	})
+	return fmt.Sprintf("Sent %s (%d bytes) to the chat.", name, len(data)), nil
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+func TestSendFileTool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(path, []byte("a,b\n1,2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var sent []bus.OutboundMessage
+	tool := NewSendFileTool(func(msg bus.OutboundMessage) { sent = append(sent, msg) })
+
+	ctx := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "42", SessionKey: "telegram:42"})
+	if _, err := tool.Execute(ctx, map[string]interface{}{"path": path, "caption": "Q3 numbers"}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(sent) != 1 || sent[0].ChatID != "42" || len(sent[0].Files) != 1 {
+		t.Fatalf("sent = %+v", sent)
+	}
+	if f := sent[0].Files[0]; f.Name != "report.csv" || string(f.Data) != "a,b\n1,2\n" || f.Caption != "Q3 numbers" {
+		t.Errorf("file = %+v", f)
+	}
+
+	cli := WithRequest(context.Background(), RequestInfo{Channel: "cli", SessionKey: "cli:default"})
+	for name, tc := range map[string]struct {
+		ctx  context.Context
+		path string
+	}{
+		"cli":       {cli, path},
+		"no chat":   {context.Background(), path},
+		"directory": {ctx, filepath.Dir(path)},
+		"missing":   {ctx, path + ".old"},
+	} {
+		if _, err := tool.Execute(tc.ctx, map[string]interface{}{"path": tc.path}); err == nil {
+			t.Errorf("%s: Execute succeeded", name)
+		}
+	}
+	if len(sent) != 1 {
+		t.Errorf("failed calls sent %d messages", len(sent)-1)
+	}
+}
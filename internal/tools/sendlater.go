@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hkuds/ubot/internal/cron"
+)
+
+// maxSendLaterDelay bounds how far ahead send_later schedules a message.
+const maxSendLaterDelay = 366 * 24 * time.Hour
+
+// SendLaterTool schedules a literal message for a chat, such as a reminder
+// to call someone at 17:00. Unlike a cron job the text is sent as is when
+// the time comes, without calling the model.
+type SendLaterTool struct {
+	BaseTool
+	scheduler *cron.Scheduler
+	now       func() time.Time
+}
+
+// NewSendLaterTool creates a SendLaterTool backed by the given Scheduler.
+func NewSendLaterTool(scheduler *cron.Scheduler) *SendLaterTool {
+	return &SendLaterTool{
+		BaseTool: NewBaseTool(
+			"send_later",
+			"Send a message at a given time or after a delay, e.g. \"remind me at 5pm to call mom\". The message is sent word for word, without thinking again when the time comes; use cron for recurring reminders or ones that need fresh information. Use 'schedule' with 'at' or 'delay', 'list' to see pending messages and 'cancel' to drop one by ID.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"schedule", "list", "cancel"},
+						"description": "The action to perform. Default: schedule.",
+					},
+					"message": map[string]interface{}{
+						"type":        "string",
+						"description": "The text to send, written to the user, e.g. \"Time to call mom!\". Required for 'schedule'.",
+					},
+					"at": map[string]interface{}{
+						"type":        "string",
+						"description": "When to send it: a time today or tomorrow (\"17:00\"), or a date and time (\"2026-03-14 09:30\", RFC 3339). Local time unless an offset is given.",
+					},
+					"delay": map[string]interface{}{
+						"type":        "string",
+						"description": "Send it after this long instead, e.g. \"20m\" or \"1h30m\".",
+					},
+					"channel": map[string]interface{}{
+						"type":        "string",
+						"description": "The channel to send to. Default: this conversation's.",
+					},
+					"chat_id": map[string]interface{}{
+						"type":        "string",
+						"description": "The chat to send to. Default: this conversation.",
+					},
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "The ID of the message to cancel. Required for 'cancel'.",
+					},
+				},
+			},
+		),
+		scheduler: scheduler,
+		now:       time.Now,
+	}
+}
+
+// Execute runs the send_later action.
+func (t *SendLaterTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	switch action := GetStringParamOr(params, "action", "schedule"); action {
+	case "schedule":
+		return t.schedule(ctx, params)
+	case "list":
+		return t.list(ctx), nil
+	case "cancel":
+		id, err := GetStringParam(params, "id")
+		if err != nil {
+			return "", fmt.Errorf("send_later cancel: %w", err)
+		}
+		if !t.visible(ctx, id) {
+			return "", fmt.Errorf("send_later cancel: scheduled message %q not found", id)
+		}
+		if err := t.scheduler.CancelMessage(id); err != nil {
+			return "", fmt.Errorf("send_later cancel: %w", err)
+		}
+		return fmt.Sprintf("Scheduled message %s cancelled.", id), nil
+	default:
+		return "", fmt.Errorf("send_later: unknown action %q (use schedule, list or cancel)", action)
+	}
+}
+
+func (t *SendLaterTool) schedule(ctx context.Context, params map[string]interface{}) (string, error) {
+	message, err := GetStringParam(params, "message")
+	if err != nil || strings.TrimSpace(message) == "" {
+		return "", errors.New("send_later: 'message' is required")
+	}
+
+	req, _ := RequestFromContext(ctx)
+	channel := GetStringParamOr(params, "channel", req.Channel)
+	chatID := GetStringParamOr(params, "chat_id", req.ChatID)
+	if channel == "" || chatID == "" {
+		return "", errors.New("send_later: no conversation to send to; pass channel and chat_id")
+	}
+
+	at, err := t.sendTime(GetStringParamOr(params, "at", ""), GetStringParamOr(params, "delay", ""))
+	if err != nil {
+		return "", fmt.Errorf("send_later: %w", err)
+	}
+
+	id, err := t.scheduler.SendLater(at, strings.TrimSpace(message), channel, chatID)
+	if err != nil {
+		return "", fmt.Errorf("send_later: %w", err)
+	}
+	return fmt.Sprintf("Message scheduled (ID: %s) for %s, in %s.", id, formatCalendarTime(at), formatDelay(at.Sub(t.now()))), nil
+}
+
+// sendTime works out when to send from at or delay, exactly one of which
+// must be given.
+func (t *SendLaterTool) sendTime(at, delay string) (time.Time, error) {
+	now := t.now()
+	var when time.Time
+	switch {
+	case at != "" && delay != "":
+		return time.Time{}, errors.New("pass either 'at' or 'delay', not both")
+	case delay != "":
+		d, err := time.ParseDuration(strings.TrimSpace(delay))
+		if err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("cannot read delay %q; use a form like 20m or 1h30m", delay)
+		}
+		when = now.Add(d)
+	case at != "":
+		var err error
+		if when, err = parseSendTime(at, now); err != nil {
+			return time.Time{}, err
+		}
+		if !when.After(now) {
+			return time.Time{}, fmt.Errorf("%s has already passed (it is now %s)", formatCalendarTime(when), formatCalendarTime(now))
+		}
+	default:
+		return time.Time{}, errors.New("'at' or 'delay' is required")
+	}
+	if when.Sub(now) > maxSendLaterDelay {
+		return time.Time{}, errors.New("messages can be scheduled up to a year ahead")
+	}
+	return when, nil
+}
+
+// parseSendTime reads a clock time, meaning its next occurrence after now,
+// or a date and time.
+func parseSendTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if c, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			t := time.Date(now.Year(), now.Month(), now.Day(), c.Hour(), c.Minute(), c.Second(), 0, now.Location())
+			if !t.After(now) {
+				t = t.AddDate(0, 0, 1)
+			}
+			return t, nil
+		}
+	}
+	t, dateOnly, err := parseCalendarTime(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot read time %q; use a form like 17:00 or 2026-03-14 09:30", s)
+	}
+	if dateOnly {
+		return time.Time{}, fmt.Errorf("%q has no time of day; add one, e.g. %s 09:00", s, s)
+	}
+	return t, nil
+}
+
+// formatDelay renders d to the minute, e.g. "2h 5m".
+func formatDelay(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	days, hours, minutes := int(d/(24*time.Hour)), int(d/time.Hour)%24, int(d/time.Minute)%60
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// pending returns the messages waiting to be sent to this conversation,
+// or all of them when the call comes from no conversation.
+func (t *SendLaterTool) pending(ctx context.Context) []cron.ScheduledMessage {
+	req, _ := RequestFromContext(ctx)
+	var msgs []cron.ScheduledMessage
+	for _, m := range t.scheduler.ListMessages() {
+		if req.ChatID == "" || (m.Channel == req.Channel && m.ChatID == req.ChatID) {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs
+}
+
+// visible reports whether the message with the given ID is pending for
+// this conversation.
+func (t *SendLaterTool) visible(ctx context.Context, id string) bool {
+	for _, m := range t.pending(ctx) {
+		if m.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// list describes the messages pending for this conversation.
+func (t *SendLaterTool) list(ctx context.Context) string {
+	var sb strings.Builder
+	for _, m := range t.pending(ctx) {
+		fmt.Fprintf(&sb, "- ID: %s | %s | %s:%s\n  %s\n", m.ID, formatCalendarTime(m.At), m.Channel, m.ChatID, m.Content)
+	}
+	if sb.Len() == 0 {
+		return "No messages are scheduled."
+	}
+	return "Scheduled messages:\n" + sb.String()
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+	"github.com/hkuds/ubot/internal/cron"
+)
+
+func TestSendLaterTool(t *testing.T) {
+	scheduler := cron.NewScheduler(bus.NewMessageBus(10), nil, "")
+	scheduler.SetPersistPath(filepath.Join(t.TempDir(), "cron_jobs.json"))
+	tool := NewSendLaterTool(scheduler)
+	now := time.Date(2026, 3, 14, 18, 30, 0, 0, time.Local)
+	tool.now = func() time.Time { return now }
+
+	ctx := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "42", SessionKey: "telegram:42"})
+	for _, tc := range []struct {
+		at, delay string
+		want      time.Time
+	}{
+		{at: "17:00", want: time.Date(2026, 3, 15, 17, 0, 0, 0, time.Local)},
+		{at: "19:00", want: time.Date(2026, 3, 14, 19, 0, 0, 0, time.Local)},
+		{at: "2026-03-20 09:30", want: time.Date(2026, 3, 20, 9, 30, 0, 0, time.Local)},
+		{delay: "1h30m", want: now.Add(90 * time.Minute)},
+	} {
+		if _, err := tool.Execute(ctx, map[string]interface{}{"message": "Call mom", "at": tc.at, "delay": tc.delay}); err != nil {
+			t.Fatalf("schedule at %q / delay %q: %v", tc.at, tc.delay, err)
+		}
+		found := false
+		for _, m := range scheduler.ListMessages() {
+			found = found || (m.At.Equal(tc.want) && m.Channel == "telegram" && m.ChatID == "42" && m.Content == "Call mom")
+		}
+		if !found {
+			t.Errorf("at %q / delay %q scheduled %+v, want one at %v", tc.at, tc.delay, scheduler.ListMessages(), tc.want)
+		}
+	}
+
+	for name, params := range map[string]map[string]interface{}{
+		"past":      {"message": "x", "at": "2026-03-14 09:00"},
+		"date only": {"message": "x", "at": "2026-03-20"},
+		"both":      {"message": "x", "at": "19:00", "delay": "5m"},
+		"neither":   {"message": "x"},
+		"no text":   {"delay": "5m"},
+		"too far":   {"message": "x", "delay": "9000h"},
+	} {
+		if _, err := tool.Execute(ctx, params); err == nil {
+			t.Errorf("%s: Execute succeeded", name)
+		}
+	}
+
+	// Other chats neither see nor cancel this chat's messages
+	other := WithRequest(context.Background(), RequestInfo{Channel: "telegram", ChatID: "7"})
+	if out, _ := tool.Execute(other, map[string]interface{}{"action": "list"}); !strings.Contains(out, "No messages") {
+		t.Errorf("other chat lists %q", out)
+	}
+	id := scheduler.ListMessages()[0].ID
+	if _, err := tool.Execute(other, map[string]interface{}{"action": "cancel", "id": id}); err == nil {
+		t.Error("other chat cancelled a message")
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "cancel", "id": id}); err != nil {
+		t.Errorf("cancel: %v", err)
+	}
+	if out, _ := tool.Execute(ctx, map[string]interface{}{"action": "list"}); strings.Count(out, "- ID:") != 3 {
+		t.Errorf("list after cancelling = %q", out)
+	}
+}
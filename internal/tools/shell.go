@@ -6,20 +6,34 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/hkuds/ubot/internal/failure"
+	"github.com/hkuds/ubot/internal/sandbox"
 )
 
 // Default configuration values for ExecTool.
 const (
 	DefaultExecTimeout = 60 * time.Second
-	MaxOutputLength    = 10000
+	MaxOutputLength    = MaxResultChars
 )
 
+// execProgressEvery is how often a running command tells whoever is
+// waiting for it what it printed last.
+var execProgressEvery = 30 * time.Second
+
+// progressLineMax is the most of a command's last output line shown in a
+// progress report, in bytes.
+const progressLineMax = 120
+
 // ErrBlockedCommand is returned when a command matches a blocked pattern.
 type ErrBlockedCommand struct {
 	Command string
@@ -30,6 +44,11 @@ func (e ErrBlockedCommand) Error() string {
 	return fmt.Sprintf("command blocked: %q matches dangerous pattern %q", e.Command, e.Pattern)
 }
 
+// Category marks the error as a blocked tool call.
+func (e ErrBlockedCommand) Category() failure.Category {
+	return failure.ToolBlocked
+}
+
 // ErrInvalidWorkingDir is returned when the working directory is invalid.
 type ErrInvalidWorkingDir struct {
 	Dir string
@@ -101,6 +120,21 @@ var blockedPatterns = []*regexp.Regexp{
 	regexp.MustCompile(`(?i)\breboot\b`),   // reboot
 	regexp.MustCompile(`(?i)\binit\s+0\b`), // init 0 (halt)
 	regexp.MustCompile(`(?i)\binit\s+6\b`), // init 6 (reboot)
+
+	// Windows and macOS
+	regexp.MustCompile(`(?i)\b(rd|rmdir|del|erase|remove-item|ri)\b.*\s["']?[a-z]:\\?\*?["']?(\s|$)`),                     // delete a drive root
+	regexp.MustCompile(`(?i)\b(rd|rmdir|del|erase|remove-item|ri)\b.*[a-z]:\\windows\b`),                                  // delete the Windows directory
+	regexp.MustCompile(`(?i)\bformat(\.com)?\s+[a-z]:`),                                                                   // format a drive
+	regexp.MustCompile(`(?i)\b(format-volume|clear-disk|initialize-disk|remove-partition)\b`),                             // PowerShell disk formatting
+	regexp.MustCompile(`(?i)\breg(\.exe)?\s+delete\b`),                                                                    // reg delete (registry deletion)
+	regexp.MustCompile(`(?i)\bbcdedit\b`),                                                                                 // bcdedit (boot configuration)
+	regexp.MustCompile(`(?i)\bvssadmin\s+delete\b`),                                                                       // delete shadow copies
+	regexp.MustCompile(`(?i)\bcipher\s+/w`),                                                                               // cipher /w (wipe free space)
+	regexp.MustCompile(`(?i)\b(stop-computer|restart-computer)\b`),                                                        // PowerShell shutdown/restart
+	regexp.MustCompile(`(?i)\b(iwr|irm|invoke-webrequest|invoke-restmethod|curl|wget)\b.*\|\s*(iex|invoke-expression)\b`), // download | iex (remote code execution)
+	regexp.MustCompile(`(?i)\bdiskutil\s+(erase\w*|zerodisk|randomdisk|secureerase|partitiondisk|reformat)`),              // diskutil erase (macOS)
+	regexp.MustCompile(`(?i)\bcsrutil\s+disable\b`),                                                                       // disable System Integrity Protection
+	regexp.MustCompile(`(?i)>\s*/dev/r?disk\d`),                                                                           // redirect to macOS disk
 }
 
 // ExecTool executes shell commands safely with timeout and safety checks.
@@ -112,6 +146,7 @@ type ExecTool struct {
 	allowedShells       []string
 	blockedPatterns     []*regexp.Regexp
 	maxOutputLength     int
+	env                 *SessionEnv // per-conversation variables; nil = none
 }
 
 // NewExecTool creates a new ExecTool with default configuration.
@@ -149,11 +184,17 @@ func NewExecToolWithOptions(timeout time.Duration, workingDir string, restrictTo
 		Timeout:             timeout,
 		WorkingDir:          workingDir,
 		RestrictToWorkspace: restrictToWorkspace,
-		allowedShells:       []string{"sh", "bash", "zsh"},
+		allowedShells:       sandbox.DefaultShells(runtime.GOOS),
 		maxOutputLength:     MaxOutputLength,
 	}
 }
 
+// SetSessionEnv passes the variables stored for the calling conversation
+// to every command and hides their values in the output.
+func (t *ExecTool) SetSessionEnv(env *SessionEnv) {
+	t.env = env
+}
+
 // Execute runs the shell command with safety checks and timeout.
 func (t *ExecTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
 	// Extract command parameter (required)
@@ -196,7 +237,7 @@ func (t *ExecTool) Execute(ctx context.Context, params map[string]interface{}) (
 	if t.RestrictToWorkspace && t.WorkingDir != "" && workingDir != "" {
 		absWorkspace, _ := filepath.Abs(t.WorkingDir)
 		absWorkDir, _ := filepath.Abs(workingDir)
-		if !strings.HasPrefix(absWorkDir, absWorkspace) {
+		if rel, err := filepath.Rel(absWorkspace, absWorkDir); err != nil || !filepath.IsLocal(rel) {
 			return "", fmt.Errorf("exec: working directory %q is outside workspace %q", workingDir, t.WorkingDir)
 		}
 	}
@@ -254,20 +295,42 @@ func (t *ExecTool) executeCommand(ctx context.Context, command, workingDir strin
 	}
 
 	// Create the command
-	cmd := exec.CommandContext(execCtx, shell, "-c", command)
+	cmd := sandbox.ShellCmd(execCtx, shell, command)
 
 	// Set working directory if provided
 	if workingDir != "" {
 		cmd.Dir = workingDir
 	}
 
-	// Capture stdout and stderr
+	// Add the conversation's variables
+	sessionKey := ""
+	if req, ok := RequestFromContext(ctx); ok && t.env != nil {
+		sessionKey = req.SessionKey
+		if vars := t.env.Environ(sessionKey); len(vars) > 0 {
+			cmd.Env = append(os.Environ(), vars...)
+		}
+	}
+
+	// Capture stdout and stderr, keeping the latest output for progress
+	// reports
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	tail := &outputTail{}
+	cmd.Stdout = io.MultiWriter(&stdout, tail)
+	cmd.Stderr = io.MultiWriter(&stderr, tail)
 
 	// Run the command
+	done := make(chan struct{})
+	go t.reportProgress(ctx, command, sessionKey, tail, done)
+	start := time.Now()
 	err := cmd.Run()
+	close(done)
+
+	// Measure what it used
+	usage := sandbox.ProcessUsage(cmd.ProcessState, time.Since(start))
+	if execCtx.Err() == context.DeadlineExceeded {
+		usage.Stopped = sandbox.StoppedTimeout
+	}
+	RecordUsage(ctx, usage)
 
 	// Get exit code
 	exitCode := 0
@@ -280,6 +343,13 @@ func (t *ExecTool) executeCommand(ctx context.Context, command, workingDir strin
 
 	// Build the output
 	output := t.buildOutput(stdout.String(), stderr.String(), exitCode)
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+	output += "[resources: " + usage.String() + "]"
+	if sessionKey != "" {
+		output = t.env.Redact(sessionKey, output)
+	}
 
 	// Check for context cancellation (timeout)
 	if execCtx.Err() == context.DeadlineExceeded {
@@ -305,6 +375,69 @@ func (t *ExecTool) executeCommand(ctx context.Context, command, workingDir strin
 	return output, nil
 }
 
+// reportProgress tells whoever is waiting for command how long it has
+// been running and what it printed last, every execProgressEvery until
+// done is closed.
+func (t *ExecTool) reportProgress(ctx context.Context, command, sessionKey string, tail *outputTail, done <-chan struct{}) {
+	shown := truncateRunes(command, 60)
+	if len(shown) < len(command) {
+		shown += "..."
+	}
+	started := time.Now()
+	ticker := time.NewTicker(execProgressEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		status := fmt.Sprintf("Still running `%s` (%s)", shown, time.Since(started).Round(time.Second))
+		if line := tail.lastLine(); line != "" {
+			if sessionKey != "" {
+				line = t.env.Redact(sessionKey, line)
+			}
+			status += ", last output: " + truncateRunes(line, progressLineMax)
+		} else {
+			status += ", no output yet"
+		}
+		ReportProgress(ctx, status)
+	}
+}
+
+// outputTail keeps the end of a command's output.
+type outputTail struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+// outputTailSize is how much output an outputTail keeps, in bytes.
+const outputTailSize = 1024
+
+func (o *outputTail) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, p...)
+	if len(o.buf) > outputTailSize {
+		o.buf = append(o.buf[:0], o.buf[len(o.buf)-outputTailSize:]...)
+	}
+	return len(p), nil
+}
+
+// lastLine returns the last line of output that is not blank. Progress bars
+// that redraw a line with carriage returns count as separate lines.
+func (o *outputTail) lastLine() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	lines := strings.FieldsFunc(string(o.buf), func(r rune) bool { return r == '\n' || r == '\r' })
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return strings.ToValidUTF8(line, "")
+		}
+	}
+	return ""
+}
+
 // findShell finds an available shell from the allowed list.
 func (t *ExecTool) findShell() string {
 	for _, shell := range t.allowedShells {
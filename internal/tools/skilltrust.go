@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SkillTrust records which skills a turn has read with read_skill. Skill
+// content often comes from third-party repositories, so it is treated as
+// untrusted: once a turn has read it, SecureRegistry applies its
+// SkillRestrictions to the rest of the turn, and instructions in a SKILL.md
+// cannot give the model more access than the user has.
+type SkillTrust struct {
+	mu     sync.Mutex
+	loaded []string
+}
+
+// NewSkillTrust creates an empty SkillTrust for one turn.
+func NewSkillTrust() *SkillTrust {
+	return &SkillTrust{}
+}
+
+// Mark records that the turn has read the skill called name.
+func (t *SkillTrust) Mark(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, n := range t.loaded {
+		if n == name {
+			return
+		}
+	}
+	t.loaded = append(t.loaded, name)
+}
+
+// Loaded returns the skills read in the turn, in the order they were read.
+func (t *SkillTrust) Loaded() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.loaded...)
+}
+
+type skillTrustKey struct{}
+
+// WithSkillTrust returns a context carrying the turn's SkillTrust.
+func WithSkillTrust(ctx context.Context, t *SkillTrust) context.Context {
+	return context.WithValue(ctx, skillTrustKey{}, t)
+}
+
+// SkillTrustFromContext returns the SkillTrust attached to ctx, if any.
+func SkillTrustFromContext(ctx context.Context) (*SkillTrust, bool) {
+	t, ok := ctx.Value(skillTrustKey{}).(*SkillTrust)
+	return t, ok
+}
+
+// SkillRestrictions tighten tool access for the rest of a turn once it has
+// read an untrusted skill.
+type SkillRestrictions struct {
+	Policies  map[string]string // per-tool policy; applied only where stricter than the usual one
+	Workspace string            // file tools may not leave this directory; "" = no limit
+	Trusted   []string          // skills whose content does not restrict the turn
+}
+
+// SetSkillRestrictions sets the restrictions applied after a turn reads an
+// untrusted skill. It must be set before the registry is used.
+func (s *SecureRegistry) SetSkillRestrictions(r SkillRestrictions) {
+	s.skillRestrictions = r
+	if r.Workspace != "" {
+		if resolved, err := resolvePath(r.Workspace); err == nil {
+			s.skillRestrictions.Workspace = resolved
+		}
+	}
+}
+
+// untrustedSkills returns the skills read in the turn of ctx that are not
+// trusted.
+func (s *SecureRegistry) untrustedSkills(ctx context.Context) []string {
+	t, ok := SkillTrustFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	var untrusted []string
+	for _, name := range t.Loaded() {
+		if !s.skillTrusted(name) {
+			untrusted = append(untrusted, name)
+		}
+	}
+	return untrusted
+}
+
+func (s *SecureRegistry) skillTrusted(name string) bool {
+	for _, trusted := range s.skillRestrictions.Trusted {
+		if trusted == name {
+			return true
+		}
+	}
+	return false
+}
+
+// markSkillRead records a successful read_skill call in the turn's
+// SkillTrust.
+func (s *SecureRegistry) markSkillRead(ctx context.Context, params map[string]interface{}) {
+	t, ok := SkillTrustFromContext(ctx)
+	if !ok {
+		return
+	}
+	if name, err := GetStringParam(params, "name"); err == nil {
+		t.Mark(strings.TrimSpace(name))
+	}
+}
+
+// validateSkillPath confines file tools to the workspace in a turn that has
+// read untrusted skills.
+func (s *SecureRegistry) validateSkillPath(pathStr string, untrusted []string) error {
+	workspace := s.skillRestrictions.Workspace
+	if workspace == "" {
+		return nil
+	}
+	resolved, err := resolvePath(pathStr)
+	if err != nil {
+		return fmt.Errorf("cannot resolve path %q: %w", pathStr, err)
+	}
+	if resolved == workspace || strings.HasPrefix(resolved, workspace+string(filepath.Separator)) {
+		return nil
+	}
+	return ErrBlockedPath{
+		Path:   pathStr,
+		Reason: "outside the workspace in a turn that read skill " + strings.Join(untrusted, ", "),
+	}
+}
+
+// skillPolicy returns the policy for a tool in a turn that read the
+// untrusted skills, with the reason when it is stricter than usual.
+func (s *SecureRegistry) skillPolicy(name, usual string, untrusted []string) (string, string) {
+	p, ok := s.skillRestrictions.Policies[name]
+	if !ok || len(untrusted) == 0 {
+		return usual, ""
+	}
+	rank := map[string]int{PolicyAuto: 0, PolicyAsk: 1, PolicyDeny: 2}
+	if p = normalizePolicy(p); rank[p] <= rank[usual] {
+		return usual, ""
+	}
+	return p, "this turn read skill " + strings.Join(untrusted, ", ")
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hkuds/ubot/internal/providers"
+)
+
+// maxJSONRepairs is how many times the model is asked to correct a reply
+// that does not match the schema.
+const maxJSONRepairs = 2
+
+// ChatJSON asks p to answer req with JSON matching schema and decodes the
+// answer into out. Models that ignore response_format are told the schema
+// in the system prompt too. A reply that is not JSON, or does not match
+// the schema, is sent back with the problems found until the model gets it
+// right, up to maxJSONRepairs times.
+func ChatJSON(ctx context.Context, p providers.Provider, req providers.ChatRequest, name string, schema map[string]interface{}, out interface{}) error {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("encode %s schema: %w", name, err)
+	}
+	req.ResponseFormat = &providers.ResponseFormat{Type: providers.FormatJSONSchema, Name: name, Schema: schema}
+	req.Messages = withJSONInstruction(req.Messages, "Answer only with JSON matching this JSON Schema, without any other text:\n"+string(schemaJSON))
+
+	for repair := 0; ; repair++ {
+		resp, err := p.Chat(ctx, req)
+		if err != nil {
+			return err
+		}
+		reply := trimCodeFence(resp.Content)
+		problems := jsonProblems(reply, schema)
+		if len(problems) == 0 {
+			if err := json.Unmarshal([]byte(reply), out); err != nil {
+				return fmt.Errorf("decode %s: %w", name, err)
+			}
+			return nil
+		}
+		if repair == maxJSONRepairs {
+			return fmt.Errorf("the model's %s does not match the schema: %s", name, strings.Join(problems, "; "))
+		}
+		req.Messages = append(req.Messages,
+			providers.ChatMessage{Role: "assistant", Content: resp.Content},
+			providers.ChatMessage{Role: "user", Content: "That answer is not valid: " + strings.Join(problems, "; ") +
+				". Reply again with only the corrected JSON."},
+		)
+	}
+}
+
+// withJSONInstruction returns messages with instruction added to the
+// system prompt, leaving messages itself unchanged.
+func withJSONInstruction(messages []providers.ChatMessage, instruction string) []providers.ChatMessage {
+	if len(messages) > 0 && messages[0].Role == "system" {
+		if text, ok := messages[0].Content.(string); ok {
+			out := append([]providers.ChatMessage(nil), messages...)
+			out[0].Content = text + "\n\n" + instruction
+			return out
+		}
+	}
+	return append([]providers.ChatMessage{{Role: "system", Content: instruction}}, messages...)
+}
+
+// trimCodeFence removes the Markdown code fence models like to put around
+// JSON.
+func trimCodeFence(reply string) string {
+	reply = strings.TrimSpace(reply)
+	if !strings.HasPrefix(reply, "```") {
+		return reply
+	}
+	if _, rest, ok := strings.Cut(reply, "\n"); ok {
+		reply = rest
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(reply), "```"))
+}
+
+// jsonProblems lists why reply is not JSON matching schema.
+func jsonProblems(reply string, schema map[string]interface{}) []string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(reply), &value); err != nil {
+		return []string{"not valid JSON: " + err.Error()}
+	}
+	if obj, ok := value.(map[string]interface{}); ok {
+		return ValidateParams(obj, schema)
+	}
+	return validateField("answer", value, schema)
+}
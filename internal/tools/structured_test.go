@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/providers"
+)
+
+// scriptedProvider answers with replies in turn, keeping the requests.
+type scriptedProvider struct {
+	replies  []string
+	requests []providers.ChatRequest
+}
+
+func (p *scriptedProvider) Name() string         { return "fake" }
+func (p *scriptedProvider) DefaultModel() string { return "m" }
+
+func (p *scriptedProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.requests = append(p.requests, req)
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return &providers.ChatResponse{Content: reply}, nil
+}
+
+func TestChatJSON(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"schedule": map[string]interface{}{"type": "string"},
+			"repeat":   map[string]interface{}{"type": "boolean"},
+		},
+		"required": []string{"schedule"},
+	}
+	var job struct {
+		Schedule string `json:"schedule"`
+		Repeat   bool   `json:"repeat"`
+	}
+	p := &scriptedProvider{replies: []string{
+		"Sure! Here it is: {",
+		`{"repeat": "yes"}`,
+		"```json\n{\"schedule\": \"0 9 * * *\", \"repeat\": true}\n```",
+	}}
+	req := providers.ChatRequest{Messages: []providers.ChatMessage{
+		{Role: "system", Content: "You parse schedules."},
+		{Role: "user", Content: "every day at 9"},
+	}}
+	if err := ChatJSON(context.Background(), p, req, "cron_job", schema, &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Schedule != "0 9 * * *" || !job.Repeat {
+		t.Errorf("job = %+v", job)
+	}
+
+	first := p.requests[0]
+	if f := first.ResponseFormat; f == nil || f.Type != providers.FormatJSONSchema || f.Name != "cron_job" {
+		t.Errorf("response format = %+v", f)
+	}
+	if system := first.Messages[0].Content.(string); !strings.HasPrefix(system, "You parse schedules.") || !strings.Contains(system, `"required":["schedule"]`) {
+		t.Errorf("system prompt = %q", system)
+	}
+	if req.Messages[0].Content != "You parse schedules." {
+		t.Error("caller's messages changed")
+	}
+	// Each correction names the problems with the last answer
+	last := p.requests[2].Messages
+	if fix := last[len(last)-1].Content.(string); !strings.Contains(fix, "missing required field: schedule") || !strings.Contains(fix, "field repeat: expected type boolean") {
+		t.Errorf("correction = %q", fix)
+	}
+
+	p = &scriptedProvider{replies: []string{"no", "no", "no"}}
+	if err := ChatJSON(context.Background(), p, req, "cron_job", schema, &job); err == nil || !strings.Contains(err.Error(), "not valid JSON") || len(p.requests) != 3 {
+		t.Errorf("after %d requests: %v", len(p.requests), err)
+	}
+}
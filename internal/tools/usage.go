@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/hkuds/ubot/internal/sandbox"
+)
+
+// usageSlot holds the resource usage a tool call recorded, if any.
+type usageSlot struct {
+	usage *sandbox.Usage
+}
+
+type usageKey struct{}
+
+// withUsageSlot returns a context in which tool calls can record the
+// resources they used into the returned slot.
+func withUsageSlot(ctx context.Context) (context.Context, *usageSlot) {
+	slot := &usageSlot{}
+	return context.WithValue(ctx, usageKey{}, slot), slot
+}
+
+// RecordUsage records the resources a command run by a tool call used, so
+// that the audit log can show them. It does nothing when the call is not
+// audited.
+func RecordUsage(ctx context.Context, u sandbox.Usage) {
+	if slot, ok := ctx.Value(usageKey{}).(*usageSlot); ok {
+		slot.usage = &u
+	}
+}
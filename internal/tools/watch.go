@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hkuds/ubot/internal/watch"
+)
+
+// WatchPathTool lets the agent ask to hear about changes to files and
+// directories. Changes arrive later as messages in the same conversation.
+type WatchPathTool struct {
+	BaseTool
+	watcher *watch.Watcher
+}
+
+// NewWatchPathTool creates a new WatchPathTool backed by watcher.
+func NewWatchPathTool(watcher *watch.Watcher) *WatchPathTool {
+	return &WatchPathTool{
+		BaseTool: NewBaseTool(
+			"watch_path",
+			"Watch a file or directory for changes, e.g. 'tell me when the build output changes'. When it changes, a message listing the changed files arrives in this conversation with your instruction, and you act on it then. Use 'add' to start watching, 'remove' to stop by ID, 'list' to see this conversation's watches.",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"add", "remove", "list"},
+						"description": "The action to perform: add, remove, or list.",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "The file or directory to watch. Supports ~ for home directory. Required for 'add'.",
+					},
+					"instruction": map[string]interface{}{
+						"type":        "string",
+						"description": "What to do when it changes, e.g. 'tell the user whether the build succeeded'. Required for 'add'.",
+					},
+					"recursive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also watch the directories inside a directory.",
+					},
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "Only report files whose names match this glob, e.g. '*.log'.",
+					},
+					"watch_id": map[string]interface{}{
+						"type":        "string",
+						"description": "The watch ID to remove. Required for 'remove'.",
+					},
+				},
+				"required": []string{"action"},
+			},
+		),
+		watcher: watcher,
+	}
+}
+
+// Execute runs the watch_path tool action.
+func (t *WatchPathTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	action, err := GetStringParam(params, "action")
+	if err != nil {
+		return "", fmt.Errorf("watch_path: %w", err)
+	}
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.SessionKey == "" {
+		return "", errors.New("watch_path: no active conversation")
+	}
+
+	switch action {
+	case "add":
+		return t.add(req, params)
+	case "remove":
+		id, err := GetStringParam(params, "watch_id")
+		if err != nil {
+			return "", fmt.Errorf("watch_path remove: %w", err)
+		}
+		if err := t.watcher.Remove(req.SessionKey, id); err != nil {
+			return "", fmt.Errorf("watch_path remove: %w", err)
+		}
+		return fmt.Sprintf("Watch %s removed.", id), nil
+	case "list":
+		return t.list(req), nil
+	default:
+		return "", fmt.Errorf("watch_path: unknown action %q (use add, remove, or list)", action)
+	}
+}
+
+func (t *WatchPathTool) add(req RequestInfo, params map[string]interface{}) (string, error) {
+	path, err := GetStringParam(params, "path")
+	if err != nil {
+		return "", fmt.Errorf("watch_path add: %w", err)
+	}
+	instruction, err := GetStringParam(params, "instruction")
+	if err != nil || strings.TrimSpace(instruction) == "" {
+		return "", errors.New("watch_path add: instruction is required")
+	}
+	expanded, err := expandPath(path)
+	if err != nil {
+		return "", fmt.Errorf("watch_path add: %w", err)
+	}
+
+	w, err := t.watcher.Add(watch.Watch{
+		Path:        expanded,
+		Recursive:   GetBoolParamOr(params, "recursive", false),
+		Pattern:     GetStringParamOr(params, "pattern", ""),
+		Instruction: instruction,
+		Channel:     req.Channel,
+		ChatID:      req.ChatID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("watch_path add: %w", err)
+	}
+	return fmt.Sprintf("Watching %s (ID: %s). Changes will arrive in this conversation as messages.", w.Path, w.ID), nil
+}
+
+func (t *WatchPathTool) list(req RequestInfo) string {
+	watches := t.watcher.List(req.SessionKey)
+	if len(watches) == 0 {
+		return "No active watches."
+	}
+	var sb strings.Builder
+	sb.WriteString("Active watches:\n\n")
+	for _, w := range watches {
+		var opts []string
+		if w.Recursive {
+			opts = append(opts, "recursive")
+		}
+		if w.Pattern != "" {
+			opts = append(opts, "pattern "+w.Pattern)
+		}
+		line := fmt.Sprintf("- ID: %s | Path: %s", w.ID, w.Path)
+		if len(opts) > 0 {
+			line += " | " + strings.Join(opts, ", ")
+		}
+		sb.WriteString(fmt.Sprintf("%s\n  Instruction: %s\n", line, w.Instruction))
+	}
+	return sb.String()
+}
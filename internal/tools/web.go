@@ -2,12 +2,11 @@
 package tools
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -15,14 +14,16 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/safenet"
 )
 
-// WebSearchTool searches the web using Brave Search API.
+// WebSearchTool searches the web through a SearchProvider.
 type WebSearchTool struct {
 	BaseTool
-	apiKey     string
+	provider   SearchProvider
 	maxResults int
-	client     *http.Client
 }
 
 // BraveSearchResult represents a single search result from Brave API.
@@ -43,8 +44,9 @@ type BraveSearchResponse struct {
 	} `json:"web"`
 }
 
-// NewWebSearchTool creates a new WebSearchTool with the given API key and max results.
-func NewWebSearchTool(apiKey string, maxResults int) *WebSearchTool {
+// NewWebSearchTool creates a new WebSearchTool that searches with provider
+// and returns up to maxResults results by default.
+func NewWebSearchTool(provider SearchProvider, maxResults int) *WebSearchTool {
 	if maxResults <= 0 {
 		maxResults = 5
 	}
@@ -73,30 +75,26 @@ func NewWebSearchTool(apiKey string, maxResults int) *WebSearchTool {
 	return &WebSearchTool{
 		BaseTool: NewBaseTool(
 			"web_search",
-			"Search the web using Brave Search API. Returns formatted results with title, URL, and description.",
+			fmt.Sprintf("Search the web using %s. Returns formatted results with title, URL, and description.", provider.Name()),
 			parameters,
 		),
-		apiKey:     apiKey,
+		provider:   provider,
 		maxResults: maxResults,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
 	}
 }
 
-// NewWebSearchToolFromEnv creates a new WebSearchTool using the BRAVE_API_KEY environment variable.
+// NewWebSearchToolFromEnv creates a new WebSearchTool using Brave Search
+// with the BRAVE_API_KEY environment variable.
 func NewWebSearchToolFromEnv(maxResults int) *WebSearchTool {
-	apiKey := os.Getenv("BRAVE_API_KEY")
-	return NewWebSearchTool(apiKey, maxResults)
+	provider, _ := NewSearchProvider(config.WebSearchConfig{
+		Provider: config.SearchBrave,
+		APIKey:   os.Getenv("BRAVE_API_KEY"),
+	})
+	return NewWebSearchTool(provider, maxResults)
 }
 
 // Execute performs the web search with the given parameters.
 func (t *WebSearchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-	// Check if API key is configured
-	if t.apiKey == "" {
-		return "", errors.New("web_search: Brave Search API key not configured (set BRAVE_API_KEY environment variable)")
-	}
-
 	query, err := GetStringParam(params, "query")
 	if err != nil {
 		return "", fmt.Errorf("web_search: %w", err)
@@ -114,51 +112,20 @@ func (t *WebSearchTool) Execute(ctx context.Context, params map[string]interface
 		count = 10
 	}
 
-	// Build request URL
-	searchURL := fmt.Sprintf(
-		"https://api.search.brave.com/res/v1/web/search?q=%s&count=%d",
-		url.QueryEscape(query),
-		count,
-	)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
+	results, err := t.provider.Search(ctx, query, count)
 	if err != nil {
-		return "", fmt.Errorf("web_search: failed to create request: %w", err)
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-Subscription-Token", t.apiKey)
-
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("web_search: request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("web_search: API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("web_search: failed to read response: %w", err)
-	}
-
-	var braveResp BraveSearchResponse
-	if err := json.Unmarshal(body, &braveResp); err != nil {
-		return "", fmt.Errorf("web_search: failed to parse response: %w", err)
+		return "", fmt.Errorf("web_search: %s: %w", t.provider.Name(), err)
 	}
 
 	// Format results
-	if len(braveResp.Web.Results) == 0 {
+	if len(results) == 0 {
 		return "No results found for the query.", nil
 	}
 
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("Search results for %q:\n\n", query))
 
-	for i, r := range braveResp.Web.Results {
+	for i, r := range results {
 		result.WriteString(fmt.Sprintf("%d. %s\n", i+1, r.Title))
 		result.WriteString(fmt.Sprintf("   URL: %s\n", r.URL))
 		if r.Description != "" {
@@ -170,16 +137,24 @@ func (t *WebSearchTool) Execute(ctx context.Context, params map[string]interface
 	return result.String(), nil
 }
 
-// SetAPIKey updates the API key.
-func (t *WebSearchTool) SetAPIKey(apiKey string) {
-	t.apiKey = apiKey
-}
-
 // WebFetchTool fetches and parses web pages.
 type WebFetchTool struct {
 	BaseTool
 	maxChars int
 	client   *http.Client
+	polite   *Politeness
+	renderer PageRenderer // runs pages' JavaScript for render=true; nil = never
+	// blocked reports URLs that must not be fetched; tests allow local
+	// servers.
+	blocked func(string) bool
+}
+
+// PageRenderer loads pages in a browser, so that content built by their
+// JavaScript can be extracted.
+type PageRenderer interface {
+	// Render loads url and returns the HTML of the page once it has
+	// settled, and the URL it ended up at.
+	Render(ctx context.Context, url string) (html string, finalURL string, err error)
 }
 
 // WebFetchResult represents the result of fetching a web page.
@@ -188,8 +163,11 @@ type WebFetchResult struct {
 	FinalURL  string `json:"final_url"`
 	Status    int    `json:"status"`
 	Title     string `json:"title,omitempty"`
+	Byline    string `json:"byline,omitempty"`
+	Published string `json:"published,omitempty"`
 	Content   string `json:"content"`
 	Truncated bool   `json:"truncated,omitempty"`
+	Note      string `json:"note,omitempty"`
 }
 
 // NewWebFetchTool creates a new WebFetchTool with the given max characters limit.
@@ -207,10 +185,15 @@ func NewWebFetchTool(maxChars int) *WebFetchTool {
 			},
 			"extract_mode": map[string]interface{}{
 				"type":        "string",
-				"description": "Extraction mode: 'markdown' for structured content, 'text' for plain text, 'raw' for raw HTML",
-				"enum":        []string{"markdown", "text", "raw"},
+				"description": "Extraction mode: 'markdown' for structured content, 'text' for plain text, 'raw' for raw HTML, 'article' for only the main article of a news or blog page, with its author and date",
+				"enum":        []string{"markdown", "text", "raw", "article"},
 				"default":     "markdown",
 			},
+			"render": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Load the page in headless Chrome and run its JavaScript first, for sites that return an empty shell without it. Slower; falls back to a plain fetch when Chrome is not available.",
+				"default":     false,
+			},
 		},
 		"required": []string{"url"},
 	}
@@ -218,12 +201,13 @@ func NewWebFetchTool(maxChars int) *WebFetchTool {
 	return &WebFetchTool{
 		BaseTool: NewBaseTool(
 			"web_fetch",
-			"Fetch and parse web pages. Extracts main content from HTML pages and returns as markdown, plain text, or raw HTML.",
+			"Fetch and parse web pages. Extracts main content from HTML pages and returns as markdown, plain text, or raw HTML, or just the article of a news or blog page.",
 			parameters,
 		),
 		maxChars: maxChars,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: safenet.NewTransport(),
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				if len(via) >= 5 {
 					return errors.New("too many redirects (max 5)")
@@ -231,9 +215,16 @@ func NewWebFetchTool(maxChars int) *WebFetchTool {
 				return nil
 			},
 		},
+		polite:  sharedPoliteness,
+		blocked: safenet.IsInternalURL,
 	}
 }
 
+// SetRenderer makes render=true load pages with r.
+func (t *WebFetchTool) SetRenderer(r PageRenderer) {
+	t.renderer = r
+}
+
 // Execute fetches and parses the web page with the given parameters.
 func (t *WebFetchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
 	rawURL, err := GetStringParam(params, "url")
@@ -252,15 +243,34 @@ func (t *WebFetchTool) Execute(ctx context.Context, params map[string]interface{
 	}
 
 	// SSRF protection: block requests to internal/private network addresses
-	if isInternalURL(rawURL) {
+	if t.blocked != nil && t.blocked(rawURL) {
 		return "", errors.New("web_fetch: access to internal/private network addresses is blocked")
 	}
 
 	extractMode := GetStringParamOr(params, "extract_mode", "markdown")
-	if extractMode != "markdown" && extractMode != "text" && extractMode != "raw" {
+	if extractMode != "markdown" && extractMode != "text" && extractMode != "raw" && extractMode != "article" {
 		extractMode = "markdown"
 	}
 
+	note := ""
+	if render, _ := params["render"].(bool); render {
+		if t.renderer == nil {
+			note = "Rendering is not available in this build; fetched without JavaScript."
+		} else {
+			output, err := t.render(ctx, rawURL, extractMode)
+			if err == nil {
+				return output, nil
+			}
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("web_fetch: %w", ctx.Err())
+			}
+			if errors.Is(err, errNotLoaded) {
+				return "", fmt.Errorf("web_fetch: %w", err)
+			}
+			note = fmt.Sprintf("Rendering failed (%v); fetched without JavaScript.", err)
+		}
+	}
+
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
@@ -270,7 +280,7 @@ func (t *WebFetchTool) Execute(ctx context.Context, params map[string]interface{
 	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; uBot/1.0)")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 
-	resp, err := t.client.Do(req)
+	resp, err := t.polite.Do(t.client, req)
 	if err != nil {
 		return "", fmt.Errorf("web_fetch: request failed: %w", err)
 	}
@@ -280,6 +290,7 @@ func (t *WebFetchTool) Execute(ctx context.Context, params map[string]interface{
 		URL:      rawURL,
 		FinalURL: resp.Request.URL.String(),
 		Status:   resp.StatusCode,
+		Note:     note,
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -300,13 +311,9 @@ func (t *WebFetchTool) Execute(ctx context.Context, params map[string]interface{
 			result.Truncated = true
 		}
 	} else if strings.Contains(contentType, "text/html") || strings.Contains(contentType, "application/xhtml") {
-		content, title, err := extractHTMLContent(resp.Body, extractMode)
-		if err != nil {
+		if err := t.extract(&result, resp.Body, extractMode); err != nil {
 			return "", fmt.Errorf("web_fetch: failed to extract content: %w", err)
 		}
-		result.Title = title
-		result.Content = truncateText(content, t.maxChars)
-		result.Truncated = len(content) > t.maxChars
 	} else {
 		// For non-HTML content, just read the body
 		body, err := io.ReadAll(io.LimitReader(resp.Body, int64(t.maxChars)))
@@ -319,22 +326,108 @@ func (t *WebFetchTool) Execute(ctx context.Context, params map[string]interface{
 		}
 	}
 
-	// Format output
+	return result.format(), nil
+}
+
+// render loads rawURL with the renderer, within the site's limits, and
+// extracts its content like a fetched page.
+func (t *WebFetchTool) render(ctx context.Context, rawURL, extractMode string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	release, err := t.polite.Wait(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errNotLoaded, err)
+	}
+	html, finalURL, err := t.renderer.Render(ctx, rawURL)
+	release()
+	if err != nil {
+		return "", err
+	}
+	// Chrome follows redirects on its own, so where it ended up is checked
+	// afterwards
+	if t.blocked != nil && finalURL != "" && t.blocked(finalURL) {
+		return "", fmt.Errorf("%w: redirected to %s: %w", errNotLoaded, finalURL, safenet.ErrBlocked)
+	}
+
+	result := WebFetchResult{URL: rawURL, FinalURL: finalURL, Status: http.StatusOK}
+	if finalURL == "" {
+		result.FinalURL = rawURL
+	}
+	if extractMode == "raw" {
+		result.Content = truncateText(html, t.maxChars)
+		result.Truncated = len(html) > t.maxChars
+		return result.format(), nil
+	}
+	if err := t.extract(&result, strings.NewReader(html), extractMode); err != nil {
+		return "", fmt.Errorf("failed to extract content: %w", err)
+	}
+	return result.format(), nil
+}
+
+// extract fills result with the content of the HTML page r. In article
+// mode, a page without an article is extracted as markdown.
+func (t *WebFetchTool) extract(result *WebFetchResult, r io.Reader, mode string) error {
+	var content, title string
+	if mode == "article" {
+		page, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+		if err != nil {
+			return err
+		}
+		if a, ok := extractArticle(doc); ok {
+			content, title = a.Content, a.Title
+			result.Byline, result.Published = a.Byline, a.Published
+		} else {
+			result.Note = strings.TrimSpace(result.Note + " No article found; extracted the whole page.")
+			r, mode = bytes.NewReader(page), "markdown"
+		}
+	}
+	if mode != "article" {
+		var err error
+		if content, title, err = extractHTMLContent(r, mode); err != nil {
+			return err
+		}
+	}
+	result.Title = title
+	result.Content = truncateText(content, t.maxChars)
+	result.Truncated = len(content) > t.maxChars
+	return nil
+}
+
+// errNotLoaded marks a page that must not be loaded at all, because of the
+// politeness limits or where it leads; a plain fetch would be refused too.
+var errNotLoaded = errors.New("not loaded")
+
+// format returns the result as shown to the model.
+func (r WebFetchResult) format() string {
 	var output strings.Builder
-	output.WriteString(fmt.Sprintf("URL: %s\n", result.URL))
-	if result.FinalURL != result.URL {
-		output.WriteString(fmt.Sprintf("Redirected to: %s\n", result.FinalURL))
+	output.WriteString(fmt.Sprintf("URL: %s\n", r.URL))
+	if r.FinalURL != r.URL {
+		output.WriteString(fmt.Sprintf("Redirected to: %s\n", r.FinalURL))
+	}
+	if r.Title != "" {
+		output.WriteString(fmt.Sprintf("Title: %s\n", r.Title))
+	}
+	if r.Byline != "" {
+		output.WriteString(fmt.Sprintf("Byline: %s\n", r.Byline))
 	}
-	if result.Title != "" {
-		output.WriteString(fmt.Sprintf("Title: %s\n", result.Title))
+	if r.Published != "" {
+		output.WriteString(fmt.Sprintf("Published: %s\n", r.Published))
+	}
+	if r.Note != "" {
+		output.WriteString(fmt.Sprintf("Note: %s\n", r.Note))
 	}
 	output.WriteString("\n")
-	output.WriteString(result.Content)
-	if result.Truncated {
+	output.WriteString(r.Content)
+	if r.Truncated {
 		output.WriteString("\n\n[Content truncated]")
 	}
-
-	return output.String(), nil
+	return output.String()
 }
 
 // extractHTMLContent extracts main content from HTML using goquery.
@@ -560,38 +653,3 @@ func truncateText(text string, maxChars int) string {
 	}
 	return truncated + "..."
 }
-
-// isInternalURL checks whether a URL targets an internal or private network address.
-// It returns true if the resolved IP is loopback, private, link-local, or a known
-// cloud metadata address (169.254.169.254).
-func isInternalURL(rawURL string) bool {
-	parsed, err := url.Parse(rawURL)
-	if err != nil {
-		return true
-	}
-
-	hostname := parsed.Hostname()
-	if hostname == "" {
-		return true
-	}
-
-	ips, err := net.LookupIP(hostname)
-	if err != nil {
-		// If DNS resolution fails, allow the request — it will fail at HTTP level
-		// with a clearer error. Blocking here would break fetch in no-DNS environments.
-		return false
-	}
-
-	cloudMetadataIP := net.ParseIP("169.254.169.254")
-
-	for _, ip := range ips {
-		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
-			return true
-		}
-		if ip.Equal(cloudMetadataIP) {
-			return true
-		}
-	}
-
-	return false
-}
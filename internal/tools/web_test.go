@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/safenet"
+)
+
+type fakeRenderer struct {
+	html     string
+	finalURL string // default: the URL asked for
+	err      error
+}
+
+func (r fakeRenderer) Render(ctx context.Context, url string) (string, string, error) {
+	if r.finalURL != "" {
+		url = r.finalURL
+	}
+	return r.html, url, r.err
+}
+
+func TestWebFetchRender(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>App</title></head><body><div id="root"></div><noscript>Enable JavaScript</noscript></body></html>`))
+	}))
+	defer srv.Close()
+
+	newTool := func(r PageRenderer) *WebFetchTool {
+		tool := NewWebFetchTool(0)
+		tool.client = srv.Client()
+		tool.polite = NewPoliteness(config.WebPolitenessConfig{})
+		tool.blocked = nil
+		if r != nil {
+			tool.SetRenderer(r)
+		}
+		return tool
+	}
+	fetch := func(tool *WebFetchTool, render bool) string {
+		out, err := tool.Execute(context.Background(), map[string]interface{}{"url": srv.URL, "render": render})
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		return out
+	}
+
+	rendered := fakeRenderer{html: `<html><head><title>App</title></head><body><article>Built by script</article></body></html>`}
+	if out := fetch(newTool(rendered), true); !strings.Contains(out, "Built by script") {
+		t.Errorf("rendered fetch = %q", out)
+	}
+	if out := fetch(newTool(rendered), false); strings.Contains(out, "Built by script") {
+		t.Errorf("page rendered without render=true: %q", out)
+	}
+
+	out := fetch(newTool(fakeRenderer{err: errors.New("no Chrome or Chromium binary found")}), true)
+	if !strings.Contains(out, "Title: App") || !strings.Contains(out, "Rendering failed (no Chrome") {
+		t.Errorf("fallback fetch = %q", out)
+	}
+	if out := fetch(newTool(nil), true); !strings.Contains(out, "Rendering is not available") {
+		t.Errorf("fetch without renderer = %q", out)
+	}
+
+	// Chrome followed a redirect to an internal address
+	tool := newTool(fakeRenderer{html: rendered.html, finalURL: "http://169.254.169.254/latest/meta-data/"})
+	tool.blocked = safenet.IsInternalURL
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"url": "https://app.example/", "render": true})
+	if !errors.Is(err, safenet.ErrBlocked) {
+		t.Errorf("render redirected internally: err = %v", err)
+	}
+}
+
+func TestWebFetchArticle(t *testing.T) {
+	page := newsPage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+	tool := NewWebFetchTool(0)
+	tool.client = srv.Client()
+	tool.polite = NewPoliteness(config.WebPolitenessConfig{})
+	tool.blocked = nil
+
+	out, err := tool.Execute(context.Background(), map[string]interface{}{"url": srv.URL, "extract_mode": "article"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Title: Rivers rise after storm\n", "Byline: Ann Lee, Bo Chen\n", "Published: 2026-03-14\n", "highest level in forty years"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Most read") {
+		t.Errorf("output has the sidebar:\n%s", out)
+	}
+
+	// Pages without an article are extracted whole
+	page = `<html><head><title>Login</title></head><body><main><p>Sign in to continue</p></main></body></html>`
+	out, err = tool.Execute(context.Background(), map[string]interface{}{"url": srv.URL, "extract_mode": "article"})
+	if err != nil || !strings.Contains(out, "Note: No article found") || !strings.Contains(out, "Sign in to continue") {
+		t.Errorf("output = %q, %v", out, err)
+	}
+}
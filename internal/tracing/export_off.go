@@ -0,0 +1,15 @@
+//go:build lite || notracing
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+// Setup reports that trace export is not compiled into this build.
+func Setup(ctx context.Context, cfg config.TracingConfig, version string) (func(context.Context) error, error) {
+	return nil, fmt.Errorf("tracing is not included in this build")
+}
@@ -0,0 +1,32 @@
+//go:build !lite && !notracing
+
+package tracing
+
+import (
+	"testing"
+
+	"github.com/hkuds/ubot/internal/config"
+)
+
+func TestExportEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	tests := map[string]string{
+		"":                                   config.DefaultTracingEndpoint,
+		"http://jaeger:4318":                 "http://jaeger:4318/v1/traces",
+		"https://otlp.example.com/":          "https://otlp.example.com/v1/traces",
+		"https://otlp.example.com/v1/traces": "https://otlp.example.com/v1/traces",
+		"https://otlp.example.com/custom":    "https://otlp.example.com/custom",
+	}
+	for endpoint, want := range tests {
+		if got := exportEndpoint(endpoint); got != want {
+			t.Errorf("exportEndpoint(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+
+	// The standard variables choose the endpoint when none is configured
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+	if got := exportEndpoint(""); got != "" {
+		t.Errorf("exportEndpoint with OTEL_EXPORTER_OTLP_ENDPOINT = %q, want empty", got)
+	}
+}
@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/hkuds/ubot/internal/providers"
+)
+
+// provider records a span for each chat request.
+type provider struct {
+	providers.Provider
+}
+
+// WrapProvider returns a Provider that records every chat request made
+// through p as a span, with the model and the tokens used.
+func WrapProvider(p providers.Provider) providers.Provider {
+	return &provider{Provider: p}
+}
+
+// Chat forwards the request within a span.
+func (p *provider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.DefaultModel()
+	}
+	ctx, span := Start(ctx, "chat "+model,
+		attribute.String("gen_ai.system", p.Name()),
+		attribute.String("gen_ai.request.model", model),
+		attribute.Int("ubot.messages", len(req.Messages)),
+	)
+	resp, err := p.Provider.Chat(ctx, req)
+	if resp != nil {
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", resp.Usage.PromptTokens),
+			attribute.Int("gen_ai.usage.output_tokens", resp.Usage.CompletionTokens),
+			attribute.Int("ubot.tool_calls", len(resp.ToolCalls)),
+		)
+	}
+	End(span, err)
+	return resp, err
+}
+
+// Unwrap returns the traced provider.
+func (p *provider) Unwrap() providers.Provider {
+	return p.Provider
+}
@@ -0,0 +1,60 @@
+// Package tracing records OpenTelemetry spans along the path of a message:
+// the channel receiving it, the agent turn, each model and tool call, and
+// the reply being sent. The trace context travels with bus messages, so a
+// clustered poller and worker contribute to the same trace.
+//
+// Spans are recorded only after Setup installs an exporter; until then every
+// function here is a cheap no-op.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation names the tracer that uBot's spans come from.
+const instrumentation = "github.com/hkuds/ubot"
+
+// propagator carries the trace context in bus messages as W3C traceparent
+// and tracestate entries.
+var propagator = propagation.TraceContext{}
+
+// Start starts a span called name as a child of the span in ctx, if any.
+// The caller must end it, usually with End.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed if err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of ctx to be sent along with a message,
+// or nil when ctx holds no span being recorded.
+func Inject(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract returns ctx with the trace context carried by a message, so that
+// spans started from it continue the message's trace.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier(carrier))
+}
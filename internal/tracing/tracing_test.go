@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/hkuds/ubot/internal/providers"
+)
+
+// fakeProvider answers every request with a fixed response or error.
+type fakeProvider struct {
+	resp *providers.ChatResponse
+	err  error
+}
+
+func (f *fakeProvider) Name() string         { return "fake" }
+func (f *fakeProvider) DefaultModel() string { return "fake-model" }
+func (f *fakeProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	return f.resp, f.err
+}
+
+// recordSpans installs a tracer provider that keeps finished spans in
+// memory for the rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return recorder
+}
+
+func TestInjectWithoutSpan(t *testing.T) {
+	if carrier := Inject(context.Background()); carrier != nil {
+		t.Errorf("Inject = %v, want nil without a span", carrier)
+	}
+	ctx := context.Background()
+	if Extract(ctx, nil) != ctx {
+		t.Error("Extract with no carrier should return ctx unchanged")
+	}
+}
+
+func TestTraceFollowsMessage(t *testing.T) {
+	recorder := recordSpans(t)
+
+	// A channel receives the message and sends its trace context along
+	ctx, receive := Start(context.Background(), "receive telegram")
+	carrier := Inject(ctx)
+	receive.End()
+	if carrier["traceparent"] == "" {
+		t.Fatalf("carrier = %v, want a traceparent", carrier)
+	}
+
+	// The agent continues it and calls the model
+	ctx, turn := Start(Extract(context.Background(), carrier), "handle message")
+	p := WrapProvider(&fakeProvider{resp: &providers.ChatResponse{
+		Usage: providers.Usage{PromptTokens: 120, CompletionTokens: 30},
+	}})
+	if _, err := p.Chat(ctx, providers.ChatRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	failing := WrapProvider(&fakeProvider{err: errors.New("rate limited")})
+	if _, err := failing.Chat(ctx, providers.ChatRequest{Model: "other"}); err == nil {
+		t.Fatal("expected the provider's error")
+	}
+	turn.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("recorded %d spans, want 4", len(spans))
+	}
+	traceID := spans[0].SpanContext().TraceID()
+	for _, s := range spans {
+		if s.SpanContext().TraceID() != traceID {
+			t.Errorf("span %q is in another trace", s.Name())
+		}
+	}
+	chat, failed, handle := spans[1], spans[2], spans[3]
+	if handle.Parent().SpanID() != spans[0].SpanContext().SpanID() {
+		t.Error("handle message is not a child of receive")
+	}
+	if chat.Name() != "chat fake-model" || chat.Parent().SpanID() != handle.SpanContext().SpanID() {
+		t.Errorf("chat span = %q with parent %s", chat.Name(), chat.Parent().SpanID())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range chat.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["gen_ai.usage.input_tokens"].AsInt64() != 120 || attrs["gen_ai.system"].AsString() != "fake" {
+		t.Errorf("chat attributes = %v", chat.Attributes())
+	}
+	if failed.Name() != "chat other" || failed.Status().Code != codes.Error {
+		t.Errorf("failed chat span = %q, status %v", failed.Name(), failed.Status())
+	}
+}
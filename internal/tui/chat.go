@@ -0,0 +1,524 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Chat display styles.
+var (
+	chatTitleStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("205"))
+
+	chatDimStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240"))
+
+	chatUserStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("205"))
+
+	chatBotStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("39"))
+
+	chatInfoStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("245"))
+
+	chatWarningStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("214"))
+
+	chatErrorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196"))
+)
+
+const chatHelp = "enter send · esc cancel · ↑/↓ history · pgup/pgdn scroll · ctrl+l clear · ctrl+o model · ctrl+s session · ctrl+c quit"
+
+// Roles of transcript entries besides "user" and "assistant".
+const (
+	RoleTool  = "tool"
+	RoleInfo  = "info"
+	RoleError = "error"
+)
+
+// ChatEntry is one message in the chat transcript.
+type ChatEntry struct {
+	Role    string // "user", "assistant", RoleTool, RoleInfo or RoleError
+	Content string
+}
+
+// CommandResult describes what a chat command did.
+type CommandResult struct {
+	Reply   string      // shown in the transcript
+	History []ChatEntry // replaces the transcript when not nil
+	Clear   bool        // empties the transcript
+	Model   string      // the model now in use, when it changed
+	Session string      // the session now in use, when it changed
+	Quit    bool
+}
+
+// ChatConfig configures a Chat.
+type ChatConfig struct {
+	Title   string
+	Model   string
+	Session string
+	History []ChatEntry // shown when the chat opens
+
+	// Send runs a turn for message and returns the reply. It passes reply
+	// text to onDelta as it streams in and the name of each tool to onTool
+	// before the tool runs. ctx is cancelled when the user presses Esc.
+	Send func(ctx context.Context, message string, onDelta, onTool func(string)) (string, error)
+
+	// Command handles input that is a command rather than a message,
+	// reporting whether it was one.
+	Command func(line string) (CommandResult, bool)
+}
+
+// Chat is a full-screen chat with scrollback, streamed replies rendered as
+// Markdown, and tool approvals.
+type Chat struct {
+	cfg     ChatConfig
+	program *tea.Program
+}
+
+// NewChat creates a Chat.
+func NewChat(cfg ChatConfig) *Chat {
+	return &Chat{cfg: cfg}
+}
+
+// Run shows the chat until the user quits or ctx is cancelled.
+func (c *Chat) Run(ctx context.Context) error {
+	m := newChatModel(ctx, c.cfg)
+	c.program = tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithContext(ctx))
+	m.send = c.program.Send
+
+	_, err := c.program.Run()
+	if m.cancel != nil {
+		m.cancel()
+	}
+	if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Approve asks the user to allow a tool call and waits for a y/n key. It
+// must be called from Send while the chat is running.
+func (c *Chat) Approve(ctx context.Context, prompt string) (bool, error) {
+	answer := make(chan bool, 1)
+	c.program.Send(approvalMsg{prompt: prompt, answer: answer})
+	select {
+	case ok := <-answer:
+		return ok, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+type (
+	deltaMsg string
+	toolMsg  string
+	doneMsg  struct {
+		reply string
+		err   error
+	}
+	approvalMsg struct {
+		prompt string
+		answer chan bool
+	}
+)
+
+// chatModel is the Bubble Tea model behind Chat.
+type chatModel struct {
+	ctx  context.Context
+	cfg  ChatConfig
+	send func(tea.Msg)
+
+	entries   []ChatEntry
+	rendered  []string // entries as displayed; "" = not rendered yet
+	streaming bool     // the last entry is a reply still streaming in
+
+	viewport viewport.Model
+	input    textinput.Model
+	spinner  spinner.Model
+	width    int
+	ready    bool
+
+	busy     bool
+	cancel   context.CancelFunc
+	status   string
+	approval *approvalMsg
+
+	sent   []string // earlier input, for recall with up/down
+	recall int
+}
+
+func newChatModel(ctx context.Context, cfg ChatConfig) *chatModel {
+	input := textinput.New()
+	input.Placeholder = "Message uBot, or /help"
+	input.Prompt = "› "
+	input.Focus()
+
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
+	sp.Style = spinnerStyle
+
+	m := &chatModel{
+		ctx:      ctx,
+		cfg:      cfg,
+		viewport: viewport.New(80, 20),
+		input:    input,
+		spinner:  sp,
+		width:    80,
+	}
+	m.setEntries(cfg.History)
+	return m
+}
+
+func (m *chatModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.viewport.Width = msg.Width
+		m.viewport.Height = max(msg.Height-4, 1)
+		m.input.Width = max(msg.Width-4, 10)
+		m.rendered = make([]string, len(m.entries))
+		m.ready = true
+		m.refresh(true)
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+
+	case spinner.TickMsg:
+		if !m.busy {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case deltaMsg:
+		if !m.streaming {
+			m.addEntry(ChatEntry{Role: "assistant"})
+			m.streaming = true
+		}
+		m.updateLast(m.entries[len(m.entries)-1].Content + string(msg))
+		m.status = ""
+		return m, nil
+
+	case toolMsg:
+		m.streaming = false
+		m.addEntry(ChatEntry{Role: RoleTool, Content: string(msg)})
+		m.status = "Running " + string(msg) + "…"
+		return m, nil
+
+	case approvalMsg:
+		m.approval = &msg
+		m.streaming = false
+		m.addEntry(ChatEntry{Role: RoleInfo, Content: msg.prompt})
+		return m, nil
+
+	case doneMsg:
+		m.finishTurn(msg)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *chatModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.approval != nil {
+		switch msg.String() {
+		case "y", "Y":
+			m.answerApproval(true)
+		case "n", "N", "esc":
+			m.answerApproval(false)
+		case "ctrl+c":
+			m.answerApproval(false)
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		if m.busy && m.cancel != nil {
+			m.cancel()
+			m.status = "Cancelling…"
+		}
+		return m, nil
+	case "enter":
+		line := strings.TrimSpace(m.input.Value())
+		if line == "" {
+			return m, nil
+		}
+		if m.busy {
+			m.status = "Still working. Press Esc to cancel."
+			return m, nil
+		}
+		m.sent = append(m.sent, line)
+		m.recall = len(m.sent)
+		m.input.Reset()
+		if cmd, ok := m.runCommand(line); ok {
+			return m, cmd
+		}
+		return m, m.startTurn(line)
+	case "ctrl+l":
+		cmd, _ := m.runCommand("/clear")
+		return m, cmd
+	case "ctrl+o":
+		return m, m.prefillCommand("/model")
+	case "ctrl+s":
+		return m, m.prefillCommand("/session")
+	case "up":
+		m.recallInput(-1)
+		return m, nil
+	case "down":
+		m.recallInput(1)
+		return m, nil
+	case "pgup":
+		m.viewport.ViewUp()
+		return m, nil
+	case "pgdown":
+		m.viewport.ViewDown()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// runCommand passes line to the configured command handler and applies
+// the result. It reports whether line was a command.
+func (m *chatModel) runCommand(line string) (tea.Cmd, bool) {
+	if m.cfg.Command == nil {
+		return nil, false
+	}
+	if m.busy {
+		m.status = "Wait for the reply, or press Esc to cancel."
+		return nil, true
+	}
+	res, ok := m.cfg.Command(line)
+	if !ok {
+		return nil, false
+	}
+	if res.Quit {
+		return tea.Quit, true
+	}
+	if res.Clear {
+		m.setEntries(nil)
+	}
+	if res.History != nil {
+		m.setEntries(res.History)
+	}
+	if res.Model != "" {
+		m.cfg.Model = res.Model
+	}
+	if res.Session != "" {
+		m.cfg.Session = res.Session
+	}
+	if res.Reply != "" {
+		m.addEntry(ChatEntry{Role: RoleInfo, Content: res.Reply})
+	}
+	m.refresh(true)
+	return nil, true
+}
+
+// prefillCommand runs a command without arguments, which shows the current
+// setting, and leaves it in the input for the user to complete.
+func (m *chatModel) prefillCommand(name string) tea.Cmd {
+	cmd, _ := m.runCommand(name)
+	if !m.busy {
+		m.input.SetValue(name + " ")
+		m.input.CursorEnd()
+	}
+	return cmd
+}
+
+// recallInput moves through earlier input by delta.
+func (m *chatModel) recallInput(delta int) {
+	next := m.recall + delta
+	if next < 0 || next > len(m.sent) {
+		return
+	}
+	m.recall = next
+	if next == len(m.sent) {
+		m.input.Reset()
+		return
+	}
+	m.input.SetValue(m.sent[next])
+	m.input.CursorEnd()
+}
+
+// startTurn sends line to the model in the background.
+func (m *chatModel) startTurn(line string) tea.Cmd {
+	m.addEntry(ChatEntry{Role: "user", Content: line})
+	m.refresh(true)
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.busy = true
+	m.cancel = cancel
+	m.streaming = false
+	m.status = ""
+
+	send := m.send
+	turn := func() tea.Msg {
+		defer cancel()
+		reply, err := m.cfg.Send(ctx, line,
+			func(text string) { send(deltaMsg(text)) },
+			func(tool string) { send(toolMsg(tool)) })
+		return doneMsg{reply: reply, err: err}
+	}
+	return tea.Batch(m.spinner.Tick, turn)
+}
+
+// finishTurn shows the outcome of a turn.
+func (m *chatModel) finishTurn(msg doneMsg) {
+	cancelled := m.ctx.Err() == nil && errors.Is(msg.err, context.Canceled)
+	m.busy = false
+	m.cancel = nil
+	m.approval = nil
+	m.status = ""
+
+	switch {
+	case cancelled:
+		m.addEntry(ChatEntry{Role: RoleInfo, Content: "Cancelled."})
+	case msg.err != nil:
+		m.addEntry(ChatEntry{Role: RoleError, Content: "Error: " + msg.err.Error()})
+	case m.streaming:
+		// The final text is authoritative; it may differ from the stream
+		// when a provider does not stream
+		m.updateLast(msg.reply)
+	case msg.reply != "":
+		m.addEntry(ChatEntry{Role: "assistant", Content: msg.reply})
+	}
+	m.streaming = false
+	m.refresh(false)
+}
+
+func (m *chatModel) answerApproval(ok bool) {
+	m.approval.answer <- ok
+	m.approval = nil
+	if ok {
+		m.addEntry(ChatEntry{Role: RoleInfo, Content: "Approved."})
+	} else {
+		m.addEntry(ChatEntry{Role: RoleInfo, Content: "Denied."})
+	}
+}
+
+// setEntries replaces the transcript.
+func (m *chatModel) setEntries(entries []ChatEntry) {
+	m.entries = append([]ChatEntry(nil), entries...)
+	m.rendered = make([]string, len(m.entries))
+	m.streaming = false
+	m.refresh(true)
+}
+
+func (m *chatModel) addEntry(e ChatEntry) {
+	m.entries = append(m.entries, e)
+	m.rendered = append(m.rendered, "")
+	m.refresh(false)
+}
+
+// updateLast replaces the content of the last entry.
+func (m *chatModel) updateLast(content string) {
+	last := len(m.entries) - 1
+	m.entries[last].Content = content
+	m.rendered[last] = ""
+	m.refresh(false)
+}
+
+// refresh renders new entries into the viewport, following the end of the
+// transcript if the user has not scrolled up, or always when toBottom.
+func (m *chatModel) refresh(toBottom bool) {
+	if !m.ready {
+		return
+	}
+	follow := toBottom || m.viewport.AtBottom()
+	blocks := make([]string, len(m.entries))
+	for i, e := range m.entries {
+		if m.rendered[i] == "" {
+			m.rendered[i] = m.renderEntry(e)
+		}
+		blocks[i] = m.rendered[i]
+	}
+	m.viewport.SetContent(strings.Join(blocks, "\n\n"))
+	if follow {
+		m.viewport.GotoBottom()
+	}
+}
+
+func (m *chatModel) renderEntry(e ChatEntry) string {
+	width := max(m.width-2, 20)
+	switch e.Role {
+	case "user":
+		return chatUserStyle.Render("You") + "\n" + ansi.Wrap(e.Content, width, " ")
+	case "assistant":
+		return chatBotStyle.Render("uBot") + "\n" + RenderMarkdown(e.Content, width)
+	case RoleTool:
+		return chatDimStyle.Render("⚙ " + e.Content)
+	case RoleError:
+		return chatErrorStyle.Render(ansi.Wrap(e.Content, width, " "))
+	default:
+		return chatInfoStyle.Render(ansi.Wrap(e.Content, width, " "))
+	}
+}
+
+func (m *chatModel) View() string {
+	header := chatTitleStyle.Render(m.cfg.Title)
+	var details []string
+	if m.cfg.Model != "" {
+		details = append(details, m.cfg.Model)
+	}
+	if m.cfg.Session != "" {
+		details = append(details, m.cfg.Session)
+	}
+	if len(details) > 0 {
+		header += chatDimStyle.Render(" · " + strings.Join(details, " · "))
+	}
+
+	var status string
+	switch {
+	case m.approval != nil:
+		status = chatWarningStyle.Render("Allow this tool call? [y/n]")
+	case m.busy:
+		text := m.status
+		if text == "" {
+			text = "Thinking…"
+		}
+		status = m.spinner.View() + " " + chatDimStyle.Render(text)
+	default:
+		status = chatDimStyle.Render(m.status)
+	}
+
+	return strings.Join([]string{
+		ansi.Truncate(header, m.width, "…"),
+		m.viewport.View(),
+		ansi.Truncate(status, m.width, "…"),
+		m.input.View(),
+		chatDimStyle.Render(ansi.Truncate(chatHelp, m.width, "…")),
+	}, "\n")
+}
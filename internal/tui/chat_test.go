@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"context"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestChat returns a chat model sized for a terminal, with Send
+// replaced by one that records messages without running a turn.
+func newTestChat(t *testing.T, cfg ChatConfig) (*chatModel, *[]string) {
+	t.Helper()
+	var sent []string
+	cfg.Send = func(ctx context.Context, message string, onDelta, onTool func(string)) (string, error) {
+		sent = append(sent, message)
+		return "", nil
+	}
+	m := newChatModel(context.Background(), cfg)
+	m.send = func(tea.Msg) {}
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	return m, &sent
+}
+
+func typeLine(m *chatModel, line string) tea.Cmd {
+	m.input.SetValue(line)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return cmd
+}
+
+func TestChatStreamsReply(t *testing.T) {
+	m, _ := newTestChat(t, ChatConfig{Title: "uBot"})
+
+	typeLine(m, "list files")
+	if !m.busy {
+		t.Fatal("chat is not busy after sending a message")
+	}
+	for _, msg := range []tea.Msg{deltaMsg("Checking"), toolMsg("list_dir"), deltaMsg("Two "), deltaMsg("files."), doneMsg{reply: "Two files."}} {
+		m.Update(msg)
+	}
+
+	want := []ChatEntry{
+		{Role: "user", Content: "list files"},
+		{Role: "assistant", Content: "Checking"},
+		{Role: RoleTool, Content: "list_dir"},
+		{Role: "assistant", Content: "Two files."},
+	}
+	if m.busy || len(m.entries) != len(want) {
+		t.Fatalf("busy = %v, entries = %+v", m.busy, m.entries)
+	}
+	for i := range want {
+		if m.entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, m.entries[i], want[i])
+		}
+	}
+}
+
+func TestChatCommands(t *testing.T) {
+	var commands []string
+	m, sent := newTestChat(t, ChatConfig{
+		Title:   "uBot",
+		History: []ChatEntry{{Role: "user", Content: "hi"}},
+		Command: func(line string) (CommandResult, bool) {
+			commands = append(commands, line)
+			switch line {
+			case "/clear":
+				return CommandResult{Clear: true, Reply: "Cleared."}, true
+			case "/session work":
+				return CommandResult{Session: "cli:work", History: []ChatEntry{{Role: "user", Content: "old"}}}, true
+			}
+			return CommandResult{}, false
+		},
+	})
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if len(m.entries) != 1 || m.entries[0].Content != "Cleared." {
+		t.Errorf("entries after ctrl+l = %+v", m.entries)
+	}
+
+	typeLine(m, "/session work")
+	if m.cfg.Session != "cli:work" || len(m.entries) != 1 || m.entries[0].Content != "old" {
+		t.Errorf("after /session: session = %q, entries = %+v", m.cfg.Session, m.entries)
+	}
+
+	if cmd := typeLine(m, "hello"); cmd == nil || !m.busy {
+		t.Error("a message that is not a command did not start a turn")
+	}
+	if len(commands) != 3 || len(*sent) != 0 {
+		t.Errorf("commands = %q, sent = %q", commands, *sent)
+	}
+
+	// Earlier input can be recalled
+	m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if got := m.input.Value(); got != "hello" {
+		t.Errorf("recalled input = %q, want %q", got, "hello")
+	}
+}
+
+func TestChatApproval(t *testing.T) {
+	m, _ := newTestChat(t, ChatConfig{Title: "uBot"})
+	typeLine(m, "delete it")
+
+	answer := make(chan bool, 1)
+	m.Update(approvalMsg{prompt: "Allow exec(command=rm x)?", answer: answer})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if len(answer) != 0 {
+		t.Fatal("an unrelated key answered the approval")
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if ok := <-answer; ok || m.approval != nil {
+		t.Errorf("answer = %v, pending = %v; want denied", ok, m.approval != nil)
+	}
+}
@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hkuds/ubot/internal/doctor"
+)
+
+// ShowDoctor prints the results of 'ubot doctor'.
+func ShowDoctor(results []doctor.Result) {
+	fmt.Print(RenderDoctor(results))
+}
+
+// RenderDoctor renders check results one per line, marked pass, warn or
+// fail, with the hint for each problem below it and a count at the end.
+func RenderDoctor(results []doctor.Result) string {
+	var sb strings.Builder
+	sb.WriteString(statusTitleStyle.Render("uBot Doctor"))
+	sb.WriteString("\n\n")
+
+	counts := map[doctor.Status]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		mark := statusEnabledStyle.Render("✓")
+		switch r.Status {
+		case doctor.Warn:
+			mark = statusWarningStyle.Render("!")
+		case doctor.Fail:
+			mark = statusErrorStyle.Render("✗")
+		}
+		fmt.Fprintf(&sb, "  %s %s %s\n", mark, statusLabelStyle.Render(r.Name), statusValueStyle.Render(r.Detail))
+		if r.Hint != "" {
+			fmt.Fprintf(&sb, "    %s %s\n", statusLabelStyle.Render(""), statusDisabledStyle.Render("→ "+r.Hint))
+		}
+	}
+
+	summary := fmt.Sprintf("%d passed, %d warning(s), %d failed", counts[doctor.Pass], counts[doctor.Warn], counts[doctor.Fail])
+	switch {
+	case counts[doctor.Fail] > 0:
+		summary = statusErrorStyle.Render(summary)
+	case counts[doctor.Warn] > 0:
+		summary = statusWarningStyle.Render(summary)
+	default:
+		summary = statusEnabledStyle.Render(summary)
+	}
+	fmt.Fprintf(&sb, "\n  %s\n", summary)
+	return sb.String()
+}
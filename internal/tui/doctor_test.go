@@ -0,0 +1,30 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/hkuds/ubot/internal/doctor"
+)
+
+func TestRenderDoctor(t *testing.T) {
+	got := ansi.Strip(RenderDoctor([]doctor.Result{
+		{Name: "Config", Status: doctor.Pass, Detail: "config.json"},
+		{Name: "Docker", Status: doctor.Warn, Detail: "not reachable", Hint: "Start Docker"},
+		{Name: "Provider", Status: doctor.Fail, Detail: "rejected", Hint: "Check the API key"},
+	}))
+
+	for _, want := range []string{
+		"✓ Config",
+		"! Docker",
+		"→ Start Docker",
+		"✗ Provider",
+		"1 passed, 1 warning(s), 1 failed",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Markdown styles.
+var (
+	mdHeadingStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("39"))
+
+	mdCodeBlockStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("252")).
+				Background(lipgloss.Color("236"))
+
+	mdCodeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("205"))
+
+	mdBoldStyle = lipgloss.NewStyle().Bold(true)
+
+	mdQuoteStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("245")).
+			Italic(true)
+
+	mdRuleStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240"))
+)
+
+var (
+	mdHeadingRe = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	mdBulletRe  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdNumberRe  = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	mdRuleRe    = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	mdInlineRe  = regexp.MustCompile("`[^`]+`|\\*\\*[^*]+\\*\\*|__[^_]+__")
+)
+
+// RenderMarkdown renders the common parts of Markdown in model replies —
+// headings, lists, block quotes, fenced code, bold and inline code — for a
+// terminal width columns wide. Anything else is shown as written.
+func RenderMarkdown(text string, width int) string {
+	if width < 20 {
+		width = 20
+	}
+
+	var out []string
+	inCode := false
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			code := ansi.Truncate(strings.ReplaceAll(line, "\t", "    "), width-2, "…")
+			out = append(out, mdCodeBlockStyle.Render(" "+code+" "))
+			continue
+		}
+
+		switch {
+		case mdHeadingRe.MatchString(trimmed):
+			heading := mdHeadingRe.FindStringSubmatch(trimmed)[1]
+			out = append(out, mdHeadingStyle.Render(ansi.Wrap(heading, width, " ")))
+		case mdRuleRe.MatchString(line):
+			out = append(out, mdRuleStyle.Render(strings.Repeat("─", width)))
+		case mdBulletRe.MatchString(line):
+			m := mdBulletRe.FindStringSubmatch(line)
+			out = append(out, hangingIndent(m[1]+"• ", renderInline(m[2]), width))
+		case mdNumberRe.MatchString(line):
+			m := mdNumberRe.FindStringSubmatch(line)
+			out = append(out, hangingIndent(m[1]+m[2]+" ", renderInline(m[3]), width))
+		case strings.HasPrefix(trimmed, ">"):
+			quote := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			out = append(out, hangingIndent("│ ", mdQuoteStyle.Render(quote), width))
+		default:
+			out = append(out, ansi.Wrap(renderInline(line), width, " "))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// renderInline styles bold text and inline code in a line.
+func renderInline(line string) string {
+	return mdInlineRe.ReplaceAllStringFunc(line, func(m string) string {
+		if strings.HasPrefix(m, "`") {
+			return mdCodeStyle.Render(strings.Trim(m, "`"))
+		}
+		return mdBoldStyle.Render(m[2 : len(m)-2])
+	})
+}
+
+// hangingIndent wraps text after prefix, indenting continuation lines to
+// line up with the first.
+func hangingIndent(prefix, text string, width int) string {
+	indent := strings.Repeat(" ", ansi.StringWidth(prefix))
+	lines := strings.Split(ansi.Wrap(text, width-len(indent), " "), "\n")
+	for i := range lines {
+		if i == 0 {
+			lines[i] = prefix + lines[i]
+		} else {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	text := strings.Join([]string{
+		"## Results",
+		"",
+		"Found **two** files with `grep`:",
+		"- main.go",
+		"* a very long entry that has to wrap onto the next line",
+		"> quoted",
+		"```go",
+		"**not bold**",
+		"```",
+	}, "\n")
+
+	got := ansi.Strip(RenderMarkdown(text, 30))
+	want := strings.Join([]string{
+		"Results",
+		"",
+		"Found two files with grep:",
+		"• main.go",
+		"• a very long entry that has",
+		"  to wrap onto the next line",
+		"│ quoted",
+		" **not bold** ",
+	}, "\n")
+	if got != want {
+		t.Errorf("RenderMarkdown =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -2,11 +2,14 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/providers"
 	"github.com/hkuds/ubot/internal/skills"
 )
 
@@ -84,14 +87,20 @@ type SetupState struct {
 	BaseURL        string
 	Model          string
 	CustomModel    string
+	CopilotModels  []string // models the Copilot subscription offers
 	MiniMaxRegion string
 	ConfigTelegram bool
 	TelegramToken  string
 	TelegramUsers  string
 	ConfigWhatsApp bool
 	ConfigSearch   bool
+	SearchProvider string // one of the config.Search constants
 	SearchAPIKey   string
+	SearchURL      string // SearxNG instance
+	SearchEngineID string // Google Programmable Search Engine ID
 	ConfigSkills   bool
+	SkillRepos     []string // names of the enabled knownSkillRepos
+	ExtraSkillRepo string   // another git URL or local path
 	SelectedSkills []string
 	Confirmed      bool
 }
@@ -100,7 +109,8 @@ type SetupState struct {
 // Returns the configured Config or error.
 func RunSetup() (*config.Config, error) {
 	state := &SetupState{
-		BaseURL: "http://localhost:11434",
+		BaseURL:      "http://localhost:11434",
+		ConfigSearch: true, // DuckDuckGo needs no key
 	}
 
 	// Step 1: Welcome & Provider Selection
@@ -280,6 +290,15 @@ func runCopilotAuthStep(state *SetupState) error {
 	}
 
 	state.APIKey = token
+
+	// Offer the models the subscription includes
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	models, err := providers.NewCopilotProvider(token, "").ListModels(ctx)
+	if err != nil {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("Could not list the Copilot models (%v); offering the usual ones.", err)))
+	}
+	state.CopilotModels = models
 	return nil
 }
 
@@ -331,6 +350,9 @@ func runOllamaStep(state *SetupState) error {
 // runModelSelectionStep allows user to select or enter a model.
 func runModelSelectionStep(state *SetupState) error {
 	models := ModelOptions[state.Provider]
+	if state.Provider == ProviderCopilot && len(state.CopilotModels) > 0 {
+		models = state.CopilotModels
+	}
 
 	if state.Provider == ProviderOllama || len(models) == 0 {
 		// Free-form model input for Ollama
@@ -408,9 +430,9 @@ func runChannelsStep(state *SetupState) error {
 						return nil
 					}),
 				huh.NewInput().
-					Title("Allowed User IDs (optional)").
-					Description("Comma-separated list of Telegram user IDs that can use the bot").
-					Placeholder("123456789, 987654321").
+					Title("Allowed Users (optional)").
+					Description("Comma-separated Telegram user IDs or @usernames that can use the bot").
+					Placeholder("123456789, @alice").
 					Value(&state.TelegramUsers),
 			),
 		)
@@ -448,7 +470,7 @@ func runWebSearchStep(state *SetupState) error {
 		huh.NewGroup(
 			huh.NewConfirm().
 				Title("Enable Web Search?").
-				Description("Allow the AI to search the web (requires Brave Search API key)").
+				Description("Allow the AI to search the web (DuckDuckGo works without an API key)").
 				Value(&state.ConfigSearch),
 		),
 	)
@@ -456,34 +478,104 @@ func runWebSearchStep(state *SetupState) error {
 	if err := form.Run(); err != nil {
 		return err
 	}
+	if !state.ConfigSearch {
+		return nil
+	}
 
-	if state.ConfigSearch {
-		searchForm := huh.NewForm(
-			huh.NewGroup(
-				huh.NewInput().
-					Title("Brave Search API Key").
-					Description("Get your API key from https://brave.com/search/api/").
-					Placeholder("BSA...").
-					EchoMode(huh.EchoModePassword).
-					Value(&state.SearchAPIKey).
-					Validate(func(s string) error {
-						if strings.TrimSpace(s) == "" {
-							return fmt.Errorf("API key is required for web search")
-						}
-						return nil
-					}),
-			),
-		)
+	state.SearchProvider = config.SearchDuckDuckGo
+	providerForm := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Select search provider").
+				Options(
+					huh.NewOption("DuckDuckGo (no API key)", config.SearchDuckDuckGo),
+					huh.NewOption("Brave Search (API key)", config.SearchBrave),
+					huh.NewOption("SearxNG (self-hosted instance)", config.SearchSearxNG),
+					huh.NewOption("Google Custom Search (API key and engine ID)", config.SearchGoogle),
+				).
+				Value(&state.SearchProvider),
+		),
+	)
+	if err := providerForm.Run(); err != nil {
+		return err
+	}
 
-		if err := searchForm.Run(); err != nil {
-			return err
+	required := func(what string) func(string) error {
+		return func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return fmt.Errorf("%s is required for this search provider", what)
+			}
+			return nil
 		}
 	}
+	var fields []huh.Field
+	switch state.SearchProvider {
+	case config.SearchBrave:
+		fields = append(fields, huh.NewInput().
+			Title("Brave Search API Key").
+			Description("Get your API key from https://brave.com/search/api/").
+			Placeholder("BSA...").
+			EchoMode(huh.EchoModePassword).
+			Value(&state.SearchAPIKey).
+			Validate(required("API key")))
+	case config.SearchSearxNG:
+		fields = append(fields, huh.NewInput().
+			Title("SearxNG URL").
+			Description("The instance must allow the json format (search.formats in settings.yml)").
+			Placeholder("https://searx.example.org").
+			Value(&state.SearchURL).
+			Validate(required("URL")))
+	case config.SearchGoogle:
+		fields = append(fields,
+			huh.NewInput().
+				Title("Google API Key").
+				Description("Create one at https://console.cloud.google.com/apis/credentials").
+				EchoMode(huh.EchoModePassword).
+				Value(&state.SearchAPIKey).
+				Validate(required("API key")),
+			huh.NewInput().
+				Title("Search Engine ID").
+				Description("The cx of your engine at https://programmablesearchengine.google.com/").
+				Value(&state.SearchEngineID).
+				Validate(required("engine ID")))
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return huh.NewForm(huh.NewGroup(fields...)).Run()
+}
 
-	return nil
+// knownSkillRepos are the skill repositories offered by the setup wizard.
+var knownSkillRepos = []config.SkillRepoConfig{
+	{Name: "community", URL: skills.DefaultSkillsRepo},
+}
+
+// skillRepos returns the skill repositories chosen in the wizard, or nil
+// when only the default one is enabled.
+func (s *SetupState) skillRepos() []config.SkillRepoConfig {
+	enabled := make(map[string]bool)
+	for _, name := range s.SkillRepos {
+		enabled[name] = true
+	}
+	repos := make([]config.SkillRepoConfig, 0, len(knownSkillRepos)+1)
+	for _, r := range knownSkillRepos {
+		r.Disabled = !enabled[r.Name]
+		repos = append(repos, r)
+	}
+	if extra := strings.TrimSpace(s.ExtraSkillRepo); extra != "" {
+		if strings.Contains(extra, "://") || strings.HasPrefix(extra, "git@") {
+			repos = append(repos, config.SkillRepoConfig{URL: extra})
+		} else {
+			repos = append(repos, config.SkillRepoConfig{Path: extra})
+		}
+	}
+	if len(repos) == 1 && !repos[0].Disabled {
+		return nil
+	}
+	return repos
 }
 
-// runSkillsStep configures skills from the remote repository.
+// runSkillsStep configures skills from the skill repositories.
 func runSkillsStep(state *SetupState) error {
 	form := huh.NewForm(
 		huh.NewGroup(
@@ -502,22 +594,56 @@ func runSkillsStep(state *SetupState) error {
 		return nil
 	}
 
+	// Choose the repositories to install skills from
+	repoOptions := make([]huh.Option[string], 0, len(knownSkillRepos))
+	for _, r := range knownSkillRepos {
+		repoOptions = append(repoOptions, huh.NewOption(fmt.Sprintf("%s (%s)", r.Name, r.URL), r.Name))
+	}
+	state.SkillRepos = []string{knownSkillRepos[0].Name}
+	reposForm := huh.NewForm(
+		huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Skill repositories").
+				Description("Use space to select, enter to confirm").
+				Options(repoOptions...).
+				Value(&state.SkillRepos),
+			huh.NewInput().
+				Title("Another repository (optional)").
+				Description("A git URL or a local directory with SKILL.md files; its skills are named <repo>.<skill>").
+				Value(&state.ExtraSkillRepo),
+		),
+	)
+	if err := reposForm.Run(); err != nil {
+		return err
+	}
+
 	fmt.Println(subtitleStyle.Render("\nFetching available skills..."))
 
 	// Create skills manager
 	configDir := config.GetConfigDir()
 	workspacePath := config.GetConfigDir() + "/workspace" // Default workspace path
 	manager := skills.NewManager(configDir, workspacePath)
+	if repos := state.skillRepos(); repos != nil {
+		var sources []skills.Source
+		for _, r := range repos {
+			if !r.Disabled {
+				sources = append(sources, skills.Source{Name: r.Name, URL: r.URL, Path: r.Path})
+			}
+		}
+		if len(sources) == 0 {
+			fmt.Println(subtitleStyle.Render("No skill repositories selected."))
+			return nil
+		}
+		manager.SetSources(sources)
+	}
 
 	// Clone or update the skills repo
 	isNew, err := manager.EnsureRepo()
 	if err != nil {
+		// Other repositories may still have been fetched; discovery below
+		// fails if none were
 		fmt.Println(warningStyle.Render("Failed to fetch skills repository: " + err.Error()))
-		fmt.Println(subtitleStyle.Render("You can install skills manually later."))
-		return nil // Don't fail setup
-	}
-
-	if isNew {
+	} else if isNew {
 		fmt.Println(successStyle.Render("Skills repository downloaded!"))
 	} else {
 		fmt.Println(successStyle.Render("Skills repository updated!"))
@@ -526,7 +652,8 @@ func runSkillsStep(state *SetupState) error {
 	// Discover available skills from remote repo
 	if err := manager.DiscoverAvailable(); err != nil {
 		fmt.Println(warningStyle.Render("Failed to discover skills: " + err.Error()))
-		return nil
+		fmt.Println(subtitleStyle.Render("You can install skills manually later."))
+		return nil // Don't fail setup
 	}
 
 	// Also discover bundled skills from the local repo clone
@@ -653,7 +780,7 @@ func buildSummary(state *SetupState) string {
 
 	// Web Search
 	if state.ConfigSearch {
-		sb.WriteString(fmt.Sprintf("Web Search: %s\n", successStyle.Render("enabled")))
+		sb.WriteString(fmt.Sprintf("Web Search: %s\n", successStyle.Render(state.SearchProvider)))
 	} else {
 		sb.WriteString(fmt.Sprintf("Web Search: %s\n", subtitleStyle.Render("disabled")))
 	}
@@ -710,9 +837,11 @@ func buildConfigFromState(state *SetupState) *config.Config {
 		cfg.Channels.Telegram.Enabled = true
 		cfg.Channels.Telegram.Token = state.TelegramToken
 		if state.TelegramUsers != "" {
-			users := strings.Split(state.TelegramUsers, ",")
-			for i, u := range users {
-				users[i] = strings.TrimSpace(u)
+			var users []string
+			for _, u := range strings.Split(state.TelegramUsers, ",") {
+				if u = strings.TrimSpace(u); u != "" {
+					users = append(users, u)
+				}
 			}
 			cfg.Channels.Telegram.AllowFrom = users
 		}
@@ -725,7 +854,17 @@ func buildConfigFromState(state *SetupState) *config.Config {
 
 	// Configure Web Search
 	if state.ConfigSearch {
+		cfg.Tools.Web.Search.Provider = state.SearchProvider
 		cfg.Tools.Web.Search.APIKey = state.SearchAPIKey
+		cfg.Tools.Web.Search.URL = state.SearchURL
+		cfg.Tools.Web.Search.EngineID = state.SearchEngineID
+	} else {
+		cfg.Tools.Web.Search.Provider = config.SearchNone
+	}
+
+	// Skill repositories, when not just the default one
+	if state.ConfigSkills {
+		cfg.Skills.Repos = state.skillRepos()
 	}
 
 	return cfg
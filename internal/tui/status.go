@@ -2,10 +2,12 @@ package tui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/hkuds/ubot/internal/config"
+	"github.com/hkuds/ubot/internal/control"
 	"github.com/hkuds/ubot/internal/skills"
 )
 
@@ -53,8 +55,9 @@ var (
 				Foreground(lipgloss.Color("240"))
 )
 
-// ShowStatus displays the current configuration status.
-func ShowStatus(cfg *config.Config) error {
+// ShowStatus displays the current configuration status. health is that of
+// the running gateway, or nil when it serves no health checks.
+func ShowStatus(cfg *config.Config, health *control.Health) error {
 	var sb strings.Builder
 
 	// Title
@@ -66,6 +69,9 @@ func ShowStatus(cfg *config.Config) error {
 	sb.WriteString(statusSectionStyle.Render("Provider"))
 	sb.WriteString("\n")
 	sb.WriteString(renderProviderStatus(cfg))
+	if health != nil {
+		sb.WriteString(renderCircuitStatus(*health))
+	}
 	sb.WriteString("\n")
 
 	// Channels section
@@ -121,15 +127,48 @@ func renderProviderStatus(cfg *config.Config) string {
 
 	// API Base (if custom)
 	if apiBase != "" && !isDefaultAPIBase(providerName, apiBase) {
-		sb.WriteString(renderStatusRow("API Base", statusValueStyle.Render(apiBase)))
+		sb.WriteString(renderStatusRow("API Base", statusValueStyle.Render(config.RedactURL(apiBase))))
 	}
 
 	// API Key status (masked)
 	if apiKey != "" {
-		masked := maskAPIKey(apiKey)
-		sb.WriteString(renderStatusRow("API Key", statusValueStyle.Render(masked)))
+		sb.WriteString(renderStatusRow("API Key", statusValueStyle.Render(config.MaskSecret(apiKey))))
+	}
+
+	return sb.String()
+}
+
+// renderCircuitStatus renders the state of the gateway's provider circuit
+// breakers.
+func renderCircuitStatus(health control.Health) string {
+	if health.Status == control.HealthUnavailable {
+		return renderStatusRow("Circuits", statusDisabledStyle.Render("gateway not reachable"))
+	}
+
+	var names []string
+	for name := range health.Checks {
+		if strings.HasPrefix(name, "provider.") {
+			names = append(names, name)
+		}
 	}
+	if len(names) == 0 {
+		return renderStatusRow("Circuits", statusDisabledStyle.Render("no requests yet"))
+	}
+	sort.Strings(names)
 
+	var sb strings.Builder
+	for _, name := range names {
+		check := health.Checks[name]
+		style := statusEnabledStyle
+		switch {
+		case check.Status == control.CheckDown:
+			style = statusErrorStyle
+		case strings.Contains(check.Detail, "half-open") || strings.Contains(check.Detail, "failures"):
+			style = statusWarningStyle
+		}
+		label := "Circuit " + strings.TrimPrefix(name, "provider.")
+		sb.WriteString(renderStatusRow(label, style.Render(strings.TrimPrefix(check.Detail, "circuit "))))
+	}
 	return sb.String()
 }
 
@@ -147,7 +186,7 @@ func renderChannelsStatus(cfg *config.Config) string {
 			}
 			sb.WriteString(renderStatusRow("  Allowed", statusValueStyle.Render(users)))
 		} else {
-			sb.WriteString(renderStatusRow("  Allowed", statusWarningStyle.Render("all users (not recommended)")))
+			sb.WriteString(renderStatusRow("  Allowed", statusWarningStyle.Render("nobody (senders held for approval)")))
 		}
 	} else {
 		sb.WriteString(renderStatusRow("Telegram", statusDisabledStyle.Render("disabled")))
@@ -156,7 +195,7 @@ func renderChannelsStatus(cfg *config.Config) string {
 	// WhatsApp
 	if cfg.Channels.WhatsApp.Enabled {
 		sb.WriteString(renderStatusRow("WhatsApp", statusEnabledStyle.Render("enabled")))
-		sb.WriteString(renderStatusRow("  Bridge", statusValueStyle.Render(cfg.Channels.WhatsApp.BridgeURL)))
+		sb.WriteString(renderStatusRow("  Bridge", statusValueStyle.Render(config.RedactURL(cfg.Channels.WhatsApp.BridgeURL))))
 	} else {
 		sb.WriteString(renderStatusRow("WhatsApp", statusDisabledStyle.Render("disabled")))
 	}
@@ -169,8 +208,8 @@ func renderToolsStatus(cfg *config.Config) string {
 	var sb strings.Builder
 
 	// Web Search
-	if cfg.Tools.Web.Search.APIKey != "" {
-		sb.WriteString(renderStatusRow("Web Search", statusEnabledStyle.Render("enabled")))
+	if search := cfg.Tools.Web.Search.ProviderName(); search != config.SearchNone {
+		sb.WriteString(renderStatusRow("Web Search", statusEnabledStyle.Render(search)))
 		sb.WriteString(renderStatusRow("  Max Results", statusValueStyle.Render(fmt.Sprintf("%d", cfg.Tools.Web.Search.MaxResults))))
 	} else {
 		sb.WriteString(renderStatusRow("Web Search", statusDisabledStyle.Render("disabled")))
@@ -241,14 +280,6 @@ func renderStatusRow(label, value string) string {
 	)
 }
 
-// maskAPIKey masks an API key for display.
-func maskAPIKey(key string) string {
-	if len(key) <= 8 {
-		return "****"
-	}
-	return key[:4] + "****" + key[len(key)-4:]
-}
-
 // isDefaultAPIBase checks if the API base is the default for the provider.
 func isDefaultAPIBase(provider, apiBase string) bool {
 	defaults := map[string]string{
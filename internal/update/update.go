@@ -0,0 +1,339 @@
+// Package update downloads ubot release binaries from GitHub, verifies
+// them against the release's checksums and replaces the running binary.
+//
+// A release carries one binary per platform, named by AssetName, and a
+// checksums.txt listing their SHA-256 sums in sha256sum format. When the
+// build knows the release signing key, checksums.txt.sig must hold a
+// base64 ed25519 signature of checksums.txt made with it.
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRepo is the GitHub repository releases are taken from.
+	DefaultRepo = "lubluniky/ubot"
+	// DefaultAPIURL is the GitHub API endpoint.
+	DefaultAPIURL = "https://api.github.com"
+
+	// ChecksumsAsset lists the SHA-256 sums of a release's binaries.
+	ChecksumsAsset = "checksums.txt"
+	// SignatureAsset is the signature of ChecksumsAsset.
+	SignatureAsset = "checksums.txt.sig"
+
+	// maxBinarySize bounds a downloaded binary.
+	maxBinarySize = 200 << 20
+	// maxSmallAsset bounds the checksums, signature and API responses.
+	maxSmallAsset = 1 << 20
+)
+
+// Release is a published ubot release.
+type Release struct {
+	Version string            // tag name, e.g. "v0.2.0"
+	Assets  map[string]string // download URL by asset name
+}
+
+// Updater fetches releases of Repo.
+type Updater struct {
+	Repo      string            // "owner/name"; DefaultRepo when empty
+	APIURL    string            // DefaultAPIURL when empty
+	PublicKey ed25519.PublicKey // release signing key; nil skips the signature check
+	HTTP      *http.Client      // a client with a 5 minute timeout when nil
+}
+
+// AssetName returns the name of the release binary for a platform, e.g.
+// "ubot_linux_amd64" or "ubot_windows_amd64.exe".
+func AssetName(goos, goarch string) string {
+	name := "ubot_" + goos + "_" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key. An empty key gives
+// nil.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	if s == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("release signing key must be a base64 ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Latest returns the newest published release.
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	return u.release(ctx, "releases/latest")
+}
+
+// Release returns the release tagged version; a missing "v" prefix is
+// added.
+func (u *Updater) Release(ctx context.Context, version string) (*Release, error) {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	return u.release(ctx, "releases/tags/"+url.PathEscape(version))
+}
+
+func (u *Updater) release(ctx context.Context, path string) (*Release, error) {
+	repo := u.Repo
+	if repo == "" {
+		repo = DefaultRepo
+	}
+	api := u.APIURL
+	if api == "" {
+		api = DefaultAPIURL
+	}
+	body, err := u.get(ctx, api+"/repos/"+repo+"/"+path, maxSmallAsset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up release: %w", err)
+	}
+
+	var data struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	if data.TagName == "" {
+		return nil, errors.New("release has no tag")
+	}
+	rel := &Release{Version: data.TagName, Assets: make(map[string]string, len(data.Assets))}
+	for _, a := range data.Assets {
+		rel.Assets[a.Name] = a.URL
+	}
+	return rel, nil
+}
+
+// Install downloads the binary of rel for this platform, verifies it and
+// atomically replaces the file at path with it. The file keeps its
+// permissions. On failure path is left untouched.
+func (u *Updater) Install(ctx context.Context, rel *Release, path string) error {
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	binURL, ok := rel.Assets[name]
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", rel.Version, runtime.GOOS, runtime.GOARCH)
+	}
+	sumsURL, ok := rel.Assets[ChecksumsAsset]
+	if !ok {
+		return fmt.Errorf("release %s has no %s", rel.Version, ChecksumsAsset)
+	}
+
+	sums, err := u.get(ctx, sumsURL, maxSmallAsset)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", ChecksumsAsset, err)
+	}
+	if u.PublicKey != nil {
+		sigURL, ok := rel.Assets[SignatureAsset]
+		if !ok {
+			return fmt.Errorf("release %s is not signed (no %s)", rel.Version, SignatureAsset)
+		}
+		sig, err := u.get(ctx, sigURL, maxSmallAsset)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", SignatureAsset, err)
+		}
+		if err := VerifySignature(u.PublicKey, sums, sig); err != nil {
+			return err
+		}
+	}
+	want, err := Checksum(sums, name)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	// The temporary file lives next to path so the rename stays on one
+	// filesystem and is atomic
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write next to %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	got, err := u.download(ctx, binURL, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return replace(tmp.Name(), path)
+}
+
+// replace renames src over dst. Windows cannot replace a running binary,
+// but it can rename it, so the old one is moved aside first.
+func replace(src, dst string) error {
+	if runtime.GOOS == "windows" {
+		old := dst + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(dst, old); err != nil {
+			return err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			_ = os.Rename(old, dst)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(src, dst)
+}
+
+// Checksum returns the hex SHA-256 sum listed for name in a sha256sum
+// formatted checksums file.
+func Checksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		sum := strings.ToLower(fields[0])
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return "", fmt.Errorf("malformed checksum for %s", name)
+		}
+		return sum, nil
+	}
+	return "", fmt.Errorf("%s lists no checksum for %s", ChecksumsAsset, name)
+}
+
+// VerifySignature checks that sig, a base64 ed25519 signature, signs data
+// with key.
+func VerifySignature(key ed25519.PublicKey, data, sig []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || len(raw) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed %s", SignatureAsset)
+	}
+	if !ed25519.Verify(key, data, raw) {
+		return fmt.Errorf("%s does not match the release signing key", SignatureAsset)
+	}
+	return nil
+}
+
+// Newer reports whether version a is newer than b. Versions are compared
+// by their dot-separated numbers, ignoring a "v" prefix and anything after
+// a "-" or "+"; a version that does not parse is never newer.
+func Newer(a, b string) bool {
+	pa, ok := parseVersion(a)
+	if !ok {
+		return false
+	}
+	pb, ok := parseVersion(b)
+	if !ok {
+		return true
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
+
+// get fetches rawURL, reading at most limit bytes.
+func (u *Updater) get(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := u.fetch(ctx, rawURL, &buf, limit); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// download writes rawURL to w and returns the hex SHA-256 sum of the
+// content.
+func (u *Updater) download(ctx context.Context, rawURL string, w io.Writer) (string, error) {
+	hash := sha256.New()
+	if _, err := u.fetch(ctx, rawURL, io.MultiWriter(w, hash), maxBinarySize); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (u *Updater) fetch(ctx context.Context, rawURL string, w io.Writer, limit int64) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "ubot-self-update")
+	client := u.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, fmt.Errorf("%s: not found", rawURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s: HTTP %d", rawURL, resp.StatusCode)
+	}
+	n, err := io.Copy(w, io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return n, err
+	}
+	if n > limit {
+		return n, fmt.Errorf("%s: larger than %d bytes", rawURL, limit)
+	}
+	return n, nil
+}
@@ -0,0 +1,147 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// releaseServer serves a fake GitHub release of binary, signed with priv
+// when it is not nil.
+func releaseServer(t *testing.T, binary []byte, sums string, priv ed25519.PrivateKey) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	assets := []string{
+		fmt.Sprintf(`{"name":%q,"browser_download_url":%q}`, name, srv.URL+"/dl/bin"),
+		fmt.Sprintf(`{"name":%q,"browser_download_url":%q}`, ChecksumsAsset, srv.URL+"/dl/sums"),
+	}
+	if priv != nil {
+		assets = append(assets, fmt.Sprintf(`{"name":%q,"browser_download_url":%q}`, SignatureAsset, srv.URL+"/dl/sig"))
+	}
+	mux.HandleFunc("/repos/o/r/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name":"v0.2.0","assets":[%s]}`, strings.Join(assets, ","))
+	})
+	mux.HandleFunc("/dl/bin", func(w http.ResponseWriter, r *http.Request) { w.Write(binary) })
+	mux.HandleFunc("/dl/sums", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(sums)) })
+	mux.HandleFunc("/dl/sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(sums))) + "\n"))
+	})
+	return srv
+}
+
+func sumsFor(binary []byte) string {
+	sum := sha256.Sum256(binary)
+	return fmt.Sprintf("%s  %s\n%s  ubot_plan9_386\n",
+		hex.EncodeToString(sum[:]), AssetName(runtime.GOOS, runtime.GOARCH), strings.Repeat("0", 64))
+}
+
+func currentBinary(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ubot")
+	if err := os.WriteFile(path, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInstall(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	binary := []byte("new binary")
+	srv := releaseServer(t, binary, sumsFor(binary), priv)
+	u := &Updater{Repo: "o/r", APIURL: srv.URL, PublicKey: pub}
+
+	rel, err := u.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if rel.Version != "v0.2.0" {
+		t.Errorf("Version = %q", rel.Version)
+	}
+
+	path := currentBinary(t)
+	if err := u.Install(context.Background(), rel, path); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != string(binary) {
+		t.Errorf("binary = %q", got)
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0o755 {
+		t.Errorf("mode = %v", info.Mode())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("left behind %d files", len(entries)-1)
+	}
+}
+
+func TestInstallRejectsTampering(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	binary := []byte("new binary")
+
+	tests := []struct {
+		name string
+		sums string
+		priv ed25519.PrivateKey
+		key  ed25519.PublicKey
+		want string
+	}{
+		{"checksum", sumsFor([]byte("other binary")), nil, nil, "checksum mismatch"},
+		{"unsigned", sumsFor(binary), nil, pub, "not signed"},
+		{"wrong key", sumsFor(binary), priv, otherPub, "does not match"},
+		{"not listed", "", nil, nil, "no checksum"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := releaseServer(t, binary, tt.sums, tt.priv)
+			u := &Updater{Repo: "o/r", APIURL: srv.URL, PublicKey: tt.key}
+			rel, err := u.Latest(context.Background())
+			if err != nil {
+				t.Fatalf("Latest: %v", err)
+			}
+			path := currentBinary(t)
+			err = u.Install(context.Background(), rel, path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Install error = %v, want %q", err, tt.want)
+			}
+			if got, _ := os.ReadFile(path); string(got) != "old" {
+				t.Errorf("binary replaced with %q", got)
+			}
+		})
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"v0.2.0", "0.1.0", true},
+		{"v0.1.0", "0.1.0", false},
+		{"v0.1.10", "v0.1.9", true},
+		{"v1.0", "v1.0.1", false},
+		{"v1.2.0-rc1", "1.1.9", true},
+		{"v0.2.0", "dev", true},
+		{"nightly", "0.1.0", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.a, tt.b); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,468 @@
+// Package watch notices changes to the files and directories conversations
+// asked about and posts them to the conversation as messages, so the agent
+// can act on them, e.g. tell the user that a build's output changed.
+// Watches are persisted to a JSON file and survive restarts.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+const (
+	// defaultDebounce is how long a watched path must be quiet before its
+	// changes are reported, so a build writing many files causes one
+	// message.
+	defaultDebounce = 2 * time.Second
+	// defaultMinInterval is the least time between two messages for one
+	// watch; changes in between are collected into the next message.
+	defaultMinInterval = time.Minute
+	// maxWatches caps the watches of all conversations together.
+	maxWatches = 50
+	// maxDirs caps the directories one recursive watch follows.
+	maxDirs = 1000
+	// maxListed is how many changed paths one message names.
+	maxListed = 20
+)
+
+// Sender is the SenderID of the messages watches publish.
+const Sender = "watch"
+
+// Watch is a file or directory a conversation wants to hear about.
+type Watch struct {
+	ID          string    `json:"id"`
+	Path        string    `json:"path"` // absolute
+	Recursive   bool      `json:"recursive,omitempty"`
+	Pattern     string    `json:"pattern,omitempty"` // glob on file names, e.g. "*.log"
+	Instruction string    `json:"instruction"`       // what to do on a change
+	Channel     string    `json:"channel"`
+	ChatID      string    `json:"chat_id"`
+	Added       time.Time `json:"added"`
+}
+
+// Owner returns the session key of the conversation that added w.
+func (w Watch) Owner() string {
+	return w.Channel + ":" + w.ChatID
+}
+
+// covers reports whether a change to name concerns w.
+func (w Watch) covers(name string, isDir bool) bool {
+	if name == w.Path {
+		return true
+	}
+	rel, err := filepath.Rel(w.Path, name)
+	if err != nil || !filepath.IsLocal(rel) {
+		return false
+	}
+	if !w.Recursive && strings.ContainsRune(rel, filepath.Separator) {
+		return false
+	}
+	if w.Pattern != "" && !isDir {
+		ok, _ := filepath.Match(w.Pattern, filepath.Base(name))
+		return ok
+	}
+	return true
+}
+
+// entry is a Watch with the changes not reported yet.
+type entry struct {
+	Watch
+	dirs     []string          // directories watched for it
+	changes  map[string]string // path -> what happened, e.g. "modified"
+	since    time.Time         // first unreported change
+	timer    *time.Timer
+	lastSent time.Time
+}
+
+// Watcher follows the watches of all conversations and publishes their
+// changes to the message bus as messages from the conversation's chat.
+type Watcher struct {
+	publish     func(bus.InboundMessage)
+	persistPath string
+	debounce    time.Duration
+	minInterval time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]*entry
+	nextID   int
+	notify   *fsnotify.Watcher // nil until Start
+	dirUsers map[string]int    // watched directory -> number of entries using it
+}
+
+// NewWatcher creates a Watcher persisting its watches to path and handing
+// change messages to publish, usually MessageBus.PublishInbound.
+func NewWatcher(path string, publish func(bus.InboundMessage)) *Watcher {
+	return &Watcher{
+		publish:     publish,
+		persistPath: path,
+		debounce:    defaultDebounce,
+		minInterval: defaultMinInterval,
+		entries:     make(map[string]*entry),
+		nextID:      1,
+		dirUsers:    make(map[string]int),
+	}
+}
+
+// Start loads the persisted watches and follows them until ctx is
+// cancelled.
+func (w *Watcher) Start(ctx context.Context) error {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+
+	w.mu.Lock()
+	w.notify = notify
+	if err := w.load(); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to load watches: %v", err)
+	}
+	for _, e := range w.entries {
+		if err := w.followLocked(e); err != nil {
+			log.Printf("Warning: watch %s: %v", e.ID, err)
+		}
+	}
+	w.mu.Unlock()
+
+	go w.run(ctx)
+	return nil
+}
+
+// run handles file events until ctx is cancelled.
+func (w *Watcher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			w.notify.Close()
+			for _, e := range w.entries {
+				if e.timer != nil {
+					e.timer.Stop()
+				}
+			}
+			w.mu.Unlock()
+			return
+		case event, ok := <-w.notify.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-w.notify.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Warning: file watcher: %v", err)
+		}
+	}
+}
+
+// handle records event for the watches it concerns.
+func (w *Watcher) handle(event fsnotify.Event) {
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+	isDir := false
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			isDir = true
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, e := range w.entries {
+		if !e.covers(event.Name, isDir) {
+			continue
+		}
+		if isDir && e.Recursive {
+			// Follow directories created inside a recursive watch
+			if err := w.addDirsLocked(e, event.Name); err != nil {
+				log.Printf("Warning: watch %s: %v", e.ID, err)
+			}
+		}
+		if isDir && e.Pattern != "" {
+			continue // only files matching the pattern are reported
+		}
+		e.record(event)
+		w.scheduleLocked(e)
+	}
+}
+
+// record notes what event did to a path.
+func (e *entry) record(event fsnotify.Event) {
+	what := "modified"
+	switch {
+	case event.Has(fsnotify.Create):
+		what = "created"
+	case event.Has(fsnotify.Remove):
+		what = "removed"
+	case event.Has(fsnotify.Rename):
+		what = "renamed or moved"
+	}
+	if e.changes == nil {
+		e.changes = make(map[string]string)
+		e.since = time.Now()
+	}
+	// A file written after it was created is still new
+	if e.changes[event.Name] == "created" && what == "modified" {
+		return
+	}
+	e.changes[event.Name] = what
+}
+
+// scheduleLocked (re)starts the timer that reports e's changes once the
+// path has been quiet for the debounce time and the last message is old
+// enough.
+func (w *Watcher) scheduleLocked(e *entry) {
+	delay := max(w.debounce, time.Until(e.lastSent.Add(w.minInterval)))
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	id := e.ID
+	e.timer = time.AfterFunc(delay, func() { w.flush(id) })
+}
+
+// flush publishes the changes collected for the watch id.
+func (w *Watcher) flush(id string) {
+	w.mu.Lock()
+	e, ok := w.entries[id]
+	if !ok || len(e.changes) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	msg := e.message()
+	e.changes, e.timer, e.lastSent = nil, nil, time.Now()
+	w.mu.Unlock()
+
+	w.publish(msg)
+}
+
+// message describes e's changes to its conversation.
+func (e *entry) message() bus.InboundMessage {
+	paths := make([]string, 0, len(e.changes))
+	for p := range e.changes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[File watch %s] %d change(s) in %s since %s:\n", e.ID, len(paths), e.Path, e.since.Format("15:04:05"))
+	for i, p := range paths {
+		if i == maxListed {
+			fmt.Fprintf(&sb, "- ... and %d more\n", len(paths)-maxListed)
+			break
+		}
+		name := p
+		if rel, err := filepath.Rel(e.Path, p); err == nil && rel != "." {
+			name = rel
+		}
+		fmt.Fprintf(&sb, "- %s %s\n", e.changes[p], name)
+	}
+	fmt.Fprintf(&sb, "\nThis message comes from a watch, not from the user. Instruction given when it was set up: %s", e.Instruction)
+
+	return bus.InboundMessage{
+		Channel:   e.Channel,
+		ChatID:    e.ChatID,
+		SenderID:  Sender,
+		Content:   sb.String(),
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"event": "watch", "watchId": e.ID},
+	}
+}
+
+// Add starts watching for wt.Channel and wt.ChatID and returns the watch
+// with its ID.
+func (w *Watcher) Add(wt Watch) (Watch, error) {
+	abs, err := filepath.Abs(wt.Path)
+	if err != nil {
+		return Watch{}, err
+	}
+	wt.Path = abs
+	info, err := os.Stat(wt.Path)
+	if err != nil {
+		return Watch{}, fmt.Errorf("cannot watch %s: %w", wt.Path, err)
+	}
+	if !info.IsDir() && (wt.Recursive || wt.Pattern != "") {
+		return Watch{}, errors.New("recursive and pattern only apply to directories")
+	}
+	if wt.Pattern != "" {
+		if _, err := filepath.Match(wt.Pattern, ""); err != nil {
+			return Watch{}, fmt.Errorf("invalid pattern %q: %w", wt.Pattern, err)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.entries) >= maxWatches {
+		return Watch{}, fmt.Errorf("too many watches (at most %d); remove one first", maxWatches)
+	}
+	wt.ID = strconv.Itoa(w.nextID)
+	wt.Added = time.Now()
+	e := &entry{Watch: wt}
+	if w.notify != nil {
+		if err := w.followLocked(e); err != nil {
+			w.unfollowLocked(e)
+			return Watch{}, err
+		}
+	}
+	w.nextID++
+	w.entries[wt.ID] = e
+
+	if err := w.saveLocked(); err != nil {
+		return wt, fmt.Errorf("watch added but failed to persist: %w", err)
+	}
+	return wt, nil
+}
+
+// Remove stops the watch id of owner.
+func (w *Watcher) Remove(owner, id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	e, ok := w.entries[id]
+	if !ok || e.Owner() != owner {
+		return fmt.Errorf("watch %s not found", id)
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	w.unfollowLocked(e)
+	delete(w.entries, id)
+	return w.saveLocked()
+}
+
+// List returns owner's watches in the order they were added.
+func (w *Watcher) List(owner string) []Watch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var list []Watch
+	for _, e := range w.entries {
+		if e.Owner() == owner {
+			list = append(list, e.Watch)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, _ := strconv.Atoi(list[i].ID)
+		b, _ := strconv.Atoi(list[j].ID)
+		return a < b
+	})
+	return list
+}
+
+// followLocked adds the directories e needs to the fsnotify watcher. A
+// file is followed through its directory, since editors often replace
+// files instead of writing them.
+func (w *Watcher) followLocked(e *entry) error {
+	info, err := os.Stat(e.Path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return w.addDirLocked(e, filepath.Dir(e.Path))
+	}
+	if !e.Recursive {
+		return w.addDirLocked(e, e.Path)
+	}
+	return w.addDirsLocked(e, e.Path)
+}
+
+// addDirsLocked follows root and the directories below it, except hidden
+// ones and node_modules.
+func (w *Watcher) addDirsLocked(e *entry, root string) error {
+	return filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if p != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+			return filepath.SkipDir
+		}
+		if len(e.dirs) >= maxDirs {
+			return fmt.Errorf("%s has more than %d directories; watch a smaller part of it", e.Path, maxDirs)
+		}
+		return w.addDirLocked(e, p)
+	})
+}
+
+// addDirLocked follows dir for e.
+func (w *Watcher) addDirLocked(e *entry, dir string) error {
+	for _, d := range e.dirs {
+		if d == dir {
+			return nil
+		}
+	}
+	if w.dirUsers[dir] == 0 {
+		if err := w.notify.Add(dir); err != nil {
+			return fmt.Errorf("cannot watch %s: %w", dir, err)
+		}
+	}
+	w.dirUsers[dir]++
+	e.dirs = append(e.dirs, dir)
+	return nil
+}
+
+// unfollowLocked stops following the directories only e needed.
+func (w *Watcher) unfollowLocked(e *entry) {
+	for _, dir := range e.dirs {
+		w.dirUsers[dir]--
+		if w.dirUsers[dir] <= 0 {
+			delete(w.dirUsers, dir)
+			w.notify.Remove(dir)
+		}
+	}
+	e.dirs = nil
+}
+
+// --- persistence ---
+
+type persistedState struct {
+	Watches []Watch `json:"watches"`
+	NextID  int     `json:"next_id"`
+}
+
+func (w *Watcher) saveLocked() error {
+	state := persistedState{
+		Watches: make([]Watch, 0, len(w.entries)),
+		NextID:  w.nextID,
+	}
+	for _, e := range w.entries {
+		state.Watches = append(state.Watches, e.Watch)
+	}
+	if err := os.MkdirAll(filepath.Dir(w.persistPath), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(w.persistPath, data, 0o600)
+}
+
+func (w *Watcher) load() error {
+	data, err := os.ReadFile(w.persistPath)
+	if err != nil {
+		return err
+	}
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	for _, wt := range state.Watches {
+		w.entries[wt.ID] = &entry{Watch: wt}
+	}
+	if state.NextID > w.nextID {
+		w.nextID = state.NextID
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkuds/ubot/internal/bus"
+)
+
+func newTestWatcher(t *testing.T, persist string) (*Watcher, chan bus.InboundMessage) {
+	t.Helper()
+	got := make(chan bus.InboundMessage, 10)
+	w := NewWatcher(persist, func(msg bus.InboundMessage) { got <- msg })
+	w.debounce, w.minInterval = 100*time.Millisecond, 0
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return w, got
+}
+
+func receive(t *testing.T, got chan bus.InboundMessage) bus.InboundMessage {
+	t.Helper()
+	select {
+	case msg := <-got:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+		return bus.InboundMessage{}
+	}
+}
+
+func TestWatcherReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	build := filepath.Join(dir, "build")
+	if err := os.MkdirAll(filepath.Join(build, "logs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	persist := filepath.Join(t.TempDir(), "watches.json")
+	w, got := newTestWatcher(t, persist)
+
+	wt, err := w.Add(Watch{Path: build, Recursive: true, Pattern: "*.log", Instruction: "tell me if the build failed", Channel: "telegram", ChatID: "42"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// Several writes in a burst make one message; other names are ignored
+	os.WriteFile(filepath.Join(build, "logs", "build.log"), []byte("FAILED"), 0o644)
+	os.WriteFile(filepath.Join(build, "logs", "build.log"), []byte("FAILED twice"), 0o644)
+	os.WriteFile(filepath.Join(build, "app.bin"), []byte("binary"), 0o644)
+	msg := receive(t, got)
+	if msg.Channel != "telegram" || msg.ChatID != "42" || msg.SessionKey() != wt.Owner() || msg.Metadata["watchId"] != wt.ID {
+		t.Errorf("message routed to %s with %v", msg.SessionKey(), msg.Metadata)
+	}
+	if !strings.Contains(msg.Content, "1 change(s)") || !strings.Contains(msg.Content, "created "+filepath.Join("logs", "build.log")) ||
+		strings.Contains(msg.Content, "app.bin") || !strings.Contains(msg.Content, "tell me if the build failed") {
+		t.Errorf("content = %q", msg.Content)
+	}
+
+	// Directories created later are followed too
+	os.MkdirAll(filepath.Join(build, "nested"), 0o755)
+	time.Sleep(200 * time.Millisecond)
+	os.WriteFile(filepath.Join(build, "nested", "test.log"), []byte("ok"), 0o644)
+	if msg := receive(t, got); !strings.Contains(msg.Content, filepath.Join("nested", "test.log")) {
+		t.Errorf("nested change: %q", msg.Content)
+	}
+
+	// Watches belong to their conversation and survive a restart
+	if err := w.Remove("cli:direct", wt.ID); err == nil {
+		t.Error("another conversation removed the watch")
+	}
+	w2, _ := newTestWatcher(t, persist)
+	if list := w2.List("telegram:42"); len(list) != 1 || list[0].Path != build || !list[0].Recursive {
+		t.Errorf("after restart: %+v", list)
+	}
+	if err := w2.Remove("telegram:42", wt.ID); err != nil || len(w2.List("telegram:42")) != 0 {
+		t.Errorf("Remove: %v", err)
+	}
+}
+
+func TestWatcherFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "output.txt")
+	os.WriteFile(file, []byte("v1"), 0o644)
+	w, got := newTestWatcher(t, filepath.Join(dir, "watches.json"))
+
+	if _, err := w.Add(Watch{Path: file, Instruction: "report", Channel: "cli", ChatID: "direct"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := w.Add(Watch{Path: file, Recursive: true, Channel: "cli", ChatID: "direct"}); err == nil {
+		t.Error("recursive watch of a file was accepted")
+	}
+	if _, err := w.Add(Watch{Path: filepath.Join(dir, "missing"), Channel: "cli", ChatID: "direct"}); err == nil {
+		t.Error("watch of a missing path was accepted")
+	}
+
+	os.WriteFile(filepath.Join(dir, "other.txt"), []byte("x"), 0o644)
+	os.WriteFile(file, []byte("v2"), 0o644)
+	msg := receive(t, got)
+	if !strings.Contains(msg.Content, "modified "+file) || strings.Contains(msg.Content, "other.txt") {
+		t.Errorf("content = %q", msg.Content)
+	}
+}